@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"time"
 
 	govclient "github.com/chronnie/governance/client"
 	"github.com/chronnie/governance/models"
+	"github.com/hsdfat8/eir/internal/adapters/auditprotect"
 	"github.com/hsdfat8/eir/internal/adapters/diameter"
+	"github.com/hsdfat8/eir/internal/adapters/factory"
 	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
 	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/adapters/redis"
 	"github.com/hsdfat8/eir/internal/config"
 	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
 	"github.com/hsdfat8/eir/internal/logger"
 )
 
@@ -19,6 +26,15 @@ import (
 type Application struct {
 	cfg            *config.Config
 	logger         logger.Logger
+	database       ports.DatabaseAdapter // nil when running on the memory backend
+	dualWrite      ports.DatabaseAdapter // secondary receiving mirrored writes, nil unless dual-write is enabled
+	memoryStore    io.Closer             // write-ahead log of the memory backend, nil when not persisted
+	cacheClient    io.Closer             // nil when caching is disabled
+	stopChangeFeed context.CancelFunc
+	snapshots      *service.SnapshotScheduler
+	checkpointer   *service.AuditCheckpointer
+	checks         *service.CheckCounter
+	maintenance    *service.MaintenanceScheduler
 	httpServer     *httpAdapter.Server
 	diameterServer *diameter.Server
 	govClient      *govclient.Client
@@ -50,16 +66,397 @@ func getRegistrationIP(configuredHost string) string {
 	return configuredHost
 }
 
-// initializeRepositories sets up IMEI and audit repositories
-func initializeRepositories(log logger.Logger) (ports.IMEIRepository, ports.AuditRepository) {
-	imeiRepo := memory.NewInMemoryIMEIRepository()
-	auditRepo := memory.NewInMemoryAuditRepository()
-	log.Info("✓ Repositories initialized")
-	return imeiRepo, auditRepo
+// initializeRepositories sets up IMEI and audit repositories for the configured backend.
+// The returned adapter is nil for the memory backend.
+func initializeRepositories(cfg *config.Config, log logger.Logger) (ports.IMEIRepository, ports.AuditRepository, ports.DatabaseAdapter) {
+	if cfg.Database.Type == "" || cfg.Database.Type == "memory" {
+		imeiRepo := initializeMemoryRepository(cfg.Database.Memory, log)
+		auditRepo := memory.NewInMemoryAuditRepository()
+		log.Infow("✓ Repositories initialized", "backend", "memory")
+		return imeiRepo, auditRepo, nil
+	}
+
+	adapter, err := connectDatabase(cfg.Database, log)
+	if err != nil {
+		log.Fatalw("Failed to connect to database", "type", cfg.Database.Type, "error", err)
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateDatabase(adapter, cfg.Database, log); err != nil {
+			_ = adapter.Disconnect(context.Background())
+			log.Fatalw("Failed to run database migrations", "type", cfg.Database.Type, "error", err)
+		}
+	}
+
+	log.Infow("✓ Repositories initialized", "backend", adapter.GetType())
+	return adapter.GetIMEIRepository(), adapter.GetAuditRepository(), adapter
+}
+
+// initializeMemoryRepository creates the memory IMEI repository, restoring it
+// from its write-ahead log when a data directory is configured
+func initializeMemoryRepository(cfg config.MemoryConfig, log logger.Logger) ports.IMEIRepository {
+	if cfg.DataDir == "" {
+		return memory.NewInMemoryIMEIRepository()
+	}
+
+	repo, err := memory.OpenPersistentIMEIRepository(memory.PersistenceConfig{
+		Dir:              cfg.DataDir,
+		Fsync:            memory.FsyncPolicy(cfg.Fsync),
+		FsyncInterval:    cfg.FsyncInterval,
+		SnapshotInterval: cfg.SnapshotInterval,
+		CompactSize:      cfg.CompactSize,
+	})
+	if err != nil {
+		log.Fatalw("Failed to restore memory repository", "dir", cfg.DataDir, "error", err)
+	}
+	log.Infow("✓ Memory repository restored", "dir", cfg.DataDir, "fsync", cfg.Fsync)
+	return repo
+}
+
+// initializeHistory returns the repository provisioning changes are recorded in
+func initializeHistory(database ports.DatabaseAdapter) ports.HistoryRepository {
+	if database != nil {
+		return database.GetHistoryRepository()
+	}
+	return memory.NewInMemoryHistoryRepository()
+}
+
+// initializeSnapshots returns the repository equipment snapshots are kept in
+func initializeSnapshots(database ports.DatabaseAdapter) ports.SnapshotRepository {
+	if database != nil {
+		return database.GetSnapshotRepository()
+	}
+	return memory.NewInMemorySnapshotRepository()
+}
+
+// initializeAuditProtection returns the wrapper that pseudonymises or
+// encrypts the subscriber identifiers of new audits before they reach an
+// audit repository. It returns repositories unchanged when protection is disabled.
+func initializeAuditProtection(cfg config.AuditConfig, log logger.Logger) func(ports.AuditRepository) ports.AuditRepository {
+	if !cfg.Protect {
+		return func(auditRepo ports.AuditRepository) ports.AuditRepository { return auditRepo }
+	}
+
+	protector, err := auditprotect.NewProtector(cfg)
+	if err != nil {
+		log.Fatalw("Failed to initialize audit protection", "error", err)
+	}
+
+	log.Infow("✓ Audit identifier protection enabled", "activeKey", cfg.ActiveKey, "userName", cfg.UserName, "supi", cfg.SUPI, "gpsi", cfg.GPSI)
+	return func(auditRepo ports.AuditRepository) ports.AuditRepository {
+		return auditprotect.NewAuditRepository(auditRepo, protector)
+	}
+}
+
+// auditChainStore returns the hash-chained audit records of the backend.
+// auditRepo must be the repository before any wrapping.
+func auditChainStore(database ports.DatabaseAdapter, auditRepo ports.AuditRepository) (ports.AuditChainStore, bool) {
+	if database != nil {
+		adapter, ok := database.(ports.AuditChainAdapter)
+		if !ok {
+			return nil, false
+		}
+		return adapter.GetAuditChainStore(), true
+	}
+	store, ok := auditRepo.(ports.AuditChainStore)
+	return store, ok
+}
+
+// startAuditCheckpointer starts signing audit chain checkpoints when an
+// interval is configured. It returns nil when it is not.
+func startAuditCheckpointer(cfg config.AuditChainConfig, store ports.AuditChainStore, log logger.Logger) *service.AuditCheckpointer {
+	if cfg.CheckpointInterval <= 0 {
+		return nil
+	}
+	if store == nil {
+		log.Warnw("Audit chain checkpoints are not supported by this backend, skipping")
+		return nil
+	}
+
+	keys, err := service.NewAuditChainKeys(cfg)
+	if err != nil {
+		log.Fatalw("Failed to load audit chain keys", "error", err)
+	}
+	checkpointer, err := service.NewAuditCheckpointer(store, keys, cfg.CheckpointInterval)
+	if err != nil {
+		log.Fatalw("Failed to create audit checkpointer", "error", err)
+	}
+	checkpointer.Start()
+	log.Infow("✓ Audit chain checkpoints started", "interval", cfg.CheckpointInterval, "key", cfg.SigningKeyID)
+	return checkpointer
+}
+
+// startCheckCounter starts counting equipment checks when enabled. It
+// returns nil when it is not.
+func startCheckCounter(cfg config.CheckCountersConfig, imeiRepo ports.IMEIRepository, log logger.Logger) *service.CheckCounter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	counter, err := service.NewCheckCounter(imeiRepo, cfg)
+	if err != nil {
+		log.Fatalw("Failed to create check counter", "error", err)
+	}
+	counter.Start()
+	log.Infow("✓ Check counters started", "flushInterval", cfg.FlushInterval, "batchSize", cfg.BatchSize)
+	return counter
+}
+
+// startSnapshotScheduler starts taking SCHEDULED snapshots when they are
+// enabled. It returns nil when they are not.
+func startSnapshotScheduler(cfg config.SnapshotConfig, eirService ports.EIRService, log logger.Logger) *service.SnapshotScheduler {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	scheduler, err := service.NewSnapshotScheduler(eirService, cfg)
+	if err != nil {
+		log.Fatalw("Failed to create snapshot scheduler", "schedule", cfg.Schedule, "error", err)
+	}
+	scheduler.Start()
+	log.Infow("✓ Snapshot scheduler started", "schedule", cfg.Schedule, "retention", cfg.Retention)
+	return scheduler
+}
+
+// startMaintenance creates the database maintenance scheduler, starting its
+// schedule when enabled. Manual runs are available whenever a database is
+// configured; the memory backend has nothing to maintain and gets nil.
+func startMaintenance(cfg config.MaintenanceConfig, database ports.DatabaseAdapter, log logger.Logger) *service.MaintenanceScheduler {
+	if database == nil {
+		if cfg.Enabled {
+			log.Warnw("Database maintenance needs a database backend, skipping")
+		}
+		return nil
+	}
+
+	scheduler, err := service.NewMaintenanceScheduler(database, cfg)
+	if err != nil {
+		log.Fatalw("Failed to create maintenance scheduler", "schedule", cfg.Schedule, "error", err)
+	}
+	scheduler.Start()
+	if cfg.Enabled {
+		log.Infow("✓ Maintenance scheduler started",
+			"schedule", cfg.Schedule,
+			"window", cfg.WindowStart+"-"+cfg.WindowEnd,
+			"batch_size", cfg.BatchSize)
+	}
+	return scheduler
+}
+
+// initializeTransactions returns what provisioning runs its transactions on:
+// the database adapter, or a transaction manager over the memory repositories
+func initializeTransactions(imeiRepo ports.IMEIRepository, auditRepo ports.AuditRepository, history ports.HistoryRepository, database ports.DatabaseAdapter, log logger.Logger) ports.TransactionProvider {
+	if database != nil {
+		return database
+	}
+
+	txs, err := memory.NewTransactionManager(imeiRepo, auditRepo, history)
+	if err != nil {
+		log.Warnw("Memory transactions unavailable, provisioning is not atomic", "error", err)
+		return nil
+	}
+	return txs
+}
+
+// initializeDualWrite connects the dual-write secondary, or returns nil when dual-write is disabled
+func initializeDualWrite(cfg config.DualWriteConfig, log logger.Logger) ports.DatabaseAdapter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	adapter, err := connectDatabase(cfg.Target, log)
+	if err != nil {
+		log.Fatalw("Failed to connect to dual-write target", "type", cfg.Target.Type, "error", err)
+	}
+	if cfg.Target.AutoMigrate {
+		if err := migrateDatabase(adapter, cfg.Target, log); err != nil {
+			_ = adapter.Disconnect(context.Background())
+			log.Fatalw("Failed to run dual-write target migrations", "type", cfg.Target.Type, "error", err)
+		}
+	}
+
+	// Records written before this moment must be copied with `migrate copy -cutoff`
+	log.Infow("✓ Dual-write enabled", "target", adapter.GetType(), "since", time.Now().UTC().Format(time.RFC3339))
+	return adapter
+}
+
+// initializeCache connects the optional equipment cache. The cache is an
+// optimisation, so an unreachable Redis is logged and the service runs without it.
+func initializeCache(cfg *config.Config, log logger.Logger) (ports.CacheRepository, io.Closer) {
+	if !cfg.Cache.Enabled {
+		return nil, nil
+	}
+	if cfg.Cache.Provider != "redis" {
+		log.Warnw("Cache provider not supported, caching disabled", "provider", cfg.Cache.Provider)
+		return nil, nil
+	}
+
+	rc := cfg.Cache.Redis
+	client, err := redis.NewClient(redis.Config{
+		Host:         rc.Host,
+		Port:         rc.Port,
+		Password:     rc.Password,
+		DB:           rc.DB,
+		PoolSize:     rc.PoolSize,
+		MinIdleConns: rc.MinIdleConns,
+		DialTimeout:  rc.DialTimeout,
+		ReadTimeout:  rc.ReadTimeout,
+		WriteTimeout: rc.WriteTimeout,
+	})
+	if err != nil {
+		log.Warnw("Failed to connect to Redis, caching disabled", "host", rc.Host, "port", rc.Port, "error", err)
+		return nil, nil
+	}
+
+	log.Infow("✓ Cache initialized", "provider", "redis", "namespace", rc.Namespace, "ttl", rc.TTL)
+	return redis.NewCacheRepository(client, rc.Namespace, rc.TTL), client
+}
+
+// initializeDecisionCache creates the optional in-process check decision cache
+func initializeDecisionCache(cfg *config.Config, log logger.Logger) ports.DecisionCache {
+	dc := cfg.Cache.Decision
+	if !dc.Enabled {
+		return nil
+	}
+
+	log.Infow("✓ Decision cache initialized", "shards", dc.Shards, "capacity", dc.Capacity, "positive_ttl", dc.PositiveTTL, "negative_ttl", dc.NegativeTTL)
+	return memory.NewDecisionCache(memory.DecisionCacheConfig{
+		Shards:      dc.Shards,
+		Capacity:    dc.Capacity,
+		PositiveTTL: dc.PositiveTTL,
+		NegativeTTL: dc.NegativeTTL,
+	})
+}
+
+// startChangeFeed subscribes to the database change feed, when the backend
+// provides one, so that changes made through other replicas invalidate local
+// caches. The returned function stops the subscription.
+func startChangeFeed(database ports.DatabaseAdapter, cache ports.CacheRepository, decisions ports.DecisionCache, log logger.Logger) context.CancelFunc {
+	provider, ok := database.(ports.ChangeFeedProvider)
+	if !ok {
+		return func() {}
+	}
+	feed := provider.GetChangeFeed()
+	if feed == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go service.NewChangeApplier(cache, decisions).Run(ctx, feed)
+
+	log.Infow("✓ Change feed started", "backend", database.GetType())
+	return cancel
+}
+
+// databaseConfig maps the application database settings onto the adapter configuration
+func databaseConfig(cfg config.DatabaseConfig) *ports.DatabaseConfig {
+	dbConfig := &ports.DatabaseConfig{Type: ports.DatabaseType(cfg.Type)}
+
+	switch dbConfig.Type {
+	case ports.DatabaseTypePostgreSQL:
+		dbConfig.PostgresConfig = &ports.PostgresConfig{
+			Host:            cfg.Host,
+			Port:            cfg.Port,
+			User:            cfg.User,
+			Password:        cfg.Password,
+			Database:        cfg.Database,
+			SSLMode:         cfg.SSLMode,
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: int(cfg.ConnMaxLifetime.Seconds()),
+			ConnMaxIdleTime: int(cfg.ConnMaxIdleTime.Seconds()),
+			QueryTimeout:    int(cfg.QueryTimeout.Seconds()),
+			EnableNotify:    cfg.EnableNotify,
+
+			ReplicaMaxLag:        int(cfg.ReplicaMaxLag.Seconds()),
+			ReplicaCheckInterval: int(cfg.ReplicaCheckInterval.Seconds()),
+		}
+		for _, replica := range cfg.Replicas {
+			dbConfig.PostgresConfig.Replicas = append(dbConfig.PostgresConfig.Replicas, ports.PostgresReplicaConfig{Host: replica.Host, Port: replica.Port})
+		}
+	case ports.DatabaseTypeMongoDB:
+		dbConfig.MongoDBConfig = &ports.MongoDBConfig{
+			URI:                cfg.Mongo.URI,
+			Database:           cfg.Mongo.Database,
+			MaxPoolSize:        cfg.Mongo.MaxPoolSize,
+			MinPoolSize:        cfg.Mongo.MinPoolSize,
+			MaxConnIdleTime:    int(cfg.Mongo.MaxConnIdleTime.Seconds()),
+			ServerTimeout:      int(cfg.Mongo.ServerTimeout.Seconds()),
+			SocketTimeout:      int(cfg.Mongo.SocketTimeout.Seconds()),
+			ReplicaSet:         cfg.Mongo.ReplicaSet,
+			ReadPreference:     cfg.Mongo.ReadPreference,
+			WriteConcern:       cfg.Mongo.WriteConcern,
+			EnableChangeStream: cfg.Mongo.EnableChangeStream,
+		}
+	case ports.DatabaseTypeEmbedded:
+		dbConfig.EmbeddedConfig = &ports.EmbeddedConfig{
+			Path:        cfg.Embedded.Path,
+			LockTimeout: int(cfg.Embedded.LockTimeout.Seconds()),
+			NoSync:      cfg.Embedded.NoSync,
+		}
+	}
+
+	return dbConfig
+}
+
+// connectDatabase creates the configured adapter and connects it, retrying
+// so the service can start alongside a database that is still booting
+func connectDatabase(cfg config.DatabaseConfig, log logger.Logger) (ports.DatabaseAdapter, error) {
+	adapterFactory := factory.NewDatabaseAdapterFactory()
+	dbConfig := databaseConfig(cfg)
+
+	if err := adapterFactory.ValidateConfig(dbConfig); err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	attempts := cfg.ConnectRetries + 1
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		ctx := context.Background()
+		cancel := func() {}
+		if cfg.ConnectTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		}
+		adapter, err := adapterFactory.CreateAndConnectAdapter(ctx, dbConfig)
+		cancel()
+		if err == nil {
+			log.Infow("✓ Connected to database", "type", dbConfig.Type, "attempt", attempt)
+			return adapter, nil
+		}
+
+		lastErr = err
+		if attempt < attempts {
+			log.Warnw("Database connection failed, retrying",
+				"type", dbConfig.Type,
+				"attempt", attempt,
+				"max_attempts", attempts,
+				"retry_in", cfg.ConnectRetryInterval.String(),
+				"error", err)
+			time.Sleep(cfg.ConnectRetryInterval)
+		}
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, lastErr)
+}
+
+// migrateDatabase applies pending schema migrations when the adapter supports them
+func migrateDatabase(adapter ports.DatabaseAdapter, cfg config.DatabaseConfig, log logger.Logger) error {
+	migratable, ok := adapter.(ports.MigratableAdapter)
+	if !ok {
+		log.Infow("Database adapter has no migrations, skipping", "type", adapter.GetType())
+		return nil
+	}
+
+	if err := migratable.GetMigrationManager().Run(context.Background()); err != nil {
+		return err
+	}
+
+	log.Infow("✓ Database migrations applied", "type", adapter.GetType())
+	return nil
 }
 
 // initializeHTTPServer configures and starts the HTTP/2 server
-func initializeHTTPServer(cfg *config.Config, eirService ports.EIRService, log logger.Logger) *httpAdapter.Server {
+func initializeHTTPServer(cfg *config.Config, eirService ports.EIRService, database ports.DatabaseAdapter, maintenance *service.MaintenanceScheduler, exporter ports.DataExporter, log logger.Logger) *httpAdapter.Server {
 	httpServerConfig := httpAdapter.ServerConfig{
 		ListenAddr:   fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 		ReadTimeout:  cfg.Server.ReadTimeout,
@@ -69,6 +466,22 @@ func initializeHTTPServer(cfg *config.Config, eirService ports.EIRService, log l
 	}
 
 	httpServer := httpAdapter.NewServer(httpServerConfig, eirService)
+	if database != nil {
+		httpServer.SetHealthReporter(database)
+	}
+	if maintenance != nil {
+		httpServer.SetMaintenanceRunner(maintenance)
+	}
+	httpServer.SetDataExporter(exporter)
+	httpServer.SetAuditAccessTokens(cfg.Audit.AccessTokens)
+	apiTokens := make(map[string]string, len(cfg.Server.APITokens))
+	for _, token := range cfg.Server.APITokens {
+		apiTokens[token.Token] = token.Actor
+	}
+	httpServer.SetAPITokens(apiTokens)
+	if err := httpServer.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalw("Invalid trusted proxies", "error", err)
+	}
 
 	if err := httpServer.Start(); err != nil {
 		log.Fatalw("Failed to start HTTP server", "error", err)
@@ -195,4 +608,71 @@ func (app *Application) shutdown() {
 	}
 
 	app.logger.Info("Servers stopped gracefully")
+
+	// No more checks can arrive, so the last counted ones are written now
+	if app.checks != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := app.checks.Stop(ctx); err != nil {
+			app.logger.Errorw("Failed to flush check counters", "pending", app.checks.Pending(), "error", err)
+		} else {
+			app.logger.Info("✓ Check counters flushed")
+		}
+		cancel()
+	}
+
+	if app.stopChangeFeed != nil {
+		app.stopChangeFeed()
+	}
+
+	if app.snapshots != nil {
+		app.snapshots.Stop()
+		app.logger.Info("✓ Snapshot scheduler stopped")
+	}
+
+	if app.checkpointer != nil {
+		app.checkpointer.Stop()
+		app.logger.Info("✓ Audit checkpointer stopped")
+	}
+
+	if app.maintenance != nil {
+		app.maintenance.Stop()
+		app.logger.Info("✓ Maintenance scheduler stopped")
+	}
+
+	if app.cacheClient != nil {
+		if err := app.cacheClient.Close(); err != nil {
+			app.logger.Errorw("Cache close error", "error", err)
+		} else {
+			app.logger.Info("✓ Cache connection closed")
+		}
+	}
+
+	// Close the database last so in-flight requests can finish
+	if app.memoryStore != nil {
+		if err := app.memoryStore.Close(); err != nil {
+			app.logger.Errorw("Memory repository close error", "error", err)
+		} else {
+			app.logger.Info("✓ Memory repository flushed")
+		}
+	}
+
+	if app.dualWrite != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.dualWrite.Disconnect(ctx); err != nil {
+			app.logger.Errorw("Dual-write target disconnect error", "error", err)
+		} else {
+			app.logger.Info("✓ Dual-write target disconnected")
+		}
+	}
+
+	if app.database != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.database.Disconnect(ctx); err != nil {
+			app.logger.Errorw("Database disconnect error", "error", err)
+		} else {
+			app.logger.Info("✓ Database disconnected")
+		}
+	}
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hsdfat8/eir/internal/adapters/export"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+const exportUsage = `Usage: eir export [flags] <equipment|audits|history>
+
+Streams a dataset from the configured database to stdout or a file.
+Logs are written to stderr.
+
+Flags:
+`
+
+// newDataExporter returns the adapter's native exporter, falling back to
+// paging through the repositories for the memory and embedded backends
+func newDataExporter(database ports.DatabaseAdapter, imeiRepo ports.IMEIRepository, auditRepo ports.AuditRepository, history ports.HistoryRepository) ports.DataExporter {
+	if exportable, ok := database.(ports.ExportableAdapter); ok {
+		return exportable.GetDataExporter()
+	}
+	return memory.NewDataExporter(imeiRepo, auditRepo, history)
+}
+
+// runExport implements `eir export` and returns the process exit code
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	var (
+		format = flags.String("format", export.FormatNDJSON, "Output format (csv, ndjson, json)")
+		start  = flags.String("start", "", "Earliest audit check or history change to export (RFC 3339 or YYYY-MM-DD)")
+		end    = flags.String("end", "", "Latest audit check or history change to export (RFC 3339 or YYYY-MM-DD)")
+		output = flags.String("o", "", "Output file (default stdout)")
+	)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, exportUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	dataset := flags.Arg(0)
+	if dataset != "equipment" && dataset != "audits" && dataset != "history" {
+		fmt.Fprintf(os.Stderr, "Unknown dataset %q\n", dataset)
+		return 2
+	}
+	if err := export.ValidateFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	log := logger.New("eir-export", "info")
+	cfg, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	imeiRepo, auditRepo, database := initializeRepositories(cfg, log)
+	defer closeRepositories(imeiRepo, database)
+	exporter := newDataExporter(database, imeiRepo, auditRepo, initializeHistory(database))
+	defer exporter.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *output, err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	ctx := context.Background()
+	switch dataset {
+	case "equipment":
+		err = exporter.ExportEquipment(ctx, out, *format)
+	case "audits":
+		err = exporter.ExportAudits(ctx, out, *format, *start, *end)
+	case "history":
+		err = exporter.ExportHistory(ctx, out, *format, *start, *end)
+	}
+	if err != nil {
+		log.Errorw("Export failed", "dataset", dataset, "error", err)
+		return 1
+	}
+	log.Infow("✓ Export complete", "dataset", dataset, "format", *format)
+	return 0
+}
@@ -1,16 +1,31 @@
 package main
 
 import (
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/hsdfat8/eir/internal/adapters/dualwrite"
 	"github.com/hsdfat8/eir/internal/config"
 	"github.com/hsdfat8/eir/internal/domain/service"
 	"github.com/hsdfat8/eir/internal/logger"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "backup":
+			os.Exit(runBackup(os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		}
+	}
+
 	log := logger.New("eir-main", "info")
 
 	cfg, err := config.Load("")
@@ -18,15 +33,60 @@ func main() {
 		log.Fatalw("Failed to load configuration", "error", err)
 	}
 
-	imeiRepo, auditRepo := initializeRepositories(log)
+	imeiRepo, auditRepo, database := initializeRepositories(cfg, log)
+	chainStore, _ := auditChainStore(database, auditRepo)
+	// Every audit writer, transactions and dual-write included, goes through protection
+	protectAudits := initializeAuditProtection(cfg.Audit, log)
+	auditRepo = protectAudits(auditRepo)
+	memoryStore, _ := imeiRepo.(io.Closer)
+	history := initializeHistory(database)
+	snapshots := initializeSnapshots(database)
+	txs := initializeTransactions(imeiRepo, auditRepo, history, database, log)
+
+	dualWriteTarget := initializeDualWrite(cfg.DualWrite, log)
+	if dualWriteTarget != nil {
+		imeiRepo = dualwrite.NewIMEIRepository(imeiRepo, dualWriteTarget.GetIMEIRepository(), log)
+		auditRepo = dualwrite.NewAuditRepository(auditRepo, protectAudits(dualWriteTarget.GetAuditRepository()), log)
+		history = dualwrite.NewHistoryRepository(history, dualWriteTarget.GetHistoryRepository(), log)
+		snapshots = dualwrite.NewSnapshotRepository(snapshots, dualWriteTarget.GetSnapshotRepository(), dualWriteTarget.GetIMEIRepository(), log)
+		if txs != nil {
+			txs = dualwrite.NewTransactionProvider(txs, dualWriteTarget, log)
+		}
+	}
+
+	cache, cacheClient := initializeCache(cfg, log)
 
-	eirService := service.NewEIRService(cfg, imeiRepo, auditRepo, nil)
+	eirService := service.NewEIRService(cfg, imeiRepo, auditRepo, cache)
+	decisions := initializeDecisionCache(cfg, log)
+	if decisions != nil {
+		eirService.SetDecisionCache(decisions)
+	}
+	checks := startCheckCounter(cfg.Checks, imeiRepo, log)
+	if checks != nil {
+		eirService.SetCheckRecorder(checks)
+	}
+	eirService.SetHistoryRepository(history)
+	eirService.SetSnapshotRepository(snapshots)
+	if txs != nil {
+		eirService.SetTransactionProvider(txs)
+	}
 	log.Info("✓ EIR service initialized")
 
+	maintenance := startMaintenance(cfg.Maintenance, database, log)
+
 	app := &Application{
 		cfg:            cfg,
 		logger:         log,
-		httpServer:     initializeHTTPServer(cfg, eirService, log),
+		database:       database,
+		dualWrite:      dualWriteTarget,
+		memoryStore:    memoryStore,
+		cacheClient:    cacheClient,
+		stopChangeFeed: startChangeFeed(database, cache, decisions, log),
+		snapshots:      startSnapshotScheduler(cfg.Snapshot, eirService, log),
+		checkpointer:   startAuditCheckpointer(cfg.Audit.Chain, chainStore, log),
+		checks:         checks,
+		maintenance:    maintenance,
+		httpServer:     initializeHTTPServer(cfg, eirService, database, maintenance, newDataExporter(database, imeiRepo, auditRepo, history), log),
 		diameterServer: initializeDiameterServer(cfg, eirService, log),
 		govClient:      registerWithGovernance(cfg, log),
 	}
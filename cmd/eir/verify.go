@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+const verifyUsage = `Usage: eir verify [flags]
+
+Walks the hash chain of the audit log in the configured database, checking
+every record hash, link and signed checkpoint, and reports the first broken
+link. Exits 1 when the chain is broken. Logs are written to stderr.
+
+Flags:
+`
+
+// runVerify implements `eir verify` and returns the process exit code
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	partitions := flags.String("partition", "", "Comma-separated UTC days (YYYY-MM-DD) to verify (default all)")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, verifyUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	log := logger.New("eir-verify", "info")
+	cfg, err := config.Load("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	keys, err := service.NewAuditChainKeys(cfg.Audit.Chain)
+	if err != nil {
+		log.Errorw("Failed to load audit chain keys", "error", err)
+		return 1
+	}
+
+	imeiRepo, auditRepo, database := initializeRepositories(cfg, log)
+	defer closeRepositories(imeiRepo, database)
+	store, ok := auditChainStore(database, auditRepo)
+	if !ok {
+		log.Errorw("The configured backend does not chain audit records")
+		return 1
+	}
+
+	var selected []string
+	if *partitions != "" {
+		selected = strings.Split(*partitions, ",")
+	}
+	report, err := service.VerifyAuditChain(context.Background(), store, keys, selected)
+	if err != nil {
+		log.Errorw("Audit chain verification failed", "error", err)
+		return 1
+	}
+	if report.Break != nil {
+		log.Errorw("✗ Audit chain broken",
+			"partition", report.Break.Partition,
+			"seq", report.Break.Seq,
+			"audit_id", report.Break.AuditID,
+			"reason", report.Break.Reason,
+			"records_verified", report.Records)
+		return 1
+	}
+	log.Infow("✓ Audit chain verified", "partitions", report.Partitions, "records", report.Records, "checkpoints", report.Checkpoints)
+	return 0
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/hsdfat8/eir/internal/adapters/factory"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// connectionFlags describes one database on the command line. The source is
+// registered without a prefix and the copy target with "to-".
+type connectionFlags struct {
+	dbType        *string
+	databaseURL   *string
+	host          *string
+	port          *int
+	user          *string
+	password      *string
+	dbname        *string
+	sslmode       *string
+	mongoURI      *string
+	mongoDatabase *string
+	path          *string
+}
+
+func registerConnectionFlags(prefix, role, defaultType string) *connectionFlags {
+	return &connectionFlags{
+		dbType:        flag.String(prefix+"type", defaultType, role+"Database type (postgres, mongodb, embedded)"),
+		databaseURL:   flag.String(prefix+"database-url", "", role+"PostgreSQL connection string (overrides individual flags)"),
+		host:          flag.String(prefix+"host", "localhost", role+"Database host"),
+		port:          flag.Int(prefix+"port", 5432, role+"Database port"),
+		user:          flag.String(prefix+"user", "eir", role+"Database user"),
+		password:      flag.String(prefix+"password", "eir_password", role+"Database password"),
+		dbname:        flag.String(prefix+"dbname", "eir", role+"Database name"),
+		sslmode:       flag.String(prefix+"sslmode", "disable", role+"SSL mode (disable, require, verify-ca, verify-full)"),
+		mongoURI:      flag.String(prefix+"mongo-uri", "mongodb://localhost:27017", role+"MongoDB connection URI"),
+		mongoDatabase: flag.String(prefix+"mongo-database", "eir", role+"MongoDB database name"),
+		path:          flag.String(prefix+"path", "data/eir.db", role+"Embedded database file"),
+	}
+}
+
+// dsn returns the PostgreSQL connection string
+func (c *connectionFlags) dsn() string {
+	if *c.databaseURL != "" {
+		return *c.databaseURL
+	}
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		*c.host, *c.port, *c.user, *c.password, *c.dbname, *c.sslmode,
+	)
+}
+
+// connect opens a full database adapter, as the service does
+func (c *connectionFlags) connect(ctx context.Context) (ports.DatabaseAdapter, error) {
+	dbConfig := factory.CreateDefaultConfig(ports.DatabaseType(*c.dbType))
+	switch dbConfig.Type {
+	case ports.DatabaseTypePostgreSQL:
+		if *c.databaseURL != "" {
+			return nil, fmt.Errorf("-database-url is not supported here, use the individual connection flags")
+		}
+		dbConfig.PostgresConfig.Host = *c.host
+		dbConfig.PostgresConfig.Port = *c.port
+		dbConfig.PostgresConfig.User = *c.user
+		dbConfig.PostgresConfig.Password = *c.password
+		dbConfig.PostgresConfig.Database = *c.dbname
+		dbConfig.PostgresConfig.SSLMode = *c.sslmode
+	case ports.DatabaseTypeMongoDB:
+		dbConfig.MongoDBConfig.URI = *c.mongoURI
+		dbConfig.MongoDBConfig.Database = *c.mongoDatabase
+	case ports.DatabaseTypeEmbedded:
+		dbConfig.EmbeddedConfig.Path = *c.path
+	default:
+		return nil, fmt.Errorf("unsupported database type: %q", *c.dbType)
+	}
+
+	adapterFactory := factory.NewDatabaseAdapterFactory()
+	if err := adapterFactory.ValidateConfig(dbConfig); err != nil {
+		return nil, err
+	}
+	return adapterFactory.CreateAndConnectAdapter(ctx, dbConfig)
+}
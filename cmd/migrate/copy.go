@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/dbcopy"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+func copyOptions(batchSize int, checkpoint string, restart bool, cutoff, datasets string) (dbcopy.Options, error) {
+	opts := dbcopy.Options{BatchSize: batchSize, Checkpoint: checkpoint, Restart: restart}
+	if cutoff != "" {
+		t, err := time.Parse(time.RFC3339, cutoff)
+		if err != nil {
+			return opts, fmt.Errorf("invalid -cutoff %q: %w", cutoff, err)
+		}
+		opts.Cutoff = t
+	}
+	if datasets != "" {
+		opts.Datasets = strings.Split(datasets, ",")
+	}
+	return opts, nil
+}
+
+// runCopy implements the copy and verify-copy commands and returns the exit code
+func runCopy(ctx context.Context, command string, source, target *connectionFlags, opts dbcopy.Options) int {
+	if *target.dbType == "" {
+		fmt.Fprintf(os.Stderr, "%s needs a target database: set -to-type and its connection flags\n", command)
+		return 2
+	}
+
+	// Interrupting a copy is safe: progress is saved after every batch
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Println("Connecting to source database...")
+	src, err := source.connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to source database: %v\n", err)
+		return 1
+	}
+	defer src.Disconnect(context.Background())
+
+	fmt.Println("Connecting to target database...")
+	dst, err := target.connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to target database: %v\n", err)
+		return 1
+	}
+	defer dst.Disconnect(context.Background())
+
+	if command == "copy" {
+		opts.Progress = func(dataset string, progress dbcopy.DatasetCheckpoint, elapsed time.Duration) {
+			fmt.Printf("  %-10s read %d, copied %d (%s)\n", dataset, progress.Offset, progress.Copied, elapsed.Round(time.Second))
+		}
+	}
+	copier, err := dbcopy.NewCopier(src, dst, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	if command == "copy" {
+		if migratable, ok := dst.(ports.MigratableAdapter); ok {
+			if err := migratable.GetMigrationManager().Run(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to migrate target schema: %v\n", err)
+				return 1
+			}
+		}
+
+		fmt.Printf("\nCopying %s to %s...\n", src.GetType(), dst.GetType())
+		cp, err := copier.Copy(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Copy failed: %v\n", err)
+			if opts.Checkpoint != "" {
+				fmt.Fprintf(os.Stderr, "Progress is saved in %s; run the same command again to resume\n", opts.Checkpoint)
+			}
+			return 1
+		}
+		fmt.Printf("✓ Copy complete (append-only records up to %s)\n", cp.Cutoff.Format(time.RFC3339))
+	}
+
+	fmt.Println("\nVerifying...")
+	report, err := copier.Verify(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verification failed: %v\n", err)
+		return 1
+	}
+	printReport(report)
+	if !report.OK() {
+		return 1
+	}
+	fmt.Println("\n✓ All operations completed successfully!")
+	return 0
+}
+
+func printReport(report *dbcopy.Report) {
+	fmt.Printf("Append-only records compared up to %s\n\n", report.Cutoff.Format(time.RFC3339))
+	for _, d := range report.Datasets {
+		mark := "✓"
+		if !d.OK {
+			mark = "✗"
+		}
+		fmt.Printf("%s %-10s source %d, target %d", mark, d.Dataset, d.SourceCount, d.TargetCount)
+		if d.Mismatched > 0 {
+			fmt.Printf(", %d missing or different", d.Mismatched)
+		}
+		fmt.Println()
+		if len(d.Keys) > 0 {
+			fmt.Printf("  e.g. %s\n", strings.Join(d.Keys, ", "))
+		}
+		if !d.OK && d.SourceDigest != "" {
+			fmt.Printf("  digest source %s\n", d.SourceDigest)
+			fmt.Printf("  digest target %s\n", d.TargetDigest)
+		}
+	}
+}
@@ -5,118 +5,247 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hsdfat8/eir/internal/adapters/dbcopy"
+	"github.com/hsdfat8/eir/internal/adapters/mongodb"
 	"github.com/hsdfat8/eir/internal/adapters/postgres"
+	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const usage = `Usage: migrate [flags] [command]
+
+Commands:
+  up          Apply all pending migrations (default)
+  down N      Revert the last N applied migrations
+  status      Show every migration and whether it is applied
+  goto V      Migrate up or down to version V (0 reverts everything)
+  copy        Copy every dataset to the -to-* database in resumable batches, then verify it
+  verify-copy Compare the source and -to-* databases: counts and per-record hashes
+
+Flags:
+`
+
 func main() {
+	source := registerConnectionFlags("", "", "postgres")
+	target := registerConnectionFlags("to-", "Copy target: ", "")
 	var (
-		databaseURL     = flag.String("database-url", "", "PostgreSQL connection string (overrides individual flags)")
-		host            = flag.String("host", "localhost", "Database host")
-		port            = flag.Int("port", 5432, "Database port")
-		user            = flag.String("user", "eir", "Database user")
-		password        = flag.String("password", "eir_password", "Database password")
-		dbname          = flag.String("dbname", "eir", "Database name")
-		sslmode         = flag.String("sslmode", "disable", "SSL mode (disable, require, verify-ca, verify-full)")
-		verify          = flag.Bool("verify", false, "Verify schema after migration")
-		createPartition = flag.Int("create-partition", 0, "Create audit_log partitions for a specific year (e.g., 2025)")
-		status          = flag.Bool("status", false, "Show migration status")
+		verify          = flag.Bool("verify", false, "Verify schema after migration (postgres only)")
+		createPartition = flag.Int("create-partition", 0, "Create audit_log partitions for a specific year, e.g. 2025 (postgres only)")
+		status          = flag.Bool("status", false, "Show migration status (same as the status command)")
+		batchSize       = flag.Int("batch-size", 1000, "Records per batch for copy")
+		checkpoint      = flag.String("checkpoint", "migrate-copy.json", "Copy progress file, used to resume an interrupted copy")
+		restart         = flag.Bool("restart", false, "Start the copy over, ignoring the checkpoint")
+		cutoff          = flag.String("cutoff", "", "Copy audits, history and snapshots up to this RFC3339 time (default now); use the time dual-write was enabled")
+		datasets        = flag.String("datasets", "", "Comma-separated datasets to copy or verify (default all: "+strings.Join(dbcopy.Datasets, ",")+")")
 	)
 
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	// Build connection string
-	var dsn string
-	if *databaseURL != "" {
-		dsn = *databaseURL
-	} else {
-		dsn = fmt.Sprintf(
-			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			*host, *port, *user, *password, *dbname, *sslmode,
-		)
+	command := "up"
+	if *status {
+		command = "status"
+	}
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
 	}
 
-	// Connect to database
-	fmt.Println("Connecting to database...")
-	db, err := sqlx.Connect("postgres", dsn)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
-		os.Exit(1)
+	ctx := context.Background()
+
+	if command == "copy" || command == "verify-copy" {
+		opts, err := copyOptions(*batchSize, *checkpoint, *restart, *cutoff, *datasets)
+		if err != nil {
+			fatalf("%v\n", err)
+		}
+		os.Exit(runCopy(ctx, command, source, target, opts))
 	}
-	defer db.Close()
 
-	// Configure connection pool
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	var (
+		manager  ports.MigrationManager
+		migrator *postgres.Migrator
+	)
 
-	// Test connection
-	ctx := context.Background()
-	if err := db.PingContext(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to ping database: %v\n", err)
-		os.Exit(1)
+	switch ports.DatabaseType(*source.dbType) {
+	case ports.DatabaseTypePostgreSQL:
+		db, err := connectPostgres(ctx, source.dsn())
+		if err != nil {
+			fatalf("Failed to connect to database: %v\n", err)
+		}
+		defer db.Close()
+
+		migrator = postgres.NewMigrator(db)
+		manager = migrator
+
+	case ports.DatabaseTypeMongoDB:
+		client, err := connectMongo(ctx, *source.mongoURI)
+		if err != nil {
+			fatalf("Failed to connect to database: %v\n", err)
+		}
+		defer client.Disconnect(ctx)
+
+		manager = mongodb.NewMigrator(client.Database(*source.mongoDatabase))
+
+	default:
+		fatalf("Unsupported database type: %s\n", *source.dbType)
 	}
 
 	fmt.Println("Successfully connected to database!")
 
-	// Create migrator
-	migrator := postgres.NewMigrator(db)
+	// Legacy partition flag keeps working alongside the versioned commands
+	if *createPartition > 0 {
+		if migrator == nil {
+			fatalf("-create-partition is only supported for postgres\n")
+		}
+		if err := migrator.CreatePartitionsForYear(ctx, *createPartition); err != nil {
+			fatalf("Failed to create partitions: %v\n", err)
+		}
+		fmt.Println("\n✓ All operations completed successfully!")
+		return
+	}
 
-	// Handle different commands
-	switch {
-	case *status:
-		if err := showMigrationStatus(ctx, migrator); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to get migration status: %v\n", err)
-			os.Exit(1)
+	switch command {
+	case "up":
+		if err := manager.Run(ctx); err != nil {
+			fatalf("Migration failed: %v\n", err)
 		}
 
-	case *createPartition > 0:
-		if err := migrator.CreatePartitionsForYear(ctx, *createPartition); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to create partitions: %v\n", err)
-			os.Exit(1)
+	case "down":
+		n, err := intArg(1)
+		if err != nil {
+			fatalf("Usage: migrate down N (%v)\n", err)
+		}
+		if err := manager.Down(ctx, int(n)); err != nil {
+			fatalf("Rollback failed: %v\n", err)
 		}
 
-	default:
-		// Run migration
-		if err := migrator.Migrate(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
-			os.Exit(1)
+	case "goto":
+		version, err := intArg(-1)
+		if err != nil {
+			fatalf("Usage: migrate goto V (%v)\n", err)
+		}
+		if err := manager.Goto(ctx, version); err != nil {
+			fatalf("Migration failed: %v\n", err)
 		}
 
-		// Verify schema if requested
-		if *verify {
-			if err := migrator.VerifySchema(ctx); err != nil {
-				fmt.Fprintf(os.Stderr, "Schema verification failed: %v\n", err)
-				os.Exit(1)
-			}
+	case "status":
+		if err := showMigrationStatus(ctx, manager); err != nil {
+			fatalf("Failed to get migration status: %v\n", err)
+		}
+		return
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Verify schema if requested
+	if *verify {
+		if migrator == nil {
+			fatalf("-verify is only supported for postgres\n")
+		}
+		if err := migrator.VerifySchema(ctx); err != nil {
+			fatalf("Schema verification failed: %v\n", err)
 		}
 	}
 
 	fmt.Println("\n✓ All operations completed successfully!")
 }
 
-func showMigrationStatus(ctx context.Context, migrator *postgres.Migrator) error {
+// connectPostgres opens and pings a PostgreSQL connection pool
+func connectPostgres(ctx context.Context, dsn string) (*sqlx.DB, error) {
+	fmt.Println("Connecting to database...")
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure connection pool
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, nil
+}
+
+// connectMongo opens and pings a MongoDB client
+func connectMongo(ctx context.Context, uri string) (*mongo.Client, error) {
+	fmt.Println("Connecting to database...")
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return client, nil
+}
+
+// intArg parses the command argument; a negative fallback makes it required
+func intArg(fallback int64) (int64, error) {
+	if flag.NArg() < 2 {
+		if fallback < 0 {
+			return 0, fmt.Errorf("missing argument")
+		}
+		return fallback, nil
+	}
+	value, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid argument %q", flag.Arg(1))
+	}
+	return value, nil
+}
+
+func showMigrationStatus(ctx context.Context, manager ports.MigrationManager) error {
 	fmt.Println("\nMigration Status:")
 	fmt.Println("================")
 
-	migrations, err := migrator.GetMigrationStatus(ctx)
+	migrations, err := manager.Status(ctx)
 	if err != nil {
 		return err
 	}
 
 	if len(migrations) == 0 {
-		fmt.Println("No migrations have been applied yet.")
+		fmt.Println("No migrations are defined.")
 		return nil
 	}
 
 	for _, m := range migrations {
-		fmt.Printf("\n✓ %s\n", m.MigrationName)
-		fmt.Printf("  Description: %s\n", m.Description)
-		fmt.Printf("  Applied at:  %s\n", m.AppliedAt.Format(time.RFC3339))
+		mark := " "
+		if m.Applied {
+			mark = "✓"
+		}
+		fmt.Printf("\n%s %04d %s\n", mark, m.Version, m.Description)
+		if m.Applied {
+			fmt.Printf("  Applied at:  %s\n", m.AppliedAt)
+			fmt.Printf("  Checksum:    %s\n", m.Checksum)
+		} else {
+			fmt.Println("  Pending")
+		}
+		if m.Dirty {
+			fmt.Println("  WARNING: migration file changed after it was applied")
+		}
 	}
 
 	return nil
 }
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
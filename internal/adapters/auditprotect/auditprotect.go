@@ -0,0 +1,228 @@
+// Package auditprotect keeps subscriber identifiers out of audit logs in
+// clear text. Each of UserName, SUPI and GPSI is stored as written, as a
+// keyed-hash pseudonym that still supports lookups, or encrypted with AES-GCM
+// so that authorized reads can recover it. Protected values name the key
+// they were made with, so keys can be rotated without rewriting old audits.
+package auditprotect
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+)
+
+// Mode is how an identifier is stored
+type Mode string
+
+const (
+	ModeClear     Mode = "clear"     // Stored as written
+	ModePseudonym Mode = "pseudonym" // Keyed hash, not recoverable
+	ModeEncrypted Mode = "encrypted" // AES-GCM, recoverable with the key
+)
+
+// Field names, bound into every pseudonym and ciphertext so that a value
+// protected for one field is never accepted for another
+const (
+	fieldUserName = "user_name"
+	fieldSUPI     = "supi"
+	fieldGPSI     = "gpsi"
+)
+
+// Prefixes of protected values, followed by "<key id>:<base64 payload>"
+const (
+	pseudonymPrefix = "pn:"
+	encryptedPrefix = "enc:"
+)
+
+// ErrUnknownKey is returned for a value protected with a key that is no longer configured
+var ErrUnknownKey = errors.New("audit protection key is not configured")
+
+// key holds the subkeys derived from one configured key
+type key struct {
+	id        string
+	pseudonym []byte // HMAC-SHA256 key
+	aead      cipher.AEAD
+}
+
+// Protector protects and reveals the identifiers of audit logs
+type Protector struct {
+	modes  map[string]Mode
+	active *key
+	keys   map[string]*key
+}
+
+// NewProtector creates a protector from the audit configuration
+func NewProtector(cfg config.AuditConfig) (*Protector, error) {
+	p := &Protector{
+		modes: map[string]Mode{
+			fieldUserName: Mode(cfg.UserName),
+			fieldSUPI:     Mode(cfg.SUPI),
+			fieldGPSI:     Mode(cfg.GPSI),
+		},
+		keys: make(map[string]*key, len(cfg.Keys)),
+	}
+	for field, mode := range p.modes {
+		switch mode {
+		case ModeClear, ModePseudonym, ModeEncrypted:
+		default:
+			return nil, fmt.Errorf("invalid protection mode %q for %s", mode, field)
+		}
+	}
+
+	for id, encoded := range cfg.Keys {
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes encoded as base64", id)
+		}
+		k, err := deriveKey(id, secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %q: %w", id, err)
+		}
+		p.keys[id] = k
+	}
+	if p.active = p.keys[cfg.ActiveKey]; p.active == nil {
+		return nil, fmt.Errorf("active key %q is not configured", cfg.ActiveKey)
+	}
+	return p, nil
+}
+
+// deriveKey splits a configured key into independent subkeys for
+// pseudonyms and encryption
+func deriveKey(id string, secret []byte) (*key, error) {
+	derive := func(purpose string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(purpose))
+		return mac.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("eir audit encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &key{id: id, pseudonym: derive("eir audit pseudonym"), aead: aead}, nil
+}
+
+// Protect returns a copy of audit with its identifiers protected. Values
+// already protected are kept as they are.
+func (p *Protector) Protect(audit *models.AuditLog) (*models.AuditLog, error) {
+	protected := *audit
+	var err error
+	if protected.UserName, err = p.protect(fieldUserName, audit.UserName); err != nil {
+		return nil, err
+	}
+	if protected.SUPI, err = p.protect(fieldSUPI, audit.SUPI); err != nil {
+		return nil, err
+	}
+	if protected.GPSI, err = p.protect(fieldGPSI, audit.GPSI); err != nil {
+		return nil, err
+	}
+
+	// An encrypted SUPI is found through the pseudonym of its clear value
+	if p.modes[fieldSUPI] == ModeEncrypted && audit.SUPI != nil && !isProtected(*audit.SUPI) {
+		index := p.pseudonym(p.active, fieldSUPI, *audit.SUPI)
+		protected.SUPIIndex = &index
+	}
+	return &protected, nil
+}
+
+// Reveal returns a copy of audit with its encrypted identifiers decrypted.
+// Pseudonyms cannot be reversed and are returned as they are.
+func (p *Protector) Reveal(audit *models.AuditLog) (*models.AuditLog, error) {
+	revealed := *audit
+	var err error
+	if revealed.UserName, err = p.reveal(fieldUserName, audit.UserName); err != nil {
+		return nil, err
+	}
+	if revealed.SUPI, err = p.reveal(fieldSUPI, audit.SUPI); err != nil {
+		return nil, err
+	}
+	if revealed.GPSI, err = p.reveal(fieldGPSI, audit.GPSI); err != nil {
+		return nil, err
+	}
+	return &revealed, nil
+}
+
+// SUPILookupValues returns every form supi may be stored in: in clear text
+// for audits written before protection was enabled, and its pseudonym under
+// each configured key
+func (p *Protector) SUPILookupValues(supi string) []string {
+	values := []string{supi}
+	for _, k := range p.keys {
+		values = append(values, p.pseudonym(k, fieldSUPI, supi))
+	}
+	return values
+}
+
+// protect applies the mode of field to value
+func (p *Protector) protect(field string, value *string) (*string, error) {
+	if value == nil || isProtected(*value) {
+		return value, nil
+	}
+	var protected string
+	switch p.modes[field] {
+	case ModePseudonym:
+		protected = p.pseudonym(p.active, field, *value)
+	case ModeEncrypted:
+		nonce := make([]byte, p.active.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", field, err)
+		}
+		sealed := p.active.aead.Seal(nonce, nonce, []byte(*value), []byte(field))
+		protected = encryptedPrefix + p.active.id + ":" + base64.RawURLEncoding.EncodeToString(sealed)
+	default:
+		return value, nil
+	}
+	return &protected, nil
+}
+
+// reveal decrypts value if it was encrypted
+func (p *Protector) reveal(field string, value *string) (*string, error) {
+	if value == nil || !strings.HasPrefix(*value, encryptedPrefix) {
+		return value, nil
+	}
+	id, payload, ok := strings.Cut(strings.TrimPrefix(*value, encryptedPrefix), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed encrypted %s", field)
+	}
+	k := p.keys[id]
+	if k == nil {
+		return nil, fmt.Errorf("failed to decrypt %s with key %q: %w", field, id, ErrUnknownKey)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted %s", field)
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	plain, err := k.aead.Open(nil, nonce, ciphertext, []byte(field))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+	revealed := string(plain)
+	return &revealed, nil
+}
+
+// pseudonym is the keyed hash of value for field under k
+func (p *Protector) pseudonym(k *key, field, value string) string {
+	mac := hmac.New(sha256.New, k.pseudonym)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return pseudonymPrefix + k.id + ":" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// isProtected reports whether value already is a pseudonym or ciphertext
+func isProtected(value string) bool {
+	return strings.HasPrefix(value, pseudonymPrefix) || strings.HasPrefix(value, encryptedPrefix)
+}
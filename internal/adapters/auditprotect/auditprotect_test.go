@@ -0,0 +1,111 @@
+package auditprotect
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func testConfig() config.AuditConfig {
+	return config.AuditConfig{
+		Protect:   true,
+		UserName:  "pseudonym",
+		SUPI:      "encrypted",
+		GPSI:      "clear",
+		ActiveKey: "k1",
+		Keys:      map[string]string{"k1": testKey('a')},
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestProtector_ProtectAndReveal(t *testing.T) {
+	protector, err := NewProtector(testConfig())
+	require.NoError(t, err)
+
+	audit := &models.AuditLog{
+		IMEI:     "490154203237518",
+		UserName: strPtr("001010123456789"),
+		SUPI:     strPtr("imsi-001010123456789"),
+		GPSI:     strPtr("msisdn-15551234567"),
+	}
+	protected, err := protector.Protect(audit)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(*protected.UserName, "pn:k1:"))
+	assert.True(t, strings.HasPrefix(*protected.SUPI, "enc:k1:"))
+	assert.Equal(t, "msisdn-15551234567", *protected.GPSI)
+	require.NotNil(t, protected.SUPIIndex)
+	assert.Contains(t, protector.SUPILookupValues("imsi-001010123456789"), *protected.SUPIIndex)
+	assert.Equal(t, "imsi-001010123456789", *audit.SUPI, "the caller's audit is not modified")
+
+	again, err := protector.Protect(protected)
+	require.NoError(t, err)
+	assert.Equal(t, *protected.SUPI, *again.SUPI, "protected values are not protected twice")
+
+	revealed, err := protector.Reveal(protected)
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-001010123456789", *revealed.SUPI)
+	assert.Equal(t, *protected.UserName, *revealed.UserName, "pseudonyms are not reversible")
+}
+
+func TestProtector_KeyRotation(t *testing.T) {
+	cfg := testConfig()
+	old, err := NewProtector(cfg)
+	require.NoError(t, err)
+	protected, err := old.Protect(&models.AuditLog{SUPI: strPtr("imsi-001010123456789")})
+	require.NoError(t, err)
+
+	cfg.Keys = map[string]string{"k1": testKey('a'), "k2": testKey('b')}
+	cfg.ActiveKey = "k2"
+	rotated, err := NewProtector(cfg)
+	require.NoError(t, err)
+
+	revealed, err := rotated.Reveal(protected)
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-001010123456789", *revealed.SUPI)
+	assert.Contains(t, rotated.SUPILookupValues("imsi-001010123456789"), *protected.SUPIIndex)
+
+	cfg.Keys = map[string]string{"k2": testKey('b')}
+	retired, err := NewProtector(cfg)
+	require.NoError(t, err)
+	_, err = retired.Reveal(protected)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestAuditRepository_LookupAndAccess(t *testing.T) {
+	ctx := context.Background()
+	protector, err := NewProtector(testConfig())
+	require.NoError(t, err)
+	base := memory.NewInMemoryAuditRepository()
+	repo := NewAuditRepository(base, protector)
+
+	// Written before protection was enabled
+	require.NoError(t, base.LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", SUPI: strPtr("imsi-001010123456789")}))
+	require.NoError(t, repo.LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", SUPI: strPtr("imsi-001010123456789")}))
+	require.NoError(t, repo.LogCheck(ctx, &models.AuditLog{IMEI: "356938035643809", SUPI: strPtr("imsi-001010000000000")}))
+
+	audits, err := repo.GetAuditsBySUPI(ctx, []string{"imsi-001010123456789"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, audits, 2)
+	assert.True(t, strings.HasPrefix(*audits[0].SUPI, "enc:"), "identifiers stay protected without access")
+
+	audits, err = repo.GetAuditsBySUPI(ports.WithIdentifierAccess(ctx), []string{"imsi-001010123456789"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, audits, 2)
+	for _, audit := range audits {
+		assert.Equal(t, "imsi-001010123456789", *audit.SUPI)
+	}
+}
@@ -0,0 +1,84 @@
+package auditprotect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// auditRepository protects the identifiers of the audits it writes and
+// reveals them on reads made with ports.WithIdentifierAccess
+type auditRepository struct {
+	ports.AuditRepository
+	protector *Protector
+}
+
+// NewAuditRepository creates an audit repository that protects identifiers
+// before they reach repo
+func NewAuditRepository(repo ports.AuditRepository, protector *Protector) ports.AuditRepository {
+	return &auditRepository{AuditRepository: repo, protector: protector}
+}
+
+// LogCheck records an equipment check with its identifiers protected
+func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	protected, err := r.protector.Protect(audit)
+	if err != nil {
+		return fmt.Errorf("failed to protect audit: %w", err)
+	}
+	if err := r.AuditRepository.LogCheck(ctx, protected); err != nil {
+		return err
+	}
+	audit.ID = protected.ID
+	audit.CheckTime = protected.CheckTime
+	return nil
+}
+
+// GetAuditsByIMEI retrieves audit logs for a specific IMEI
+func (r *auditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	audits, err := r.AuditRepository.GetAuditsByIMEI(ctx, imei, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.reveal(ctx, audits)
+}
+
+// GetAuditsByTimeRange retrieves audit logs within a time range
+func (r *auditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error) {
+	audits, err := r.AuditRepository.GetAuditsByTimeRange(ctx, startTime, endTime, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.reveal(ctx, audits)
+}
+
+// GetAuditsBySUPI retrieves the audit logs of the clear-text SUPIs in supis,
+// whichever form they were stored in
+func (r *auditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	var values []string
+	for _, supi := range supis {
+		values = append(values, r.protector.SUPILookupValues(supi)...)
+	}
+	audits, err := r.AuditRepository.GetAuditsBySUPI(ctx, values, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.reveal(ctx, audits)
+}
+
+// reveal decrypts the identifiers of audits when ctx grants access to them
+func (r *auditRepository) reveal(ctx context.Context, audits []*models.AuditLog) ([]*models.AuditLog, error) {
+	if !ports.HasIdentifierAccess(ctx) {
+		return audits, nil
+	}
+	revealed := make([]*models.AuditLog, 0, len(audits))
+	for _, audit := range audits {
+		plain, err := r.protector.Reveal(audit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reveal audit %d: %w", audit.ID, err)
+		}
+		revealed = append(revealed, plain)
+	}
+	return revealed, nil
+}
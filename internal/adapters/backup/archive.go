@@ -0,0 +1,284 @@
+// Package backup writes and reads portable backups of the list data. An
+// archive is a tar file holding a manifest followed by one gzip-compressed
+// NDJSON member per dataset. The manifest records the size, SHA-256 and
+// record count of every member, so an archive can be verified without a
+// database and restored into any backend.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// FormatVersion is the archive layout written by this package. Archives with
+// a higher version are rejected.
+const FormatVersion = 1
+
+// manifestName is the first member of every archive
+const manifestName = "manifest.json"
+
+// ErrInvalidArchive is returned when an archive is malformed or fails verification
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// Dataset names one kind of record in an archive
+type Dataset string
+
+const (
+	DatasetEquipment Dataset = "equipment"
+	DatasetImeiInfo  Dataset = "imei_info"
+	DatasetTacInfo   Dataset = "tac_info"
+	DatasetHistory   Dataset = "history"
+)
+
+// datasets lists the archive members in the order they are written and restored
+var datasets = []Dataset{DatasetEquipment, DatasetImeiInfo, DatasetTacInfo, DatasetHistory}
+
+// memberName returns the archive member holding dataset
+func memberName(dataset Dataset) string {
+	return string(dataset) + ".ndjson.gz"
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Source    string         `json:"source"` // Database type the backup was taken from
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one dataset member of an archive
+type ManifestFile struct {
+	Dataset Dataset `json:"dataset"`
+	Name    string  `json:"name"`
+	Records int64   `json:"records"`
+	Size    int64   `json:"size"`   // Compressed size in bytes
+	SHA256  string  `json:"sha256"` // Of the compressed member
+}
+
+// file returns the manifest entry for name
+func (m *Manifest) file(name string) (ManifestFile, bool) {
+	for _, f := range m.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// spool compresses one dataset to a temporary file, since a tar header needs
+// the member size before its content
+type spool struct {
+	file    *os.File
+	hash    hash.Hash
+	counter *countingWriter
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	records int64
+}
+
+func newSpool() (*spool, error) {
+	file, err := os.CreateTemp("", "eir-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	s := &spool{file: file, hash: sha256.New()}
+	s.counter = &countingWriter{w: io.MultiWriter(file, s.hash)}
+	s.gzip = gzip.NewWriter(s.counter)
+	s.encoder = json.NewEncoder(s.gzip)
+	return s, nil
+}
+
+// write appends one record as an NDJSON line
+func (s *spool) write(record interface{}) error {
+	if err := s.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	s.records++
+	return nil
+}
+
+// finish completes the compressed stream and describes it
+func (s *spool) finish(dataset Dataset) (ManifestFile, error) {
+	if err := s.gzip.Close(); err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to compress %s: %w", dataset, err)
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to rewind %s: %w", dataset, err)
+	}
+	return ManifestFile{
+		Dataset: dataset,
+		Name:    memberName(dataset),
+		Records: s.records,
+		Size:    s.counter.n,
+		SHA256:  hex.EncodeToString(s.hash.Sum(nil)),
+	}, nil
+}
+
+func (s *spool) remove() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// writeArchive writes the manifest followed by the spooled members
+func writeArchive(w io.Writer, manifest *Manifest, spools []*spool) error {
+	tw := tar.NewWriter(w)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeMember(tw, manifestName, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	for i, f := range manifest.Files {
+		if err := writeMember(tw, f.Name, f.Size, manifest.CreatedAt, spools[i].file); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+func writeMember(tw *tar.Writer, name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s header: %w", name, err)
+	}
+	if _, err := io.Copy(tw, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// archiveReader walks an archive, checking every member against the manifest
+type archiveReader struct {
+	tar      *tar.Reader
+	manifest *Manifest
+	seen     map[string]bool
+}
+
+// openArchive reads and validates the manifest at the start of r
+func openArchive(r io.Reader) (*archiveReader, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read manifest: %v", ErrInvalidArchive, err)
+	}
+	if header.Name != manifestName {
+		return nil, fmt.Errorf("%w: first member is %q, expected %s", ErrInvalidArchive, header.Name, manifestName)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode manifest: %v", ErrInvalidArchive, err)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+	for _, dataset := range datasets {
+		if _, ok := manifest.file(memberName(dataset)); !ok {
+			return nil, fmt.Errorf("%w: manifest has no %s entry", ErrInvalidArchive, dataset)
+		}
+	}
+
+	return &archiveReader{tar: tr, manifest: &manifest, seen: make(map[string]bool)}, nil
+}
+
+// next returns the next dataset member, or io.EOF after the last one. Every
+// record line of the member is passed to fn; the member's checksum, size and
+// record count are checked once it has been read to the end.
+func (a *archiveReader) next(fn func(dataset Dataset, line []byte) error) (ManifestFile, error) {
+	header, err := a.tar.Next()
+	if err == io.EOF {
+		for _, f := range a.manifest.Files {
+			if !a.seen[f.Name] {
+				return ManifestFile{}, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, f.Name)
+			}
+		}
+		return ManifestFile{}, io.EOF
+	}
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	f, ok := a.manifest.file(header.Name)
+	if !ok || a.seen[header.Name] {
+		return ManifestFile{}, fmt.Errorf("%w: unexpected member %q", ErrInvalidArchive, header.Name)
+	}
+	a.seen[header.Name] = true
+
+	digest := sha256.New()
+	counter := &countingReader{r: io.TeeReader(a.tar, digest)}
+	gz, err := gzip.NewReader(counter)
+	if err != nil {
+		return f, fmt.Errorf("%w: %s is not gzip compressed: %v", ErrInvalidArchive, f.Name, err)
+	}
+
+	var records int64
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		records++
+		if err := fn(f.Dataset, line); err != nil {
+			return f, fmt.Errorf("%s record %d: %w", f.Name, records, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return f, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidArchive, f.Name, err)
+	}
+	// Drain any trailing bytes so the checksum covers the whole member
+	if _, err := io.Copy(io.Discard, counter); err != nil {
+		return f, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidArchive, f.Name, err)
+	}
+
+	if counter.n != f.Size {
+		return f, fmt.Errorf("%w: %s is %d bytes, manifest says %d", ErrInvalidArchive, f.Name, counter.n, f.Size)
+	}
+	if sum := hex.EncodeToString(digest.Sum(nil)); sum != f.SHA256 {
+		return f, fmt.Errorf("%w: %s checksum mismatch", ErrInvalidArchive, f.Name)
+	}
+	if records != f.Records {
+		return f, fmt.Errorf("%w: %s holds %d records, manifest says %d", ErrInvalidArchive, f.Name, records, f.Records)
+	}
+	return f, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/export"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// pageSize is how many equipment or history records are read at a time
+const pageSize = 1000
+
+// Store is the list data a backup is taken from or restored into. Every
+// backend provides it: a DatabaseAdapter through its IMEI and history
+// repositories, the memory backend through its own.
+type Store struct {
+	IMEIs   ports.IMEIRepository
+	History ports.HistoryRepository
+}
+
+// imeiInfoRecord is the archived form of an IMEI_INFO entry. Archives
+// written before the lists stored status names hold legacy colors, which are
+// restored as statuses.
+type imeiInfoRecord struct {
+	StartIMEI string                 `json:"start_imei"`
+	EndIMEI   []string               `json:"end_imei"`
+	Color     models.EquipmentStatus `json:"color"`
+}
+
+// tacInfoRecord is the archived form of a TAC_INFO range
+type tacInfoRecord struct {
+	KeyTac        string                 `json:"key_tac"`
+	StartRangeTac string                 `json:"start_range_tac"`
+	EndRangeTac   string                 `json:"end_range_tac"`
+	Color         models.EquipmentStatus `json:"color"`
+	PrevLink      *string                `json:"prev_link,omitempty"`
+}
+
+// Backup writes an archive of every dataset in store to w. source names the
+// backend in the manifest.
+func Backup(ctx context.Context, w io.Writer, store Store, source string) (*Manifest, error) {
+	manifest := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC(), Source: source}
+
+	var spools []*spool
+	defer func() {
+		for _, s := range spools {
+			s.remove()
+		}
+	}()
+
+	for _, dataset := range datasets {
+		s, err := newSpool()
+		if err != nil {
+			return nil, err
+		}
+		spools = append(spools, s)
+
+		if err := dump(ctx, store, dataset, s); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", dataset, err)
+		}
+		f, err := s.finish(dataset)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	if err := writeArchive(w, manifest, spools); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// dump writes every record of dataset to s
+func dump(ctx context.Context, store Store, dataset Dataset, s *spool) error {
+	switch dataset {
+	case DatasetEquipment:
+		for offset := 0; ; offset += pageSize {
+			page, err := store.IMEIs.ListWithDeleted(ctx, offset, pageSize)
+			if err != nil {
+				return err
+			}
+			for _, equipment := range page {
+				if err := s.write(equipment); err != nil {
+					return err
+				}
+			}
+			if len(page) < pageSize {
+				return nil
+			}
+		}
+
+	case DatasetImeiInfo:
+		for _, info := range store.IMEIs.ListAllImeiInfo(ctx) {
+			record := imeiInfoRecord{StartIMEI: info.StartIMEI, EndIMEI: info.EndIMEI, Color: info.Color}
+			if err := s.write(record); err != nil {
+				return err
+			}
+		}
+
+	case DatasetTacInfo:
+		for _, info := range store.IMEIs.ListAllTacInfo(ctx) {
+			record := tacInfoRecord{
+				KeyTac:        info.KeyTac,
+				StartRangeTac: info.StartRangeTac,
+				EndRangeTac:   info.EndRangeTac,
+				Color:         info.Color,
+				PrevLink:      info.PrevLink,
+			}
+			if err := s.write(record); err != nil {
+				return err
+			}
+		}
+
+	case DatasetHistory:
+		if store.History == nil {
+			return nil
+		}
+		start, end := export.TimeRange{}.Bounds()
+		for offset := 0; ; offset += pageSize {
+			page, err := store.History.GetHistoryByTimeRange(ctx, start, end, offset, pageSize)
+			if err != nil {
+				return err
+			}
+			for _, entry := range page {
+				if err := s.write(entry); err != nil {
+					return err
+				}
+			}
+			if len(page) < pageSize {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Verify checks the manifest, checksums, sizes and record counts of the
+// archive in r without touching a database
+func Verify(r io.Reader) (*Manifest, error) {
+	archive, err := openArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err := archive.next(func(_ Dataset, line []byte) error {
+			if !json.Valid(line) {
+				return fmt.Errorf("%w: record is not valid JSON", ErrInvalidArchive)
+			}
+			return nil
+		})
+		if err == io.EOF {
+			return archive.manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Restore loads the archive in r into store. Equipment, IMEI_INFO and TAC_INFO
+// entries replace any existing entry with the same key; history entries are
+// appended. Members are checked as they are read, so verify an archive before
+// restoring it to avoid applying part of a damaged one.
+func Restore(ctx context.Context, r io.Reader, store Store) (*Manifest, error) {
+	archive, err := openArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err := archive.next(func(dataset Dataset, line []byte) error {
+			return restoreRecord(ctx, store, dataset, line)
+		})
+		if err == io.EOF {
+			return archive.manifest, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore: %w", err)
+		}
+	}
+}
+
+// restoreRecord decodes line as a record of dataset and saves it in store
+func restoreRecord(ctx context.Context, store Store, dataset Dataset, line []byte) error {
+	switch dataset {
+	case DatasetEquipment:
+		var equipment models.Equipment
+		if err := json.Unmarshal(line, &equipment); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		// The archived version belongs to the source; overwrite whatever is stored
+		equipment.Version = 0
+		if existing, err := store.IMEIs.GetByIMEI(ctx, equipment.IMEI); err == nil && existing != nil {
+			equipment.ID = existing.ID
+			return store.IMEIs.Update(ctx, &equipment)
+		}
+		equipment.ID = 0
+		return store.IMEIs.Create(ctx, &equipment)
+
+	case DatasetImeiInfo:
+		var record imeiInfoRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		return store.IMEIs.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: record.StartIMEI, EndIMEI: record.EndIMEI, Color: models.NormalizeStatus(models.ImeiList, record.Color)})
+
+	case DatasetTacInfo:
+		var record tacInfoRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		return store.IMEIs.SaveTacInfo(ctx, &ports.TacInfo{
+			KeyTac:        record.KeyTac,
+			StartRangeTac: record.StartRangeTac,
+			EndRangeTac:   record.EndRangeTac,
+			Color:         models.NormalizeStatus(models.TacList, record.Color),
+			PrevLink:      record.PrevLink,
+		})
+
+	case DatasetHistory:
+		if store.History == nil {
+			return nil
+		}
+		var entry models.EquipmentHistory
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		entry.ID = 0
+		return store.History.RecordChange(ctx, &entry)
+	}
+	return nil
+}
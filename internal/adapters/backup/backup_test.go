@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func memoryStore(t *testing.T) Store {
+	ctx := context.Background()
+	store := Store{IMEIs: memory.NewInMemoryIMEIRepository(), History: memory.NewInMemoryHistoryRepository()}
+
+	reason := "stolen"
+	require.NoError(t, store.IMEIs.Create(ctx, &models.Equipment{IMEI: "490154203237518", Status: models.EquipmentStatusBlacklisted, Reason: &reason, AddedBy: "ops"}))
+	require.NoError(t, store.IMEIs.Create(ctx, &models.Equipment{IMEI: "356938035643809", Status: models.EquipmentStatusWhitelisted, AddedBy: "ops"}))
+	require.NoError(t, store.IMEIs.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "49015420", EndIMEI: []string{"49015429"}, Color: models.EquipmentStatusBlacklisted}))
+	require.NoError(t, store.IMEIs.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "35693803-35693809", StartRangeTac: "35693803", EndRangeTac: "35693809", Color: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, store.History.RecordChange(ctx, &models.EquipmentHistory{
+		IMEI: "490154203237518", ChangeType: models.ChangeTypeCreate, ChangedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ChangedBy: "ops", NewStatus: models.EquipmentStatusBlacklisted, ChangeDetails: models.ChangeDetails{"list": "equipment"},
+	}))
+	return store
+}
+
+func TestBackupRestore_MemoryToEmbedded(t *testing.T) {
+	ctx := context.Background()
+
+	var archive bytes.Buffer
+	manifest, err := Backup(ctx, &archive, memoryStore(t), "memory")
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 4)
+	assert.Equal(t, int64(2), manifest.Files[0].Records)
+
+	verified, err := Verify(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files, verified.Files)
+
+	adapter := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	require.NoError(t, adapter.Connect(ctx))
+	defer adapter.Disconnect(ctx)
+	target := Store{IMEIs: adapter.GetIMEIRepository(), History: adapter.GetHistoryRepository()}
+
+	// Restoring twice replaces list entries rather than duplicating them
+	for i := 0; i < 2; i++ {
+		_, err = Restore(ctx, bytes.NewReader(archive.Bytes()), target)
+		require.NoError(t, err)
+	}
+
+	equipment, err := target.IMEIs.GetByIMEI(ctx, "490154203237518")
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, equipment.Status)
+	require.NotNil(t, equipment.Reason)
+	assert.Equal(t, "stolen", *equipment.Reason)
+
+	all, err := target.IMEIs.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Len(t, target.IMEIs.ListAllImeiInfo(ctx), 1)
+	tac, ok := target.IMEIs.LookupTacInfo(ctx, "35693803-35693809")
+	require.True(t, ok)
+	assert.Equal(t, models.EquipmentStatusWhitelisted, tac.Color)
+
+	history, err := target.History.GetHistoryByIMEI(ctx, "490154203237518", 0, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), history[0].ChangedAt.UTC())
+	assert.Equal(t, "equipment", history[0].ChangeDetails["list"])
+}
+
+func TestVerify_DetectsDamage(t *testing.T) {
+	var archive bytes.Buffer
+	_, err := Backup(context.Background(), &archive, memoryStore(t), "memory")
+	require.NoError(t, err)
+
+	// Flip a byte inside the equipment member
+	damaged := append([]byte(nil), archive.Bytes()...)
+	offset := memberOffset(t, damaged, memberName(DatasetEquipment))
+	damaged[offset+10] ^= 0xff
+	_, err = Verify(bytes.NewReader(damaged))
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	// Drop the history member
+	var truncated bytes.Buffer
+	tw := tar.NewWriter(&truncated)
+	tr := tar.NewReader(bytes.NewReader(archive.Bytes()))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if header.Name == memberName(DatasetHistory) {
+			continue
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err = io.Copy(tw, tr)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	_, err = Verify(&truncated)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	_, err = Verify(bytes.NewReader([]byte("not an archive")))
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+// memberOffset returns where the content of member starts inside archive
+func memberOffset(t *testing.T, archive []byte, member string) int {
+	reader := bytes.NewReader(archive)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		require.NoError(t, err)
+		if header.Name == member {
+			return len(archive) - reader.Len()
+		}
+	}
+}
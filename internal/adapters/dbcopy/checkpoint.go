@@ -0,0 +1,81 @@
+package dbcopy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Checkpoint records how far a copy has got, so an interrupted copy resumes
+// where it stopped instead of starting over
+type Checkpoint struct {
+	Source   string                        `json:"source"`
+	Target   string                        `json:"target"`
+	Cutoff   time.Time                     `json:"cutoff"` // Append-only records after this are left to dual-write
+	Started  time.Time                     `json:"started"`
+	Datasets map[string]*DatasetCheckpoint `json:"datasets"`
+}
+
+// DatasetCheckpoint is the progress of one dataset
+type DatasetCheckpoint struct {
+	Offset int   `json:"offset"` // Source records (or IMEIs, for snapshots) already read
+	Copied int64 `json:"copied"` // Records written to the target
+	Done   bool  `json:"done"`
+}
+
+func newCheckpoint(source, target string, cutoff time.Time) *Checkpoint {
+	cp := &Checkpoint{
+		Source:   source,
+		Target:   target,
+		Cutoff:   cutoff.UTC(),
+		Started:  time.Now().UTC(),
+		Datasets: make(map[string]*DatasetCheckpoint),
+	}
+	for _, dataset := range Datasets {
+		cp.Datasets[dataset] = &DatasetCheckpoint{}
+	}
+	return cp
+}
+
+// loadCheckpoint reads the checkpoint at path, returning nil if there is none
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", path, err)
+	}
+	for _, dataset := range Datasets {
+		if cp.Datasets[dataset] == nil {
+			cp.Datasets[dataset] = &DatasetCheckpoint{}
+		}
+	}
+	return &cp, nil
+}
+
+// save writes the checkpoint atomically by renaming a temporary file over path
+func (cp *Checkpoint) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// Package dbcopy copies the data of one DatabaseAdapter into another in
+// resumable batches and verifies the result, for moving a site between
+// storage backends. Equipment, IMEI_INFO and TAC_INFO are upserted, so they
+// can be copied again safely; history, audits and snapshots are append-only
+// and are copied up to a cutoff, after which the service's dual-write phase
+// keeps the target current.
+package dbcopy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// Datasets copied, in order
+const (
+	DatasetEquipment = "equipment"
+	DatasetImeiInfo  = "imei_info"
+	DatasetTacInfo   = "tac_info"
+	DatasetHistory   = "history"
+	DatasetAudits    = "audits"
+	DatasetSnapshots = "snapshots"
+)
+
+// Datasets lists every dataset in the order it is copied
+var Datasets = []string{DatasetEquipment, DatasetImeiInfo, DatasetTacInfo, DatasetHistory, DatasetAudits, DatasetSnapshots}
+
+// auditTimeLayout formats the string bounds of AuditRepository.GetAuditsByTimeRange
+const auditTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// earliest is the lower bound of the append-only datasets
+var earliest = time.Unix(0, math.MinInt64).UTC()
+
+// Options controls a copy
+type Options struct {
+	BatchSize  int
+	Checkpoint string    // File progress is saved to after every batch; empty disables resuming
+	Restart    bool      // Ignore an existing checkpoint
+	Cutoff     time.Time // Copy append-only records up to this time; zero means now
+	Datasets   []string  // Subset to copy or verify; empty means all
+
+	// Progress is called after every batch
+	Progress func(dataset string, progress DatasetCheckpoint, elapsed time.Duration)
+}
+
+// Copier copies and verifies data between two connected adapters
+type Copier struct {
+	source ports.DatabaseAdapter
+	target ports.DatabaseAdapter
+	opts   Options
+}
+
+// NewCopier creates a copier from source to target
+func NewCopier(source, target ports.DatabaseAdapter, opts Options) (*Copier, error) {
+	if opts.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+	for _, dataset := range opts.Datasets {
+		if !knownDataset(dataset) {
+			return nil, fmt.Errorf("unknown dataset %q", dataset)
+		}
+	}
+	return &Copier{source: source, target: target, opts: opts}, nil
+}
+
+// Copy copies every selected dataset, resuming from the checkpoint when one
+// exists, and returns the final checkpoint
+func (c *Copier) Copy(ctx context.Context) (*Checkpoint, error) {
+	cp, err := c.checkpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dataset := range c.datasets() {
+		progress := cp.Datasets[dataset]
+		if progress.Done {
+			continue
+		}
+		if err := c.copyDataset(ctx, cp, dataset, progress); err != nil {
+			return cp, fmt.Errorf("failed to copy %s: %w", dataset, err)
+		}
+	}
+	return cp, nil
+}
+
+// checkpoint loads the saved checkpoint or starts a new one
+func (c *Copier) checkpoint() (*Checkpoint, error) {
+	source, target := string(c.source.GetType()), string(c.target.GetType())
+	if c.opts.Checkpoint != "" && !c.opts.Restart {
+		cp, err := loadCheckpoint(c.opts.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if cp != nil {
+			if cp.Source != source || cp.Target != target {
+				return nil, fmt.Errorf("checkpoint %s is for a copy from %s to %s", c.opts.Checkpoint, cp.Source, cp.Target)
+			}
+			return cp, nil
+		}
+	}
+
+	cutoff := c.opts.Cutoff
+	if cutoff.IsZero() {
+		cutoff = time.Now()
+	}
+	cp := newCheckpoint(source, target, cutoff)
+	return cp, cp.save(c.opts.Checkpoint)
+}
+
+// copyDataset copies dataset batch by batch from the checkpointed offset
+func (c *Copier) copyDataset(ctx context.Context, cp *Checkpoint, dataset string, progress *DatasetCheckpoint) error {
+	started := time.Now()
+	for !progress.Done {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		read, copied, err := c.copyBatch(ctx, cp.Cutoff, dataset, progress.Offset)
+		if err != nil {
+			return err
+		}
+		progress.Offset += read
+		progress.Copied += copied
+		progress.Done = read < c.opts.BatchSize
+		if err := cp.save(c.opts.Checkpoint); err != nil {
+			return err
+		}
+		if c.opts.Progress != nil {
+			c.opts.Progress(dataset, *progress, time.Since(started))
+		}
+	}
+	return nil
+}
+
+// copyBatch copies the batch of dataset starting at offset and reports how
+// many source records were read and how many were written
+func (c *Copier) copyBatch(ctx context.Context, cutoff time.Time, dataset string, offset int) (int, int64, error) {
+	limit := c.opts.BatchSize
+	src, dst := c.source, c.target
+
+	switch dataset {
+	case DatasetEquipment:
+		page, err := src.GetIMEIRepository().ListWithDeleted(ctx, offset, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, equipment := range page {
+			if err := upsertEquipment(ctx, dst.GetIMEIRepository(), equipment); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(page), int64(len(page)), nil
+
+	case DatasetImeiInfo:
+		all := src.GetIMEIRepository().ListAllImeiInfo(ctx)
+		page := all[min(offset, len(all)):min(offset+limit, len(all))]
+		for _, info := range page {
+			copied := *info
+			copied.Version = 0
+			if err := dst.GetIMEIRepository().SaveImeiInfo(ctx, &copied); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(page), int64(len(page)), nil
+
+	case DatasetTacInfo:
+		all := src.GetIMEIRepository().ListAllTacInfo(ctx)
+		page := all[min(offset, len(all)):min(offset+limit, len(all))]
+		for _, info := range page {
+			copied := *info
+			copied.Version = 0
+			if err := dst.GetIMEIRepository().SaveTacInfo(ctx, &copied); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(page), int64(len(page)), nil
+
+	case DatasetHistory:
+		page, err := src.GetHistoryRepository().GetHistoryByTimeRange(ctx, earliest, cutoff, offset, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entry := range page {
+			copied := *entry
+			copied.ID = 0
+			if err := dst.GetHistoryRepository().RecordChange(ctx, &copied); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(page), int64(len(page)), nil
+
+	case DatasetAudits:
+		page, err := src.GetAuditRepository().GetAuditsByTimeRange(ctx,
+			earliest.Format(auditTimeLayout), cutoff.UTC().Format(auditTimeLayout), offset, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, audit := range page {
+			copied := *audit
+			copied.ID = 0
+			if err := dst.GetAuditRepository().LogCheck(ctx, &copied); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(page), int64(len(page)), nil
+
+	case DatasetSnapshots:
+		// Snapshots are only listed per IMEI, so the batch is a page of equipment
+		page, err := src.GetIMEIRepository().ListWithDeleted(ctx, offset, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		var copied int64
+		for _, equipment := range page {
+			snapshots, err := snapshotsOf(ctx, src.GetSnapshotRepository(), equipment.IMEI, cutoff, limit)
+			if err != nil {
+				return 0, 0, err
+			}
+			if len(snapshots) == 0 {
+				continue
+			}
+			// Snapshots reference the equipment by the target's own ID
+			targetEquipment, err := dst.GetIMEIRepository().GetByIMEI(ctx, equipment.IMEI)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to find copied equipment %s: %w", equipment.IMEI, err)
+			}
+			for _, snapshot := range snapshots {
+				s := *snapshot
+				s.ID = 0
+				s.EquipmentID = targetEquipment.ID
+				if err := dst.GetSnapshotRepository().CreateSnapshot(ctx, &s); err != nil {
+					return 0, 0, err
+				}
+				copied++
+			}
+		}
+		return len(page), copied, nil
+	}
+	return 0, 0, fmt.Errorf("unknown dataset %q", dataset)
+}
+
+// upsertEquipment creates equipment in repo or replaces the record with the
+// same IMEI, whatever its version
+func upsertEquipment(ctx context.Context, repo ports.IMEIRepository, equipment *models.Equipment) error {
+	copied := *equipment
+	copied.Version = 0
+	if existing, err := repo.GetByIMEI(ctx, copied.IMEI); err == nil && existing != nil {
+		copied.ID = existing.ID
+		return repo.Update(ctx, &copied)
+	}
+	copied.ID = 0
+	return repo.Create(ctx, &copied)
+}
+
+// snapshotsOf returns every snapshot of imei taken up to cutoff
+func snapshotsOf(ctx context.Context, repo ports.SnapshotRepository, imei string, cutoff time.Time, pageSize int) ([]*models.EquipmentSnapshot, error) {
+	var all []*models.EquipmentSnapshot
+	for offset := 0; ; offset += pageSize {
+		page, err := repo.GetSnapshotsByIMEI(ctx, imei, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range page {
+			if !snapshot.SnapshotTime.After(cutoff) {
+				all = append(all, snapshot)
+			}
+		}
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+func (c *Copier) datasets() []string {
+	if len(c.opts.Datasets) == 0 {
+		return Datasets
+	}
+	// Keep the canonical order whatever order they were given in
+	var selected []string
+	for _, dataset := range Datasets {
+		for _, wanted := range c.opts.Datasets {
+			if dataset == wanted {
+				selected = append(selected, dataset)
+			}
+		}
+	}
+	return selected
+}
+
+func knownDataset(name string) bool {
+	for _, dataset := range Datasets {
+		if dataset == name {
+			return true
+		}
+	}
+	return false
+}
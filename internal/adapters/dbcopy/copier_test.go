@@ -0,0 +1,135 @@
+package dbcopy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func connectEmbedded(t *testing.T) ports.DatabaseAdapter {
+	ctx := context.Background()
+	adapter := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	require.NoError(t, adapter.Connect(ctx))
+	t.Cleanup(func() { adapter.Disconnect(ctx) })
+	return adapter
+}
+
+func seed(t *testing.T, adapter ports.DatabaseAdapter) {
+	ctx := context.Background()
+	imeis := adapter.GetIMEIRepository()
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		imei := fmt.Sprintf("49015420323751%d", i)
+		require.NoError(t, imeis.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusBlacklisted, AddedBy: "ops"}))
+		equipment, err := imeis.GetByIMEI(ctx, imei)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{
+			IMEI: imei, ChangeType: models.ChangeTypeCreate, ChangedAt: at.Add(time.Duration(i) * time.Hour),
+			ChangedBy: "ops", NewStatus: models.EquipmentStatusBlacklisted,
+		}))
+		require.NoError(t, adapter.GetAuditRepository().LogCheck(ctx, &models.AuditLog{
+			IMEI: imei, Status: models.EquipmentStatusBlacklisted, CheckTime: at.Add(time.Duration(i) * time.Minute), RequestSource: "HTTP_5G",
+		}))
+		require.NoError(t, adapter.GetSnapshotRepository().CreateSnapshot(ctx, &models.EquipmentSnapshot{
+			EquipmentID: equipment.ID, IMEI: imei, SnapshotTime: at, Status: models.EquipmentStatusBlacklisted,
+			CreatedBy: "ops", SnapshotType: models.SnapshotTypeManual,
+		}))
+	}
+	require.NoError(t, imeis.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "49015420", EndIMEI: []string{"49015429"}, Color: models.EquipmentStatusBlacklisted}))
+	require.NoError(t, imeis.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "35693803-35693809", StartRangeTac: "35693803", EndRangeTac: "35693809", Color: models.EquipmentStatusWhitelisted}))
+}
+
+func TestCopyAndVerify(t *testing.T) {
+	ctx := context.Background()
+	source, target := connectEmbedded(t), connectEmbedded(t)
+	seed(t, source)
+
+	copier, err := NewCopier(source, target, Options{BatchSize: 2, Cutoff: time.Now()})
+	require.NoError(t, err)
+	cp, err := copier.Copy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), cp.Datasets[DatasetEquipment].Copied)
+	assert.Equal(t, int64(5), cp.Datasets[DatasetSnapshots].Copied)
+
+	report, err := copier.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%+v", report.Datasets)
+
+	// Snapshots point at the target's own equipment IDs
+	equipment, err := target.GetIMEIRepository().GetByIMEI(ctx, "490154203237510")
+	require.NoError(t, err)
+	snapshots, err := target.GetSnapshotRepository().GetSnapshotsByIMEI(ctx, equipment.IMEI, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, equipment.ID, snapshots[0].EquipmentID)
+
+	// A changed record and an extra audit are both reported
+	reason := "changed"
+	equipment.Reason = &reason
+	require.NoError(t, target.GetIMEIRepository().Update(ctx, equipment))
+	require.NoError(t, target.GetAuditRepository().LogCheck(ctx, &models.AuditLog{
+		IMEI: equipment.IMEI, Status: models.EquipmentStatusBlacklisted, CheckTime: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), RequestSource: "HTTP_5G",
+	}))
+
+	report, err = copier.Verify(ctx)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	for _, d := range report.Datasets {
+		switch d.Dataset {
+		case DatasetEquipment:
+			assert.Equal(t, []string{"490154203237510"}, d.Keys)
+		case DatasetAudits:
+			assert.False(t, d.OK)
+			assert.Equal(t, d.SourceCount+1, d.TargetCount)
+		default:
+			assert.True(t, d.OK, d.Dataset)
+		}
+	}
+}
+
+func TestCopyResumesFromCheckpoint(t *testing.T) {
+	source, target := connectEmbedded(t), connectEmbedded(t)
+	seed(t, source)
+	opts := Options{BatchSize: 2, Checkpoint: filepath.Join(t.TempDir(), "copy.json")}
+
+	// Interrupt the copy part way through the history
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.Progress = func(dataset string, _ DatasetCheckpoint, _ time.Duration) {
+		if dataset == DatasetHistory {
+			cancel()
+		}
+	}
+	copier, err := NewCopier(source, target, opts)
+	require.NoError(t, err)
+	_, err = copier.Copy(ctx)
+	require.True(t, errors.Is(err, context.Canceled), "got %v", err)
+
+	saved, err := loadCheckpoint(opts.Checkpoint)
+	require.NoError(t, err)
+	assert.True(t, saved.Datasets[DatasetTacInfo].Done)
+	assert.Equal(t, 2, saved.Datasets[DatasetHistory].Offset)
+
+	opts.Progress = nil
+	copier, err = NewCopier(source, target, opts)
+	require.NoError(t, err)
+	cp, err := copier.Copy(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, saved.Cutoff, cp.Cutoff)
+	assert.Equal(t, int64(5), cp.Datasets[DatasetHistory].Copied)
+
+	// History was not copied twice
+	report, err := copier.Verify(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%+v", report.Datasets)
+}
@@ -0,0 +1,310 @@
+package dbcopy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// maxReportedKeys caps the mismatching keys listed per dataset
+const maxReportedKeys = 20
+
+// Report is the outcome of a verification pass
+type Report struct {
+	Cutoff   time.Time       `json:"cutoff"`
+	Datasets []DatasetReport `json:"datasets"`
+}
+
+// OK reports whether every dataset matched
+func (r *Report) OK() bool {
+	for _, d := range r.Datasets {
+		if !d.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// DatasetReport compares one dataset. Keyed datasets (equipment, IMEI_INFO,
+// TAC_INFO) are compared record by record; append-only datasets, which have
+// no key shared between backends, by count and an order-independent digest.
+type DatasetReport struct {
+	Dataset      string   `json:"dataset"`
+	SourceCount  int64    `json:"source_count"`
+	TargetCount  int64    `json:"target_count"`
+	Mismatched   int64    `json:"mismatched"` // Keyed records missing from or different in the target
+	Keys         []string `json:"keys,omitempty"`
+	SourceDigest string   `json:"source_digest,omitempty"`
+	TargetDigest string   `json:"target_digest,omitempty"`
+	OK           bool     `json:"ok"`
+}
+
+func (d *DatasetReport) mismatch(key string) {
+	d.Mismatched++
+	if len(d.Keys) < maxReportedKeys {
+		d.Keys = append(d.Keys, key)
+	}
+}
+
+// Verify compares every selected dataset of the source and target. The
+// append-only datasets are compared up to the checkpoint's cutoff when a
+// checkpoint exists, and up to now otherwise.
+func (c *Copier) Verify(ctx context.Context) (*Report, error) {
+	cutoff := c.opts.Cutoff
+	if c.opts.Checkpoint != "" {
+		cp, err := loadCheckpoint(c.opts.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if cp != nil {
+			cutoff = cp.Cutoff
+		}
+	}
+	if cutoff.IsZero() {
+		cutoff = time.Now()
+	}
+
+	report := &Report{Cutoff: cutoff.UTC()}
+	for _, dataset := range c.datasets() {
+		d := DatasetReport{Dataset: dataset}
+		var err error
+		switch dataset {
+		case DatasetEquipment:
+			err = c.verifyEquipment(ctx, &d)
+		case DatasetImeiInfo:
+			verifyKeyed(&d, imeiInfoHashes(c.source.GetIMEIRepository().ListAllImeiInfo(ctx)), imeiInfoHashes(c.target.GetIMEIRepository().ListAllImeiInfo(ctx)))
+		case DatasetTacInfo:
+			verifyKeyed(&d, tacInfoHashes(c.source.GetIMEIRepository().ListAllTacInfo(ctx)), tacInfoHashes(c.target.GetIMEIRepository().ListAllTacInfo(ctx)))
+		case DatasetHistory:
+			err = c.verifyDigests(&d, func(adapter ports.DatabaseAdapter, add func([]byte)) error {
+				return eachHistory(ctx, adapter, cutoff, c.opts.BatchSize, func(h *models.EquipmentHistory) { add(historyHash(h)) })
+			})
+		case DatasetAudits:
+			err = c.verifyDigests(&d, func(adapter ports.DatabaseAdapter, add func([]byte)) error {
+				return eachAudit(ctx, adapter, cutoff, c.opts.BatchSize, func(a *models.AuditLog) { add(auditHash(a)) })
+			})
+		case DatasetSnapshots:
+			err = c.verifyDigests(&d, func(adapter ports.DatabaseAdapter, add func([]byte)) error {
+				return c.eachSnapshot(ctx, adapter, cutoff, func(s *models.EquipmentSnapshot) { add(snapshotHash(s)) })
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", dataset, err)
+		}
+		report.Datasets = append(report.Datasets, d)
+	}
+	return report, nil
+}
+
+// verifyEquipment looks every source record up in the target and compares
+// their hashes, then counts the target to catch records only it holds
+func (c *Copier) verifyEquipment(ctx context.Context, d *DatasetReport) error {
+	source, target := c.source.GetIMEIRepository(), c.target.GetIMEIRepository()
+	for offset := 0; ; offset += c.opts.BatchSize {
+		page, err := source.ListWithDeleted(ctx, offset, c.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, equipment := range page {
+			d.SourceCount++
+			copied, err := target.GetByIMEI(ctx, equipment.IMEI)
+			if err != nil || copied == nil || string(equipmentHash(copied)) != string(equipmentHash(equipment)) {
+				d.mismatch(equipment.IMEI)
+			}
+		}
+		if len(page) < c.opts.BatchSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += c.opts.BatchSize {
+		page, err := target.ListWithDeleted(ctx, offset, c.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		d.TargetCount += int64(len(page))
+		if len(page) < c.opts.BatchSize {
+			break
+		}
+	}
+
+	d.OK = d.Mismatched == 0 && d.SourceCount == d.TargetCount
+	return nil
+}
+
+// verifyKeyed compares two key to hash maps
+func verifyKeyed(d *DatasetReport, source, target map[string]string) {
+	d.SourceCount, d.TargetCount = int64(len(source)), int64(len(target))
+	for key, hash := range source {
+		if target[key] != hash {
+			d.mismatch(key)
+		}
+	}
+	d.OK = d.Mismatched == 0 && d.SourceCount == d.TargetCount
+}
+
+// verifyDigests compares the count and digest of records walked by each
+func (c *Copier) verifyDigests(d *DatasetReport, each func(adapter ports.DatabaseAdapter, add func([]byte)) error) error {
+	var source, target digest
+	if err := each(c.source, source.add); err != nil {
+		return err
+	}
+	if err := each(c.target, target.add); err != nil {
+		return err
+	}
+	d.SourceCount, d.TargetCount = source.count, target.count
+	d.SourceDigest, d.TargetDigest = source.String(), target.String()
+	d.OK = source == target
+	return nil
+}
+
+// digest combines record hashes by addition, so it does not depend on the
+// order records are read in but does change when one is missing or duplicated
+type digest struct {
+	count int64
+	sum   [4]uint64
+}
+
+func (g *digest) add(hash []byte) {
+	g.count++
+	for i := range g.sum {
+		g.sum[i] += binary.BigEndian.Uint64(hash[i*8:])
+	}
+}
+
+func (g digest) String() string {
+	out := make([]byte, 32)
+	for i, v := range g.sum {
+		binary.BigEndian.PutUint64(out[i*8:], v)
+	}
+	return hex.EncodeToString(out)
+}
+
+func eachHistory(ctx context.Context, adapter ports.DatabaseAdapter, cutoff time.Time, pageSize int, fn func(*models.EquipmentHistory)) error {
+	for offset := 0; ; offset += pageSize {
+		page, err := adapter.GetHistoryRepository().GetHistoryByTimeRange(ctx, earliest, cutoff, offset, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			fn(entry)
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+	}
+}
+
+func eachAudit(ctx context.Context, adapter ports.DatabaseAdapter, cutoff time.Time, pageSize int, fn func(*models.AuditLog)) error {
+	start, end := earliest.Format(auditTimeLayout), cutoff.UTC().Format(auditTimeLayout)
+	for offset := 0; ; offset += pageSize {
+		page, err := adapter.GetAuditRepository().GetAuditsByTimeRange(ctx, start, end, offset, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, audit := range page {
+			fn(audit)
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+	}
+}
+
+// eachSnapshot walks the snapshots of every source IMEI in adapter
+func (c *Copier) eachSnapshot(ctx context.Context, adapter ports.DatabaseAdapter, cutoff time.Time, fn func(*models.EquipmentSnapshot)) error {
+	for offset := 0; ; offset += c.opts.BatchSize {
+		page, err := c.source.GetIMEIRepository().ListWithDeleted(ctx, offset, c.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, equipment := range page {
+			snapshots, err := snapshotsOf(ctx, adapter.GetSnapshotRepository(), equipment.IMEI, cutoff, c.opts.BatchSize)
+			if err != nil {
+				return err
+			}
+			for _, snapshot := range snapshots {
+				fn(snapshot)
+			}
+		}
+		if len(page) < c.opts.BatchSize {
+			return nil
+		}
+	}
+}
+
+// The hashes below cover the fields a copy preserves. IDs are assigned by
+// each backend, and equipment timestamps and counters move with every check,
+// so they are left out; times are compared at millisecond precision, the
+// finest every backend stores.
+
+func equipmentHash(e *models.Equipment) []byte {
+	return hashFields(e.IMEI, str(e.IMEISV), string(e.Status), str(e.Reason), e.AddedBy,
+		str(e.Metadata), str(e.ManufacturerTAC), str(e.ManufacturerName), strconv.FormatBool(e.IsDeleted()), str(e.DeletedBy))
+}
+
+func imeiInfoHashes(infos []*ports.ImeiInfo) map[string]string {
+	hashes := make(map[string]string, len(infos))
+	for _, info := range infos {
+		hashes[info.StartIMEI] = string(hashFields(info.StartIMEI, strings.Join(info.EndIMEI, ","), string(info.Color)))
+	}
+	return hashes
+}
+
+func tacInfoHashes(infos []*ports.TacInfo) map[string]string {
+	hashes := make(map[string]string, len(infos))
+	for _, info := range infos {
+		hashes[info.KeyTac] = string(hashFields(info.KeyTac, info.StartRangeTac, info.EndRangeTac, string(info.Color), str(info.PrevLink)))
+	}
+	return hashes
+}
+
+func historyHash(h *models.EquipmentHistory) []byte {
+	previousStatus := ""
+	if h.PreviousStatus != nil {
+		previousStatus = string(*h.PreviousStatus)
+	}
+	details, _ := json.Marshal(h.ChangeDetails)
+	return hashFields(h.IMEI, string(h.ChangeType), millis(h.ChangedAt), h.ChangedBy, previousStatus,
+		string(h.NewStatus), str(h.PreviousReason), str(h.NewReason), string(details), str(h.SessionID))
+}
+
+func auditHash(a *models.AuditLog) []byte {
+	resultCode := ""
+	if a.ResultCode != nil {
+		resultCode = fmt.Sprint(*a.ResultCode)
+	}
+	return hashFields(a.IMEI, str(a.IMEISV), string(a.Status), millis(a.CheckTime), str(a.OriginHost),
+		str(a.OriginRealm), str(a.UserName), str(a.SUPI), str(a.GPSI), a.RequestSource, str(a.SessionID), resultCode, str(a.SUPIIndex))
+}
+
+func snapshotHash(s *models.EquipmentSnapshot) []byte {
+	return hashFields(s.IMEI, millis(s.SnapshotTime), string(s.Status), str(s.Reason), fmt.Sprint(s.CheckCount),
+		str(s.Metadata), s.CreatedBy, s.SnapshotType)
+}
+
+// hashFields hashes fields with a separator no field contains
+func hashFields(fields ...string) []byte {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return sum[:]
+}
+
+func millis(t time.Time) string {
+	return fmt.Sprint(t.UnixMilli())
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
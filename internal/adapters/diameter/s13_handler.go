@@ -95,8 +95,7 @@ func (h *S13Handler) buildSuccessAnswerFromTac(req *s13.MEIdentityCheckRequest,
 	resultCode := models_base.Unsigned32(DiameterResultCodeSuccess)
 	answer.ResultCode = &resultCode
 
-	// Convert color to equipment status
-	equipmentStatus := convertColorToEquipmentStatus(checkResponse.Color)
+	equipmentStatus := checkResponse.EquipmentStatus()
 	diameterStatus := models_base.Enumerated(models.ToDialDialStatus(equipmentStatus))
 	answer.EquipmentStatus = &diameterStatus
 
@@ -104,21 +103,6 @@ func (h *S13Handler) buildSuccessAnswerFromTac(req *s13.MEIdentityCheckRequest,
 	return answer
 }
 
-// convertColorToEquipmentStatus converts pkg/logic color codes to EquipmentStatus
-func convertColorToEquipmentStatus(color string) models.EquipmentStatus {
-	switch color {
-	case "black":
-		return models.EquipmentStatusBlacklisted
-	case "grey":
-		return models.EquipmentStatusGreylisted
-	case "white":
-		return models.EquipmentStatusWhitelisted
-	default:
-		// Default to whitelisted for unknown
-		return models.EquipmentStatusWhitelisted
-	}
-}
-
 // buildErrorAnswer creates an error ME-Identity-Check-Answer
 func (h *S13Handler) buildErrorAnswer(req *s13.MEIdentityCheckRequest, resultCode uint32) *s13.MEIdentityCheckAnswer {
 	logger.Log.Warnw("Diameter S13 building error answer", "session_id", req.SessionId, "result_code", resultCode)
@@ -81,7 +81,7 @@ func (m *mockEIRService) CheckTac(ctx context.Context, imei string, status model
 	}, nil
 }
 
-func (m *mockEIRService) InsertImei(ctx context.Context, imei string, color string, status models.SystemStatus) (*ports.InsertImeiResult, error) {
+func (m *mockEIRService) InsertImei(ctx context.Context, imei string, color models.EquipmentStatus, status models.SystemStatus) (*ports.InsertImeiResult, error) {
 	legacyStatus := legacyModels.SystemStatus{
 		OverloadLevel: status.OverloadLevel,
 		TPSOverload:   status.TPSOverload,
@@ -146,25 +146,95 @@ func (m *mockEIRService) ListAllImeiInfo() []*ports.ImeiInfo {
 	return m.imeiRepo.ListAllImeiInfo(context.Background())
 }
 
+func (m *mockEIRService) UpdateEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	return equipment, nil
+}
+
 func (m *mockEIRService) RemoveEquipment(ctx context.Context, imei string) error {
 	return nil
 }
 
-func (m *mockEIRService) GetEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+func (m *mockEIRService) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	return []*models.AuditLog{}, nil
+}
+
+func (m *mockEIRService) GetAuditsBySUPI(ctx context.Context, supi string, offset, limit int) ([]*models.AuditLog, error) {
+	return []*models.AuditLog{}, nil
+}
+
+func (m *mockEIRService) UndeleteEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+	return &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}, nil
+}
+
+func (m *mockEIRService) ProvisionEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	provisioned := *equipment
+	provisioned.Version = 1
+	return &provisioned, nil
+}
+
+func (m *mockEIRService) GetEquipment(ctx context.Context, imei string, includeDeleted bool) (*models.Equipment, error) {
 	return &models.Equipment{
 		IMEI:   imei,
 		Status: models.EquipmentStatusWhitelisted,
 	}, nil
 }
 
-func (m *mockEIRService) ListEquipment(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+func (m *mockEIRService) ListEquipment(ctx context.Context, offset, limit int, includeDeleted bool) ([]*models.Equipment, error) {
 	return []*models.Equipment{}, nil
 }
 
+func (m *mockEIRService) GetEquipmentHistory(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return []*models.EquipmentHistory{}, nil
+}
+
+func (m *mockEIRService) GetStatusAt(ctx context.Context, imei string, at time.Time) (*models.PointInTimeStatus, error) {
+	return &models.PointInTimeStatus{IMEI: imei, At: at}, nil
+}
+
+func (m *mockEIRService) SnapshotEquipment(ctx context.Context, imei string) (*models.EquipmentSnapshot, error) {
+	return &models.EquipmentSnapshot{IMEI: imei, SnapshotType: models.SnapshotTypeManual}, nil
+}
+
+func (m *mockEIRService) SnapshotAllEquipment(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockEIRService) ListSnapshots(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error) {
+	return []*models.EquipmentSnapshot{}, nil
+}
+
+func (m *mockEIRService) RestoreSnapshot(ctx context.Context, imei string, snapshotID int64, expectedVersion int64) (*models.Equipment, error) {
+	return &models.Equipment{IMEI: imei}, nil
+}
+
+func (m *mockEIRService) PurgeSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockEIRService) SetLogger(l logger.Logger) {
 	// Mock implementation - no-op for testing
 }
 
+func (m *mockEIRService) SetDecisionCache(c ports.DecisionCache) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetCheckRecorder(r ports.CheckRecorder) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetTransactionProvider(p ports.TransactionProvider) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetHistoryRepository(h ports.HistoryRepository) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetSnapshotRepository(r ports.SnapshotRepository) {
+	// Mock implementation - no-op for testing
+}
+
 // TestServerBasicSetup tests basic server creation and startup
 func TestServerBasicSetup(t *testing.T) {
 	config := ServerConfig{
@@ -485,7 +555,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 	t.Run("InsertAndCheck_Blacklisted_EIR_10", func(t *testing.T) {
 		mockService.ClearImeiInfo()
 		imei := "9"
-		color := "b" // black
+		color := models.EquipmentStatusBlacklisted // black
 		// Step 1: Insert IMEI via Service logic
 		ctx := context.Background()
 		mockService.InsertImei(ctx, imei, color, models.SystemStatus{})
@@ -503,7 +573,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 	t.Run("InsertAndCheck_Greylisted_EIR_11", func(t *testing.T) {
 		mockService.ClearImeiInfo()
 		imei := "912"
-		color := "g"
+		color := models.EquipmentStatusGreylisted
 
 		ctx := context.Background()
 		mockService.InsertImei(ctx, imei, color, models.SystemStatus{})
@@ -521,7 +591,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 	t.Run("InsertAndCheck_LongBlacklisted_EIR_12", func(t *testing.T) {
 		mockService.ClearImeiInfo()
 		imei := "9123456789012"
-		color := "b"
+		color := models.EquipmentStatusBlacklisted
 
 		ctx := context.Background()
 		mockService.InsertImei(ctx, imei, color, models.SystemStatus{})
@@ -539,7 +609,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 	t.Run("InsertAndCheck_Whitelisted_EIR_13", func(t *testing.T) {
 		mockService.ClearImeiInfo()
 		imei := "91234567895264"
-		color := "w"
+		color := models.EquipmentStatusWhitelisted
 
 		ctx := context.Background()
 		mockService.InsertImei(ctx, imei, color, models.SystemStatus{})
@@ -595,8 +665,11 @@ func TestCheckTacWithPCAP(t *testing.T) {
 
 	// Step 1: Insert TAC ranges (Provisioning)
 	t.Run("Step1_InsertTacRanges", func(t *testing.T) {
-		testCases := []struct{ s, e, c string }{
-			{"35", "35", "black"}, {"35310", "35319", "white"}, {"353200", "353299", "grey"},
+		testCases := []struct {
+			s, e string
+			c    models.EquipmentStatus
+		}{
+			{"35", "35", models.EquipmentStatusBlacklisted}, {"35310", "35319", models.EquipmentStatusWhitelisted}, {"353200", "353299", models.EquipmentStatusGreylisted},
 		}
 		for _, tc := range testCases {
 			mockService.InsertTac(context.Background(), &ports.TacInfo{StartRangeTac: tc.s, EndRangeTac: tc.e, Color: tc.c})
@@ -605,9 +678,10 @@ func TestCheckTacWithPCAP(t *testing.T) {
 
 	t.Run("Step2_CheckTacQueries", func(t *testing.T) {
 		testCases := []struct {
-			imei, expectedColor string
+			imei          string
+			expectedColor models.EquipmentStatus
 		}{
-			{"35", "black"}, {"35315", "white"}, {"353250", "grey"}, {"1", "unknown"},
+			{"35", models.EquipmentStatusBlacklisted}, {"35315", models.EquipmentStatusWhitelisted}, {"353250", models.EquipmentStatusGreylisted}, {"1", models.CheckOutcomeUnknown},
 		}
 		for _, tc := range testCases {
 			// Gọi trực tiếp function CheckTac của service
@@ -680,10 +754,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "white"},
-			{"2-2", "2", "2", "white"},
+			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", models.EquipmentStatusWhitelisted},
+			{"2-2", "2", "2", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -722,14 +796,14 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"111-1222", "111", "1222", "white"},
-			{"1223-13", "1223", "13", "white"},
-			{"123456789012345-123456789012349", "123456789012345", "123456789012349", "white"},
-			{"1-9", "1", "9", "white"},
-			{"4-4234567890123456", "4", "4234567890123456", "white"},
-			{"1234567890123456-1234567890123457", "1234567890123456", "1234567890123457", "white"},
+			{"111-1222", "111", "1222", models.EquipmentStatusWhitelisted},
+			{"1223-13", "1223", "13", models.EquipmentStatusWhitelisted},
+			{"123456789012345-123456789012349", "123456789012345", "123456789012349", models.EquipmentStatusWhitelisted},
+			{"1-9", "1", "9", models.EquipmentStatusWhitelisted},
+			{"4-4234567890123456", "4", "4234567890123456", models.EquipmentStatusWhitelisted},
+			{"1234567890123456-1234567890123457", "1234567890123456", "1234567890123457", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -772,11 +846,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"133-133", "133", "133", "white"},
-			{"132-132", "132", "132", "white"},
-			{"134-134", "134", "134", "white"},
+			{"133-133", "133", "133", models.EquipmentStatusWhitelisted},
+			{"132-132", "132", "132", models.EquipmentStatusWhitelisted},
+			{"134-134", "134", "134", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -820,10 +894,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"133-135", "133", "135", "white"},
-			{"133-139", "133", "139", "white"},
+			{"133-135", "133", "135", models.EquipmentStatusWhitelisted},
+			{"133-139", "133", "139", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -867,13 +941,13 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1222-1999", "1222", "1999", "white"},
-			{"1222-1333", "1222", "1333", "white"},
-			{"1666-1999", "1666", "1999", "white"},
-			{"1888-1888", "1888", "1888", "white"},
-			{"1222345-1222345", "1222345", "1222345", "white"},
+			{"1222-1999", "1222", "1999", models.EquipmentStatusWhitelisted},
+			{"1222-1333", "1222", "1333", models.EquipmentStatusWhitelisted},
+			{"1666-1999", "1666", "1999", models.EquipmentStatusWhitelisted},
+			{"1888-1888", "1888", "1888", models.EquipmentStatusWhitelisted},
+			{"1222345-1222345", "1222345", "1222345", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -917,11 +991,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1222-1666", "1222", "1666", "white"},
-			{"1333-1555", "1333", "1555", "white"},
-			{"1777-1888", "1777", "1888", "white"},
+			{"1222-1666", "1222", "1666", models.EquipmentStatusWhitelisted},
+			{"1333-1555", "1333", "1555", models.EquipmentStatusWhitelisted},
+			{"1777-1888", "1777", "1888", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -965,11 +1039,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"9-1", "9", "1", "white"},
-			{"abcd12354-12345a@#@#$@#", "abcd12354", "12345a@#@#$@#", "white"},
-			{"\"1234 56789-12345\"", "\"1234 56789\"", "12345", "white"},
+			{"9-1", "9", "1", models.EquipmentStatusWhitelisted},
+			{"abcd12354-12345a@#@#$@#", "abcd12354", "12345a@#@#$@#", models.EquipmentStatusWhitelisted},
+			{"\"1234 56789-12345\"", "\"1234 56789\"", "12345", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1012,7 +1086,7 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
 			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "."},
 			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "g"},
@@ -1058,10 +1132,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", "white"},
-			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", "white"},
+			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", models.EquipmentStatusWhitelisted},
+			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", models.EquipmentStatusWhitelisted},
 		}
 		i := 0
 		for _, tc := range testCases {
@@ -1114,10 +1188,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1234-1235", "1234", "1235", "white"},
-			{"1232-1234", "1232", "1234", "white"},
+			{"1234-1235", "1234", "1235", models.EquipmentStatusWhitelisted},
+			{"1232-1234", "1232", "1234", models.EquipmentStatusWhitelisted},
 		}
 		i := 0
 		for _, tc := range testCases {
@@ -1221,11 +1295,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 	t.Run("EIR_Add_1", func(t *testing.T) {
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"1", "w", false, "Valid IMEI - white"},
+			{"1", models.EquipmentStatusWhitelisted, false, "Valid IMEI - white"},
 		}
 
 		for _, tc := range testCases {
@@ -1269,11 +1343,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 	t.Run("EIR_Add_2", func(t *testing.T) {
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"123456789012345", "g", false, "Valid IMEI - grey"},
+			{"123456789012345", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -1317,11 +1391,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 	t.Run("EIR_Add_3", func(t *testing.T) {
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234", "g", false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -1365,11 +1439,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 	t.Run("EIR_Add_4", func(t *testing.T) {
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234567", "g", false, "Valid IMEI - grey"},
+			{"12345678901234567", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -1413,19 +1487,19 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 	t.Run("EIR_Add_5", func(t *testing.T) {
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234", "g", false, "Valid IMEI - grey"},
-			{"123456789012341", "g", false, "Valid IMEI - grey"},
-			{"1234567890123411", "g", false, "Valid IMEI - grey"},
-			{"12345678901234111", "g", false, "Valid IMEI - grey"},
-			{"12345678901234112", "g", false, "Valid IMEI - grey"},
-			{"12345678901234222", "g", false, "Valid IMEI - grey"},
-			{"12345678901234333", "g", false, "Valid IMEI - grey"},
-			{"12345678901234", "g", false, "Valid IMEI - grey (duplicate)"},
-			{"12345678901234444", "g", false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"123456789012341", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"1234567890123411", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234111", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234112", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234222", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234333", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey (duplicate)"},
+			{"12345678901234444", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -0,0 +1,51 @@
+// Package dualwrite mirrors repository writes to a second backend while a
+// site moves between databases. Reads are served by the primary alone and a
+// write reaches the secondary only after it succeeded on the primary, so the
+// secondary can never hold a change the primary rejected. Failures on the
+// secondary are logged and counted but never fail the request: the
+// verification pass of cmd/migrate finds any record they left behind.
+package dualwrite
+
+import (
+	"context"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// Repository names used in logs and the eir_dual_write_errors_total metric
+const (
+	repoIMEI     = "imei"
+	repoAudit    = "audit"
+	repoHistory  = "history"
+	repoSnapshot = "snapshot"
+)
+
+// mirror applies writes to the secondary backend
+type mirror struct {
+	log logger.Logger
+}
+
+// report records the outcome of a secondary write
+func (m mirror) report(repository, op string, err error) {
+	if err == nil {
+		return
+	}
+	logger.DualWriteErrorsTotal.WithLabelValues(repository).Inc()
+	m.log.Warnw("Dual-write to secondary database failed", "repository", repository, "op", op, "error", err)
+}
+
+// upsertEquipment writes equipment to repo by IMEI. The secondary assigns its
+// own IDs and versions, so a record the primary updates may be new to it and
+// vice versa; the primary has already checked the version.
+func upsertEquipment(ctx context.Context, repo ports.IMEIRepository, equipment *models.Equipment) error {
+	copied := *equipment
+	copied.Version = 0
+	if existing, err := repo.GetByIMEI(ctx, copied.IMEI); err == nil && existing != nil {
+		copied.ID = existing.ID
+		return repo.Update(ctx, &copied)
+	}
+	copied.ID = 0
+	return repo.Create(ctx, &copied)
+}
@@ -0,0 +1,109 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingIMEIRepository rejects every write
+type failingIMEIRepository struct {
+	ports.IMEIRepository
+}
+
+func (failingIMEIRepository) GetByIMEI(context.Context, string) (*models.Equipment, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingIMEIRepository) Create(context.Context, *models.Equipment) error {
+	return errors.New("unavailable")
+}
+
+func TestIMEIRepository_MirrorsWrites(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("test", "info")
+	primary, secondary := memory.NewInMemoryIMEIRepository(), memory.NewInMemoryIMEIRepository()
+
+	// The secondary assigns its own ID to a record it has not seen yet
+	require.NoError(t, secondary.Create(ctx, &models.Equipment{IMEI: "356938035643809", Status: models.EquipmentStatusWhitelisted}))
+
+	repo := NewIMEIRepository(primary, secondary, log)
+	equipment := &models.Equipment{IMEI: "490154203237518", Status: models.EquipmentStatusBlacklisted, AddedBy: "ops"}
+	require.NoError(t, repo.Create(ctx, equipment))
+
+	mirrored, err := secondary.GetByIMEI(ctx, equipment.IMEI)
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, mirrored.Status)
+	assert.NotEqual(t, equipment.ID, mirrored.ID, "the caller's record keeps the primary's ID")
+
+	equipment.Status = models.EquipmentStatusGreylisted
+	require.NoError(t, repo.Update(ctx, equipment))
+	mirrored, err = secondary.GetByIMEI(ctx, equipment.IMEI)
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusGreylisted, mirrored.Status)
+
+	require.NoError(t, repo.Delete(ctx, equipment.IMEI))
+	_, err = secondary.GetByIMEI(ctx, equipment.IMEI)
+	assert.Error(t, err)
+}
+
+func TestIMEIRepository_SecondaryFailureIsNotReturned(t *testing.T) {
+	ctx := context.Background()
+	primary := memory.NewInMemoryIMEIRepository()
+	repo := NewIMEIRepository(primary, failingIMEIRepository{}, logger.New("test", "info"))
+
+	require.NoError(t, repo.Create(ctx, &models.Equipment{IMEI: "490154203237518", Status: models.EquipmentStatusBlacklisted}))
+	_, err := primary.GetByIMEI(ctx, "490154203237518")
+	assert.NoError(t, err)
+}
+
+func TestTransactionProvider_MirrorsOnCommitOnly(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("test", "info")
+
+	primaryIMEIs := memory.NewInMemoryIMEIRepository()
+	primaryAudits := memory.NewInMemoryAuditRepository()
+	primaryHistory := memory.NewInMemoryHistoryRepository()
+	txs, err := memory.NewTransactionManager(primaryIMEIs, primaryAudits, primaryHistory)
+	require.NoError(t, err)
+
+	secondary := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	require.NoError(t, secondary.Connect(ctx))
+	defer secondary.Disconnect(ctx)
+
+	provider := NewTransactionProvider(txs, secondary, log)
+
+	tx, err := provider.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().Create(ctx, &models.Equipment{IMEI: "356938035643809", Status: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, tx.Rollback(ctx))
+
+	tx, err = provider.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().Create(ctx, &models.Equipment{IMEI: "490154203237518", Status: models.EquipmentStatusBlacklisted}))
+	require.NoError(t, tx.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{
+		IMEI: "490154203237518", ChangeType: models.ChangeTypeCreate, NewStatus: models.EquipmentStatusBlacklisted,
+	}))
+
+	_, err = secondary.GetIMEIRepository().GetByIMEI(ctx, "490154203237518")
+	assert.Error(t, err, "nothing reaches the secondary before commit")
+
+	require.NoError(t, tx.Commit(ctx))
+	_, err = secondary.GetIMEIRepository().GetByIMEI(ctx, "490154203237518")
+	assert.NoError(t, err)
+	history, err := secondary.GetHistoryRepository().GetHistoryByIMEI(ctx, "490154203237518", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	_, err = secondary.GetIMEIRepository().GetByIMEI(ctx, "356938035643809")
+	assert.Error(t, err, "rolled back writes are dropped")
+}
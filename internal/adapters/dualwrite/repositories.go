@@ -0,0 +1,245 @@
+package dualwrite
+
+import (
+	"context"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// write is a secondary write, run immediately or queued until a transaction commits
+type write func(ctx context.Context)
+
+// imeiRepository mirrors equipment, IMEI_INFO and TAC_INFO writes
+type imeiRepository struct {
+	ports.IMEIRepository // primary
+	secondary            ports.IMEIRepository
+	mirror               mirror
+	apply                func(ctx context.Context, w write)
+}
+
+// tacRangeIMEIRepository keeps the native TAC range queries of a primary that has them
+type tacRangeIMEIRepository struct {
+	*imeiRepository
+	ports.TacRangeRepository
+}
+
+// NewIMEIRepository creates an IMEI repository that mirrors writes from primary to secondary
+func NewIMEIRepository(primary, secondary ports.IMEIRepository, log logger.Logger) ports.IMEIRepository {
+	return newIMEIRepository(primary, secondary, mirror{log: log}, runNow)
+}
+
+func newIMEIRepository(primary, secondary ports.IMEIRepository, m mirror, apply func(ctx context.Context, w write)) ports.IMEIRepository {
+	repo := &imeiRepository{IMEIRepository: primary, secondary: secondary, mirror: m, apply: apply}
+	if ranges, ok := primary.(ports.TacRangeRepository); ok {
+		return &tacRangeIMEIRepository{imeiRepository: repo, TacRangeRepository: ranges}
+	}
+	return repo
+}
+
+func runNow(ctx context.Context, w write) {
+	w(ctx)
+}
+
+func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment) error {
+	if err := r.IMEIRepository.Create(ctx, equipment); err != nil {
+		return err
+	}
+	copied := *equipment
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "create", upsertEquipment(ctx, r.secondary, &copied))
+	})
+	return nil
+}
+
+func (r *imeiRepository) Update(ctx context.Context, equipment *models.Equipment) error {
+	if err := r.IMEIRepository.Update(ctx, equipment); err != nil {
+		return err
+	}
+	copied := *equipment
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "update", upsertEquipment(ctx, r.secondary, &copied))
+	})
+	return nil
+}
+
+func (r *imeiRepository) Delete(ctx context.Context, imei string) error {
+	if err := r.IMEIRepository.Delete(ctx, imei); err != nil {
+		return err
+	}
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "delete", r.secondary.Delete(ctx, imei))
+	})
+	return nil
+}
+
+func (r *imeiRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	purged, err := r.IMEIRepository.PurgeDeleted(ctx, before, limit)
+	if err != nil {
+		return purged, err
+	}
+	r.apply(ctx, func(ctx context.Context) {
+		_, err := r.secondary.PurgeDeleted(ctx, before, limit)
+		r.mirror.report(repoIMEI, "purge_deleted", err)
+	})
+	return purged, nil
+}
+
+func (r *imeiRepository) IncrementCheckCount(ctx context.Context, imei string) error {
+	if err := r.IMEIRepository.IncrementCheckCount(ctx, imei); err != nil {
+		return err
+	}
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "increment_check_count", r.secondary.IncrementCheckCount(ctx, imei))
+	})
+	return nil
+}
+
+func (r *imeiRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if err := r.IMEIRepository.ApplyCheckCounts(ctx, deltas); err != nil {
+		return err
+	}
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "apply_check_counts", r.secondary.ApplyCheckCounts(ctx, deltas))
+	})
+	return nil
+}
+
+func (r *imeiRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	if err := r.IMEIRepository.SaveImeiInfo(ctx, info); err != nil {
+		return err
+	}
+	copied := *info
+	copied.Version = 0
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "save_imei_info", r.secondary.SaveImeiInfo(ctx, &copied))
+	})
+	return nil
+}
+
+func (r *imeiRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	if err := r.IMEIRepository.DeleteImeiInfo(ctx, info); err != nil {
+		return err
+	}
+	copied := *info
+	copied.Version = 0
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "delete_imei_info", r.secondary.DeleteImeiInfo(ctx, &copied))
+	})
+	return nil
+}
+
+func (r *imeiRepository) ClearImeiInfo(ctx context.Context) {
+	r.IMEIRepository.ClearImeiInfo(ctx)
+	r.apply(ctx, r.secondary.ClearImeiInfo)
+}
+
+func (r *imeiRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
+	if err := r.IMEIRepository.SaveTacInfo(ctx, info); err != nil {
+		return err
+	}
+	copied := *info
+	copied.Version = 0
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoIMEI, "save_tac_info", r.secondary.SaveTacInfo(ctx, &copied))
+	})
+	return nil
+}
+
+func (r *imeiRepository) ClearTacInfo(ctx context.Context) {
+	r.IMEIRepository.ClearTacInfo(ctx)
+	r.apply(ctx, r.secondary.ClearTacInfo)
+}
+
+// auditRepository mirrors audit logs
+type auditRepository struct {
+	ports.AuditRepository // primary
+	secondary             ports.AuditRepository
+	mirror                mirror
+	apply                 func(ctx context.Context, w write)
+}
+
+// NewAuditRepository creates an audit repository that mirrors writes from primary to secondary
+func NewAuditRepository(primary, secondary ports.AuditRepository, log logger.Logger) ports.AuditRepository {
+	return &auditRepository{AuditRepository: primary, secondary: secondary, mirror: mirror{log: log}, apply: runNow}
+}
+
+func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	if err := r.AuditRepository.LogCheck(ctx, audit); err != nil {
+		return err
+	}
+	copied := *audit
+	copied.ID = 0
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoAudit, "log_check", r.secondary.LogCheck(ctx, &copied))
+	})
+	return nil
+}
+
+// historyRepository mirrors change history
+type historyRepository struct {
+	ports.HistoryRepository // primary
+	secondary               ports.HistoryRepository
+	mirror                  mirror
+	apply                   func(ctx context.Context, w write)
+}
+
+// NewHistoryRepository creates a history repository that mirrors writes from primary to secondary
+func NewHistoryRepository(primary, secondary ports.HistoryRepository, log logger.Logger) ports.HistoryRepository {
+	return &historyRepository{HistoryRepository: primary, secondary: secondary, mirror: mirror{log: log}, apply: runNow}
+}
+
+func (r *historyRepository) RecordChange(ctx context.Context, history *models.EquipmentHistory) error {
+	if err := r.HistoryRepository.RecordChange(ctx, history); err != nil {
+		return err
+	}
+	copied := *history
+	copied.ID = 0
+	r.apply(ctx, func(ctx context.Context) {
+		r.mirror.report(repoHistory, "record_change", r.secondary.RecordChange(ctx, &copied))
+	})
+	return nil
+}
+
+// snapshotRepository mirrors snapshots
+type snapshotRepository struct {
+	ports.SnapshotRepository // primary
+	secondary                ports.SnapshotRepository
+	equipment                ports.IMEIRepository // secondary, to map equipment IDs
+	mirror                   mirror
+}
+
+// NewSnapshotRepository creates a snapshot repository that mirrors writes
+// from primary to secondary. secondaryIMEIs resolves the secondary's own ID
+// for the equipment a snapshot belongs to.
+func NewSnapshotRepository(primary, secondary ports.SnapshotRepository, secondaryIMEIs ports.IMEIRepository, log logger.Logger) ports.SnapshotRepository {
+	return &snapshotRepository{SnapshotRepository: primary, secondary: secondary, equipment: secondaryIMEIs, mirror: mirror{log: log}}
+}
+
+func (r *snapshotRepository) CreateSnapshot(ctx context.Context, snapshot *models.EquipmentSnapshot) error {
+	if err := r.SnapshotRepository.CreateSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+	copied := *snapshot
+	copied.ID = 0
+	equipment, err := r.equipment.GetByIMEI(ctx, copied.IMEI)
+	if err != nil {
+		r.mirror.report(repoSnapshot, "create_snapshot", err)
+		return nil
+	}
+	copied.EquipmentID = equipment.ID
+	r.mirror.report(repoSnapshot, "create_snapshot", r.secondary.CreateSnapshot(ctx, &copied))
+	return nil
+}
+
+func (r *snapshotRepository) DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.SnapshotRepository.DeleteOldSnapshots(ctx, before)
+	if err != nil {
+		return deleted, err
+	}
+	_, err = r.secondary.DeleteOldSnapshots(ctx, before)
+	r.mirror.report(repoSnapshot, "delete_old_snapshots", err)
+	return deleted, nil
+}
@@ -0,0 +1,79 @@
+package dualwrite
+
+import (
+	"context"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// transactionProvider begins transactions on the primary whose writes reach
+// the secondary only once the primary transaction has committed
+type transactionProvider struct {
+	primary   ports.TransactionProvider
+	secondary ports.DatabaseAdapter
+	mirror    mirror
+}
+
+// NewTransactionProvider creates a transaction provider over primary that
+// mirrors committed writes to secondary
+func NewTransactionProvider(primary ports.TransactionProvider, secondary ports.DatabaseAdapter, log logger.Logger) ports.TransactionProvider {
+	return &transactionProvider{primary: primary, secondary: secondary, mirror: mirror{log: log}}
+}
+
+func (p *transactionProvider) BeginTransaction(ctx context.Context) (ports.Transaction, error) {
+	tx, err := p.primary.BeginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &transaction{Transaction: tx}
+	t.imeiRepo = newIMEIRepository(tx.GetIMEIRepository(), p.secondary.GetIMEIRepository(), p.mirror, t.queue)
+	t.auditRepo = &auditRepository{AuditRepository: tx.GetAuditRepository(), secondary: p.secondary.GetAuditRepository(), mirror: p.mirror, apply: t.queue}
+	t.historyRepo = &historyRepository{HistoryRepository: tx.GetHistoryRepository(), secondary: p.secondary.GetHistoryRepository(), mirror: p.mirror, apply: t.queue}
+	return t, nil
+}
+
+// transaction queues secondary writes until the primary commits
+type transaction struct {
+	ports.Transaction // primary
+	imeiRepo          ports.IMEIRepository
+	auditRepo         ports.AuditRepository
+	historyRepo       ports.HistoryRepository
+	pending           []write
+}
+
+func (t *transaction) queue(_ context.Context, w write) {
+	t.pending = append(t.pending, w)
+}
+
+// Commit commits the primary transaction, then applies the queued writes to the secondary
+func (t *transaction) Commit(ctx context.Context) error {
+	if err := t.Transaction.Commit(ctx); err != nil {
+		return err
+	}
+	pending := t.pending
+	t.pending = nil
+	for _, w := range pending {
+		w(ctx)
+	}
+	return nil
+}
+
+// Rollback rolls back the primary transaction and drops the queued writes
+func (t *transaction) Rollback(ctx context.Context) error {
+	t.pending = nil
+	return t.Transaction.Rollback(ctx)
+}
+
+func (t *transaction) GetIMEIRepository() ports.IMEIRepository {
+	return t.imeiRepo
+}
+
+func (t *transaction) GetAuditRepository() ports.AuditRepository {
+	return t.auditRepo
+}
+
+func (t *transaction) GetHistoryRepository() ports.HistoryRepository {
+	return t.historyRepo
+}
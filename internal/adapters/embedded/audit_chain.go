@@ -0,0 +1,149 @@
+package embedded
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// auditChainStore implements the AuditChainStore interface on the embedded store
+type auditChainStore struct {
+	store store
+}
+
+// NewAuditChainStore creates a new embedded audit chain store
+func NewAuditChainStore(s store) ports.AuditChainStore {
+	return &auditChainStore{store: s}
+}
+
+// chainHead returns the last link of a partition, or nil when it has none
+func chainHead(tx *bolt.Tx, partition string) (*models.AuditChainHead, error) {
+	data := tx.Bucket(bucketAuditChainHeads).Get([]byte(partition))
+	if data == nil {
+		return nil, nil
+	}
+	var head models.AuditChainHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("failed to decode audit chain head: %w", err)
+	}
+	return &head, nil
+}
+
+// checkpointKey orders a partition's checkpoints by chain position
+func checkpointKey(partition string, seq int64, id uint64) []byte {
+	key := make([]byte, 0, len(partition)+17)
+	key = append(key, partition...)
+	key = append(key, 0)
+	key = binary.BigEndian.AppendUint64(key, uint64(seq))
+	return binary.BigEndian.AppendUint64(key, id)
+}
+
+// GetAuditChainPartitions lists the partitions that have chained audits, oldest first
+func (s *auditChainStore) GetAuditChainPartitions(ctx context.Context) ([]string, error) {
+	partitions := make([]string, 0)
+	err := s.store.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAuditChainHeads).ForEach(func(k, _ []byte) error {
+			partitions = append(partitions, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit chain partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// GetAuditChainHead returns the last link of a partition, or nil when it has none
+func (s *auditChainStore) GetAuditChainHead(ctx context.Context, partition string) (*models.AuditChainHead, error) {
+	var head *models.AuditChainHead
+	err := s.store.view(func(tx *bolt.Tx) error {
+		var err error
+		head, err = chainHead(tx, partition)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit chain head: %w", err)
+	}
+	return head, nil
+}
+
+// WalkAuditChain calls fn with the records of a partition in chain order.
+// Audits are keyed by check time, so the partition's day is read and sorted.
+func (s *auditChainStore) WalkAuditChain(ctx context.Context, partition string, fn func(audit *models.AuditLogExtended) error) error {
+	day, err := time.Parse(models.AuditChainPartitionLayout, partition)
+	if err != nil {
+		return fmt.Errorf("invalid audit chain partition %q: %w", partition, err)
+	}
+
+	chain := make([]*models.AuditLogExtended, 0)
+	err = s.store.view(func(tx *bolt.Tx) error {
+		return auditsInRange(tx, day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), func(audit *models.AuditLogExtended) bool {
+			if audit.ChainPartition == partition {
+				chain = append(chain, audit)
+			}
+			return false
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk audit chain: %w", err)
+	}
+
+	sort.Slice(chain, func(i, j int) bool { return chain[i].ChainSeq < chain[j].ChainSeq })
+	for _, audit := range chain {
+		if err := fn(audit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAuditCheckpoint stores a signed checkpoint
+func (s *auditChainStore) SaveAuditCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	err := s.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketAuditCheckpoint)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		checkpoint.ID = int64(id)
+
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			return fmt.Errorf("failed to encode checkpoint: %w", err)
+		}
+		return bucket.Put(checkpointKey(checkpoint.Partition, checkpoint.Seq, id), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save audit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetAuditCheckpoints returns the checkpoints of a partition in chain order
+func (s *auditChainStore) GetAuditCheckpoints(ctx context.Context, partition string) ([]*models.AuditCheckpoint, error) {
+	prefix := append([]byte(partition), 0)
+	checkpoints := make([]*models.AuditCheckpoint, 0)
+	err := s.store.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketAuditCheckpoint).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var checkpoint models.AuditCheckpoint
+			if err := json.Unmarshal(v, &checkpoint); err != nil {
+				return fmt.Errorf("failed to decode checkpoint: %w", err)
+			}
+			checkpoints = append(checkpoints, &checkpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
@@ -0,0 +1,183 @@
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// auditRepository implements the AuditRepository interface on the embedded store.
+// Plain and extended audits share the audit_log bucket, keyed by check time.
+type auditRepository struct {
+	store store
+}
+
+// NewAuditRepository creates a new embedded audit repository
+func NewAuditRepository(s store) ports.AuditRepository {
+	return &auditRepository{store: s}
+}
+
+// putAudit stores an audit entry and its IMEI index entry, assigning its ID
+// and appending it to the hash chain of its partition
+func putAudit(tx *bolt.Tx, audit *models.AuditLogExtended) error {
+	bucket := tx.Bucket(bucketAuditLog)
+	id, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	audit.ID = int64(id)
+
+	partition := audit.PrepareChainLink()
+	head, err := chainHead(tx, partition)
+	if err != nil {
+		return err
+	}
+	audit.LinkTo(head)
+	audit.Hash = audit.ChainHash()
+
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit: %w", err)
+	}
+	key := timeKey(audit.CheckTime, id)
+	if err := bucket.Put(key, data); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketAuditLogByIMEI).Put(indexKey(audit.IMEI, key), nil); err != nil {
+		return err
+	}
+
+	headData, err := json.Marshal(audit.ChainHead())
+	if err != nil {
+		return fmt.Errorf("failed to encode audit chain head: %w", err)
+	}
+	return tx.Bucket(bucketAuditChainHeads).Put([]byte(partition), headData)
+}
+
+// auditsByIMEI pages through the audits of imei, newest first
+func auditsByIMEI(tx *bolt.Tx, imei string, offset, limit int) ([]*models.AuditLogExtended, error) {
+	audits := make([]*models.AuditLogExtended, 0)
+	records := tx.Bucket(bucketAuditLog)
+	p := pager{offset: offset, limit: limit}
+
+	from, to := indexRange(imei)
+	err := descend(tx.Bucket(bucketAuditLogByIMEI).Cursor(), from, to, func(k, _ []byte) (bool, error) {
+		if !p.take() {
+			return false, nil
+		}
+		var audit models.AuditLogExtended
+		if err := json.Unmarshal(records.Get(k[len(from):]), &audit); err != nil {
+			return true, fmt.Errorf("failed to decode audit: %w", err)
+		}
+		audits = append(audits, &audit)
+		return p.full(), nil
+	})
+	return audits, err
+}
+
+// auditsInRange walks the audits checked within [start, end], newest first
+func auditsInRange(tx *bolt.Tx, start, end time.Time, fn func(audit *models.AuditLogExtended) bool) error {
+	from, to := timeKeyRange(start, end)
+	return descend(tx.Bucket(bucketAuditLog).Cursor(), from, to, func(_, v []byte) (bool, error) {
+		var audit models.AuditLogExtended
+		if err := json.Unmarshal(v, &audit); err != nil {
+			return true, fmt.Errorf("failed to decode audit: %w", err)
+		}
+		return fn(&audit), nil
+	})
+}
+
+// LogCheck records an equipment check operation
+func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	extended := &models.AuditLogExtended{AuditLog: *audit}
+	err := r.store.update(func(tx *bolt.Tx) error {
+		return putAudit(tx, extended)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log check: %w", err)
+	}
+	*audit = extended.AuditLog
+	return nil
+}
+
+// GetAuditsByIMEI retrieves audit logs for a specific IMEI
+func (r *auditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	var audits []*models.AuditLog
+	err := r.store.view(func(tx *bolt.Tx) error {
+		extended, err := auditsByIMEI(tx, imei, offset, limit)
+		audits = baseAudits(extended)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by IMEI: %w", err)
+	}
+	return audits, nil
+}
+
+// GetAuditsByTimeRange retrieves audit logs within a time range
+func (r *auditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error) {
+	start, err := parseTime(startTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by time range: %w", err)
+	}
+	end, err := parseTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by time range: %w", err)
+	}
+
+	audits := make([]*models.AuditLog, 0)
+	p := pager{offset: offset, limit: limit}
+	err = r.store.view(func(tx *bolt.Tx) error {
+		return auditsInRange(tx, start, end, func(audit *models.AuditLogExtended) bool {
+			if p.take() {
+				audits = append(audits, &audit.AuditLog)
+			}
+			return p.full()
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by time range: %w", err)
+	}
+	return audits, nil
+}
+
+// GetAuditsBySUPI retrieves audit logs whose SUPI or SUPI index is one of supis
+func (r *auditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	wanted := make(map[string]bool, len(supis))
+	for _, supi := range supis {
+		wanted[supi] = true
+	}
+	matches := func(value *string) bool { return value != nil && wanted[*value] }
+
+	audits := make([]*models.AuditLog, 0)
+	p := pager{offset: offset, limit: limit}
+	err := r.store.view(func(tx *bolt.Tx) error {
+		return descend(tx.Bucket(bucketAuditLog).Cursor(), nil, nil, func(_, v []byte) (bool, error) {
+			var audit models.AuditLogExtended
+			if err := json.Unmarshal(v, &audit); err != nil {
+				return true, fmt.Errorf("failed to decode audit: %w", err)
+			}
+			if (matches(audit.SUPI) || matches(audit.SUPIIndex)) && p.take() {
+				audits = append(audits, &audit.AuditLog)
+			}
+			return p.full(), nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by SUPI: %w", err)
+	}
+	return audits, nil
+}
+
+func baseAudits(extended []*models.AuditLogExtended) []*models.AuditLog {
+	audits := make([]*models.AuditLog, 0, len(extended))
+	for _, audit := range extended {
+		audits = append(audits, &audit.AuditLog)
+	}
+	return audits
+}
@@ -0,0 +1,182 @@
+package embedded
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_OrderingAndRanges(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	repo := adapter.GetAuditRepository()
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		imei := "123456789012345"
+		if i%2 == 1 {
+			imei = "490154203237518"
+		}
+		require.NoError(t, repo.LogCheck(ctx, &models.AuditLog{
+			IMEI:          imei,
+			Status:        models.EquipmentStatusWhitelisted,
+			CheckTime:     base.Add(time.Duration(i) * time.Hour),
+			RequestSource: "HTTP_5G",
+		}))
+	}
+
+	byIMEI, err := repo.GetAuditsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, byIMEI, 3)
+	assert.True(t, byIMEI[0].CheckTime.Equal(base.Add(4*time.Hour)), "newest first")
+	assert.True(t, byIMEI[2].CheckTime.Equal(base))
+
+	// The time range is inclusive at both ends
+	inRange, err := repo.GetAuditsByTimeRange(ctx, "2025-03-01T13:00:00Z", "2025-03-01 15:00:00", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, inRange, 3)
+
+	paged, err := repo.GetAuditsByTimeRange(ctx, "2025-03-01", "2025-03-02", 1, 2)
+	require.NoError(t, err)
+	require.Len(t, paged, 2)
+	assert.True(t, paged[0].CheckTime.Equal(base.Add(3*time.Hour)))
+
+	_, err = repo.GetAuditsByTimeRange(ctx, "not a time", "2025-03-02", 0, 10)
+	assert.Error(t, err)
+}
+
+func TestExtendedAuditRepository_Statistics(t *testing.T) {
+	repo := setupTestAdapter(t).GetExtendedAuditRepository()
+	ctx := context.Background()
+
+	now := time.Now()
+	ms := int64(10)
+	require.NoError(t, repo.LogCheckExtended(ctx, &models.AuditLogExtended{
+		AuditLog: models.AuditLog{
+			IMEI: "123456789012345", Status: models.EquipmentStatusBlacklisted,
+			CheckTime: now, RequestSource: "DIAMETER_S13",
+		},
+		ProcessingTimeMs: &ms,
+	}))
+	require.NoError(t, repo.LogCheck(ctx, &models.AuditLog{
+		IMEI: "123456789012345", Status: models.EquipmentStatusWhitelisted,
+		CheckTime: now, RequestSource: "HTTP_5G",
+	}))
+
+	stats, err := repo.GetAuditStatistics(ctx, now.Add(-time.Minute), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats["total_checks"])
+	assert.Equal(t, int64(1), stats["unique_imeis"])
+	assert.Equal(t, int64(1), stats["blacklisted_count"])
+	assert.Equal(t, int64(1), stats["diameter_checks"])
+	assert.Equal(t, 10.0, stats["avg_processing_time_ms"])
+
+	bySource, err := repo.GetAuditsByRequestSource(ctx, "DIAMETER_S13", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, bySource, 1)
+
+	extended, err := repo.GetExtendedAuditsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, extended, 2)
+	assert.NotNil(t, extended[1].ProcessingTimeMs)
+}
+
+func TestHistoryAndSnapshotRepositories(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	history := adapter.GetHistoryRepository()
+	snapshots := adapter.GetSnapshotRepository()
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, changeType := range []models.ChangeType{models.ChangeTypeCreate, models.ChangeTypeUpdate, models.ChangeTypeUpdate} {
+		require.NoError(t, history.RecordChange(ctx, &models.EquipmentHistory{
+			IMEI: "123456789012345", ChangeType: changeType, ChangedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	updates, err := history.GetHistoryByChangeType(ctx, models.ChangeTypeUpdate, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, updates, 2)
+
+	window, err := history.GetHistoryByTimeRange(ctx, base, base.Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, window, 2)
+	assert.Equal(t, models.ChangeTypeUpdate, window[0].ChangeType)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, snapshots.CreateSnapshot(ctx, &models.EquipmentSnapshot{
+			IMEI: "123456789012345", SnapshotTime: base.Add(time.Duration(i) * 24 * time.Hour), SnapshotType: "SCHEDULED",
+		}))
+	}
+
+	got, err := snapshots.GetSnapshotByID(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, got.SnapshotTime.Equal(base.Add(24*time.Hour)))
+	_, err = snapshots.GetSnapshotByID(ctx, 99)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	latest, err := snapshots.GetLatestSnapshot(ctx, "123456789012345", base.Add(36*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, int64(2), latest.ID)
+	latest, err = snapshots.GetLatestSnapshot(ctx, "123456789012345", base.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+
+	deleted, err := snapshots.DeleteOldSnapshots(ctx, base.Add(36*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	remaining, err := snapshots.GetSnapshotsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, int64(3), remaining[0].ID)
+}
+
+func TestHistoryRepository_ListHistory(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	history := adapter.GetHistoryRepository()
+	ctx := context.Background()
+
+	start := "49015420323751"
+	tacKey := func(lo, hi string) string {
+		return lo + strings.Repeat(" ", 16-len(lo)) + "-" + hi + strings.Repeat("ÿ", 16-len(hi))
+	}
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*models.EquipmentHistory{
+		{IMEI: "4901", ChangeDetails: models.ChangeDetails{"list": "tac", "key_tac": tacKey("4901", "4902")}},
+		{IMEI: "3000", ChangeDetails: models.ChangeDetails{"list": "tac", "key_tac": tacKey("3000", "3999")}},
+		{IMEI: "490154203237518", ChangeDetails: models.ChangeDetails{"list": "imei", "start_imei": start}},
+		{IMEI: "490154203237518", ChangeDetails: models.ChangeDetails{"list": "equipment"}},
+		{IMEI: "35", ChangeDetails: models.ChangeDetails{"list": "imei", "start_imei": "35" + strings.Repeat(" ", 12)}},
+		{IMEI: "490154203237518", ChangeType: models.ChangeTypeDelete, ChangeDetails: models.ChangeDetails{"list": "imei", "start_imei": start}},
+	}
+	for i, entry := range entries {
+		entry.ChangedAt = base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, history.RecordChange(ctx, entry))
+	}
+
+	tac := "490154203237518 "
+	got, err := history.GetListHistory(ctx, start, tac, base.Add(4*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, entries[0].ID, got[0].ID)
+	assert.Equal(t, entries[2].ID, got[1].ID)
+
+	got, err = history.GetListHistory(ctx, start, tac, base.Add(5*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, models.ChangeTypeDelete, got[2].ChangeType)
+
+	// Purged entries leave the list index too
+	_, err = adapter.PurgeHistoryBatch(ctx, base.Add(time.Hour), 0)
+	require.NoError(t, err)
+	got, err = history.GetListHistory(ctx, start, tac, base.Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,154 @@
+package embedded
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the embedded store. Record buckets are keyed so that a cursor
+// walk returns rows in the order the repositories serve them; the *_imei
+// buckets are secondary indexes holding imei + 0x00 + record key.
+var (
+	bucketEquipment       = []byte("equipment")
+	bucketEquipmentIMEISV = []byte("equipment_imeisv")
+	bucketImeiInfo        = []byte("imei_info")
+	bucketTacInfo         = []byte("tac_info")
+	bucketAuditLog        = []byte("audit_log")
+	bucketAuditLogByIMEI  = []byte("audit_log_imei")
+	bucketHistory         = []byte("equipment_history")
+	bucketHistoryByIMEI   = []byte("equipment_history_imei")
+	bucketHistoryByList   = []byte("equipment_history_list") // list key + 0x00 + record key, see listIndexKey
+	bucketSnapshots       = []byte("equipment_snapshots")
+	bucketSnapshotsByIMEI = []byte("equipment_snapshots_imei")
+	bucketAuditChainHeads = []byte("audit_chain_heads") // partition -> last link
+	bucketAuditCheckpoint = []byte("audit_checkpoints") // partition + 0x00 + seq + id -> checkpoint
+	allBuckets            = [][]byte{
+		bucketEquipment, bucketEquipmentIMEISV, bucketImeiInfo, bucketTacInfo,
+		bucketAuditLog, bucketAuditLogByIMEI, bucketHistory, bucketHistoryByIMEI, bucketHistoryByList,
+		bucketSnapshots, bucketSnapshotsByIMEI, bucketAuditChainHeads, bucketAuditCheckpoint,
+	}
+)
+
+var errNotConnected = errors.New("database not connected")
+
+// store runs bolt transactions, either against the open database or inside a
+// caller-managed ports.Transaction
+type store interface {
+	view(fn func(tx *bolt.Tx) error) error
+	update(fn func(tx *bolt.Tx) error) error
+}
+
+// txStore runs every call inside one open read-write transaction
+type txStore struct {
+	tx *bolt.Tx
+}
+
+func (s txStore) view(fn func(tx *bolt.Tx) error) error   { return fn(s.tx) }
+func (s txStore) update(fn func(tx *bolt.Tx) error) error { return fn(s.tx) }
+
+// timeKey orders records by time and breaks ties with the bucket sequence.
+// The sign bit is flipped so pre-1970 times still sort first.
+func timeKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano())^(1<<63))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// timeKeyRange returns the inclusive key bounds covering [start, end]
+func timeKeyRange(start, end time.Time) ([]byte, []byte) {
+	return timeKey(start, 0), timeKey(end, ^uint64(0))
+}
+
+// idKey encodes a record ID so that keys sort numerically
+func idKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// indexKey builds a secondary index entry pointing at the record stored under key
+func indexKey(imei string, key []byte) []byte {
+	out := make([]byte, 0, len(imei)+1+len(key))
+	out = append(out, imei...)
+	out = append(out, 0)
+	return append(out, key...)
+}
+
+// indexRange returns the inclusive bounds of the index entries for imei
+func indexRange(imei string) ([]byte, []byte) {
+	from := indexKey(imei, nil)
+	return from, append(append([]byte{}, from...), bytes.Repeat([]byte{0xff}, 16)...)
+}
+
+// descend walks the keys in [from, to] from newest to oldest; nil bounds are
+// open. The walk ends when fn returns true or an error.
+func descend(c *bolt.Cursor, from, to []byte, fn func(k, v []byte) (bool, error)) error {
+	var k, v []byte
+	if to == nil {
+		k, v = c.Last()
+	} else {
+		// Seek lands on the first key >= to, which is past the range unless equal
+		k, v = c.Seek(to)
+		if k == nil {
+			k, v = c.Last()
+		} else if bytes.Compare(k, to) > 0 {
+			k, v = c.Prev()
+		}
+	}
+
+	for ; k != nil; k, v = c.Prev() {
+		if from != nil && bytes.Compare(k, from) < 0 {
+			return nil
+		}
+		stop, err := fn(k, v)
+		if err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+// pager applies offset and limit to a stream of matching records; a
+// non-positive limit means no limit
+type pager struct {
+	offset int
+	limit  int
+	seen   int
+}
+
+// take counts a match and reports whether it falls inside the page
+func (p *pager) take() bool {
+	p.seen++
+	return p.seen > p.offset
+}
+
+// full reports whether the page is complete
+func (p *pager) full() bool {
+	return p.limit > 0 && p.seen >= p.offset+p.limit
+}
+
+// timeLayouts are the formats accepted for the string time bounds of the ports
+// interfaces, matching what PostgreSQL accepts for a ::timestamp cast
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses a time bound passed as a string
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
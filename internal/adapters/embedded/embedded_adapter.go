@@ -0,0 +1,458 @@
+package embedded
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// compactTxMaxSize bounds the size of each copy transaction during OptimizeDatabase
+const compactTxMaxSize = 64 << 20
+
+// EmbeddedAdapter implements the DatabaseAdapter interface on a single bbolt
+// file, for edge deployments without a database server. bbolt allows one
+// writer at a time: an open Transaction blocks writes made through the
+// adapter's own repositories until it is committed or rolled back.
+type EmbeddedAdapter struct {
+	// mu guards db; OptimizeDatabase holds it exclusively while it swaps the file
+	mu                sync.RWMutex
+	db                *bolt.DB
+	config            *ports.EmbeddedConfig
+	imeiRepo          ports.IMEIRepository
+	auditRepo         ports.AuditRepository
+	extendedAuditRepo ports.ExtendedAuditRepository
+	historyRepo       ports.HistoryRepository
+	snapshotRepo      ports.SnapshotRepository
+}
+
+// NewEmbeddedAdapter creates a new embedded database adapter
+func NewEmbeddedAdapter(config *ports.EmbeddedConfig) *EmbeddedAdapter {
+	a := &EmbeddedAdapter{config: config}
+	a.imeiRepo = NewIMEIRepository(a)
+	a.auditRepo = NewAuditRepository(a)
+	a.extendedAuditRepo = NewExtendedAuditRepository(a)
+	a.historyRepo = NewHistoryRepository(a)
+	a.snapshotRepo = NewSnapshotRepository(a)
+	return a
+}
+
+var _ ports.DatabaseAdapter = (*EmbeddedAdapter)(nil)
+
+// Connect opens (or creates) the database file and its buckets
+func (a *EmbeddedAdapter) Connect(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.db != nil {
+		return nil
+	}
+	db, err := a.open()
+	if err != nil {
+		return err
+	}
+	a.db = db
+	return nil
+}
+
+func (a *EmbeddedAdapter) open() (*bolt.DB, error) {
+	if dir := filepath.Dir(a.config.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := bolt.Open(a.config.Path, 0o600, &bolt.Options{
+		Timeout: time.Duration(a.config.LockTimeout) * time.Second,
+		NoSync:  a.config.NoSync,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		unindexed := tx.Bucket(bucketHistoryByList) == nil
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", name, err)
+			}
+		}
+		if unindexed {
+			if err := indexListHistory(tx); err != nil {
+				return fmt.Errorf("failed to index list history: %w", err)
+			}
+		}
+		return migrateListColors(tx)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrateListColors rewrites IMEI_INFO and TAC_INFO entries stored with the
+// legacy colors of their list ("b", "black", ...) under the status they
+// stand for. Entries already holding a status name are left untouched.
+func migrateListColors(tx *bolt.Tx) error {
+	lists := []struct {
+		name []byte
+		list models.StatusList
+	}{{bucketImeiInfo, models.ImeiList}, {bucketTacInfo, models.TacList}}
+	for _, l := range lists {
+		bucket := tx.Bucket(l.name)
+		migrated := make(map[string][]byte)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var entry map[string]json.RawMessage
+			var color string
+			if json.Unmarshal(v, &entry) != nil || json.Unmarshal(entry["Color"], &color) != nil {
+				return nil
+			}
+			status, err := models.ParseEquipmentStatus(l.list, color)
+			if err != nil || string(status) == color {
+				return nil
+			}
+			entry["Color"], _ = json.Marshal(status)
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", k, err)
+			}
+			migrated[string(k)] = data
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, data := range migrated {
+			if err := bucket.Put([]byte(k), data); err != nil {
+				return fmt.Errorf("failed to migrate %s colors: %w", l.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Disconnect closes the database file
+func (a *EmbeddedAdapter) Disconnect(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.db == nil {
+		return nil
+	}
+	err := a.db.Close()
+	a.db = nil
+	return err
+}
+
+// Ping checks that the database is open and readable
+func (a *EmbeddedAdapter) Ping(ctx context.Context) error {
+	return a.view(func(tx *bolt.Tx) error { return nil })
+}
+
+// GetType returns the database type
+func (a *EmbeddedAdapter) GetType() ports.DatabaseType {
+	return ports.DatabaseTypeEmbedded
+}
+
+func (a *EmbeddedAdapter) view(fn func(tx *bolt.Tx) error) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.db == nil {
+		return errNotConnected
+	}
+	return a.db.View(fn)
+}
+
+func (a *EmbeddedAdapter) update(fn func(tx *bolt.Tx) error) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.db == nil {
+		return errNotConnected
+	}
+	return a.db.Update(fn)
+}
+
+// BeginTransaction starts a read-write bbolt transaction. It must be committed
+// or rolled back from the goroutine that uses it.
+func (a *EmbeddedAdapter) BeginTransaction(ctx context.Context) (ports.Transaction, error) {
+	a.mu.RLock()
+	if a.db == nil {
+		a.mu.RUnlock()
+		return nil, errNotConnected
+	}
+
+	tx, err := a.db.Begin(true)
+	if err != nil {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	s := txStore{tx: tx}
+	return &embeddedTransaction{
+		tx:          tx,
+		release:     a.mu.RUnlock,
+		imeiRepo:    NewIMEIRepository(s),
+		auditRepo:   NewAuditRepository(s),
+		historyRepo: NewHistoryRepository(s),
+	}, nil
+}
+
+// GetIMEIRepository returns the IMEI repository
+func (a *EmbeddedAdapter) GetIMEIRepository() ports.IMEIRepository {
+	return a.imeiRepo
+}
+
+// GetAuditRepository returns the audit repository
+func (a *EmbeddedAdapter) GetAuditRepository() ports.AuditRepository {
+	return a.auditRepo
+}
+
+// GetExtendedAuditRepository returns the extended audit repository
+func (a *EmbeddedAdapter) GetExtendedAuditRepository() ports.ExtendedAuditRepository {
+	return a.extendedAuditRepo
+}
+
+// GetHistoryRepository returns the history repository
+func (a *EmbeddedAdapter) GetHistoryRepository() ports.HistoryRepository {
+	return a.historyRepo
+}
+
+// GetSnapshotRepository returns the snapshot repository
+func (a *EmbeddedAdapter) GetSnapshotRepository() ports.SnapshotRepository {
+	return a.snapshotRepo
+}
+
+// GetAuditChainStore returns the audit hash chain of the store
+func (a *EmbeddedAdapter) GetAuditChainStore() ports.AuditChainStore {
+	return NewAuditChainStore(a)
+}
+
+// HealthCheck verifies the database is open and every bucket is present
+func (a *EmbeddedAdapter) HealthCheck(ctx context.Context) error {
+	err := a.view(func(tx *bolt.Tx) error {
+		for _, name := range allBuckets {
+			if tx.Bucket(name) == nil {
+				return fmt.Errorf("bucket %s is missing", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	return nil
+}
+
+// GetConnectionStats returns database connection statistics. The file has a
+// single handle, so the connection counts are 0 or 1.
+func (a *EmbeddedAdapter) GetConnectionStats() ports.ConnectionStats {
+	healthy := a.Ping(context.Background()) == nil
+	open := 0
+	if healthy {
+		open = 1
+	}
+
+	return ports.ConnectionStats{
+		OpenConnections:  open,
+		IdleConnections:  0,
+		MaxConnections:   1,
+		DatabaseType:     string(ports.DatabaseTypeEmbedded),
+		ConnectionString: a.config.Path,
+		Healthy:          healthy,
+	}
+}
+
+// PurgeOldAudits removes audit logs older than the specified date
+func (a *EmbeddedAdapter) PurgeOldAudits(ctx context.Context, beforeDate string) (int64, error) {
+	before, err := parseTime(beforeDate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old audits: %w", err)
+	}
+
+	purged, err := a.purgeBefore(bucketAuditLog, bucketAuditLogByIMEI, before, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old audits: %w", err)
+	}
+	return purged, nil
+}
+
+// PurgeOldHistory removes history records older than the specified date
+func (a *EmbeddedAdapter) PurgeOldHistory(ctx context.Context, beforeDate string) (int64, error) {
+	before, err := parseTime(beforeDate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old history: %w", err)
+	}
+
+	purged, err := a.purgeBefore(bucketHistory, bucketHistoryByIMEI, before, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old history: %w", err)
+	}
+	return purged, nil
+}
+
+// PurgeAuditsBatch removes at most limit audit logs older than before, oldest first
+func (a *EmbeddedAdapter) PurgeAuditsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	purged, err := a.purgeBefore(bucketAuditLog, bucketAuditLogByIMEI, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old audits: %w", err)
+	}
+	return purged, nil
+}
+
+// PurgeHistoryBatch removes at most limit history records older than before, oldest first
+func (a *EmbeddedAdapter) PurgeHistoryBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	purged, err := a.purgeBefore(bucketHistory, bucketHistoryByIMEI, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old history: %w", err)
+	}
+	return purged, nil
+}
+
+// PurgeSnapshotsBatch removes at most limit snapshots older than before, in ID order
+func (a *EmbeddedAdapter) PurgeSnapshotsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return deleteSnapshotsBefore(a, before, limit)
+}
+
+// purgeBefore deletes up to limit time-keyed records older than before, with
+// their IMEI index entries and, for history, their list index entries; a
+// limit of 0 deletes them all
+func (a *EmbeddedAdapter) purgeBefore(records, index []byte, before time.Time, limit int) (int64, error) {
+	var purged int64
+	err := a.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(records)
+		end := timeKey(before, 0)
+
+		// Collect first: deleting under a live cursor skips the following key
+		var keys, indexKeys, listKeys [][]byte
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k, end) < 0 && (limit <= 0 || len(keys) < limit); k, v = c.Next() {
+			var row struct {
+				IMEI          string               `json:"imei"`
+				ChangeDetails models.ChangeDetails `json:"change_details"`
+			}
+			if err := json.Unmarshal(v, &row); err != nil {
+				return fmt.Errorf("failed to decode record: %w", err)
+			}
+			key := append([]byte{}, k...)
+			keys = append(keys, key)
+			indexKeys = append(indexKeys, indexKey(row.IMEI, key))
+			if listKey := listIndexKey(row.ChangeDetails, key); listKey != nil {
+				listKeys = append(listKeys, listKey)
+			}
+		}
+
+		for i, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			if err := tx.Bucket(index).Delete(indexKeys[i]); err != nil {
+				return err
+			}
+		}
+		for _, listKey := range listKeys {
+			if err := tx.Bucket(bucketHistoryByList).Delete(listKey); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(keys))
+		return nil
+	})
+	return purged, err
+}
+
+// OptimizeDatabase compacts the file into a fresh copy and swaps it in,
+// returning the pages freed by deletes to the filesystem. Reads and writes
+// wait until the swap completes.
+func (a *EmbeddedAdapter) OptimizeDatabase(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.db == nil {
+		return errNotConnected
+	}
+
+	tmpPath := a.config.Path + ".compact"
+	_ = os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to create compaction file: %w", err)
+	}
+	if err := bolt.Compact(dst, a.db, compactTxMaxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to close compaction file: %w", err)
+	}
+
+	if err := a.db.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	a.db = nil
+	if err := os.Rename(tmpPath, a.config.Path); err != nil {
+		_ = os.Remove(tmpPath)
+		// The original file is untouched; reopen it so the adapter stays usable
+		if db, openErr := a.open(); openErr == nil {
+			a.db = db
+		}
+		return fmt.Errorf("failed to replace database file: %w", err)
+	}
+
+	db, err := a.open()
+	if err != nil {
+		return err
+	}
+	a.db = db
+	return nil
+}
+
+// embeddedTransaction implements the Transaction interface
+type embeddedTransaction struct {
+	tx          *bolt.Tx
+	release     func()
+	once        sync.Once
+	imeiRepo    ports.IMEIRepository
+	auditRepo   ports.AuditRepository
+	historyRepo ports.HistoryRepository
+}
+
+// Commit commits the transaction
+func (t *embeddedTransaction) Commit(ctx context.Context) error {
+	defer t.once.Do(t.release)
+	return t.tx.Commit()
+}
+
+// Rollback rolls back the transaction
+func (t *embeddedTransaction) Rollback(ctx context.Context) error {
+	defer t.once.Do(t.release)
+	return t.tx.Rollback()
+}
+
+// GetIMEIRepository returns a transactional IMEI repository
+func (t *embeddedTransaction) GetIMEIRepository() ports.IMEIRepository {
+	return t.imeiRepo
+}
+
+// GetAuditRepository returns a transactional audit repository
+func (t *embeddedTransaction) GetAuditRepository() ports.AuditRepository {
+	return t.auditRepo
+}
+
+// GetHistoryRepository returns a transactional history repository
+func (t *embeddedTransaction) GetHistoryRepository() ports.HistoryRepository {
+	return t.historyRepo
+}
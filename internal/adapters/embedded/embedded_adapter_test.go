@@ -0,0 +1,207 @@
+package embedded
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestEmbeddedAdapter_PersistsAcrossReconnect(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+
+	require.NoError(t, adapter.GetIMEIRepository().Create(ctx, &models.Equipment{
+		IMEI:   "123456789012345",
+		Status: models.EquipmentStatusGreylisted,
+	}))
+	require.NoError(t, adapter.Disconnect(ctx))
+	assert.Error(t, adapter.Ping(ctx))
+
+	require.NoError(t, adapter.Connect(ctx))
+	require.NoError(t, adapter.HealthCheck(ctx))
+	got, err := adapter.GetIMEIRepository().GetByIMEI(ctx, "123456789012345")
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusGreylisted, got.Status)
+
+	stats := adapter.GetConnectionStats()
+	assert.True(t, stats.Healthy)
+	assert.Equal(t, string(ports.DatabaseTypeEmbedded), stats.DatabaseType)
+}
+
+func TestEmbeddedAdapter_MigratesListColors(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+
+	// Entries written before the lists stored status names
+	require.NoError(t, adapter.update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketImeiInfo).Put([]byte("35000000"), []byte(`{"StartIMEI":"35000000","EndIMEI":[" "],"Color":"b","Version":3}`)); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketTacInfo).Put([]byte("35-35"), []byte(`{"KeyTac":"35-35","StartRangeTac":"35","EndRangeTac":"35","Color":"grey","Version":1}`))
+	}))
+	require.NoError(t, adapter.Disconnect(ctx))
+	require.NoError(t, adapter.Connect(ctx))
+
+	var stored []byte
+	require.NoError(t, adapter.view(func(tx *bolt.Tx) error {
+		stored = append(stored, tx.Bucket(bucketImeiInfo).Get([]byte("35000000"))...)
+		return nil
+	}))
+	assert.Contains(t, string(stored), `"Color":"BLACKLISTED"`)
+	assert.Contains(t, string(stored), `"Version":3`)
+
+	tac, ok := adapter.GetIMEIRepository().LookupTacInfo(ctx, "35-35")
+	require.True(t, ok)
+	assert.Equal(t, models.EquipmentStatusGreylisted, tac.Color)
+}
+
+func TestEmbeddedAdapter_IndexesListHistory(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+
+	require.NoError(t, adapter.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{
+		IMEI:       "35000000",
+		ChangeType: models.ChangeTypeCreate,
+		ChangedAt:  time.Now().Add(-time.Hour),
+		ChangedBy:  "tester",
+		ChangeDetails: models.ChangeDetails{
+			"list":       "imei",
+			"start_imei": "35000000000000",
+			"color":      "BLACKLISTED",
+		},
+	}))
+	// A store written before the list index existed
+	require.NoError(t, adapter.update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(bucketHistoryByList)
+	}))
+	require.NoError(t, adapter.Disconnect(ctx))
+	require.NoError(t, adapter.Connect(ctx))
+
+	got, err := adapter.GetHistoryRepository().GetListHistory(ctx, "35000000000000", "35000000", time.Now())
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "35000000", got[0].IMEI)
+}
+
+func TestEmbeddedAdapter_Transaction(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+
+	tx, err := adapter.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().Create(ctx, &models.Equipment{IMEI: "111111111111111"}))
+	require.NoError(t, tx.GetAuditRepository().LogCheck(ctx, &models.AuditLog{IMEI: "111111111111111"}))
+	require.NoError(t, tx.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{
+		IMEI: "111111111111111", ChangeType: models.ChangeTypeCreate,
+	}))
+	require.NoError(t, tx.Rollback(ctx))
+
+	_, err = adapter.GetIMEIRepository().GetByIMEI(ctx, "111111111111111")
+	assert.ErrorIs(t, err, ErrNotFound)
+	audits, err := adapter.GetAuditRepository().GetAuditsByIMEI(ctx, "111111111111111", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, audits)
+	changes, err := adapter.GetHistoryRepository().GetHistoryByIMEI(ctx, "111111111111111", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	tx, err = adapter.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().Create(ctx, &models.Equipment{IMEI: "222222222222222"}))
+	// Reads inside the transaction see its own writes
+	_, err = tx.GetIMEIRepository().GetByIMEI(ctx, "222222222222222")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+
+	_, err = adapter.GetIMEIRepository().GetByIMEI(ctx, "222222222222222")
+	assert.NoError(t, err)
+}
+
+func TestEmbeddedAdapter_PurgeBatches(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+	var _ ports.BatchPurger = adapter
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		at := base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, adapter.GetAuditRepository().LogCheck(ctx, &models.AuditLog{IMEI: "123456789012345", CheckTime: at}))
+		require.NoError(t, adapter.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{
+			IMEI: "123456789012345", ChangeType: models.ChangeTypeUpdate, ChangedAt: at,
+		}))
+		require.NoError(t, adapter.GetSnapshotRepository().CreateSnapshot(ctx, &models.EquipmentSnapshot{
+			IMEI: "123456789012345", SnapshotTime: at,
+		}))
+	}
+	cutoff := base.Add(4 * time.Hour)
+
+	for name, purge := range map[string]func(context.Context, time.Time, int) (int64, error){
+		"audits":    adapter.PurgeAuditsBatch,
+		"history":   adapter.PurgeHistoryBatch,
+		"snapshots": adapter.PurgeSnapshotsBatch,
+	} {
+		var batches []int64
+		for {
+			purged, err := purge(ctx, cutoff, 3)
+			require.NoError(t, err, name)
+			batches = append(batches, purged)
+			if purged < 3 {
+				break
+			}
+		}
+		assert.Equal(t, []int64{3, 1}, batches, name)
+	}
+
+	remaining, err := adapter.GetAuditRepository().GetAuditsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.True(t, remaining[0].CheckTime.Equal(cutoff))
+	snapshots, err := adapter.GetSnapshotRepository().GetSnapshotsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+}
+
+func TestEmbeddedAdapter_PurgeAndOptimize(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	ctx := context.Background()
+	audits := adapter.GetAuditRepository()
+	history := adapter.GetHistoryRepository()
+
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{old, recent} {
+		require.NoError(t, audits.LogCheck(ctx, &models.AuditLog{IMEI: "123456789012345", CheckTime: at}))
+		require.NoError(t, history.RecordChange(ctx, &models.EquipmentHistory{
+			IMEI: "123456789012345", ChangeType: models.ChangeTypeUpdate, ChangedAt: at,
+		}))
+	}
+
+	purged, err := adapter.PurgeOldAudits(ctx, "2025-01-01")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	purged, err = adapter.PurgeOldHistory(ctx, "2025-01-01 00:00:00")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = adapter.PurgeOldAudits(ctx, "yesterday")
+	assert.Error(t, err)
+
+	require.NoError(t, adapter.OptimizeDatabase(ctx))
+
+	// The purged rows are gone from both the records and the IMEI index
+	remaining, err := audits.GetAuditsByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.True(t, remaining[0].CheckTime.Equal(recent))
+
+	changes, err := history.GetHistoryByIMEI(ctx, "123456789012345", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.True(t, changes[0].ChangedAt.Equal(recent))
+}
@@ -0,0 +1,122 @@
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// extendedAuditRepository implements the ExtendedAuditRepository interface on the embedded store
+type extendedAuditRepository struct {
+	*auditRepository
+}
+
+// NewExtendedAuditRepository creates a new embedded extended audit repository
+func NewExtendedAuditRepository(s store) ports.ExtendedAuditRepository {
+	return &extendedAuditRepository{auditRepository: &auditRepository{store: s}}
+}
+
+// LogCheckExtended records an extended equipment check with additional metadata
+func (r *extendedAuditRepository) LogCheckExtended(ctx context.Context, audit *models.AuditLogExtended) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		return putAudit(tx, audit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log extended check: %w", err)
+	}
+	return nil
+}
+
+// GetExtendedAuditsByIMEI retrieves extended audit logs for a specific IMEI
+func (r *extendedAuditRepository) GetExtendedAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLogExtended, error) {
+	var audits []*models.AuditLogExtended
+	err := r.store.view(func(tx *bolt.Tx) error {
+		var err error
+		audits, err = auditsByIMEI(tx, imei, offset, limit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extended audits: %w", err)
+	}
+	return audits, nil
+}
+
+// GetAuditsByRequestSource retrieves audits filtered by request source
+func (r *extendedAuditRepository) GetAuditsByRequestSource(ctx context.Context, requestSource string, offset, limit int) ([]*models.AuditLog, error) {
+	audits := make([]*models.AuditLog, 0)
+	p := pager{offset: offset, limit: limit}
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		return descend(tx.Bucket(bucketAuditLog).Cursor(), nil, nil, func(_, v []byte) (bool, error) {
+			var audit models.AuditLogExtended
+			if err := json.Unmarshal(v, &audit); err != nil {
+				return true, fmt.Errorf("failed to decode audit: %w", err)
+			}
+			if audit.RequestSource == requestSource && p.take() {
+				audits = append(audits, &audit.AuditLog)
+			}
+			return p.full(), nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by request source: %w", err)
+	}
+	return audits, nil
+}
+
+// GetAuditStatistics retrieves aggregated audit statistics
+func (r *extendedAuditRepository) GetAuditStatistics(ctx context.Context, startTime, endTime time.Time) (map[string]interface{}, error) {
+	var total, whitelisted, blacklisted, greylisted, diameter, httpChecks int64
+	var processingTotal, processingCount int64
+	imeis := make(map[string]struct{})
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		return auditsInRange(tx, startTime, endTime, func(audit *models.AuditLogExtended) bool {
+			total++
+			imeis[audit.IMEI] = struct{}{}
+			switch audit.Status {
+			case models.EquipmentStatusWhitelisted:
+				whitelisted++
+			case models.EquipmentStatusBlacklisted:
+				blacklisted++
+			case models.EquipmentStatusGreylisted:
+				greylisted++
+			}
+			switch audit.RequestSource {
+			case "DIAMETER_S13":
+				diameter++
+			case "HTTP_5G":
+				httpChecks++
+			}
+			if audit.ProcessingTimeMs != nil {
+				processingTotal += *audit.ProcessingTimeMs
+				processingCount++
+			}
+			return false
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit statistics: %w", err)
+	}
+
+	avgProcessingTime := 0.0
+	if processingCount > 0 {
+		avgProcessingTime = float64(processingTotal) / float64(processingCount)
+	}
+
+	return map[string]interface{}{
+		"total_checks":           total,
+		"unique_imeis":           int64(len(imeis)),
+		"whitelisted_count":      whitelisted,
+		"blacklisted_count":      blacklisted,
+		"greylisted_count":       greylisted,
+		"diameter_checks":        diameter,
+		"http_checks":            httpChecks,
+		"avg_processing_time_ms": avgProcessingTime,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyRepository implements the HistoryRepository interface on the embedded store.
+// equipment_history is keyed by change time, so time ranges are a cursor walk.
+type historyRepository struct {
+	store store
+}
+
+// NewHistoryRepository creates a new embedded history repository
+func NewHistoryRepository(s store) ports.HistoryRepository {
+	return &historyRepository{store: s}
+}
+
+// RecordChange records a change to equipment status or metadata
+func (r *historyRepository) RecordChange(ctx context.Context, history *models.EquipmentHistory) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketHistory)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		history.ID = int64(id)
+		if history.ChangedAt.IsZero() {
+			history.ChangedAt = time.Now()
+		}
+
+		data, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("failed to encode history: %w", err)
+		}
+		key := timeKey(history.ChangedAt, id)
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+		if listKey := listIndexKey(history.ChangeDetails, key); listKey != nil {
+			if err := tx.Bucket(bucketHistoryByList).Put(listKey, nil); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketHistoryByIMEI).Put(indexKey(history.IMEI, key), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+	return nil
+}
+
+// GetHistoryByIMEI retrieves change history for a specific IMEI
+func (r *historyRepository) GetHistoryByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	history := make([]*models.EquipmentHistory, 0)
+	p := pager{offset: offset, limit: limit}
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucketHistory)
+		from, to := indexRange(imei)
+		return descend(tx.Bucket(bucketHistoryByIMEI).Cursor(), from, to, func(k, _ []byte) (bool, error) {
+			if !p.take() {
+				return false, nil
+			}
+			var entry models.EquipmentHistory
+			if err := json.Unmarshal(records.Get(k[len(from):]), &entry); err != nil {
+				return true, fmt.Errorf("failed to decode history: %w", err)
+			}
+			history = append(history, &entry)
+			return p.full(), nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history by IMEI: %w", err)
+	}
+	return history, nil
+}
+
+// GetHistoryByTimeRange retrieves change history within a time range
+func (r *historyRepository) GetHistoryByTimeRange(ctx context.Context, startTime, endTime time.Time, offset, limit int) ([]*models.EquipmentHistory, error) {
+	from, to := timeKeyRange(startTime, endTime)
+	history, err := r.scan(from, to, offset, limit, func(*models.EquipmentHistory) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history by time range: %w", err)
+	}
+	return history, nil
+}
+
+// GetHistoryByChangeType retrieves history filtered by change type
+func (r *historyRepository) GetHistoryByChangeType(ctx context.Context, changeType models.ChangeType, offset, limit int) ([]*models.EquipmentHistory, error) {
+	history, err := r.scan(nil, nil, offset, limit, func(h *models.EquipmentHistory) bool {
+		return h.ChangeType == changeType
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history by change type: %w", err)
+	}
+	return history, nil
+}
+
+// GetListHistory retrieves, oldest first, the changes made at or before until
+// to the IMEI_INFO entry of startImei and to the TAC ranges enclosing tac
+func (r *historyRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	history := make([]*models.EquipmentHistory, 0)
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucketHistory)
+		collect := func(from, to []byte) error {
+			return descend(tx.Bucket(bucketHistoryByList).Cursor(), from, to, func(k, _ []byte) (bool, error) {
+				var entry models.EquipmentHistory
+				if err := json.Unmarshal(records.Get(k[len(k)-16:]), &entry); err != nil {
+					return true, fmt.Errorf("failed to decode history: %w", err)
+				}
+				if !entry.ChangedAt.After(until) && ports.InListHistory(&entry, startImei, tac) {
+					history = append(history, &entry)
+				}
+				return false, nil
+			})
+		}
+
+		if err := collect(indexRange("imei:" + startImei)); err != nil {
+			return err
+		}
+		// TAC keys hold equally padded bounds, so the ranges starting at or
+		// before tac sort up to tac + "-\uffff"
+		return collect([]byte("tac:"), indexKey("tac:"+tac+"-\uffff", nil))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list history: %w", err)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].ChangedAt.Equal(history[j].ChangedAt) {
+			return history[i].ChangedAt.Before(history[j].ChangedAt)
+		}
+		return history[i].ID < history[j].ID
+	})
+	return history, nil
+}
+
+// listIndexKey returns the bucketHistoryByList entry of the list history entry
+// stored under key: "imei:" and its start IMEI, or "tac:" and its key_tac. It
+// returns nil for changes to equipment records.
+func listIndexKey(details models.ChangeDetails, key []byte) []byte {
+	switch list, _ := details["list"].(string); list {
+	case "imei":
+		start, _ := details["start_imei"].(string)
+		return indexKey("imei:"+start, key)
+	case "tac":
+		keyTac, _ := details["key_tac"].(string)
+		return indexKey("tac:"+keyTac, key)
+	}
+	return nil
+}
+
+// indexListHistory fills bucketHistoryByList from the history recorded before
+// the bucket existed
+func indexListHistory(tx *bolt.Tx) error {
+	index := tx.Bucket(bucketHistoryByList)
+	return tx.Bucket(bucketHistory).ForEach(func(k, v []byte) error {
+		var row struct {
+			ChangeDetails models.ChangeDetails `json:"change_details"`
+		}
+		if err := json.Unmarshal(v, &row); err != nil {
+			return fmt.Errorf("failed to decode history: %w", err)
+		}
+		if listKey := listIndexKey(row.ChangeDetails, k); listKey != nil {
+			return index.Put(listKey, nil)
+		}
+		return nil
+	})
+}
+
+// scan pages through the matching history entries keyed within [from, to], newest first
+func (r *historyRepository) scan(from, to []byte, offset, limit int, match func(*models.EquipmentHistory) bool) ([]*models.EquipmentHistory, error) {
+	history := make([]*models.EquipmentHistory, 0)
+	p := pager{offset: offset, limit: limit}
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		return descend(tx.Bucket(bucketHistory).Cursor(), from, to, func(_, v []byte) (bool, error) {
+			var entry models.EquipmentHistory
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return true, fmt.Errorf("failed to decode history: %w", err)
+			}
+			if match(&entry) && p.take() {
+				history = append(history, &entry)
+			}
+			return p.full(), nil
+		})
+	})
+	return history, err
+}
@@ -0,0 +1,527 @@
+package embedded
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+var (
+	ErrNotFound      = errors.New("equipment not found")
+	ErrAlreadyExists = errors.New("equipment already exists")
+)
+
+// imeiRepository implements the IMEIRepository interface on the embedded store
+type imeiRepository struct {
+	store store
+}
+
+// NewIMEIRepository creates a new embedded IMEI repository
+func NewIMEIRepository(s store) ports.IMEIRepository {
+	return &imeiRepository{store: s}
+}
+
+// getEquipment loads the equipment stored under imei
+func getEquipment(tx *bolt.Tx, imei string) (*models.Equipment, error) {
+	data := tx.Bucket(bucketEquipment).Get([]byte(imei))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var equipment models.Equipment
+	if err := json.Unmarshal(data, &equipment); err != nil {
+		return nil, fmt.Errorf("failed to decode equipment: %w", err)
+	}
+	return &equipment, nil
+}
+
+// putEquipment stores equipment and keeps the IMEISV index in step with it
+func putEquipment(tx *bolt.Tx, previous, equipment *models.Equipment) error {
+	data, err := json.Marshal(equipment)
+	if err != nil {
+		return fmt.Errorf("failed to encode equipment: %w", err)
+	}
+	if err := tx.Bucket(bucketEquipment).Put([]byte(equipment.IMEI), data); err != nil {
+		return err
+	}
+
+	index := tx.Bucket(bucketEquipmentIMEISV)
+	if previous != nil && previous.IMEISV != nil {
+		if err := index.Delete([]byte(*previous.IMEISV)); err != nil {
+			return err
+		}
+	}
+	if equipment.IMEISV != nil {
+		return index.Put([]byte(*equipment.IMEISV), []byte(equipment.IMEI))
+	}
+	return nil
+}
+
+// GetByIMEI retrieves equipment by IMEI
+func (r *imeiRepository) GetByIMEI(ctx context.Context, imei string) (*models.Equipment, error) {
+	var equipment *models.Equipment
+	err := r.store.view(func(tx *bolt.Tx) error {
+		var err error
+		equipment, err = getEquipment(tx, imei)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get equipment: %w", err)
+	}
+	return equipment, nil
+}
+
+// GetByIMEISV retrieves equipment by IMEISV
+func (r *imeiRepository) GetByIMEISV(ctx context.Context, imeisv string) (*models.Equipment, error) {
+	var equipment *models.Equipment
+	err := r.store.view(func(tx *bolt.Tx) error {
+		imei := tx.Bucket(bucketEquipmentIMEISV).Get([]byte(imeisv))
+		if imei == nil {
+			return ErrNotFound
+		}
+		var err error
+		equipment, err = getEquipment(tx, string(imei))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get equipment by IMEISV: %w", err)
+	}
+	return equipment, nil
+}
+
+// Create adds a new equipment record
+func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketEquipment)
+		if bucket.Get([]byte(equipment.IMEI)) != nil {
+			return ErrAlreadyExists
+		}
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		equipment.ID = int64(id)
+		equipment.LastUpdated = time.Now()
+		equipment.Version = 1
+
+		return putEquipment(tx, nil, equipment)
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyExists) {
+			return ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to create equipment: %w", err)
+	}
+	return nil
+}
+
+// Update updates an existing equipment record. Like the other backends it
+// leaves the ID, check counters and AddedBy untouched.
+func (r *imeiRepository) Update(ctx context.Context, equipment *models.Equipment) error {
+	var version int64
+	err := r.store.update(func(tx *bolt.Tx) error {
+		existing, err := getEquipment(tx, equipment.IMEI)
+		if err != nil {
+			return err
+		}
+		if version, err = ports.NextVersion("equipment", equipment.IMEI, equipment.Version, existing.Version); err != nil {
+			return err
+		}
+
+		equipment.LastUpdated = time.Now()
+		updated := *existing
+		updated.Version = version
+		updated.IMEISV = equipment.IMEISV
+		updated.Status = equipment.Status
+		updated.Reason = equipment.Reason
+		updated.LastUpdated = equipment.LastUpdated
+		updated.Metadata = equipment.Metadata
+		updated.ManufacturerTAC = equipment.ManufacturerTAC
+		updated.ManufacturerName = equipment.ManufacturerName
+		updated.DeletedAt = equipment.DeletedAt
+		updated.DeletedBy = equipment.DeletedBy
+
+		return putEquipment(tx, existing, &updated)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to update equipment: %w", err)
+	}
+	equipment.Version = version
+	return nil
+}
+
+// Delete removes an equipment record
+func (r *imeiRepository) Delete(ctx context.Context, imei string) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		existing, err := getEquipment(tx, imei)
+		if err != nil {
+			return err
+		}
+		if existing.IMEISV != nil {
+			if err := tx.Bucket(bucketEquipmentIMEISV).Delete([]byte(*existing.IMEISV)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketEquipment).Delete([]byte(imei))
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete equipment: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeleted removes tombstones deleted before the given time, in IMEI order
+func (r *imeiRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+	err := r.store.update(func(tx *bolt.Tx) error {
+		var expired []*models.Equipment
+		c := tx.Bucket(bucketEquipment).Cursor()
+		for k, v := c.First(); k != nil && len(expired) < limit; k, v = c.Next() {
+			var equipment models.Equipment
+			if err := json.Unmarshal(v, &equipment); err != nil {
+				return fmt.Errorf("failed to decode equipment: %w", err)
+			}
+			if equipment.IsDeleted() && equipment.DeletedAt.Before(before) {
+				expired = append(expired, &equipment)
+			}
+		}
+
+		for _, equipment := range expired {
+			if equipment.IMEISV != nil {
+				if err := tx.Bucket(bucketEquipmentIMEISV).Delete([]byte(*equipment.IMEISV)); err != nil {
+					return err
+				}
+			}
+			if err := tx.Bucket(bucketEquipment).Delete([]byte(equipment.IMEI)); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted equipment: %w", err)
+	}
+	return purged, nil
+}
+
+// List retrieves equipment with pagination, ordered by IMEI, skipping tombstones
+func (r *imeiRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(offset, limit, func(e *models.Equipment) bool { return !e.IsDeleted() })
+}
+
+// ListWithDeleted retrieves equipment with pagination, ordered by IMEI
+func (r *imeiRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(offset, limit, func(*models.Equipment) bool { return true })
+}
+
+// ListByStatus retrieves equipment by status, ordered by IMEI, skipping tombstones
+func (r *imeiRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(offset, limit, func(e *models.Equipment) bool { return e.Status == status && !e.IsDeleted() })
+}
+
+func (r *imeiRepository) list(offset, limit int, match func(*models.Equipment) bool) ([]*models.Equipment, error) {
+	result := make([]*models.Equipment, 0)
+	p := pager{offset: offset, limit: limit}
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketEquipment).Cursor()
+		for k, v := c.First(); k != nil && !p.full(); k, v = c.Next() {
+			var equipment models.Equipment
+			if err := json.Unmarshal(v, &equipment); err != nil {
+				return fmt.Errorf("failed to decode equipment: %w", err)
+			}
+			if match(&equipment) && p.take() {
+				result = append(result, &equipment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list equipment: %w", err)
+	}
+	return result, nil
+}
+
+// IncrementCheckCount atomically increments the check counter
+func (r *imeiRepository) IncrementCheckCount(ctx context.Context, imei string) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		equipment, err := getEquipment(tx, imei)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		equipment.CheckCount++
+		equipment.LastCheckTime = &now
+		return putEquipment(tx, equipment, equipment)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to increment check count: %w", err)
+	}
+	return nil
+}
+
+// ApplyCheckCounts adds flushed check counts in one transaction
+func (r *imeiRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	err := r.store.update(func(tx *bolt.Tx) error {
+		for _, delta := range deltas {
+			equipment, err := getEquipment(tx, delta.IMEI)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if equipment.IsDeleted() {
+				continue
+			}
+			equipment.AddChecks(delta.Count, delta.LastCheckTime)
+			if err := putEquipment(tx, equipment, equipment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply check counts: %w", err)
+	}
+	return nil
+}
+
+// IMEI logic operations
+
+func (r *imeiRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
+	var info *ports.ImeiInfo
+	_ = r.store.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketImeiInfo).Get([]byte(startRange))
+		if data == nil {
+			return nil
+		}
+		var decoded ports.ImeiInfo
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+		info = &decoded
+		return nil
+	})
+	return info, info != nil
+}
+
+func (r *imeiRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	saved := *info
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketImeiInfo)
+		var current ports.ImeiInfo
+		if err := getVersioned(bucket, info.StartIMEI, &current); err != nil {
+			return err
+		}
+		version, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, current.Version)
+		if err != nil {
+			return err
+		}
+		saved.Version = version
+		return putVersioned(bucket, info.StartIMEI, &saved)
+	})
+	if err != nil {
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to save imei info: %w", err)
+	}
+	info.Version = saved.Version
+	return nil
+}
+
+func (r *imeiRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketImeiInfo)
+		var current ports.ImeiInfo
+		if err := getVersioned(bucket, info.StartIMEI, &current); err != nil {
+			return err
+		}
+		if _, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, current.Version); err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(info.StartIMEI))
+	})
+	if err != nil {
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to delete imei info: %w", err)
+	}
+	return nil
+}
+
+func (r *imeiRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
+	result := make([]*ports.ImeiInfo, 0)
+	_ = r.store.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketImeiInfo).ForEach(func(_, v []byte) error {
+			var info ports.ImeiInfo
+			if err := json.Unmarshal(v, &info); err != nil {
+				return nil
+			}
+			result = append(result, &info)
+			return nil
+		})
+	})
+	return result
+}
+
+func (r *imeiRepository) ClearImeiInfo(ctx context.Context) {
+	_ = r.store.update(func(tx *bolt.Tx) error {
+		return recreateBucket(tx, bucketImeiInfo)
+	})
+}
+
+// TAC logic operations. tac_info is keyed by KeyTac, so bolt's sorted keys
+// give PrevTacInfo/NextTacInfo as a single cursor step.
+
+func (r *imeiRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
+	saved := *info
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketTacInfo)
+		var current ports.TacInfo
+		if err := getVersioned(bucket, info.KeyTac, &current); err != nil {
+			return err
+		}
+		version, err := ports.NextVersion("tac_info", info.KeyTac, info.Version, current.Version)
+		if err != nil {
+			return err
+		}
+		saved.Version = version
+		return putVersioned(bucket, info.KeyTac, &saved)
+	})
+	if err != nil {
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to save tac info: %w", err)
+	}
+	info.Version = saved.Version
+	return nil
+}
+
+// getVersioned decodes the entry stored under key into v, leaving v untouched
+// when there is none
+func getVersioned(bucket *bolt.Bucket, key string, v interface{}) error {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return nil
+}
+
+func putVersioned(bucket *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+func (r *imeiRepository) LookupTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.seekTacInfo(func(c *bolt.Cursor) ([]byte, []byte) {
+		k, v := c.Seek([]byte(key))
+		if !bytes.Equal(k, []byte(key)) {
+			return nil, nil
+		}
+		return k, v
+	})
+}
+
+func (r *imeiRepository) PrevTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.seekTacInfo(func(c *bolt.Cursor) ([]byte, []byte) {
+		if k, _ := c.Seek([]byte(key)); k == nil {
+			return c.Last()
+		}
+		return c.Prev()
+	})
+}
+
+func (r *imeiRepository) NextTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.seekTacInfo(func(c *bolt.Cursor) ([]byte, []byte) {
+		k, v := c.Seek([]byte(key))
+		if bytes.Equal(k, []byte(key)) {
+			return c.Next()
+		}
+		return k, v
+	})
+}
+
+func (r *imeiRepository) seekTacInfo(seek func(c *bolt.Cursor) ([]byte, []byte)) (*ports.TacInfo, bool) {
+	var info *ports.TacInfo
+	_ = r.store.view(func(tx *bolt.Tx) error {
+		k, v := seek(tx.Bucket(bucketTacInfo).Cursor())
+		if k == nil {
+			return nil
+		}
+		var decoded ports.TacInfo
+		if err := json.Unmarshal(v, &decoded); err != nil {
+			return err
+		}
+		info = &decoded
+		return nil
+	})
+	return info, info != nil
+}
+
+func (r *imeiRepository) ListAllTacInfo(ctx context.Context) []*ports.TacInfo {
+	result := make([]*ports.TacInfo, 0)
+	_ = r.store.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketTacInfo).ForEach(func(_, v []byte) error {
+			var info ports.TacInfo
+			if err := json.Unmarshal(v, &info); err != nil {
+				return nil
+			}
+			result = append(result, &info)
+			return nil
+		})
+	})
+	return result
+}
+
+func (r *imeiRepository) ClearTacInfo(ctx context.Context) {
+	_ = r.store.update(func(tx *bolt.Tx) error {
+		return recreateBucket(tx, bucketTacInfo)
+	})
+}
+
+// recreateBucket drops every key of a bucket in one step
+func recreateBucket(tx *bolt.Tx, name []byte) error {
+	if err := tx.DeleteBucket(name); err != nil && !errors.Is(err, berrors.ErrBucketNotFound) {
+		return err
+	}
+	_, err := tx.CreateBucket(name)
+	return err
+}
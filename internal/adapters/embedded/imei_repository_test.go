@@ -0,0 +1,196 @@
+package embedded
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestAdapter(t *testing.T) *EmbeddedAdapter {
+	t.Helper()
+	adapter := NewEmbeddedAdapter(&ports.EmbeddedConfig{
+		Path:        filepath.Join(t.TempDir(), "eir.db"),
+		LockTimeout: 1,
+	})
+	require.NoError(t, adapter.Connect(context.Background()))
+	t.Cleanup(func() { _ = adapter.Disconnect(context.Background()) })
+	return adapter
+}
+
+func TestIMEIRepository_CRUD(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	imeisv := "1234567890123456"
+	equipment := &models.Equipment{
+		IMEI:    "123456789012345",
+		IMEISV:  &imeisv,
+		Status:  models.EquipmentStatusWhitelisted,
+		AddedBy: "test",
+	}
+	require.NoError(t, repo.Create(ctx, equipment))
+	assert.Equal(t, int64(1), equipment.ID)
+	assert.ErrorIs(t, repo.Create(ctx, equipment), ErrAlreadyExists)
+
+	byIMEISV, err := repo.GetByIMEISV(ctx, imeisv)
+	require.NoError(t, err)
+	assert.Equal(t, equipment.IMEI, byIMEISV.IMEI)
+
+	require.NoError(t, repo.IncrementCheckCount(ctx, equipment.IMEI))
+
+	// Update leaves the check counters alone and moves the IMEISV index
+	newIMEISV := "1234567890123499"
+	require.NoError(t, repo.Update(ctx, &models.Equipment{
+		IMEI:   equipment.IMEI,
+		IMEISV: &newIMEISV,
+		Status: models.EquipmentStatusBlacklisted,
+	}))
+	got, err := repo.GetByIMEI(ctx, equipment.IMEI)
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, got.Status)
+	assert.Equal(t, int64(1), got.CheckCount)
+	assert.NotNil(t, got.LastCheckTime)
+	assert.Equal(t, "test", got.AddedBy)
+
+	_, err = repo.GetByIMEISV(ctx, imeisv)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, repo.Delete(ctx, equipment.IMEI))
+	_, err = repo.GetByIMEI(ctx, equipment.IMEI)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.GetByIMEISV(ctx, newIMEISV)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, equipment.IMEI), ErrNotFound)
+}
+
+func TestIMEIRepository_ApplyCheckCounts(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &models.Equipment{
+		IMEI:    "123456789012345",
+		Status:  models.EquipmentStatusWhitelisted,
+		AddedBy: "test",
+	}))
+	before, err := repo.GetByIMEI(ctx, "123456789012345")
+	require.NoError(t, err)
+
+	seen := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.ApplyCheckCounts(ctx, []ports.CheckCountDelta{
+		{IMEI: "123456789012345", Count: 5, LastCheckTime: seen},
+		{IMEI: "999999999999994", Count: 1, LastCheckTime: seen}, // Unknown IMEIs are skipped
+	}))
+	require.NoError(t, repo.ApplyCheckCounts(ctx, []ports.CheckCountDelta{
+		{IMEI: "123456789012345", Count: 2, LastCheckTime: seen.Add(-time.Hour)},
+	}))
+
+	got, err := repo.GetByIMEI(ctx, "123456789012345")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), got.CheckCount)
+	require.NotNil(t, got.LastCheckTime)
+	assert.True(t, got.LastCheckTime.Equal(seen), "an older flush must not move the last check time back")
+	assert.Equal(t, before.Version, got.Version)
+}
+
+func TestIMEIRepository_ListByStatusPaging(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	for i, status := range []models.EquipmentStatus{
+		models.EquipmentStatusBlacklisted,
+		models.EquipmentStatusWhitelisted,
+		models.EquipmentStatusBlacklisted,
+		models.EquipmentStatusBlacklisted,
+	} {
+		imei := "35000000000000" + string(rune('0'+i))
+		require.NoError(t, repo.Create(ctx, &models.Equipment{IMEI: imei, Status: status}))
+	}
+
+	page, err := repo.ListByStatus(ctx, models.EquipmentStatusBlacklisted, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "350000000000002", page[0].IMEI)
+
+	all, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+func TestIMEIRepository_TacNavigation(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	for _, key := range []string{"b", "d", "f"} {
+		require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: key, Color: models.EquipmentStatusBlacklisted}))
+	}
+
+	tests := []struct {
+		name string
+		fn   func(context.Context, string) (*ports.TacInfo, bool)
+		key  string
+		want string
+	}{
+		{"prev of existing key", repo.PrevTacInfo, "d", "b"},
+		{"prev between keys", repo.PrevTacInfo, "e", "d"},
+		{"prev past the end", repo.PrevTacInfo, "z", "f"},
+		{"prev before the start", repo.PrevTacInfo, "b", ""},
+		{"next of existing key", repo.NextTacInfo, "d", "f"},
+		{"next between keys", repo.NextTacInfo, "c", "d"},
+		{"next before the start", repo.NextTacInfo, "a", "b"},
+		{"next past the end", repo.NextTacInfo, "f", ""},
+		{"lookup existing key", repo.LookupTacInfo, "d", "d"},
+		{"lookup missing key", repo.LookupTacInfo, "c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := tt.fn(ctx, tt.key)
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, info.KeyTac)
+		})
+	}
+
+	repo.ClearTacInfo(ctx)
+	assert.Empty(t, repo.ListAllTacInfo(ctx))
+}
+
+func TestIMEIRepository_ImeiInfo(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	info := &ports.ImeiInfo{StartIMEI: "35000000", EndIMEI: []string{"35000000999"}, Color: models.EquipmentStatusGreylisted}
+	require.NoError(t, repo.SaveImeiInfo(ctx, info))
+
+	got, ok := repo.LookupImeiInfo(ctx, "35000000")
+	require.True(t, ok)
+	assert.Equal(t, info.EndIMEI, got.EndIMEI)
+	assert.Equal(t, models.EquipmentStatusGreylisted, got.Color)
+
+	repo.ClearImeiInfo(ctx)
+	_, ok = repo.LookupImeiInfo(ctx, "35000000")
+	assert.False(t, ok)
+}
+
+func TestIMEIRepository_DeleteImeiInfo(t *testing.T) {
+	repo := setupTestAdapter(t).GetIMEIRepository()
+	ctx := context.Background()
+
+	info := &ports.ImeiInfo{StartIMEI: "35000000", EndIMEI: []string{" "}, Color: models.EquipmentStatusBlacklisted}
+	require.NoError(t, repo.SaveImeiInfo(ctx, info))
+
+	stale := &ports.ImeiInfo{StartIMEI: info.StartIMEI, Version: info.Version + 1}
+	assert.ErrorIs(t, repo.DeleteImeiInfo(ctx, stale), ports.ErrVersionConflict)
+
+	require.NoError(t, repo.DeleteImeiInfo(ctx, info))
+	_, ok := repo.LookupImeiInfo(ctx, "35000000")
+	assert.False(t, ok)
+}
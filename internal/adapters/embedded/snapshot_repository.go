@@ -0,0 +1,166 @@
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotRepository implements the SnapshotRepository interface on the embedded store.
+// equipment_snapshots is keyed by ID; the IMEI index is ordered by snapshot time.
+type snapshotRepository struct {
+	store store
+}
+
+// NewSnapshotRepository creates a new embedded snapshot repository
+func NewSnapshotRepository(s store) ports.SnapshotRepository {
+	return &snapshotRepository{store: s}
+}
+
+// CreateSnapshot creates a point-in-time snapshot of equipment
+func (r *snapshotRepository) CreateSnapshot(ctx context.Context, snapshot *models.EquipmentSnapshot) error {
+	err := r.store.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSnapshots)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		snapshot.ID = int64(id)
+		if snapshot.SnapshotTime.IsZero() {
+			snapshot.SnapshotTime = time.Now()
+		}
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+		if err := bucket.Put(idKey(id), data); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketSnapshotsByIMEI).Put(snapshotIndexKey(snapshot), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetSnapshotsByIMEI retrieves snapshots for a specific IMEI, newest first
+func (r *snapshotRepository) GetSnapshotsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error) {
+	snapshots := make([]*models.EquipmentSnapshot, 0)
+	p := pager{offset: offset, limit: limit}
+
+	err := r.store.view(func(tx *bolt.Tx) error {
+		records := tx.Bucket(bucketSnapshots)
+		from, to := indexRange(imei)
+		return descend(tx.Bucket(bucketSnapshotsByIMEI).Cursor(), from, to, func(k, _ []byte) (bool, error) {
+			if !p.take() {
+				return false, nil
+			}
+			// The index entry ends with the snapshot's time key, whose last 8 bytes are its ID
+			var snapshot models.EquipmentSnapshot
+			if err := json.Unmarshal(records.Get(k[len(k)-8:]), &snapshot); err != nil {
+				return true, fmt.Errorf("failed to decode snapshot: %w", err)
+			}
+			snapshots = append(snapshots, &snapshot)
+			return p.full(), nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots by IMEI: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetSnapshotByID retrieves a specific snapshot
+func (r *snapshotRepository) GetSnapshotByID(ctx context.Context, id int64) (*models.EquipmentSnapshot, error) {
+	var snapshot models.EquipmentSnapshot
+	err := r.store.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketSnapshots).Get(idKey(uint64(id)))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &snapshot)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// GetLatestSnapshot retrieves the latest snapshot of imei taken at or before at
+func (r *snapshotRepository) GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error) {
+	var latest *models.EquipmentSnapshot
+	err := r.store.view(func(tx *bolt.Tx) error {
+		from, _ := indexRange(imei)
+		to := indexKey(imei, timeKey(at, ^uint64(0)))
+		return descend(tx.Bucket(bucketSnapshotsByIMEI).Cursor(), from, to, func(k, _ []byte) (bool, error) {
+			var snapshot models.EquipmentSnapshot
+			if err := json.Unmarshal(tx.Bucket(bucketSnapshots).Get(k[len(k)-8:]), &snapshot); err != nil {
+				return true, fmt.Errorf("failed to decode snapshot: %w", err)
+			}
+			latest = &snapshot
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+	return latest, nil
+}
+
+// DeleteOldSnapshots removes snapshots older than the specified date
+func (r *snapshotRepository) DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	return deleteSnapshotsBefore(r.store, before, 0)
+}
+
+// deleteSnapshotsBefore removes up to limit snapshots older than before, in ID
+// order; a limit of 0 removes them all
+func deleteSnapshotsBefore(s store, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSnapshots)
+		index := tx.Bucket(bucketSnapshotsByIMEI)
+
+		// Collect first: deleting under a live cursor skips the following key
+		var old []*models.EquipmentSnapshot
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil && (limit <= 0 || len(old) < limit); k, v = c.Next() {
+			var snapshot models.EquipmentSnapshot
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return fmt.Errorf("failed to decode snapshot: %w", err)
+			}
+			if snapshot.SnapshotTime.Before(before) {
+				old = append(old, &snapshot)
+			}
+		}
+
+		for _, snapshot := range old {
+			if err := bucket.Delete(idKey(uint64(snapshot.ID))); err != nil {
+				return err
+			}
+			if err := index.Delete(snapshotIndexKey(snapshot)); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(old))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old snapshots: %w", err)
+	}
+	return deleted, nil
+}
+
+func snapshotIndexKey(snapshot *models.EquipmentSnapshot) []byte {
+	return indexKey(snapshot.IMEI, timeKey(snapshot.SnapshotTime, uint64(snapshot.ID)))
+}
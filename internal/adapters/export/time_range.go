@@ -0,0 +1,67 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// timeLayouts are the accepted forms of an export range bound
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// TimeRange bounds an export by record time. A zero bound is open.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ParseTimeRange parses the start and end bounds given to ExportAudits and
+// ExportHistory. Either may be empty to leave that side open.
+func ParseTimeRange(start, end string) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if r.Start, err = parseBound(start); err != nil {
+		return TimeRange{}, fmt.Errorf("invalid start time: %w", err)
+	}
+	if r.End, err = parseBound(end); err != nil {
+		return TimeRange{}, fmt.Errorf("invalid end time: %w", err)
+	}
+	if !r.Start.IsZero() && !r.End.IsZero() && r.End.Before(r.Start) {
+		return TimeRange{}, fmt.Errorf("end time %s is before start time %s", end, start)
+	}
+	return r, nil
+}
+
+// Contains reports whether t falls inside the range, bounds included
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.Start.IsZero() || !t.Before(r.Start)) && (r.End.IsZero() || !t.After(r.End))
+}
+
+// Bounds returns the range with open sides replaced by the earliest and latest
+// times a nanosecond timestamp can hold, for repositories that need both bounds
+func (r TimeRange) Bounds() (time.Time, time.Time) {
+	start, end := r.Start, r.End
+	if start.IsZero() {
+		start = time.Unix(0, math.MinInt64).UTC()
+	}
+	if end.IsZero() {
+		end = time.Unix(0, math.MaxInt64).UTC()
+	}
+	return start, end
+}
+
+func parseBound(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 timestamp or date", value)
+}
@@ -0,0 +1,227 @@
+// Package export encodes equipment, audit and history records as CSV, NDJSON
+// or JSON one record at a time, so the DataExporter implementations can stream
+// a dataset without holding it in memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+)
+
+// Supported export formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// ErrUnsupportedFormat is returned for a format other than csv, ndjson or json
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// bufferSize is how much output is gathered before it reaches the underlying writer
+const bufferSize = 32 * 1024
+
+// ValidateFormat checks that format can be exported
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatJSON:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+}
+
+// ContentType returns the MIME type of format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+// Writer encodes records of one dataset in one format. Close must be called
+// to complete the output.
+type Writer struct {
+	format string
+	out    *bufio.Writer
+	csv    *csv.Writer
+	row    func(record interface{}) []string
+	count  int
+}
+
+// NewEquipmentWriter returns a Writer for equipment records
+func NewEquipmentWriter(w io.Writer, format string) (*Writer, error) {
+	return newWriter(w, format, equipmentColumns, func(record interface{}) []string {
+		return equipmentRow(record.(*models.Equipment))
+	})
+}
+
+// NewAuditWriter returns a Writer for audit logs
+func NewAuditWriter(w io.Writer, format string) (*Writer, error) {
+	return newWriter(w, format, auditColumns, func(record interface{}) []string {
+		return auditRow(record.(*models.AuditLog))
+	})
+}
+
+// NewHistoryWriter returns a Writer for change history entries
+func NewHistoryWriter(w io.Writer, format string) (*Writer, error) {
+	return newWriter(w, format, historyColumns, func(record interface{}) []string {
+		return historyRow(record.(*models.EquipmentHistory))
+	})
+}
+
+func newWriter(w io.Writer, format string, columns []string, row func(interface{}) []string) (*Writer, error) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+
+	ew := &Writer{format: format, out: bufio.NewWriterSize(w, bufferSize), row: row}
+	switch format {
+	case FormatCSV:
+		ew.csv = csv.NewWriter(ew.out)
+		if err := ew.csv.Write(columns); err != nil {
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	case FormatJSON:
+		if _, err := ew.out.WriteString("["); err != nil {
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	return ew, nil
+}
+
+// Write encodes one record, which must match the kind the Writer was created for
+func (w *Writer) Write(record interface{}) error {
+	var err error
+	switch w.format {
+	case FormatCSV:
+		err = w.csv.Write(w.row(record))
+	case FormatNDJSON:
+		err = w.writeJSON(record, "\n")
+	case FormatJSON:
+		separator := ","
+		if w.count == 0 {
+			separator = ""
+		}
+		if _, err = w.out.WriteString(separator); err == nil {
+			err = w.writeJSON(record, "")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Count returns how many records were written
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Close completes the output and flushes it to the underlying writer
+func (w *Writer) Close() error {
+	switch w.format {
+	case FormatCSV:
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return fmt.Errorf("failed to flush export: %w", err)
+		}
+	case FormatJSON:
+		if _, err := w.out.WriteString("]\n"); err != nil {
+			return fmt.Errorf("failed to flush export: %w", err)
+		}
+	}
+	if err := w.out.Flush(); err != nil {
+		return fmt.Errorf("failed to flush export: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) writeJSON(record interface{}, terminator string) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.out.Write(data); err != nil {
+		return err
+	}
+	_, err = w.out.WriteString(terminator)
+	return err
+}
+
+var equipmentColumns = []string{
+	"id", "imei", "imeisv", "status", "reason", "last_updated", "last_check_time",
+	"check_count", "added_by", "metadata", "manufacturer_tac", "manufacturer_name",
+}
+
+func equipmentRow(e *models.Equipment) []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10), e.IMEI, str(e.IMEISV), string(e.Status), str(e.Reason),
+		timestamp(&e.LastUpdated), timestamp(e.LastCheckTime), strconv.FormatInt(e.CheckCount, 10),
+		e.AddedBy, str(e.Metadata), str(e.ManufacturerTAC), str(e.ManufacturerName),
+	}
+}
+
+var auditColumns = []string{
+	"id", "imei", "imeisv", "status", "check_time", "origin_host", "origin_realm",
+	"user_name", "supi", "gpsi", "request_source", "session_id", "result_code",
+}
+
+func auditRow(a *models.AuditLog) []string {
+	resultCode := ""
+	if a.ResultCode != nil {
+		resultCode = strconv.FormatInt(int64(*a.ResultCode), 10)
+	}
+	return []string{
+		strconv.FormatInt(a.ID, 10), a.IMEI, str(a.IMEISV), string(a.Status), timestamp(&a.CheckTime),
+		str(a.OriginHost), str(a.OriginRealm), str(a.UserName), str(a.SUPI), str(a.GPSI),
+		a.RequestSource, str(a.SessionID), resultCode,
+	}
+}
+
+var historyColumns = []string{
+	"id", "imei", "change_type", "changed_at", "changed_by", "previous_status",
+	"new_status", "previous_reason", "new_reason", "change_details", "session_id",
+}
+
+func historyRow(h *models.EquipmentHistory) []string {
+	previousStatus := ""
+	if h.PreviousStatus != nil {
+		previousStatus = string(*h.PreviousStatus)
+	}
+	details := ""
+	if len(h.ChangeDetails) > 0 {
+		data, _ := json.Marshal(h.ChangeDetails)
+		details = string(data)
+	}
+	return []string{
+		strconv.FormatInt(h.ID, 10), h.IMEI, string(h.ChangeType), timestamp(&h.ChangedAt), h.ChangedBy,
+		previousStatus, string(h.NewStatus), str(h.PreviousReason), str(h.NewReason), details, str(h.SessionID),
+	}
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timestamp(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
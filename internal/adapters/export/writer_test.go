@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEquipment() []*models.Equipment {
+	reason := "stolen"
+	return []*models.Equipment{
+		{ID: 1, IMEI: "490154203237518", Status: models.EquipmentStatusBlacklisted, Reason: &reason,
+			LastUpdated: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), CheckCount: 7, AddedBy: "ops"},
+		{ID: 2, IMEI: "356938035643809", Status: models.EquipmentStatusWhitelisted, AddedBy: "ops"},
+	}
+}
+
+func writeEquipment(t *testing.T, format string, records []*models.Equipment) string {
+	var buf bytes.Buffer
+	w, err := NewEquipmentWriter(&buf, format)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, w.Write(record))
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, len(records), w.Count())
+	return buf.String()
+}
+
+func TestWriter_CSV(t *testing.T) {
+	out := writeEquipment(t, FormatCSV, testEquipment())
+
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, equipmentColumns, rows[0])
+	assert.Equal(t, []string{"1", "490154203237518", "", "BLACKLISTED", "stolen", "2025-01-02T03:04:05Z", "", "7", "ops", "", "", ""}, rows[1])
+	assert.Equal(t, "", rows[2][5], "a zero time is exported empty")
+}
+
+func TestWriter_NDJSON(t *testing.T) {
+	out := writeEquipment(t, FormatNDJSON, testEquipment())
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 2)
+	var decoded models.Equipment
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &decoded))
+	assert.Equal(t, "356938035643809", decoded.IMEI)
+}
+
+func TestWriter_JSON(t *testing.T) {
+	var decoded []models.Equipment
+	require.NoError(t, json.Unmarshal([]byte(writeEquipment(t, FormatJSON, testEquipment())), &decoded))
+	assert.Len(t, decoded, 2)
+
+	require.NoError(t, json.Unmarshal([]byte(writeEquipment(t, FormatJSON, nil)), &decoded))
+	assert.Empty(t, decoded)
+}
+
+func TestWriter_HistoryDetails(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewHistoryWriter(&buf, FormatCSV)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(&models.EquipmentHistory{
+		IMEI: "490154203237518", ChangeType: models.ChangeTypeUpdate,
+		ChangeDetails: models.ChangeDetails{"list": "equipment"},
+	}))
+	require.NoError(t, w.Close())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, `{"list":"equipment"}`, rows[1][9])
+}
+
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	_, err := NewAuditWriter(&bytes.Buffer{}, "xml")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestParseTimeRange(t *testing.T) {
+	r, err := ParseTimeRange("2025-01-01", "2025-01-31 23:59:59")
+	require.NoError(t, err)
+	assert.True(t, r.Contains(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, r.Contains(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)))
+
+	r, err = ParseTimeRange("", "2025-01-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.True(t, r.Contains(time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)))
+	start, end := r.Bounds()
+	assert.True(t, start.Before(r.End))
+	assert.Equal(t, r.End, end)
+
+	_, err = ParseTimeRange("yesterday", "")
+	assert.Error(t, err)
+	_, err = ParseTimeRange("2025-02-01", "2025-01-01")
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
 	"github.com/hsdfat8/eir/internal/adapters/mongodb"
 	"github.com/hsdfat8/eir/internal/adapters/postgres"
 	"github.com/hsdfat8/eir/internal/domain/ports"
@@ -32,6 +33,12 @@ func (f *DatabaseAdapterFactory) CreateAdapter(config *ports.DatabaseConfig) (po
 		}
 		return mongodb.NewMongoDBAdapter(config.MongoDBConfig), nil
 
+	case ports.DatabaseTypeEmbedded:
+		if config.EmbeddedConfig == nil {
+			return nil, fmt.Errorf("embedded configuration is required for embedded adapter")
+		}
+		return embedded.NewEmbeddedAdapter(config.EmbeddedConfig), nil
+
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
 	}
@@ -63,6 +70,8 @@ func (f *DatabaseAdapterFactory) ValidateConfig(config *ports.DatabaseConfig) er
 		return f.validatePostgresConfig(config.PostgresConfig)
 	case ports.DatabaseTypeMongoDB:
 		return f.validateMongoDBConfig(config.MongoDBConfig)
+	case ports.DatabaseTypeEmbedded:
+		return f.validateEmbeddedConfig(config.EmbeddedConfig)
 	default:
 		return fmt.Errorf("unsupported database type: %s", config.Type)
 	}
@@ -101,6 +110,19 @@ func (f *DatabaseAdapterFactory) validatePostgresConfig(config *ports.PostgresCo
 		return fmt.Errorf("max_idle_conns cannot be greater than max_open_conns")
 	}
 
+	for i, replica := range config.Replicas {
+		if replica.Host == "" {
+			return fmt.Errorf("postgres replica %d host is required", i)
+		}
+		if replica.Port <= 0 || replica.Port > 65535 {
+			return fmt.Errorf("postgres replica %d port must be between 1 and 65535", i)
+		}
+	}
+
+	if config.ReplicaMaxLag < 0 {
+		return fmt.Errorf("replica_max_lag cannot be negative")
+	}
+
 	return nil
 }
 
@@ -132,6 +154,22 @@ func (f *DatabaseAdapterFactory) validateMongoDBConfig(config *ports.MongoDBConf
 	return nil
 }
 
+func (f *DatabaseAdapterFactory) validateEmbeddedConfig(config *ports.EmbeddedConfig) error {
+	if config == nil {
+		return fmt.Errorf("embedded configuration is nil")
+	}
+
+	if config.Path == "" {
+		return fmt.Errorf("embedded database path is required")
+	}
+
+	if config.LockTimeout < 0 {
+		return fmt.Errorf("lock_timeout cannot be negative")
+	}
+
+	return nil
+}
+
 // GetDefaultPostgresConfig returns a default PostgreSQL configuration
 func GetDefaultPostgresConfig() *ports.PostgresConfig {
 	return &ports.PostgresConfig{
@@ -166,6 +204,15 @@ func GetDefaultMongoDBConfig() *ports.MongoDBConfig {
 	}
 }
 
+// GetDefaultEmbeddedConfig returns a default embedded store configuration
+func GetDefaultEmbeddedConfig() *ports.EmbeddedConfig {
+	return &ports.EmbeddedConfig{
+		Path:        "data/eir.db",
+		LockTimeout: 5, // 5 seconds
+		NoSync:      false,
+	}
+}
+
 // CreateDefaultConfig creates a default database configuration for the specified type
 func CreateDefaultConfig(dbType ports.DatabaseType) *ports.DatabaseConfig {
 	config := &ports.DatabaseConfig{
@@ -177,6 +224,8 @@ func CreateDefaultConfig(dbType ports.DatabaseType) *ports.DatabaseConfig {
 		config.PostgresConfig = GetDefaultPostgresConfig()
 	case ports.DatabaseTypeMongoDB:
 		config.MongoDBConfig = GetDefaultMongoDBConfig()
+	case ports.DatabaseTypeEmbedded:
+		config.EmbeddedConfig = GetDefaultEmbeddedConfig()
 	}
 
 	return config
@@ -1,19 +1,55 @@
 package http
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hsdfat8/eir/internal/adapters/export"
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/hsdfat8/eir/internal/domain/service"
 	"github.com/hsdfat8/eir/internal/logger"
 )
 
+// healthCheckTimeout bounds the storage probe made by GET /health
+const healthCheckTimeout = 2 * time.Second
+
+// maxPageSize caps the limit accepted by the paged history and snapshot listings
+const maxPageSize = 1000
+
+// HealthReporter reports the health of the storage backend behind the service.
+// ports.DatabaseAdapter satisfies it.
+type HealthReporter interface {
+	HealthCheck(ctx context.Context) error
+	GetConnectionStats() ports.ConnectionStats
+}
+
+// MaintenanceRunner runs database maintenance on demand and reports on it.
+// service.MaintenanceScheduler satisfies it.
+type MaintenanceRunner interface {
+	Status() models.MaintenanceStatus
+	RunNow() error
+}
+
 // Handler handles HTTP requests for the EIR service
 type Handler struct {
-	eirService ports.EIRService
+	eirService  ports.EIRService
+	health      HealthReporter
+	maintenance MaintenanceRunner
+	exporter    ports.DataExporter
+	auditTokens []string // Bearer tokens that may read encrypted identifiers
+
+	apiTokens      map[string]string // Bearer token to the actor it authenticates
+	trustedProxies []*net.IPNet      // Peers whose X-Actor header is believed
 }
 
 // NewHandler creates a new HTTP handler
@@ -77,8 +113,7 @@ func (h *Handler) GetEquipmentStatus(c *gin.Context) {
 		return
 	}
 
-	// Convert color to equipment status
-	equipmentStatus := convertColorToEquipmentStatus(response.Color)
+	equipmentStatus := response.EquipmentStatus()
 
 	logger.Log.Infow("HTTP GetEquipmentStatus response", "pei", pei, "status", equipmentStatus, "color", response.Color)
 	// Return response
@@ -100,39 +135,58 @@ func (h *Handler) ProvisionEquipment(c *gin.Context) {
 		return
 	}
 
-	// Convert equipment status to color code
-	color := convertEquipmentStatusToColor(req.Status)
-
-	// Build system status (default: normal operation)
-	systemStatus := models.SystemStatus{
-		OverloadLevel: 0,
-		TPSOverload:   false,
-	}
-
-	// Provision equipment using IMEI logic
-	result, err := h.eirService.InsertImei(c.Request.Context(), req.IMEI, color, systemStatus)
-	if err != nil || result.Status != "ok" {
-		detail := "Failed to provision equipment"
-		if result.Error != nil {
-			detail = *result.Error
+	// The equipment record and the IMEI lists checks read are written together
+	equipment, err := h.eirService.ProvisionEquipment(c.Request.Context(), &models.Equipment{
+		IMEI:             req.IMEI,
+		IMEISV:           req.IMEISV,
+		Status:           req.Status,
+		Reason:           req.Reason,
+		Metadata:         req.Metadata,
+		ManufacturerTAC:  req.ManufacturerTAC,
+		ManufacturerName: req.ManufacturerName,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEquipmentExists):
+			c.JSON(http.StatusConflict, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: "Equipment already exists",
+			})
+		case errors.Is(err, service.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "Failed to provision equipment",
+			})
 		}
-		c.JSON(http.StatusInternalServerError, ProblemDetails{
-			Type:   "about:blank",
-			Title:  "Internal Server Error",
-			Status: http.StatusInternalServerError,
-			Detail: detail,
-		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Equipment provisioned successfully"})
+	c.Header("ETag", etag(equipment.Version))
+	c.JSON(http.StatusCreated, equipmentResponse(equipment))
 }
 
-// GetEquipment handles GET /equipment/:imei
+// GetEquipment handles GET /equipment/:imei. Deleted equipment is only
+// returned with include_deleted=true.
 func (h *Handler) GetEquipment(c *gin.Context) {
 	imei := c.Param("imei")
 
-	equipment, err := h.eirService.GetEquipment(c.Request.Context(), imei)
+	includeDeleted, ok := includeDeletedQuery(c)
+	if !ok {
+		return
+	}
+
+	equipment, err := h.eirService.GetEquipment(c.Request.Context(), imei, includeDeleted)
 	if err != nil {
 		if errors.Is(err, service.ErrEquipmentNotFound) {
 			c.JSON(http.StatusNotFound, ProblemDetails{
@@ -153,24 +207,73 @@ func (h *Handler) GetEquipment(c *gin.Context) {
 		return
 	}
 
-	// Convert to response
-	response := EquipmentResponse{
-		IMEI:             equipment.IMEI,
-		IMEISV:           equipment.IMEISV,
-		Status:           equipment.Status,
-		Reason:           equipment.Reason,
-		LastUpdated:      equipment.LastUpdated.Format("2006-01-02T15:04:05Z07:00"),
-		CheckCount:       equipment.CheckCount,
-		ManufacturerTAC:  equipment.ManufacturerTAC,
-		ManufacturerName: equipment.ManufacturerName,
+	c.Header("ETag", etag(equipment.Version))
+	c.JSON(http.StatusOK, equipmentResponse(equipment))
+}
+
+// UpdateEquipment handles PUT /equipment/:imei. With an If-Match header the
+// update only applies if the record is still at that version.
+func (h *Handler) UpdateEquipment(c *gin.Context) {
+	imei := c.Param("imei")
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
 	}
 
-	if equipment.LastCheckTime != nil {
-		lastCheckTime := equipment.LastCheckTime.Format("2006-01-02T15:04:05Z07:00")
-		response.LastCheckTime = &lastCheckTime
+	var req UpdateEquipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	equipment, err := h.eirService.UpdateEquipment(c.Request.Context(), &models.Equipment{
+		IMEI:             imei,
+		IMEISV:           req.IMEISV,
+		Status:           req.Status,
+		Reason:           req.Reason,
+		Metadata:         req.Metadata,
+		ManufacturerTAC:  req.ManufacturerTAC,
+		ManufacturerName: req.ManufacturerName,
+		Version:          version,
+	})
+	if err != nil {
+		var conflict *ports.VersionConflictError
+		switch {
+		case errors.As(err, &conflict):
+			preconditionFailed(c, conflict)
+		case errors.Is(err, service.ErrEquipmentNotFound):
+			c.JSON(http.StatusNotFound, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "Equipment not found",
+			})
+		case errors.Is(err, models.ErrInvalidIMEI), errors.Is(err, service.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "Failed to update equipment",
+			})
+		}
+		return
+	}
+
+	c.Header("ETag", etag(equipment.Version))
+	c.JSON(http.StatusOK, equipmentResponse(equipment))
 }
 
 // DeleteEquipment handles DELETE /equipment/:imei
@@ -178,6 +281,16 @@ func (h *Handler) DeleteEquipment(c *gin.Context) {
 	imei := c.Param("imei")
 
 	if err := h.eirService.RemoveEquipment(c.Request.Context(), imei); err != nil {
+		if errors.Is(err, service.ErrEquipmentNotFound) {
+			c.JSON(http.StatusNotFound, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "Equipment not found",
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, ProblemDetails{
 			Type:   "about:blank",
 			Title:  "Internal Server Error",
@@ -190,7 +303,44 @@ func (h *Handler) DeleteEquipment(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
-// ListEquipment handles GET /equipment
+// UndeleteEquipment handles POST /equipment/:imei/undelete
+func (h *Handler) UndeleteEquipment(c *gin.Context) {
+	imei := c.Param("imei")
+
+	equipment, err := h.eirService.UndeleteEquipment(c.Request.Context(), imei)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEquipmentNotFound):
+			c.JSON(http.StatusNotFound, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "Equipment not found",
+			})
+		case errors.Is(err, service.ErrEquipmentNotDeleted):
+			c.JSON(http.StatusConflict, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "Failed to undelete equipment",
+			})
+		}
+		return
+	}
+
+	c.Header("ETag", etag(equipment.Version))
+	c.JSON(http.StatusOK, equipmentResponse(equipment))
+}
+
+// ListEquipment handles GET /equipment. Deleted equipment is only listed
+// with include_deleted=true.
 func (h *Handler) ListEquipment(c *gin.Context) {
 	offset := 0
 	limit := 100
@@ -207,7 +357,12 @@ func (h *Handler) ListEquipment(c *gin.Context) {
 		}
 	}
 
-	equipments, err := h.eirService.ListEquipment(c.Request.Context(), offset, limit)
+	includeDeleted, ok := includeDeletedQuery(c)
+	if !ok {
+		return
+	}
+
+	equipments, err := h.eirService.ListEquipment(c.Request.Context(), offset, limit, includeDeleted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ProblemDetails{
 			Type:   "about:blank",
@@ -221,26 +376,547 @@ func (h *Handler) ListEquipment(c *gin.Context) {
 	// Convert to response
 	var responses []EquipmentResponse
 	for _, equipment := range equipments {
-		response := EquipmentResponse{
-			IMEI:             equipment.IMEI,
-			IMEISV:           equipment.IMEISV,
-			Status:           equipment.Status,
-			Reason:           equipment.Reason,
-			LastUpdated:      equipment.LastUpdated.Format("2006-01-02T15:04:05Z07:00"),
-			CheckCount:       equipment.CheckCount,
-			ManufacturerTAC:  equipment.ManufacturerTAC,
-			ManufacturerName: equipment.ManufacturerName,
+		responses = append(responses, equipmentResponse(equipment))
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetEquipmentHistory handles GET /equipment/:imei/history
+func (h *Handler) GetEquipmentHistory(c *gin.Context) {
+	imei := c.Param("imei")
+
+	offset, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
+	}
+
+	entries, err := h.eirService.GetEquipmentHistory(c.Request.Context(), imei, offset, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrHistoryUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: err.Error(),
+			})
+			return
 		}
 
-		if equipment.LastCheckTime != nil {
-			lastCheckTime := equipment.LastCheckTime.Format("2006-01-02T15:04:05Z07:00")
-			response.LastCheckTime = &lastCheckTime
+		c.JSON(http.StatusInternalServerError, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Internal Server Error",
+			Status: http.StatusInternalServerError,
+			Detail: "Failed to retrieve equipment history",
+		})
+		return
+	}
+
+	response := HistoryResponse{
+		IMEI:    imei,
+		Offset:  offset,
+		Limit:   limit,
+		Entries: make([]HistoryEntryResponse, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, historyEntryResponse(entry))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetAuditAccessTokens sets the bearer tokens whose GET /audits requests
+// see encrypted subscriber identifiers decrypted, and that may start
+// maintenance runs
+func (h *Handler) SetAuditAccessTokens(tokens []string) {
+	h.auditTokens = tokens
+}
+
+// SetAPITokens sets the bearer tokens that authenticate management requests,
+// each mapped to the actor the changes of its requests are attributed to
+func (h *Handler) SetAPITokens(tokens map[string]string) {
+	h.apiTokens = tokens
+}
+
+// SetTrustedProxies sets the peers allowed to name the actor of a request in
+// its X-Actor header
+func (h *Handler) SetTrustedProxies(proxies []*net.IPNet) {
+	h.trustedProxies = proxies
+}
+
+// GetAudits handles GET /audits?imei= or GET /audits?supi=
+// Requests carrying one of the audit access tokens as a bearer token get
+// encrypted subscriber identifiers decrypted; others see them protected.
+func (h *Handler) GetAudits(c *gin.Context) {
+	imei, supi := c.Query("imei"), c.Query("supi")
+	if (imei == "") == (supi == "") {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: "exactly one of imei and supi is required",
+		})
+		return
+	}
+
+	offset, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if header := c.GetHeader("Authorization"); header != "" {
+		if _, ok := h.tokenActor(header); !ok && !h.auditAccessGranted(header) {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.JSON(http.StatusUnauthorized, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Unauthorized",
+				Status: http.StatusUnauthorized,
+				Detail: "invalid audit access token",
+			})
+			return
+		}
+		if h.auditAccessGranted(header) {
+			ctx = ports.WithIdentifierAccess(ctx)
 		}
+	}
 
-		responses = append(responses, response)
+	var audits []*models.AuditLog
+	if imei != "" {
+		audits, err = h.eirService.GetAuditsByIMEI(ctx, imei, offset, limit)
+	} else {
+		audits, err = h.eirService.GetAuditsBySUPI(ctx, supi, offset, limit)
 	}
+	if err != nil {
+		if errors.Is(err, service.ErrAuditsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: err.Error(),
+			})
+			return
+		}
 
-	c.JSON(http.StatusOK, responses)
+		c.JSON(http.StatusInternalServerError, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Internal Server Error",
+			Status: http.StatusInternalServerError,
+			Detail: "Failed to retrieve audit logs",
+		})
+		return
+	}
+
+	response := AuditResponse{
+		Offset:  offset,
+		Limit:   limit,
+		Entries: make([]AuditEntryResponse, 0, len(audits)),
+	}
+	for _, audit := range audits {
+		response.Entries = append(response.Entries, auditEntryResponse(audit))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// auditAccessGranted reports whether an Authorization header carries one of
+// the audit access tokens
+func (h *Handler) auditAccessGranted(header string) bool {
+	token, ok := bearerToken(header)
+	if !ok {
+		return false
+	}
+	granted := false
+	for _, allowed := range h.auditTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			granted = true
+		}
+	}
+	return granted
+}
+
+// tokenActor returns the actor of the API token an Authorization header
+// carries, if it carries one
+func (h *Handler) tokenActor(header string) (string, bool) {
+	token, ok := bearerToken(header)
+	if !ok {
+		return "", false
+	}
+	actor, found := "", false
+	for allowed, owner := range h.apiTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			actor, found = owner, true
+		}
+	}
+	return actor, found
+}
+
+// bearerToken returns the non-empty bearer token of an Authorization header
+func bearerToken(header string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	return token, ok && token != ""
+}
+
+// GetStatusAt handles GET /equipment/:imei/status?at=RFC3339 timestamp
+func (h *Handler) GetStatusAt(c *gin.Context) {
+	imei := c.Param("imei")
+
+	at := time.Now()
+	if atParam := c.Query("at"); atParam != "" {
+		parsed, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: fmt.Sprintf("invalid at: %q is not an RFC 3339 timestamp", atParam),
+			})
+			return
+		}
+		at = parsed
+	}
+
+	status, err := h.eirService.GetStatusAt(c.Request.Context(), imei, at)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Invalid PEI",
+				Status: http.StatusBadRequest,
+				Detail: err.Error(),
+			})
+		case errors.Is(err, service.ErrHistoryUnavailable):
+			c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Service Unavailable",
+				Status: http.StatusServiceUnavailable,
+				Detail: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "Failed to reconstruct equipment status",
+			})
+		}
+		return
+	}
+
+	response := StatusAtResponse{
+		IMEI:          status.IMEI,
+		At:            status.At.Format("2006-01-02T15:04:05Z07:00"),
+		Status:        status.Status,
+		Source:        status.Source,
+		Contributions: make([]StatusContributionResponse, 0, len(status.Contributions)),
+	}
+	for _, contribution := range status.Contributions {
+		item := StatusContributionResponse{
+			Source:    contribution.Source,
+			Status:    contribution.Status,
+			Effective: contribution.Effective,
+			History:   make([]HistoryEntryResponse, 0, len(contribution.History)),
+		}
+		for _, entry := range contribution.History {
+			item.History = append(item.History, historyEntryResponse(entry))
+		}
+		if contribution.Snapshot != nil {
+			snapshotTime := contribution.Snapshot.SnapshotTime.Format("2006-01-02T15:04:05Z07:00")
+			item.SnapshotID = &contribution.Snapshot.ID
+			item.SnapshotTime = &snapshotTime
+		}
+		response.Contributions = append(response.Contributions, item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSnapshot handles POST /equipment/:imei/snapshots
+func (h *Handler) CreateSnapshot(c *gin.Context) {
+	imei := c.Param("imei")
+
+	snapshot, err := h.eirService.SnapshotEquipment(c.Request.Context(), imei)
+	if err != nil {
+		h.snapshotError(c, err, "Failed to create snapshot")
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshotResponse(snapshot))
+}
+
+// ListSnapshots handles GET /equipment/:imei/snapshots
+func (h *Handler) ListSnapshots(c *gin.Context) {
+	imei := c.Param("imei")
+
+	offset, limit, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
+	}
+
+	snapshots, err := h.eirService.ListSnapshots(c.Request.Context(), imei, offset, limit)
+	if err != nil {
+		h.snapshotError(c, err, "Failed to retrieve snapshots")
+		return
+	}
+
+	response := SnapshotsResponse{
+		IMEI:      imei,
+		Offset:    offset,
+		Limit:     limit,
+		Snapshots: make([]SnapshotResponse, 0, len(snapshots)),
+	}
+	for _, snapshot := range snapshots {
+		response.Snapshots = append(response.Snapshots, snapshotResponse(snapshot))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreSnapshot handles POST /equipment/:imei/snapshots/:id/restore
+func (h *Handler) RestoreSnapshot(c *gin.Context) {
+	imei := c.Param("imei")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("invalid snapshot id: %q", c.Param("id")),
+		})
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	equipment, err := h.eirService.RestoreSnapshot(c.Request.Context(), imei, id, version)
+	if err != nil {
+		h.snapshotError(c, err, "Failed to restore snapshot")
+		return
+	}
+
+	c.Header("ETag", etag(equipment.Version))
+	c.JSON(http.StatusOK, equipmentResponse(equipment))
+}
+
+// snapshotError writes the response for an error of a snapshot operation
+func (h *Handler) snapshotError(c *gin.Context, err error, detail string) {
+	var conflict *ports.VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		preconditionFailed(c, conflict)
+	case errors.Is(err, service.ErrEquipmentNotFound), errors.Is(err, service.ErrSnapshotNotFound):
+		c.JSON(http.StatusNotFound, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Not Found",
+			Status: http.StatusNotFound,
+			Detail: err.Error(),
+		})
+	case errors.Is(err, service.ErrSnapshotsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Service Unavailable",
+			Status: http.StatusServiceUnavailable,
+			Detail: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Internal Server Error",
+			Status: http.StatusInternalServerError,
+			Detail: detail,
+		})
+	}
+}
+
+// equipmentResponse converts an equipment record for the management API
+func equipmentResponse(equipment *models.Equipment) EquipmentResponse {
+	response := EquipmentResponse{
+		IMEI:             equipment.IMEI,
+		IMEISV:           equipment.IMEISV,
+		Status:           equipment.Status,
+		Reason:           equipment.Reason,
+		LastUpdated:      equipment.LastUpdated.Format("2006-01-02T15:04:05Z07:00"),
+		CheckCount:       equipment.CheckCount,
+		ManufacturerTAC:  equipment.ManufacturerTAC,
+		ManufacturerName: equipment.ManufacturerName,
+		Version:          equipment.Version,
+	}
+
+	if equipment.LastCheckTime != nil {
+		lastCheckTime := equipment.LastCheckTime.Format("2006-01-02T15:04:05Z07:00")
+		response.LastCheckTime = &lastCheckTime
+	}
+	if equipment.DeletedAt != nil {
+		deletedAt := equipment.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.DeletedAt = &deletedAt
+		response.DeletedBy = equipment.DeletedBy
+	}
+	return response
+}
+
+// etag is the entity tag of an equipment record at a version
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion returns the version required by the request's If-Match
+// header, 0 when there is none or it is "*". A malformed header is answered
+// with 400 and ok false.
+func ifMatchVersion(c *gin.Context) (version int64, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err == nil {
+		version, err = strconv.ParseInt(unquoted, 10, 64)
+	}
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("invalid If-Match header: %s", header),
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+// preconditionFailed answers a write whose If-Match version is stale, with
+// the current ETag when the record still exists
+func preconditionFailed(c *gin.Context, conflict *ports.VersionConflictError) {
+	if conflict.Actual > 0 {
+		c.Header("ETag", etag(conflict.Actual))
+	}
+	c.JSON(http.StatusPreconditionFailed, ProblemDetails{
+		Type:   "about:blank",
+		Title:  "Precondition Failed",
+		Status: http.StatusPreconditionFailed,
+		Detail: conflict.Error(),
+	})
+}
+
+// snapshotResponse converts a snapshot for the management API
+func snapshotResponse(snapshot *models.EquipmentSnapshot) SnapshotResponse {
+	return SnapshotResponse{
+		ID:           snapshot.ID,
+		IMEI:         snapshot.IMEI,
+		SnapshotTime: snapshot.SnapshotTime.Format("2006-01-02T15:04:05Z07:00"),
+		SnapshotType: snapshot.SnapshotType,
+		CreatedBy:    snapshot.CreatedBy,
+		Status:       snapshot.Status,
+		Reason:       snapshot.Reason,
+		CheckCount:   snapshot.CheckCount,
+		Metadata:     snapshot.Metadata,
+	}
+}
+
+// historyEntryResponse converts a history entry for the management API
+func historyEntryResponse(entry *models.EquipmentHistory) HistoryEntryResponse {
+	return HistoryEntryResponse{
+		ID:             entry.ID,
+		ChangeType:     entry.ChangeType,
+		ChangedAt:      entry.ChangedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ChangedBy:      entry.ChangedBy,
+		PreviousStatus: entry.PreviousStatus,
+		NewStatus:      entry.NewStatus,
+		PreviousReason: entry.PreviousReason,
+		NewReason:      entry.NewReason,
+		ChangeDetails:  entry.ChangeDetails,
+	}
+}
+
+// auditEntryResponse converts an audit log for the management API
+func auditEntryResponse(audit *models.AuditLog) AuditEntryResponse {
+	return AuditEntryResponse{
+		ID:            audit.ID,
+		IMEI:          audit.IMEI,
+		IMEISV:        audit.IMEISV,
+		Status:        audit.Status,
+		CheckTime:     audit.CheckTime.Format("2006-01-02T15:04:05Z07:00"),
+		OriginHost:    audit.OriginHost,
+		OriginRealm:   audit.OriginRealm,
+		UserName:      audit.UserName,
+		SUPI:          audit.SUPI,
+		GPSI:          audit.GPSI,
+		RequestSource: audit.RequestSource,
+		SessionID:     audit.SessionID,
+		ResultCode:    audit.ResultCode,
+	}
+}
+
+// pageQuery reads the offset and limit query parameters of a paged listing
+func pageQuery(c *gin.Context) (offset, limit int, err error) {
+	if offset, err = queryInt(c, "offset", 0); err != nil {
+		return 0, 0, err
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	if limit, err = queryInt(c, "limit", 100); err != nil {
+		return 0, 0, err
+	}
+	if limit < 1 || limit > maxPageSize {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	return offset, limit, nil
+}
+
+// includeDeletedQuery reads the include_deleted query parameter. A malformed
+// value is answered with 400 and ok false.
+func includeDeletedQuery(c *gin.Context) (includeDeleted bool, ok bool) {
+	value := c.Query("include_deleted")
+	if value == "" {
+		return false, true
+	}
+	includeDeleted, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("invalid include_deleted: %q", value),
+		})
+		return false, false
+	}
+	return includeDeleted, true
+}
+
+// queryInt parses the integer query parameter name, returning def when absent
+func queryInt(c *gin.Context, name string, def int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return n, nil
 }
 
 func (h *Handler) GetCheckImei(c *gin.Context) {
@@ -288,8 +964,7 @@ func (h *Handler) GetCheckImei(c *gin.Context) {
 		return
 	}
 
-	// Convert color to equipment status
-	equipmentStatus := convertColorToEquipmentStatus(response.Color)
+	equipmentStatus := response.EquipmentStatus()
 
 	logger.Log.Infow("HTTP GetCheckImei response", "imei", imei, "status", equipmentStatus, "color", response.Color)
 	// Return response
@@ -343,8 +1018,7 @@ func (h *Handler) GetCheckTac(c *gin.Context) {
 		return
 	}
 
-	// Convert color to equipment status
-	equipmentStatus := convertColorToEquipmentStatus(response.Color)
+	equipmentStatus := response.EquipmentStatus()
 
 	logger.Log.Infow("HTTP GetCheckTac response", "imei", imei, "status", equipmentStatus, "color", response.Color)
 	// Return response
@@ -369,6 +1043,9 @@ func (h *Handler) PostInsertTac(c *gin.Context) {
 	}
 
 	logger.Log.Infow("HTTP PostInsertTac parsed request", "start_range", tacInfo.StartRangeTac, "end_range", tacInfo.EndRangeTac, "color", tacInfo.Color)
+	if !validColor(c, models.TacList, &tacInfo.Color) {
+		return
+	}
 
 	// Perform equipment check using TAC-based logic
 	response, err := h.eirService.InsertTac(c.Request.Context(), &tacInfo)
@@ -394,17 +1071,21 @@ func (h *Handler) PostInsertTac(c *gin.Context) {
 		return
 	}
 
-	// Convert color to equipment status
-	var equipmentStatus models.EquipmentStatus
+	equipmentStatus := tacInfo.Color
 	if response.TacInfo != nil {
-		equipmentStatus = convertColorToEquipmentStatus(response.TacInfo.Color)
-	} else {
-		equipmentStatus = convertColorToEquipmentStatus(tacInfo.Color)
+		equipmentStatus = response.TacInfo.Color
 	}
 
 	logger.Log.Infow("HTTP PostInsertTac response", "start_range", tacInfo.StartRangeTac, "status", response.Status, "equipment_status", equipmentStatus)
 	// Return response
-	if response.Status == "error" {
+	if response.Error != nil && *response.Error == "version_conflict" {
+		c.JSON(http.StatusConflict, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Conflict",
+			Status: http.StatusConflict,
+			Detail: "The list entry was changed concurrently; retry the request",
+		})
+	} else if response.Status == "error" {
 		c.JSON(http.StatusBadRequest, EirResponseData{
 			Status: equipmentStatus,
 		})
@@ -431,6 +1112,9 @@ func (h *Handler) PostInsertImei(c *gin.Context) {
 	}
 
 	logger.Log.Infow("HTTP PostInsertImei parsed request", "imei", imeiInfo.Imei, "color", imeiInfo.Color)
+	if !validColor(c, models.ImeiList, &imeiInfo.Color) {
+		return
+	}
 
 	// Build system status (default: normal operation)
 	systemStatus := models.SystemStatus{
@@ -462,13 +1146,18 @@ func (h *Handler) PostInsertImei(c *gin.Context) {
 		return
 	}
 
-	// Convert color to equipment status
-	var equipmentStatus models.EquipmentStatus
-	equipmentStatus = convertColorToEquipmentStatus(imeiInfo.Color)
+	equipmentStatus := imeiInfo.Color
 
 	logger.Log.Infow("HTTP PostInsertImei response", "imei", imeiInfo.Imei, "status", response.Status, "equipment_status", equipmentStatus)
 	// Return response
-	if response.Status == "error" {
+	if response.Error != nil && *response.Error == "version_conflict" {
+		c.JSON(http.StatusConflict, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Conflict",
+			Status: http.StatusConflict,
+			Detail: "The list entry was changed concurrently; retry the request",
+		})
+	} else if response.Status == "error" {
 		c.JSON(http.StatusBadRequest, EirResponseData{
 			Status: equipmentStatus,
 		})
@@ -479,14 +1168,202 @@ func (h *Handler) PostInsertImei(c *gin.Context) {
 	}
 }
 
+// SetHealthReporter attaches the storage backend reported by GET /health
+func (h *Handler) SetHealthReporter(reporter HealthReporter) {
+	h.health = reporter
+}
+
 // HealthCheck handles GET /health
+// When a storage backend is attached its health is included, and an
+// unhealthy backend turns the response into 503 Service Unavailable.
 func (h *Handler) HealthCheck(c *gin.Context) {
+	if h.health == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "healthy",
+			"service": "eir",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	stats := h.health.GetConnectionStats()
+	database := gin.H{
+		"type":             stats.DatabaseType,
+		"healthy":          true,
+		"open_connections": stats.OpenConnections,
+		"idle_connections": stats.IdleConnections,
+		"max_connections":  stats.MaxConnections,
+	}
+
+	if err := h.health.HealthCheck(ctx); err != nil {
+		logger.Log.Warnw("HTTP health check database unhealthy", "error", err)
+		database["healthy"] = false
+		database["error"] = err.Error()
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "unhealthy",
+			"service":  "eir",
+			"database": database,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "eir",
+		"status":   "healthy",
+		"service":  "eir",
+		"database": database,
+	})
+}
+
+// SetMaintenanceRunner attaches the scheduler served under /admin/maintenance
+func (h *Handler) SetMaintenanceRunner(runner MaintenanceRunner) {
+	h.maintenance = runner
+}
+
+// GetMaintenanceStatus handles GET /admin/maintenance
+func (h *Handler) GetMaintenanceStatus(c *gin.Context) {
+	if h.maintenance == nil {
+		h.maintenanceUnavailable(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.maintenance.Status())
+}
+
+// RunMaintenance handles POST /admin/maintenance/run
+// The run happens in the background; poll GET /admin/maintenance for its report.
+// Only requests carrying one of the audit access tokens as a bearer token may
+// start a run.
+func (h *Handler) RunMaintenance(c *gin.Context) {
+	if !h.auditAccessGranted(c.GetHeader("Authorization")) {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.JSON(http.StatusUnauthorized, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Unauthorized",
+			Status: http.StatusUnauthorized,
+			Detail: "an audit access token is required to run maintenance",
+		})
+		return
+	}
+	if h.maintenance == nil {
+		h.maintenanceUnavailable(c)
+		return
+	}
+
+	if err := h.maintenance.RunNow(); err != nil {
+		if errors.Is(err, service.ErrMaintenanceRunning) {
+			c.JSON(http.StatusConflict, ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Internal Server Error",
+			Status: http.StatusInternalServerError,
+			Detail: "Failed to start maintenance",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Maintenance run started"})
+}
+
+func (h *Handler) maintenanceUnavailable(c *gin.Context) {
+	c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+		Type:   "about:blank",
+		Title:  "Service Unavailable",
+		Status: http.StatusServiceUnavailable,
+		Detail: "database maintenance is not configured",
 	})
 }
 
+// SetDataExporter attaches the exporter served under /export
+func (h *Handler) SetDataExporter(exporter ports.DataExporter) {
+	h.exporter = exporter
+}
+
+// ExportData handles GET /export/:dataset
+// Streams equipment, audits or history as ?format=csv|ndjson|json (default
+// ndjson) with chunked transfer; audits and history accept ?start= and ?end=.
+func (h *Handler) ExportData(c *gin.Context) {
+	if h.exporter == nil {
+		c.JSON(http.StatusServiceUnavailable, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Service Unavailable",
+			Status: http.StatusServiceUnavailable,
+			Detail: "data export is not configured",
+		})
+		return
+	}
+
+	dataset := c.Param("dataset")
+	format := c.DefaultQuery("format", export.FormatNDJSON)
+	start, end := c.Query("start"), c.Query("end")
+
+	var run func(w io.Writer) error
+	switch dataset {
+	case "equipment":
+		run = func(w io.Writer) error { return h.exporter.ExportEquipment(c.Request.Context(), w, format) }
+	case "audits":
+		run = func(w io.Writer) error { return h.exporter.ExportAudits(c.Request.Context(), w, format, start, end) }
+	case "history":
+		run = func(w io.Writer) error { return h.exporter.ExportHistory(c.Request.Context(), w, format, start, end) }
+	default:
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("unknown dataset %q, expected equipment, audits or history", dataset),
+		})
+		return
+	}
+	// Once streaming starts the status is sent, so reject bad input up front
+	if err := export.ValidateFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
+	}
+	if _, err := export.ParseTimeRange(start, end); err != nil {
+		c.JSON(http.StatusBadRequest, ProblemDetails{
+			Type:   "about:blank",
+			Title:  "Bad Request",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", dataset, format))
+	c.Status(http.StatusOK)
+
+	if err := run(flushWriter{c.Writer}); err != nil {
+		// The response is already under way; the client sees a truncated body
+		logger.Log.Errorw("HTTP export failed", "dataset", dataset, "format", format, "error", err)
+	}
+}
+
+// flushWriter sends every chunk the exporter writes to the client right away
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.w.Flush()
+	return n, err
+}
+
 // Helper function to convert string to pointer
 func stringPtr(s string) *string {
 	if s == "" {
@@ -495,31 +1372,19 @@ func stringPtr(s string) *string {
 	return &s
 }
 
-// convertColorToEquipmentStatus converts pkg/logic color codes to EquipmentStatus
-func convertColorToEquipmentStatus(color string) models.EquipmentStatus {
-	switch color {
-	case "black", "b":
-		return models.EquipmentStatusBlacklisted
-	case "grey", "g":
-		return models.EquipmentStatusGreylisted
-	case "white", "w":
-		return models.EquipmentStatusWhitelisted
-	default:
-		// Default to whitelisted for unknown
-		return models.EquipmentStatusWhitelisted
-	}
-}
-
-// convertEquipmentStatusToColor converts EquipmentStatus to pkg/logic color codes
-func convertEquipmentStatusToColor(status models.EquipmentStatus) string {
-	switch status {
-	case models.EquipmentStatusBlacklisted:
-		return "b"
-	case models.EquipmentStatusGreylisted:
-		return "g"
-	case models.EquipmentStatusWhitelisted:
-		return "w"
-	default:
-		return "w" // Default to white
+// validColor converts a legacy color of list to the status it stands for and
+// answers 400 unless color is then a status
+func validColor(c *gin.Context, list models.StatusList, color *models.EquipmentStatus) bool {
+	*color = models.NormalizeStatus(list, *color)
+	if models.ValidateStatus(*color) == nil {
+		return true
 	}
+	logger.Log.Warnw("HTTP invalid color", "list", list, "color", *color, "client_ip", c.ClientIP())
+	c.JSON(http.StatusBadRequest, ProblemDetails{
+		Type:   "about:blank",
+		Title:  "Invalid Color",
+		Status: http.StatusBadRequest,
+		Detail: "Color must be WHITELISTED, BLACKLISTED or GREYLISTED",
+	})
+	return false
 }
@@ -27,6 +27,17 @@ type ProvisionRequest struct {
 	ManufacturerName *string                 `json:"manufacturer_name,omitempty"`
 }
 
+// UpdateEquipmentRequest represents a change to an existing equipment record.
+// The fields replace the stored ones; send If-Match to make it conditional.
+type UpdateEquipmentRequest struct {
+	IMEISV           *string                 `json:"imeisv,omitempty"`
+	Status           models.EquipmentStatus  `json:"status" binding:"required"`
+	Reason           *string                 `json:"reason,omitempty"`
+	Metadata         *string                 `json:"metadata,omitempty"`
+	ManufacturerTAC  *string                 `json:"manufacturer_tac,omitempty"`
+	ManufacturerName *string                 `json:"manufacturer_name,omitempty"`
+}
+
 // EquipmentResponse represents equipment information response
 type EquipmentResponse struct {
 	IMEI             string                  `json:"imei"`
@@ -38,4 +49,93 @@ type EquipmentResponse struct {
 	CheckCount       int64                   `json:"check_count"`
 	ManufacturerTAC  *string                 `json:"manufacturer_tac,omitempty"`
 	ManufacturerName *string                 `json:"manufacturer_name,omitempty"`
+	Version          int64                   `json:"version"`
+	DeletedAt        *string                 `json:"deleted_at,omitempty"`
+	DeletedBy        *string                 `json:"deleted_by,omitempty"`
+}
+
+// HistoryEntryResponse represents one recorded change to an IMEI
+type HistoryEntryResponse struct {
+	ID             int64                   `json:"id"`
+	ChangeType     models.ChangeType       `json:"change_type"`
+	ChangedAt      string                  `json:"changed_at"`
+	ChangedBy      string                  `json:"changed_by"`
+	PreviousStatus *models.EquipmentStatus `json:"previous_status,omitempty"`
+	NewStatus      models.EquipmentStatus  `json:"new_status"`
+	PreviousReason *string                 `json:"previous_reason,omitempty"`
+	NewReason      *string                 `json:"new_reason,omitempty"`
+	ChangeDetails  models.ChangeDetails    `json:"change_details,omitempty"`
+}
+
+// HistoryResponse represents a page of an IMEI's change history, newest first
+type HistoryResponse struct {
+	IMEI    string                 `json:"imei"`
+	Offset  int                    `json:"offset"`
+	Limit   int                    `json:"limit"`
+	Entries []HistoryEntryResponse `json:"entries"`
+}
+
+// AuditEntryResponse represents one logged equipment check
+type AuditEntryResponse struct {
+	ID            int64                  `json:"id"`
+	IMEI          string                 `json:"imei"`
+	IMEISV        *string                `json:"imeisv,omitempty"`
+	Status        models.EquipmentStatus `json:"status"`
+	CheckTime     string                 `json:"check_time"`
+	OriginHost    *string                `json:"origin_host,omitempty"`
+	OriginRealm   *string                `json:"origin_realm,omitempty"`
+	UserName      *string                `json:"user_name,omitempty"`
+	SUPI          *string                `json:"supi,omitempty"`
+	GPSI          *string                `json:"gpsi,omitempty"`
+	RequestSource string                 `json:"request_source"`
+	SessionID     *string                `json:"session_id,omitempty"`
+	ResultCode    *int32                 `json:"result_code,omitempty"`
+}
+
+// AuditResponse represents a page of audit logs
+type AuditResponse struct {
+	Offset  int                  `json:"offset"`
+	Limit   int                  `json:"limit"`
+	Entries []AuditEntryResponse `json:"entries"`
+}
+
+// StatusContributionResponse represents what one kind of provisioning said
+// about an IMEI, with the history entries it was reconstructed from
+type StatusContributionResponse struct {
+	Source       models.StatusSource    `json:"source"`
+	Status       models.EquipmentStatus `json:"status"`
+	Effective    bool                   `json:"effective"`
+	History      []HistoryEntryResponse `json:"history"`
+	SnapshotID   *int64                 `json:"snapshot_id,omitempty"`
+	SnapshotTime *string                `json:"snapshot_time,omitempty"`
+}
+
+// StatusAtResponse represents the reconstructed status of an IMEI at a past moment
+type StatusAtResponse struct {
+	IMEI          string                       `json:"imei"`
+	At            string                       `json:"at"`
+	Status        *models.EquipmentStatus      `json:"status"`
+	Source        models.StatusSource          `json:"source,omitempty"`
+	Contributions []StatusContributionResponse `json:"contributions"`
+}
+
+// SnapshotResponse represents a saved state of an IMEI's equipment record
+type SnapshotResponse struct {
+	ID           int64                  `json:"id"`
+	IMEI         string                 `json:"imei"`
+	SnapshotTime string                 `json:"snapshot_time"`
+	SnapshotType string                 `json:"snapshot_type"`
+	CreatedBy    string                 `json:"created_by"`
+	Status       models.EquipmentStatus `json:"status"`
+	Reason       *string                `json:"reason,omitempty"`
+	CheckCount   int64                  `json:"check_count"`
+	Metadata     *string                `json:"metadata,omitempty"`
+}
+
+// SnapshotsResponse represents a page of an IMEI's snapshots, newest first
+type SnapshotsResponse struct {
+	IMEI      string             `json:"imei"`
+	Offset    int                `json:"offset"`
+	Limit     int                `json:"limit"`
+	Snapshots []SnapshotResponse `json:"snapshots"`
 }
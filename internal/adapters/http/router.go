@@ -2,7 +2,9 @@ package http
 
 import (
 	"fmt"
+	"net"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -96,13 +98,80 @@ func ginRecovery() gin.HandlerFunc {
 	}
 }
 
+// actorHeader names the caller on whose behalf a trusted proxy forwards a
+// management request
+const actorHeader = "X-Actor"
+
+// anonymousActor prefixes the client address of requests that neither
+// authenticate nor come through a trusted proxy
+const anonymousActor = "anonymous@"
+
+// requestActor attributes the changes a request makes to the actor of the API
+// token it carries as a bearer token. Without one, the X-Actor header is
+// believed only from a trusted proxy; any other request is recorded as
+// anonymous, by client address.
+func (h *Handler) requestActor(c *gin.Context) {
+	actor, ok := h.tokenActor(c.GetHeader("Authorization"))
+	if !ok {
+		actor = c.GetHeader(actorHeader)
+		if actor == "" || !h.trustedPeer(c.RemoteIP()) {
+			actor = anonymousActor + c.ClientIP()
+		}
+	}
+	c.Request = c.Request.WithContext(ports.WithActor(c.Request.Context(), actor))
+	c.Next()
+}
+
+// trustedPeer reports whether the connection came from a trusted proxy
+func (h *Handler) trustedPeer(address string) bool {
+	ip := net.ParseIP(address)
+	for _, proxy := range h.trustedProxies {
+		if ip != nil && proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies parses proxy addresses given as IPs or CIDR blocks
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 // SetupRouter creates and configures the HTTP router
 func SetupRouter(eirService ports.EIRService) *gin.Engine {
+	return setupRouter(NewHandler(eirService))
+}
+
+// setupRouter registers every route on a new engine backed by handler
+func setupRouter(handler *Handler) *gin.Engine {
 	// Set Gin to release mode to disable debug logging
 	gin.SetMode(gin.ReleaseMode)
 
-	// Create router without default middleware
+	// Create router without default middleware; client addresses are taken
+	// from X-Forwarded-For only once trusted proxies are set
 	router := gin.New()
+	_ = router.SetTrustedProxies(nil)
 
 	// Add custom recovery middleware (must be first)
 	router.Use(ginRecovery())
@@ -110,7 +179,8 @@ func SetupRouter(eirService ports.EIRService) *gin.Engine {
 	// Add custom logger middleware
 	router.Use(ginLogger())
 
-	handler := NewHandler(eirService)
+	// Attribute changes to the requesting actor
+	router.Use(handler.requestActor)
 
 	// 5G N5g-eir API (3GPP TS 29.511)
 	v1 := router.Group("/n5g-eir-eic/v1")
@@ -123,12 +193,23 @@ func SetupRouter(eirService ports.EIRService) *gin.Engine {
 	{
 		api.POST("/equipment", handler.ProvisionEquipment)
 		api.GET("/equipment/:imei", handler.GetEquipment)
+		api.GET("/equipment/:imei/history", handler.GetEquipmentHistory)
+		api.GET("/equipment/:imei/status", handler.GetStatusAt)
+		api.POST("/equipment/:imei/snapshots", handler.CreateSnapshot)
+		api.GET("/equipment/:imei/snapshots", handler.ListSnapshots)
+		api.POST("/equipment/:imei/snapshots/:id/restore", handler.RestoreSnapshot)
+		api.PUT("/equipment/:imei", handler.UpdateEquipment)
 		api.DELETE("/equipment/:imei", handler.DeleteEquipment)
+		api.POST("/equipment/:imei/undelete", handler.UndeleteEquipment)
 		api.GET("/equipment", handler.ListEquipment)
+		api.GET("/audits", handler.GetAudits)
 		api.GET("/check-imei/:imei", handler.GetCheckImei)
 		api.GET("/check-tac/:imei", handler.GetCheckTac)
 		api.POST("/insert-tac", handler.PostInsertTac)
 		api.POST("/insert-imei", handler.PostInsertImei)
+		api.GET("/admin/maintenance", handler.GetMaintenanceStatus)
+		api.POST("/admin/maintenance/run", handler.RunMaintenance)
+		api.GET("/export/:dataset", handler.ExportData)
 	}
 
 	// Health check
@@ -35,6 +35,7 @@ type Server struct {
 	httpServer *http.Server
 	listener   net.Listener
 	eirService ports.EIRService
+	handler    *Handler
 	router     *gin.Engine
 	logger     logger.Logger
 }
@@ -58,7 +59,8 @@ func NewServer(config ServerConfig, eirService ports.EIRService) *Server {
 		config.ShutdownTimeout = 10 * time.Second
 	}
 
-	router := SetupRouter(eirService)
+	handler := NewHandler(eirService)
+	router := setupRouter(handler)
 
 	// Initialize logger
 	log := logger.New("http-server", "debug")
@@ -66,11 +68,53 @@ func NewServer(config ServerConfig, eirService ports.EIRService) *Server {
 	return &Server{
 		config:     config,
 		eirService: eirService,
+		handler:    handler,
 		router:     router,
 		logger:     log,
 	}
 }
 
+// SetHealthReporter attaches the storage backend reported by GET /health
+func (s *Server) SetHealthReporter(reporter HealthReporter) {
+	s.handler.SetHealthReporter(reporter)
+}
+
+// SetMaintenanceRunner attaches the scheduler served under /api/v1/admin/maintenance
+func (s *Server) SetMaintenanceRunner(runner MaintenanceRunner) {
+	s.handler.SetMaintenanceRunner(runner)
+}
+
+// SetDataExporter attaches the exporter served under /api/v1/export
+func (s *Server) SetDataExporter(exporter ports.DataExporter) {
+	s.handler.SetDataExporter(exporter)
+}
+
+// SetAuditAccessTokens sets the bearer tokens that may read encrypted
+// identifiers from /api/v1/audits and start /api/v1/admin/maintenance/run
+func (s *Server) SetAuditAccessTokens(tokens []string) {
+	s.handler.SetAuditAccessTokens(tokens)
+}
+
+// SetAPITokens sets the bearer tokens that authenticate management requests,
+// each mapped to the actor the changes of its requests are recorded under
+func (s *Server) SetAPITokens(tokens map[string]string) {
+	s.handler.SetAPITokens(tokens)
+}
+
+// SetTrustedProxies sets the proxies, as IPs or CIDR blocks, whose X-Actor
+// and X-Forwarded-For headers are believed
+func (s *Server) SetTrustedProxies(proxies []string) error {
+	networks, err := ParseTrustedProxies(proxies)
+	if err != nil {
+		return err
+	}
+	if err := s.router.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+	s.handler.SetTrustedProxies(networks)
+	return nil
+}
+
 // Start starts the HTTP/2 server
 func (s *Server) Start() error {
 	// Create listener first (supports port 0 for testing)
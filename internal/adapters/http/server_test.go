@@ -9,14 +9,18 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hsdfat8/eir/internal/adapters/memory"
 	"github.com/hsdfat8/eir/internal/adapters/postgres"
 	"github.com/hsdfat8/eir/internal/adapters/testutil"
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
 	"github.com/hsdfat8/eir/internal/logger"
 	legacyModels "github.com/hsdfat8/eir/models"
 	"github.com/hsdfat8/eir/pkg/logic"
@@ -91,7 +95,7 @@ func (m *mockEIRService) CheckTac(ctx context.Context, imei string, status model
 	}, nil
 }
 
-func (m *mockEIRService) InsertImei(ctx context.Context, imei string, color string, status models.SystemStatus) (*ports.InsertImeiResult, error) {
+func (m *mockEIRService) InsertImei(ctx context.Context, imei string, color models.EquipmentStatus, status models.SystemStatus) (*ports.InsertImeiResult, error) {
 	// Convert domain model to legacy model
 	legacyStatus := legacyModels.SystemStatus{
 		OverloadLevel: status.OverloadLevel,
@@ -185,25 +189,95 @@ func (m *mockEIRService) ListAllImeiInfo() []*ports.ImeiInfo {
 	return m.imeiRepo.ListAllImeiInfo(ctx)
 }
 
+func (m *mockEIRService) UpdateEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	return equipment, nil
+}
+
 func (m *mockEIRService) RemoveEquipment(ctx context.Context, imei string) error {
 	return nil
 }
 
-func (m *mockEIRService) GetEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+func (m *mockEIRService) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	return []*models.AuditLog{}, nil
+}
+
+func (m *mockEIRService) GetAuditsBySUPI(ctx context.Context, supi string, offset, limit int) ([]*models.AuditLog, error) {
+	return []*models.AuditLog{}, nil
+}
+
+func (m *mockEIRService) UndeleteEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+	return &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}, nil
+}
+
+func (m *mockEIRService) ProvisionEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	provisioned := *equipment
+	provisioned.Version = 1
+	return &provisioned, nil
+}
+
+func (m *mockEIRService) GetEquipment(ctx context.Context, imei string, includeDeleted bool) (*models.Equipment, error) {
 	return &models.Equipment{
 		IMEI:   imei,
 		Status: models.EquipmentStatusWhitelisted,
 	}, nil
 }
 
-func (m *mockEIRService) ListEquipment(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+func (m *mockEIRService) ListEquipment(ctx context.Context, offset, limit int, includeDeleted bool) ([]*models.Equipment, error) {
 	return []*models.Equipment{}, nil
 }
 
+func (m *mockEIRService) GetEquipmentHistory(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return []*models.EquipmentHistory{}, nil
+}
+
+func (m *mockEIRService) GetStatusAt(ctx context.Context, imei string, at time.Time) (*models.PointInTimeStatus, error) {
+	return &models.PointInTimeStatus{IMEI: imei, At: at}, nil
+}
+
+func (m *mockEIRService) SnapshotEquipment(ctx context.Context, imei string) (*models.EquipmentSnapshot, error) {
+	return &models.EquipmentSnapshot{IMEI: imei, SnapshotType: models.SnapshotTypeManual}, nil
+}
+
+func (m *mockEIRService) SnapshotAllEquipment(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockEIRService) ListSnapshots(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error) {
+	return []*models.EquipmentSnapshot{}, nil
+}
+
+func (m *mockEIRService) RestoreSnapshot(ctx context.Context, imei string, snapshotID int64, expectedVersion int64) (*models.Equipment, error) {
+	return &models.Equipment{IMEI: imei}, nil
+}
+
+func (m *mockEIRService) PurgeSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockEIRService) SetLogger(l logger.Logger) {
 	// Mock implementation - no-op for testing
 }
 
+func (m *mockEIRService) SetDecisionCache(c ports.DecisionCache) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetCheckRecorder(r ports.CheckRecorder) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetTransactionProvider(p ports.TransactionProvider) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetHistoryRepository(h ports.HistoryRepository) {
+	// Mock implementation - no-op for testing
+}
+
+func (m *mockEIRService) SetSnapshotRepository(r ports.SnapshotRepository) {
+	// Mock implementation - no-op for testing
+}
+
 // TestServerHTTP1Basic tests basic HTTP/1.1 server
 func TestServerHTTP1Basic(t *testing.T) {
 	config := ServerConfig{
@@ -585,6 +659,274 @@ func TestServerGracefulShutdown(t *testing.T) {
 	t.Log("Graceful shutdown test passed")
 }
 
+// fakeHealthReporter is a HealthReporter with a fixed result
+type fakeHealthReporter struct {
+	err error
+}
+
+func (f *fakeHealthReporter) HealthCheck(ctx context.Context) error {
+	return f.err
+}
+
+func (f *fakeHealthReporter) GetConnectionStats() ports.ConnectionStats {
+	return ports.ConnectionStats{DatabaseType: "postgres", OpenConnections: 3, MaxConnections: 25, Healthy: f.err == nil}
+}
+
+// TestHealthCheckReportsDatabase tests that /health includes the storage backend state
+func TestHealthCheckReportsDatabase(t *testing.T) {
+	mockService := &mockEIRService{}
+
+	tests := []struct {
+		name       string
+		reporter   HealthReporter
+		wantStatus int
+		wantHealth string
+	}{
+		{name: "no database", reporter: nil, wantStatus: http.StatusOK, wantHealth: "healthy"},
+		{name: "healthy database", reporter: &fakeHealthReporter{}, wantStatus: http.StatusOK, wantHealth: "healthy"},
+		{name: "unhealthy database", reporter: &fakeHealthReporter{err: fmt.Errorf("ping failed")}, wantStatus: http.StatusServiceUnavailable, wantHealth: "unhealthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(mockService)
+			if tt.reporter != nil {
+				handler.SetHealthReporter(tt.reporter)
+			}
+			router := setupRouter(handler)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body["status"] != tt.wantHealth {
+				t.Errorf("Expected status %q, got %v", tt.wantHealth, body["status"])
+			}
+
+			_, hasDatabase := body["database"]
+			if hasDatabase != (tt.reporter != nil) {
+				t.Errorf("Expected database section present=%v, got %v", tt.reporter != nil, hasDatabase)
+			}
+		})
+	}
+}
+
+// fakeMaintenanceRunner is a MaintenanceRunner that counts the runs it starts
+type fakeMaintenanceRunner struct {
+	runs    int
+	running bool
+}
+
+func (f *fakeMaintenanceRunner) Status() models.MaintenanceStatus {
+	return models.MaintenanceStatus{Running: f.running}
+}
+
+func (f *fakeMaintenanceRunner) RunNow() error {
+	if f.running {
+		return service.ErrMaintenanceRunning
+	}
+	f.runs++
+	f.running = true
+	return nil
+}
+
+// TestMaintenanceEndpoints tests the maintenance status and run-now endpoints
+func TestMaintenanceEndpoints(t *testing.T) {
+	handler := NewHandler(&mockEIRService{})
+	router := setupRouter(handler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/maintenance", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without a maintenance runner, got %d", rec.Code)
+	}
+
+	runner := &fakeMaintenanceRunner{}
+	handler.SetMaintenanceRunner(runner)
+	handler.SetAuditAccessTokens([]string{"admin"})
+	handler.SetAPITokens(map[string]string{"operator": "ops"})
+
+	// Runs need an audit access token; an API token is not enough
+	for _, header := range []string{"", "Bearer operator", "Bearer guess"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/maintenance/run", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Fatalf("Expected 401 with a Bearer challenge for %q, got %d", header, rec.Code)
+		}
+	}
+
+	for _, want := range []int{http.StatusAccepted, http.StatusConflict} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/maintenance/run", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("Expected status %d, got %d", want, rec.Code)
+		}
+	}
+	if runner.runs != 1 {
+		t.Errorf("Expected one run to start, got %d", runner.runs)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/maintenance", nil))
+	var status models.MaintenanceStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !status.Running {
+		t.Errorf("Expected a running status, got %+v", status)
+	}
+}
+
+// actorRecorder is a mockEIRService that remembers the actor of the last
+// snapshot request
+type actorRecorder struct {
+	mockEIRService
+	actor string
+}
+
+func (r *actorRecorder) SnapshotEquipment(ctx context.Context, imei string) (*models.EquipmentSnapshot, error) {
+	r.actor = ports.ActorFromContext(ctx)
+	return &models.EquipmentSnapshot{IMEI: imei, CreatedBy: r.actor}, nil
+}
+
+// TestRequestActor tests which actor the changes of a request are attributed to
+func TestRequestActor(t *testing.T) {
+	recorder := &actorRecorder{}
+	handler := NewHandler(recorder)
+	handler.SetAPITokens(map[string]string{"secret": "ops"})
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.1", "172.16.0.0/12"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies failed: %v", err)
+	}
+	handler.SetTrustedProxies(proxies)
+	router := setupRouter(handler)
+
+	tests := []struct {
+		name   string
+		remote string
+		token  string
+		header string
+		want   string
+	}{
+		{name: "API token", remote: "192.0.2.1:1234", token: "secret", want: "ops"},
+		{name: "API token over X-Actor", remote: "10.0.0.1:1234", token: "secret", header: "mallory", want: "ops"},
+		{name: "trusted proxy", remote: "10.0.0.1:1234", header: "alice", want: "alice"},
+		{name: "trusted proxy block", remote: "172.16.5.5:1234", header: "bob", want: "bob"},
+		{name: "untrusted X-Actor", remote: "192.0.2.1:1234", header: "mallory", want: "anonymous@192.0.2.1"},
+		{name: "unknown token", remote: "192.0.2.1:1234", token: "guess", want: "anonymous@192.0.2.1"},
+		{name: "trusted proxy without X-Actor", remote: "10.0.0.1:1234", want: "anonymous@10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/equipment/490154203237518/snapshots", nil)
+			req.RemoteAddr = tt.remote
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Actor", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if recorder.actor != tt.want {
+				t.Errorf("Expected actor %q, got %q", tt.want, recorder.actor)
+			}
+		})
+	}
+
+	// API tokens may also read audits, only without identifier access
+	for token, want := range map[string]int{"secret": http.StatusOK, "guess": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audits?imei=490154203237518", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Expected %d for audits with token %q, got %d", want, token, rec.Code)
+		}
+	}
+
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("Expected a host name to be rejected as a trusted proxy")
+	}
+}
+
+func TestExportEndpoint(t *testing.T) {
+	handler := NewHandler(&mockEIRService{})
+	router := setupRouter(handler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/equipment", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without an exporter, got %d", rec.Code)
+	}
+
+	ctx := context.Background()
+	imeis := memory.NewInMemoryIMEIRepository()
+	audits := memory.NewInMemoryAuditRepository()
+	for _, imei := range []string{"490154203237518", "356938035643809"} {
+		if err := imeis.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	for _, day := range []int{1, 10, 20} {
+		at := time.Date(2025, 1, day, 12, 0, 0, 0, time.UTC)
+		if err := audits.LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", CheckTime: at}); err != nil {
+			t.Fatalf("LogCheck failed: %v", err)
+		}
+	}
+	handler.SetDataExporter(memory.NewDataExporter(imeis, audits, memory.NewInMemoryHistoryRepository()))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/equipment?format=csv", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Expected text/csv, got %q", got)
+	}
+	if lines := strings.Count(rec.Body.String(), "\n"); lines != 3 {
+		t.Errorf("Expected a header and two rows, got %d lines: %s", lines, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/audits?format=json&start=2025-01-05&end=2025-01-31", nil))
+	var exported []models.AuditLog
+	if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if len(exported) != 2 {
+		t.Errorf("Expected the two audits inside the range, got %d", len(exported))
+	}
+
+	for _, target := range []string{
+		"/api/v1/export/tacs",
+		"/api/v1/export/equipment?format=xml",
+		"/api/v1/export/history?start=yesterday",
+	} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+}
+
 func TestCheckImeiWithPCAP(t *testing.T) {
 	pcapFile := "http2_check_imei_8080_test.pcap"
 	pcapWriter, err := testutil.NewPCAPWriter(pcapFile)
@@ -635,7 +977,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 		mockService.ClearImeiInfo()
 
 		imei := "9"
-		color := "b"
+		color := models.EquipmentStatusBlacklisted
 
 		// Step 1: Insert IMEI with black color
 		insertReq := ports.ImeiInfoInsert{
@@ -687,7 +1029,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 		mockService.ClearImeiInfo()
 
 		imei := "912"
-		color := "g"
+		color := models.EquipmentStatusGreylisted
 
 		// Step 1: Insert IMEI with grey color
 		insertReq := ports.ImeiInfoInsert{
@@ -739,7 +1081,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 		mockService.ClearImeiInfo()
 
 		imei := "9123456789012"
-		color := "b"
+		color := models.EquipmentStatusBlacklisted
 
 		// Step 1: Insert IMEI with black color
 		insertReq := ports.ImeiInfoInsert{
@@ -791,7 +1133,7 @@ func TestCheckImeiWithPCAP(t *testing.T) {
 		mockService.ClearImeiInfo()
 
 		imei := "91234567895264"
-		color := "w"
+		color := models.EquipmentStatusWhitelisted
 
 		// Step 1: Insert IMEI with white color
 		insertReq := ports.ImeiInfoInsert{
@@ -935,7 +1277,7 @@ func TestCheckTacWithPCAP(t *testing.T) {
 		testCases := []struct {
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 			description   string
 		}{
 			{"35", "35", "black", "Single TAC - Blacklisted"},
@@ -997,43 +1339,43 @@ func TestCheckTacWithPCAP(t *testing.T) {
 	t.Run("Step2_CheckTacQueries", func(t *testing.T) {
 		testCases := []struct {
 			imei           string
-			expectedColor  string
+			expectedColor  models.EquipmentStatus
 			expectedStatus models.EquipmentStatus
 			description    string
 		}{
 			// Test exact matches
-			{"35", "black", models.EquipmentStatusBlacklisted, "Exact match - Blacklisted"},
-			{"353", "grey", models.EquipmentStatusGreylisted, "Exact match - Greylisted"},
-			{"3531", "white", models.EquipmentStatusWhitelisted, "Exact match - Whitelisted"},
+			{"35", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "Exact match - Blacklisted"},
+			{"353", models.EquipmentStatusGreylisted, models.EquipmentStatusGreylisted, "Exact match - Greylisted"},
+			{"3531", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Exact match - Whitelisted"},
 
 			// Test range matches
-			{"35310", "white", models.EquipmentStatusWhitelisted, "Start of range"},
-			{"35315", "white", models.EquipmentStatusWhitelisted, "Middle of range"},
-			{"35319", "white", models.EquipmentStatusWhitelisted, "End of range"},
-			{"353200", "grey", models.EquipmentStatusGreylisted, "Start of 100-value range"},
-			{"353250", "grey", models.EquipmentStatusGreylisted, "Middle of 100-value range"},
-			{"353299", "grey", models.EquipmentStatusGreylisted, "End of 100-value range"},
+			{"35310", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Start of range"},
+			{"35315", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Middle of range"},
+			{"35319", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "End of range"},
+			{"353200", models.EquipmentStatusGreylisted, models.EquipmentStatusGreylisted, "Start of 100-value range"},
+			{"353250", models.EquipmentStatusGreylisted, models.EquipmentStatusGreylisted, "Middle of 100-value range"},
+			{"353299", models.EquipmentStatusGreylisted, models.EquipmentStatusGreylisted, "End of 100-value range"},
 
 			// Test large range
-			{"3533000", "black", models.EquipmentStatusBlacklisted, "Start of large range"},
-			{"3533500", "black", models.EquipmentStatusBlacklisted, "Middle of large range"},
-			{"3533999", "black", models.EquipmentStatusBlacklisted, "End of large range"},
+			{"3533000", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "Start of large range"},
+			{"3533500", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "Middle of large range"},
+			{"3533999", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "End of large range"},
 
 			// Test very large range
-			{"35340000", "white", models.EquipmentStatusWhitelisted, "Start of very large range"},
-			{"35345000", "white", models.EquipmentStatusWhitelisted, "Middle of very large range"},
-			{"35349999", "white", models.EquipmentStatusWhitelisted, "End of very large range"},
+			{"35340000", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Start of very large range"},
+			{"35345000", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Middle of very large range"},
+			{"35349999", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "End of very large range"},
 
 			// Test specific IMEIs from CheckImei tests
-			{"9", "black", models.EquipmentStatusBlacklisted, "Single digit in black range"},
-			{"912", "grey", models.EquipmentStatusGreylisted, "Exact greylisted TAC"},
-			{"9123456789012", "black", models.EquipmentStatusBlacklisted, "Long blacklisted TAC"},
-			{"91234567895264", "white", models.EquipmentStatusWhitelisted, "Exact whitelisted IMEI"},
+			{"9", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "Single digit in black range"},
+			{"912", models.EquipmentStatusGreylisted, models.EquipmentStatusGreylisted, "Exact greylisted TAC"},
+			{"9123456789012", models.EquipmentStatusBlacklisted, models.EquipmentStatusBlacklisted, "Long blacklisted TAC"},
+			{"91234567895264", models.EquipmentStatusWhitelisted, models.EquipmentStatusWhitelisted, "Exact whitelisted IMEI"},
 
 			// Test out of range (should return unknown color with error status)
-			{"1", "unknown", models.EquipmentStatusWhitelisted, "Not in any range"},
-			{"88", "unknown", models.EquipmentStatusWhitelisted, "Below 90-99 range"},
-			{"100", "unknown", models.EquipmentStatusWhitelisted, "Above 90-99 range"},
+			{"1", models.CheckOutcomeUnknown, models.EquipmentStatusWhitelisted, "Not in any range"},
+			{"88", models.CheckOutcomeUnknown, models.EquipmentStatusWhitelisted, "Below 90-99 range"},
+			{"100", models.CheckOutcomeUnknown, models.EquipmentStatusWhitelisted, "Above 90-99 range"},
 		}
 
 		for _, tc := range testCases {
@@ -1055,10 +1397,10 @@ func TestCheckTacWithPCAP(t *testing.T) {
 			}
 
 			var result struct {
-				Status  string         `json:"status"`
-				IMEI    string         `json:"imei"`
-				Color   string         `json:"color"`
-				TacInfo *ports.TacInfo `json:"tac_info,omitempty"`
+				Status  string                 `json:"status"`
+				IMEI    string                 `json:"imei"`
+				Color   models.EquipmentStatus `json:"color"`
+				TacInfo *ports.TacInfo         `json:"tac_info,omitempty"`
 			}
 
 			if err := json.Unmarshal(bodyBytes, &result); err != nil {
@@ -1143,10 +1485,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "white"},
-			{"2-2", "2", "2", "white"},
+			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", models.EquipmentStatusWhitelisted},
+			{"2-2", "2", "2", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1194,14 +1536,14 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"111-1222", "111", "1222", "white"},
-			{"1223-13", "1223", "13", "white"},
-			{"123456789012345-123456789012349", "123456789012345", "123456789012349", "white"},
-			{"1-9", "1", "9", "white"},
-			{"4-4234567890123456", "4", "4234567890123456", "white"},
-			{"1234567890123456-1234567890123457", "1234567890123456", "1234567890123457", "white"},
+			{"111-1222", "111", "1222", models.EquipmentStatusWhitelisted},
+			{"1223-13", "1223", "13", models.EquipmentStatusWhitelisted},
+			{"123456789012345-123456789012349", "123456789012345", "123456789012349", models.EquipmentStatusWhitelisted},
+			{"1-9", "1", "9", models.EquipmentStatusWhitelisted},
+			{"4-4234567890123456", "4", "4234567890123456", models.EquipmentStatusWhitelisted},
+			{"1234567890123456-1234567890123457", "1234567890123456", "1234567890123457", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1252,11 +1594,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"133-133", "133", "133", "white"},
-			{"132-132", "132", "132", "white"},
-			{"134-134", "134", "134", "white"},
+			{"133-133", "133", "133", models.EquipmentStatusWhitelisted},
+			{"132-132", "132", "132", models.EquipmentStatusWhitelisted},
+			{"134-134", "134", "134", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1308,10 +1650,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"133-135", "133", "135", "white"},
-			{"133-139", "133", "139", "white"},
+			{"133-135", "133", "135", models.EquipmentStatusWhitelisted},
+			{"133-139", "133", "139", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1363,13 +1705,13 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1222-1999", "1222", "1999", "white"},
-			{"1222-1333", "1222", "1333", "white"},
-			{"1666-1999", "1666", "1999", "white"},
-			{"1888-1888", "1888", "1888", "white"},
-			{"1222345-1222345", "1222345", "1222345", "white"},
+			{"1222-1999", "1222", "1999", models.EquipmentStatusWhitelisted},
+			{"1222-1333", "1222", "1333", models.EquipmentStatusWhitelisted},
+			{"1666-1999", "1666", "1999", models.EquipmentStatusWhitelisted},
+			{"1888-1888", "1888", "1888", models.EquipmentStatusWhitelisted},
+			{"1222345-1222345", "1222345", "1222345", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1421,11 +1763,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1222-1666", "1222", "1666", "white"},
-			{"1333-1555", "1333", "1555", "white"},
-			{"1777-1888", "1777", "1888", "white"},
+			{"1222-1666", "1222", "1666", models.EquipmentStatusWhitelisted},
+			{"1333-1555", "1333", "1555", models.EquipmentStatusWhitelisted},
+			{"1777-1888", "1777", "1888", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1477,11 +1819,11 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"9-1", "9", "1", "white"},
-			{"abcd12354-12345a@#@#$@#", "abcd12354", "12345a@#@#$@#", "white"},
-			{"\"1234 56789-12345\"", "\"1234 56789\"", "12345", "white"},
+			{"9-1", "9", "1", models.EquipmentStatusWhitelisted},
+			{"abcd12354-12345a@#@#$@#", "abcd12354", "12345a@#@#$@#", models.EquipmentStatusWhitelisted},
+			{"\"1234 56789-12345\"", "\"1234 56789\"", "12345", models.EquipmentStatusWhitelisted},
 		}
 
 		for _, tc := range testCases {
@@ -1533,7 +1875,7 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
 			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "."},
 			{"1134567890123456-1134567890123456", "1134567890123456", "1134567890123456", "g"},
@@ -1588,10 +1930,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", "white"},
-			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", "white"},
+			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", models.EquipmentStatusWhitelisted},
+			{"12345678901234567-12345678901234567", "12345678901234567", "12345678901234567", models.EquipmentStatusWhitelisted},
 		}
 		i := 0
 		for _, tc := range testCases {
@@ -1645,10 +1987,10 @@ func TestInsertTacWithPCAP(t *testing.T) {
 			keyTac        string
 			startRangeTac string
 			endRangeTac   string
-			color         string
+			color         models.EquipmentStatus
 		}{
-			{"1234-1235", "1234", "1235", "white"},
-			{"1232-1234", "1232", "1234", "white"},
+			{"1234-1235", "1234", "1235", models.EquipmentStatusWhitelisted},
+			{"1232-1234", "1232", "1234", models.EquipmentStatusWhitelisted},
 		}
 		i := 0
 		for _, tc := range testCases {
@@ -1750,11 +2092,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"1", "w", false, "Valid IMEI - white"},
+			{"1", models.EquipmentStatusWhitelisted, false, "Valid IMEI - white"},
 		}
 
 		for _, tc := range testCases {
@@ -1816,11 +2158,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"123456789012345", "g", false, "Valid IMEI - grey"},
+			{"123456789012345", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -1882,11 +2224,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234", "g", false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -1948,11 +2290,11 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234567", "g", false, "Valid IMEI - grey"},
+			{"12345678901234567", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
@@ -2014,19 +2356,19 @@ func TestInsertImeiWithPCAP(t *testing.T) {
 
 		testCases := []struct {
 			imei          string
-			color         string
+			color         models.EquipmentStatus
 			expectedError bool
 			description   string
 		}{
-			{"12345678901234", "g", false, "Valid IMEI - grey"},
-			{"123456789012341", "g", false, "Valid IMEI - grey"},
-			{"1234567890123411", "g", false, "Valid IMEI - grey"},
-			{"12345678901234111", "g", false, "Valid IMEI - grey"},
-			{"12345678901234112", "g", false, "Valid IMEI - grey"},
-			{"12345678901234222", "g", false, "Valid IMEI - grey"},
-			{"12345678901234333", "g", false, "Valid IMEI - grey"},
-			{"12345678901234", "g", false, "Valid IMEI - grey"},
-			{"12345678901234444", "g", false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"123456789012341", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"1234567890123411", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234111", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234112", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234222", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234333", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
+			{"12345678901234444", models.EquipmentStatusGreylisted, false, "Valid IMEI - grey"},
 		}
 
 		for _, tc := range testCases {
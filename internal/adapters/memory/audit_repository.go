@@ -2,17 +2,21 @@ package memory
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
 )
 
-// InMemoryAuditRepository is an in-memory implementation for testing
+// InMemoryAuditRepository is an in-memory implementation for testing. It
+// also implements ports.AuditChainStore.
 type InMemoryAuditRepository struct {
-	mu     sync.RWMutex
-	audits []*models.AuditLog
-	nextID int64
+	mu          sync.RWMutex
+	audits      []*models.AuditLog
+	nextID      int64
+	heads       map[string]*models.AuditChainHead
+	checkpoints []*models.AuditCheckpoint
 }
 
 // NewInMemoryAuditRepository creates a new in-memory audit repository
@@ -20,6 +24,7 @@ func NewInMemoryAuditRepository() ports.AuditRepository {
 	return &InMemoryAuditRepository{
 		audits: make([]*models.AuditLog, 0),
 		nextID: 1,
+		heads:  make(map[string]*models.AuditChainHead),
 	}
 }
 
@@ -27,6 +32,11 @@ func (r *InMemoryAuditRepository) LogCheck(ctx context.Context, audit *models.Au
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	partition := audit.PrepareChainLink()
+	audit.LinkTo(r.heads[partition])
+	audit.Hash = audit.ChainHash()
+	r.heads[partition] = audit.ChainHead()
+
 	audit.ID = r.nextID
 	r.nextID++
 	r.audits = append(r.audits, audit)
@@ -71,3 +81,97 @@ func (r *InMemoryAuditRepository) GetAuditsByTimeRange(ctx context.Context, star
 	}
 	return result, nil
 }
+
+func (r *InMemoryAuditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(supis))
+	for _, supi := range supis {
+		wanted[supi] = true
+	}
+	matches := func(value *string) bool { return value != nil && wanted[*value] }
+
+	result := make([]*models.AuditLog, 0)
+	count := 0
+	for i := len(r.audits) - 1; i >= 0; i-- {
+		audit := r.audits[i]
+		if matches(audit.SUPI) || matches(audit.SUPIIndex) {
+			if count >= offset {
+				result = append(result, audit)
+				if len(result) >= limit {
+					break
+				}
+			}
+			count++
+		}
+	}
+	return result, nil
+}
+
+func (r *InMemoryAuditRepository) GetAuditChainPartitions(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	partitions := make([]string, 0, len(r.heads))
+	for partition := range r.heads {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+	return partitions, nil
+}
+
+func (r *InMemoryAuditRepository) GetAuditChainHead(ctx context.Context, partition string) (*models.AuditChainHead, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if head, ok := r.heads[partition]; ok {
+		copied := *head
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *InMemoryAuditRepository) WalkAuditChain(ctx context.Context, partition string, fn func(audit *models.AuditLogExtended) error) error {
+	r.mu.RLock()
+	chain := make([]*models.AuditLogExtended, 0)
+	for _, audit := range r.audits {
+		if audit.ChainPartition == partition {
+			chain = append(chain, &models.AuditLogExtended{AuditLog: *audit})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(chain, func(i, j int) bool { return chain[i].ChainSeq < chain[j].ChainSeq })
+	for _, audit := range chain {
+		if err := fn(audit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryAuditRepository) SaveAuditCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checkpoint.ID = int64(len(r.checkpoints) + 1)
+	copied := *checkpoint
+	r.checkpoints = append(r.checkpoints, &copied)
+	return nil
+}
+
+func (r *InMemoryAuditRepository) GetAuditCheckpoints(ctx context.Context, partition string) ([]*models.AuditCheckpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	checkpoints := make([]*models.AuditCheckpoint, 0)
+	for _, checkpoint := range r.checkpoints {
+		if checkpoint.Partition == partition {
+			copied := *checkpoint
+			checkpoints = append(checkpoints, &copied)
+		}
+	}
+	sort.SliceStable(checkpoints, func(i, j int) bool { return checkpoints[i].Seq < checkpoints[j].Seq })
+	return checkpoints, nil
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// DecisionCacheConfig sizes the in-process decision cache
+type DecisionCacheConfig struct {
+	Shards      int           // Number of independently locked LRU shards
+	Capacity    int           // Total entries across all shards
+	PositiveTTL time.Duration // Lifetime of "ok" decisions
+	NegativeTTL time.Duration // Lifetime of "unknown"/error decisions
+}
+
+type decisionKey struct {
+	kind ports.CheckKind
+	imei string
+}
+
+type decisionEntry struct {
+	key      decisionKey
+	decision ports.CheckDecision
+	expires  time.Time
+}
+
+type decisionShard struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[decisionKey]*list.Element
+	lru      *list.List // front = most recently used
+}
+
+// DecisionCache is a sharded LRU of check decisions. Invalidation scans the
+// cached entries rather than the affected range, so its cost is bounded by the
+// cache size even when a TAC range covers millions of IMEIs.
+type DecisionCache struct {
+	shards      []*decisionShard
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+	generation  atomic.Uint64
+	now         func() time.Time
+}
+
+// NewDecisionCache creates a new in-process decision cache
+func NewDecisionCache(cfg DecisionCacheConfig) *DecisionCache {
+	shards := cfg.Shards
+	if shards < 1 {
+		shards = 1
+	}
+	perShard := cfg.Capacity / shards
+	if perShard < 1 {
+		perShard = 1
+	}
+
+	c := &DecisionCache{
+		shards:      make([]*decisionShard, shards),
+		positiveTTL: cfg.PositiveTTL,
+		negativeTTL: cfg.NegativeTTL,
+		now:         time.Now,
+	}
+	for i := range c.shards {
+		c.shards[i] = &decisionShard{
+			capacity: perShard,
+			items:    make(map[decisionKey]*list.Element),
+			lru:      list.New(),
+		}
+	}
+	return c
+}
+
+var _ ports.DecisionCache = (*DecisionCache)(nil)
+
+func (c *DecisionCache) shard(imei string) *decisionShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(imei))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *DecisionCache) Generation() uint64 {
+	return c.generation.Load()
+}
+
+func (c *DecisionCache) Get(kind ports.CheckKind, imei string) (*ports.CheckDecision, bool) {
+	s := c.shard(imei)
+	key := decisionKey{kind: kind, imei: imei}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		logger.CacheHitTotal.WithLabelValues("decision", "miss").Inc()
+		return nil, false
+	}
+	entry := elem.Value.(*decisionEntry)
+	if c.now().After(entry.expires) {
+		s.lru.Remove(elem)
+		delete(s.items, key)
+		logger.CacheHitTotal.WithLabelValues("decision", "miss").Inc()
+		return nil, false
+	}
+
+	s.lru.MoveToFront(elem)
+	logger.CacheHitTotal.WithLabelValues("decision", "hit").Inc()
+	decision := entry.decision
+	return &decision, true
+}
+
+func (c *DecisionCache) Set(kind ports.CheckKind, imei string, generation uint64, decision *ports.CheckDecision) {
+	ttl := c.positiveTTL
+	if decision.Status != "ok" {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	s := c.shard(imei)
+	key := decisionKey{kind: kind, imei: imei}
+	entry := &decisionEntry{key: key, decision: *decision, expires: c.now().Add(ttl)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Invalidations bump the generation before scanning the shards, so a
+	// decision computed from data that has since changed never lands here
+	if c.generation.Load() != generation {
+		return
+	}
+
+	if elem, ok := s.items[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return
+	}
+
+	s.items[key] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.items, oldest.Value.(*decisionEntry).key)
+	}
+}
+
+func (c *DecisionCache) Invalidate(kind ports.CheckKind, imei string) {
+	c.generation.Add(1)
+
+	s := c.shard(imei)
+	key := decisionKey{kind: kind, imei: imei}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.lru.Remove(elem)
+		delete(s.items, key)
+	}
+}
+
+func (c *DecisionCache) InvalidateMatching(kind ports.CheckKind, match func(imei string) bool) int {
+	c.generation.Add(1)
+
+	removed := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, elem := range s.items {
+			if key.kind == kind && match(key.imei) {
+				s.lru.Remove(elem)
+				delete(s.items, key)
+				removed++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return removed
+}
+
+func (c *DecisionCache) Purge(kind ports.CheckKind) {
+	c.InvalidateMatching(kind, func(string) bool { return true })
+}
+
+// Len returns the number of cached decisions, including expired ones not yet evicted
+func (c *DecisionCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDecisionCache(capacity int) (*DecisionCache, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewDecisionCache(DecisionCacheConfig{
+		Shards:      4,
+		Capacity:    capacity,
+		PositiveTTL: time.Minute,
+		NegativeTTL: 10 * time.Second,
+	})
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestDecisionCacheGetSet(t *testing.T) {
+	c, _ := newTestDecisionCache(100)
+	imei := "490154203237518"
+
+	hits := testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("decision", "hit"))
+	misses := testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("decision", "miss"))
+
+	_, ok := c.Get(ports.CheckKindTAC, imei)
+	assert.False(t, ok)
+
+	c.Set(ports.CheckKindTAC, imei, c.Generation(), &ports.CheckDecision{Status: "ok", IMEI: imei, Color: models.EquipmentStatusBlacklisted})
+
+	d, ok := c.Get(ports.CheckKindTAC, imei)
+	require.True(t, ok)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, d.Color)
+
+	// Kinds are cached independently
+	_, ok = c.Get(ports.CheckKindIMEI, imei)
+	assert.False(t, ok)
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("decision", "hit")))
+	assert.Equal(t, misses+2, testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("decision", "miss")))
+}
+
+func TestDecisionCacheSeparateTTLs(t *testing.T) {
+	c, now := newTestDecisionCache(100)
+
+	c.Set(ports.CheckKindTAC, "111111111111111", c.Generation(), &ports.CheckDecision{Status: "ok", Color: models.EquipmentStatusWhitelisted})
+	c.Set(ports.CheckKindTAC, "222222222222222", c.Generation(), &ports.CheckDecision{Status: "error", Color: "unknown"})
+
+	*now = now.Add(30 * time.Second)
+
+	_, ok := c.Get(ports.CheckKindTAC, "111111111111111")
+	assert.True(t, ok, "positive entry should outlive the negative TTL")
+	_, ok = c.Get(ports.CheckKindTAC, "222222222222222")
+	assert.False(t, ok, "negative entry should expire after the negative TTL")
+
+	*now = now.Add(time.Minute)
+	_, ok = c.Get(ports.CheckKindTAC, "111111111111111")
+	assert.False(t, ok)
+}
+
+func TestDecisionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewDecisionCache(DecisionCacheConfig{Shards: 1, Capacity: 2, PositiveTTL: time.Minute})
+	d := &ports.CheckDecision{Status: "ok", Color: models.EquipmentStatusWhitelisted}
+
+	c.Set(ports.CheckKindIMEI, "1", c.Generation(), d)
+	c.Set(ports.CheckKindIMEI, "2", c.Generation(), d)
+	_, _ = c.Get(ports.CheckKindIMEI, "1")
+	c.Set(ports.CheckKindIMEI, "3", c.Generation(), d)
+
+	_, ok := c.Get(ports.CheckKindIMEI, "2")
+	assert.False(t, ok)
+	_, ok = c.Get(ports.CheckKindIMEI, "1")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestDecisionCacheStaleSetDropped(t *testing.T) {
+	c, _ := newTestDecisionCache(100)
+
+	generation := c.Generation()
+	c.Invalidate(ports.CheckKindTAC, "333333333333333")
+	c.Set(ports.CheckKindTAC, "490154203237518", generation, &ports.CheckDecision{Status: "ok", Color: models.EquipmentStatusGreylisted})
+
+	_, ok := c.Get(ports.CheckKindTAC, "490154203237518")
+	assert.False(t, ok, "decision computed before an invalidation must not be cached")
+}
+
+func TestDecisionCacheInvalidateMatching(t *testing.T) {
+	c, _ := newTestDecisionCache(100)
+	d := &ports.CheckDecision{Status: "error", Color: "unknown"}
+
+	for _, imei := range []string{"490154203237518", "490154209999999", "356938035643809"} {
+		c.Set(ports.CheckKindTAC, imei, c.Generation(), d)
+		c.Set(ports.CheckKindIMEI, imei, c.Generation(), d)
+	}
+
+	n := c.InvalidateMatching(ports.CheckKindTAC, func(imei string) bool {
+		return strings.HasPrefix(imei, "49015420")
+	})
+	assert.Equal(t, 2, n)
+
+	_, ok := c.Get(ports.CheckKindTAC, "490154203237518")
+	assert.False(t, ok)
+	_, ok = c.Get(ports.CheckKindTAC, "356938035643809")
+	assert.True(t, ok)
+	_, ok = c.Get(ports.CheckKindIMEI, "490154203237518")
+	assert.True(t, ok, "other kinds are left alone")
+
+	c.Purge(ports.CheckKindIMEI)
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/export"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// exportPageSize is how many records are read from a repository at a time
+const exportPageSize = 1000
+
+// dataExporter pages through the repository ports. It backs the in-memory
+// store and any adapter without a native exporter.
+type dataExporter struct {
+	imeis   ports.IMEIRepository
+	audits  ports.AuditRepository
+	history ports.HistoryRepository
+}
+
+// NewDataExporter creates a data exporter reading through the given repositories
+func NewDataExporter(imeis ports.IMEIRepository, audits ports.AuditRepository, history ports.HistoryRepository) ports.DataExporter {
+	return &dataExporter{imeis: imeis, audits: audits, history: history}
+}
+
+// ExportEquipment exports all equipment but tombstones, in the repository's list order
+func (e *dataExporter) ExportEquipment(ctx context.Context, writer io.Writer, format string) error {
+	w, err := export.NewEquipmentWriter(writer, format)
+	if err != nil {
+		return err
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		page, err := e.imeis.List(ctx, offset, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list equipment for export: %w", err)
+		}
+		for _, equipment := range page {
+			if err := w.Write(equipment); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return w.Close()
+		}
+	}
+}
+
+// ExportAudits exports audit logs checked within the range
+func (e *dataExporter) ExportAudits(ctx context.Context, writer io.Writer, format string, startTime, endTime string) error {
+	timeRange, err := export.ParseTimeRange(startTime, endTime)
+	if err != nil {
+		return err
+	}
+	w, err := export.NewAuditWriter(writer, format)
+	if err != nil {
+		return err
+	}
+
+	start, end := timeRange.Bounds()
+	from, to := start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano)
+	for offset := 0; ; offset += exportPageSize {
+		page, err := e.audits.GetAuditsByTimeRange(ctx, from, to, offset, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list audits for export: %w", err)
+		}
+		for _, audit := range page {
+			// Not every repository applies the range itself
+			if !timeRange.Contains(audit.CheckTime) {
+				continue
+			}
+			if err := w.Write(audit); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return w.Close()
+		}
+	}
+}
+
+// ExportHistory exports change history recorded within the range
+func (e *dataExporter) ExportHistory(ctx context.Context, writer io.Writer, format string, startTime, endTime string) error {
+	timeRange, err := export.ParseTimeRange(startTime, endTime)
+	if err != nil {
+		return err
+	}
+	w, err := export.NewHistoryWriter(writer, format)
+	if err != nil {
+		return err
+	}
+
+	start, end := timeRange.Bounds()
+	for offset := 0; ; offset += exportPageSize {
+		page, err := e.history.GetHistoryByTimeRange(ctx, start, end, offset, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list history for export: %w", err)
+		}
+		for _, entry := range page {
+			if err := w.Write(entry); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return w.Close()
+		}
+	}
+}
+
+// Close releases the exporter. The repositories belong to the caller.
+func (e *dataExporter) Close() error {
+	return nil
+}
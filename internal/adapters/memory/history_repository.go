@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// InMemoryHistoryRepository is an in-memory implementation for testing
+type InMemoryHistoryRepository struct {
+	mu      sync.RWMutex
+	history []*models.EquipmentHistory
+	nextID  int64
+}
+
+// NewInMemoryHistoryRepository creates a new in-memory history repository
+func NewInMemoryHistoryRepository() ports.HistoryRepository {
+	return &InMemoryHistoryRepository{
+		history: make([]*models.EquipmentHistory, 0),
+		nextID:  1,
+	}
+}
+
+func (r *InMemoryHistoryRepository) RecordChange(ctx context.Context, history *models.EquipmentHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if history.ChangedAt.IsZero() {
+		history.ChangedAt = time.Now()
+	}
+	history.ID = r.nextID
+	r.nextID++
+	r.history = append(r.history, history)
+	return nil
+}
+
+func (r *InMemoryHistoryRepository) GetHistoryByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.newestFirst(offset, limit, func(h *models.EquipmentHistory) bool {
+		return h.IMEI == imei
+	}), nil
+}
+
+func (r *InMemoryHistoryRepository) GetHistoryByTimeRange(ctx context.Context, startTime, endTime time.Time, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.newestFirst(offset, limit, func(h *models.EquipmentHistory) bool {
+		return !h.ChangedAt.Before(startTime) && !h.ChangedAt.After(endTime)
+	}), nil
+}
+
+func (r *InMemoryHistoryRepository) GetHistoryByChangeType(ctx context.Context, changeType models.ChangeType, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.newestFirst(offset, limit, func(h *models.EquipmentHistory) bool {
+		return h.ChangeType == changeType
+	}), nil
+}
+
+// GetListHistory returns the changes made at or before until to the IMEI_INFO
+// entry of startImei and to the TAC ranges enclosing tac, oldest first
+func (r *InMemoryHistoryRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	matched := r.newestFirst(0, -1, func(h *models.EquipmentHistory) bool {
+		return !h.ChangedAt.After(until) && ports.InListHistory(h, startImei, tac)
+	})
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched, nil
+}
+
+// newestFirst pages through the matching entries ordered like the database
+// adapters: most recent change first, later records first on ties
+func (r *InMemoryHistoryRepository) newestFirst(offset, limit int, match func(*models.EquipmentHistory) bool) []*models.EquipmentHistory {
+	r.mu.RLock()
+	matched := make([]*models.EquipmentHistory, 0)
+	for _, h := range r.history {
+		if match(h) {
+			matched = append(matched, h)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].ChangedAt.Equal(matched[j].ChangedAt) {
+			return matched[i].ChangedAt.After(matched[j].ChangedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	if offset >= len(matched) {
+		return []*models.EquipmentHistory{}
+	}
+	matched = matched[offset:]
+	if limit >= 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched
+}
@@ -3,11 +3,13 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/google/btree"
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
-	"github.com/hsdfat8/eir/internal/logger"
 )
 
 // InMemoryIMEIRepository is an in-memory implementation for testing
@@ -17,15 +19,29 @@ type InMemoryIMEIRepository struct {
 	nextID    int64
 	// For IMEI/TAC logic operations
 	imeiData map[string]*ports.ImeiInfo
-	tacData  map[string]*ports.TacInfo
+	// TAC ranges ordered by KeyTac, so Prev/Next are O(log n)
+	tacData *btree.BTreeG[*ports.TacInfo]
+}
+
+// tacTreeDegree keeps nodes around a few cache lines wide
+const tacTreeDegree = 32
+
+func newTacTree() *btree.BTreeG[*ports.TacInfo] {
+	return btree.NewG(tacTreeDegree, func(a, b *ports.TacInfo) bool {
+		return a.KeyTac < b.KeyTac
+	})
 }
 
 // NewInMemoryIMEIRepository creates a new in-memory IMEI repository
 func NewInMemoryIMEIRepository() ports.IMEIRepository {
+	return newInMemoryIMEIRepository()
+}
+
+func newInMemoryIMEIRepository() *InMemoryIMEIRepository {
 	return &InMemoryIMEIRepository{
 		equipment: make(map[string]*models.Equipment),
 		imeiData:  make(map[string]*ports.ImeiInfo),
-		tacData:   make(map[string]*ports.TacInfo),
+		tacData:   newTacTree(),
 		nextID:    1,
 	}
 }
@@ -62,6 +78,7 @@ func (r *InMemoryIMEIRepository) Create(ctx context.Context, equipment *models.E
 
 	equipment.ID = r.nextID
 	r.nextID++
+	equipment.Version = 1
 	r.equipment[equipment.IMEI] = equipment
 	return nil
 }
@@ -70,10 +87,16 @@ func (r *InMemoryIMEIRepository) Update(ctx context.Context, equipment *models.E
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.equipment[equipment.IMEI]; !exists {
+	current, exists := r.equipment[equipment.IMEI]
+	if !exists {
 		return fmt.Errorf("equipment not found")
 	}
+	version, err := ports.NextVersion("equipment", equipment.IMEI, equipment.Version, current.Version)
+	if err != nil {
+		return err
+	}
 
+	equipment.Version = version
 	r.equipment[equipment.IMEI] = equipment
 	return nil
 }
@@ -91,41 +114,70 @@ func (r *InMemoryIMEIRepository) Delete(ctx context.Context, imei string) error
 }
 
 func (r *InMemoryIMEIRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	return r.page(offset, limit, func(e *models.Equipment) bool { return !e.IsDeleted() }), nil
+}
 
-	result := make([]*models.Equipment, 0)
-	count := 0
-	for _, equip := range r.equipment {
-		if count >= offset {
-			result = append(result, equip)
-			if len(result) >= limit {
-				break
-			}
+func (r *InMemoryIMEIRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.page(offset, limit, func(*models.Equipment) bool { return true }), nil
+}
+
+func (r *InMemoryIMEIRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+	return r.page(offset, limit, func(e *models.Equipment) bool { return e.Status == status && !e.IsDeleted() }), nil
+}
+
+func (r *InMemoryIMEIRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return int64(len(r.purgeDeleted(before, limit))), nil
+}
+
+// purgeDeleted removes at most limit tombstones deleted before the given time
+// and returns their IMEIs
+func (r *InMemoryIMEIRepository) purgeDeleted(before time.Time, limit int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged []string
+	for imei, equip := range r.equipment {
+		if len(purged) >= limit {
+			break
+		}
+		if equip.IsDeleted() && equip.DeletedAt.Before(before) {
+			delete(r.equipment, imei)
+			purged = append(purged, imei)
 		}
-		count++
 	}
-	return result, nil
+	return purged
 }
 
-func (r *InMemoryIMEIRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+func (r *InMemoryIMEIRepository) page(offset, limit int, match func(*models.Equipment) bool) []*models.Equipment {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	result := make([]*models.Equipment, 0)
-	count := 0
+	matched := make([]*models.Equipment, 0)
 	for _, equip := range r.equipment {
-		if equip.Status == status {
-			if count >= offset {
-				result = append(result, equip)
-				if len(result) >= limit {
-					break
-				}
-			}
-			count++
+		if match(equip) {
+			matched = append(matched, equip)
+		}
+	}
+	r.mu.RUnlock()
+	return pageEquipment(matched, offset, limit)
+}
+
+// pageEquipment orders equipment like the database adapters, most recently
+// updated first, so that offsets are stable between calls
+func pageEquipment(matched []*models.Equipment, offset, limit int) []*models.Equipment {
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].LastUpdated.Equal(matched[j].LastUpdated) {
+			return matched[i].LastUpdated.After(matched[j].LastUpdated)
 		}
+		return matched[i].IMEI < matched[j].IMEI
+	})
+
+	if offset >= len(matched) {
+		return []*models.Equipment{}
+	}
+	matched = matched[offset:]
+	if limit >= 0 && limit < len(matched) {
+		matched = matched[:limit]
 	}
-	return result, nil
+	return matched
 }
 
 func (r *InMemoryIMEIRepository) IncrementCheckCount(ctx context.Context, imei string) error {
@@ -139,6 +191,10 @@ func (r *InMemoryIMEIRepository) IncrementCheckCount(ctx context.Context, imei s
 	return fmt.Errorf("equipment not found")
 }
 
+func (r *InMemoryIMEIRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	return r.apply(&walRecord{Op: opCheckCounts, Counts: deltas})
+}
+
 // IMEI logic operations
 func (r *InMemoryIMEIRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
 	r.mu.RLock()
@@ -152,10 +208,36 @@ func (r *InMemoryIMEIRepository) SaveImeiInfo(ctx context.Context, info *ports.I
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	var actual int64
+	if current, ok := r.imeiData[info.StartIMEI]; ok {
+		actual = current.Version
+	}
+	version, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, actual)
+	if err != nil {
+		return err
+	}
+
+	info.Version = version
 	r.imeiData[info.StartIMEI] = info
 	return nil
 }
 
+func (r *InMemoryIMEIRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var actual int64
+	if current, ok := r.imeiData[info.StartIMEI]; ok {
+		actual = current.Version
+	}
+	if _, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, actual); err != nil {
+		return err
+	}
+
+	delete(r.imeiData, info.StartIMEI)
+	return nil
+}
+
 func (r *InMemoryIMEIRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -179,67 +261,92 @@ func (r *InMemoryIMEIRepository) SaveTacInfo(ctx context.Context, info *ports.Ta
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.tacData[info.KeyTac] = info
-	return nil
+	return saveTac(r.tacData, info)
 }
 
 func (r *InMemoryIMEIRepository) LookupTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	info, ok := r.tacData[key]
-	return info, ok
+	return r.tacData.Get(&ports.TacInfo{KeyTac: key})
 }
 
 func (r *InMemoryIMEIRepository) PrevTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var prev *ports.TacInfo
-	for k, v := range r.tacData {
-		if k < key {
-			if prev == nil || k > prev.KeyTac {
-				prev = v
-			}
-		}
-	}
-	if prev == nil {
-		return nil, false
-	}
-	return prev, true
+	return prevTac(r.tacData, key)
 }
 
 func (r *InMemoryIMEIRepository) NextTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var next *ports.TacInfo
-	for k, v := range r.tacData {
-		logger.Log.Debugw("find next", "key", key, "data", k, "value", v)
-		if k > key {
-			if next == nil || k < next.KeyTac {
-				next = v
-			}
-		}
-	}
-	if next == nil {
-		return nil, false
-	}
-	return next, true
+	return nextTac(r.tacData, key)
 }
 
+// ListAllTacInfo returns the ranges in key order
 func (r *InMemoryIMEIRepository) ListAllTacInfo(ctx context.Context) []*ports.TacInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	result := make([]*ports.TacInfo, 0, len(r.tacData))
-	for _, info := range r.tacData {
-		result = append(result, info)
-	}
-	return result
+	return listTac(r.tacData)
 }
 
-// ClearTacInfo implements ports.IMEIRepository.
 func (r *InMemoryIMEIRepository) ClearTacInfo(ctx context.Context) {
-	fmt.Println("unimplemented")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tacData = newTacTree()
+}
+
+// saveTac stores info in tree if its version check passes
+func saveTac(tree *btree.BTreeG[*ports.TacInfo], info *ports.TacInfo) error {
+	var actual int64
+	if current, ok := tree.Get(info); ok {
+		actual = current.Version
+	}
+	version, err := ports.NextVersion("tac_info", info.KeyTac, info.Version, actual)
+	if err != nil {
+		return err
+	}
+
+	info.Version = version
+	tree.ReplaceOrInsert(info)
+	return nil
+}
+
+// prevTac returns the range with the greatest key below key
+func prevTac(tree *btree.BTreeG[*ports.TacInfo], key string) (*ports.TacInfo, bool) {
+	var prev *ports.TacInfo
+	tree.DescendLessOrEqual(&ports.TacInfo{KeyTac: key}, func(info *ports.TacInfo) bool {
+		if info.KeyTac == key {
+			return true
+		}
+		prev = info
+		return false
+	})
+	return prev, prev != nil
+}
+
+// nextTac returns the range with the smallest key above key
+func nextTac(tree *btree.BTreeG[*ports.TacInfo], key string) (*ports.TacInfo, bool) {
+	var next *ports.TacInfo
+	tree.AscendGreaterOrEqual(&ports.TacInfo{KeyTac: key}, func(info *ports.TacInfo) bool {
+		if info.KeyTac == key {
+			return true
+		}
+		next = info
+		return false
+	})
+	return next, next != nil
+}
+
+func listTac(tree *btree.BTreeG[*ports.TacInfo]) []*ports.TacInfo {
+	result := make([]*ports.TacInfo, 0, tree.Len())
+	tree.Ascend(func(info *ports.TacInfo) bool {
+		result = append(result, info)
+		return true
+	})
+	return result
 }
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/hsdfat8/eir/models"
+	"github.com/hsdfat8/eir/pkg/logic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryIMEIRepository_TacNavigation(t *testing.T) {
+	repo := NewInMemoryIMEIRepository()
+	ctx := context.Background()
+
+	for _, key := range []string{"30", "10", "20"} {
+		require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: key}))
+	}
+
+	prev, ok := repo.PrevTacInfo(ctx, "20")
+	require.True(t, ok)
+	assert.Equal(t, "10", prev.KeyTac)
+	next, ok := repo.NextTacInfo(ctx, "20")
+	require.True(t, ok)
+	assert.Equal(t, "30", next.KeyTac)
+
+	// Keys that are not stored navigate to their neighbours
+	prev, ok = repo.PrevTacInfo(ctx, "25")
+	require.True(t, ok)
+	assert.Equal(t, "20", prev.KeyTac)
+	next, ok = repo.NextTacInfo(ctx, "25")
+	require.True(t, ok)
+	assert.Equal(t, "30", next.KeyTac)
+
+	_, ok = repo.PrevTacInfo(ctx, "10")
+	assert.False(t, ok)
+	_, ok = repo.NextTacInfo(ctx, "30")
+	assert.False(t, ok)
+
+	// Saving an existing key replaces it in place
+	require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "20", Color: domainModels.EquipmentStatusBlacklisted}))
+	all := repo.ListAllTacInfo(ctx)
+	require.Len(t, all, 3)
+	assert.Equal(t, []string{"10", "20", "30"}, []string{all[0].KeyTac, all[1].KeyTac, all[2].KeyTac})
+	assert.Equal(t, domainModels.EquipmentStatusBlacklisted, all[1].Color)
+
+	repo.ClearTacInfo(ctx)
+	assert.Empty(t, repo.ListAllTacInfo(ctx))
+}
+
+const benchmarkTacRanges = 100000
+
+// benchmarkTacRangeOrder returns disjoint range indexes in a fixed shuffled
+// order, so inserts land all over the key space
+func benchmarkTacRangeOrder() []int {
+	order := rand.New(rand.NewSource(1)).Perm(benchmarkTacRanges)
+	return order
+}
+
+func BenchmarkInsertTac_100kRanges(b *testing.B) {
+	logger.SetLevel("error")
+	order := benchmarkTacRangeOrder()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		repo := NewInMemoryIMEIRepository()
+		for _, n := range order {
+			result := logic.InsertTac(repo, models.TacInfo{
+				StartRangeTac: fmt.Sprintf("%08d0", n),
+				EndRangeTac:   fmt.Sprintf("%08d9", n),
+				Color:         domainModels.EquipmentStatusBlacklisted,
+			})
+			if result.Status != "ok" {
+				b.Fatalf("insert %d failed: %s", n, result.Error)
+			}
+		}
+	}
+}
+
+func BenchmarkInsertTac_100kNestedRanges(b *testing.B) {
+	logger.SetLevel("error")
+	order := benchmarkTacRangeOrder()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		repo := NewInMemoryIMEIRepository()
+		// Every range is inserted under an enclosing parent, so each insert
+		// walks past its parent as well as its neighbours
+		for p := 0; p < benchmarkTacRanges/1000; p++ {
+			result := logic.InsertTac(repo, models.TacInfo{
+				StartRangeTac: fmt.Sprintf("%05d", p),
+				EndRangeTac:   fmt.Sprintf("%05d", p),
+				Color:         domainModels.EquipmentStatusGreylisted,
+			})
+			if result.Status != "ok" {
+				b.Fatalf("insert parent %d failed: %s", p, result.Error)
+			}
+		}
+		for _, n := range order {
+			result := logic.InsertTac(repo, models.TacInfo{
+				StartRangeTac: fmt.Sprintf("%08d0", n),
+				EndRangeTac:   fmt.Sprintf("%08d9", n),
+				Color:         domainModels.EquipmentStatusBlacklisted,
+			})
+			if result.Status != "ok" {
+				b.Fatalf("insert %d failed: %s", n, result.Error)
+			}
+		}
+	}
+}
+
+func BenchmarkNextTacInfo_100kRanges(b *testing.B) {
+	repo := NewInMemoryIMEIRepository()
+	ctx := context.Background()
+	keys := make([]string, benchmarkTacRanges)
+	for n := range keys {
+		keys[n] = fmt.Sprintf("%08d", n)
+		_ = repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: keys[n]})
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		repo.NextTacInfo(ctx, keys[i%len(keys)])
+		repo.PrevTacInfo(ctx, keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,753 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// FsyncPolicy controls when the write-ahead log is flushed to stable storage
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"   // fsync before every write returns
+	FsyncInterval FsyncPolicy = "interval" // fsync in the background every FsyncInterval
+	FsyncNone     FsyncPolicy = "none"     // leave flushing to the operating system
+)
+
+// PersistenceConfig configures the write-ahead log and snapshots of the persistent memory repository
+type PersistenceConfig struct {
+	Dir              string        // Directory holding the log segments and snapshots
+	Fsync            FsyncPolicy   // When appended records reach stable storage
+	FsyncInterval    time.Duration // Flush period for FsyncInterval
+	SnapshotInterval time.Duration // Periodic compaction; 0 disables it
+	CompactSize      int64         // Compact once the current log segment exceeds this many bytes; 0 disables it
+}
+
+// ErrRepositoryClosed is returned by writes made after Close
+var ErrRepositoryClosed = errors.New("repository closed")
+
+// errCorruptRecord marks a record that is truncated or fails its checksum,
+// which is expected at the tail of the log after a crash
+var errCorruptRecord = errors.New("corrupt record")
+
+// maxRecordSize guards against allocating on a garbage length prefix
+const maxRecordSize = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Record operations. Create and Update both log the full equipment row, so
+// replay never depends on the ID counter having advanced the same way.
+const (
+	opPutEquipment    = "put_equipment"
+	opDeleteEquipment = "delete_equipment"
+	opIncrementCheck  = "increment_check"
+	opCheckCounts     = "check_counts"
+	opPutImeiInfo     = "put_imei_info"
+	opDeleteImeiInfo  = "delete_imei_info"
+	opClearImeiInfo   = "clear_imei_info"
+	opPutTacInfo      = "put_tac_info"
+	opClearTacInfo    = "clear_tac_info"
+	opNextID          = "next_id"
+	opBatch           = "batch" // the writes of one committed transaction, applied together
+)
+
+// walRecord is one logged change. Snapshots are written as the sequence of
+// records that rebuilds the state, so both files share one reader.
+type walRecord struct {
+	Op        string                  `json:"op"`
+	Key       string                  `json:"key,omitempty"`
+	Equipment *models.Equipment       `json:"equipment,omitempty"`
+	ImeiInfo  *ports.ImeiInfo         `json:"imei_info,omitempty"`
+	TacInfo   *ports.TacInfo          `json:"tac_info,omitempty"`
+	NextID    int64                   `json:"next_id,omitempty"`
+	Records   []*walRecord            `json:"records,omitempty"`
+	Counts    []ports.CheckCountDelta `json:"counts,omitempty"`
+}
+
+// PersistentIMEIRepository is an InMemoryIMEIRepository whose changes are
+// appended to a write-ahead log and periodically compacted into a snapshot.
+// Reads are served from memory exactly as before; on open the latest snapshot
+// and the log written after it are replayed.
+type PersistentIMEIRepository struct {
+	*InMemoryIMEIRepository
+
+	cfg PersistenceConfig
+
+	// walMu orders log appends with the in-memory changes they describe
+	walMu   sync.Mutex
+	wal     *os.File
+	walSeq  uint64
+	walSize int64
+	dirty   bool
+
+	compactMu sync.Mutex // one compaction at a time
+	compactCh chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+var _ ports.IMEIRepository = (*PersistentIMEIRepository)(nil)
+
+// OpenPersistentIMEIRepository restores the repository from cfg.Dir, creating
+// the directory if needed, and starts the background fsync and compaction loops
+func OpenPersistentIMEIRepository(cfg PersistenceConfig) (*PersistentIMEIRepository, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("persistence directory is required")
+	}
+	switch cfg.Fsync {
+	case FsyncAlways, FsyncNone:
+	case FsyncInterval, "":
+		cfg.Fsync = FsyncInterval
+		if cfg.FsyncInterval <= 0 {
+			cfg.FsyncInterval = time.Second
+		}
+	default:
+		return nil, fmt.Errorf("unknown fsync policy %q", cfg.Fsync)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create persistence directory: %w", err)
+	}
+
+	r := &PersistentIMEIRepository{
+		InMemoryIMEIRepository: newInMemoryIMEIRepository(),
+		cfg:                    cfg,
+		compactCh:              make(chan struct{}, 1),
+		stop:                   make(chan struct{}),
+	}
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+
+	r.wg.Add(2)
+	go r.syncLoop()
+	go r.compactLoop()
+	return r, nil
+}
+
+// Persistence file names embed a sequence number. snapshot-N holds the state
+// before log segment N, so recovery loads it and replays segments N and later.
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("wal-%016d.log", seq))
+}
+
+func snapshotPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("snapshot-%016d.snap", seq))
+}
+
+// listPersistenceFiles returns the snapshot and segment sequence numbers in dir, ascending
+func listPersistenceFiles(dir string) (snapshots, segments []uint64, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list persistence directory: %w", err)
+	}
+	for _, entry := range entries {
+		var seq uint64
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, ".tmp"):
+			// Left behind by a crash during compaction
+			_ = os.Remove(filepath.Join(dir, name))
+		case strings.HasPrefix(name, "snapshot-"):
+			if _, err := fmt.Sscanf(name, "snapshot-%016d.snap", &seq); err == nil {
+				snapshots = append(snapshots, seq)
+			}
+		case strings.HasPrefix(name, "wal-"):
+			if _, err := fmt.Sscanf(name, "wal-%016d.log", &seq); err == nil {
+				segments = append(segments, seq)
+			}
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i] < snapshots[j] })
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return snapshots, segments, nil
+}
+
+// recover loads the latest snapshot, replays the log written after it and
+// opens a fresh segment for new writes
+func (r *PersistentIMEIRepository) recover() error {
+	snapshots, segments, err := listPersistenceFiles(r.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	var base uint64
+	if len(snapshots) > 0 {
+		base = snapshots[len(snapshots)-1]
+		// Snapshots are renamed into place only once complete, so damage here is not a torn write
+		if _, err := r.replayFile(snapshotPath(r.cfg.Dir, base)); err != nil {
+			return fmt.Errorf("failed to load snapshot %d: %w", base, err)
+		}
+	}
+
+	last := base
+	for i, seq := range segments {
+		if seq < base {
+			continue
+		}
+		path := segmentPath(r.cfg.Dir, seq)
+		valid, err := r.replayFile(path)
+		if errors.Is(err, errCorruptRecord) && i == len(segments)-1 {
+			logger.Log.Warnw("Truncating torn write-ahead log tail", "segment", path, "valid_bytes", valid)
+			if err := os.Truncate(path, valid); err != nil {
+				return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to replay write-ahead log %s: %w", path, err)
+		}
+		last = seq
+	}
+	r.removeBefore(base)
+
+	r.walSeq = last + 1
+	wal, err := openSegment(r.cfg.Dir, r.walSeq)
+	if err != nil {
+		return err
+	}
+	r.wal = wal
+	return nil
+}
+
+// replayFile applies every record in path and returns the length of the valid prefix
+func (r *PersistentIMEIRepository) replayFile(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return readRecords(bufio.NewReader(f), r.InMemoryIMEIRepository.apply)
+}
+
+func openSegment(dir string, seq uint64) (*os.File, error) {
+	f, err := os.OpenFile(segmentPath(dir, seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	if err := syncDir(dir); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// syncDir makes created and renamed files in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open persistence directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync persistence directory: %w", err)
+	}
+	return nil
+}
+
+// encodeRecord frames a record as length (4 bytes) | CRC-32C (4 bytes) | JSON payload
+func encodeRecord(rec *walRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	frame := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.Checksum(payload, crcTable))
+	copy(frame[8:], payload)
+	return frame, nil
+}
+
+// readRecords calls fn for each framed record and returns the number of bytes
+// consumed by complete, valid records
+func readRecords(r io.Reader, fn func(*walRecord) error) (int64, error) {
+	var valid int64
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return valid, nil
+			}
+			return valid, errCorruptRecord
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > maxRecordSize {
+			return valid, errCorruptRecord
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return valid, errCorruptRecord
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+			return valid, errCorruptRecord
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return valid, fmt.Errorf("failed to decode record: %w", err)
+		}
+		if err := fn(&rec); err != nil {
+			return valid, err
+		}
+		valid += int64(len(header)) + int64(size)
+	}
+}
+
+// apply replays one record onto the in-memory state
+func (r *InMemoryIMEIRepository) apply(rec *walRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.applyLocked(rec)
+}
+
+// applyLocked is apply with r.mu held, so a batch becomes visible at once
+func (r *InMemoryIMEIRepository) applyLocked(rec *walRecord) error {
+	switch rec.Op {
+	case opPutEquipment:
+		if rec.Equipment == nil {
+			return fmt.Errorf("%s record without equipment", rec.Op)
+		}
+		r.equipment[rec.Equipment.IMEI] = rec.Equipment
+		if rec.Equipment.ID >= r.nextID {
+			r.nextID = rec.Equipment.ID + 1
+		}
+	case opDeleteEquipment:
+		delete(r.equipment, rec.Key)
+	case opIncrementCheck:
+		if equip, ok := r.equipment[rec.Key]; ok {
+			equip.CheckCount++
+		}
+	case opCheckCounts:
+		for _, delta := range rec.Counts {
+			if equip, ok := r.equipment[delta.IMEI]; ok && !equip.IsDeleted() {
+				// Readers may hold the stored record, so count on a copy
+				copied := *equip
+				copied.AddChecks(delta.Count, delta.LastCheckTime)
+				r.equipment[delta.IMEI] = &copied
+			}
+		}
+	case opPutImeiInfo:
+		if rec.ImeiInfo == nil {
+			return fmt.Errorf("%s record without imei info", rec.Op)
+		}
+		// Logs written before the lists stored status names hold legacy colors
+		rec.ImeiInfo.Color = models.NormalizeStatus(models.ImeiList, rec.ImeiInfo.Color)
+		r.imeiData[rec.ImeiInfo.StartIMEI] = rec.ImeiInfo
+	case opDeleteImeiInfo:
+		delete(r.imeiData, rec.Key)
+	case opClearImeiInfo:
+		r.imeiData = make(map[string]*ports.ImeiInfo)
+	case opPutTacInfo:
+		if rec.TacInfo == nil {
+			return fmt.Errorf("%s record without tac info", rec.Op)
+		}
+		rec.TacInfo.Color = models.NormalizeStatus(models.TacList, rec.TacInfo.Color)
+		r.tacData.ReplaceOrInsert(rec.TacInfo)
+	case opClearTacInfo:
+		r.tacData = newTacTree()
+	case opNextID:
+		r.nextID = rec.NextID
+	case opBatch:
+		for _, nested := range rec.Records {
+			if err := r.applyLocked(nested); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown record operation %q", rec.Op)
+	}
+	return nil
+}
+
+// snapshotRecords copies the state as the records that rebuild it. It holds
+// only the read lock, so concurrent reads carry on while it runs.
+func (r *InMemoryIMEIRepository) snapshotRecords() []*walRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := make([]*walRecord, 0, 1+len(r.equipment)+len(r.imeiData)+r.tacData.Len())
+	records = append(records, &walRecord{Op: opNextID, NextID: r.nextID})
+	for _, equip := range r.equipment {
+		copied := *equip
+		records = append(records, &walRecord{Op: opPutEquipment, Equipment: &copied})
+	}
+	for _, info := range r.imeiData {
+		copied := *info
+		copied.EndIMEI = append(copied.EndIMEI[:0:0], info.EndIMEI...)
+		records = append(records, &walRecord{Op: opPutImeiInfo, ImeiInfo: &copied})
+	}
+	r.tacData.Ascend(func(info *ports.TacInfo) bool {
+		copied := *info
+		records = append(records, &walRecord{Op: opPutTacInfo, TacInfo: &copied})
+		return true
+	})
+	return records
+}
+
+// logged applies a change to memory and appends its record while holding
+// walMu, so the log order matches the order changes became visible
+func (r *PersistentIMEIRepository) logged(change func() (*walRecord, error)) error {
+	r.walMu.Lock()
+	defer r.walMu.Unlock()
+
+	if r.wal == nil {
+		return ErrRepositoryClosed
+	}
+	rec, err := change()
+	if err != nil {
+		return err
+	}
+	return r.append(rec)
+}
+
+// append writes rec to the current segment; walMu must be held
+func (r *PersistentIMEIRepository) append(rec *walRecord) error {
+	frame, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := r.wal.Write(frame); err != nil {
+		// Drop a partial frame so later records are not hidden behind it
+		_ = r.wal.Truncate(r.walSize)
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	r.walSize += int64(len(frame))
+
+	if r.cfg.Fsync == FsyncAlways {
+		if err := r.wal.Sync(); err != nil {
+			return fmt.Errorf("failed to sync write-ahead log: %w", err)
+		}
+	} else {
+		r.dirty = true
+	}
+
+	if r.cfg.CompactSize > 0 && r.walSize >= r.cfg.CompactSize {
+		select {
+		case r.compactCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (r *PersistentIMEIRepository) Create(ctx context.Context, equipment *models.Equipment) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.Create(ctx, equipment); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opPutEquipment, Equipment: equipment}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) Update(ctx context.Context, equipment *models.Equipment) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.Update(ctx, equipment); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opPutEquipment, Equipment: equipment}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) Delete(ctx context.Context, imei string) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.Delete(ctx, imei); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opDeleteEquipment, Key: imei}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged []string
+	err := r.logged(func() (*walRecord, error) {
+		purged = r.InMemoryIMEIRepository.purgeDeleted(before, limit)
+		records := make([]*walRecord, len(purged))
+		for i, imei := range purged {
+			records[i] = &walRecord{Op: opDeleteEquipment, Key: imei}
+		}
+		return &walRecord{Op: opBatch, Records: records}, nil
+	})
+	return int64(len(purged)), err
+}
+
+func (r *PersistentIMEIRepository) IncrementCheckCount(ctx context.Context, imei string) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.IncrementCheckCount(ctx, imei); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opIncrementCheck, Key: imei}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.ApplyCheckCounts(ctx, deltas); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opCheckCounts, Counts: deltas}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.SaveImeiInfo(ctx, info); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opPutImeiInfo, ImeiInfo: info}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.DeleteImeiInfo(ctx, info); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opDeleteImeiInfo, Key: info.StartIMEI}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) ClearImeiInfo(ctx context.Context) {
+	err := r.logged(func() (*walRecord, error) {
+		r.InMemoryIMEIRepository.ClearImeiInfo(ctx)
+		return &walRecord{Op: opClearImeiInfo}, nil
+	})
+	if err != nil {
+		logger.Log.Errorw("Failed to persist IMEI info clear", "error", err)
+	}
+}
+
+func (r *PersistentIMEIRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
+	return r.logged(func() (*walRecord, error) {
+		if err := r.InMemoryIMEIRepository.SaveTacInfo(ctx, info); err != nil {
+			return nil, err
+		}
+		return &walRecord{Op: opPutTacInfo, TacInfo: info}, nil
+	})
+}
+
+func (r *PersistentIMEIRepository) ClearTacInfo(ctx context.Context) {
+	err := r.logged(func() (*walRecord, error) {
+		r.InMemoryIMEIRepository.ClearTacInfo(ctx)
+		return &walRecord{Op: opClearTacInfo}, nil
+	})
+	if err != nil {
+		logger.Log.Errorw("Failed to persist TAC info clear", "error", err)
+	}
+}
+
+// commit logs the writes of a transaction as one record, so replay after a
+// crash applies all of them or none
+func (r *PersistentIMEIRepository) commit(records []*walRecord) error {
+	return r.logged(func() (*walRecord, error) {
+		rec := &walRecord{Op: opBatch, Records: records}
+		if err := r.InMemoryIMEIRepository.apply(rec); err != nil {
+			return nil, err
+		}
+		return rec, nil
+	})
+}
+
+// Compact writes a snapshot of the current state and removes the log
+// segments it covers. Writers wait only while the log rotates and the state
+// is copied; readers are never blocked.
+func (r *PersistentIMEIRepository) Compact(ctx context.Context) error {
+	r.compactMu.Lock()
+	defer r.compactMu.Unlock()
+
+	r.walMu.Lock()
+	if r.wal == nil {
+		r.walMu.Unlock()
+		return ErrRepositoryClosed
+	}
+	seq, err := r.rotate()
+	if err != nil {
+		r.walMu.Unlock()
+		return err
+	}
+	records := r.InMemoryIMEIRepository.snapshotRecords()
+	r.walMu.Unlock()
+
+	if err := writeSnapshot(r.cfg.Dir, seq, records); err != nil {
+		return err
+	}
+	r.removeBefore(seq)
+	return nil
+}
+
+// rotate closes the current segment and starts the next one; walMu must be held
+func (r *PersistentIMEIRepository) rotate() (uint64, error) {
+	next, err := openSegment(r.cfg.Dir, r.walSeq+1)
+	if err != nil {
+		return 0, err
+	}
+	// The old segment stays authoritative until the snapshot lands, so make it durable
+	if err := r.wal.Sync(); err != nil {
+		_ = next.Close()
+		return 0, fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	_ = r.wal.Close()
+
+	r.wal = next
+	r.walSeq++
+	r.walSize = 0
+	r.dirty = false
+	return r.walSeq, nil
+}
+
+// writeSnapshot writes records to snapshot-seq, renaming it into place once it is durable
+func writeSnapshot(dir string, seq uint64, records []*walRecord) error {
+	path := snapshotPath(dir, seq)
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, rec := range records {
+		frame, err := encodeRecord(rec)
+		if err == nil {
+			_, err = w.Write(frame)
+		}
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	if err := w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to install snapshot: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// removeBefore deletes the snapshots and segments superseded by snapshot-seq
+func (r *PersistentIMEIRepository) removeBefore(seq uint64) {
+	snapshots, segments, err := listPersistenceFiles(r.cfg.Dir)
+	if err != nil {
+		logger.Log.Warnw("Failed to clean up persistence files", "error", err)
+		return
+	}
+	for _, s := range snapshots {
+		if s < seq {
+			_ = os.Remove(snapshotPath(r.cfg.Dir, s))
+		}
+	}
+	for _, s := range segments {
+		if s < seq {
+			_ = os.Remove(segmentPath(r.cfg.Dir, s))
+		}
+	}
+}
+
+// Sync flushes the current log segment to stable storage
+func (r *PersistentIMEIRepository) Sync() error {
+	r.walMu.Lock()
+	defer r.walMu.Unlock()
+
+	if r.wal == nil || !r.dirty {
+		return nil
+	}
+	if err := r.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+func (r *PersistentIMEIRepository) syncLoop() {
+	defer r.wg.Done()
+	if r.cfg.Fsync != FsyncInterval {
+		<-r.stop
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.FsyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Sync(); err != nil {
+				logger.Log.Errorw("Write-ahead log sync failed", "error", err)
+			}
+		}
+	}
+}
+
+func (r *PersistentIMEIRepository) compactLoop() {
+	defer r.wg.Done()
+
+	var tick <-chan time.Time
+	if r.cfg.SnapshotInterval > 0 {
+		ticker := time.NewTicker(r.cfg.SnapshotInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-tick:
+		case <-r.compactCh:
+		}
+		if err := r.Compact(context.Background()); err != nil {
+			logger.Log.Errorw("Memory repository compaction failed", "error", err)
+		}
+	}
+}
+
+// Close stops the background loops and flushes and closes the log. Later
+// writes fail with ErrRepositoryClosed; reads keep working from memory.
+func (r *PersistentIMEIRepository) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	r.walMu.Lock()
+	defer r.walMu.Unlock()
+
+	if r.wal == nil {
+		return nil
+	}
+	err := r.wal.Sync()
+	if closeErr := r.wal.Close(); err == nil {
+		err = closeErr
+	}
+	r.wal = nil
+	if err != nil {
+		return fmt.Errorf("failed to close write-ahead log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestRepository(t *testing.T, dir string) *PersistentIMEIRepository {
+	t.Helper()
+	repo, err := OpenPersistentIMEIRepository(PersistenceConfig{Dir: dir, Fsync: FsyncAlways})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func populate(t *testing.T, repo ports.IMEIRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &models.Equipment{IMEI: "111111111111111", Status: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, repo.Create(ctx, &models.Equipment{IMEI: "222222222222222", Status: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, repo.Update(ctx, &models.Equipment{ID: 2, IMEI: "222222222222222", Status: models.EquipmentStatusBlacklisted}))
+	require.NoError(t, repo.IncrementCheckCount(ctx, "222222222222222"))
+	require.NoError(t, repo.Delete(ctx, "111111111111111"))
+
+	require.NoError(t, repo.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "old", Color: models.EquipmentStatusWhitelisted}))
+	repo.ClearImeiInfo(ctx)
+	require.NoError(t, repo.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "35000000", EndIMEI: []string{"35000099"}, Color: models.EquipmentStatusBlacklisted}))
+	require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "k1", StartRangeTac: "35", EndRangeTac: "36", Color: models.EquipmentStatusGreylisted}))
+}
+
+func assertPopulated(t *testing.T, repo ports.IMEIRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := repo.GetByIMEI(ctx, "111111111111111")
+	assert.Error(t, err)
+
+	equip, err := repo.GetByIMEI(ctx, "222222222222222")
+	require.NoError(t, err)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, equip.Status)
+	assert.Equal(t, int64(1), equip.CheckCount)
+
+	_, ok := repo.LookupImeiInfo(ctx, "old")
+	assert.False(t, ok)
+	info, ok := repo.LookupImeiInfo(ctx, "35000000")
+	require.True(t, ok)
+	assert.Equal(t, []string{"35000099"}, []string(info.EndIMEI))
+
+	tac, ok := repo.LookupTacInfo(ctx, "k1")
+	require.True(t, ok)
+	assert.Equal(t, models.EquipmentStatusGreylisted, tac.Color)
+}
+
+// assertNextID checks the ID counter survived, so new rows do not reuse IDs
+func assertNextID(t *testing.T, repo ports.IMEIRepository) {
+	t.Helper()
+	next := &models.Equipment{IMEI: "333333333333333"}
+	require.NoError(t, repo.Create(context.Background(), next))
+	assert.Equal(t, int64(3), next.ID)
+}
+
+func TestPersistentIMEIRepository_ReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openTestRepository(t, dir)
+	populate(t, repo)
+	require.NoError(t, repo.Close())
+
+	reopened := openTestRepository(t, dir)
+	assertPopulated(t, reopened)
+	assertNextID(t, reopened)
+}
+
+func TestPersistentIMEIRepository_RecoversWithoutClose(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulates a crash: the first instance is never closed
+	populate(t, openTestRepository(t, dir))
+
+	assertPopulated(t, openTestRepository(t, dir))
+}
+
+func TestPersistentIMEIRepository_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	populate(t, repo)
+	require.NoError(t, repo.Compact(ctx))
+	require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "k2", Color: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, repo.Close())
+
+	// Only the new snapshot and the segment written after it remain
+	snapshots, segments, err := listPersistenceFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, snapshots)
+	assert.Equal(t, []uint64{2}, segments)
+
+	reopened := openTestRepository(t, dir)
+	assertPopulated(t, reopened)
+	assertNextID(t, reopened)
+	_, ok := reopened.LookupTacInfo(ctx, "k2")
+	assert.True(t, ok)
+}
+
+func TestPersistentIMEIRepository_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openTestRepository(t, dir)
+	populate(t, repo)
+	require.NoError(t, repo.Close())
+
+	// A crash in the middle of an append leaves a partial frame behind
+	f, err := os.OpenFile(segmentPath(dir, 1), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 42, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reopened := openTestRepository(t, dir)
+	assertPopulated(t, reopened)
+	require.NoError(t, reopened.Close())
+
+	// The torn tail is gone, so the segment replays cleanly next time too
+	assertPopulated(t, openTestRepository(t, dir))
+}
+
+func TestPersistentIMEIRepository_CompactsBySize(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo, err := OpenPersistentIMEIRepository(PersistenceConfig{Dir: dir, Fsync: FsyncNone, CompactSize: 1024})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: fmt.Sprintf("k%03d", i), Color: models.EquipmentStatusBlacklisted}))
+	}
+	// Compaction runs in the background; an explicit one makes the outcome deterministic
+	require.NoError(t, repo.Compact(ctx))
+	require.NoError(t, repo.Close())
+
+	snapshots, _, err := listPersistenceFiles(dir)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+
+	reopened := openTestRepository(t, dir)
+	assert.Len(t, reopened.ListAllTacInfo(ctx), 100)
+}
+
+func TestPersistentIMEIRepository_ConcurrentCompaction(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo, err := OpenPersistentIMEIRepository(PersistenceConfig{Dir: dir, Fsync: FsyncInterval})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("w%d-%03d", w, i)
+				assert.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: key}))
+				_, ok := repo.LookupTacInfo(ctx, key)
+				assert.True(t, ok)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, repo.Compact(ctx))
+		}
+	}()
+	wg.Wait()
+	require.NoError(t, repo.Close())
+
+	reopened := openTestRepository(t, dir)
+	assert.Len(t, reopened.ListAllTacInfo(ctx), 800)
+}
+
+func TestOpenPersistentIMEIRepository_InvalidConfig(t *testing.T) {
+	_, err := OpenPersistentIMEIRepository(PersistenceConfig{})
+	assert.Error(t, err)
+
+	_, err = OpenPersistentIMEIRepository(PersistenceConfig{Dir: filepath.Join(t.TempDir(), "data"), Fsync: "sometimes"})
+	assert.Error(t, err)
+}
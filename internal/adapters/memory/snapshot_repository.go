@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// InMemorySnapshotRepository is an in-memory implementation for testing
+type InMemorySnapshotRepository struct {
+	mu        sync.RWMutex
+	snapshots map[int64]*models.EquipmentSnapshot
+	nextID    int64
+}
+
+// NewInMemorySnapshotRepository creates a new in-memory snapshot repository
+func NewInMemorySnapshotRepository() ports.SnapshotRepository {
+	return &InMemorySnapshotRepository{
+		snapshots: make(map[int64]*models.EquipmentSnapshot),
+		nextID:    1,
+	}
+}
+
+func (r *InMemorySnapshotRepository) CreateSnapshot(ctx context.Context, snapshot *models.EquipmentSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if snapshot.SnapshotTime.IsZero() {
+		snapshot.SnapshotTime = time.Now()
+	}
+	snapshot.ID = r.nextID
+	r.nextID++
+	r.snapshots[snapshot.ID] = snapshot
+	return nil
+}
+
+// GetSnapshotsByIMEI returns the snapshots of imei, newest first
+func (r *InMemorySnapshotRepository) GetSnapshotsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error) {
+	r.mu.RLock()
+	matched := make([]*models.EquipmentSnapshot, 0)
+	for _, s := range r.snapshots {
+		if s.IMEI == imei {
+			matched = append(matched, s)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].SnapshotTime.Equal(matched[j].SnapshotTime) {
+			return matched[i].SnapshotTime.After(matched[j].SnapshotTime)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	if offset >= len(matched) {
+		return []*models.EquipmentSnapshot{}, nil
+	}
+	matched = matched[offset:]
+	if limit >= 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+func (r *InMemorySnapshotRepository) GetSnapshotByID(ctx context.Context, id int64) (*models.EquipmentSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot, exists := r.snapshots[id]
+	if !exists {
+		return nil, fmt.Errorf("snapshot not found")
+	}
+	return snapshot, nil
+}
+
+// GetLatestSnapshot returns the latest snapshot of imei taken at or before at
+func (r *InMemorySnapshotRepository) GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.EquipmentSnapshot
+	for _, s := range r.snapshots {
+		if s.IMEI != imei || s.SnapshotTime.After(at) {
+			continue
+		}
+		if latest == nil || s.SnapshotTime.After(latest.SnapshotTime) ||
+			(s.SnapshotTime.Equal(latest.SnapshotTime) && s.ID > latest.ID) {
+			latest = s
+		}
+	}
+	return latest, nil
+}
+
+func (r *InMemorySnapshotRepository) DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, s := range r.snapshots {
+		if s.SnapshotTime.Before(before) {
+			delete(r.snapshots, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
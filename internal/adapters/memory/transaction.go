@@ -0,0 +1,529 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// ErrTransactionDone is returned when a transaction is used after Commit or Rollback
+var ErrTransactionDone = errors.New("transaction already committed or rolled back")
+
+// transactionalRepository is implemented by the memory IMEI repositories,
+// which apply the buffered writes of a committed transaction in one step
+type transactionalRepository interface {
+	ports.IMEIRepository
+	reserveID() int64
+	cloneTacData() *btree.BTreeG[*ports.TacInfo]
+	commit(records []*walRecord) error
+}
+
+// TransactionManager provides ports.Transaction for the memory backend.
+// Writes are buffered and applied to the repositories on Commit, so readers
+// never see a half-applied change. Transactions run one at a time, which keeps
+// provisioning serializable; writes made outside a transaction are
+// last-writer-wins against it.
+type TransactionManager struct {
+	repo    transactionalRepository
+	audit   ports.AuditRepository
+	history ports.HistoryRepository
+	slot    chan struct{} // held by the running transaction
+}
+
+// NewTransactionManager creates a transaction manager over the memory
+// repositories; repo must come from this package
+func NewTransactionManager(repo ports.IMEIRepository, audit ports.AuditRepository, history ports.HistoryRepository) (*TransactionManager, error) {
+	base, ok := repo.(transactionalRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository %T does not support transactions", repo)
+	}
+	return &TransactionManager{repo: base, audit: audit, history: history, slot: make(chan struct{}, 1)}, nil
+}
+
+// BeginTransaction starts a transaction, waiting for the running one to finish
+func (m *TransactionManager) BeginTransaction(ctx context.Context) (ports.Transaction, error) {
+	select {
+	case m.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to begin transaction: %w", ctx.Err())
+	}
+	return &memoryTransaction{
+		manager: m,
+		imeiRepo: &txIMEIRepository{
+			base:      m.repo,
+			equipment: make(map[string]*models.Equipment),
+			imeiData:  make(map[string]*ports.ImeiInfo),
+			tacData:   m.repo.cloneTacData(),
+		},
+		auditRepo:   &txAuditRepository{base: m.audit},
+		historyRepo: &txHistoryRepository{base: m.history},
+	}, nil
+}
+
+// memoryTransaction implements the Transaction interface
+type memoryTransaction struct {
+	manager     *TransactionManager
+	imeiRepo    *txIMEIRepository
+	auditRepo   *txAuditRepository
+	historyRepo *txHistoryRepository
+	done        bool
+}
+
+// Commit applies the buffered writes
+func (t *memoryTransaction) Commit(ctx context.Context) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	defer t.finish()
+
+	if len(t.imeiRepo.records) > 0 {
+		if err := t.manager.repo.commit(t.imeiRepo.records); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+	for _, audit := range t.auditRepo.pending {
+		if err := t.manager.audit.LogCheck(ctx, audit); err != nil {
+			return fmt.Errorf("failed to commit audit log: %w", err)
+		}
+	}
+	for _, history := range t.historyRepo.pending {
+		if err := t.manager.history.RecordChange(ctx, history); err != nil {
+			return fmt.Errorf("failed to commit history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollback discards the buffered writes
+func (t *memoryTransaction) Rollback(ctx context.Context) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.finish()
+	return nil
+}
+
+func (t *memoryTransaction) finish() {
+	t.done = true
+	<-t.manager.slot
+}
+
+// GetIMEIRepository returns a transactional IMEI repository
+func (t *memoryTransaction) GetIMEIRepository() ports.IMEIRepository {
+	return t.imeiRepo
+}
+
+// GetAuditRepository returns a transactional audit repository
+func (t *memoryTransaction) GetAuditRepository() ports.AuditRepository {
+	return t.auditRepo
+}
+
+// GetHistoryRepository returns a transactional history repository
+func (t *memoryTransaction) GetHistoryRepository() ports.HistoryRepository {
+	return t.historyRepo
+}
+
+// reserveID hands out an equipment ID ahead of commit; like a database
+// sequence, IDs reserved by a rolled back transaction are not reused
+func (r *InMemoryIMEIRepository) reserveID() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.nextID
+	r.nextID++
+	return id
+}
+
+// cloneTacData returns a copy-on-write copy of the TAC ranges. Cloning
+// mutates the tree's bookkeeping, so it takes the write lock.
+func (r *InMemoryIMEIRepository) cloneTacData() *btree.BTreeG[*ports.TacInfo] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tacData.Clone()
+}
+
+func (r *InMemoryIMEIRepository) commit(records []*walRecord) error {
+	return r.apply(&walRecord{Op: opBatch, Records: records})
+}
+
+// txIMEIRepository layers a transaction's writes over the base repository.
+// Equipment and IMEI info keep only the changed keys; the TAC ranges are a
+// copy-on-write clone so Prev/Next navigation sees the pending ranges.
+type txIMEIRepository struct {
+	base transactionalRepository
+
+	mu          sync.RWMutex
+	equipment   map[string]*models.Equipment // nil marks a deleted IMEI
+	imeiData    map[string]*ports.ImeiInfo   // nil marks a deleted entry
+	imeiCleared bool
+	tacData     *btree.BTreeG[*ports.TacInfo]
+	records     []*walRecord
+}
+
+// lookup returns the equipment as the transaction sees it; mu must be held
+func (r *txIMEIRepository) lookup(ctx context.Context, imei string) (*models.Equipment, bool) {
+	if equip, ok := r.equipment[imei]; ok {
+		return equip, equip != nil
+	}
+	equip, err := r.base.GetByIMEI(ctx, imei)
+	return equip, err == nil
+}
+
+func (r *txIMEIRepository) GetByIMEI(ctx context.Context, imei string) (*models.Equipment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if equip, ok := r.lookup(ctx, imei); ok {
+		return equip, nil
+	}
+	return nil, fmt.Errorf("equipment not found")
+}
+
+func (r *txIMEIRepository) GetByIMEISV(ctx context.Context, imeisv string) (*models.Equipment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, equip := range r.equipment {
+		if equip != nil && equip.IMEISV != nil && *equip.IMEISV == imeisv {
+			return equip, nil
+		}
+	}
+	equip, err := r.base.GetByIMEISV(ctx, imeisv)
+	if err != nil {
+		return nil, err
+	}
+	if _, changed := r.equipment[equip.IMEI]; changed {
+		return nil, fmt.Errorf("equipment not found")
+	}
+	return equip, nil
+}
+
+func (r *txIMEIRepository) Create(ctx context.Context, equipment *models.Equipment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.lookup(ctx, equipment.IMEI); exists {
+		return fmt.Errorf("equipment already exists")
+	}
+
+	equipment.ID = r.base.reserveID()
+	equipment.Version = 1
+	r.equipment[equipment.IMEI] = equipment
+	r.records = append(r.records, &walRecord{Op: opPutEquipment, Equipment: equipment})
+	return nil
+}
+
+func (r *txIMEIRepository) Update(ctx context.Context, equipment *models.Equipment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.lookup(ctx, equipment.IMEI)
+	if !exists {
+		return fmt.Errorf("equipment not found")
+	}
+	version, err := ports.NextVersion("equipment", equipment.IMEI, equipment.Version, current.Version)
+	if err != nil {
+		return err
+	}
+
+	equipment.Version = version
+	r.equipment[equipment.IMEI] = equipment
+	r.records = append(r.records, &walRecord{Op: opPutEquipment, Equipment: equipment})
+	return nil
+}
+
+func (r *txIMEIRepository) Delete(ctx context.Context, imei string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.lookup(ctx, imei); !exists {
+		return fmt.Errorf("equipment not found")
+	}
+
+	r.equipment[imei] = nil
+	r.records = append(r.records, &walRecord{Op: opDeleteEquipment, Key: imei})
+	return nil
+}
+
+func (r *txIMEIRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(ctx, offset, limit, func(equip *models.Equipment) bool { return !equip.IsDeleted() })
+}
+
+func (r *txIMEIRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(ctx, offset, limit, func(*models.Equipment) bool { return true })
+}
+
+func (r *txIMEIRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(ctx, offset, limit, func(equip *models.Equipment) bool { return equip.Status == status && !equip.IsDeleted() })
+}
+
+func (r *txIMEIRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	expired, err := r.list(ctx, 0, math.MaxInt, func(equip *models.Equipment) bool {
+		return equip.IsDeleted() && equip.DeletedAt.Before(before)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, equip := range expired {
+		r.equipment[equip.IMEI] = nil
+		r.records = append(r.records, &walRecord{Op: opDeleteEquipment, Key: equip.IMEI})
+	}
+	return int64(len(expired)), nil
+}
+
+func (r *txIMEIRepository) list(ctx context.Context, offset, limit int, match func(*models.Equipment) bool) ([]*models.Equipment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all, err := r.base.ListWithDeleted(ctx, 0, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]*models.Equipment, 0, len(all)+len(r.equipment))
+	for _, equip := range all {
+		if _, changed := r.equipment[equip.IMEI]; !changed && match(equip) {
+			merged = append(merged, equip)
+		}
+	}
+	for _, equip := range r.equipment {
+		if equip != nil && match(equip) {
+			merged = append(merged, equip)
+		}
+	}
+	return pageEquipment(merged, offset, limit), nil
+}
+
+func (r *txIMEIRepository) IncrementCheckCount(ctx context.Context, imei string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	equip, exists := r.lookup(ctx, imei)
+	if !exists {
+		return fmt.Errorf("equipment not found")
+	}
+
+	// The base row is shared with readers, so count on a private copy
+	copied := *equip
+	copied.CheckCount++
+	r.equipment[imei] = &copied
+	r.records = append(r.records, &walRecord{Op: opIncrementCheck, Key: imei})
+	return nil
+}
+
+func (r *txIMEIRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, delta := range deltas {
+		if equip, exists := r.lookup(ctx, delta.IMEI); exists && !equip.IsDeleted() {
+			copied := *equip
+			copied.AddChecks(delta.Count, delta.LastCheckTime)
+			r.equipment[delta.IMEI] = &copied
+		}
+	}
+	r.records = append(r.records, &walRecord{Op: opCheckCounts, Counts: deltas})
+	return nil
+}
+
+// IMEI logic operations
+func (r *txIMEIRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if info, ok := r.imeiData[startRange]; ok {
+		return info, info != nil
+	}
+	if r.imeiCleared {
+		return nil, false
+	}
+	return r.base.LookupImeiInfo(ctx, startRange)
+}
+
+func (r *txIMEIRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, r.imeiVersion(ctx, info.StartIMEI))
+	if err != nil {
+		return err
+	}
+
+	info.Version = version
+	r.imeiData[info.StartIMEI] = info
+	r.records = append(r.records, &walRecord{Op: opPutImeiInfo, ImeiInfo: info})
+	return nil
+}
+
+func (r *txIMEIRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := ports.NextVersion("imei_info", info.StartIMEI, info.Version, r.imeiVersion(ctx, info.StartIMEI)); err != nil {
+		return err
+	}
+
+	r.imeiData[info.StartIMEI] = nil
+	r.records = append(r.records, &walRecord{Op: opDeleteImeiInfo, Key: info.StartIMEI})
+	return nil
+}
+
+// imeiVersion is the version of the IMEI_INFO entry of start as the
+// transaction sees it, 0 when there is none; r.mu must be held
+func (r *txIMEIRepository) imeiVersion(ctx context.Context, start string) int64 {
+	if current, ok := r.imeiData[start]; ok {
+		if current == nil {
+			return 0
+		}
+		return current.Version
+	}
+	if r.imeiCleared {
+		return 0
+	}
+	if current, ok := r.base.LookupImeiInfo(ctx, start); ok {
+		return current.Version
+	}
+	return 0
+}
+
+func (r *txIMEIRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*ports.ImeiInfo, 0, len(r.imeiData))
+	if !r.imeiCleared {
+		for _, info := range r.base.ListAllImeiInfo(ctx) {
+			if _, changed := r.imeiData[info.StartIMEI]; !changed {
+				result = append(result, info)
+			}
+		}
+	}
+	for _, info := range r.imeiData {
+		if info != nil {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+func (r *txIMEIRepository) ClearImeiInfo(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.imeiData = make(map[string]*ports.ImeiInfo)
+	r.imeiCleared = true
+	r.records = append(r.records, &walRecord{Op: opClearImeiInfo})
+}
+
+// TAC logic operations
+func (r *txIMEIRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := saveTac(r.tacData, info); err != nil {
+		return err
+	}
+	r.records = append(r.records, &walRecord{Op: opPutTacInfo, TacInfo: info})
+	return nil
+}
+
+func (r *txIMEIRepository) LookupTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.tacData.Get(&ports.TacInfo{KeyTac: key})
+}
+
+func (r *txIMEIRepository) PrevTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return prevTac(r.tacData, key)
+}
+
+func (r *txIMEIRepository) NextTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return nextTac(r.tacData, key)
+}
+
+func (r *txIMEIRepository) ListAllTacInfo(ctx context.Context) []*ports.TacInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return listTac(r.tacData)
+}
+
+func (r *txIMEIRepository) ClearTacInfo(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tacData = newTacTree()
+	r.records = append(r.records, &walRecord{Op: opClearTacInfo})
+}
+
+// txAuditRepository buffers audit entries until commit. Reads go to the base
+// repository and do not include the buffered entries.
+type txAuditRepository struct {
+	base    ports.AuditRepository
+	pending []*models.AuditLog
+}
+
+func (r *txAuditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	r.pending = append(r.pending, audit)
+	return nil
+}
+
+func (r *txAuditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.base.GetAuditsByIMEI(ctx, imei, offset, limit)
+}
+
+func (r *txAuditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.base.GetAuditsByTimeRange(ctx, startTime, endTime, offset, limit)
+}
+
+func (r *txAuditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.base.GetAuditsBySUPI(ctx, supis, offset, limit)
+}
+
+// txHistoryRepository buffers history entries until commit. Reads go to the
+// base repository and do not include the buffered entries.
+type txHistoryRepository struct {
+	base    ports.HistoryRepository
+	pending []*models.EquipmentHistory
+}
+
+func (r *txHistoryRepository) RecordChange(ctx context.Context, history *models.EquipmentHistory) error {
+	r.pending = append(r.pending, history)
+	return nil
+}
+
+func (r *txHistoryRepository) GetHistoryByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.base.GetHistoryByIMEI(ctx, imei, offset, limit)
+}
+
+func (r *txHistoryRepository) GetHistoryByTimeRange(ctx context.Context, startTime, endTime time.Time, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.base.GetHistoryByTimeRange(ctx, startTime, endTime, offset, limit)
+}
+
+func (r *txHistoryRepository) GetHistoryByChangeType(ctx context.Context, changeType models.ChangeType, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.base.GetHistoryByChangeType(ctx, changeType, offset, limit)
+}
+
+func (r *txHistoryRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	return r.base.GetListHistory(ctx, startImei, tac, until)
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionManager_CommitAndRollback(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryIMEIRepository()
+	history := NewInMemoryHistoryRepository()
+	txs, err := NewTransactionManager(repo, NewInMemoryAuditRepository(), history)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Create(ctx, &models.Equipment{IMEI: "111111111111111", Status: models.EquipmentStatusWhitelisted}))
+	require.NoError(t, repo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "20"}))
+
+	tx, err := txs.BeginTransaction(ctx)
+	require.NoError(t, err)
+	txRepo := tx.GetIMEIRepository()
+	require.NoError(t, txRepo.Create(ctx, &models.Equipment{IMEI: "222222222222222"}))
+	require.NoError(t, txRepo.Delete(ctx, "111111111111111"))
+	require.NoError(t, txRepo.SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "10"}))
+	require.NoError(t, tx.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{IMEI: "222222222222222"}))
+
+	// The transaction sees its own writes; everyone else sees none of them
+	_, err = txRepo.GetByIMEI(ctx, "111111111111111")
+	assert.Error(t, err)
+	listed, err := txRepo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "222222222222222", listed[0].IMEI)
+	prev, ok := txRepo.PrevTacInfo(ctx, "20")
+	require.True(t, ok)
+	assert.Equal(t, "10", prev.KeyTac)
+
+	_, err = repo.GetByIMEI(ctx, "222222222222222")
+	assert.Error(t, err)
+	_, ok = repo.PrevTacInfo(ctx, "20")
+	assert.False(t, ok)
+
+	require.NoError(t, tx.Rollback(ctx))
+	assert.ErrorIs(t, tx.Commit(ctx), ErrTransactionDone)
+	assert.Len(t, repo.ListAllTacInfo(ctx), 1)
+	_, err = repo.GetByIMEI(ctx, "111111111111111")
+	assert.NoError(t, err)
+
+	tx, err = txs.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "10"}))
+	require.NoError(t, tx.GetIMEIRepository().IncrementCheckCount(ctx, "111111111111111"))
+	require.NoError(t, tx.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{IMEI: "111111111111111"}))
+	require.NoError(t, tx.Commit(ctx))
+
+	assert.Len(t, repo.ListAllTacInfo(ctx), 2)
+	equip, err := repo.GetByIMEI(ctx, "111111111111111")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), equip.CheckCount)
+	recorded, err := history.GetHistoryByIMEI(ctx, "111111111111111", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, recorded, 1)
+}
+
+func TestTransactionManager_PersistsCommitAsOneRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	repo := openTestRepository(t, dir)
+	txs, err := NewTransactionManager(repo, NewInMemoryAuditRepository(), NewInMemoryHistoryRepository())
+	require.NoError(t, err)
+
+	tx, err := txs.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "k1"}))
+	require.NoError(t, tx.GetIMEIRepository().SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "k2"}))
+	require.NoError(t, tx.Commit(ctx))
+
+	tx, err = txs.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.GetIMEIRepository().SaveTacInfo(ctx, &ports.TacInfo{KeyTac: "k3"}))
+	require.NoError(t, tx.Rollback(ctx))
+	require.NoError(t, repo.Close())
+
+	reopened := openTestRepository(t, dir)
+	assert.Len(t, reopened.ListAllTacInfo(ctx), 2)
+}
+
+func TestTransactionManager_BeginHonoursContext(t *testing.T) {
+	txs, err := NewTransactionManager(NewInMemoryIMEIRepository(), NewInMemoryAuditRepository(), NewInMemoryHistoryRepository())
+	require.NoError(t, err)
+
+	tx, err := txs.BeginTransaction(context.Background())
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	// Transactions run one at a time, so a second Begin waits for the first
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = txs.BeginTransaction(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -18,6 +18,7 @@ type MockAuditRepository struct {
 	LogCheckFunc            func(ctx context.Context, audit *models.AuditLog) error
 	GetAuditsByIMEIFunc     func(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error)
 	GetAuditsByTimeRangeFunc func(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error)
+	GetAuditsBySUPIFunc     func(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error)
 }
 
 // NewMockAuditRepository creates a new mock audit repository
@@ -121,6 +122,47 @@ func (m *MockAuditRepository) GetAuditsByTimeRange(ctx context.Context, startTim
 	return result[offset:endIndex], nil
 }
 
+// GetAuditsBySUPI retrieves audit logs whose SUPI or SUPI index is one of supis
+func (m *MockAuditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	if m.GetAuditsBySUPIFunc != nil {
+		return m.GetAuditsBySUPIFunc(ctx, supis, offset, limit)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := func(value *string) bool {
+		if value == nil {
+			return false
+		}
+		for _, supi := range supis {
+			if *value == supi {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []*models.AuditLog
+	for _, log := range m.auditLogs {
+		if matches(log.SUPI) || matches(log.SUPIIndex) {
+			result = append(result, m.copyAuditLog(log))
+		}
+	}
+
+	// Apply pagination
+	if offset >= len(result) {
+		return []*models.AuditLog{}, nil
+	}
+
+	end := offset + limit
+	if end > len(result) {
+		end = len(result)
+	}
+
+	return result[offset:end], nil
+}
+
 // Helper methods
 
 // GetAllLogs returns all audit logs (for testing)
@@ -197,6 +239,11 @@ func (m *MockAuditRepository) copyAuditLog(a *models.AuditLog) *models.AuditLog
 		copy.GPSI = &val
 	}
 
+	if a.SUPIIndex != nil {
+		val := *a.SUPIIndex
+		copy.SUPIIndex = &val
+	}
+
 	if a.SessionID != nil {
 		val := *a.SessionID
 		copy.SessionID = &val
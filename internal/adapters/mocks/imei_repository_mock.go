@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
 )
 
 // MockIMEIRepository is a mock implementation of IMEIRepository for testing
@@ -22,8 +23,11 @@ type MockIMEIRepository struct {
 	UpdateFunc             func(ctx context.Context, equipment *models.Equipment) error
 	DeleteFunc             func(ctx context.Context, imei string) error
 	ListFunc               func(ctx context.Context, offset, limit int) ([]*models.Equipment, error)
+	ListWithDeletedFunc    func(ctx context.Context, offset, limit int) ([]*models.Equipment, error)
+	PurgeDeletedFunc       func(ctx context.Context, before time.Time, limit int) (int64, error)
 	ListByStatusFunc       func(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error)
 	IncrementCheckCountFunc func(ctx context.Context, imei string) error
+	ApplyCheckCountsFunc    func(ctx context.Context, deltas []ports.CheckCountDelta) error
 }
 
 // NewMockIMEIRepository creates a new mock IMEI repository
@@ -87,6 +91,7 @@ func (m *MockIMEIRepository) Create(ctx context.Context, equipment *models.Equip
 	// Assign ID
 	equipment.ID = m.nextID
 	m.nextID++
+	equipment.Version = 1
 
 	// Set timestamps
 	equipment.LastUpdated = time.Now()
@@ -110,9 +115,14 @@ func (m *MockIMEIRepository) Update(ctx context.Context, equipment *models.Equip
 	if !exists {
 		return errors.New("equipment not found")
 	}
+	version, err := ports.NextVersion("equipment", equipment.IMEI, equipment.Version, existing.Version)
+	if err != nil {
+		return err
+	}
 
 	// Preserve ID
 	equipment.ID = existing.ID
+	equipment.Version = version
 
 	// Update timestamp
 	equipment.LastUpdated = time.Now()
@@ -140,52 +150,44 @@ func (m *MockIMEIRepository) Delete(ctx context.Context, imei string) error {
 	return nil
 }
 
-// List retrieves equipment with pagination
+// List retrieves equipment with pagination, skipping tombstones
 func (m *MockIMEIRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
 	if m.ListFunc != nil {
 		return m.ListFunc(ctx, offset, limit)
 	}
+	return m.list(offset, limit, func(e *models.Equipment) bool { return !e.IsDeleted() }), nil
+}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var result []*models.Equipment
-	for _, equipment := range m.equipment {
-		result = append(result, m.copyEquipment(equipment))
-	}
-
-	// Apply pagination
-	if offset >= len(result) {
-		return []*models.Equipment{}, nil
-	}
-
-	end := offset + limit
-	if end > len(result) {
-		end = len(result)
+// ListWithDeleted retrieves equipment with pagination, tombstones included
+func (m *MockIMEIRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	if m.ListWithDeletedFunc != nil {
+		return m.ListWithDeletedFunc(ctx, offset, limit)
 	}
-
-	return result[offset:end], nil
+	return m.list(offset, limit, func(*models.Equipment) bool { return true }), nil
 }
 
-// ListByStatus retrieves equipment by status
+// ListByStatus retrieves equipment by status, skipping tombstones
 func (m *MockIMEIRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
 	if m.ListByStatusFunc != nil {
 		return m.ListByStatusFunc(ctx, status, offset, limit)
 	}
+	return m.list(offset, limit, func(e *models.Equipment) bool { return e.Status == status && !e.IsDeleted() }), nil
+}
 
+func (m *MockIMEIRepository) list(offset, limit int, match func(*models.Equipment) bool) []*models.Equipment {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	var result []*models.Equipment
 	for _, equipment := range m.equipment {
-		if equipment.Status == status {
+		if match(equipment) {
 			result = append(result, m.copyEquipment(equipment))
 		}
 	}
 
 	// Apply pagination
 	if offset >= len(result) {
-		return []*models.Equipment{}, nil
+		return []*models.Equipment{}
 	}
 
 	end := offset + limit
@@ -193,7 +195,26 @@ func (m *MockIMEIRepository) ListByStatus(ctx context.Context, status models.Equ
 		end = len(result)
 	}
 
-	return result[offset:end], nil
+	return result[offset:end]
+}
+
+// PurgeDeleted removes tombstones deleted before the given time
+func (m *MockIMEIRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if m.PurgeDeletedFunc != nil {
+		return m.PurgeDeletedFunc(ctx, before, limit)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for imei, equipment := range m.equipment {
+		if purged < int64(limit) && equipment.IsDeleted() && equipment.DeletedAt.Before(before) {
+			delete(m.equipment, imei)
+			purged++
+		}
+	}
+	return purged, nil
 }
 
 // IncrementCheckCount increments the check counter
@@ -217,6 +238,23 @@ func (m *MockIMEIRepository) IncrementCheckCount(ctx context.Context, imei strin
 	return nil
 }
 
+// ApplyCheckCounts adds flushed check counts
+func (m *MockIMEIRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if m.ApplyCheckCountsFunc != nil {
+		return m.ApplyCheckCountsFunc(ctx, deltas)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, delta := range deltas {
+		if equipment, exists := m.equipment[delta.IMEI]; exists && !equipment.IsDeleted() {
+			equipment.AddChecks(delta.Count, delta.LastCheckTime)
+		}
+	}
+	return nil
+}
+
 // Helper methods
 
 // AddEquipment adds equipment directly (for test setup)
@@ -262,6 +300,7 @@ func (m *MockIMEIRepository) copyEquipment(e *models.Equipment) *models.Equipmen
 		LastUpdated:      e.LastUpdated,
 		CheckCount:       e.CheckCount,
 		AddedBy:          e.AddedBy,
+		Version:          e.Version,
 	}
 
 	if e.IMEISV != nil {
@@ -289,6 +328,16 @@ func (m *MockIMEIRepository) copyEquipment(e *models.Equipment) *models.Equipmen
 		copy.ManufacturerTAC = &val
 	}
 
+	if e.DeletedAt != nil {
+		val := *e.DeletedAt
+		copy.DeletedAt = &val
+	}
+
+	if e.DeletedBy != nil {
+		val := *e.DeletedBy
+		copy.DeletedBy = &val
+	}
+
 	if e.ManufacturerName != nil {
 		val := *e.ManufacturerName
 		copy.ManufacturerName = &val
@@ -0,0 +1,129 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// auditChainStore implements the AuditChainStore interface using MongoDB
+type auditChainStore struct {
+	audits      *mongo.Collection
+	checkpoints *mongo.Collection
+}
+
+// NewAuditChainStore creates a new MongoDB audit chain store
+func NewAuditChainStore(db *mongo.Database) ports.AuditChainStore {
+	return &auditChainStore{
+		audits:      db.Collection("audit_log"),
+		checkpoints: db.Collection("audit_checkpoints"),
+	}
+}
+
+// GetAuditChainPartitions lists the partitions that have chained audits, oldest first
+func (s *auditChainStore) GetAuditChainPartitions(ctx context.Context) ([]string, error) {
+	values, err := s.audits.Distinct(ctx, "chain_partition", bson.M{"chain_partition": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit chain partitions: %w", err)
+	}
+
+	partitions := make([]string, 0, len(values))
+	for _, value := range values {
+		if partition, ok := value.(string); ok {
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Strings(partitions)
+	return partitions, nil
+}
+
+// GetAuditChainHead returns the last link of a partition, or nil when it has none
+func (s *auditChainStore) GetAuditChainHead(ctx context.Context, partition string) (*models.AuditChainHead, error) {
+	return chainHead(ctx, s.audits, partition)
+}
+
+// WalkAuditChain calls fn with the records of a partition in chain order
+func (s *auditChainStore) WalkAuditChain(ctx context.Context, partition string, fn func(audit *models.AuditLogExtended) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "chain_seq", Value: 1}})
+	cursor, err := s.audits.Find(ctx, bson.M{"chain_partition": partition}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to walk audit chain: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var audit models.AuditLogExtended
+		if err := cursor.Decode(&audit); err != nil {
+			return fmt.Errorf("failed to decode audit: %w", err)
+		}
+		if audit.AdditionalData != nil {
+			audit.AdditionalData = plainDocument(audit.AdditionalData)
+		}
+		if err := fn(&audit); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// plainDocument converts the BSON documents and arrays nested in a decoded
+// map back into the maps and slices they were written from, so the map
+// encodes to the same JSON it was hashed as
+func plainDocument(doc map[string]interface{}) map[string]interface{} {
+	plain := make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		plain[key] = plainValue(value)
+	}
+	return plain
+}
+
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.D:
+		return plainDocument(v.Map())
+	case primitive.M:
+		return plainDocument(v)
+	case map[string]interface{}:
+		return plainDocument(v)
+	case primitive.A:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = plainValue(item)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+// SaveAuditCheckpoint stores a signed checkpoint
+func (s *auditChainStore) SaveAuditCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	if _, err := s.checkpoints.InsertOne(ctx, checkpoint); err != nil {
+		return fmt.Errorf("failed to save audit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetAuditCheckpoints returns the checkpoints of a partition in chain order
+func (s *auditChainStore) GetAuditCheckpoints(ctx context.Context, partition string) ([]*models.AuditCheckpoint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := s.checkpoints.Find(ctx, bson.M{"chain_partition": partition}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit checkpoints: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	checkpoints := make([]*models.AuditCheckpoint, 0)
+	if err := cursor.All(ctx, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode audit checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
@@ -23,18 +24,63 @@ func NewAuditRepository(db *mongo.Database) ports.AuditRepository {
 	}
 }
 
-// LogCheck records an equipment check operation
+// maxChainAttempts bounds the retries of an audit that lost its place in the
+// chain to a concurrent writer
+const maxChainAttempts = 10
+
+// LogCheck records an equipment check operation, appending it to the hash
+// chain of its partition
 func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
-	result, err := r.collection.InsertOne(ctx, audit)
-	if err != nil {
+	if err := r.appendAudit(ctx, audit, audit, audit.ChainHash); err != nil {
 		return fmt.Errorf("failed to log check: %w", err)
 	}
+	return nil
+}
 
-	if oid, ok := result.InsertedID.(int64); ok {
-		audit.ID = oid
+// appendAudit links audit after the head of its partition, hashes it with
+// hash and inserts doc, the document holding audit. The unique chain index
+// rejects a link another writer took first, and the audit is linked again
+// after the new head.
+func (r *auditRepository) appendAudit(ctx context.Context, audit *models.AuditLog, doc interface{}, hash func() string) error {
+	partition := audit.PrepareChainLink()
+	for attempt := 1; ; attempt++ {
+		head, err := chainHead(ctx, r.collection, partition)
+		if err != nil {
+			return err
+		}
+		audit.LinkTo(head)
+		audit.Hash = hash()
+
+		result, err := r.collection.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) && attempt < maxChainAttempts {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if oid, ok := result.InsertedID.(int64); ok {
+			audit.ID = oid
+		}
+		return nil
 	}
+}
 
-	return nil
+// chainHead returns the last link of a partition, or nil when it has none
+func chainHead(ctx context.Context, collection *mongo.Collection, partition string) (*models.AuditChainHead, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "chain_seq", Value: -1}}).
+		SetProjection(bson.M{"chain_partition": 1, "chain_seq": 1, "hash": 1})
+
+	var head models.AuditChainHead
+	err := collection.FindOne(ctx, bson.M{"chain_partition": partition}, opts).Decode(&head)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit chain head: %w", err)
+	}
+	return &head, nil
 }
 
 // GetAuditsByIMEI retrieves audit logs for a specific IMEI
@@ -85,3 +131,31 @@ func (r *auditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, e
 
 	return audits, nil
 }
+
+// GetAuditsBySUPI retrieves audit logs whose SUPI or SUPI index is one of supis
+func (r *auditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"supi": bson.M{"$in": supis}},
+			bson.M{"supi_index": bson.M{"$in": supis}},
+		},
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "check_time", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by SUPI: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var audits []*models.AuditLog
+	if err = cursor.All(ctx, &audits); err != nil {
+		return nil, fmt.Errorf("failed to decode audits: %w", err)
+	}
+
+	return audits, nil
+}
@@ -0,0 +1,189 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	changeStreamMinRetry = time.Second
+	changeStreamMaxRetry = 10 * time.Second
+
+	// Resume tokens older than the oplog window can no longer be used
+	errCodeChangeStreamHistoryLost = 286
+	errCodeChangeStreamFatal       = 280
+)
+
+// changeKeyFields maps each watched collection to the field carrying its key
+var changeKeyFields = map[ports.ChangeTable]string{
+	ports.ChangeTableEquipment: "imei",
+	ports.ChangeTableImeiInfo:  "startimei",
+	ports.ChangeTableTacInfo:   "keytac",
+}
+
+// changeStreamEvent is the subset of a change event the feed needs
+type changeStreamEvent struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	FullDocument      bson.M `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// checkCounterFields are the fields flushed check counts update. Updates to
+// them alone are not provisioning changes and leave the caches alone.
+var checkCounterFields = map[string]bool{"check_count": true, "last_check_time": true}
+
+// countersOnly reports whether an update changed nothing but check counters
+func (e changeStreamEvent) countersOnly() bool {
+	if e.OperationType != "update" || len(e.UpdateDescription.UpdatedFields) == 0 {
+		return false
+	}
+	for field := range e.UpdateDescription.UpdatedFields {
+		if !checkCounterFields[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// toChangeEvents converts a change stream event into feed events. Deletes carry
+// only the document _id, so they are reported without keys.
+func toChangeEvents(e changeStreamEvent) []ports.ChangeEvent {
+	table := ports.ChangeTable(e.NS.Coll)
+
+	switch e.OperationType {
+	case "insert", "update", "replace", "delete":
+		if _, watched := changeKeyFields[table]; !watched || e.countersOnly() {
+			return nil
+		}
+		op := map[string]string{
+			"insert":  ports.ChangeOpInsert,
+			"update":  ports.ChangeOpUpdate,
+			"replace": ports.ChangeOpUpdate,
+			"delete":  ports.ChangeOpDelete,
+		}[e.OperationType]
+
+		event := ports.ChangeEvent{Table: table, Op: op}
+		if key, ok := e.FullDocument[changeKeyFields[table]].(string); ok {
+			event.Keys = []string{key}
+		}
+		return []ports.ChangeEvent{event}
+
+	case "drop", "rename":
+		if _, watched := changeKeyFields[table]; !watched {
+			return nil
+		}
+		return []ports.ChangeEvent{{Table: table, Op: ports.ChangeOpTruncate}}
+
+	case "dropDatabase", "invalidate":
+		return resyncEvents(ports.ChangeOpTruncate)
+	}
+	return nil
+}
+
+func resyncEvents(op string) []ports.ChangeEvent {
+	events := make([]ports.ChangeEvent, 0, len(ports.ChangeTables))
+	for _, table := range ports.ChangeTables {
+		events = append(events, ports.ChangeEvent{Table: table, Op: op})
+	}
+	return events
+}
+
+// changeFeed implements ports.ChangeFeed with a change stream on the list collections
+type changeFeed struct {
+	db *mongo.Database
+}
+
+// NewChangeFeed creates a change feed over the equipment, imei_info and tac_info collections.
+// Change streams require a replica set or sharded cluster.
+func NewChangeFeed(db *mongo.Database) ports.ChangeFeed {
+	return &changeFeed{db: db}
+}
+
+func (f *changeFeed) pipeline() mongo.Pipeline {
+	collections := bson.A{}
+	for _, table := range ports.ChangeTables {
+		collections = append(collections, string(table))
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"ns.coll": bson.M{"$in": collections}},
+			bson.M{"operationType": bson.M{"$in": bson.A{"dropDatabase", "invalidate"}}},
+		}}}},
+	}
+}
+
+func (f *changeFeed) Listen(ctx context.Context, handler func(ports.ChangeEvent)) error {
+	var resumeToken bson.Raw
+	retry := changeStreamMinRetry
+
+	for {
+		opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+
+		stream, err := f.db.Watch(ctx, f.pipeline(), opts)
+		if err == nil {
+			err = f.consume(ctx, stream, handler, &resumeToken, &retry)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if resumeToken != nil && hasErrorCode(err, errCodeChangeStreamHistoryLost, errCodeChangeStreamFatal) {
+			// Events between the token and now are gone; start over from scratch
+			logger.Log.Warnw("Change stream history lost, resynchronising", "error", err)
+			resumeToken = nil
+			for _, event := range resyncEvents(ports.ChangeOpResync) {
+				handler(event)
+			}
+		} else {
+			logger.Log.Warnw("Change stream interrupted, retrying", "retry_in", retry, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > changeStreamMaxRetry {
+			retry = changeStreamMaxRetry
+		}
+	}
+}
+
+// consume forwards events until the stream fails, remembering the resume token
+func (f *changeFeed) consume(ctx context.Context, stream *mongo.ChangeStream, handler func(ports.ChangeEvent), resumeToken *bson.Raw, retry *time.Duration) error {
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var e changeStreamEvent
+		if err := stream.Decode(&e); err != nil {
+			logger.Log.Warnw("Ignoring undecodable change event", "error", err)
+		} else {
+			for _, event := range toChangeEvents(e) {
+				handler(event)
+			}
+		}
+		*resumeToken = stream.ResumeToken()
+		*retry = changeStreamMinRetry
+	}
+
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("change stream closed")
+}
@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func changeEvent(op, coll string, doc bson.M) changeStreamEvent {
+	e := changeStreamEvent{OperationType: op, FullDocument: doc}
+	e.NS.Coll = coll
+	return e
+}
+
+func TestToChangeEvents(t *testing.T) {
+	t.Run("insert carries the key", func(t *testing.T) {
+		events := toChangeEvents(changeEvent("insert", "imei_info", bson.M{"startimei": "35693803564380"}))
+		require.Len(t, events, 1)
+		assert.Equal(t, ports.ChangeTableImeiInfo, events[0].Table)
+		assert.Equal(t, ports.ChangeOpInsert, events[0].Op)
+		assert.Equal(t, []string{"35693803564380"}, events[0].Keys)
+	})
+
+	t.Run("replace maps to update", func(t *testing.T) {
+		events := toChangeEvents(changeEvent("replace", "equipment", bson.M{"imei": "490154203237518"}))
+		require.Len(t, events, 1)
+		assert.Equal(t, ports.ChangeOpUpdate, events[0].Op)
+		assert.Equal(t, []string{"490154203237518"}, events[0].Keys)
+	})
+
+	t.Run("delete has no key", func(t *testing.T) {
+		events := toChangeEvents(changeEvent("delete", "tac_info", nil))
+		require.Len(t, events, 1)
+		assert.Equal(t, ports.ChangeOpDelete, events[0].Op)
+		assert.Nil(t, events[0].Keys)
+	})
+
+	t.Run("check counter flush is ignored", func(t *testing.T) {
+		e := changeEvent("update", "equipment", bson.M{"imei": "490154203237518"})
+		e.UpdateDescription.UpdatedFields = bson.M{"check_count": int64(3), "last_check_time": "2025-03-01T00:00:00Z"}
+		assert.Empty(t, toChangeEvents(e))
+
+		e.UpdateDescription.UpdatedFields["status"] = "BLACKLISTED"
+		assert.Len(t, toChangeEvents(e), 1)
+	})
+
+	t.Run("unwatched collection is ignored", func(t *testing.T) {
+		assert.Empty(t, toChangeEvents(changeEvent("insert", "audit_log", bson.M{"imei": "1"})))
+	})
+
+	t.Run("invalidate resets every table", func(t *testing.T) {
+		events := toChangeEvents(changeEvent("invalidate", "", nil))
+		assert.Len(t, events, len(ports.ChangeTables))
+	})
+}
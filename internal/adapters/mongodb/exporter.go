@@ -0,0 +1,112 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hsdfat8/eir/internal/adapters/export"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exportBatchSize is how many documents the cursor fetches per round trip
+const exportBatchSize = 1000
+
+// dataExporter streams collections document by document from a cursor
+type dataExporter struct {
+	db *mongo.Database
+}
+
+// NewDataExporter creates a MongoDB data exporter
+func NewDataExporter(db *mongo.Database) ports.DataExporter {
+	return &dataExporter{db: db}
+}
+
+// ExportEquipment exports all equipment but tombstones, in insertion order
+func (e *dataExporter) ExportEquipment(ctx context.Context, writer io.Writer, format string) error {
+	w, err := export.NewEquipmentWriter(writer, format)
+	if err != nil {
+		return err
+	}
+	return e.stream(ctx, w, "equipment", notDeleted, bson.D{{Key: "_id", Value: 1}},
+		func() interface{} { return &models.Equipment{} })
+}
+
+// ExportAudits exports audit logs checked within the range, oldest first
+func (e *dataExporter) ExportAudits(ctx context.Context, writer io.Writer, format string, startTime, endTime string) error {
+	timeRange, err := export.ParseTimeRange(startTime, endTime)
+	if err != nil {
+		return err
+	}
+	w, err := export.NewAuditWriter(writer, format)
+	if err != nil {
+		return err
+	}
+	return e.stream(ctx, w, "audit_log", rangeFilter("check_time", timeRange),
+		bson.D{{Key: "check_time", Value: 1}, {Key: "_id", Value: 1}},
+		func() interface{} { return &models.AuditLog{} })
+}
+
+// ExportHistory exports change history recorded within the range, oldest first
+func (e *dataExporter) ExportHistory(ctx context.Context, writer io.Writer, format string, startTime, endTime string) error {
+	timeRange, err := export.ParseTimeRange(startTime, endTime)
+	if err != nil {
+		return err
+	}
+	w, err := export.NewHistoryWriter(writer, format)
+	if err != nil {
+		return err
+	}
+	return e.stream(ctx, w, "equipment_history", rangeFilter("changed_at", timeRange),
+		bson.D{{Key: "changed_at", Value: 1}, {Key: "_id", Value: 1}},
+		func() interface{} { return &models.EquipmentHistory{} })
+}
+
+// Close releases the exporter. The client belongs to the adapter.
+func (e *dataExporter) Close() error {
+	return nil
+}
+
+// stream decodes each document matching filter into a fresh record and hands it to w
+func (e *dataExporter) stream(ctx context.Context, w *export.Writer, collection string, filter bson.M, sort bson.D, newRecord func() interface{}) error {
+	opts := options.Find().SetSort(sort).SetBatchSize(exportBatchSize)
+	cursor, err := e.db.Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to query %s export: %w", collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		record := newRecord()
+		if err := cursor.Decode(record); err != nil {
+			return fmt.Errorf("failed to decode %s export document: %w", collection, err)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read %s export: %w", collection, err)
+	}
+
+	return w.Close()
+}
+
+// rangeFilter bounds field by timeRange
+func rangeFilter(field string, timeRange export.TimeRange) bson.M {
+	bounds := bson.M{}
+	if !timeRange.Start.IsZero() {
+		bounds["$gte"] = timeRange.Start
+	}
+	if !timeRange.End.IsZero() {
+		bounds["$lte"] = timeRange.End
+	}
+	if len(bounds) == 0 {
+		return bson.M{}
+	}
+	return bson.M{field: bounds}
+}
@@ -29,15 +29,11 @@ func NewExtendedAuditRepository(db *mongo.Database) ports.ExtendedAuditRepositor
 // LogCheckExtended records an extended equipment check with additional metadata
 func (r *extendedAuditRepository) LogCheckExtended(ctx context.Context, audit *models.AuditLogExtended) error {
 	// MongoDB stores the extended audit as a single document with embedded fields
-	result, err := r.collection.InsertOne(ctx, audit)
+	err := r.appendAudit(ctx, &audit.AuditLog, audit, audit.ChainHash)
 	if err != nil {
 		return fmt.Errorf("failed to log extended check: %w", err)
 	}
 
-	if oid, ok := result.InsertedID.(int64); ok {
-		audit.ID = oid
-	}
-
 	// Record change history if provided
 	if audit.ChangeHistory != nil {
 		err = r.historyRepo.RecordChange(ctx, audit.ChangeHistory)
@@ -107,3 +107,39 @@ func (r *historyRepository) GetHistoryByChangeType(ctx context.Context, changeTy
 
 	return history, nil
 }
+
+// GetListHistory retrieves, oldest first, the changes made at or before until
+// to the IMEI_INFO entry of startImei and to the TAC ranges enclosing tac
+func (r *historyRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	// key_tac is "start-end" with both bounds padded to the same length, so
+	// keys up to tac + "-\uffff" are the ranges starting at or before tac
+	filter := bson.M{
+		"changed_at": bson.M{"$lte": until},
+		"$or": bson.A{
+			bson.M{"change_details.list": "imei", "change_details.start_imei": startImei},
+			bson.M{
+				"change_details.list":    "tac",
+				"change_details.key_tac": bson.M{"$lte": tac + "-\uffff"},
+				"$expr": bson.M{"$gte": bson.A{
+					bson.M{"$arrayElemAt": bson.A{bson.M{"$split": bson.A{"$change_details.key_tac", "-"}}, 1}},
+					tac,
+				}},
+			},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "changed_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var history []*models.EquipmentHistory
+	if err = cursor.All(ctx, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode history: %w", err)
+	}
+
+	return history, nil
+}
@@ -69,6 +69,7 @@ func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment
 	}
 
 	equipment.LastUpdated = time.Now()
+	equipment.Version = 1
 
 	result, err := r.collection.InsertOne(ctx, equipment)
 	if err != nil {
@@ -89,28 +90,68 @@ func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment
 func (r *imeiRepository) Update(ctx context.Context, equipment *models.Equipment) error {
 	equipment.LastUpdated = time.Now()
 
-	update := bson.M{
-		"$set": bson.M{
-			"imeisv":            equipment.IMEISV,
-			"status":            equipment.Status,
-			"reason":            equipment.Reason,
-			"last_updated":      equipment.LastUpdated,
-			"metadata":          equipment.Metadata,
-			"manufacturer_tac":  equipment.ManufacturerTAC,
-			"manufacturer_name": equipment.ManufacturerName,
-		},
-	}
-
-	result, err := r.collection.UpdateOne(ctx, bson.M{"imei": equipment.IMEI}, update)
+	set := bson.M{
+		"imeisv":            equipment.IMEISV,
+		"status":            equipment.Status,
+		"reason":            equipment.Reason,
+		"last_updated":      equipment.LastUpdated,
+		"metadata":          equipment.Metadata,
+		"manufacturer_tac":  equipment.ManufacturerTAC,
+		"manufacturer_name": equipment.ManufacturerName,
+		"deleted_at":        equipment.DeletedAt,
+		"deleted_by":        equipment.DeletedBy,
+	}
+
+	version, err := versionedUpdate(ctx, r.collection, "equipment", "imei", equipment.IMEI, equipment.Version, set, false)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
 		return fmt.Errorf("failed to update equipment: %w", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrNotFound
+	equipment.Version = version
+	return nil
+}
+
+// versionedUpdate applies set to the document whose keyField is key and
+// advances its version, returning the new one. A non-zero expected version
+// must match the stored one, otherwise the result is a VersionConflictError.
+// With upsert an unconditional update creates a missing document and a
+// conditional one conflicts with it; without, both return mongo.ErrNoDocuments.
+func versionedUpdate(ctx context.Context, collection *mongo.Collection, entity, keyField, key string, expected int64, set bson.M, upsert bool) (int64, error) {
+	filter := bson.M{keyField: key}
+	if expected != 0 {
+		filter["version"] = expected
 	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(upsert && expected == 0).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"version": 1})
 
-	return nil
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set, "$inc": bson.M{"version": 1}}, opts).Decode(&updated)
+	if err == nil {
+		return updated.Version, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) || expected == 0 {
+		return 0, err
+	}
+
+	// The filter did not match: tell a stale version from a missing document
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	err = collection.FindOne(ctx, bson.M{keyField: key}, options.FindOne().SetProjection(bson.M{"version": 1})).Decode(&current)
+	if err != nil && (!errors.Is(err, mongo.ErrNoDocuments) || !upsert) {
+		return 0, err
+	}
+	return 0, &ports.VersionConflictError{Entity: entity, Key: key, Expected: expected, Actual: current.Version}
 }
 
 // Delete removes an equipment record
@@ -127,14 +168,31 @@ func (r *imeiRepository) Delete(ctx context.Context, imei string) error {
 	return nil
 }
 
-// List retrieves equipment with pagination
+// notDeleted matches equipment that is not a tombstone
+var notDeleted = bson.M{"deleted_at": nil}
+
+// List retrieves equipment with pagination, skipping tombstones
 func (r *imeiRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.find(ctx, notDeleted, offset, limit)
+}
+
+// ListWithDeleted retrieves equipment with pagination, tombstones included
+func (r *imeiRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.find(ctx, bson.M{}, offset, limit)
+}
+
+// ListByStatus retrieves equipment by status with pagination, skipping tombstones
+func (r *imeiRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+	return r.find(ctx, bson.M{"status": status, "deleted_at": nil}, offset, limit)
+}
+
+func (r *imeiRepository) find(ctx context.Context, filter bson.M, offset, limit int) ([]*models.Equipment, error) {
 	opts := options.Find().
 		SetSort(bson.D{{Key: "last_updated", Value: -1}}).
 		SetSkip(int64(offset)).
 		SetLimit(int64(limit))
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list equipment: %w", err)
 	}
@@ -148,25 +206,34 @@ func (r *imeiRepository) List(ctx context.Context, offset, limit int) ([]*models
 	return equipments, nil
 }
 
-// ListByStatus retrieves equipment by status with pagination
-func (r *imeiRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
-	opts := options.Find().
-		SetSort(bson.D{{Key: "last_updated", Value: -1}}).
-		SetSkip(int64(offset)).
-		SetLimit(int64(limit))
-
-	cursor, err := r.collection.Find(ctx, bson.M{"status": status}, opts)
+// PurgeDeleted removes at most limit tombstones deleted before the given time
+func (r *imeiRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	expired := bson.M{"deleted_at": bson.M{"$lt": before}}
+	opts := options.Find().SetLimit(int64(limit)).SetProjection(bson.M{"imei": 1})
+	cursor, err := r.collection.Find(ctx, expired, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list equipment by status: %w", err)
+		return 0, fmt.Errorf("failed to find deleted equipment: %w", err)
 	}
-	defer cursor.Close(ctx)
-
-	var equipments []*models.Equipment
-	if err = cursor.All(ctx, &equipments); err != nil {
-		return nil, fmt.Errorf("failed to decode equipment: %w", err)
+	var keys []struct {
+		IMEI string `bson:"imei"`
+	}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return 0, fmt.Errorf("failed to decode deleted equipment: %w", err)
+	}
+	if len(keys) == 0 {
+		return 0, nil
 	}
 
-	return equipments, nil
+	imeis := make([]string, len(keys))
+	for i, key := range keys {
+		imeis[i] = key.IMEI
+	}
+	expired["imei"] = bson.M{"$in": imeis}
+	result, err := r.collection.DeleteMany(ctx, expired)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted equipment: %w", err)
+	}
+	return result.DeletedCount, nil
 }
 
 // IncrementCheckCount atomically increments check counter and updates last check time
@@ -188,6 +255,28 @@ func (r *imeiRepository) IncrementCheckCount(ctx context.Context, imei string) e
 	return nil
 }
 
+// ApplyCheckCounts adds flushed check counts in one unordered bulk write
+func (r *imeiRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	updates := make([]mongo.WriteModel, len(deltas))
+	for i, delta := range deltas {
+		updates[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imei": delta.IMEI, "deleted_at": nil}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"check_count": delta.Count},
+				"$max": bson.M{"last_check_time": delta.LastCheckTime},
+			})
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to apply check counts: %w", err)
+	}
+	return nil
+}
+
 // IMEI logic operations
 func (r *imeiRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
 	imeiCollection := r.collection.Database().Collection("imei_info")
@@ -206,23 +295,49 @@ func (r *imeiRepository) LookupImeiInfo(ctx context.Context, startRange string)
 func (r *imeiRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
 	imeiCollection := r.collection.Database().Collection("imei_info")
 
-	filter := bson.M{"startimei": info.StartIMEI}
-	update := bson.M{
-		"$set": bson.M{
-			"startimei": info.StartIMEI,
-			"endimei":   info.EndIMEI,
-			"color":     info.Color,
-		},
+	set := bson.M{
+		"startimei": info.StartIMEI,
+		"endimei":   info.EndIMEI,
+		"color":     info.Color,
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := imeiCollection.UpdateOne(ctx, filter, update, opts)
+	version, err := versionedUpdate(ctx, imeiCollection, "imei_info", "startimei", info.StartIMEI, info.Version, set, true)
 	if err != nil {
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
 		return fmt.Errorf("failed to save imei info: %w", err)
 	}
+	info.Version = version
 	return nil
 }
 
+func (r *imeiRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	imeiCollection := r.collection.Database().Collection("imei_info")
+
+	filter := bson.M{"startimei": info.StartIMEI}
+	if info.Version != 0 {
+		filter["version"] = info.Version
+	}
+	result, err := imeiCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete imei info: %w", err)
+	}
+	if result.DeletedCount > 0 || info.Version == 0 {
+		return nil
+	}
+
+	// The filter did not match: the entry changed or is already gone
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	err = imeiCollection.FindOne(ctx, bson.M{"startimei": info.StartIMEI}, options.FindOne().SetProjection(bson.M{"version": 1})).Decode(&current)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to delete imei info: %w", err)
+	}
+	return &ports.VersionConflictError{Entity: "imei_info", Key: info.StartIMEI, Expected: info.Version, Actual: current.Version}
+}
+
 func (r *imeiRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
 	return []*ports.ImeiInfo{}
 }
@@ -235,22 +350,22 @@ func (r *imeiRepository) ClearImeiInfo(ctx context.Context) {
 func (r *imeiRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
 	tacCollection := r.collection.Database().Collection("tac_info")
 
-	filter := bson.M{"keytac": info.KeyTac}
-	update := bson.M{
-		"$set": bson.M{
-			"keytac":        info.KeyTac,
-			"startrangetac": info.StartRangeTac,
-			"endrangetac":   info.EndRangeTac,
-			"color":         info.Color,
-			"prevlink":      info.PrevLink,
-		},
+	set := bson.M{
+		"keytac":        info.KeyTac,
+		"startrangetac": info.StartRangeTac,
+		"endrangetac":   info.EndRangeTac,
+		"color":         info.Color,
+		"prevlink":      info.PrevLink,
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := tacCollection.UpdateOne(ctx, filter, update, opts)
+	version, err := versionedUpdate(ctx, tacCollection, "tac_info", "keytac", info.KeyTac, info.Version, set, true)
 	if err != nil {
+		if errors.Is(err, ports.ErrVersionConflict) {
+			return err
+		}
 		return fmt.Errorf("failed to save tac info: %w", err)
 	}
+	info.Version = version
 	return nil
 }
 
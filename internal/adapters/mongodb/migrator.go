@@ -0,0 +1,562 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationsCollection tracks applied migrations, mirroring the Postgres schema_migrations table
+const migrationsCollection = "schema_migrations"
+
+// Server error codes tolerated while migrating
+const (
+	errCodeNamespaceNotFound = 26
+	errCodeIndexNotFound     = 27
+	errCodeNamespaceExists   = 48
+)
+
+// indexSpec declares a single index owned by a migration
+type indexSpec struct {
+	Collection string `json:"collection"`
+	Keys       bson.D `json:"keys"`
+	Unique     bool   `json:"unique,omitempty"`
+	Partial    bson.M `json:"partial,omitempty"` // Only documents matching this filter are indexed
+}
+
+// name returns the index name the driver would generate, e.g. imei_1_check_time_-1
+func (s indexSpec) name() string {
+	parts := make([]string, 0, len(s.Keys)*2)
+	for _, key := range s.Keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// model converts the spec into a driver index model
+func (s indexSpec) model() mongo.IndexModel {
+	opts := options.Index().SetName(s.name())
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.Partial != nil {
+		opts.SetPartialFilterExpression(s.Partial)
+	}
+	return mongo.IndexModel{Keys: s.Keys, Options: opts}
+}
+
+// fieldRename declares a field moved to a new name in every document of a
+// collection. Where a document already has the new field, it wins and the
+// old one is dropped.
+type fieldRename struct {
+	Collection string `json:"collection"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// apply renames from to to in every document of the collection
+func (r fieldRename) apply(ctx context.Context, db *mongo.Database, from, to string) error {
+	collection := db.Collection(r.Collection)
+	_, err := collection.UpdateMany(ctx,
+		bson.M{from: bson.M{"$exists": true}, to: bson.M{"$exists": false}},
+		bson.M{"$rename": bson.M{from: to}})
+	if err != nil {
+		return fmt.Errorf("failed to rename %s.%s to %s: %w", r.Collection, from, to, err)
+	}
+	_, err = collection.UpdateMany(ctx, bson.M{from: bson.M{"$exists": true}}, bson.M{"$unset": bson.M{from: ""}})
+	if err != nil {
+		return fmt.Errorf("failed to drop %s.%s: %w", r.Collection, from, err)
+	}
+	return nil
+}
+
+// valueRename declares a field value replaced by a new one in every document
+// of a collection
+type valueRename struct {
+	Collection string `json:"collection"`
+	Field      string `json:"field"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// apply replaces the value from of the field with to
+func (r valueRename) apply(ctx context.Context, db *mongo.Database, from, to string) error {
+	_, err := db.Collection(r.Collection).UpdateMany(ctx,
+		bson.M{r.Field: from}, bson.M{"$set": bson.M{r.Field: to}})
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s.%s %q as %q: %w", r.Collection, r.Field, from, to, err)
+	}
+	return nil
+}
+
+// mongoMigration is a declarative, versioned set of collections, indexes,
+// field renames and value rewrites
+type mongoMigration struct {
+	version     int64
+	description string
+	collections []string
+	indexes     []indexSpec
+	renames     []fieldRename
+	values      []valueRename
+	db          *mongo.Database
+}
+
+// Up creates the migration's collections and indexes
+func (m *mongoMigration) Up(ctx context.Context) error {
+	existing, err := m.db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+	present := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		present[name] = true
+	}
+
+	for _, name := range m.collections {
+		if present[name] {
+			continue
+		}
+		if err := m.db.CreateCollection(ctx, name); err != nil && !hasErrorCode(err, errCodeNamespaceExists) {
+			return fmt.Errorf("failed to create collection %s: %w", name, err)
+		}
+	}
+
+	for _, rename := range m.renames {
+		if err := rename.apply(ctx, m.db, rename.From, rename.To); err != nil {
+			return err
+		}
+	}
+	for _, value := range m.values {
+		if err := value.apply(ctx, m.db, value.From, value.To); err != nil {
+			return err
+		}
+	}
+
+	for _, collection := range m.indexCollections() {
+		var models []mongo.IndexModel
+		for _, spec := range m.indexes {
+			if spec.Collection == collection {
+				models = append(models, spec.model())
+			}
+		}
+		if _, err := m.db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", collection, err)
+		}
+	}
+
+	return nil
+}
+
+// Down drops the migration's indexes and renames fields and values back.
+// Collections are kept so data is never lost.
+func (m *mongoMigration) Down(ctx context.Context) error {
+	for _, spec := range m.indexes {
+		_, err := m.db.Collection(spec.Collection).Indexes().DropOne(ctx, spec.name())
+		if err != nil && !hasErrorCode(err, errCodeIndexNotFound, errCodeNamespaceNotFound) {
+			return fmt.Errorf("failed to drop index %s on %s: %w", spec.name(), spec.Collection, err)
+		}
+	}
+	for _, rename := range m.renames {
+		if err := rename.apply(ctx, m.db, rename.To, rename.From); err != nil {
+			return err
+		}
+	}
+	for _, value := range m.values {
+		if err := value.apply(ctx, m.db, value.To, value.From); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version returns the migration version
+func (m *mongoMigration) Version() int64 {
+	return m.version
+}
+
+// Description returns the migration description
+func (m *mongoMigration) Description() string {
+	return m.description
+}
+
+// checksum hashes the declarative content of the migration
+func (m *mongoMigration) checksum() string {
+	content, _ := json.Marshal(struct {
+		Collections []string      `json:"collections"`
+		Indexes     []indexSpec   `json:"indexes"`
+		Renames     []fieldRename `json:"renames,omitempty"`
+		Values      []valueRename `json:"values,omitempty"`
+	}{m.collections, m.indexes, m.renames, m.values})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// indexCollections returns the collections touched by the migration's indexes in declaration order
+func (m *mongoMigration) indexCollections() []string {
+	var collections []string
+	seen := make(map[string]bool)
+	for _, spec := range m.indexes {
+		if !seen[spec.Collection] {
+			seen[spec.Collection] = true
+			collections = append(collections, spec.Collection)
+		}
+	}
+	return collections
+}
+
+// hasErrorCode reports whether err is a server error with one of the given codes
+func hasErrorCode(err error, codes ...int) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	for _, code := range codes {
+		if serverErr.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// baselineIndexes are the indexes the adapter has always created on connect
+var baselineIndexes = []indexSpec{
+	// Equipment collection indexes
+	{Collection: "equipment", Keys: bson.D{{Key: "imei", Value: 1}}, Unique: true},
+	{Collection: "equipment", Keys: bson.D{{Key: "status", Value: 1}}},
+	{Collection: "equipment", Keys: bson.D{{Key: "manufacturer_tac", Value: 1}}},
+	{Collection: "equipment", Keys: bson.D{{Key: "last_check_time", Value: -1}}},
+	{Collection: "equipment", Keys: bson.D{{Key: "check_count", Value: -1}}},
+	{Collection: "equipment", Keys: bson.D{{Key: "last_updated", Value: -1}}},
+
+	// Audit log collection indexes
+	{Collection: "audit_log", Keys: bson.D{{Key: "imei", Value: 1}}},
+	{Collection: "audit_log", Keys: bson.D{{Key: "check_time", Value: -1}}},
+	{Collection: "audit_log", Keys: bson.D{{Key: "status", Value: 1}}},
+	{Collection: "audit_log", Keys: bson.D{{Key: "request_source", Value: 1}}},
+	{Collection: "audit_log", Keys: bson.D{{Key: "supi", Value: 1}}},
+	{Collection: "audit_log", Keys: bson.D{{Key: "imei", Value: 1}, {Key: "check_time", Value: -1}}},
+
+	// Equipment history collection indexes
+	{Collection: "equipment_history", Keys: bson.D{{Key: "imei", Value: 1}}},
+	{Collection: "equipment_history", Keys: bson.D{{Key: "changed_at", Value: -1}}},
+	{Collection: "equipment_history", Keys: bson.D{{Key: "change_type", Value: 1}}},
+	{Collection: "equipment_history", Keys: bson.D{{Key: "changed_by", Value: 1}}},
+
+	// Equipment snapshots collection indexes
+	{Collection: "equipment_snapshots", Keys: bson.D{{Key: "imei", Value: 1}}},
+	{Collection: "equipment_snapshots", Keys: bson.D{{Key: "snapshot_time", Value: -1}}},
+	{Collection: "equipment_snapshots", Keys: bson.D{{Key: "equipment_id", Value: 1}}},
+	{Collection: "equipment_snapshots", Keys: bson.D{{Key: "snapshot_type", Value: 1}}},
+
+	// IMEI and TAC list collection indexes
+	{Collection: "imei_info", Keys: bson.D{{Key: "startimei", Value: 1}}, Unique: true},
+	{Collection: "tac_info", Keys: bson.D{{Key: "keytac", Value: 1}}, Unique: true},
+}
+
+// newMigrations returns the ordered migration set bound to db.
+// Append new versions here; never edit a migration that has shipped.
+func newMigrations(db *mongo.Database) []*mongoMigration {
+	return []*mongoMigration{
+		{
+			version:     1,
+			description: "initial collections and indexes",
+			collections: []string{
+				"equipment",
+				"audit_log",
+				"equipment_history",
+				"equipment_snapshots",
+				"imei_info",
+				"tac_info",
+			},
+			indexes: baselineIndexes,
+			db:      db,
+		},
+		{
+			version:     2,
+			description: "history and snapshot timeline indexes",
+			indexes: []indexSpec{
+				{Collection: "equipment_history", Keys: bson.D{{Key: "imei", Value: 1}, {Key: "changed_at", Value: -1}}},
+				{Collection: "equipment_snapshots", Keys: bson.D{{Key: "imei", Value: 1}, {Key: "snapshot_time", Value: -1}}},
+			},
+			db: db,
+		},
+		{
+			version:     3,
+			description: "equipment tombstone index",
+			indexes: []indexSpec{
+				{Collection: "equipment", Keys: bson.D{{Key: "deleted_at", Value: 1}}},
+			},
+			db: db,
+		},
+		{
+			version:     4,
+			description: "audit SUPI index",
+			indexes: []indexSpec{
+				{Collection: "audit_log", Keys: bson.D{{Key: "supi_index", Value: 1}}},
+			},
+			db: db,
+		},
+		{
+			version:     5,
+			description: "audit hash chain",
+			collections: []string{"audit_checkpoints"},
+			indexes: []indexSpec{
+				// Two writers can never take the same place in a chain
+				{
+					Collection: "audit_log",
+					Keys:       bson.D{{Key: "chain_partition", Value: 1}, {Key: "chain_seq", Value: 1}},
+					Unique:     true,
+					Partial:    bson.M{"chain_seq": bson.M{"$exists": true}},
+				},
+				{Collection: "audit_checkpoints", Keys: bson.D{{Key: "chain_partition", Value: 1}, {Key: "seq", Value: 1}}},
+			},
+			db: db,
+		},
+		{
+			version:     6,
+			description: "equipment field names",
+			// Equipment inserted before its fields had BSON names was stored
+			// under the driver's lowercased Go names, which the queries,
+			// updates and indexes never matched
+			renames: []fieldRename{
+				{Collection: "equipment", From: "lastupdated", To: "last_updated"},
+				{Collection: "equipment", From: "lastchecktime", To: "last_check_time"},
+				{Collection: "equipment", From: "checkcount", To: "check_count"},
+				{Collection: "equipment", From: "addedby", To: "added_by"},
+				{Collection: "equipment", From: "manufacturertac", To: "manufacturer_tac"},
+				{Collection: "equipment", From: "manufacturername", To: "manufacturer_name"},
+				{Collection: "equipment", From: "deletedat", To: "deleted_at"},
+				{Collection: "equipment", From: "deletedby", To: "deleted_by"},
+			},
+			db: db,
+		},
+		{
+			version:     7,
+			description: "list status vocabulary",
+			// The IMEI and TAC lists stored their own colors before they
+			// stored equipment status names
+			values: []valueRename{
+				{Collection: "imei_info", Field: "color", From: "w", To: "WHITELISTED"},
+				{Collection: "imei_info", Field: "color", From: "b", To: "BLACKLISTED"},
+				{Collection: "imei_info", Field: "color", From: "g", To: "GREYLISTED"},
+				{Collection: "tac_info", Field: "color", From: "white", To: "WHITELISTED"},
+				{Collection: "tac_info", Field: "color", From: "black", To: "BLACKLISTED"},
+				{Collection: "tac_info", Field: "color", From: "grey", To: "GREYLISTED"},
+			},
+			db: db,
+		},
+		{
+			version:     8,
+			description: "point-in-time list history indexes",
+			indexes: []indexSpec{
+				{
+					Collection: "equipment_history",
+					Keys:       bson.D{{Key: "change_details.start_imei", Value: 1}, {Key: "changed_at", Value: 1}},
+					Partial:    bson.M{"change_details.list": "imei"},
+				},
+				{
+					Collection: "equipment_history",
+					Keys:       bson.D{{Key: "change_details.key_tac", Value: 1}},
+					Partial:    bson.M{"change_details.list": "tac"},
+				},
+			},
+			db: db,
+		},
+	}
+}
+
+// migrationRecord is a document in the schema_migrations collection
+type migrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	Checksum    string    `bson:"checksum"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrator implements ports.MigrationManager for MongoDB collections and indexes
+type Migrator struct {
+	db         *mongo.Database
+	migrations []*mongoMigration
+}
+
+var _ ports.MigrationManager = (*Migrator)(nil)
+
+// NewMigrator creates a new MongoDB migrator
+func NewMigrator(db *mongo.Database) *Migrator {
+	migrations := newMigrations(db)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Run applies every pending migration in version order
+func (m *Migrator) Run(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].version)
+}
+
+// Rollback reverts the most recently applied migration
+func (m *Migrator) Rollback(ctx context.Context) error {
+	return m.Down(ctx, 1)
+}
+
+// Down reverts the last n applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive, got %d", n)
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var versions []int64
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; ok {
+			versions = append(versions, mig.version)
+		}
+	}
+	if len(versions) == 0 {
+		fmt.Println("No migrations to revert")
+		return nil
+	}
+	if n > len(versions) {
+		n = len(versions)
+	}
+
+	target := int64(0)
+	if idx := len(versions) - n - 1; idx >= 0 {
+		target = versions[idx]
+	}
+	return m.Goto(ctx, target)
+}
+
+// Goto migrates up or down until version is the latest applied migration
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		if rec, ok := applied[mig.version]; ok && rec.Checksum != mig.checksum() {
+			return fmt.Errorf("checksum mismatch for applied migration %04d: database has %s, code has %s",
+				mig.version, rec.Checksum, mig.checksum())
+		}
+	}
+
+	fmt.Println("Starting database migration...")
+
+	collection := m.db.Collection(migrationsCollection)
+
+	// Revert newest first, then apply oldest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.version]; !ok || mig.version <= version {
+			continue
+		}
+		fmt.Printf("Reverting migration %04d (%s)...\n", mig.version, mig.description)
+		if err := mig.Down(ctx); err != nil {
+			return fmt.Errorf("failed to revert migration %04d: %w", mig.version, err)
+		}
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": mig.version}); err != nil {
+			return fmt.Errorf("failed to remove migration record: %w", err)
+		}
+		fmt.Printf("✓ Reverted migration %04d\n", mig.version)
+	}
+
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; ok || mig.version > version {
+			continue
+		}
+		fmt.Printf("Applying migration %04d (%s)...\n", mig.version, mig.description)
+		if err := mig.Up(ctx); err != nil {
+			return fmt.Errorf("failed to apply migration %04d: %w", mig.version, err)
+		}
+		record := migrationRecord{
+			Version:     mig.version,
+			Description: mig.description,
+			Checksum:    mig.checksum(),
+			AppliedAt:   time.Now(),
+		}
+		// A concurrent migrator may have recorded it first; migrations are idempotent
+		if _, err := collection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+		fmt.Printf("✓ Applied migration %04d\n", mig.version)
+	}
+
+	fmt.Println("Database migration completed successfully!")
+	return nil
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]ports.MigrationStatus, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ports.MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := ports.MigrationStatus{
+			Version:     mig.version,
+			Description: mig.description,
+		}
+		if rec, ok := applied[mig.version]; ok {
+			status.Applied = true
+			status.AppliedAt = rec.AppliedAt.Format(time.RFC3339)
+			status.Checksum = rec.Checksum
+			status.Dirty = rec.Checksum != mig.checksum()
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// appliedVersions loads the schema_migrations collection
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]migrationRecord, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]migrationRecord, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// find returns the migration with the given version
+func (m *Migrator) find(version int64) *mongoMigration {
+	for _, mig := range m.migrations {
+		if mig.version == version {
+			return mig
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoMigrationsOrdering(t *testing.T) {
+	m := NewMigrator(nil)
+	require.NotEmpty(t, m.migrations)
+
+	for i, mig := range m.migrations {
+		assert.Equal(t, int64(i+1), mig.version, "versions must be contiguous")
+		assert.NotEmpty(t, mig.description)
+		assert.Len(t, mig.checksum(), 64)
+	}
+}
+
+func TestMongoMigrationChecksumStable(t *testing.T) {
+	a := newMigrations(nil)
+	b := newMigrations(nil)
+	for i := range a {
+		assert.Equal(t, a[i].checksum(), b[i].checksum())
+	}
+	assert.NotEqual(t, a[0].checksum(), a[1].checksum())
+}
+
+func TestIndexSpecName(t *testing.T) {
+	t.Run("single key", func(t *testing.T) {
+		spec := indexSpec{Collection: "equipment", Keys: bson.D{{Key: "imei", Value: 1}}}
+		assert.Equal(t, "imei_1", spec.name())
+	})
+
+	t.Run("compound key matches driver default", func(t *testing.T) {
+		spec := indexSpec{Collection: "audit_log", Keys: bson.D{{Key: "imei", Value: 1}, {Key: "check_time", Value: -1}}}
+		assert.Equal(t, "imei_1_check_time_-1", spec.name())
+	})
+}
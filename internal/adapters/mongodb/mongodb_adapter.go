@@ -82,6 +82,7 @@ func (a *MongoDBAdapter) Connect(ctx context.Context) error {
 
 	// Ping to verify connection
 	if err = client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
 		return fmt.Errorf("failed to ping mongodb: %w", err)
 	}
 
@@ -138,10 +139,11 @@ func (a *MongoDBAdapter) BeginTransaction(ctx context.Context) (ports.Transactio
 	}
 
 	return &mongoTransaction{
-		session:   session,
-		db:        a.db,
-		imeiRepo:  NewIMEIRepository(a.db),
-		auditRepo: NewAuditRepository(a.db),
+		session:     session,
+		db:          a.db,
+		imeiRepo:    &sessionIMEIRepository{session: session, repo: a.imeiRepo},
+		auditRepo:   &sessionAuditRepository{session: session, repo: a.auditRepo},
+		historyRepo: &sessionHistoryRepository{session: session, repo: a.historyRepo},
 	}, nil
 }
 
@@ -170,6 +172,11 @@ func (a *MongoDBAdapter) GetSnapshotRepository() ports.SnapshotRepository {
 	return a.snapshotRepo
 }
 
+// GetAuditChainStore returns the audit hash chain of this database
+func (a *MongoDBAdapter) GetAuditChainStore() ports.AuditChainStore {
+	return NewAuditChainStore(a.db)
+}
+
 // HealthCheck performs a health check on the database
 func (a *MongoDBAdapter) HealthCheck(ctx context.Context) error {
 	if err := a.Ping(ctx); err != nil {
@@ -223,6 +230,57 @@ func (a *MongoDBAdapter) PurgeOldHistory(ctx context.Context, beforeDate string)
 	return result.DeletedCount, nil
 }
 
+// PurgeAuditsBatch removes at most limit audit logs older than before, oldest first
+func (a *MongoDBAdapter) PurgeAuditsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return a.purgeBatch(ctx, "audit_log", "check_time", before, limit)
+}
+
+// PurgeHistoryBatch removes at most limit history records older than before, oldest first
+func (a *MongoDBAdapter) PurgeHistoryBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return a.purgeBatch(ctx, "equipment_history", "changed_at", before, limit)
+}
+
+// PurgeSnapshotsBatch removes at most limit snapshots older than before, oldest first
+func (a *MongoDBAdapter) PurgeSnapshotsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return a.purgeBatch(ctx, "equipment_snapshots", "snapshot_time", before, limit)
+}
+
+// purgeBatch deletes the oldest documents of collection whose timeField is
+// before the given time. DeleteMany has no limit, so their IDs are found first.
+func (a *MongoDBAdapter) purgeBatch(ctx context.Context, collection, timeField string, before time.Time, limit int) (int64, error) {
+	coll := a.db.Collection(collection)
+	filter := bson.M{timeField: bson.M{"$lt": before}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: timeField, Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find old records in %s: %w", collection, err)
+	}
+	var docs []struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, fmt.Errorf("failed to decode old records in %s: %w", collection, err)
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	result, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old records in %s: %w", collection, err)
+	}
+
+	return result.DeletedCount, nil
+}
+
 // OptimizeDatabase performs database optimization operations
 func (a *MongoDBAdapter) OptimizeDatabase(ctx context.Context) error {
 	// Run compact on collections
@@ -242,142 +300,51 @@ func (a *MongoDBAdapter) OptimizeDatabase(ctx context.Context) error {
 }
 
 // createIndexes creates necessary indexes for optimal performance
+// The index set is shared with migration 0001 so a later `migrate up` adopts it.
 func (a *MongoDBAdapter) createIndexes(ctx context.Context) error {
-	// Equipment collection indexes
-	equipmentIndexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "imei", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.D{{Key: "status", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "manufacturer_tac", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "last_check_time", Value: -1}},
-		},
-		{
-			Keys: bson.D{{Key: "check_count", Value: -1}},
-		},
-		{
-			Keys: bson.D{{Key: "last_updated", Value: -1}},
-		},
-	}
-
-	_, err := a.db.Collection("equipment").Indexes().CreateMany(ctx, equipmentIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create equipment indexes: %w", err)
-	}
-
-	// Audit log collection indexes
-	auditIndexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "imei", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "check_time", Value: -1}},
-		},
-		{
-			Keys: bson.D{{Key: "status", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "request_source", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "supi", Value: 1}},
-		},
-		{
-			Keys: bson.D{
-				{Key: "imei", Value: 1},
-				{Key: "check_time", Value: -1},
-			},
-		},
-	}
-
-	_, err = a.db.Collection("audit_log").Indexes().CreateMany(ctx, auditIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create audit log indexes: %w", err)
-	}
-
-	// Equipment history collection indexes
-	historyIndexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "imei", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "changed_at", Value: -1}},
-		},
-		{
-			Keys: bson.D{{Key: "change_type", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "changed_by", Value: 1}},
-		},
-	}
-
-	_, err = a.db.Collection("equipment_history").Indexes().CreateMany(ctx, historyIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create history indexes: %w", err)
-	}
-
-	// Equipment snapshots collection indexes
-	snapshotIndexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "imei", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "snapshot_time", Value: -1}},
-		},
-		{
-			Keys: bson.D{{Key: "equipment_id", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "snapshot_type", Value: 1}},
-		},
-	}
-
-	_, err = a.db.Collection("equipment_snapshots").Indexes().CreateMany(ctx, snapshotIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create snapshot indexes: %w", err)
+	byCollection := make(map[string][]mongo.IndexModel)
+	var order []string
+	for _, spec := range baselineIndexes {
+		if _, ok := byCollection[spec.Collection]; !ok {
+			order = append(order, spec.Collection)
+		}
+		byCollection[spec.Collection] = append(byCollection[spec.Collection], spec.model())
 	}
 
-	// IMEI info collection indexes
-	imeiInfoIndexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "startimei", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
+	for _, collection := range order {
+		if _, err := a.db.Collection(collection).Indexes().CreateMany(ctx, byCollection[collection]); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", collection, err)
+		}
 	}
 
-	_, err = a.db.Collection("imei_info").Indexes().CreateMany(ctx, imeiInfoIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create imei_info indexes: %w", err)
-	}
+	return nil
+}
 
-	// TAC info collection indexes
-	tacInfoIndexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "keytac", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
+// GetChangeFeed returns the change stream feed, or nil when EnableChangeStream is off
+func (a *MongoDBAdapter) GetChangeFeed() ports.ChangeFeed {
+	if !a.config.EnableChangeStream {
+		return nil
 	}
+	return NewChangeFeed(a.db)
+}
 
-	_, err = a.db.Collection("tac_info").Indexes().CreateMany(ctx, tacInfoIndexes)
-	if err != nil {
-		return fmt.Errorf("failed to create tac_info indexes: %w", err)
-	}
+// GetMigrationManager returns the migration manager for this database
+func (a *MongoDBAdapter) GetMigrationManager() ports.MigrationManager {
+	return NewMigrator(a.db)
+}
 
-	return nil
+// GetDataExporter returns an exporter streaming from this database
+func (a *MongoDBAdapter) GetDataExporter() ports.DataExporter {
+	return NewDataExporter(a.db)
 }
 
 // mongoTransaction implements the Transaction interface
 type mongoTransaction struct {
-	session   mongo.Session
-	db        *mongo.Database
-	imeiRepo  ports.IMEIRepository
-	auditRepo ports.AuditRepository
+	session     mongo.Session
+	db          *mongo.Database
+	imeiRepo    ports.IMEIRepository
+	auditRepo   ports.AuditRepository
+	historyRepo ports.HistoryRepository
 }
 
 // Commit commits the transaction
@@ -403,3 +370,8 @@ func (t *mongoTransaction) GetIMEIRepository() ports.IMEIRepository {
 func (t *mongoTransaction) GetAuditRepository() ports.AuditRepository {
 	return t.auditRepo
 }
+
+// GetHistoryRepository returns a transactional history repository
+func (t *mongoTransaction) GetHistoryRepository() ports.HistoryRepository {
+	return t.historyRepo
+}
@@ -0,0 +1,156 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operations only join a MongoDB transaction when their context carries its
+// session, so the repositories handed out by mongoTransaction bind every call
+// to the session before delegating to the regular repositories.
+
+// sessionIMEIRepository runs IMEIRepository operations inside a session
+type sessionIMEIRepository struct {
+	session mongo.Session
+	repo    ports.IMEIRepository
+}
+
+func (r *sessionIMEIRepository) GetByIMEI(ctx context.Context, imei string) (*models.Equipment, error) {
+	return r.repo.GetByIMEI(mongo.NewSessionContext(ctx, r.session), imei)
+}
+
+func (r *sessionIMEIRepository) GetByIMEISV(ctx context.Context, imeisv string) (*models.Equipment, error) {
+	return r.repo.GetByIMEISV(mongo.NewSessionContext(ctx, r.session), imeisv)
+}
+
+func (r *sessionIMEIRepository) Create(ctx context.Context, equipment *models.Equipment) error {
+	return r.repo.Create(mongo.NewSessionContext(ctx, r.session), equipment)
+}
+
+func (r *sessionIMEIRepository) Update(ctx context.Context, equipment *models.Equipment) error {
+	return r.repo.Update(mongo.NewSessionContext(ctx, r.session), equipment)
+}
+
+func (r *sessionIMEIRepository) Delete(ctx context.Context, imei string) error {
+	return r.repo.Delete(mongo.NewSessionContext(ctx, r.session), imei)
+}
+
+func (r *sessionIMEIRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.repo.List(mongo.NewSessionContext(ctx, r.session), offset, limit)
+}
+
+func (r *sessionIMEIRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.repo.ListWithDeleted(mongo.NewSessionContext(ctx, r.session), offset, limit)
+}
+
+func (r *sessionIMEIRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.repo.PurgeDeleted(mongo.NewSessionContext(ctx, r.session), before, limit)
+}
+
+func (r *sessionIMEIRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
+	return r.repo.ListByStatus(mongo.NewSessionContext(ctx, r.session), status, offset, limit)
+}
+
+func (r *sessionIMEIRepository) IncrementCheckCount(ctx context.Context, imei string) error {
+	return r.repo.IncrementCheckCount(mongo.NewSessionContext(ctx, r.session), imei)
+}
+
+func (r *sessionIMEIRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	return r.repo.ApplyCheckCounts(mongo.NewSessionContext(ctx, r.session), deltas)
+}
+
+func (r *sessionIMEIRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
+	return r.repo.LookupImeiInfo(mongo.NewSessionContext(ctx, r.session), startRange)
+}
+
+func (r *sessionIMEIRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	return r.repo.SaveImeiInfo(mongo.NewSessionContext(ctx, r.session), info)
+}
+
+func (r *sessionIMEIRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	return r.repo.DeleteImeiInfo(mongo.NewSessionContext(ctx, r.session), info)
+}
+
+func (r *sessionIMEIRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
+	return r.repo.ListAllImeiInfo(mongo.NewSessionContext(ctx, r.session))
+}
+
+func (r *sessionIMEIRepository) ClearImeiInfo(ctx context.Context) {
+	r.repo.ClearImeiInfo(mongo.NewSessionContext(ctx, r.session))
+}
+
+func (r *sessionIMEIRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
+	return r.repo.SaveTacInfo(mongo.NewSessionContext(ctx, r.session), info)
+}
+
+func (r *sessionIMEIRepository) LookupTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.repo.LookupTacInfo(mongo.NewSessionContext(ctx, r.session), key)
+}
+
+func (r *sessionIMEIRepository) PrevTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.repo.PrevTacInfo(mongo.NewSessionContext(ctx, r.session), key)
+}
+
+func (r *sessionIMEIRepository) NextTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
+	return r.repo.NextTacInfo(mongo.NewSessionContext(ctx, r.session), key)
+}
+
+func (r *sessionIMEIRepository) ListAllTacInfo(ctx context.Context) []*ports.TacInfo {
+	return r.repo.ListAllTacInfo(mongo.NewSessionContext(ctx, r.session))
+}
+
+func (r *sessionIMEIRepository) ClearTacInfo(ctx context.Context) {
+	r.repo.ClearTacInfo(mongo.NewSessionContext(ctx, r.session))
+}
+
+// sessionAuditRepository runs AuditRepository operations inside a session
+type sessionAuditRepository struct {
+	session mongo.Session
+	repo    ports.AuditRepository
+}
+
+func (r *sessionAuditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	return r.repo.LogCheck(mongo.NewSessionContext(ctx, r.session), audit)
+}
+
+func (r *sessionAuditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.repo.GetAuditsByIMEI(mongo.NewSessionContext(ctx, r.session), imei, offset, limit)
+}
+
+func (r *sessionAuditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.repo.GetAuditsByTimeRange(mongo.NewSessionContext(ctx, r.session), startTime, endTime, offset, limit)
+}
+
+func (r *sessionAuditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	return r.repo.GetAuditsBySUPI(mongo.NewSessionContext(ctx, r.session), supis, offset, limit)
+}
+
+// sessionHistoryRepository runs HistoryRepository operations inside a session
+type sessionHistoryRepository struct {
+	session mongo.Session
+	repo    ports.HistoryRepository
+}
+
+func (r *sessionHistoryRepository) RecordChange(ctx context.Context, history *models.EquipmentHistory) error {
+	return r.repo.RecordChange(mongo.NewSessionContext(ctx, r.session), history)
+}
+
+func (r *sessionHistoryRepository) GetHistoryByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.repo.GetHistoryByIMEI(mongo.NewSessionContext(ctx, r.session), imei, offset, limit)
+}
+
+func (r *sessionHistoryRepository) GetHistoryByTimeRange(ctx context.Context, startTime, endTime time.Time, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.repo.GetHistoryByTimeRange(mongo.NewSessionContext(ctx, r.session), startTime, endTime, offset, limit)
+}
+
+func (r *sessionHistoryRepository) GetHistoryByChangeType(ctx context.Context, changeType models.ChangeType, offset, limit int) ([]*models.EquipmentHistory, error) {
+	return r.repo.GetHistoryByChangeType(mongo.NewSessionContext(ctx, r.session), changeType, offset, limit)
+}
+
+func (r *sessionHistoryRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	return r.repo.GetListHistory(mongo.NewSessionContext(ctx, r.session), startImei, tac, until)
+}
@@ -75,6 +75,26 @@ func (r *snapshotRepository) GetSnapshotByID(ctx context.Context, id int64) (*mo
 	return &snapshot, nil
 }
 
+// GetLatestSnapshot retrieves the latest snapshot of imei taken at or before at
+func (r *snapshotRepository) GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error) {
+	filter := bson.M{
+		"imei":          imei,
+		"snapshot_time": bson.M{"$lte": at},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "snapshot_time", Value: -1}, {Key: "_id", Value: -1}})
+
+	var snapshot models.EquipmentSnapshot
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&snapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
 // DeleteOldSnapshots removes snapshots older than the specified date
 func (r *snapshotRepository) DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error) {
 	result, err := r.collection.DeleteMany(ctx, bson.M{
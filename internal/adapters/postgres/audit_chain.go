@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+)
+
+// auditChainStore implements the AuditChainStore interface using PostgreSQL.
+// It reads from the primary so that verification never sees a lagging chain.
+type auditChainStore struct {
+	db dbExecutor
+}
+
+// NewAuditChainStore creates a new PostgreSQL audit chain store
+func NewAuditChainStore(db dbExecutor) ports.AuditChainStore {
+	return &auditChainStore{db: db}
+}
+
+// GetAuditChainPartitions lists the partitions that have chained audits, oldest first
+func (s *auditChainStore) GetAuditChainPartitions(ctx context.Context) ([]string, error) {
+	var partitions []string
+	err := s.db.SelectContext(ctx, &partitions, `SELECT chain_partition FROM audit_chain_head WHERE seq > 0 ORDER BY chain_partition`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit chain partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// GetAuditChainHead returns the last link of a partition, or nil when it has none
+func (s *auditChainStore) GetAuditChainHead(ctx context.Context, partition string) (*models.AuditChainHead, error) {
+	var head models.AuditChainHead
+	err := s.db.GetContext(ctx, &head, `SELECT chain_partition, seq, hash FROM audit_chain_head WHERE chain_partition = $1 AND seq > 0`, partition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit chain head: %w", err)
+	}
+	return &head, nil
+}
+
+// WalkAuditChain calls fn with the records of a partition in chain order
+func (s *auditChainStore) WalkAuditChain(ctx context.Context, partition string, fn func(audit *models.AuditLogExtended) error) error {
+	day, err := time.Parse(models.AuditChainPartitionLayout, partition)
+	if err != nil {
+		return fmt.Errorf("invalid audit chain partition %q: %w", partition, err)
+	}
+
+	// The check_time bounds let the planner skip the other audit_log partitions
+	query := `
+		SELECT
+			al.id, al.imei, al.imeisv, al.status, al.check_time, al.origin_host, al.origin_realm,
+			al.user_name, al.supi, al.gpsi, al.supi_index, al.request_source, al.session_id, al.result_code,
+			al.chain_partition, al.chain_seq, al.prev_hash, al.hash,
+			ale.ip_address, ale.user_agent, ale.additional_data, ale.processing_time_ms
+		FROM audit_log al
+		LEFT JOIN audit_log_extended ale ON al.id = ale.audit_log_id
+		WHERE al.chain_partition = $1 AND al.check_time >= $2 AND al.check_time < $3
+		ORDER BY al.chain_seq
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, partition, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return fmt.Errorf("failed to walk audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var audit models.AuditLogExtended
+		var additionalDataJSON []byte
+
+		err := rows.Scan(
+			&audit.ID, &audit.IMEI, &audit.IMEISV, &audit.Status, &audit.CheckTime,
+			&audit.OriginHost, &audit.OriginRealm, &audit.UserName, &audit.SUPI, &audit.GPSI, &audit.SUPIIndex,
+			&audit.RequestSource, &audit.SessionID, &audit.ResultCode,
+			&audit.ChainPartition, &audit.ChainSeq, &audit.PrevHash, &audit.Hash,
+			&audit.IPAddress, &audit.UserAgent, &additionalDataJSON, &audit.ProcessingTimeMs,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit row: %w", err)
+		}
+
+		if len(additionalDataJSON) > 0 {
+			if err := json.Unmarshal(additionalDataJSON, &audit.AdditionalData); err != nil {
+				return fmt.Errorf("failed to unmarshal additional data: %w", err)
+			}
+		}
+
+		if err := fn(&audit); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// SaveAuditCheckpoint stores a signed checkpoint
+func (s *auditChainStore) SaveAuditCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	query := `
+		INSERT INTO audit_checkpoint (chain_partition, seq, hash, created_at, key_id, signature)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	err := s.db.GetContext(ctx, &checkpoint.ID, query,
+		checkpoint.Partition, checkpoint.Seq, checkpoint.Hash, checkpoint.CreatedAt, checkpoint.KeyID, checkpoint.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to save audit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetAuditCheckpoints returns the checkpoints of a partition in chain order
+func (s *auditChainStore) GetAuditCheckpoints(ctx context.Context, partition string) ([]*models.AuditCheckpoint, error) {
+	query := `
+		SELECT id, chain_partition, seq, hash, created_at, key_id, signature
+		FROM audit_checkpoint
+		WHERE chain_partition = $1
+		ORDER BY seq, id
+	`
+
+	checkpoints := make([]*models.AuditCheckpoint, 0)
+	if err := s.db.SelectContext(ctx, &checkpoints, query, partition); err != nil {
+		return nil, fmt.Errorf("failed to get audit checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
@@ -6,31 +6,63 @@ import (
 
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/lib/pq"
 )
 
 // auditRepository implements the AuditRepository interface using PostgreSQL
 type auditRepository struct {
-	db dbExecutor
+	db     dbExecutor
+	reader dbExecutor // Read-only queries; db itself unless reads go to replicas
 }
 
 // NewAuditRepository creates a new PostgreSQL audit repository
 func NewAuditRepository(db dbExecutor) ports.AuditRepository {
-	return &auditRepository{db: db}
+	return &auditRepository{db: db, reader: db}
 }
 
-// LogCheck records an equipment check operation
+// LogCheck records an equipment check operation, appending it to the hash
+// chain of its partition
 func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog) error {
+	return inTx(ctx, r.db, func(tx dbExecutor) error {
+		return appendAudit(ctx, tx, audit, audit.ChainHash)
+	})
+}
+
+// appendAudit links audit after the head of its partition, hashes it with
+// hash and inserts it. The head row stays locked until tx ends, so
+// concurrent writers append one at a time.
+func appendAudit(ctx context.Context, tx dbExecutor, audit *models.AuditLog, hash func() string) error {
+	partition := audit.PrepareChainLink()
+
+	var head models.AuditChainHead
+	err := tx.GetContext(ctx, &head, `
+		INSERT INTO audit_chain_head (chain_partition, seq, hash) VALUES ($1, 0, '')
+		ON CONFLICT (chain_partition) DO UPDATE SET chain_partition = EXCLUDED.chain_partition
+		RETURNING chain_partition, seq, hash
+	`, partition)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit chain head: %w", err)
+	}
+	if head.Seq == 0 {
+		audit.LinkTo(nil)
+	} else {
+		audit.LinkTo(&head)
+	}
+	audit.Hash = hash()
+
 	query := `
 		INSERT INTO audit_log (
 			imei, imeisv, status, check_time, origin_host, origin_realm,
-			user_name, supi, gpsi, request_source, session_id, result_code
+			user_name, supi, gpsi, request_source, session_id, result_code, supi_index,
+			chain_partition, chain_seq, prev_hash, hash
 		) VALUES (
 			:imei, :imeisv, :status, :check_time, :origin_host, :origin_realm,
-			:user_name, :supi, :gpsi, :request_source, :session_id, :result_code
+			:user_name, :supi, :gpsi, :request_source, :session_id, :result_code, :supi_index,
+			:chain_partition, :chain_seq, :prev_hash, :hash
 		) RETURNING id
 	`
 
-	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	stmt, err := tx.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
@@ -41,6 +73,12 @@ func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog)
 		return fmt.Errorf("failed to log check: %w", err)
 	}
 
+	_, err = tx.ExecContext(ctx, `UPDATE audit_chain_head SET seq = $2, hash = $3 WHERE chain_partition = $1`,
+		partition, audit.ChainSeq, audit.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to advance audit chain head: %w", err)
+	}
+
 	return nil
 }
 
@@ -48,7 +86,7 @@ func (r *auditRepository) LogCheck(ctx context.Context, audit *models.AuditLog)
 func (r *auditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
 	query := `
 		SELECT id, imei, imeisv, status, check_time, origin_host, origin_realm,
-		       user_name, supi, gpsi, request_source, session_id, result_code
+		       user_name, supi, gpsi, request_source, session_id, result_code, supi_index
 		FROM audit_log
 		WHERE imei = $1
 		ORDER BY check_time DESC
@@ -56,7 +94,7 @@ func (r *auditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offs
 	`
 
 	var audits []*models.AuditLog
-	err := r.db.SelectContext(ctx, &audits, query, imei, limit, offset)
+	err := r.reader.SelectContext(ctx, &audits, query, imei, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get audits by IMEI: %w", err)
 	}
@@ -68,7 +106,7 @@ func (r *auditRepository) GetAuditsByIMEI(ctx context.Context, imei string, offs
 func (r *auditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error) {
 	query := `
 		SELECT id, imei, imeisv, status, check_time, origin_host, origin_realm,
-		       user_name, supi, gpsi, request_source, session_id, result_code
+		       user_name, supi, gpsi, request_source, session_id, result_code, supi_index
 		FROM audit_log
 		WHERE check_time >= $1::timestamp AND check_time <= $2::timestamp
 		ORDER BY check_time DESC
@@ -76,10 +114,30 @@ func (r *auditRepository) GetAuditsByTimeRange(ctx context.Context, startTime, e
 	`
 
 	var audits []*models.AuditLog
-	err := r.db.SelectContext(ctx, &audits, query, startTime, endTime, limit, offset)
+	err := r.reader.SelectContext(ctx, &audits, query, startTime, endTime, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get audits by time range: %w", err)
 	}
 
 	return audits, nil
 }
+
+// GetAuditsBySUPI retrieves audit logs whose SUPI or SUPI index is one of supis
+func (r *auditRepository) GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error) {
+	query := `
+		SELECT id, imei, imeisv, status, check_time, origin_host, origin_realm,
+		       user_name, supi, gpsi, request_source, session_id, result_code, supi_index
+		FROM audit_log
+		WHERE supi = ANY($1) OR supi_index = ANY($1)
+		ORDER BY check_time DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var audits []*models.AuditLog
+	err := r.reader.SelectContext(ctx, &audits, query, pq.Array(supis), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audits by SUPI: %w", err)
+	}
+
+	return audits, nil
+}
@@ -19,6 +19,15 @@ func TestNewAuditRepository(t *testing.T) {
 	assert.NotNil(t, repo)
 }
 
+// expectChainHead expects the transaction LogCheck opens and the lock on the
+// chain head of its partition
+func expectChainHead(mock sqlmock.Sqlmock, seq int64, hash string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO audit_chain_head").
+		WillReturnRows(sqlmock.NewRows([]string{"chain_partition", "seq", "hash"}).
+			AddRow(models.AuditChainPartition(time.Now()), seq, hash))
+}
+
 func TestLogCheck_Success(t *testing.T) {
 	db, mock := setupTestDB(t)
 	defer db.Close()
@@ -51,14 +60,54 @@ func TestLogCheck_Success(t *testing.T) {
 		ResultCode:    &resultCode,
 	}
 
+	expectChainHead(mock, 0, "")
 	mock.ExpectPrepare("INSERT INTO audit_log").
 		ExpectQuery().
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE audit_chain_head").
+		WithArgs(sqlmock.AnyArg(), int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	err := repo.LogCheck(ctx, audit)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), audit.ID)
+	assert.Equal(t, int64(1), audit.ChainSeq)
+	assert.Empty(t, audit.PrevHash)
+	assert.Equal(t, audit.ChainHash(), audit.Hash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLogCheck_LinksToChainHead(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewAuditRepository(db)
+	ctx := context.Background()
+
+	audit := &models.AuditLog{
+		IMEI:          "490154203237518",
+		Status:        models.EquipmentStatusWhitelisted,
+		CheckTime:     time.Now(),
+		RequestSource: "DIAMETER_S13",
+	}
+
+	expectChainHead(mock, 41, "previous")
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec("UPDATE audit_chain_head").
+		WithArgs(audit.PrepareChainLink(), int64(42), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.LogCheck(ctx, audit)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), audit.ChainSeq)
+	assert.Equal(t, "previous", audit.PrevHash)
+	assert.Equal(t, audit.ChainHash(), audit.Hash)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -76,8 +125,10 @@ func TestLogCheck_PrepareError(t *testing.T) {
 		RequestSource: "DIAMETER_S13",
 	}
 
+	expectChainHead(mock, 0, "")
 	mock.ExpectPrepare("INSERT INTO audit_log").
 		WillReturnError(errors.New("prepare statement failed"))
+	mock.ExpectRollback()
 
 	err := repo.LogCheck(ctx, audit)
 
@@ -100,9 +151,11 @@ func TestLogCheck_InsertError(t *testing.T) {
 		RequestSource: "DIAMETER_S13",
 	}
 
+	expectChainHead(mock, 0, "")
 	mock.ExpectPrepare("INSERT INTO audit_log").
 		ExpectQuery().
 		WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
 
 	err := repo.LogCheck(ctx, audit)
 
@@ -321,9 +374,14 @@ func TestAuditLog_AllFields(t *testing.T) {
 		ResultCode:    &resultCode,
 	}
 
+	expectChainHead(mock, 0, "")
 	mock.ExpectPrepare("INSERT INTO audit_log").
 		ExpectQuery().
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE audit_chain_head").
+		WithArgs(sqlmock.AnyArg(), int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	err := repo.LogCheck(ctx, audit)
 
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/lib/pq"
+)
+
+// changeChannel is the NOTIFY channel written by notify_eir_change() (migration 0004)
+const changeChannel = "eir_changes"
+
+const (
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = 10 * time.Second
+	listenerPingInterval = 30 * time.Second
+)
+
+type changePayload struct {
+	Table string   `json:"table"`
+	Op    string   `json:"op"`
+	Keys  []string `json:"keys"`
+}
+
+// parseChangePayload decodes a notification sent by notify_eir_change()
+func parseChangePayload(payload string) (ports.ChangeEvent, error) {
+	var p changePayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return ports.ChangeEvent{}, fmt.Errorf("failed to decode change notification: %w", err)
+	}
+	if p.Table == "" || p.Op == "" {
+		return ports.ChangeEvent{}, fmt.Errorf("incomplete change notification: %q", payload)
+	}
+	return ports.ChangeEvent{Table: ports.ChangeTable(p.Table), Op: p.Op, Keys: p.Keys}, nil
+}
+
+// changeFeed implements ports.ChangeFeed with LISTEN/NOTIFY on a dedicated connection
+type changeFeed struct {
+	dsn string
+}
+
+// NewChangeFeed creates a change feed listening on the eir_changes channel
+func NewChangeFeed(dsn string) ports.ChangeFeed {
+	return &changeFeed{dsn: dsn}
+}
+
+func (f *changeFeed) Listen(ctx context.Context, handler func(ports.ChangeEvent)) error {
+	listener := pq.NewListener(f.dsn, listenerMinReconnect, listenerMaxReconnect, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Log.Warnw("Change feed connection problem", "event", event, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(changeChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", changeChannel, err)
+	}
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case n := <-listener.Notify:
+			if n == nil {
+				// The connection was re-established; notifications sent while it
+				// was down are lost, so everything has to be treated as changed
+				logger.Log.Warnw("Change feed reconnected, resynchronising")
+				for _, table := range ports.ChangeTables {
+					handler(ports.ChangeEvent{Table: table, Op: ports.ChangeOpResync})
+				}
+				continue
+			}
+			event, err := parseChangePayload(n.Extra)
+			if err != nil {
+				logger.Log.Warnw("Ignoring malformed change notification", "error", err)
+				continue
+			}
+			handler(event)
+
+		case <-ticker.C:
+			// Detects dead connections that would otherwise stay silent
+			go func() { _ = listener.Ping() }()
+		}
+	}
+}
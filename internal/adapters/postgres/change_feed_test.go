@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChangePayload(t *testing.T) {
+	t.Run("keyed change", func(t *testing.T) {
+		event, err := parseChangePayload(`{"table": "tac_info", "op": "INSERT", "keys": ["353             -353ÿÿÿÿÿÿÿÿÿÿÿÿÿ"]}`)
+		require.NoError(t, err)
+		assert.Equal(t, ports.ChangeTableTacInfo, event.Table)
+		assert.Equal(t, ports.ChangeOpInsert, event.Op)
+		assert.Equal(t, []string{"353             -353ÿÿÿÿÿÿÿÿÿÿÿÿÿ"}, event.Keys)
+	})
+
+	t.Run("bulk change has no keys", func(t *testing.T) {
+		event, err := parseChangePayload(`{"table": "imei_info", "op": "TRUNCATE", "keys": null}`)
+		require.NoError(t, err)
+		assert.Equal(t, ports.ChangeTableImeiInfo, event.Table)
+		assert.Nil(t, event.Keys)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := parseChangePayload(`not json`)
+		assert.Error(t, err)
+
+		_, err = parseChangePayload(`{"op": "DELETE"}`)
+		assert.Error(t, err)
+	})
+}
+
+func TestGetChangeFeedDisabled(t *testing.T) {
+	adapter := NewPostgresAdapter(&ports.PostgresConfig{Host: "localhost", Port: 5432})
+	assert.Nil(t, adapter.GetChangeFeed())
+
+	adapter = NewPostgresAdapter(&ports.PostgresConfig{Host: "localhost", Port: 5432, EnableNotify: true})
+	assert.NotNil(t, adapter.GetChangeFeed())
+}
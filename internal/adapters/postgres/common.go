@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -20,3 +21,25 @@ type dbExecutor interface {
 	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 }
+
+// inTx runs fn in a new transaction on db, committing only if fn succeeds.
+// When db already is a transaction, fn runs in it.
+func inTx(ctx context.Context, db dbExecutor, fn func(tx dbExecutor) error) error {
+	conn, ok := db.(*sqlx.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
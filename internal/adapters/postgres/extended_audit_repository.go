@@ -18,56 +18,59 @@ type extendedAuditRepository struct {
 // NewExtendedAuditRepository creates a new PostgreSQL extended audit repository
 func NewExtendedAuditRepository(db dbExecutor) ports.ExtendedAuditRepository {
 	return &extendedAuditRepository{
-		auditRepository: auditRepository{db: db},
+		auditRepository: auditRepository{db: db, reader: db},
 	}
 }
 
-// LogCheckExtended records an extended equipment check with additional metadata
+// LogCheckExtended records an extended equipment check with additional metadata.
+// The chain hash covers the metadata, so both rows are written in one transaction.
 func (r *extendedAuditRepository) LogCheckExtended(ctx context.Context, audit *models.AuditLogExtended) error {
-	// First, log the basic audit entry
-	err := r.auditRepository.LogCheck(ctx, &audit.AuditLog)
-	if err != nil {
-		return err
-	}
-
-	// Then, log the extended metadata
 	var additionalDataJSON []byte
 	if audit.AdditionalData != nil {
+		var err error
 		additionalDataJSON, err = json.Marshal(audit.AdditionalData)
 		if err != nil {
 			return fmt.Errorf("failed to marshal additional data: %w", err)
 		}
 	}
 
-	query := `
-		INSERT INTO audit_log_extended (
-			audit_log_id, ip_address, user_agent, additional_data, processing_time_ms
-		) VALUES (
-			$1, $2, $3, $4, $5
-		)
-	`
-
-	_, err = r.db.ExecContext(ctx, query,
-		audit.ID,
-		audit.IPAddress,
-		audit.UserAgent,
-		additionalDataJSON,
-		audit.ProcessingTimeMs,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to log extended audit: %w", err)
-	}
+	return inTx(ctx, r.db, func(tx dbExecutor) error {
+		// First, log the basic audit entry
+		if err := appendAudit(ctx, tx, &audit.AuditLog, audit.ChainHash); err != nil {
+			return err
+		}
 
-	// Record change history if provided
-	if audit.ChangeHistory != nil {
-		historyRepo := NewHistoryRepository(r.db)
-		err = historyRepo.RecordChange(ctx, audit.ChangeHistory)
+		// Then, log the extended metadata
+		query := `
+			INSERT INTO audit_log_extended (
+				audit_log_id, ip_address, user_agent, additional_data, processing_time_ms
+			) VALUES (
+				$1, $2, $3, $4, $5
+			)
+		`
+
+		_, err := tx.ExecContext(ctx, query,
+			audit.ID,
+			audit.IPAddress,
+			audit.UserAgent,
+			additionalDataJSON,
+			audit.ProcessingTimeMs,
+		)
 		if err != nil {
-			return fmt.Errorf("failed to record change history: %w", err)
+			return fmt.Errorf("failed to log extended audit: %w", err)
+		}
+
+		// Record change history if provided
+		if audit.ChangeHistory != nil {
+			historyRepo := NewHistoryRepository(tx)
+			err = historyRepo.RecordChange(ctx, audit.ChangeHistory)
+			if err != nil {
+				return fmt.Errorf("failed to record change history: %w", err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // GetExtendedAuditsByIMEI retrieves extended audit logs for a specific IMEI
@@ -84,7 +87,7 @@ func (r *extendedAuditRepository) GetExtendedAuditsByIMEI(ctx context.Context, i
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, imei, limit, offset)
+	rows, err := r.reader.QueryContext(ctx, query, imei, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get extended audits by IMEI: %w", err)
 	}
@@ -130,7 +133,7 @@ func (r *extendedAuditRepository) GetAuditsByRequestSource(ctx context.Context,
 	`
 
 	var audits []*models.AuditLog
-	err := r.db.SelectContext(ctx, &audits, query, requestSource, limit, offset)
+	err := r.reader.SelectContext(ctx, &audits, query, requestSource, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get audits by request source: %w", err)
 	}
@@ -166,7 +169,7 @@ func (r *extendedAuditRepository) GetAuditStatistics(ctx context.Context, startT
 		AvgProcessingTimeMs  float64 `db:"avg_processing_time_ms"`
 	}
 
-	err := r.db.GetContext(ctx, &stats, query, startTime, endTime)
+	err := r.reader.GetContext(ctx, &stats, query, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get audit statistics: %w", err)
 	}
@@ -11,12 +11,13 @@ import (
 
 // historyRepository implements the HistoryRepository interface using PostgreSQL
 type historyRepository struct {
-	db dbExecutor
+	db     dbExecutor
+	reader dbExecutor // Read-only queries; db itself unless reads go to replicas
 }
 
 // NewHistoryRepository creates a new PostgreSQL history repository
 func NewHistoryRepository(db dbExecutor) ports.HistoryRepository {
-	return &historyRepository{db: db}
+	return &historyRepository{db: db, reader: db}
 }
 
 // RecordChange records a change to equipment status or metadata
@@ -60,7 +61,7 @@ func (r *historyRepository) GetHistoryByIMEI(ctx context.Context, imei string, o
 	`
 
 	var history []*models.EquipmentHistory
-	err := r.db.SelectContext(ctx, &history, query, imei, limit, offset)
+	err := r.reader.SelectContext(ctx, &history, query, imei, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history by IMEI: %w", err)
 	}
@@ -81,7 +82,7 @@ func (r *historyRepository) GetHistoryByTimeRange(ctx context.Context, startTime
 	`
 
 	var history []*models.EquipmentHistory
-	err := r.db.SelectContext(ctx, &history, query, startTime, endTime, limit, offset)
+	err := r.reader.SelectContext(ctx, &history, query, startTime, endTime, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history by time range: %w", err)
 	}
@@ -102,10 +103,43 @@ func (r *historyRepository) GetHistoryByChangeType(ctx context.Context, changeTy
 	`
 
 	var history []*models.EquipmentHistory
-	err := r.db.SelectContext(ctx, &history, query, changeType, limit, offset)
+	err := r.reader.SelectContext(ctx, &history, query, changeType, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history by change type: %w", err)
 	}
 
 	return history, nil
 }
+
+// GetListHistory retrieves, oldest first, the changes made at or before until
+// to the IMEI_INFO entry of startImei and to the TAC ranges enclosing tac
+func (r *historyRepository) GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error) {
+	// Both branches match the partial indexes of migration 0013
+	query := `
+		SELECT id, imei, change_type, changed_at, changed_by,
+		       previous_status, new_status, previous_reason, new_reason,
+		       change_details, session_id
+		FROM equipment_history
+		WHERE change_details->>'list' = 'imei'
+		  AND change_details->>'start_imei' = $1
+		  AND changed_at <= $3
+		UNION ALL
+		SELECT id, imei, change_type, changed_at, changed_by,
+		       previous_status, new_status, previous_reason, new_reason,
+		       change_details, session_id
+		FROM equipment_history
+		WHERE change_details->>'list' = 'tac'
+		  AND tac_range(split_part(change_details->>'key_tac', '-', 1),
+		                split_part(change_details->>'key_tac', '-', 2), '[]') @> $2::text
+		  AND changed_at <= $3
+		ORDER BY changed_at ASC, id ASC
+	`
+
+	var history []*models.EquipmentHistory
+	err := r.reader.SelectContext(ctx, &history, query, startImei, tac, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list history: %w", err)
+	}
+
+	return history, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
@@ -19,12 +20,30 @@ var (
 
 // imeiRepository implements the IMEIRepository interface using PostgreSQL
 type imeiRepository struct {
-	db dbExecutor
+	db     dbExecutor
+	reader dbExecutor // Read-only queries; db itself unless reads go to replicas
+	checks bool       // Set on the view returned by ForChecks
 }
 
 // NewIMEIRepository creates a new PostgreSQL IMEI repository
 func NewIMEIRepository(db dbExecutor) ports.IMEIRepository {
-	return &imeiRepository{db: db}
+	return &imeiRepository{db: db, reader: db}
+}
+
+// ForChecks returns a view of the repository whose lookups may be served by
+// a read replica. Checks tolerate the replica lag, the read-modify-write
+// paths do not, so only checks use it.
+func (r *imeiRepository) ForChecks() ports.IMEIRepository {
+	return &imeiRepository{db: r.db, reader: r.reader, checks: true}
+}
+
+// lookup returns the executor of single-record lookups: the primary, unless
+// the repository is the checks view
+func (r *imeiRepository) lookup() dbExecutor {
+	if r.checks {
+		return r.reader
+	}
+	return r.db
 }
 
 func (r *imeiRepository) SetLogger(l logger.Logger) {
@@ -35,13 +54,14 @@ func (r *imeiRepository) SetLogger(l logger.Logger) {
 func (r *imeiRepository) GetByIMEI(ctx context.Context, imei string) (*models.Equipment, error) {
 	query := `
 		SELECT id, imei, imeisv, status, reason, last_updated, last_check_time,
-		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name
+		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name, version,
+		       deleted_at, deleted_by
 		FROM equipment
 		WHERE imei = $1
 	`
 
 	var equipment models.Equipment
-	err := r.db.GetContext(ctx, &equipment, query, imei)
+	err := r.lookup().GetContext(ctx, &equipment, query, imei)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -56,13 +76,14 @@ func (r *imeiRepository) GetByIMEI(ctx context.Context, imei string) (*models.Eq
 func (r *imeiRepository) GetByIMEISV(ctx context.Context, imeisv string) (*models.Equipment, error) {
 	query := `
 		SELECT id, imei, imeisv, status, reason, last_updated, last_check_time,
-		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name
+		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name, version,
+		       deleted_at, deleted_by
 		FROM equipment
 		WHERE imeisv = $1
 	`
 
 	var equipment models.Equipment
-	err := r.db.GetContext(ctx, &equipment, query, imeisv)
+	err := r.reader.GetContext(ctx, &equipment, query, imeisv)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -78,10 +99,12 @@ func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment
 	query := `
 		INSERT INTO equipment (
 			imei, imeisv, status, reason, last_updated, last_check_time,
-			check_count, added_by, metadata, manufacturer_tac, manufacturer_name
+			check_count, added_by, metadata, manufacturer_tac, manufacturer_name,
+			deleted_at, deleted_by
 		) VALUES (
 			:imei, :imeisv, :status, :reason, :last_updated, :last_check_time,
-			:check_count, :added_by, :metadata, :manufacturer_tac, :manufacturer_name
+			:check_count, :added_by, :metadata, :manufacturer_tac, :manufacturer_name,
+			:deleted_at, :deleted_by
 		) RETURNING id
 	`
 
@@ -96,10 +119,13 @@ func (r *imeiRepository) Create(ctx context.Context, equipment *models.Equipment
 		return fmt.Errorf("failed to create equipment: %w", err)
 	}
 
+	// The column defaults to 1 (migration 0007)
+	equipment.Version = 1
 	return nil
 }
 
-// Update updates an existing equipment record
+// Update updates an existing equipment record, conditionally on its version
+// when it has one
 func (r *imeiRepository) Update(ctx context.Context, equipment *models.Equipment) error {
 	query := `
 		UPDATE equipment
@@ -109,25 +135,84 @@ func (r *imeiRepository) Update(ctx context.Context, equipment *models.Equipment
 		    last_updated = :last_updated,
 		    metadata = :metadata,
 		    manufacturer_tac = :manufacturer_tac,
-		    manufacturer_name = :manufacturer_name
+		    manufacturer_name = :manufacturer_name,
+		    deleted_at = :deleted_at,
+		    deleted_by = :deleted_by,
+		    version = version + 1
 		WHERE imei = :imei
 	`
+	if equipment.Version != 0 {
+		query += ` AND version = :version`
+	}
+	query += ` RETURNING version`
 
-	result, err := r.db.NamedExecContext(ctx, query, equipment)
+	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var version int64
+	err = stmt.GetContext(ctx, &version, equipment)
+	if errors.Is(err, sql.ErrNoRows) {
+		if equipment.Version == 0 {
+			return ErrNotFound
+		}
+		actual, found, err := r.currentVersion(ctx, "equipment", "imei", equipment.IMEI)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+		return &ports.VersionConflictError{Entity: "equipment", Key: equipment.IMEI, Expected: equipment.Version, Actual: actual}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update equipment: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	equipment.Version = version
+	return nil
+}
+
+// currentVersion reads the version of the row of table whose keyColumn is
+// key, from the primary as it explains why a write just matched no row
+func (r *imeiRepository) currentVersion(ctx context.Context, table, keyColumn, key string) (int64, bool, error) {
+	var version int64
+	query := fmt.Sprintf(`SELECT version FROM %s WHERE %s = $1`, table, keyColumn)
+	err := r.db.GetContext(ctx, &version, query, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return 0, false, fmt.Errorf("failed to read %s version: %w", table, err)
 	}
+	return version, true, nil
+}
 
-	if rowsAffected == 0 {
-		return ErrNotFound
+// saveVersioned runs upsert when expected is 0 and update otherwise; both
+// return the new version, and update matches no row on a stale version
+func (r *imeiRepository) saveVersioned(ctx context.Context, table, keyColumn, key string, expected int64, upsert, update string, args ...interface{}) (int64, error) {
+	var version int64
+	if expected == 0 {
+		if err := r.db.GetContext(ctx, &version, upsert, args...); err != nil {
+			return 0, fmt.Errorf("failed to save %s: %w", table, err)
+		}
+		return version, nil
 	}
 
-	return nil
+	err := r.db.GetContext(ctx, &version, update, append(args, expected)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		actual, _, err := r.currentVersion(ctx, table, keyColumn, key)
+		if err != nil {
+			return 0, err
+		}
+		return 0, &ports.VersionConflictError{Entity: table, Key: key, Expected: expected, Actual: actual}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to save %s: %w", table, err)
+	}
+	return version, nil
 }
 
 // Delete removes an equipment record
@@ -151,18 +236,29 @@ func (r *imeiRepository) Delete(ctx context.Context, imei string) error {
 	return nil
 }
 
-// List retrieves equipment with pagination
+// List retrieves equipment with pagination, skipping tombstones
 func (r *imeiRepository) List(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(ctx, "WHERE deleted_at IS NULL", offset, limit)
+}
+
+// ListWithDeleted retrieves equipment with pagination, tombstones included
+func (r *imeiRepository) ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
+	return r.list(ctx, "", offset, limit)
+}
+
+func (r *imeiRepository) list(ctx context.Context, where string, offset, limit int) ([]*models.Equipment, error) {
 	query := `
 		SELECT id, imei, imeisv, status, reason, last_updated, last_check_time,
-		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name
+		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name, version,
+		       deleted_at, deleted_by
 		FROM equipment
+		` + where + `
 		ORDER BY last_updated DESC
 		LIMIT $1 OFFSET $2
 	`
 
 	var equipments []*models.Equipment
-	err := r.db.SelectContext(ctx, &equipments, query, limit, offset)
+	err := r.reader.SelectContext(ctx, &equipments, query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list equipment: %w", err)
 	}
@@ -170,19 +266,37 @@ func (r *imeiRepository) List(ctx context.Context, offset, limit int) ([]*models
 	return equipments, nil
 }
 
-// ListByStatus retrieves equipment by status with pagination
+// PurgeDeleted removes at most limit tombstones deleted before the given time
+func (r *imeiRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM equipment WHERE id IN (
+			SELECT id FROM equipment
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+		)`
+
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted equipment: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListByStatus retrieves equipment by status with pagination, skipping tombstones
 func (r *imeiRepository) ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error) {
 	query := `
 		SELECT id, imei, imeisv, status, reason, last_updated, last_check_time,
-		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name
+		       check_count, added_by, metadata, manufacturer_tac, manufacturer_name, version,
+		       deleted_at, deleted_by
 		FROM equipment
-		WHERE status = $1
+		WHERE status = $1 AND deleted_at IS NULL
 		ORDER BY last_updated DESC
 		LIMIT $2 OFFSET $3
 	`
 
 	var equipments []*models.Equipment
-	err := r.db.SelectContext(ctx, &equipments, query, status, limit, offset)
+	err := r.reader.SelectContext(ctx, &equipments, query, status, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list equipment by status: %w", err)
 	}
@@ -202,13 +316,49 @@ func (r *imeiRepository) IncrementCheckCount(ctx context.Context, imei string) e
 	return nil
 }
 
+// ApplyCheckCounts adds flushed check counts in one statement. The flag set
+// for the transaction tells the equipment triggers that the update is not a
+// provisioning change (see migration 0011).
+func (r *imeiRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	imeis := make([]string, len(deltas))
+	counts := make([]int64, len(deltas))
+	times := make([]time.Time, len(deltas))
+	for i, delta := range deltas {
+		imeis[i], counts[i], times[i] = delta.IMEI, delta.Count, delta.LastCheckTime
+	}
+
+	query := `
+		UPDATE equipment e
+		SET check_count = e.check_count + d.count,
+		    last_check_time = GREATEST(e.last_check_time, d.last_check_time)
+		FROM unnest($1::varchar[], $2::bigint[], $3::timestamptz[]) AS d(imei, count, last_check_time)
+		WHERE e.imei = d.imei AND e.deleted_at IS NULL
+	`
+
+	err := inTx(ctx, r.db, func(tx dbExecutor) error {
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('eir.check_counts', 'on', true)`); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, query, pq.Array(imeis), pq.Array(counts), pq.Array(times))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply check counts: %w", err)
+	}
+	return nil
+}
+
 // IMEI logic operations (not implemented for PostgreSQL - use in-memory for testing)
 func (r *imeiRepository) LookupImeiInfo(ctx context.Context, startRange string) (*ports.ImeiInfo, bool) {
-	query := `SELECT startimei, endimei, color FROM imei_info WHERE startimei = $1`
+	query := `SELECT startimei, endimei, color, version FROM imei_info WHERE startimei = $1`
 
 	var info ports.ImeiInfo
 	// Lưu ý: ports.ImeiInfo.EndIMEI nên là []string để tương thích với TEXT[]
-	err := r.db.GetContext(ctx, &info, query, startRange)
+	err := r.lookup().GetContext(ctx, &info, query, startRange)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, false
@@ -220,24 +370,57 @@ func (r *imeiRepository) LookupImeiInfo(ctx context.Context, startRange string)
 
 func (r *imeiRepository) SaveImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
 	logger.Log.Debugw("Jump into SaveImeiInfo into database")
-	query := `
+	upsert := `
 		INSERT INTO imei_info (startimei, endimei, color)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (startimei) 
-		DO UPDATE SET endimei = EXCLUDED.endimei, color = EXCLUDED.color
+		DO UPDATE SET endimei = EXCLUDED.endimei, color = EXCLUDED.color, version = imei_info.version + 1
+		RETURNING version
+	`
+	update := `
+		UPDATE imei_info SET endimei = $2, color = $3, version = version + 1
+		WHERE startimei = $1 AND version = $4
+		RETURNING version
 	`
-	_, err := r.db.ExecContext(ctx, query, info.StartIMEI, pq.Array(info.EndIMEI), info.Color)
+	version, err := r.saveVersioned(ctx, "imei_info", "startimei", info.StartIMEI, info.Version, upsert, update,
+		info.StartIMEI, pq.Array(info.EndIMEI), info.Color)
 	if err != nil {
-		return fmt.Errorf("failed to save imei info: %w", err)
+		return err
+	}
+	info.Version = version
+	return nil
+}
+
+func (r *imeiRepository) DeleteImeiInfo(ctx context.Context, info *ports.ImeiInfo) error {
+	query := `DELETE FROM imei_info WHERE startimei = $1 AND ($2::bigint = 0 OR version = $2)`
+
+	result, err := r.db.ExecContext(ctx, query, info.StartIMEI, info.Version)
+	if err != nil {
+		return fmt.Errorf("failed to delete imei_info: %w", err)
+	}
+	if info.Version == 0 {
+		return nil
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		actual, _, err := r.currentVersion(ctx, "imei_info", "startimei", info.StartIMEI)
+		if err != nil {
+			return err
+		}
+		return &ports.VersionConflictError{Entity: "imei_info", Key: info.StartIMEI, Expected: info.Version, Actual: actual}
 	}
 	return nil
 }
 
 func (r *imeiRepository) ListAllImeiInfo(ctx context.Context) []*ports.ImeiInfo {
-	query := `SELECT startimei, endimei, color FROM imei_info`
+	query := `SELECT startimei, endimei, color, version FROM imei_info`
 
 	var result []*ports.ImeiInfo
-	err := r.db.SelectContext(ctx, &result, query)
+	err := r.reader.SelectContext(ctx, &result, query)
 	if err != nil {
 		logger.Log.Errorf("CRITICAL: ListAllImeiInfo database error: %v", err)
 		return []*ports.ImeiInfo{}
@@ -255,7 +438,7 @@ func (r *imeiRepository) ClearImeiInfo(ctx context.Context) {
 func (r *imeiRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) error {
 	logger.Log.Debugw("Jump into SaveTacInfo in database")
 
-	query := `
+	upsert := `
 		INSERT INTO tac_info (keytac, startrangetac, endrangetac, color, prevlink)
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (keytac) 
@@ -263,24 +446,33 @@ func (r *imeiRepository) SaveTacInfo(ctx context.Context, info *ports.TacInfo) e
 			startrangetac = EXCLUDED.startrangetac, 
 			endrangetac = EXCLUDED.endrangetac, 
 			color = EXCLUDED.color, 
-			prevlink = EXCLUDED.prevlink
+			prevlink = EXCLUDED.prevlink,
+			version = tac_info.version + 1
+		RETURNING version
+	`
+	update := `
+		UPDATE tac_info
+		SET startrangetac = $2, endrangetac = $3, color = $4, prevlink = $5, version = version + 1
+		WHERE keytac = $1 AND version = $6
+		RETURNING version
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	version, err := r.saveVersioned(ctx, "tac_info", "keytac", info.KeyTac, info.Version, upsert, update,
 		info.KeyTac, info.StartRangeTac, info.EndRangeTac, info.Color, info.PrevLink)
 	if err != nil {
 		logger.Log.Debugw("error executing SaveTacInfo: ", err)
-		return fmt.Errorf("failed to save tac info: %w", err)
+		return err
 	}
+	info.Version = version
 	return nil
 }
 
 func (r *imeiRepository) LookupTacInfo(ctx context.Context, key string) (*ports.TacInfo, bool) {
 	logger.Log.Debugw("Jump into LookupTacInfo in database")
 
-	query := `SELECT keytac, startrangetac, endrangetac, color, prevlink FROM tac_info WHERE keytac = $1`
+	query := `SELECT keytac, startrangetac, endrangetac, color, prevlink, version FROM tac_info WHERE keytac = $1`
 
 	var info ports.TacInfo
-	err := r.db.GetContext(ctx, &info, query, key)
+	err := r.lookup().GetContext(ctx, &info, query, key)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, false
@@ -294,7 +486,7 @@ func (r *imeiRepository) PrevTacInfo(ctx context.Context, key string) (*ports.Ta
 	logger.Log.Debugw("Jump into PrevTacInfo in database")
 
 	query := `
-		SELECT keytac, startrangetac, endrangetac, color, prevlink 
+		SELECT keytac, startrangetac, endrangetac, color, prevlink, version 
 		FROM tac_info 
 		WHERE keytac < $1 
 		ORDER BY keytac DESC 
@@ -302,7 +494,7 @@ func (r *imeiRepository) PrevTacInfo(ctx context.Context, key string) (*ports.Ta
 	`
 
 	var info ports.TacInfo
-	err := r.db.GetContext(ctx, &info, query, key)
+	err := r.lookup().GetContext(ctx, &info, query, key)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, false
@@ -316,7 +508,7 @@ func (r *imeiRepository) NextTacInfo(ctx context.Context, key string) (*ports.Ta
 	logger.Log.Debugw("Jump into NextTacInfo in database")
 
 	query := `
-		SELECT keytac, startrangetac, endrangetac, color, prevlink 
+		SELECT keytac, startrangetac, endrangetac, color, prevlink, version 
 		FROM tac_info 
 		WHERE keytac > $1 
 		ORDER BY keytac ASC 
@@ -324,7 +516,7 @@ func (r *imeiRepository) NextTacInfo(ctx context.Context, key string) (*ports.Ta
 	`
 
 	var info ports.TacInfo
-	err := r.db.GetContext(ctx, &info, query, key)
+	err := r.lookup().GetContext(ctx, &info, query, key)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, false
@@ -336,10 +528,10 @@ func (r *imeiRepository) NextTacInfo(ctx context.Context, key string) (*ports.Ta
 
 func (r *imeiRepository) ListAllTacInfo(ctx context.Context) []*ports.TacInfo {
 	logger.Log.Debugw("Jump into ListAllTacInfo in database")
-	query := `SELECT keytac, startrangetac, endrangetac, color, prevlink FROM tac_info ORDER BY keytac ASC`
+	query := `SELECT keytac, startrangetac, endrangetac, color, prevlink, version FROM tac_info ORDER BY keytac ASC`
 
 	var result []*ports.TacInfo
-	err := r.db.SelectContext(ctx, &result, query)
+	err := r.reader.SelectContext(ctx, &result, query)
 	if err != nil {
 		return []*ports.TacInfo{}
 	}
@@ -351,3 +543,41 @@ func (r *imeiRepository) ClearTacInfo(ctx context.Context) {
 	query := `DELETE FROM tac_info`
 	_, _ = r.db.ExecContext(ctx, query)
 }
+
+// EnclosingTacInfo resolves the innermost range containing value using the
+// GiST-indexed tac_bounds column (migration 0003)
+func (r *imeiRepository) EnclosingTacInfo(ctx context.Context, value string) (*ports.TacInfo, bool) {
+	query := `
+		SELECT keytac, startrangetac, endrangetac, color, prevlink, version
+		FROM tac_info
+		WHERE tac_bounds @> $1::text
+		ORDER BY startrangetac COLLATE "C" DESC, endrangetac COLLATE "C" ASC
+		LIMIT 1
+	`
+
+	var info ports.TacInfo
+	err := r.lookup().GetContext(ctx, &info, query, value)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Log.Errorw("failed to resolve enclosing tac range", "value", value, "error", err)
+		}
+		return nil, false
+	}
+	return &info, true
+}
+
+// OverlappingTacInfo returns every range that shares at least one value with [start, end]
+func (r *imeiRepository) OverlappingTacInfo(ctx context.Context, start, end string) ([]*ports.TacInfo, error) {
+	query := `
+		SELECT keytac, startrangetac, endrangetac, color, prevlink, version
+		FROM tac_info
+		WHERE tac_bounds && tac_range($1, $2, '[]')
+		ORDER BY keytac ASC
+	`
+
+	var result []*ports.TacInfo
+	if err := r.lookup().SelectContext(ctx, &result, query, start, end); err != nil {
+		return nil, fmt.Errorf("failed to list overlapping tac ranges: %w", err)
+	}
+	return result, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -247,12 +248,45 @@ func TestUpdate_Success(t *testing.T) {
 		LastUpdated: time.Now(),
 	}
 
-	mock.ExpectExec("UPDATE equipment").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE equipment").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
 
 	err := repo.Update(ctx, equipment)
 
 	assert.NoError(t, err)
+	assert.Equal(t, int64(4), equipment.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_VersionConflict(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db)
+	ctx := context.Background()
+
+	equipment := &models.Equipment{
+		IMEI:        "490154203237518",
+		Status:      models.EquipmentStatusBlacklisted,
+		LastUpdated: time.Now(),
+		Version:     2,
+	}
+
+	mock.ExpectPrepare("UPDATE equipment (.+) AND version = (.+) RETURNING version").
+		ExpectQuery().
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT version FROM equipment WHERE imei = (.+)").
+		WithArgs(equipment.IMEI).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	err := repo.Update(ctx, equipment)
+
+	var conflict *ports.VersionConflictError
+	require.True(t, errors.As(err, &conflict), "got %v", err)
+	assert.True(t, errors.Is(err, ports.ErrVersionConflict))
+	assert.Equal(t, int64(2), conflict.Expected)
+	assert.Equal(t, int64(3), conflict.Actual)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -269,8 +303,9 @@ func TestUpdate_NotFound(t *testing.T) {
 		LastUpdated: time.Now(),
 	}
 
-	mock.ExpectExec("UPDATE equipment").
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE equipment").
+		ExpectQuery().
+		WillReturnError(sql.ErrNoRows)
 
 	err := repo.Update(ctx, equipment)
 
@@ -292,7 +327,8 @@ func TestUpdate_DatabaseError(t *testing.T) {
 		LastUpdated: time.Now(),
 	}
 
-	mock.ExpectExec("UPDATE equipment").
+	mock.ExpectPrepare("UPDATE equipment").
+		ExpectQuery().
 		WillReturnError(errors.New("database error"))
 
 	err := repo.Update(ctx, equipment)
@@ -353,7 +389,7 @@ func TestList_Success(t *testing.T) {
 		AddRow(1, "490154203237518", nil, models.EquipmentStatusWhitelisted, nil, time.Now(), nil, 0, "admin", nil, nil, nil).
 		AddRow(2, "490154203237519", nil, models.EquipmentStatusBlacklisted, nil, time.Now(), nil, 0, "admin", nil, nil, nil)
 
-	mock.ExpectQuery("SELECT (.+) FROM equipment ORDER BY last_updated DESC LIMIT (.+) OFFSET (.+)").
+	mock.ExpectQuery("SELECT (.+) FROM equipment WHERE deleted_at IS NULL ORDER BY last_updated DESC LIMIT (.+) OFFSET (.+)").
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
@@ -376,7 +412,7 @@ func TestList_Empty(t *testing.T) {
 		"check_count", "added_by", "metadata", "manufacturer_tac", "manufacturer_name",
 	})
 
-	mock.ExpectQuery("SELECT (.+) FROM equipment ORDER BY last_updated DESC LIMIT (.+) OFFSET (.+)").
+	mock.ExpectQuery("SELECT (.+) FROM equipment WHERE deleted_at IS NULL ORDER BY last_updated DESC LIMIT (.+) OFFSET (.+)").
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
@@ -474,3 +510,137 @@ func TestIncrementCheckCount_Error(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to increment check count")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestApplyCheckCounts_Success(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config\\('eir.check_counts'").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE equipment e").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := repo.ApplyCheckCounts(ctx, []ports.CheckCountDelta{
+		{IMEI: "356938035643809", Count: 1, LastCheckTime: now},
+		{IMEI: "490154203237518", Count: 3, LastCheckTime: now},
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyCheckCounts_Error(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config\\('eir.check_counts'").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE equipment e").
+		WillReturnError(errors.New("deadlock detected"))
+	mock.ExpectRollback()
+
+	err := repo.ApplyCheckCounts(ctx, []ports.CheckCountDelta{
+		{IMEI: "490154203237518", Count: 1, LastCheckTime: time.Now()},
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply check counts")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnclosingTacInfo_Success(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db).(ports.TacRangeRepository)
+	ctx := context.Background()
+	value := "3531230000000000"
+
+	rows := sqlmock.NewRows([]string{"keytac", "startrangetac", "endrangetac", "color", "prevlink"}).
+		AddRow("353123          -353123ÿÿÿÿÿÿÿÿÿÿ", "353123          ", "353123ÿÿÿÿÿÿÿÿÿÿ", "GREYLISTED", "35              -35ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ")
+
+	mock.ExpectQuery(`SELECT (.+) FROM tac_info WHERE tac_bounds @> (.+) ORDER BY startrangetac COLLATE "C" DESC`).
+		WithArgs(value).
+		WillReturnRows(rows)
+
+	info, ok := repo.EnclosingTacInfo(ctx, value)
+
+	require.True(t, ok)
+	assert.Equal(t, models.EquipmentStatusGreylisted, info.Color)
+	assert.Equal(t, "353123          ", info.StartRangeTac)
+	require.NotNil(t, info.PrevLink)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnclosingTacInfo_NotFound(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db).(ports.TacRangeRepository)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT (.+) FROM tac_info WHERE tac_bounds @>").
+		WithArgs("9999999999999999").
+		WillReturnError(sql.ErrNoRows)
+
+	info, ok := repo.EnclosingTacInfo(ctx, "9999999999999999")
+
+	assert.False(t, ok)
+	assert.Nil(t, info)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOverlappingTacInfo_Success(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db).(ports.TacRangeRepository)
+	ctx := context.Background()
+	start, end := "353             ", "353ÿÿÿÿÿÿÿÿÿÿÿÿÿ"
+
+	rows := sqlmock.NewRows([]string{"keytac", "startrangetac", "endrangetac", "color", "prevlink"}).
+		AddRow("35              -35ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ", "35              ", "35ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ", "WHITELISTED", nil).
+		AddRow("353123          -353123ÿÿÿÿÿÿÿÿÿÿ", "353123          ", "353123ÿÿÿÿÿÿÿÿÿÿ", "BLACKLISTED", "35              -35ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ")
+
+	mock.ExpectQuery(`SELECT (.+) FROM tac_info WHERE tac_bounds && tac_range\(\$1, \$2, '\[\]'\) ORDER BY keytac ASC`).
+		WithArgs(start, end).
+		WillReturnRows(rows)
+
+	result, err := repo.OverlappingTacInfo(ctx, start, end)
+
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Nil(t, result[0].PrevLink)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, result[1].Color)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOverlappingTacInfo_Error(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewIMEIRepository(db).(ports.TacRangeRepository)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT (.+) FROM tac_info WHERE tac_bounds &&").
+		WithArgs("1", "2").
+		WillReturnError(errors.New(`type "tac_range" does not exist`))
+
+	result, err := repo.OverlappingTacInfo(ctx, "1", "2")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "failed to list overlapping tac ranges")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,16 @@
+-- Revert migration 0001: drop the base EIR schema.
+-- Extensions are left installed because other schemas may depend on them.
+
+DROP VIEW IF EXISTS equipment_statistics;
+DROP VIEW IF EXISTS hot_equipment;
+
+DROP TRIGGER IF EXISTS update_equipment_last_updated ON equipment;
+DROP FUNCTION IF EXISTS increment_equipment_check_count(VARCHAR);
+DROP FUNCTION IF EXISTS update_last_updated_column();
+
+DROP TABLE IF EXISTS TAC_INFO;
+DROP TABLE IF EXISTS IMEI_INFO;
+
+-- Dropping the partitioned parent drops every audit_log partition with it
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS equipment;
@@ -1,5 +1,7 @@
 -- EIR Database Schema for PostgreSQL
 -- Production-grade schema with indexing, constraints, and partitioning support
+-- Migration 0001: every statement is idempotent so databases bootstrapped from
+-- the old schema.sql can be adopted by the versioned migrator.
 
 -- Enable required extensions
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
@@ -24,12 +26,12 @@ CREATE TABLE IF NOT EXISTS equipment (
 );
 
 -- Indexes for equipment table
-CREATE INDEX idx_equipment_imei ON equipment USING btree (imei);
-CREATE INDEX idx_equipment_status ON equipment USING btree (status);
-CREATE INDEX idx_equipment_manufacturer_tac ON equipment USING btree (manufacturer_tac);
-CREATE INDEX idx_equipment_last_check_time ON equipment USING btree (last_check_time DESC NULLS LAST);
-CREATE INDEX idx_equipment_check_count ON equipment USING btree (check_count DESC);
-CREATE INDEX idx_equipment_metadata_gin ON equipment USING gin (metadata jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_equipment_imei ON equipment USING btree (imei);
+CREATE INDEX IF NOT EXISTS idx_equipment_status ON equipment USING btree (status);
+CREATE INDEX IF NOT EXISTS idx_equipment_manufacturer_tac ON equipment USING btree (manufacturer_tac);
+CREATE INDEX IF NOT EXISTS idx_equipment_last_check_time ON equipment USING btree (last_check_time DESC NULLS LAST);
+CREATE INDEX IF NOT EXISTS idx_equipment_check_count ON equipment USING btree (check_count DESC);
+CREATE INDEX IF NOT EXISTS idx_equipment_metadata_gin ON equipment USING gin (metadata jsonb_path_ops);
 
 -- Audit log table: Track all equipment check operations
 -- This table is partitioned by check_time for efficient querying and maintenance
@@ -82,19 +84,19 @@ CREATE TABLE IF NOT EXISTS audit_log_2026_q1 PARTITION OF audit_log
     FOR VALUES FROM ('2026-01-01') TO ('2026-04-01');
 
 -- Indexes for audit_log partitions (applied to parent table)
-CREATE INDEX idx_audit_log_imei ON audit_log USING btree (imei);
-CREATE INDEX idx_audit_log_check_time ON audit_log USING btree (check_time DESC);
-CREATE INDEX idx_audit_log_status ON audit_log USING btree (status);
-CREATE INDEX idx_audit_log_request_source ON audit_log USING btree (request_source);
-CREATE INDEX idx_audit_log_supi ON audit_log USING btree (supi) WHERE supi IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_imei ON audit_log USING btree (imei);
+CREATE INDEX IF NOT EXISTS idx_audit_log_check_time ON audit_log USING btree (check_time DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_status ON audit_log USING btree (status);
+CREATE INDEX IF NOT EXISTS idx_audit_log_request_source ON audit_log USING btree (request_source);
+CREATE INDEX IF NOT EXISTS idx_audit_log_supi ON audit_log USING btree (supi) WHERE supi IS NOT NULL;
 
-CREATE TABLE IMEI_INFO (
+CREATE TABLE IF NOT EXISTS IMEI_INFO (
     StartIMEI VARCHAR(16) PRIMARY KEY,
     EndIMEI TEXT[] DEFAULT '{}',
     Color CHAR(1) NOT NULL CHECK (Color IN ('w', 'b', 'g'))
 );
 
-CREATE TABLE TAC_INFO (
+CREATE TABLE IF NOT EXISTS TAC_INFO (
     KeyTAC VARCHAR(64) PRIMARY KEY,
     StartRangeTAC VARCHAR(20) NOT NULL,
     EndRangeTAC VARCHAR(20) NOT NULL,
@@ -102,7 +104,7 @@ CREATE TABLE TAC_INFO (
     PrevLink VARCHAR(64) REFERENCES TAC_INFO(KeyTAC) ON DELETE SET NULL
 );
 
-CREATE INDEX idx_tac_range_lookup ON TAC_INFO (StartRangeTAC, EndRangeTAC);
+CREATE INDEX IF NOT EXISTS idx_tac_range_lookup ON TAC_INFO (StartRangeTAC, EndRangeTAC);
 
 -- Function to automatically update last_updated timestamp
 CREATE OR REPLACE FUNCTION update_last_updated_column()
@@ -114,6 +116,7 @@ END;
 $$ LANGUAGE plpgsql;
 
 -- Trigger to update last_updated on equipment table
+DROP TRIGGER IF EXISTS update_equipment_last_updated ON equipment;
 CREATE TRIGGER update_equipment_last_updated
     BEFORE UPDATE ON equipment
     FOR EACH ROW
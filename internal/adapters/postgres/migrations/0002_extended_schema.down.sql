@@ -0,0 +1,18 @@
+-- Revert migration 0002: drop history, snapshot and extended audit objects.
+
+DROP FUNCTION IF EXISTS cleanup_old_data(INTEGER);
+DROP FUNCTION IF EXISTS get_equipment_timeline(VARCHAR);
+
+DROP VIEW IF EXISTS v_equipment_with_history;
+DROP VIEW IF EXISTS v_audit_stats_by_day;
+DROP VIEW IF EXISTS v_recent_equipment_changes;
+
+DROP TRIGGER IF EXISTS trigger_equipment_change_history ON equipment;
+DROP FUNCTION IF EXISTS record_equipment_change();
+DROP TRIGGER IF EXISTS trigger_equipment_snapshot_before_update ON equipment;
+DROP FUNCTION IF EXISTS create_equipment_snapshot_before_update();
+
+DROP TABLE IF EXISTS audit_log_default;
+DROP TABLE IF EXISTS audit_log_extended;
+DROP TABLE IF EXISTS equipment_snapshots;
+DROP TABLE IF EXISTS equipment_history;
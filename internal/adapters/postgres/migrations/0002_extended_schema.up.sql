@@ -1,5 +1,6 @@
 -- Extended schema for audit and history tracking
 -- This extends the base schema with additional tables for comprehensive tracking
+-- Migration 0002
 
 -- Equipment History Table
 -- Tracks all changes to equipment records
@@ -14,8 +15,9 @@ CREATE TABLE IF NOT EXISTS equipment_history (
     previous_reason TEXT,
     new_reason TEXT,
     change_details JSONB,
-    session_id VARCHAR(255),
-    CONSTRAINT fk_equipment_history_imei FOREIGN KEY (imei) REFERENCES equipment(imei) ON DELETE CASCADE
+    session_id VARCHAR(255)
+    -- No foreign key to equipment: history must outlive deleted equipment and
+    -- also covers IMEI_INFO / TAC_INFO list entries that have no equipment row.
 );
 
 -- Create indexes for equipment_history
@@ -56,8 +58,9 @@ CREATE TABLE IF NOT EXISTS audit_log_extended (
     user_agent TEXT,
     additional_data JSONB,
     processing_time_ms BIGINT,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT fk_audit_log_extended_audit FOREIGN KEY (audit_log_id) REFERENCES audit_log(id) ON DELETE CASCADE
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    -- audit_log is partitioned on (id, check_time), so audit_log.id alone
+    -- cannot be referenced by a foreign key.
 );
 
 -- Create indexes for audit_log_extended
@@ -133,31 +136,10 @@ CREATE TRIGGER trigger_equipment_change_history
     FOR EACH ROW
     EXECUTE FUNCTION record_equipment_change();
 
--- Partitioning for equipment_history (by month)
--- Create partitions for equipment_history table
-CREATE TABLE IF NOT EXISTS equipment_history_2024_q1 PARTITION OF equipment_history
-    FOR VALUES FROM ('2024-01-01') TO ('2024-04-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2024_q2 PARTITION OF equipment_history
-    FOR VALUES FROM ('2024-04-01') TO ('2024-07-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2024_q3 PARTITION OF equipment_history
-    FOR VALUES FROM ('2024-07-01') TO ('2024-10-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2024_q4 PARTITION OF equipment_history
-    FOR VALUES FROM ('2024-10-01') TO ('2025-01-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2025_q1 PARTITION OF equipment_history
-    FOR VALUES FROM ('2025-01-01') TO ('2025-04-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2025_q2 PARTITION OF equipment_history
-    FOR VALUES FROM ('2025-04-01') TO ('2025-07-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2025_q3 PARTITION OF equipment_history
-    FOR VALUES FROM ('2025-07-01') TO ('2025-10-01');
-
-CREATE TABLE IF NOT EXISTS equipment_history_2025_q4 PARTITION OF equipment_history
-    FOR VALUES FROM ('2025-10-01') TO ('2026-01-01');
+-- Catch-all partition so audit inserts never fail once the pre-created
+-- quarterly partitions run out. Use `migrate -create-partition YEAR` to keep
+-- hot data in dedicated partitions.
+CREATE TABLE IF NOT EXISTS audit_log_default PARTITION OF audit_log DEFAULT;
 
 -- Views for reporting and analytics
 
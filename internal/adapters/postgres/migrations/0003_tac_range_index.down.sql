@@ -0,0 +1,5 @@
+-- Revert migration 0003: drop the TAC range column, index and type.
+
+DROP INDEX IF EXISTS idx_tac_info_bounds_gist;
+ALTER TABLE TAC_INFO DROP COLUMN IF EXISTS tac_bounds;
+DROP TYPE IF EXISTS tac_range;
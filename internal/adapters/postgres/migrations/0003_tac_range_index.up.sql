@@ -0,0 +1,23 @@
+-- TAC range index
+-- Stores the bounds of every TAC_INFO row as a range so that the most specific
+-- enclosing range and all overlapping ranges can be resolved in one query.
+-- Migration 0003
+
+-- Range over text compared byte-wise ("C" collation), which matches the Go
+-- string ordering used by pkg/logic for padded TAC bounds (' ' < '0'..'9' < 'ÿ').
+DO $$
+BEGIN
+    CREATE TYPE tac_range AS RANGE (subtype = text, collation = "C");
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+
+ALTER TABLE TAC_INFO
+    ADD COLUMN IF NOT EXISTS tac_bounds tac_range
+    GENERATED ALWAYS AS (tac_range(StartRangeTAC::text, EndRangeTAC::text, '[]')) STORED;
+
+-- Lookup index only: TAC ranges are allowed to nest (a range inside a parent
+-- range overrides its color), so an exclusion constraint on && would reject
+-- valid data. Partial overlaps are rejected by InsertTac instead.
+CREATE INDEX IF NOT EXISTS idx_tac_info_bounds_gist ON TAC_INFO USING gist (tac_bounds);
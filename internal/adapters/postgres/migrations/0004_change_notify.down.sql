@@ -0,0 +1,16 @@
+-- Revert migration 0004: drop the change notification triggers.
+
+DO $$
+DECLARE
+    tbl TEXT;
+    op TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY['equipment', 'imei_info', 'tac_info'] LOOP
+        FOREACH op IN ARRAY ARRAY['insert', 'update', 'delete', 'truncate'] LOOP
+            EXECUTE format('DROP TRIGGER IF EXISTS trigger_%s_notify_%s ON %s', tbl, op, tbl);
+        END LOOP;
+    END LOOP;
+END
+$$;
+
+DROP FUNCTION IF EXISTS notify_eir_change();
@@ -0,0 +1,79 @@
+-- Change notifications
+-- Broadcasts committed changes to the list tables on the eir_changes channel so
+-- that every EIR replica can invalidate its local caches.
+-- Migration 0004
+
+-- Statement-level trigger: one notification per statement, listing the changed
+-- keys. Large statements (e.g. clearing a table) send no keys, which tells the
+-- listeners to drop everything derived from that table; NOTIFY payloads are
+-- limited to 8000 bytes.
+CREATE OR REPLACE FUNCTION notify_eir_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    key_column TEXT := TG_ARGV[0];
+    changed_count BIGINT := -1;
+    changed_keys JSONB;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(n) ->> key_column)
+        INTO changed_count, changed_keys FROM new_rows n;
+    ELSIF TG_OP = 'UPDATE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT k)
+        INTO changed_count, changed_keys
+        FROM (
+            SELECT to_jsonb(o) ->> key_column AS k FROM old_rows o
+            UNION
+            SELECT to_jsonb(n) ->> key_column FROM new_rows n
+        ) changed;
+    ELSIF TG_OP = 'DELETE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(o) ->> key_column)
+        INTO changed_count, changed_keys FROM old_rows o;
+    END IF;
+
+    IF changed_count = 0 THEN
+        RETURN NULL;
+    END IF;
+    IF changed_count < 0 OR changed_count > 100 THEN
+        changed_keys := NULL;
+    END IF;
+
+    PERFORM pg_notify('eir_changes', jsonb_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'keys', changed_keys
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    t RECORD;
+BEGIN
+    FOR t IN SELECT * FROM (VALUES
+        ('equipment', 'imei'),
+        ('imei_info', 'startimei'),
+        ('tac_info', 'keytac')
+    ) AS v(tbl, key_column)
+    LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS trigger_%1$s_notify_insert ON %1$s', t.tbl);
+        EXECUTE format('CREATE TRIGGER trigger_%1$s_notify_insert AFTER INSERT ON %1$s
+            REFERENCING NEW TABLE AS new_rows
+            FOR EACH STATEMENT EXECUTE FUNCTION notify_eir_change(%2$L)', t.tbl, t.key_column);
+
+        EXECUTE format('DROP TRIGGER IF EXISTS trigger_%1$s_notify_update ON %1$s', t.tbl);
+        EXECUTE format('CREATE TRIGGER trigger_%1$s_notify_update AFTER UPDATE ON %1$s
+            REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+            FOR EACH STATEMENT EXECUTE FUNCTION notify_eir_change(%2$L)', t.tbl, t.key_column);
+
+        EXECUTE format('DROP TRIGGER IF EXISTS trigger_%1$s_notify_delete ON %1$s', t.tbl);
+        EXECUTE format('CREATE TRIGGER trigger_%1$s_notify_delete AFTER DELETE ON %1$s
+            REFERENCING OLD TABLE AS old_rows
+            FOR EACH STATEMENT EXECUTE FUNCTION notify_eir_change(%2$L)', t.tbl, t.key_column);
+
+        EXECUTE format('DROP TRIGGER IF EXISTS trigger_%1$s_notify_truncate ON %1$s', t.tbl);
+        EXECUTE format('CREATE TRIGGER trigger_%1$s_notify_truncate AFTER TRUNCATE ON %1$s
+            FOR EACH STATEMENT EXECUTE FUNCTION notify_eir_change(%2$L)', t.tbl, t.key_column);
+    END LOOP;
+END
+$$;
@@ -0,0 +1,7 @@
+-- Revert migration 0005: record equipment changes from the trigger again.
+
+DROP TRIGGER IF EXISTS trigger_equipment_change_history ON equipment;
+CREATE TRIGGER trigger_equipment_change_history
+    AFTER INSERT OR UPDATE OR DELETE ON equipment
+    FOR EACH ROW
+    EXECUTE FUNCTION record_equipment_change();
@@ -0,0 +1,7 @@
+-- Service-recorded history
+-- The EIR service now records every provisioning change in equipment_history
+-- together with the actor who requested it, so the equipment trigger would
+-- only duplicate its entries under the 'SYSTEM' actor.
+-- Migration 0005
+
+DROP TRIGGER IF EXISTS trigger_equipment_change_history ON equipment;
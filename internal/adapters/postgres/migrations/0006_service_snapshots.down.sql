@@ -0,0 +1,7 @@
+-- Revert migration 0006: take PRE_UPDATE snapshots from the trigger again.
+
+DROP TRIGGER IF EXISTS trigger_equipment_snapshot_before_update ON equipment;
+CREATE TRIGGER trigger_equipment_snapshot_before_update
+    BEFORE UPDATE ON equipment
+    FOR EACH ROW
+    EXECUTE FUNCTION create_equipment_snapshot_before_update();
@@ -0,0 +1,7 @@
+-- Service-taken snapshots
+-- The EIR service now takes a PRE_UPDATE snapshot before every status change,
+-- attributed to the actor who requested it, so the equipment trigger would
+-- only duplicate its snapshots under the 'SYSTEM' actor.
+-- Migration 0006
+
+DROP TRIGGER IF EXISTS trigger_equipment_snapshot_before_update ON equipment;
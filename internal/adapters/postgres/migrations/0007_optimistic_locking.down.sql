@@ -0,0 +1,5 @@
+-- Revert migration 0007: drop the record versions.
+
+ALTER TABLE TAC_INFO DROP COLUMN IF EXISTS version;
+ALTER TABLE IMEI_INFO DROP COLUMN IF EXISTS version;
+ALTER TABLE equipment DROP COLUMN IF EXISTS version;
@@ -0,0 +1,10 @@
+-- Optimistic locking
+-- Every equipment record, IMEI_INFO entry and TAC range carries a version
+-- that each write advances. A write made on behalf of a reader who saw an
+-- older version matches no row, so concurrent edits are rejected instead of
+-- overwriting one another. Existing rows start at version 1.
+-- Migration 0007
+
+ALTER TABLE equipment ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE IMEI_INFO ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE TAC_INFO ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+-- Revert migration 0008: drop the tombstone columns.
+-- Tombstones would otherwise become live records again, so they go first.
+
+DELETE FROM equipment WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_equipment_deleted_at;
+ALTER TABLE equipment DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE equipment DROP COLUMN IF EXISTS deleted_at;
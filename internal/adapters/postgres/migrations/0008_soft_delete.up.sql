@@ -0,0 +1,11 @@
+-- Soft delete
+-- Deleting equipment leaves a tombstone: the row stays, with the time and
+-- actor of the delete, and is skipped by checks and listings until it is
+-- undeleted or purged once past the tombstone retention.
+-- Migration 0008
+
+ALTER TABLE equipment ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE equipment ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255);
+
+-- Only tombstones are indexed, for the purge
+CREATE INDEX IF NOT EXISTS idx_equipment_deleted_at ON equipment(deleted_at) WHERE deleted_at IS NOT NULL;
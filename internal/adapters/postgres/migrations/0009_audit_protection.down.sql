@@ -0,0 +1,5 @@
+-- Revert migration 0009: drop the SUPI pseudonym index column.
+-- Protected identifiers already written stay as they are.
+
+DROP INDEX IF EXISTS idx_audit_log_supi_index;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS supi_index;
@@ -0,0 +1,9 @@
+-- Audit protection
+-- Subscriber identifiers in audit_log may be stored as keyed-hash pseudonyms
+-- or AES-GCM ciphertexts. An encrypted SUPI keeps its pseudonym in
+-- supi_index so audits can still be looked up by SUPI.
+-- Migration 0009
+
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS supi_index VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_supi_index ON audit_log USING btree (supi_index) WHERE supi_index IS NOT NULL;
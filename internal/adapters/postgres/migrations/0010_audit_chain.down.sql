@@ -0,0 +1,10 @@
+-- Revert migration 0010: drop the audit hash chain and its checkpoints.
+-- The audit records themselves are kept.
+
+DROP TABLE IF EXISTS audit_checkpoint;
+DROP TABLE IF EXISTS audit_chain_head;
+DROP INDEX IF EXISTS idx_audit_log_chain;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS chain_seq;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS chain_partition;
@@ -0,0 +1,32 @@
+-- Audit hash chain
+-- Every audit_log record carries a hash over its content and the hash of the
+-- previous record of its partition (the UTC day of check_time), so altering
+-- or removing a record breaks every later link. audit_chain_head holds the
+-- last link of each partition; writers lock its row to append in order.
+-- audit_checkpoint keeps signed statements of the heads.
+-- Migration 0010
+
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS chain_partition VARCHAR(10);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS chain_seq BIGINT;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_chain ON audit_log USING btree (chain_partition, chain_seq) WHERE chain_partition IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    chain_partition VARCHAR(10) PRIMARY KEY,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoint (
+    id BIGSERIAL PRIMARY KEY,
+    chain_partition VARCHAR(10) NOT NULL,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    signature TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoint_partition ON audit_checkpoint(chain_partition, seq);
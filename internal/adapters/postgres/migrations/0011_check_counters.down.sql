@@ -0,0 +1,48 @@
+-- Revert migration 0011: counter flushes update last_updated and notify again.
+
+CREATE OR REPLACE FUNCTION update_last_updated_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.last_updated = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_eir_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    key_column TEXT := TG_ARGV[0];
+    changed_count BIGINT := -1;
+    changed_keys JSONB;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(n) ->> key_column)
+        INTO changed_count, changed_keys FROM new_rows n;
+    ELSIF TG_OP = 'UPDATE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT k)
+        INTO changed_count, changed_keys
+        FROM (
+            SELECT to_jsonb(o) ->> key_column AS k FROM old_rows o
+            UNION
+            SELECT to_jsonb(n) ->> key_column FROM new_rows n
+        ) changed;
+    ELSIF TG_OP = 'DELETE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(o) ->> key_column)
+        INTO changed_count, changed_keys FROM old_rows o;
+    END IF;
+
+    IF changed_count = 0 THEN
+        RETURN NULL;
+    END IF;
+    IF changed_count < 0 OR changed_count > 100 THEN
+        changed_keys := NULL;
+    END IF;
+
+    PERFORM pg_notify('eir_changes', jsonb_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'keys', changed_keys
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
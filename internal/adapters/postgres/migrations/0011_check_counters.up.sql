@@ -0,0 +1,61 @@
+-- Check counters
+-- Check counts and last check times are flushed in batches by the service.
+-- Those updates set the transaction-local eir.check_counts flag: they are not
+-- provisioning changes, so they keep last_updated and send no change
+-- notification that would make every replica drop its caches.
+-- Migration 0011
+
+CREATE OR REPLACE FUNCTION update_last_updated_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('eir.check_counts', true) = 'on' THEN
+        NEW.last_updated = OLD.last_updated;
+    ELSE
+        NEW.last_updated = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_eir_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    key_column TEXT := TG_ARGV[0];
+    changed_count BIGINT := -1;
+    changed_keys JSONB;
+BEGIN
+    IF current_setting('eir.check_counts', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(n) ->> key_column)
+        INTO changed_count, changed_keys FROM new_rows n;
+    ELSIF TG_OP = 'UPDATE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT k)
+        INTO changed_count, changed_keys
+        FROM (
+            SELECT to_jsonb(o) ->> key_column AS k FROM old_rows o
+            UNION
+            SELECT to_jsonb(n) ->> key_column FROM new_rows n
+        ) changed;
+    ELSIF TG_OP = 'DELETE' THEN
+        SELECT COUNT(*), jsonb_agg(DISTINCT to_jsonb(o) ->> key_column)
+        INTO changed_count, changed_keys FROM old_rows o;
+    END IF;
+
+    IF changed_count = 0 THEN
+        RETURN NULL;
+    END IF;
+    IF changed_count < 0 OR changed_count > 100 THEN
+        changed_keys := NULL;
+    END IF;
+
+    PERFORM pg_notify('eir_changes', jsonb_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'keys', changed_keys
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,21 @@
+-- Revert migration 0012: the lists store their own colors again.
+
+ALTER TABLE imei_info DROP CONSTRAINT IF EXISTS imei_info_color_check;
+UPDATE imei_info SET color = CASE color
+    WHEN 'WHITELISTED' THEN 'w'
+    WHEN 'BLACKLISTED' THEN 'b'
+    WHEN 'GREYLISTED' THEN 'g'
+    ELSE color
+END;
+ALTER TABLE imei_info ALTER COLUMN color TYPE CHAR(1);
+ALTER TABLE imei_info ADD CONSTRAINT imei_info_color_check CHECK (color IN ('w', 'b', 'g'));
+
+ALTER TABLE tac_info DROP CONSTRAINT IF EXISTS tac_info_color_check;
+UPDATE tac_info SET color = CASE color
+    WHEN 'WHITELISTED' THEN 'white'
+    WHEN 'BLACKLISTED' THEN 'black'
+    WHEN 'GREYLISTED' THEN 'grey'
+    ELSE color
+END;
+ALTER TABLE tac_info ALTER COLUMN color TYPE VARCHAR(10);
+ALTER TABLE tac_info ADD CONSTRAINT tac_info_color_check CHECK (color IN ('black', 'white', 'grey'));
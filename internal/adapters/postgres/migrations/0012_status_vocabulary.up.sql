@@ -0,0 +1,27 @@
+-- Status vocabulary
+-- The IMEI and TAC lists store the equipment status names (WHITELISTED,
+-- BLACKLISTED, GREYLISTED) instead of their own colors, "w"/"b"/"g" for IMEIs
+-- and "white"/"black"/"grey" for TACs.
+-- Migration 0012
+
+ALTER TABLE imei_info DROP CONSTRAINT IF EXISTS imei_info_color_check;
+ALTER TABLE imei_info ALTER COLUMN color TYPE VARCHAR(20);
+UPDATE imei_info SET color = CASE color
+    WHEN 'w' THEN 'WHITELISTED'
+    WHEN 'b' THEN 'BLACKLISTED'
+    WHEN 'g' THEN 'GREYLISTED'
+    ELSE color
+END;
+ALTER TABLE imei_info ADD CONSTRAINT imei_info_color_check
+    CHECK (color IN ('WHITELISTED', 'BLACKLISTED', 'GREYLISTED'));
+
+ALTER TABLE tac_info DROP CONSTRAINT IF EXISTS tac_info_color_check;
+ALTER TABLE tac_info ALTER COLUMN color TYPE VARCHAR(20);
+UPDATE tac_info SET color = CASE color
+    WHEN 'white' THEN 'WHITELISTED'
+    WHEN 'black' THEN 'BLACKLISTED'
+    WHEN 'grey' THEN 'GREYLISTED'
+    ELSE color
+END;
+ALTER TABLE tac_info ADD CONSTRAINT tac_info_color_check
+    CHECK (color IN ('WHITELISTED', 'BLACKLISTED', 'GREYLISTED'));
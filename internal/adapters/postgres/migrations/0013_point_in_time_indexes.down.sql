@@ -0,0 +1,6 @@
+-- Revert migration 0013: drop the point-in-time indexes.
+
+DROP INDEX IF EXISTS idx_equipment_snapshots_imei_time;
+DROP INDEX IF EXISTS idx_equipment_history_tac_list_gist;
+DROP INDEX IF EXISTS idx_equipment_history_imei_list;
+DROP INDEX IF EXISTS idx_equipment_history_imei_changed_at;
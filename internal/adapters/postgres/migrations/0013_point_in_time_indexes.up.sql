@@ -0,0 +1,22 @@
+-- Point-in-time indexes
+-- Reconstructing the status of one IMEI reads its own history, the history of
+-- its IMEI_INFO entry and of the TAC ranges enclosing it, and its latest
+-- snapshot, each through an index instead of a scan of the whole table.
+-- Migration 0013
+
+CREATE INDEX IF NOT EXISTS idx_equipment_history_imei_changed_at
+    ON equipment_history (imei, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_history_imei_list
+    ON equipment_history ((change_details->>'start_imei'), changed_at)
+    WHERE change_details->>'list' = 'imei';
+
+-- key_tac holds the padded bounds of the range as "start-end"
+CREATE INDEX IF NOT EXISTS idx_equipment_history_tac_list_gist
+    ON equipment_history USING gist (
+        tac_range(split_part(change_details->>'key_tac', '-', 1),
+                  split_part(change_details->>'key_tac', '-', 2), '[]'))
+    WHERE change_details->>'list' = 'tac';
+
+CREATE INDEX IF NOT EXISTS idx_equipment_snapshots_imei_time
+    ON equipment_snapshots (imei, snapshot_time DESC);
@@ -2,81 +2,429 @@ package postgres
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
 	"embed"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/jmoiron/sqlx"
 )
 
-//go:embed schema.sql
-var schemaFS embed.FS
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID is the advisory lock key serialising concurrent migrators
+const migrationLockID = 7347100501
+
+// migrationFilePattern matches files such as 0001_initial_schema.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// legacyInitialMigration is the name recorded by the pre-versioned migrator
+const legacyInitialMigration = "initial_schema"
+
+// sqlMigration is a single embedded, versioned SQL migration
+type sqlMigration struct {
+	version     int64
+	name        string
+	description string
+	upSQL       string
+	downSQL     string
+	checksum    string
+	db          dbExecutor
+}
+
+// Up applies the migration
+func (s *sqlMigration) Up(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, s.upSQL); err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", s.version, s.name, err)
+	}
+	return nil
+}
+
+// Down reverts the migration
+func (s *sqlMigration) Down(ctx context.Context) error {
+	if s.downSQL == "" {
+		return fmt.Errorf("migration %04d_%s has no down script", s.version, s.name)
+	}
+	if _, err := s.db.ExecContext(ctx, s.downSQL); err != nil {
+		return fmt.Errorf("failed to revert migration %04d_%s: %w", s.version, s.name, err)
+	}
+	return nil
+}
+
+// Version returns the migration version
+func (s *sqlMigration) Version() int64 {
+	return s.version
+}
+
+// Description returns the migration description
+func (s *sqlMigration) Description() string {
+	return s.description
+}
+
+// withExecutor returns a copy of the migration bound to db
+func (s *sqlMigration) withExecutor(db dbExecutor) *sqlMigration {
+	c := *s
+	c.db = db
+	return &c
+}
+
+// loadMigrations parses the embedded migration files, ordered by version
+func loadMigrations(fsys fs.FS, dir string) ([]*sqlMigration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*sqlMigration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &sqlMigration{
+				version:     version,
+				name:        match[2],
+				description: strings.ReplaceAll(match[2], "_", " "),
+			}
+			byVersion[version] = mig
+		} else if mig.name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s and %s", version, mig.name, match[2])
+		}
+
+		if match[3] == "up" {
+			mig.upSQL = string(content)
+			sum := sha256.Sum256(content)
+			mig.checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.downSQL = string(content)
+		}
+	}
+
+	migrations := make([]*sqlMigration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.upSQL == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", mig.version, mig.name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// appliedMigration is a versioned row of schema_migrations
+type appliedMigration struct {
+	Version   int64          `db:"version"`
+	Checksum  sql.NullString `db:"checksum"`
+	AppliedAt time.Time      `db:"applied_at"`
+}
 
 // Migrator handles database schema migrations
+// It implements ports.MigrationManager over the embedded migrations directory
 type Migrator struct {
-	db *sqlx.DB
+	db         *sqlx.DB
+	migrations []*sqlMigration
+	loadErr    error
 }
 
+var _ ports.MigrationManager = (*Migrator)(nil)
+
 // NewMigrator creates a new database migrator
 func NewMigrator(db *sqlx.DB) *Migrator {
-	return &Migrator{db: db}
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	return &Migrator{db: db, migrations: migrations, loadErr: err}
+}
+
+// Migrations returns the embedded migrations in version order
+func (m *Migrator) Migrations() []ports.DatabaseMigration {
+	result := make([]ports.DatabaseMigration, len(m.migrations))
+	for i, mig := range m.migrations {
+		result[i] = mig.withExecutor(m.db)
+	}
+	return result
 }
 
 // Migrate runs all necessary database migrations
 func (m *Migrator) Migrate(ctx context.Context) error {
-	fmt.Println("Starting database migration...")
+	return m.Run(ctx)
+}
 
-	// Create migration tracking table if it doesn't exist
-	if err := m.createMigrationTable(ctx); err != nil {
-		return fmt.Errorf("failed to create migration table: %w", err)
+// Run applies every pending migration in version order
+func (m *Migrator) Run(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return m.prepare(ctx)
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].version)
+}
+
+// Rollback reverts the most recently applied migration
+func (m *Migrator) Rollback(ctx context.Context) error {
+	return m.Down(ctx, 1)
+}
+
+// Down reverts the last n applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive, got %d", n)
+	}
+	if err := m.prepare(ctx); err != nil {
+		return err
 	}
 
-	// Check if schema has already been applied
-	applied, err := m.isMigrationApplied(ctx, "initial_schema")
+	applied, err := m.appliedVersions(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check migration status: %w", err)
+		return err
 	}
 
-	if applied {
-		fmt.Println("Initial schema already applied, skipping...")
+	versions := make([]int64, 0, len(applied))
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; ok {
+			versions = append(versions, mig.version)
+		}
+	}
+	if len(versions) == 0 {
+		fmt.Println("No migrations to revert")
 		return nil
 	}
+	if n > len(versions) {
+		n = len(versions)
+	}
+
+	target := int64(0)
+	if idx := len(versions) - n - 1; idx >= 0 {
+		target = versions[idx]
+	}
+	return m.Goto(ctx, target)
+}
+
+// Goto migrates up or down until version is the latest applied migration
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if err := m.prepare(ctx); err != nil {
+		return err
+	}
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
 
-	// Read and execute the schema.sql file
-	schemaSQL, err := schemaFS.ReadFile("schema.sql")
+	applied, err := m.appliedVersions(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to read schema.sql: %w", err)
+		return err
+	}
+	if err := m.verifyChecksums(applied); err != nil {
+		return err
 	}
 
-	fmt.Println("Applying initial schema...")
+	fmt.Println("Starting database migration...")
 
-	// Execute the schema in a transaction
+	// Revert newest first, then apply oldest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.version]; ok && mig.version > version {
+			if err := m.revert(ctx, mig); err != nil {
+				return err
+			}
+		}
+	}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; !ok && mig.version <= version {
+			if err := m.apply(ctx, mig); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Println("Database migration completed successfully!")
+	return nil
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]ports.MigrationStatus, error) {
+	if err := m.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ports.MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := ports.MigrationStatus{
+			Version:     mig.version,
+			Description: mig.description,
+		}
+		if row, ok := applied[mig.version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt.Format(time.RFC3339)
+			status.Checksum = row.Checksum.String
+			status.Dirty = row.Checksum.Valid && row.Checksum.String != mig.checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// prepare makes sure the embedded migrations parsed and the tracking table exists
+func (m *Migrator) prepare(ctx context.Context) error {
+	if m.loadErr != nil {
+		return m.loadErr
+	}
+	if err := m.createMigrationTable(ctx); err != nil {
+		return fmt.Errorf("failed to create migration table: %w", err)
+	}
+	return nil
+}
+
+// apply runs a single up migration and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, mig *sqlMigration) error {
+	return m.inMigrationTx(ctx, func(tx *sqlx.Tx) error {
+		// Another migrator may have applied it while we waited for the lock
+		var count int
+		if err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, mig.version); err != nil {
+			return fmt.Errorf("failed to check migration status: %w", err)
+		}
+		if count > 0 {
+			return nil
+		}
+
+		fmt.Printf("Applying migration %04d_%s...\n", mig.version, mig.name)
+		if err := mig.withExecutor(tx).Up(ctx); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO schema_migrations (migration_name, version, description, applied_at, checksum)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		name := fmt.Sprintf("%04d_%s", mig.version, mig.name)
+		if _, err := tx.ExecContext(ctx, query, name, mig.version, mig.description, time.Now(), mig.checksum); err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+
+		fmt.Printf("✓ Applied migration %04d_%s\n", mig.version, mig.name)
+		return nil
+	})
+}
+
+// revert runs a single down migration and removes its record in one transaction
+func (m *Migrator) revert(ctx context.Context, mig *sqlMigration) error {
+	return m.inMigrationTx(ctx, func(tx *sqlx.Tx) error {
+		var count int
+		if err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, mig.version); err != nil {
+			return fmt.Errorf("failed to check migration status: %w", err)
+		}
+		if count == 0 {
+			return nil
+		}
+
+		fmt.Printf("Reverting migration %04d_%s...\n", mig.version, mig.name)
+		if err := mig.withExecutor(tx).Down(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.version); err != nil {
+			return fmt.Errorf("failed to remove migration record: %w", err)
+		}
+
+		fmt.Printf("✓ Reverted migration %04d_%s\n", mig.version, mig.name)
+		return nil
+	})
+}
+
+// inMigrationTx runs fn in a transaction holding the migration advisory lock
+func (m *Migrator) inMigrationTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
 	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Execute the schema SQL
-	if _, err := tx.ExecContext(ctx, string(schemaSQL)); err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
 	}
 
-	// Record the migration
-	if err := m.recordMigration(ctx, tx, "initial_schema", "Applied initial database schema from schema.sql"); err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
+	if err := fn(tx); err != nil {
+		return err
 	}
 
-	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return nil
+}
+
+// verifyChecksums refuses to run when an applied migration was edited afterwards
+func (m *Migrator) verifyChecksums(applied map[int64]appliedMigration) error {
+	for _, mig := range m.migrations {
+		row, ok := applied[mig.version]
+		if !ok || !row.Checksum.Valid {
+			continue
+		}
+		if row.Checksum.String != mig.checksum {
+			return fmt.Errorf("checksum mismatch for applied migration %04d_%s: database has %s, embedded file has %s",
+				mig.version, mig.name, row.Checksum.String, mig.checksum)
+		}
+	}
+	return nil
+}
 
-	fmt.Println("Database migration completed successfully!")
+// appliedVersions loads the versioned rows of schema_migrations
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	query := `
+		SELECT version, checksum, applied_at
+		FROM schema_migrations
+		WHERE version IS NOT NULL
+	`
+	if err := m.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// find returns the embedded migration with the given version
+func (m *Migrator) find(version int64) *sqlMigration {
+	for _, mig := range m.migrations {
+		if mig.version == version {
+			return mig
+		}
+	}
 	return nil
 }
 
 // createMigrationTable creates the migrations tracking table
+// Tables created by the pre-versioned migrator are upgraded in place: the
+// legacy "initial_schema" row is adopted as version 1 with its checksum filled in.
 func (m *Migrator) createMigrationTable(ctx context.Context) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -87,9 +435,23 @@ func (m *Migrator) createMigrationTable(ctx context.Context) error {
 			checksum VARCHAR(64)
 		);
 		CREATE INDEX IF NOT EXISTS idx_migrations_name ON schema_migrations(migration_name);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS version BIGINT;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_migrations_version ON schema_migrations(version) WHERE version IS NOT NULL;
 	`
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
 
-	_, err := m.db.ExecContext(ctx, query)
+	if len(m.migrations) == 0 || m.migrations[0].version != 1 {
+		return nil
+	}
+
+	adopt := `
+		UPDATE schema_migrations
+		SET version = 1, checksum = COALESCE(checksum, $2)
+		WHERE migration_name = $1 AND version IS NULL
+	`
+	_, err := m.db.ExecContext(ctx, adopt, legacyInitialMigration, m.migrations[0].checksum)
 	return err
 }
 
@@ -104,18 +466,8 @@ func (m *Migrator) isMigrationApplied(ctx context.Context, migrationName string)
 	return count > 0, nil
 }
 
-// recordMigration records a migration in the tracking table
-func (m *Migrator) recordMigration(ctx context.Context, tx *sqlx.Tx, migrationName, description string) error {
-	query := `
-		INSERT INTO schema_migrations (migration_name, description, applied_at)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (migration_name) DO NOTHING
-	`
-	_, err := tx.ExecContext(ctx, query, migrationName, description, time.Now())
-	return err
-}
-
-// GetMigrationStatus returns the status of all applied migrations
+// GetMigrationStatus returns every row of the tracking table, including
+// partition bookkeeping that is not part of the versioned sequence
 func (m *Migrator) GetMigrationStatus(ctx context.Context) ([]MigrationRecord, error) {
 	var migrations []MigrationRecord
 	query := `
@@ -198,6 +550,11 @@ func (m *Migrator) VerifySchema(ctx context.Context) error {
 	tables := []string{
 		"equipment",
 		"audit_log",
+		"imei_info",
+		"tac_info",
+		"equipment_history",
+		"equipment_snapshots",
+		"audit_log_extended",
 		"schema_migrations",
 	}
 
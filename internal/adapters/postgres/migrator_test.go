@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectPrepare(mock sqlmock.Sqlmock, m *Migrator) {
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE schema_migrations")).
+		WithArgs(legacyInitialMigration, m.migrations[0].checksum).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func appliedRows(versions map[int64]string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version", "checksum", "applied_at"})
+	for version, checksum := range versions {
+		rows.AddRow(version, checksum, time.Now())
+	}
+	return rows
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(migrations), 2)
+
+	for i, mig := range migrations {
+		assert.Equal(t, int64(i+1), mig.version, "versions must be contiguous")
+		assert.NotEmpty(t, mig.upSQL)
+		assert.NotEmpty(t, mig.downSQL, "migration %d needs a down script", mig.version)
+		assert.Len(t, mig.checksum, 64)
+	}
+	assert.Equal(t, "initial_schema", migrations[0].name)
+	assert.Equal(t, "extended_schema", migrations[1].name)
+}
+
+func TestMigrator_RunAppliesPending(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	require.NoError(t, m.loadErr)
+	ctx := context.Background()
+
+	expectPrepare(mock, m)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at")).
+		WillReturnRows(appliedRows(map[int64]string{1: m.migrations[0].checksum}))
+
+	for _, mig := range m.migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock")).
+			WithArgs(migrationLockID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM schema_migrations WHERE version")).
+			WithArgs(mig.version).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).
+			WithArgs(sqlmock.AnyArg(), mig.version, mig.description, sqlmock.AnyArg(), mig.checksum).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+
+	require.NoError(t, m.Run(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_RunRejectsChecksumMismatch(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	ctx := context.Background()
+
+	expectPrepare(mock, m)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at")).
+		WillReturnRows(appliedRows(map[int64]string{1: "deadbeef"}))
+
+	err := m.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_DownRevertsLatest(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	ctx := context.Background()
+
+	applied := map[int64]string{}
+	for _, mig := range m.migrations {
+		applied[mig.version] = mig.checksum
+	}
+	latest := m.migrations[len(m.migrations)-1]
+
+	// Down resolves the target, then Goto re-reads state before acting
+	expectPrepare(mock, m)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at")).
+		WillReturnRows(appliedRows(applied))
+	expectPrepare(mock, m)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at")).
+		WillReturnRows(appliedRows(applied))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM schema_migrations WHERE version")).
+		WithArgs(latest.version).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(latest.downSQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version")).
+		WithArgs(latest.version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, m.Down(ctx, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	ctx := context.Background()
+
+	expectPrepare(mock, m)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at")).
+		WillReturnRows(appliedRows(map[int64]string{1: "deadbeef"}))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(m.migrations))
+
+	assert.True(t, statuses[0].Applied)
+	assert.True(t, statuses[0].Dirty)
+	assert.False(t, statuses[1].Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_GotoUnknownVersion(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	expectPrepare(mock, m)
+
+	err := m.Goto(context.Background(), 999)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown migration version")
+}
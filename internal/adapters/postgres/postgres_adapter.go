@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
@@ -19,6 +20,7 @@ type PostgresAdapter struct {
 	extendedAuditRepo       ports.ExtendedAuditRepository
 	historyRepo             ports.HistoryRepository
 	snapshotRepo            ports.SnapshotRepository
+	replicas                *replicaRouter // nil without read replicas
 }
 
 // NewPostgresAdapter creates a new PostgreSQL database adapter
@@ -30,17 +32,7 @@ func NewPostgresAdapter(config *ports.PostgresConfig) *PostgresAdapter {
 
 // Connect establishes a connection to the PostgreSQL database
 func (a *PostgresAdapter) Connect(ctx context.Context) error {
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		a.config.Host,
-		a.config.Port,
-		a.config.User,
-		a.config.Password,
-		a.config.Database,
-		a.config.SSLMode,
-	)
-
-	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+	db, err := sqlx.ConnectContext(ctx, "postgres", a.dsn())
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres: %w", err)
 	}
@@ -53,18 +45,81 @@ func (a *PostgresAdapter) Connect(ctx context.Context) error {
 
 	a.db = db
 
+	// Read-only queries go to the replicas when there are any
+	var reader dbExecutor = db
+	if len(a.config.Replicas) > 0 {
+		router, err := a.connectReplicas(ctx)
+		if err != nil {
+			db.Close()
+			return err
+		}
+		a.replicas = router
+		reader = router
+	}
+
 	// Initialize repositories
-	a.imeiRepo = NewIMEIRepository(db)
-	a.auditRepo = NewAuditRepository(db)
-	a.extendedAuditRepo = NewExtendedAuditRepository(db)
-	a.historyRepo = NewHistoryRepository(db)
-	a.snapshotRepo = NewSnapshotRepository(db)
+	a.imeiRepo = &imeiRepository{db: db, reader: reader}
+	a.auditRepo = &auditRepository{db: db, reader: reader}
+	a.extendedAuditRepo = &extendedAuditRepository{auditRepository: auditRepository{db: db, reader: reader}}
+	a.historyRepo = &historyRepository{db: db, reader: reader}
+	a.snapshotRepo = &snapshotRepository{db: db, reader: reader}
 
 	return nil
 }
 
+// connectReplicas opens the read replicas and starts checking their health and lag
+func (a *PostgresAdapter) connectReplicas(ctx context.Context) (*replicaRouter, error) {
+	replicas := make([]*replica, 0, len(a.config.Replicas))
+	for _, rc := range a.config.Replicas {
+		rep, err := openReplica(a.dsnFor(rc.Host, rc.Port), fmt.Sprintf("%s:%d", rc.Host, rc.Port))
+		if err != nil {
+			for _, opened := range replicas {
+				opened.db.Close()
+			}
+			return nil, err
+		}
+		rep.db.SetMaxOpenConns(a.config.MaxOpenConns)
+		rep.db.SetMaxIdleConns(a.config.MaxIdleConns)
+		rep.db.SetConnMaxLifetime(time.Duration(a.config.ConnMaxLifetime) * time.Second)
+		rep.db.SetConnMaxIdleTime(time.Duration(a.config.ConnMaxIdleTime) * time.Second)
+		replicas = append(replicas, rep)
+	}
+
+	interval := time.Duration(a.config.ReplicaCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	router := newReplicaRouter(a.db, replicas, time.Duration(a.config.ReplicaMaxLag)*time.Second)
+	router.start(ctx, interval)
+	return router, nil
+}
+
+// dsn builds the connection string from the adapter configuration
+func (a *PostgresAdapter) dsn() string {
+	return a.dsnFor(a.config.Host, a.config.Port)
+}
+
+// dsnFor builds the connection string of the server at host and port
+func (a *PostgresAdapter) dsnFor(host string, port int) string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		host,
+		port,
+		a.config.User,
+		a.config.Password,
+		a.config.Database,
+		a.config.SSLMode,
+	)
+}
+
 // Disconnect closes the database connection
 func (a *PostgresAdapter) Disconnect(ctx context.Context) error {
+	if a.replicas != nil {
+		if err := a.replicas.close(); err != nil {
+			logger.Log.Warnw("Failed to close read replicas", "error", err)
+		}
+		a.replicas = nil
+	}
 	if a.db != nil {
 		return a.db.Close()
 	}
@@ -92,9 +147,10 @@ func (a *PostgresAdapter) BeginTransaction(ctx context.Context) (ports.Transacti
 	}
 
 	return &postgresTransaction{
-		tx:        tx,
-		imeiRepo:  NewIMEIRepository(tx),
-		auditRepo: NewAuditRepository(tx),
+		tx:          tx,
+		imeiRepo:    NewIMEIRepository(tx),
+		auditRepo:   NewAuditRepository(tx),
+		historyRepo: NewHistoryRepository(tx),
 	}, nil
 }
 
@@ -123,6 +179,32 @@ func (a *PostgresAdapter) GetSnapshotRepository() ports.SnapshotRepository {
 	return a.snapshotRepo
 }
 
+// GetChangeFeed returns the LISTEN/NOTIFY change feed, or nil when it is disabled
+func (a *PostgresAdapter) GetChangeFeed() ports.ChangeFeed {
+	if !a.config.EnableNotify {
+		return nil
+	}
+	return NewChangeFeed(a.dsn())
+}
+
+// GetMigrationManager returns the migration manager for this database
+func (a *PostgresAdapter) GetMigrationManager() ports.MigrationManager {
+	return NewMigrator(a.db)
+}
+
+// GetDataExporter returns an exporter streaming from this database
+func (a *PostgresAdapter) GetDataExporter() ports.DataExporter {
+	if a.replicas != nil {
+		return NewDataExporter(a.replicas.db())
+	}
+	return NewDataExporter(a.db)
+}
+
+// GetAuditChainStore returns the audit hash chain of this database
+func (a *PostgresAdapter) GetAuditChainStore() ports.AuditChainStore {
+	return NewAuditChainStore(a.db)
+}
+
 // HealthCheck performs a health check on the database
 func (a *PostgresAdapter) HealthCheck(ctx context.Context) error {
 	if err := a.Ping(ctx); err != nil {
@@ -202,11 +284,63 @@ func (a *PostgresAdapter) OptimizeDatabase(ctx context.Context) error {
 	return nil
 }
 
+// PurgeAuditsBatch removes at most limit audit logs older than before, oldest first
+func (a *PostgresAdapter) PurgeAuditsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	// Matching on check_time too lets the planner prune audit_log partitions
+	query := `
+		DELETE FROM audit_log WHERE (id, check_time) IN (
+			SELECT id, check_time FROM audit_log
+			WHERE check_time < $1
+			ORDER BY check_time
+			LIMIT $2
+		)`
+	return a.purgeBatch(ctx, query, before, limit, "audits")
+}
+
+// PurgeHistoryBatch removes at most limit history records older than before, oldest first
+func (a *PostgresAdapter) PurgeHistoryBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM equipment_history WHERE id IN (
+			SELECT id FROM equipment_history
+			WHERE changed_at < $1
+			ORDER BY changed_at
+			LIMIT $2
+		)`
+	return a.purgeBatch(ctx, query, before, limit, "history")
+}
+
+// PurgeSnapshotsBatch removes at most limit snapshots older than before, oldest first
+func (a *PostgresAdapter) PurgeSnapshotsBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM equipment_snapshots WHERE id IN (
+			SELECT id FROM equipment_snapshots
+			WHERE snapshot_time < $1
+			ORDER BY snapshot_time
+			LIMIT $2
+		)`
+	return a.purgeBatch(ctx, query, before, limit, "snapshots")
+}
+
+func (a *PostgresAdapter) purgeBatch(ctx context.Context, query string, before time.Time, limit int, dataset string) (int64, error) {
+	result, err := a.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge old %s: %w", dataset, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
 // postgresTransaction implements the Transaction interface
 type postgresTransaction struct {
-	tx        *sqlx.Tx
-	imeiRepo  ports.IMEIRepository
-	auditRepo ports.AuditRepository
+	tx          *sqlx.Tx
+	imeiRepo    ports.IMEIRepository
+	auditRepo   ports.AuditRepository
+	historyRepo ports.HistoryRepository
 }
 
 // Commit commits the transaction
@@ -228,3 +362,8 @@ func (t *postgresTransaction) GetIMEIRepository() ports.IMEIRepository {
 func (t *postgresTransaction) GetAuditRepository() ports.AuditRepository {
 	return t.auditRepo
 }
+
+// GetHistoryRepository returns a transactional history repository
+func (t *postgresTransaction) GetHistoryRepository() ports.HistoryRepository {
+	return t.historyRepo
+}
@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// replicaLagQuery returns how far a replica is behind the primary in seconds.
+// A replica that has replayed everything it received reports 0 even when the
+// last replayed transaction is old, so an idle primary does not look like lag.
+// On a server that is not in recovery the functions are NULL and the lag is 0.
+const replicaLagQuery = `
+	SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)`
+
+// replica is one read replica and its last observed state
+type replica struct {
+	name    string // host:port, used in logs and metrics
+	db      *sqlx.DB
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanoseconds
+}
+
+// replicaRouter sends read-only queries to healthy replicas in turn and
+// everything else to the primary. A replica is excluded while it fails its
+// health check or lags more than maxLag behind, and as soon as a query on it
+// fails with a connection error, in which case the query is retried on the
+// primary. With no replica available reads fall back to the primary.
+//
+// It implements dbExecutor so repositories can use it as their reader; the
+// write methods go to the primary, though repositories never call them on it.
+type replicaRouter struct {
+	primary  *sqlx.DB
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func newReplicaRouter(primary *sqlx.DB, replicas []*replica, maxLag time.Duration) *replicaRouter {
+	return &replicaRouter{primary: primary, replicas: replicas, maxLag: maxLag}
+}
+
+// start checks every replica once, then again every interval until close
+func (r *replicaRouter) start(ctx context.Context, interval time.Duration) {
+	r.checkAll(ctx)
+
+	ctx, r.stop = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+// close stops the health checks and closes the replica connections
+func (r *replicaRouter) close() error {
+	if r.stop != nil {
+		r.stop()
+		<-r.done
+	}
+	var errs []error
+	for _, rep := range r.replicas {
+		errs = append(errs, rep.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *replicaRouter) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			r.check(ctx, rep)
+		}(rep)
+	}
+	wg.Wait()
+}
+
+// check measures a replica's lag and updates whether it may serve reads
+func (r *replicaRouter) check(ctx context.Context, rep *replica) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var seconds float64
+	err := rep.db.GetContext(ctx, &seconds, replicaLagQuery)
+	lag := time.Duration(seconds * float64(time.Second))
+	rep.lag.Store(int64(lag))
+	logger.DatabaseReplicaLag.WithLabelValues(rep.name).Set(lag.Seconds())
+
+	switch {
+	case err != nil:
+		r.setHealthy(rep, false, "error", err)
+	case r.maxLag > 0 && lag > r.maxLag:
+		r.setHealthy(rep, false, "lag", lag.String(), "max_lag", r.maxLag.String())
+	default:
+		r.setHealthy(rep, true, "lag", lag.String())
+	}
+}
+
+func (r *replicaRouter) setHealthy(rep *replica, healthy bool, keysAndValues ...interface{}) {
+	if rep.healthy.Swap(healthy) != healthy {
+		args := append([]interface{}{"replica", rep.name}, keysAndValues...)
+		if healthy {
+			logger.Log.Infow("✓ Postgres read replica in service", args...)
+		} else {
+			logger.Log.Warnw("Postgres read replica taken out of service", args...)
+		}
+	}
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	logger.DatabaseReplicaHealthy.WithLabelValues(rep.name).Set(value)
+}
+
+// pick returns the next healthy replica, or nil when there is none
+func (r *replicaRouter) pick() *replica {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// db returns the connection pool the next read should use
+func (r *replicaRouter) db() *sqlx.DB {
+	if rep := r.pick(); rep != nil {
+		return rep.db
+	}
+	return r.primary
+}
+
+// read runs fn on a replica, failing over to the primary when the replica is unreachable
+func (r *replicaRouter) read(fn func(db *sqlx.DB) error) error {
+	rep := r.pick()
+	if rep == nil {
+		return fn(r.primary)
+	}
+	err := fn(rep.db)
+	if err != nil && isConnectionError(err) {
+		r.setHealthy(rep, false, "error", err)
+		return fn(r.primary)
+	}
+	return err
+}
+
+// isConnectionError reports whether err means the server could not be
+// reached or dropped the connection, as opposed to a failing query
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// Class 08 is connection exceptions, 57P0x the server shutting down
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	return false
+}
+
+func (r *replicaRouter) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.read(func(db *sqlx.DB) error {
+		return db.GetContext(ctx, dest, query, args...)
+	})
+}
+
+func (r *replicaRouter) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.read(func(db *sqlx.DB) error {
+		return db.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+func (r *replicaRouter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.read(func(db *sqlx.DB) error {
+		var err error
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r *replicaRouter) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+func (r *replicaRouter) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := r.read(func(db *sqlx.DB) error {
+		var err error
+		rows, err = db.Queryx(query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r *replicaRouter) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return r.db().QueryRowx(query, args...)
+}
+
+func (r *replicaRouter) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.primary.Exec(query, args...)
+}
+
+func (r *replicaRouter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+func (r *replicaRouter) Prepare(query string) (*sql.Stmt, error) {
+	return r.primary.Prepare(query)
+}
+
+func (r *replicaRouter) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	return r.primary.PrepareNamedContext(ctx, query)
+}
+
+func (r *replicaRouter) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return r.primary.NamedExecContext(ctx, query, arg)
+}
+
+// openReplica opens a lazily connected pool for a replica; an unreachable
+// replica does not stop the adapter from connecting, it just stays out of service
+func openReplica(dsn, name string) (*replica, error) {
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica %s: %w", name, err)
+	}
+	return &replica{name: name, db: db}, nil
+}
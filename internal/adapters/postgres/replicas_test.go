@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var equipmentColumns = []string{
+	"id", "imei", "imeisv", "status", "reason", "last_updated", "last_check_time",
+	"check_count", "added_by", "metadata", "manufacturer_tac", "manufacturer_name",
+}
+
+func equipmentRow(imei string) *sqlmock.Rows {
+	return sqlmock.NewRows(equipmentColumns).AddRow(1, imei, nil, models.EquipmentStatusWhitelisted, nil, time.Now(), nil, 0, "admin", nil, nil, nil)
+}
+
+func lagRow(seconds float64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"lag"}).AddRow(seconds)
+}
+
+func setupReplicaRouter(t *testing.T, maxLag time.Duration, lag float64) (*imeiRepository, sqlmock.Sqlmock, sqlmock.Sqlmock, *replica) {
+	primary, primaryMock := setupTestDB(t)
+	replicaDB, replicaMock := setupTestDB(t)
+	t.Cleanup(func() { primary.Close(); replicaDB.Close() })
+
+	rep := &replica{name: "replica-1:5432", db: replicaDB}
+	router := newReplicaRouter(primary, []*replica{rep}, maxLag)
+	replicaMock.ExpectQuery("pg_last_wal_receive_lsn").WillReturnRows(lagRow(lag))
+	router.checkAll(context.Background())
+
+	return &imeiRepository{db: primary, reader: router}, primaryMock, replicaMock, rep
+}
+
+func TestReplicaRouter_ReadsGoToReplica(t *testing.T) {
+	repo, primaryMock, replicaMock, rep := setupReplicaRouter(t, 10*time.Second, 0.5)
+	require.True(t, rep.healthy.Load())
+	ctx := context.Background()
+
+	replicaMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").WithArgs("490154203237518").WillReturnRows(equipmentRow("490154203237518"))
+	_, err := repo.ForChecks().GetByIMEI(ctx, "490154203237518")
+	require.NoError(t, err)
+
+	// Lookups outside checks feed writes, so they stay on the primary like the writes
+	primaryMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").WithArgs("490154203237518").WillReturnRows(equipmentRow("490154203237518"))
+	_, err = repo.GetByIMEI(ctx, "490154203237518")
+	require.NoError(t, err)
+	primaryMock.ExpectExec("increment_equipment_check_count").WithArgs("490154203237518").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.IncrementCheckCount(ctx, "490154203237518"))
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestReplicaRouter_LaggingReplicaIsExcluded(t *testing.T) {
+	repo, primaryMock, replicaMock, rep := setupReplicaRouter(t, 10*time.Second, 30)
+	assert.False(t, rep.healthy.Load())
+	assert.Equal(t, 30*time.Second, time.Duration(rep.lag.Load()))
+
+	primaryMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").WithArgs("490154203237518").WillReturnRows(equipmentRow("490154203237518"))
+	_, err := repo.ForChecks().GetByIMEI(context.Background(), "490154203237518")
+	require.NoError(t, err)
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestReplicaRouter_FailsOverOnConnectionError(t *testing.T) {
+	repo, primaryMock, replicaMock, rep := setupReplicaRouter(t, 0, 0)
+	ctx := context.Background()
+
+	replicaMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").
+		WillReturnError(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")})
+	primaryMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").WithArgs("490154203237518").WillReturnRows(equipmentRow("490154203237518"))
+
+	checks := repo.ForChecks()
+	_, err := checks.GetByIMEI(ctx, "490154203237518")
+	require.NoError(t, err)
+	assert.False(t, rep.healthy.Load(), "a replica that dropped the connection is taken out of service")
+
+	// A query error is not a reason to fail over
+	rep.healthy.Store(true)
+	replicaMock.ExpectQuery("SELECT (.+) FROM equipment WHERE imei").WillReturnError(errors.New("syntax error"))
+	_, err = checks.GetByIMEI(ctx, "490154203237518")
+	assert.Error(t, err)
+	assert.True(t, rep.healthy.Load())
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
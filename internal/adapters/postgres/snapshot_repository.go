@@ -13,12 +13,13 @@ import (
 
 // snapshotRepository implements the SnapshotRepository interface using PostgreSQL
 type snapshotRepository struct {
-	db dbExecutor
+	db     dbExecutor
+	reader dbExecutor // Read-only queries; db itself unless reads go to replicas
 }
 
 // NewSnapshotRepository creates a new PostgreSQL snapshot repository
 func NewSnapshotRepository(db dbExecutor) ports.SnapshotRepository {
-	return &snapshotRepository{db: db}
+	return &snapshotRepository{db: db, reader: db}
 }
 
 // CreateSnapshot creates a point-in-time snapshot of equipment
@@ -59,7 +60,7 @@ func (r *snapshotRepository) GetSnapshotsByIMEI(ctx context.Context, imei string
 	`
 
 	var snapshots []*models.EquipmentSnapshot
-	err := r.db.SelectContext(ctx, &snapshots, query, imei, limit, offset)
+	err := r.reader.SelectContext(ctx, &snapshots, query, imei, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get snapshots by IMEI: %w", err)
 	}
@@ -77,7 +78,7 @@ func (r *snapshotRepository) GetSnapshotByID(ctx context.Context, id int64) (*mo
 	`
 
 	var snapshot models.EquipmentSnapshot
-	err := r.db.GetContext(ctx, &snapshot, query, id)
+	err := r.reader.GetContext(ctx, &snapshot, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -88,6 +89,29 @@ func (r *snapshotRepository) GetSnapshotByID(ctx context.Context, id int64) (*mo
 	return &snapshot, nil
 }
 
+// GetLatestSnapshot retrieves the latest snapshot of imei taken at or before at
+func (r *snapshotRepository) GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error) {
+	query := `
+		SELECT id, equipment_id, imei, snapshot_time, status, reason,
+		       check_count, metadata, created_by, snapshot_type
+		FROM equipment_snapshots
+		WHERE imei = $1 AND snapshot_time <= $2
+		ORDER BY snapshot_time DESC, id DESC
+		LIMIT 1
+	`
+
+	var snapshot models.EquipmentSnapshot
+	err := r.reader.GetContext(ctx, &snapshot, query, imei, at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
 // DeleteOldSnapshots removes snapshots older than the specified date
 func (r *snapshotRepository) DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error) {
 	query := `DELETE FROM equipment_snapshots WHERE snapshot_time < $1`
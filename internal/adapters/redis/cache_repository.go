@@ -0,0 +1,194 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss is returned by Get when the IMEI is not cached
+var ErrCacheMiss = errors.New("cache miss")
+
+// Config holds Redis connection and cache settings
+type Config struct {
+	Host         string
+	Port         int
+	Password     string
+	DB           int
+	Namespace    string        // Prefix for every key, e.g. "eir" gives "eir:equipment:<imei>"
+	TTL          time.Duration // Default TTL when callers pass ttlSeconds <= 0
+	PoolSize     int           // Maximum socket connections; 0 uses the client default
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// NewClient creates a pooled Redis client and verifies the connection
+func NewClient(cfg Config) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	})
+
+	// Verify connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	return client, nil
+}
+
+// cacheRepository implements ports.CacheRepository on top of Redis
+type cacheRepository struct {
+	client    goredis.UniversalClient
+	namespace string
+	ttl       time.Duration
+}
+
+// NewCacheRepository creates a Redis-backed equipment cache
+func NewCacheRepository(client goredis.UniversalClient, namespace string, ttl time.Duration) ports.CacheRepository {
+	return &cacheRepository{
+		client:    client,
+		namespace: namespace,
+		ttl:       ttl,
+	}
+}
+
+var _ ports.BatchCacheRepository = (*cacheRepository)(nil)
+
+func (r *cacheRepository) key(imei string) string {
+	if r.namespace == "" {
+		return "equipment:" + imei
+	}
+	return r.namespace + ":equipment:" + imei
+}
+
+func (r *cacheRepository) expiration(ttlSeconds int) time.Duration {
+	if ttlSeconds > 0 {
+		return time.Duration(ttlSeconds) * time.Second
+	}
+	return r.ttl
+}
+
+func (r *cacheRepository) Get(ctx context.Context, imei string) (*models.Equipment, error) {
+	data, err := r.client.Get(ctx, r.key(imei)).Bytes()
+	if err != nil {
+		logger.CacheHitTotal.WithLabelValues("redis", "miss").Inc()
+		if errors.Is(err, goredis.Nil) {
+			return nil, ErrCacheMiss
+		}
+		return nil, fmt.Errorf("failed to get cached equipment: %w", err)
+	}
+
+	var equipment models.Equipment
+	if err := json.Unmarshal(data, &equipment); err != nil {
+		logger.CacheHitTotal.WithLabelValues("redis", "miss").Inc()
+		return nil, fmt.Errorf("failed to decode cached equipment: %w", err)
+	}
+
+	logger.CacheHitTotal.WithLabelValues("redis", "hit").Inc()
+	return &equipment, nil
+}
+
+func (r *cacheRepository) Set(ctx context.Context, imei string, equipment *models.Equipment, ttlSeconds int) error {
+	data, err := json.Marshal(equipment)
+	if err != nil {
+		return fmt.Errorf("failed to encode equipment: %w", err)
+	}
+
+	if err := r.client.Set(ctx, r.key(imei), data, r.expiration(ttlSeconds)).Err(); err != nil {
+		return fmt.Errorf("failed to cache equipment: %w", err)
+	}
+	return nil
+}
+
+func (r *cacheRepository) Delete(ctx context.Context, imei string) error {
+	if err := r.client.Del(ctx, r.key(imei)).Err(); err != nil {
+		return fmt.Errorf("failed to delete cached equipment: %w", err)
+	}
+	return nil
+}
+
+func (r *cacheRepository) Exists(ctx context.Context, imei string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.key(imei)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check cached equipment: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetMany fetches all keys with a single MGET
+func (r *cacheRepository) GetMany(ctx context.Context, imeis []string) (map[string]*models.Equipment, error) {
+	result := make(map[string]*models.Equipment, len(imeis))
+	if len(imeis) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(imeis))
+	for i, imei := range imeis {
+		keys[i] = r.key(imei)
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		logger.CacheHitTotal.WithLabelValues("redis", "miss").Add(float64(len(imeis)))
+		return nil, fmt.Errorf("failed to get cached equipment: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			logger.CacheHitTotal.WithLabelValues("redis", "miss").Inc()
+			continue
+		}
+		var equipment models.Equipment
+		if err := json.Unmarshal([]byte(data), &equipment); err != nil {
+			logger.CacheHitTotal.WithLabelValues("redis", "miss").Inc()
+			continue
+		}
+		logger.CacheHitTotal.WithLabelValues("redis", "hit").Inc()
+		result[imeis[i]] = &equipment
+	}
+	return result, nil
+}
+
+// SetMany writes all entries in one pipelined round trip
+func (r *cacheRepository) SetMany(ctx context.Context, equipment map[string]*models.Equipment, ttlSeconds int) error {
+	if len(equipment) == 0 {
+		return nil
+	}
+
+	expiration := r.expiration(ttlSeconds)
+	_, err := r.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for imei, e := range equipment {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return fmt.Errorf("failed to encode equipment %s: %w", imei, err)
+			}
+			pipe.Set(ctx, r.key(imei), data, expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cache equipment: %w", err)
+	}
+	return nil
+}
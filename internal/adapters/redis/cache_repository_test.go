@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestCache(t *testing.T) (*miniredis.Miniredis, ports.BatchCacheRepository) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, NewCacheRepository(client, "eir", time.Minute).(ports.BatchCacheRepository)
+}
+
+func testEquipment(imei string) *models.Equipment {
+	return &models.Equipment{
+		IMEI:    imei,
+		Status:  models.EquipmentStatusBlacklisted,
+		AddedBy: "admin",
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	client, err := NewClient(Config{Host: mr.Host(), Port: mustPort(t, mr), PoolSize: 4, MinIdleConns: 1})
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, 4, client.Options().PoolSize)
+}
+
+func TestNewClient_Unreachable(t *testing.T) {
+	_, err := NewClient(Config{Host: "127.0.0.1", Port: 1, DialTimeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to ping redis")
+}
+
+func TestCacheSetGet(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	require.NoError(t, cache.Set(ctx, imei, testEquipment(imei), 0))
+
+	// Keys live under the configured namespace with the default TTL
+	assert.True(t, mr.Exists("eir:equipment:"+imei))
+	assert.Equal(t, time.Minute, mr.TTL("eir:equipment:"+imei))
+
+	hits := testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("redis", "hit"))
+	got, err := cache.Get(ctx, imei)
+	require.NoError(t, err)
+	assert.Equal(t, imei, got.IMEI)
+	assert.Equal(t, models.EquipmentStatusBlacklisted, got.Status)
+	assert.Equal(t, hits+1, testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("redis", "hit")))
+}
+
+func TestCacheGet_Miss(t *testing.T) {
+	_, cache := setupTestCache(t)
+
+	misses := testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("redis", "miss"))
+	got, err := cache.Get(context.Background(), "490154203237518")
+
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Nil(t, got)
+	assert.Equal(t, misses+1, testutil.ToFloat64(logger.CacheHitTotal.WithLabelValues("redis", "miss")))
+}
+
+func TestCacheGet_Corrupt(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	require.NoError(t, mr.Set("eir:equipment:490154203237518", "not-json"))
+
+	_, err := cache.Get(context.Background(), "490154203237518")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode cached equipment")
+}
+
+func TestCacheSet_ExplicitTTLExpires(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	require.NoError(t, cache.Set(ctx, imei, testEquipment(imei), 10))
+	assert.Equal(t, 10*time.Second, mr.TTL("eir:equipment:"+imei))
+
+	mr.FastForward(11 * time.Second)
+
+	_, err := cache.Get(ctx, imei)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestCacheDeleteExists(t *testing.T) {
+	_, cache := setupTestCache(t)
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	require.NoError(t, cache.Set(ctx, imei, testEquipment(imei), 0))
+
+	ok, err := cache.Exists(ctx, imei)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, cache.Delete(ctx, imei))
+
+	ok, err = cache.Exists(ctx, imei)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCacheSetManyGetMany(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	ctx := context.Background()
+
+	entries := map[string]*models.Equipment{
+		"490154203237518": testEquipment("490154203237518"),
+		"356938035643809": testEquipment("356938035643809"),
+	}
+	require.NoError(t, cache.SetMany(ctx, entries, 30))
+	assert.Equal(t, 30*time.Second, mr.TTL("eir:equipment:356938035643809"))
+
+	got, err := cache.GetMany(ctx, []string{"490154203237518", "356938035643809", "111111111111111"})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "356938035643809", got["356938035643809"].IMEI)
+	assert.NotContains(t, got, "111111111111111")
+}
+
+func TestCacheServerDown(t *testing.T) {
+	mr, cache := setupTestCache(t)
+	mr.Close()
+
+	_, err := cache.Get(context.Background(), "490154203237518")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCacheMiss)
+}
+
+func mustPort(t *testing.T, mr *miniredis.Miniredis) int {
+	t.Helper()
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+	return port
+}
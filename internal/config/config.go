@@ -1,34 +1,55 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
+	"os"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server     ServerConfig
-	Database   DatabaseConfig
-	Diameter   DiameterConfig
-	Cache      CacheConfig
-	Logging    LoggingConfig
-	Metrics    MetricsConfig
-	Governance GovernanceConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Diameter    DiameterConfig
+	Cache       CacheConfig
+	Checks      CheckCountersConfig
+	Snapshot    SnapshotConfig
+	Maintenance MaintenanceConfig
+	DualWrite   DualWriteConfig
+	Audit       AuditConfig
+	Logging     LoggingConfig
+	Metrics     MetricsConfig
+	Governance  GovernanceConfig
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Host         string
-	Port         int
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Host           string
+	Port           int
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	APITokens      []APITokenConfig // Bearer tokens that authenticate management requests
+	TrustedProxies []string         // IPs or CIDR blocks whose X-Actor header names the actor
+}
+
+// APITokenConfig is a bearer token whose management requests have their
+// changes recorded under Actor
+type APITokenConfig struct {
+	Actor string
+	Token string
 }
 
-// DatabaseConfig holds PostgreSQL configuration
+// DatabaseConfig holds the storage backend configuration
+// The top-level connection fields configure PostgreSQL; Mongo holds MongoDB settings,
+// Embedded the single-file store and Memory the optional persistence of the memory backend.
 type DatabaseConfig struct {
+	Type            string // "memory", "postgres", "mongodb", "embedded"
 	Host            string
 	Port            int
 	User            string
@@ -39,6 +60,59 @@ type DatabaseConfig struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+	QueryTimeout    time.Duration
+	Mongo           MongoConfig
+	Embedded        EmbeddedConfig
+	Memory          MemoryConfig
+
+	// Startup behaviour
+	ConnectRetries       int           // Extra connection attempts after the first failure
+	ConnectRetryInterval time.Duration // Delay between connection attempts
+	ConnectTimeout       time.Duration // Timeout for each connection attempt
+	AutoMigrate          bool          // Apply pending schema migrations on startup
+	EnableNotify         bool          // Postgres: LISTEN/NOTIFY change feed for cross-replica invalidation
+
+	// Postgres read replicas; read-only queries go to them, writes and transactions stay on Host
+	Replicas             []ReplicaConfig
+	ReplicaMaxLag        time.Duration // Skip replicas further behind than this; 0 disables the check
+	ReplicaCheckInterval time.Duration // How often replica health and lag are measured
+}
+
+// ReplicaConfig locates a Postgres read replica; it uses the primary's credentials and database
+type ReplicaConfig struct {
+	Host string
+	Port int
+}
+
+// MongoConfig holds MongoDB configuration
+type MongoConfig struct {
+	URI                string
+	Database           string
+	MaxPoolSize        int
+	MinPoolSize        int
+	MaxConnIdleTime    time.Duration
+	ServerTimeout      time.Duration
+	SocketTimeout      time.Duration
+	ReplicaSet         string
+	ReadPreference     string // "primary", "secondary", "primaryPreferred", "secondaryPreferred"
+	WriteConcern       string // "majority"
+	EnableChangeStream bool
+}
+
+// EmbeddedConfig holds the embedded single-file store configuration
+type EmbeddedConfig struct {
+	Path        string        // Database file, created with its directory if missing
+	LockTimeout time.Duration // Wait for another process holding the file lock; 0 waits forever
+	NoSync      bool          // Skip fsync on commit; faster, but the last commits can be lost on power failure
+}
+
+// MemoryConfig holds the write-ahead log settings of the memory backend
+type MemoryConfig struct {
+	DataDir          string        // Log and snapshot directory; empty keeps everything in memory only
+	Fsync            string        // "always", "interval", "none"
+	FsyncInterval    time.Duration // Flush period when fsync is "interval"
+	SnapshotInterval time.Duration // Periodic compaction; 0 disables it
+	CompactSize      int64         // Compact once the log exceeds this many bytes; 0 disables it
 }
 
 // DiameterConfig holds Diameter server configuration
@@ -64,14 +138,97 @@ type CacheConfig struct {
 	Enabled  bool
 	Provider string // "redis", "memcached", "inmemory"
 	Redis    RedisConfig
+	Decision DecisionCacheConfig
+}
+
+// DecisionCacheConfig holds the in-process check decision cache configuration.
+// It is independent of the equipment cache above.
+type DecisionCacheConfig struct {
+	Enabled     bool
+	Shards      int
+	Capacity    int           // Total entries across all shards
+	PositiveTTL time.Duration // Lifetime of "ok" decisions
+	NegativeTTL time.Duration // Lifetime of "unknown" decisions
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Host     string
-	Port     int
-	Password string
-	DB       int
+	Host         string
+	Port         int
+	Password     string
+	DB           int
+	Namespace    string        // Key prefix, lets several deployments share one Redis
+	TTL          time.Duration // Default expiry of cached equipment
+	PoolSize     int           // Maximum connections; 0 uses the client default
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// CheckCountersConfig holds the equipment check counters. Checks are counted
+// in memory and flushed to the equipment records every FlushInterval, so
+// CheckCount and LastCheckTime lag the checks by at most that long.
+type CheckCountersConfig struct {
+	Enabled       bool          // Count checks and track when each equipment was last seen
+	FlushInterval time.Duration // How often counted checks are written
+	BatchSize     int           // Equipment records updated per repository call
+	MaxPending    int           // Distinct IMEIs held between flushes; checks of further IMEIs are dropped
+}
+
+// SnapshotConfig holds the equipment snapshot settings
+type SnapshotConfig struct {
+	Enabled   bool          // Take SCHEDULED snapshots of every equipment record
+	Schedule  string        // Standard 5-field cron expression, e.g. "0 3 * * *"
+	Retention time.Duration // Delete snapshots older than this after each run; 0 keeps them
+}
+
+// MaintenanceConfig holds the database retention and maintenance settings
+type MaintenanceConfig struct {
+	Enabled            bool          // Purge old records on Schedule
+	Schedule           string        // Standard 5-field cron expression, e.g. "0 2 * * *"
+	WindowStart        string        // "HH:MM" local time scheduled runs may start batches from; empty allows any time
+	WindowEnd          string        // "HH:MM" local time scheduled runs stop at; may be before WindowStart to span midnight
+	BatchSize          int           // Records deleted per statement
+	BatchPause         time.Duration // Pause between batches, letting other writers through
+	AuditRetention     time.Duration // Purge audit logs older than this; 0 keeps them
+	HistoryRetention   time.Duration // Purge change history older than this; 0 keeps it
+	SnapshotRetention  time.Duration // Purge snapshots older than this; 0 keeps them
+	TombstoneRetention time.Duration // Purge equipment deleted longer ago than this; 0 keeps tombstones
+	Optimize           bool          // Run OptimizeDatabase after a run that purged everything due
+}
+
+// DualWriteConfig holds the dual-write phase of a migration between backends.
+// While enabled, every write that succeeds on Database is repeated on Target,
+// so the copy made by `migrate copy` stays current until cutover.
+type DualWriteConfig struct {
+	Enabled bool
+	Target  DatabaseConfig // "postgres", "mongodb" or "embedded"
+}
+
+// AuditConfig holds the protection of subscriber identifiers in audit logs.
+// UserName, SUPI and GPSI are each stored "clear", as a keyed-hash "pseudonym"
+// or "encrypted" with AES-GCM. New records use ActiveKey; the other Keys stay
+// to read and look up records written before a rotation.
+type AuditConfig struct {
+	Protect      bool
+	UserName     string            // "clear", "pseudonym" or "encrypted"
+	SUPI         string            // "clear", "pseudonym" or "encrypted"
+	GPSI         string            // "clear", "pseudonym" or "encrypted"
+	ActiveKey    string            // ID of the key new records are protected with
+	Keys         map[string]string // Key ID to base64-encoded 32-byte key
+	AccessTokens []string          // Bearer tokens whose audit reads reveal encrypted identifiers and that may run maintenance
+	Chain        AuditChainConfig
+}
+
+// AuditChainConfig holds the signed checkpoints of the audit hash chain.
+// Every audit is chained regardless; checkpoints sign the chain heads with an
+// Ed25519 key so the chain cannot be silently recomputed after tampering.
+type AuditChainConfig struct {
+	CheckpointInterval time.Duration     // How often the chain heads are signed; 0 disables checkpoints
+	SigningKeyID       string            // ID recorded with each checkpoint
+	SigningKey         string            // Base64-encoded 32-byte Ed25519 seed
+	PublicKeys         map[string]string // Key ID to base64-encoded Ed25519 public key of retired signing keys
 }
 
 // LoggingConfig holds logging configuration
@@ -149,7 +306,7 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	if configFileRead {
-		fmt.Printf("Using config file: %s\n", v.ConfigFileUsed())
+		fmt.Fprintf(os.Stderr, "Using config file: %s\n", v.ConfigFileUsed())
 	}
 
 	// Unmarshal config
@@ -176,16 +333,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.idleTimeout", "120s")
 
 	// Database defaults
-	v.SetDefault("database.host", "localhost")
-	v.SetDefault("database.port", 5432)
-	v.SetDefault("database.user", "eir")
-	v.SetDefault("database.password", "eir")
-	v.SetDefault("database.database", "eir")
-	v.SetDefault("database.sslMode", "disable")
-	v.SetDefault("database.maxOpenConns", 25)
-	v.SetDefault("database.maxIdleConns", 5)
-	v.SetDefault("database.connMaxLifetime", "5m")
-	v.SetDefault("database.connMaxIdleTime", "10m")
+	v.SetDefault("database.type", "memory")
+	setDatabaseDefaults(v, "database")
 
 	// Diameter defaults
 	v.SetDefault("diameter.host", "0.0.0.0")
@@ -210,6 +359,54 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("cache.redis.port", 6379)
 	v.SetDefault("cache.redis.password", "")
 	v.SetDefault("cache.redis.db", 0)
+	v.SetDefault("cache.redis.namespace", "eir")
+	v.SetDefault("cache.redis.ttl", "5m")
+	v.SetDefault("cache.redis.poolSize", 20)
+	v.SetDefault("cache.redis.minIdleConns", 2)
+	v.SetDefault("cache.redis.dialTimeout", "5s")
+	v.SetDefault("cache.redis.readTimeout", "3s")
+	v.SetDefault("cache.redis.writeTimeout", "3s")
+	v.SetDefault("cache.decision.enabled", false)
+	v.SetDefault("cache.decision.shards", 16)
+	v.SetDefault("cache.decision.capacity", 100000)
+	v.SetDefault("cache.decision.positiveTTL", "5m")
+	v.SetDefault("cache.decision.negativeTTL", "30s")
+
+	// Check counter defaults
+	v.SetDefault("checks.enabled", true)
+	v.SetDefault("checks.flushInterval", "10s")
+	v.SetDefault("checks.batchSize", 500)
+	v.SetDefault("checks.maxPending", 100000)
+
+	// Snapshot defaults
+	v.SetDefault("snapshot.enabled", false)
+	v.SetDefault("snapshot.schedule", "0 3 * * *")
+	v.SetDefault("snapshot.retention", "2160h")
+
+	// Maintenance defaults
+	v.SetDefault("maintenance.enabled", false)
+	v.SetDefault("maintenance.schedule", "0 2 * * *")
+	v.SetDefault("maintenance.windowStart", "02:00")
+	v.SetDefault("maintenance.windowEnd", "05:00")
+	v.SetDefault("maintenance.batchSize", 5000)
+	v.SetDefault("maintenance.batchPause", "100ms")
+	v.SetDefault("maintenance.auditRetention", "2160h")
+	v.SetDefault("maintenance.historyRetention", "8760h")
+	v.SetDefault("maintenance.snapshotRetention", "0s")
+	v.SetDefault("maintenance.tombstoneRetention", "0s")
+	v.SetDefault("maintenance.optimize", false)
+
+	// Dual-write defaults
+	v.SetDefault("dualWrite.enabled", false)
+	v.SetDefault("dualWrite.target.type", "")
+	setDatabaseDefaults(v, "dualWrite.target")
+
+	// Audit protection defaults
+	v.SetDefault("audit.protect", false)
+	v.SetDefault("audit.userName", "encrypted")
+	v.SetDefault("audit.supi", "encrypted")
+	v.SetDefault("audit.gpsi", "encrypted")
+	v.SetDefault("audit.chain.checkpointInterval", "0s")
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
@@ -227,6 +424,46 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("governance.failOnError", true)
 }
 
+// setDatabaseDefaults sets the connection defaults of the database section at prefix
+func setDatabaseDefaults(v *viper.Viper, prefix string) {
+	v.SetDefault(prefix+".host", "localhost")
+	v.SetDefault(prefix+".port", 5432)
+	v.SetDefault(prefix+".user", "eir")
+	v.SetDefault(prefix+".password", "eir")
+	v.SetDefault(prefix+".database", "eir")
+	v.SetDefault(prefix+".sslMode", "disable")
+	v.SetDefault(prefix+".maxOpenConns", 25)
+	v.SetDefault(prefix+".maxIdleConns", 5)
+	v.SetDefault(prefix+".connMaxLifetime", "5m")
+	v.SetDefault(prefix+".connMaxIdleTime", "10m")
+	v.SetDefault(prefix+".queryTimeout", "30s")
+	v.SetDefault(prefix+".connectRetries", 5)
+	v.SetDefault(prefix+".connectRetryInterval", "2s")
+	v.SetDefault(prefix+".connectTimeout", "10s")
+	v.SetDefault(prefix+".autoMigrate", false)
+	v.SetDefault(prefix+".mongo.uri", "mongodb://localhost:27017")
+	v.SetDefault(prefix+".mongo.database", "eir")
+	v.SetDefault(prefix+".mongo.maxPoolSize", 100)
+	v.SetDefault(prefix+".mongo.minPoolSize", 10)
+	v.SetDefault(prefix+".mongo.maxConnIdleTime", "10m")
+	v.SetDefault(prefix+".mongo.serverTimeout", "30s")
+	v.SetDefault(prefix+".mongo.socketTimeout", "30s")
+	v.SetDefault(prefix+".mongo.readPreference", "primary")
+	v.SetDefault(prefix+".mongo.writeConcern", "majority")
+	v.SetDefault(prefix+".mongo.enableChangeStream", false)
+	v.SetDefault(prefix+".enableNotify", false)
+	v.SetDefault(prefix+".replicaMaxLag", "10s")
+	v.SetDefault(prefix+".replicaCheckInterval", "5s")
+	v.SetDefault(prefix+".embedded.path", "data/eir.db")
+	v.SetDefault(prefix+".embedded.lockTimeout", "5s")
+	v.SetDefault(prefix+".embedded.noSync", false)
+	v.SetDefault(prefix+".memory.dataDir", "")
+	v.SetDefault(prefix+".memory.fsync", "interval")
+	v.SetDefault(prefix+".memory.fsyncInterval", "1s")
+	v.SetDefault(prefix+".memory.snapshotInterval", "10m")
+	v.SetDefault(prefix+".memory.compactSize", 64<<20)
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// Validate Server configuration
@@ -249,6 +486,31 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("cache config: %w", err)
 	}
 
+	// Validate Check counter configuration
+	if err := c.Checks.Validate(); err != nil {
+		return fmt.Errorf("checks config: %w", err)
+	}
+
+	// Validate Snapshot configuration
+	if err := c.Snapshot.Validate(); err != nil {
+		return fmt.Errorf("snapshot config: %w", err)
+	}
+
+	// Validate Maintenance configuration
+	if err := c.Maintenance.Validate(); err != nil {
+		return fmt.Errorf("maintenance config: %w", err)
+	}
+
+	// Validate DualWrite configuration
+	if err := c.DualWrite.Validate(); err != nil {
+		return fmt.Errorf("dualWrite config: %w", err)
+	}
+
+	// Validate Audit configuration
+	if err := c.Audit.Validate(); err != nil {
+		return fmt.Errorf("audit config: %w", err)
+	}
+
 	// Validate Logging configuration
 	if err := c.Logging.Validate(); err != nil {
 		return fmt.Errorf("logging config: %w", err)
@@ -281,11 +543,57 @@ func (c *ServerConfig) Validate() error {
 	if c.IdleTimeout < 0 {
 		return fmt.Errorf("idleTimeout must be positive")
 	}
+	for i, token := range c.APITokens {
+		if token.Actor == "" || token.Token == "" {
+			return fmt.Errorf("apiTokens[%d]: actor and token are required", i)
+		}
+	}
+	for i, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trustedProxies[%d]: %q is neither an IP nor a CIDR block", i, proxy)
+		}
+	}
 	return nil
 }
 
 // Validate validates the DatabaseConfig
+// Only the settings of the selected backend are checked.
 func (c *DatabaseConfig) Validate() error {
+	if c.ConnectRetries < 0 {
+		return fmt.Errorf("connectRetries must be non-negative")
+	}
+	if c.ConnectRetryInterval < 0 {
+		return fmt.Errorf("connectRetryInterval must be non-negative")
+	}
+	if c.ConnectTimeout < 0 {
+		return fmt.Errorf("connectTimeout must be non-negative")
+	}
+
+	switch c.Type {
+	case "memory":
+		if err := c.Memory.Validate(); err != nil {
+			return fmt.Errorf("memory: %w", err)
+		}
+		return nil
+	case "postgres":
+		return c.validatePostgres()
+	case "mongodb":
+		if err := c.Mongo.Validate(); err != nil {
+			return fmt.Errorf("mongo: %w", err)
+		}
+		return nil
+	case "embedded":
+		if err := c.Embedded.Validate(); err != nil {
+			return fmt.Errorf("embedded: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("type must be one of: memory, postgres, mongodb, embedded")
+	}
+}
+
+// validatePostgres validates the PostgreSQL connection settings
+func (c *DatabaseConfig) validatePostgres() error {
 	if c.Host == "" {
 		return fmt.Errorf("host is required")
 	}
@@ -316,6 +624,87 @@ func (c *DatabaseConfig) Validate() error {
 	if c.MaxIdleConns > c.MaxOpenConns {
 		return fmt.Errorf("maxIdleConns (%d) cannot exceed maxOpenConns (%d)", c.MaxIdleConns, c.MaxOpenConns)
 	}
+	if c.QueryTimeout < 0 {
+		return fmt.Errorf("queryTimeout must be non-negative")
+	}
+	for i, replica := range c.Replicas {
+		if replica.Host == "" {
+			return fmt.Errorf("replicas[%d]: host is required", i)
+		}
+		if replica.Port < 1 || replica.Port > 65535 {
+			return fmt.Errorf("replicas[%d]: port must be between 1 and 65535, got %d", i, replica.Port)
+		}
+	}
+	if c.ReplicaMaxLag < 0 {
+		return fmt.Errorf("replicaMaxLag must be non-negative")
+	}
+	if len(c.Replicas) > 0 && c.ReplicaCheckInterval < time.Second {
+		return fmt.Errorf("replicaCheckInterval must be at least 1s")
+	}
+	return nil
+}
+
+// Validate validates the MongoConfig
+func (c *MongoConfig) Validate() error {
+	if c.URI == "" {
+		return fmt.Errorf("uri is required")
+	}
+	if c.Database == "" {
+		return fmt.Errorf("database is required")
+	}
+	if c.MaxPoolSize < 1 {
+		return fmt.Errorf("maxPoolSize must be at least 1")
+	}
+	if c.MinPoolSize < 0 {
+		return fmt.Errorf("minPoolSize must be non-negative")
+	}
+	if c.MinPoolSize > c.MaxPoolSize {
+		return fmt.Errorf("minPoolSize (%d) cannot exceed maxPoolSize (%d)", c.MinPoolSize, c.MaxPoolSize)
+	}
+	validReadPreferences := map[string]bool{
+		"":                   true,
+		"primary":            true,
+		"secondary":          true,
+		"primaryPreferred":   true,
+		"secondaryPreferred": true,
+	}
+	if !validReadPreferences[c.ReadPreference] {
+		return fmt.Errorf("readPreference must be one of: primary, secondary, primaryPreferred, secondaryPreferred")
+	}
+	return nil
+}
+
+// Validate validates the EmbeddedConfig
+func (c *EmbeddedConfig) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if c.LockTimeout < 0 {
+		return fmt.Errorf("lockTimeout must be non-negative")
+	}
+	return nil
+}
+
+// Validate validates the MemoryConfig; nothing is checked while persistence is off
+func (c *MemoryConfig) Validate() error {
+	if c.DataDir == "" {
+		return nil
+	}
+	switch c.Fsync {
+	case "always", "none":
+	case "interval":
+		if c.FsyncInterval <= 0 {
+			return fmt.Errorf("fsyncInterval must be positive when fsync is interval")
+		}
+	default:
+		return fmt.Errorf("fsync must be one of: always, interval, none")
+	}
+	if c.SnapshotInterval < 0 {
+		return fmt.Errorf("snapshotInterval must be non-negative")
+	}
+	if c.CompactSize < 0 {
+		return fmt.Errorf("compactSize must be non-negative")
+	}
 	return nil
 }
 
@@ -365,6 +754,9 @@ func (c *DiameterConfig) Validate() error {
 
 // Validate validates the CacheConfig
 func (c *CacheConfig) Validate() error {
+	if err := c.Decision.Validate(); err != nil {
+		return fmt.Errorf("decision: %w", err)
+	}
 	if !c.Enabled {
 		return nil // No validation needed if cache is disabled
 	}
@@ -383,6 +775,164 @@ func (c *CacheConfig) Validate() error {
 		if c.Redis.Port < 1 || c.Redis.Port > 65535 {
 			return fmt.Errorf("redis.port must be between 1 and 65535, got %d", c.Redis.Port)
 		}
+		if c.Redis.TTL <= 0 {
+			return fmt.Errorf("redis.ttl must be positive")
+		}
+		if c.Redis.PoolSize < 0 {
+			return fmt.Errorf("redis.poolSize cannot be negative")
+		}
+		if c.Redis.MinIdleConns < 0 {
+			return fmt.Errorf("redis.minIdleConns cannot be negative")
+		}
+	}
+	return nil
+}
+
+// Validate validates the DecisionCacheConfig
+func (c *DecisionCacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Shards < 1 {
+		return fmt.Errorf("shards must be at least 1")
+	}
+	if c.Capacity < c.Shards {
+		return fmt.Errorf("capacity (%d) must be at least shards (%d)", c.Capacity, c.Shards)
+	}
+	if c.PositiveTTL <= 0 {
+		return fmt.Errorf("positiveTTL must be positive")
+	}
+	if c.NegativeTTL < 0 {
+		return fmt.Errorf("negativeTTL cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates the CheckCountersConfig
+func (c *CheckCountersConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.FlushInterval <= 0 {
+		return fmt.Errorf("flushInterval must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batchSize must be positive")
+	}
+	if c.MaxPending <= 0 {
+		return fmt.Errorf("maxPending must be positive")
+	}
+	return nil
+}
+
+// Validate validates the SnapshotConfig
+func (c *SnapshotConfig) Validate() error {
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must be non-negative")
+	}
+	if !c.Enabled {
+		return nil
+	}
+	if _, err := cron.ParseStandard(c.Schedule); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	return nil
+}
+
+// Validate validates the MaintenanceConfig
+func (c *MaintenanceConfig) Validate() error {
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batchSize must be positive")
+	}
+	if c.BatchPause < 0 {
+		return fmt.Errorf("batchPause must be non-negative")
+	}
+	if c.AuditRetention < 0 || c.HistoryRetention < 0 || c.SnapshotRetention < 0 || c.TombstoneRetention < 0 {
+		return fmt.Errorf("retention periods must be non-negative")
+	}
+	if (c.WindowStart == "") != (c.WindowEnd == "") {
+		return fmt.Errorf("windowStart and windowEnd must be set together")
+	}
+	for name, value := range map[string]string{"windowStart": c.WindowStart, "windowEnd": c.WindowEnd} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", value); err != nil {
+			return fmt.Errorf("%s must be HH:MM, got %q", name, value)
+		}
+	}
+	if !c.Enabled {
+		return nil
+	}
+	if _, err := cron.ParseStandard(c.Schedule); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	return nil
+}
+
+// Validate validates the DualWriteConfig; nothing is checked while it is disabled
+func (c *DualWriteConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Target.Type {
+	case "postgres", "mongodb", "embedded":
+	default:
+		return fmt.Errorf("target type must be one of: postgres, mongodb, embedded")
+	}
+	if err := c.Target.Validate(); err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	return nil
+}
+
+// Validate validates the AuditConfig; protection settings are only checked
+// while protection is on
+func (c *AuditConfig) Validate() error {
+	if err := c.Chain.Validate(); err != nil {
+		return fmt.Errorf("chain: %w", err)
+	}
+	if !c.Protect {
+		return nil
+	}
+	for name, mode := range map[string]string{"userName": c.UserName, "supi": c.SUPI, "gpsi": c.GPSI} {
+		switch mode {
+		case "clear", "pseudonym", "encrypted":
+		default:
+			return fmt.Errorf("%s must be one of: clear, pseudonym, encrypted", name)
+		}
+	}
+	if _, ok := c.Keys[c.ActiveKey]; !ok {
+		return fmt.Errorf("activeKey %q must be one of keys", c.ActiveKey)
+	}
+	for id, key := range c.Keys {
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("key %q must be 32 bytes encoded as base64", id)
+		}
+	}
+	return nil
+}
+
+// Validate validates the AuditChainConfig
+func (c *AuditChainConfig) Validate() error {
+	if c.CheckpointInterval < 0 {
+		return fmt.Errorf("checkpointInterval must not be negative")
+	}
+	if c.CheckpointInterval > 0 && c.SigningKey == "" {
+		return fmt.Errorf("signingKey is required when checkpoints are enabled")
+	}
+	if c.SigningKey != "" {
+		if c.SigningKeyID == "" {
+			return fmt.Errorf("signingKeyId is required with a signingKey")
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(c.SigningKey); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("signingKey must be 32 bytes encoded as base64")
+		}
+	}
+	for id, key := range c.PublicKeys {
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 32 {
+			return fmt.Errorf("public key %q must be 32 bytes encoded as base64", id)
+		}
 	}
 	return nil
 }
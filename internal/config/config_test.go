@@ -0,0 +1,341 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func validPostgresDatabaseConfig() DatabaseConfig {
+	return DatabaseConfig{
+		Type:         "postgres",
+		Host:         "localhost",
+		Port:         5432,
+		User:         "eir",
+		Database:     "eir",
+		SSLMode:      "disable",
+		MaxOpenConns: 25,
+		MaxIdleConns: 5,
+	}
+}
+
+func TestDatabaseConfig_Validate_Memory(t *testing.T) {
+	// Postgres fields are irrelevant for the memory backend
+	cfg := DatabaseConfig{Type: "memory"}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail for memory backend, got: %v", err)
+	}
+}
+
+func TestDatabaseConfig_Validate_MemoryPersistence(t *testing.T) {
+	cfg := DatabaseConfig{
+		Type:   "memory",
+		Memory: MemoryConfig{DataDir: "data/memory", Fsync: "interval", FsyncInterval: time.Second},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid memory persistence, got: %v", err)
+	}
+
+	cfg.Memory.Fsync = "sometimes"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with an unknown fsync policy")
+	}
+
+	cfg.Memory.DataDir = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should skip persistence settings when dataDir is empty, got: %v", err)
+	}
+}
+
+func TestDatabaseConfig_Validate_Postgres(t *testing.T) {
+	cfg := validPostgresDatabaseConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid postgres config, got: %v", err)
+	}
+
+	cfg.Host = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when postgres host is missing")
+	}
+}
+
+func TestDatabaseConfig_Validate_PostgresReplicas(t *testing.T) {
+	cfg := validPostgresDatabaseConfig()
+	cfg.Replicas = []ReplicaConfig{{Host: "db-replica-1", Port: 5432}}
+	cfg.ReplicaMaxLag = 10 * time.Second
+	cfg.ReplicaCheckInterval = 5 * time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid replicas, got: %v", err)
+	}
+
+	cfg.Replicas[0].Port = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when a replica port is missing")
+	}
+
+	cfg.Replicas[0].Port = 5432
+	cfg.ReplicaCheckInterval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with replicas and no check interval")
+	}
+}
+
+func TestDatabaseConfig_Validate_Mongo(t *testing.T) {
+	cfg := DatabaseConfig{
+		Type: "mongodb",
+		Mongo: MongoConfig{
+			URI:            "mongodb://localhost:27017",
+			Database:       "eir",
+			MaxPoolSize:    100,
+			MinPoolSize:    10,
+			ReadPreference: "primary",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid mongo config, got: %v", err)
+	}
+
+	cfg.Mongo.MinPoolSize = 200
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when minPoolSize exceeds maxPoolSize")
+	}
+}
+
+func TestDatabaseConfig_Validate_Embedded(t *testing.T) {
+	cfg := DatabaseConfig{
+		Type:     "embedded",
+		Embedded: EmbeddedConfig{Path: "data/eir.db", LockTimeout: 5 * time.Second},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid embedded config, got: %v", err)
+	}
+
+	cfg.Embedded.Path = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when the embedded path is missing")
+	}
+}
+
+func TestDatabaseConfig_Validate_UnknownType(t *testing.T) {
+	cfg := validPostgresDatabaseConfig()
+	cfg.Type = "oracle"
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail for unsupported database type")
+	}
+}
+
+func TestDatabaseConfig_Validate_NegativeRetry(t *testing.T) {
+	cfg := validPostgresDatabaseConfig()
+	cfg.ConnectRetryInterval = -time.Second
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with negative connectRetryInterval")
+	}
+}
+
+func TestLoad_DefaultsToMemoryBackend(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Database.Type != "memory" {
+		t.Errorf("Expected default database type memory, got %q", cfg.Database.Type)
+	}
+	if cfg.Database.Mongo.URI == "" {
+		t.Error("Expected a default mongo URI")
+	}
+}
+
+func TestServerConfig_Validate_ActorSources(t *testing.T) {
+	cfg := ServerConfig{
+		Port:           8080,
+		APITokens:      []APITokenConfig{{Actor: "ops", Token: "secret"}},
+		TrustedProxies: []string{"10.0.0.1", "192.168.0.0/16", "::1"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid tokens and proxies, got: %v", err)
+	}
+
+	cfg.APITokens[0].Actor = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when a token names no actor")
+	}
+
+	cfg.APITokens[0].Actor = "ops"
+	cfg.TrustedProxies = append(cfg.TrustedProxies, "proxy.local")
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with a trusted proxy that is not an address")
+	}
+}
+
+func TestLoad_APITokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "server:\n  port: 8080\n  apiTokens:\n    - actor: ops\n      token: secret\n  trustedProxies: [\"10.0.0.0/8\"]\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Server.APITokens) != 1 || cfg.Server.APITokens[0] != (APITokenConfig{Actor: "ops", Token: "secret"}) {
+		t.Errorf("Expected the ops token, got %+v", cfg.Server.APITokens)
+	}
+	if len(cfg.Server.TrustedProxies) != 1 || cfg.Server.TrustedProxies[0] != "10.0.0.0/8" {
+		t.Errorf("Expected one trusted proxy, got %v", cfg.Server.TrustedProxies)
+	}
+}
+
+func TestCacheConfig_Validate_Redis(t *testing.T) {
+	cfg := CacheConfig{
+		Enabled:  true,
+		Provider: "redis",
+		Redis:    RedisConfig{Host: "localhost", Port: 6379, TTL: 5 * time.Minute},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid redis config, got: %v", err)
+	}
+
+	cfg.Redis.TTL = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when redis ttl is not positive")
+	}
+
+	cfg.Redis.TTL = time.Minute
+	cfg.Redis.PoolSize = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with a negative pool size")
+	}
+
+	cfg.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should skip a disabled cache, got: %v", err)
+	}
+}
+
+func TestDecisionCacheConfig_Validate(t *testing.T) {
+	cfg := DecisionCacheConfig{Enabled: true, Shards: 16, Capacity: 1000, PositiveTTL: time.Minute, NegativeTTL: 0}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should accept a zero negative TTL, got: %v", err)
+	}
+
+	cfg.Capacity = 8
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail when capacity is below the shard count")
+	}
+
+	// The decision cache is validated even when the equipment cache is off
+	cache := CacheConfig{Enabled: false, Decision: cfg}
+	if err := cache.Validate(); err == nil {
+		t.Error("CacheConfig.Validate should report decision cache errors")
+	}
+}
+
+func TestCheckCountersConfig_Validate(t *testing.T) {
+	cfg := CheckCountersConfig{}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should skip disabled check counters, got: %v", err)
+	}
+
+	cfg = CheckCountersConfig{Enabled: true, FlushInterval: 10 * time.Second, BatchSize: 500, MaxPending: 1000}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with valid settings, got: %v", err)
+	}
+
+	for name, broken := range map[string]func(c *CheckCountersConfig){
+		"flushInterval": func(c *CheckCountersConfig) { c.FlushInterval = 0 },
+		"batchSize":     func(c *CheckCountersConfig) { c.BatchSize = 0 },
+		"maxPending":    func(c *CheckCountersConfig) { c.MaxPending = -1 },
+	} {
+		invalid := cfg
+		broken(&invalid)
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate should fail with an invalid %s", name)
+		}
+	}
+}
+
+func TestSnapshotConfig_Validate(t *testing.T) {
+	cfg := SnapshotConfig{Schedule: "not a schedule"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should skip the schedule while snapshots are disabled, got: %v", err)
+	}
+
+	cfg.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with an invalid cron expression")
+	}
+
+	cfg.Schedule = "30 2 * * 0"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with a valid cron expression, got: %v", err)
+	}
+
+	cfg.Retention = -time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with a negative retention")
+	}
+}
+
+func TestMaintenanceConfig_Validate(t *testing.T) {
+	valid := MaintenanceConfig{
+		Schedule:       "0 2 * * *",
+		WindowStart:    "22:00",
+		WindowEnd:      "04:00",
+		BatchSize:      1000,
+		AuditRetention: 24 * time.Hour,
+		Enabled:        true,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate should not fail with a window spanning midnight, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*MaintenanceConfig)
+	}{
+		{"zero batch size", func(c *MaintenanceConfig) { c.BatchSize = 0 }},
+		{"negative pause", func(c *MaintenanceConfig) { c.BatchPause = -time.Second }},
+		{"negative retention", func(c *MaintenanceConfig) { c.HistoryRetention = -time.Hour }},
+		{"window without end", func(c *MaintenanceConfig) { c.WindowEnd = "" }},
+		{"malformed window", func(c *MaintenanceConfig) { c.WindowStart = "10pm" }},
+		{"invalid schedule", func(c *MaintenanceConfig) { c.Schedule = "nightly" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate should fail with %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestDualWriteConfig_Validate(t *testing.T) {
+	cfg := DualWriteConfig{}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should skip the target while dual-write is disabled, got: %v", err)
+	}
+
+	cfg.Enabled = true
+	cfg.Target = DatabaseConfig{Type: "memory"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with a memory target")
+	}
+
+	cfg.Target = DatabaseConfig{Type: "embedded"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate should fail with an embedded target without a path")
+	}
+
+	cfg.Target.Embedded.Path = "data/eir.db"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate should not fail with a valid embedded target, got: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audit records are hash-chained per UTC day. Each record carries a hash over
+// its content, its place in the chain and the hash of the record before it,
+// so altering, removing or inserting a record breaks every later link.
+
+// AuditChainPartitionLayout formats the partition of an audit from its check time
+const AuditChainPartitionLayout = "2006-01-02"
+
+// AuditChainHead is the last link of a partition's chain
+type AuditChainHead struct {
+	Partition string `json:"partition" db:"chain_partition" bson:"chain_partition"`
+	Seq       int64  `json:"seq" db:"seq" bson:"chain_seq"`
+	Hash      string `json:"hash" db:"hash" bson:"hash"`
+}
+
+// AuditCheckpoint is a signed statement of the head of a partition's chain.
+// Records up to Seq can no longer be rewritten without the signing key, even
+// by someone able to recompute the whole chain.
+type AuditCheckpoint struct {
+	ID        int64     `json:"id" db:"id" bson:"-"`
+	Partition string    `json:"partition" db:"chain_partition" bson:"chain_partition"`
+	Seq       int64     `json:"seq" db:"seq" bson:"seq"`
+	Hash      string    `json:"hash" db:"hash" bson:"hash"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" bson:"created_at"`
+	KeyID     string    `json:"key_id" db:"key_id" bson:"key_id"`
+	Signature string    `json:"signature" db:"signature" bson:"signature"` // Base64 Ed25519 signature of SignedContent
+}
+
+// SignedContent returns the bytes a checkpoint's signature covers
+func (c *AuditCheckpoint) SignedContent() []byte {
+	return []byte(fmt.Sprintf("eir-audit-checkpoint/v1\n%s\n%d\n%s\n%d", c.Partition, c.Seq, c.Hash, c.CreatedAt.UnixMilli()))
+}
+
+// AuditChainPartition returns the partition of an audit checked at t
+func AuditChainPartition(t time.Time) string {
+	return t.UTC().Format(AuditChainPartitionLayout)
+}
+
+// PrepareChainLink sets CheckTime to the millisecond precision every backend
+// stores, defaulting it to now, and sets the partition the audit belongs to.
+// It returns the partition.
+func (a *AuditLog) PrepareChainLink() string {
+	if a.CheckTime.IsZero() {
+		a.CheckTime = time.Now()
+	}
+	a.CheckTime = a.CheckTime.UTC().Truncate(time.Millisecond)
+	a.ChainPartition = AuditChainPartition(a.CheckTime)
+	return a.ChainPartition
+}
+
+// LinkTo places the audit after head, the current head of its partition, or
+// at the start of the partition when head is nil
+func (a *AuditLog) LinkTo(head *AuditChainHead) {
+	a.ChainSeq, a.PrevHash = 1, ""
+	if head != nil {
+		a.ChainSeq, a.PrevHash = head.Seq+1, head.Hash
+	}
+}
+
+// ChainHead returns the link the next audit of the partition follows
+func (a *AuditLog) ChainHead() *AuditChainHead {
+	return &AuditChainHead{Partition: a.ChainPartition, Seq: a.ChainSeq, Hash: a.Hash}
+}
+
+// ChainHash returns the hash of a plain audit
+func (a *AuditLog) ChainHash() string {
+	return (&AuditLogExtended{AuditLog: *a}).ChainHash()
+}
+
+// ChainHash returns the hash of an audit with its extended metadata. It
+// covers every stored field except the ID assigned by the database and the
+// change history, which is recorded separately.
+func (a *AuditLogExtended) ChainHash() string {
+	content := struct {
+		Partition        string                 `json:"partition"`
+		Seq              int64                  `json:"seq"`
+		PrevHash         string                 `json:"prev_hash"`
+		IMEI             string                 `json:"imei"`
+		IMEISV           *string                `json:"imeisv"`
+		Status           EquipmentStatus        `json:"status"`
+		CheckTime        int64                  `json:"check_time"` // Unix milliseconds
+		OriginHost       *string                `json:"origin_host"`
+		OriginRealm      *string                `json:"origin_realm"`
+		UserName         *string                `json:"user_name"`
+		SUPI             *string                `json:"supi"`
+		GPSI             *string                `json:"gpsi"`
+		SUPIIndex        *string                `json:"supi_index"`
+		RequestSource    string                 `json:"request_source"`
+		SessionID        *string                `json:"session_id"`
+		ResultCode       *int32                 `json:"result_code"`
+		IPAddress        *string                `json:"ip_address"`
+		UserAgent        *string                `json:"user_agent"`
+		AdditionalData   map[string]interface{} `json:"additional_data"`
+		ProcessingTimeMs *int64                 `json:"processing_time_ms"`
+	}{
+		a.ChainPartition, a.ChainSeq, a.PrevHash,
+		a.IMEI, a.IMEISV, a.Status, a.CheckTime.UnixMilli(), a.OriginHost, a.OriginRealm,
+		a.UserName, a.SUPI, a.GPSI, a.SUPIIndex, a.RequestSource, a.SessionID, a.ResultCode,
+		a.IPAddress, a.UserAgent, a.AdditionalData, a.ProcessingTimeMs,
+	}
+
+	// Struct fields marshal in declaration order and map keys sorted, so the
+	// encoding is the same on every backend
+	data, err := json.Marshal(content)
+	if err != nil {
+		// Only AdditionalData can fail to marshal; hash its description instead
+		content.AdditionalData = map[string]interface{}{"unencodable": fmt.Sprint(a.AdditionalData)}
+		data, _ = json.Marshal(content)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChainBreak locates the first link of a chain that failed verification
+type AuditChainBreak struct {
+	Partition string `json:"partition"`
+	Seq       int64  `json:"seq"`                // Chain position of the failing record or checkpoint
+	AuditID   int64  `json:"audit_id,omitempty"` // Zero when the record is missing
+	Reason    string `json:"reason"`
+}
+
+// AuditChainReport is the outcome of verifying the audit hash chain
+type AuditChainReport struct {
+	Partitions  int              `json:"partitions"`
+	Records     int64            `json:"records"`
+	Checkpoints int              `json:"checkpoints"`
+	Break       *AuditChainBreak `json:"break,omitempty"` // Nil when every link verified
+}
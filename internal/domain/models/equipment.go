@@ -15,6 +15,12 @@ const (
 	EquipmentStatusGreylisted  EquipmentStatus = "GREYLISTED"  // Under observation/tracking
 )
 
+// Outcomes a check reports in place of a status when it finds none
+const (
+	CheckOutcomeUnknown  EquipmentStatus = "unknown"  // In no list
+	CheckOutcomeOverload EquipmentStatus = "overload" // Rejected while overloaded
+)
+
 // DiameterEquipmentStatus represents Diameter AVP values for Equipment-Status (AVP 1445)
 type DiameterEquipmentStatus int32
 
@@ -26,18 +32,35 @@ const (
 
 // Equipment represents a mobile equipment entity
 type Equipment struct {
-	ID               int64           `json:"id" db:"id"`
-	IMEI             string          `json:"imei" db:"imei"`
-	IMEISV           *string         `json:"imeisv,omitempty" db:"imeisv"`
-	Status           EquipmentStatus `json:"status" db:"status"`
-	Reason           *string         `json:"reason,omitempty" db:"reason"`
-	LastUpdated      time.Time       `json:"last_updated" db:"last_updated"`
-	LastCheckTime    *time.Time      `json:"last_check_time,omitempty" db:"last_check_time"`
-	CheckCount       int64           `json:"check_count" db:"check_count"`
-	AddedBy          string          `json:"added_by" db:"added_by"`
-	Metadata         *string         `json:"metadata,omitempty" db:"metadata"`
-	ManufacturerTAC  *string         `json:"manufacturer_tac,omitempty" db:"manufacturer_tac"`
-	ManufacturerName *string         `json:"manufacturer_name,omitempty" db:"manufacturer_name"`
+	ID               int64           `json:"id" db:"id" bson:"id"`
+	IMEI             string          `json:"imei" db:"imei" bson:"imei"`
+	IMEISV           *string         `json:"imeisv,omitempty" db:"imeisv" bson:"imeisv"`
+	Status           EquipmentStatus `json:"status" db:"status" bson:"status"`
+	Reason           *string         `json:"reason,omitempty" db:"reason" bson:"reason"`
+	LastUpdated      time.Time       `json:"last_updated" db:"last_updated" bson:"last_updated"`
+	LastCheckTime    *time.Time      `json:"last_check_time,omitempty" db:"last_check_time" bson:"last_check_time"`
+	CheckCount       int64           `json:"check_count" db:"check_count" bson:"check_count"`
+	AddedBy          string          `json:"added_by" db:"added_by" bson:"added_by"`
+	Metadata         *string         `json:"metadata,omitempty" db:"metadata" bson:"metadata"`
+	ManufacturerTAC  *string         `json:"manufacturer_tac,omitempty" db:"manufacturer_tac" bson:"manufacturer_tac"`
+	ManufacturerName *string         `json:"manufacturer_name,omitempty" db:"manufacturer_name" bson:"manufacturer_name"`
+	Version          int64           `json:"version" db:"version" bson:"version"`                    // Advanced by every update, for optimistic locking
+	DeletedAt        *time.Time      `json:"deleted_at,omitempty" db:"deleted_at" bson:"deleted_at"` // Set while the record is a tombstone
+	DeletedBy        *string         `json:"deleted_by,omitempty" db:"deleted_by" bson:"deleted_by"`
+}
+
+// IsDeleted reports whether the record is a tombstone left by a delete
+func (e *Equipment) IsDeleted() bool {
+	return e.DeletedAt != nil
+}
+
+// AddChecks adds count checks to the counter, the last of them at last. The
+// last check time only moves forward, so flushes may arrive in any order.
+func (e *Equipment) AddChecks(count int64, last time.Time) {
+	e.CheckCount += count
+	if e.LastCheckTime == nil || last.After(*e.LastCheckTime) {
+		e.LastCheckTime = &last
+	}
 }
 
 // AuditLog represents an audit entry for equipment check operations
@@ -52,9 +75,16 @@ type AuditLog struct {
 	UserName      *string         `json:"user_name,omitempty" db:"user_name"`
 	SUPI          *string         `json:"supi,omitempty" db:"supi"`
 	GPSI          *string         `json:"gpsi,omitempty" db:"gpsi"`
+	SUPIIndex     *string         `json:"supi_index,omitempty" db:"supi_index" bson:"supi_index,omitempty"` // Pseudonym of an encrypted SUPI, for lookups
 	RequestSource string          `json:"request_source" db:"request_source"` // "DIAMETER_S13", "HTTP_5G", etc.
 	SessionID     *string         `json:"session_id,omitempty" db:"session_id"`
 	ResultCode    *int32          `json:"result_code,omitempty" db:"result_code"`
+
+	// Hash chain link, set by the repository when the record is written
+	ChainPartition string `json:"chain_partition,omitempty" db:"chain_partition" bson:"chain_partition,omitempty"` // UTC day of CheckTime
+	ChainSeq       int64  `json:"chain_seq,omitempty" db:"chain_seq" bson:"chain_seq,omitempty"`
+	PrevHash       string `json:"prev_hash,omitempty" db:"prev_hash" bson:"prev_hash,omitempty"` // Hash of the previous record of the partition
+	Hash           string `json:"hash,omitempty" db:"hash" bson:"hash,omitempty"`
 }
 
 // IMEI validation constants
@@ -150,6 +180,41 @@ func ValidateStatus(status EquipmentStatus) error {
 	}
 }
 
+// StatusList names the IMEI or the TAC list, whose legacy colors differ
+type StatusList string
+
+const (
+	ImeiList StatusList = "imei" // Legacy colors "w", "b" and "g"
+	TacList  StatusList = "tac"  // Legacy colors "white", "black" and "grey"
+)
+
+// legacyStatusCodes are the colors each list used before it stored status names
+var legacyStatusCodes = map[StatusList]map[string]EquipmentStatus{
+	ImeiList: {"w": EquipmentStatusWhitelisted, "b": EquipmentStatusBlacklisted, "g": EquipmentStatusGreylisted},
+	TacList:  {"white": EquipmentStatusWhitelisted, "black": EquipmentStatusBlacklisted, "grey": EquipmentStatusGreylisted},
+}
+
+// ParseEquipmentStatus returns the status named by s, either a status name
+// or one of the legacy colors of list
+func ParseEquipmentStatus(list StatusList, s string) (EquipmentStatus, error) {
+	if status := EquipmentStatus(s); ValidateStatus(status) == nil {
+		return status, nil
+	}
+	if status, ok := legacyStatusCodes[list][s]; ok {
+		return status, nil
+	}
+	return "", ErrInvalidStatus
+}
+
+// NormalizeStatus returns the status a legacy color of list stands for. Other
+// values are returned as they are for ValidateStatus to judge.
+func NormalizeStatus(list StatusList, status EquipmentStatus) EquipmentStatus {
+	if parsed, err := ParseEquipmentStatus(list, string(status)); err == nil {
+		return parsed
+	}
+	return status
+}
+
 // ToDialDialStatus converts EquipmentStatus to Diameter Enumerated value
 func ToDialDialStatus(status EquipmentStatus) DiameterEquipmentStatus {
 	switch status {
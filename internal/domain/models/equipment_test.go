@@ -147,6 +147,44 @@ func TestValidateStatus(t *testing.T) {
 	}
 }
 
+func TestParseEquipmentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    StatusList
+		code    string
+		want    EquipmentStatus
+		wantErr bool
+	}{
+		{name: "Status name", list: TacList, code: "GREYLISTED", want: EquipmentStatusGreylisted},
+		{name: "Legacy IMEI color", list: ImeiList, code: "b", want: EquipmentStatusBlacklisted},
+		{name: "Legacy TAC color", list: TacList, code: "white", want: EquipmentStatusWhitelisted},
+		{name: "IMEI color on the TAC list", list: TacList, code: "g", wantErr: true},
+		{name: "TAC color on the IMEI list", list: ImeiList, code: "black", wantErr: true},
+		{name: "Unknown code", list: ImeiList, code: "red", wantErr: true},
+		{name: "Empty", list: ImeiList, code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEquipmentStatus(tt.list, tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEquipmentStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEquipmentStatus() = %v, want %v", got, tt.want)
+			}
+
+			normalized := NormalizeStatus(tt.list, EquipmentStatus(tt.code))
+			if !tt.wantErr && normalized != tt.want {
+				t.Errorf("NormalizeStatus() = %v, want %v", normalized, tt.want)
+			}
+			if tt.wantErr && normalized != EquipmentStatus(tt.code) {
+				t.Errorf("NormalizeStatus() = %v, want %q unchanged", normalized, tt.code)
+			}
+		})
+	}
+}
+
 func TestToDialDialStatus(t *testing.T) {
 	tests := []struct {
 		name   string
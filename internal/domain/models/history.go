@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // ChangeType represents the type of change made to a record
 type ChangeType string
@@ -14,22 +19,50 @@ const (
 
 // EquipmentHistory represents a historical record of equipment changes
 type EquipmentHistory struct {
-	ID               int64          `json:"id" bson:"_id,omitempty"`
-	IMEI             string         `json:"imei" bson:"imei"`
-	ChangeType       ChangeType     `json:"change_type" bson:"change_type"`
-	ChangedAt        time.Time      `json:"changed_at" bson:"changed_at"`
-	ChangedBy        string         `json:"changed_by" bson:"changed_by"`
-	PreviousStatus   *EquipmentStatus `json:"previous_status,omitempty" bson:"previous_status,omitempty"`
-	NewStatus        EquipmentStatus  `json:"new_status" bson:"new_status"`
-	PreviousReason   *string        `json:"previous_reason,omitempty" bson:"previous_reason,omitempty"`
-	NewReason        *string        `json:"new_reason,omitempty" bson:"new_reason,omitempty"`
-	ChangeDetails    map[string]interface{} `json:"change_details,omitempty" bson:"change_details,omitempty"`
-	SessionID        *string        `json:"session_id,omitempty" bson:"session_id,omitempty"`
+	ID             int64            `json:"id" db:"id" bson:"_id,omitempty"`
+	IMEI           string           `json:"imei" db:"imei" bson:"imei"`
+	ChangeType     ChangeType       `json:"change_type" db:"change_type" bson:"change_type"`
+	ChangedAt      time.Time        `json:"changed_at" db:"changed_at" bson:"changed_at"`
+	ChangedBy      string           `json:"changed_by" db:"changed_by" bson:"changed_by"`
+	PreviousStatus *EquipmentStatus `json:"previous_status,omitempty" db:"previous_status" bson:"previous_status,omitempty"`
+	NewStatus      EquipmentStatus  `json:"new_status" db:"new_status" bson:"new_status"`
+	PreviousReason *string          `json:"previous_reason,omitempty" db:"previous_reason" bson:"previous_reason,omitempty"`
+	NewReason      *string          `json:"new_reason,omitempty" db:"new_reason" bson:"new_reason,omitempty"`
+	ChangeDetails  ChangeDetails    `json:"change_details,omitempty" db:"change_details" bson:"change_details,omitempty"`
+	SessionID      *string          `json:"session_id,omitempty" db:"session_id" bson:"session_id,omitempty"`
+}
+
+// ChangeDetails holds free-form details of a change, stored as a JSON document
+type ChangeDetails map[string]interface{}
+
+// Value implements driver.Valuer
+func (d ChangeDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan implements sql.Scanner
+func (d *ChangeDetails) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ChangeDetails", src)
+	}
+	return json.Unmarshal(data, d)
 }
 
 // AuditLogExtended extends AuditLog with additional tracking fields
 type AuditLogExtended struct {
-	AuditLog
+	AuditLog          `bson:",inline"`
 	IPAddress         *string                `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
 	UserAgent         *string                `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
 	ChangeHistory     *EquipmentHistory      `json:"change_history,omitempty" bson:"change_history,omitempty"`
@@ -37,16 +70,52 @@ type AuditLogExtended struct {
 	ProcessingTimeMs  *int64                 `json:"processing_time_ms,omitempty" bson:"processing_time_ms,omitempty"`
 }
 
+// Snapshot types
+const (
+	SnapshotTypeManual    = "MANUAL"     // Requested through the management API
+	SnapshotTypeScheduled = "SCHEDULED"  // Taken by the snapshot scheduler
+	SnapshotTypePreUpdate = "PRE_UPDATE" // Taken before a status change
+)
+
 // EquipmentSnapshot represents a point-in-time snapshot of equipment state
 type EquipmentSnapshot struct {
-	ID            int64           `json:"id" bson:"_id,omitempty"`
-	EquipmentID   int64           `json:"equipment_id" bson:"equipment_id"`
-	IMEI          string          `json:"imei" bson:"imei"`
-	SnapshotTime  time.Time       `json:"snapshot_time" bson:"snapshot_time"`
-	Status        EquipmentStatus `json:"status" bson:"status"`
-	Reason        *string         `json:"reason,omitempty" bson:"reason,omitempty"`
-	CheckCount    int64           `json:"check_count" bson:"check_count"`
-	Metadata      *string         `json:"metadata,omitempty" bson:"metadata,omitempty"`
-	CreatedBy     string          `json:"created_by" bson:"created_by"`
-	SnapshotType  string          `json:"snapshot_type" bson:"snapshot_type"` // "MANUAL", "SCHEDULED", "PRE_UPDATE"
+	ID           int64           `json:"id" db:"id" bson:"_id,omitempty"`
+	EquipmentID  int64           `json:"equipment_id" db:"equipment_id" bson:"equipment_id"`
+	IMEI         string          `json:"imei" db:"imei" bson:"imei"`
+	SnapshotTime time.Time       `json:"snapshot_time" db:"snapshot_time" bson:"snapshot_time"`
+	Status       EquipmentStatus `json:"status" db:"status" bson:"status"`
+	Reason       *string         `json:"reason,omitempty" db:"reason" bson:"reason,omitempty"`
+	CheckCount   int64           `json:"check_count" db:"check_count" bson:"check_count"`
+	Metadata     *string         `json:"metadata,omitempty" db:"metadata" bson:"metadata,omitempty"`
+	CreatedBy    string          `json:"created_by" db:"created_by" bson:"created_by"`
+	SnapshotType string          `json:"snapshot_type" db:"snapshot_type" bson:"snapshot_type"` // "MANUAL", "SCHEDULED", "PRE_UPDATE"
+}
+
+// StatusSource names the kind of provisioning behind a status
+type StatusSource string
+
+const (
+	StatusSourceIMEI      StatusSource = "IMEI"      // IMEI list entry
+	StatusSourceEquipment StatusSource = "EQUIPMENT" // equipment record
+	StatusSourceTAC       StatusSource = "TAC"       // TAC range
+)
+
+// StatusContribution is what one kind of provisioning said about an IMEI at a
+// point in time, citing the history entries (and snapshot) it was rebuilt from
+type StatusContribution struct {
+	Source    StatusSource        `json:"source"`
+	Status    EquipmentStatus     `json:"status"`
+	Effective bool                `json:"effective"`
+	History   []*EquipmentHistory `json:"history"`
+	Snapshot  *EquipmentSnapshot  `json:"snapshot,omitempty"`
+}
+
+// PointInTimeStatus is the status of an IMEI reconstructed for a past moment.
+// Status is nil when no provisioning covered the IMEI at that time.
+type PointInTimeStatus struct {
+	IMEI          string               `json:"imei"`
+	At            time.Time            `json:"at"`
+	Status        *EquipmentStatus     `json:"status,omitempty"`
+	Source        StatusSource         `json:"source,omitempty"`
+	Contributions []StatusContribution `json:"contributions"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// MaintenanceTrigger names what started a maintenance run
+type MaintenanceTrigger string
+
+const (
+	MaintenanceTriggerSchedule MaintenanceTrigger = "schedule"
+	MaintenanceTriggerManual   MaintenanceTrigger = "manual"
+)
+
+// DatasetPurge reports what a maintenance run purged from one dataset
+type DatasetPurge struct {
+	Dataset  string    `json:"dataset"` // "audits", "history" or "snapshots"
+	Before   time.Time `json:"before"`  // Records older than this were due
+	Purged   int64     `json:"purged"`
+	Batches  int       `json:"batches"`
+	Complete bool      `json:"complete"` // False when the run window closed or an error stopped it
+	Error    string    `json:"error,omitempty"`
+}
+
+// MaintenanceRun reports one run of the maintenance scheduler
+type MaintenanceRun struct {
+	Trigger    MaintenanceTrigger `json:"trigger"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Datasets   []DatasetPurge     `json:"datasets"`
+	Optimized  bool               `json:"optimized"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// MaintenanceStatus reports the state of the maintenance scheduler
+type MaintenanceStatus struct {
+	Running bool            `json:"running"`
+	NextRun *time.Time      `json:"next_run,omitempty"` // Nil when only manual runs happen
+	LastRun *MaintenanceRun `json:"last_run,omitempty"`
+}
@@ -0,0 +1,21 @@
+package ports
+
+import "context"
+
+// SystemActor is recorded as the author of changes whose requester is unknown
+const SystemActor = "SYSTEM"
+
+type actorKey struct{}
+
+// WithActor returns a context attributing the changes made with it to actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, or SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+)
+
+// AuditChainStore reads the hash chain audit repositories link every record
+// into, and keeps the signed checkpoints of its heads
+type AuditChainStore interface {
+	// GetAuditChainPartitions lists the partitions that have chained audits, oldest first
+	GetAuditChainPartitions(ctx context.Context) ([]string, error)
+
+	// GetAuditChainHead returns the last link of a partition, or nil when it has none
+	GetAuditChainHead(ctx context.Context, partition string) (*models.AuditChainHead, error)
+
+	// WalkAuditChain calls fn with the records of a partition in chain order,
+	// stopping at the first error fn returns
+	WalkAuditChain(ctx context.Context, partition string, fn func(audit *models.AuditLogExtended) error) error
+
+	// SaveAuditCheckpoint stores a signed checkpoint
+	SaveAuditCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error
+
+	// GetAuditCheckpoints returns the checkpoints of a partition in chain order
+	GetAuditCheckpoints(ctx context.Context, partition string) ([]*models.AuditCheckpoint, error)
+}
+
+// AuditChainAdapter is implemented by adapters whose audit chain can be read back
+type AuditChainAdapter interface {
+	GetAuditChainStore() AuditChainStore
+}
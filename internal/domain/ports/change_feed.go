@@ -0,0 +1,57 @@
+package ports
+
+import "context"
+
+// ChangeTable names a table (or collection) whose changes are broadcast to every replica
+type ChangeTable string
+
+const (
+	ChangeTableEquipment ChangeTable = "equipment"
+	ChangeTableImeiInfo  ChangeTable = "imei_info"
+	ChangeTableTacInfo   ChangeTable = "tac_info"
+)
+
+// ChangeTables lists every table covered by the change feed
+var ChangeTables = []ChangeTable{ChangeTableEquipment, ChangeTableImeiInfo, ChangeTableTacInfo}
+
+const (
+	ChangeOpInsert   = "INSERT"
+	ChangeOpUpdate   = "UPDATE"
+	ChangeOpDelete   = "DELETE"
+	ChangeOpTruncate = "TRUNCATE"
+	// ChangeOpResync is emitted after the feed reconnects, when changes may have been missed
+	ChangeOpResync = "RESYNC"
+)
+
+// ChangeEvent describes a committed change made by any replica
+type ChangeEvent struct {
+	Table ChangeTable
+	Op    string
+	// Keys holds the primary keys of the changed rows: IMEI for equipment,
+	// StartIMEI for imei_info and KeyTac for tac_info. Nil means any row of
+	// Table may have changed and local state derived from it must be rebuilt.
+	Keys []string
+}
+
+// ChangeFeed delivers list changes committed by any replica
+type ChangeFeed interface {
+	// Listen calls handler for every change until ctx is cancelled. It
+	// reconnects on its own and emits ChangeOpResync when events may have been lost.
+	Listen(ctx context.Context, handler func(ChangeEvent)) error
+}
+
+// ChangeFeedProvider is implemented by database adapters that can broadcast changes
+type ChangeFeedProvider interface {
+	// GetChangeFeed returns the change feed, or nil when it is disabled in the configuration
+	GetChangeFeed() ChangeFeed
+}
+
+// LookupIndex is replica-local state derived from a table, such as an in-memory
+// search structure, that must follow changes committed by other replicas
+type LookupIndex interface {
+	// Table returns the table the index is built from
+	Table() ChangeTable
+
+	// Reload refreshes the given keys; nil keys means rebuild the whole index
+	Reload(ctx context.Context, keys []string) error
+}
@@ -3,6 +3,7 @@ package ports
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // DatabaseType represents the type of database backend
@@ -11,10 +12,11 @@ type DatabaseType string
 const (
 	DatabaseTypePostgreSQL DatabaseType = "postgres"
 	DatabaseTypeMongoDB    DatabaseType = "mongodb"
+	DatabaseTypeEmbedded   DatabaseType = "embedded"
 )
 
 // DatabaseAdapter defines the unified interface for database operations
-// This provides a common abstraction over PostgreSQL and MongoDB implementations
+// This provides a common abstraction over the PostgreSQL, MongoDB and embedded implementations
 type DatabaseAdapter interface {
 	// Connect establishes a connection to the database
 	Connect(ctx context.Context) error
@@ -48,6 +50,33 @@ type DatabaseAdapter interface {
 	OptimizeDatabase(ctx context.Context) error
 }
 
+// MigratableAdapter is implemented by adapters whose schema is versioned by a MigrationManager
+type MigratableAdapter interface {
+	// GetMigrationManager returns the migration manager bound to the connected database
+	GetMigrationManager() MigrationManager
+}
+
+// BatchPurger is implemented by adapters that can purge old records in bounded
+// batches. Each call deletes at most limit records older than before, oldest
+// first, so retention never holds long locks on hot tables.
+type BatchPurger interface {
+	PurgeAuditsBatch(ctx context.Context, before time.Time, limit int) (int64, error)
+	PurgeHistoryBatch(ctx context.Context, before time.Time, limit int) (int64, error)
+	PurgeSnapshotsBatch(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// ExportableAdapter is implemented by adapters that can stream exports
+// straight from the database instead of paging through the repositories
+type ExportableAdapter interface {
+	GetDataExporter() DataExporter
+}
+
+// TransactionProvider begins transactions. Every DatabaseAdapter is one; the
+// memory backend provides its own.
+type TransactionProvider interface {
+	BeginTransaction(ctx context.Context) (Transaction, error)
+}
+
 // Transaction represents a database transaction
 type Transaction interface {
 	// Commit commits the transaction
@@ -56,11 +85,15 @@ type Transaction interface {
 	// Rollback rolls back the transaction
 	Rollback(ctx context.Context) error
 
-	// GetIMEIRepository returns a transactional IMEI repository
+	// GetIMEIRepository returns a transactional IMEI repository, including
+	// the IMEI and TAC logic operations
 	GetIMEIRepository() IMEIRepository
 
 	// GetAuditRepository returns a transactional audit repository
 	GetAuditRepository() AuditRepository
+
+	// GetHistoryRepository returns a transactional history repository
+	GetHistoryRepository() HistoryRepository
 }
 
 // ConnectionStats provides database connection statistics
@@ -78,6 +111,7 @@ type DatabaseConfig struct {
 	Type             DatabaseType          `yaml:"type" json:"type"`
 	PostgresConfig   *PostgresConfig       `yaml:"postgres,omitempty" json:"postgres,omitempty"`
 	MongoDBConfig    *MongoDBConfig        `yaml:"mongodb,omitempty" json:"mongodb,omitempty"`
+	EmbeddedConfig   *EmbeddedConfig       `yaml:"embedded,omitempty" json:"embedded,omitempty"`
 }
 
 // PostgresConfig holds PostgreSQL-specific configuration
@@ -93,6 +127,18 @@ type PostgresConfig struct {
 	ConnMaxLifetime int    `yaml:"conn_max_lifetime" json:"conn_max_lifetime"` // in seconds
 	ConnMaxIdleTime int    `yaml:"conn_max_idle_time" json:"conn_max_idle_time"` // in seconds
 	QueryTimeout    int    `yaml:"query_timeout" json:"query_timeout"` // in seconds
+	EnableNotify    bool   `yaml:"enable_notify" json:"enable_notify"` // LISTEN/NOTIFY change feed
+
+	// Read replicas serve read-only repository calls; writes and transactions stay on the primary
+	Replicas             []PostgresReplicaConfig `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+	ReplicaMaxLag        int                     `yaml:"replica_max_lag" json:"replica_max_lag"`               // in seconds; replicas further behind are skipped, 0 disables the check
+	ReplicaCheckInterval int                     `yaml:"replica_check_interval" json:"replica_check_interval"` // in seconds
+}
+
+// PostgresReplicaConfig locates a read replica; it shares the primary's credentials and database name
+type PostgresReplicaConfig struct {
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port" json:"port"`
 }
 
 // MongoDBConfig holds MongoDB-specific configuration
@@ -110,6 +156,13 @@ type MongoDBConfig struct {
 	EnableChangeStream bool   `yaml:"enable_change_stream" json:"enable_change_stream"`
 }
 
+// EmbeddedConfig holds configuration for the embedded single-file store
+type EmbeddedConfig struct {
+	Path        string `yaml:"path" json:"path"`                 // Database file, created if missing
+	LockTimeout int    `yaml:"lock_timeout" json:"lock_timeout"` // in seconds; wait for another process holding the file lock
+	NoSync      bool   `yaml:"no_sync" json:"no_sync"`           // Skip fsync on commit; faster, but the last commits can be lost on power failure
+}
+
 // DatabaseMigration defines interface for database migrations
 type DatabaseMigration interface {
 	// Up applies the migration
@@ -133,6 +186,13 @@ type MigrationManager interface {
 	// Rollback rolls back the last migration
 	Rollback(ctx context.Context) error
 
+	// Down rolls back the last n applied migrations
+	Down(ctx context.Context, n int) error
+
+	// Goto migrates up or down until version is the latest applied migration.
+	// Version 0 reverts every migration.
+	Goto(ctx context.Context, version int64) error
+
 	// Status returns the current migration status
 	Status(ctx context.Context) ([]MigrationStatus, error)
 }
@@ -143,6 +203,9 @@ type MigrationStatus struct {
 	Description string `json:"description"`
 	Applied     bool   `json:"applied"`
 	AppliedAt   string `json:"applied_at,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
+	// Dirty is set when the applied checksum differs from the embedded migration
+	Dirty bool `json:"dirty,omitempty"`
 }
 
 // DataExporter exports data from the database
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
@@ -20,6 +21,26 @@ type HistoryRepository interface {
 
 	// GetHistoryByChangeType retrieves history filtered by change type
 	GetHistoryByChangeType(ctx context.Context, changeType models.ChangeType, offset, limit int) ([]*models.EquipmentHistory, error)
+
+	// GetListHistory retrieves, oldest first, the changes made at or before
+	// until to the IMEI_INFO entry of startImei and to the TAC ranges whose
+	// padded bounds enclose tac
+	GetListHistory(ctx context.Context, startImei, tac string, until time.Time) ([]*models.EquipmentHistory, error)
+}
+
+// InListHistory reports whether entry is one GetListHistory returns for
+// startImei and tac. TAC range entries carry their padded bounds in key_tac.
+func InListHistory(entry *models.EquipmentHistory, startImei, tac string) bool {
+	switch list, _ := entry.ChangeDetails["list"].(string); list {
+	case "imei":
+		start, _ := entry.ChangeDetails["start_imei"].(string)
+		return start == startImei
+	case "tac":
+		key, _ := entry.ChangeDetails["key_tac"].(string)
+		lo, hi, ok := strings.Cut(key, "-")
+		return ok && lo <= tac && tac <= hi
+	}
+	return false
 }
 
 // SnapshotRepository defines the interface for equipment snapshots
@@ -33,6 +54,10 @@ type SnapshotRepository interface {
 	// GetSnapshotByID retrieves a specific snapshot
 	GetSnapshotByID(ctx context.Context, id int64) (*models.EquipmentSnapshot, error)
 
+	// GetLatestSnapshot retrieves the latest snapshot of imei taken at or
+	// before at, or nil when it has none
+	GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error)
+
 	// DeleteOldSnapshots removes snapshots older than the specified date
 	DeleteOldSnapshots(ctx context.Context, before time.Time) (int64, error)
 }
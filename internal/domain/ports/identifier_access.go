@@ -0,0 +1,18 @@
+package ports
+
+import "context"
+
+type identifierAccessKey struct{}
+
+// WithIdentifierAccess returns a context whose audit reads reveal encrypted
+// subscriber identifiers
+func WithIdentifierAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identifierAccessKey{}, true)
+}
+
+// HasIdentifierAccess reports whether audit reads made with ctx may reveal
+// encrypted subscriber identifiers
+func HasIdentifierAccess(ctx context.Context) bool {
+	access, _ := ctx.Value(identifierAccessKey{}).(bool)
+	return access
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/lib/pq"
@@ -11,19 +12,28 @@ import (
 type ImeiInfo struct {
 	StartIMEI string
 	EndIMEI   pq.StringArray
-	Color     string
+	Color     models.EquipmentStatus
+	Version   int64 // See VersionConflictError
 }
 
 type ImeiInfoInsert struct {
 	Imei  string
-	Color string
+	Color models.EquipmentStatus // Also accepts the legacy "b", "g" and "w"
+}
+
+// CheckCountDelta is the checks of one IMEI since its counters were last flushed
+type CheckCountDelta struct {
+	IMEI          string
+	Count         int64
+	LastCheckTime time.Time
 }
 
 // IMEIRepository defines the interface for IMEI data access
 // This is a port owned by the domain layer
 type IMEIRepository interface {
 	// Persistence operations for Equipment
-	// GetByIMEI retrieves equipment information by IMEI
+	// GetByIMEI retrieves equipment information by IMEI, including a
+	// tombstone left by a delete (see Equipment.IsDeleted)
 	GetByIMEI(ctx context.Context, imei string) (*models.Equipment, error)
 
 	// GetByIMEISV retrieves equipment information by IMEISV
@@ -32,24 +42,44 @@ type IMEIRepository interface {
 	// Create adds a new equipment record
 	Create(ctx context.Context, equipment *models.Equipment) error
 
-	// Update updates an existing equipment record
+	// Update updates an existing equipment record. A non-zero Version makes
+	// the update conditional on it (see VersionConflictError); on success
+	// equipment.Version is the new version.
 	Update(ctx context.Context, equipment *models.Equipment) error
 
-	// Delete removes an equipment record by IMEI
+	// Delete removes an equipment record by IMEI for good. The service
+	// deletes by leaving a tombstone with Update instead.
 	Delete(ctx context.Context, imei string) error
 
-	// List retrieves equipment records with pagination
+	// List retrieves equipment records with pagination, skipping tombstones
 	List(ctx context.Context, offset, limit int) ([]*models.Equipment, error)
 
-	// ListByStatus retrieves equipment records by status
+	// ListWithDeleted retrieves equipment records with pagination, tombstones included
+	ListWithDeleted(ctx context.Context, offset, limit int) ([]*models.Equipment, error)
+
+	// PurgeDeleted removes at most limit tombstones deleted before the given
+	// time and returns how many it removed
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// ListByStatus retrieves equipment records by status, skipping tombstones
 	ListByStatus(ctx context.Context, status models.EquipmentStatus, offset, limit int) ([]*models.Equipment, error)
 
 	// IncrementCheckCount atomically increments the check counter and updates last check time
 	IncrementCheckCount(ctx context.Context, imei string) error
 
+	// ApplyCheckCounts adds each delta's count to the check counter of its
+	// equipment and moves the last check time forward to the delta's time.
+	// IMEIs without a live record are skipped. It is not a provisioning
+	// change: the version and last update time are left alone.
+	ApplyCheckCounts(ctx context.Context, deltas []CheckCountDelta) error
+
 	// IMEI logic operations (for pkg/logic integration)
+	// SaveImeiInfo and SaveTacInfo are conditional on a non-zero Version like
+	// Update, and leave the new version in info. DeleteImeiInfo removes the
+	// entry of info.StartIMEI, conditional on its Version the same way.
 	LookupImeiInfo(ctx context.Context, startRange string) (*ImeiInfo, bool)
 	SaveImeiInfo(ctx context.Context, info *ImeiInfo) error
+	DeleteImeiInfo(ctx context.Context, info *ImeiInfo) error
 	ListAllImeiInfo(ctx context.Context) []*ImeiInfo
 	ClearImeiInfo(ctx context.Context)
 
@@ -62,6 +92,23 @@ type IMEIRepository interface {
 	ClearTacInfo(ctx context.Context)
 }
 
+// TacRangeRepository is implemented by IMEI repositories that can resolve TAC
+// ranges natively, replacing the PrevTacInfo/NextTacInfo walk with one query
+type TacRangeRepository interface {
+	// EnclosingTacInfo returns the most specific (innermost) range containing value
+	EnclosingTacInfo(ctx context.Context, value string) (*TacInfo, bool)
+
+	// OverlappingTacInfo returns every range that overlaps [start, end], ordered by key
+	OverlappingTacInfo(ctx context.Context, start, end string) ([]*TacInfo, error)
+}
+
+// CheckReader is implemented by IMEI repositories that can serve checks from
+// read replicas. The view it returns may lag behind writes, so it must only be
+// used for checks, never for lookups feeding a write.
+type CheckReader interface {
+	ForChecks() IMEIRepository
+}
+
 // AuditRepository defines the interface for audit logging
 type AuditRepository interface {
 	// LogCheck records an equipment check operation
@@ -72,6 +119,10 @@ type AuditRepository interface {
 
 	// GetAuditsByTimeRange retrieves audit logs within a time range
 	GetAuditsByTimeRange(ctx context.Context, startTime, endTime string, offset, limit int) ([]*models.AuditLog, error)
+
+	// GetAuditsBySUPI retrieves audit logs whose SUPI or SUPI index is one of
+	// supis, newest first
+	GetAuditsBySUPI(ctx context.Context, supis []string, offset, limit int) ([]*models.AuditLog, error)
 }
 
 // CacheRepository defines the interface for caching (optional)
@@ -79,7 +130,7 @@ type CacheRepository interface {
 	// Get retrieves equipment data from cache
 	Get(ctx context.Context, imei string) (*models.Equipment, error)
 
-	// Set stores equipment data in cache
+	// Set stores equipment data in cache; ttlSeconds <= 0 uses the cache's default TTL
 	Set(ctx context.Context, imei string, equipment *models.Equipment, ttlSeconds int) error
 
 	// Delete removes equipment data from cache
@@ -88,3 +139,55 @@ type CacheRepository interface {
 	// Exists checks if a key exists in cache
 	Exists(ctx context.Context, imei string) (bool, error)
 }
+
+// CheckKind identifies the check that produced a cached decision
+type CheckKind string
+
+const (
+	CheckKindIMEI CheckKind = "imei" // CheckImei against IMEI_INFO
+	CheckKindTAC  CheckKind = "tac"  // CheckTac against TAC_INFO
+)
+
+// CheckDecision is the final result of a check, cached per IMEI
+type CheckDecision struct {
+	Status  string
+	IMEI    string
+	Color   models.EquipmentStatus
+	TacInfo *TacInfo
+}
+
+// DecisionCache caches check decisions in process, including negative
+// ("unknown") results, and supports precise invalidation when the
+// underlying IMEI or TAC data changes
+type DecisionCache interface {
+	// Generation returns a counter that advances on every invalidation
+	Generation() uint64
+
+	// Get returns the cached decision for imei, if present and not expired
+	Get(kind CheckKind, imei string) (*CheckDecision, bool)
+
+	// Set caches a decision computed while the cache was at generation;
+	// it is dropped if an invalidation happened in the meantime
+	Set(kind CheckKind, imei string, generation uint64, decision *CheckDecision)
+
+	// Invalidate drops the decision cached for exactly imei
+	Invalidate(kind CheckKind, imei string)
+
+	// InvalidateMatching drops every decision of kind whose IMEI matches and
+	// returns how many entries were removed
+	InvalidateMatching(kind CheckKind, match func(imei string) bool) int
+
+	// Purge drops every decision of kind
+	Purge(kind CheckKind)
+}
+
+// BatchCacheRepository is implemented by caches that can serve several keys in one round trip
+type BatchCacheRepository interface {
+	CacheRepository
+
+	// GetMany retrieves the cached entries for imeis; missing keys are absent from the result
+	GetMany(ctx context.Context, imeis []string) (map[string]*models.Equipment, error)
+
+	// SetMany stores several entries; ttlSeconds <= 0 uses the cache's default TTL
+	SetMany(ctx context.Context, equipment map[string]*models.Equipment, ttlSeconds int) error
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/logger"
@@ -11,7 +12,7 @@ import (
 // This interface follows the business logic patterns defined in pkg/logic
 type EIRService interface {
 	// CheckImei performs IMEI check using IMEI-based logic
-	// Maps to pkg/logic.CheckImei
+	// Maps to pkg/logic.CheckImeiInRepo
 	CheckImei(ctx context.Context, imei string, status models.SystemStatus) (*CheckImeiResult, error)
 
 	// CheckTac performs TAC-based equipment check
@@ -19,60 +20,152 @@ type EIRService interface {
 	CheckTac(ctx context.Context, imei string, status models.SystemStatus) (*CheckTacResult, error)
 
 	// InsertImei provisions equipment using IMEI logic
-	// Maps to pkg/logic.InsertImei
-	InsertImei(ctx context.Context, imei string, color string, status models.SystemStatus) (*InsertImeiResult, error)
+	// Maps to pkg/logic.InsertImei; a full IMEI also gets an equipment record
+	InsertImei(ctx context.Context, imei string, color models.EquipmentStatus, status models.SystemStatus) (*InsertImeiResult, error)
 
 	// InsertTac provisions equipment using TAC range logic
 	// Maps to pkg/logic.InsertTac
 	InsertTac(ctx context.Context, tacInfo *TacInfo) (*InsertTacResult, error)
 
-	// GetEquipment retrieves equipment information (for management/audit)
-	GetEquipment(ctx context.Context, imei string) (*models.Equipment, error)
+	// ProvisionEquipment adds an equipment record and lists it in the IMEI
+	// lists with the color of its status (for management)
+	ProvisionEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error)
 
-	// ListEquipment retrieves paginated equipment list (for management/audit)
-	ListEquipment(ctx context.Context, offset, limit int) ([]*models.Equipment, error)
+	// GetEquipment retrieves equipment information (for management/audit).
+	// Deleted equipment is only returned with includeDeleted.
+	GetEquipment(ctx context.Context, imei string, includeDeleted bool) (*models.Equipment, error)
 
-	// RemoveEquipment removes equipment from the database (for management)
+	// ListEquipment retrieves paginated equipment list (for management/audit).
+	// Deleted equipment is only listed with includeDeleted.
+	ListEquipment(ctx context.Context, offset, limit int, includeDeleted bool) ([]*models.Equipment, error)
+
+	// UpdateEquipment changes an existing equipment record (for management).
+	// A non-zero equipment.Version makes it conditional on that version.
+	UpdateEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error)
+
+	// RemoveEquipment deletes equipment (for management), leaving a tombstone
+	// with the time and actor of the delete
 	RemoveEquipment(ctx context.Context, imei string) error
 
+	// UndeleteEquipment brings back deleted equipment (for management)
+	UndeleteEquipment(ctx context.Context, imei string) (*models.Equipment, error)
+
+	// GetEquipmentHistory retrieves the recorded changes to an IMEI, newest first
+	GetEquipmentHistory(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error)
+
+	// GetAuditsByIMEI retrieves the audit logs of the checks of an IMEI.
+	// Encrypted subscriber identifiers are only revealed to contexts made
+	// with WithIdentifierAccess.
+	GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error)
+
+	// GetAuditsBySUPI retrieves the audit logs of the checks made for a
+	// subscriber, newest first, however its SUPI was stored
+	GetAuditsBySUPI(ctx context.Context, supi string, offset, limit int) ([]*models.AuditLog, error)
+
+	// GetStatusAt reconstructs the status of an IMEI at a past moment from the
+	// change history, citing the entries it was derived from
+	GetStatusAt(ctx context.Context, imei string, at time.Time) (*models.PointInTimeStatus, error)
+
+	// SnapshotEquipment takes a MANUAL snapshot of an equipment record
+	SnapshotEquipment(ctx context.Context, imei string) (*models.EquipmentSnapshot, error)
+
+	// SnapshotAllEquipment takes a SCHEDULED snapshot of every equipment record
+	SnapshotAllEquipment(ctx context.Context) (int, error)
+
+	// ListSnapshots retrieves the snapshots of an IMEI, newest first
+	ListSnapshots(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error)
+
+	// RestoreSnapshot rolls an equipment record back to a snapshot of it,
+	// conditionally on its version when expectedVersion is non-zero
+	RestoreSnapshot(ctx context.Context, imei string, snapshotID int64, expectedVersion int64) (*models.Equipment, error)
+
+	// PurgeSnapshots deletes the snapshots taken before the given time
+	PurgeSnapshots(ctx context.Context, before time.Time) (int64, error)
+
 	// SetLogger sets a custom logger for this service instance
 	SetLogger(l logger.Logger)
+
+	// SetDecisionCache enables caching of CheckImei/CheckTac decisions
+	SetDecisionCache(c DecisionCache)
+
+	// SetCheckRecorder counts every CheckImei/CheckTac in r
+	SetCheckRecorder(r CheckRecorder)
+
+	// SetTransactionProvider makes provisioning atomic: each change and its
+	// history record run inside one transaction
+	SetTransactionProvider(p TransactionProvider)
+
+	// SetHistoryRepository records provisioning changes, attributed to the
+	// actor carried by the request context, and serves GetEquipmentHistory
+	SetHistoryRepository(h HistoryRepository)
+
+	// SetSnapshotRepository stores equipment snapshots, taking a PRE_UPDATE
+	// snapshot before every status change, and lets GetStatusAt start from them
+	SetSnapshotRepository(r SnapshotRepository)
+}
+
+// CheckRecorder counts equipment checks. It is called on the check path, so
+// it must not block on I/O.
+type CheckRecorder interface {
+	RecordCheck(imei string, at time.Time)
 }
 
 // CheckImeiResult represents the result of IMEI check
 type CheckImeiResult struct {
-	Status string // "ok" or "error"
-	IMEI   string // The checked IMEI
-	Color  string // "b" (black), "g" (grey), "w" (white), "unknown", "overload"
+	Status string                 // "ok" or "error"
+	IMEI   string                 // The checked IMEI
+	Color  models.EquipmentStatus // Status of the matching IMEI_INFO entry, or "unknown", "overload"
+}
+
+// EquipmentStatus is the status the check reports: the color of the matching
+// entry, or WHITELISTED when no entry matched
+func (r *CheckImeiResult) EquipmentStatus() models.EquipmentStatus {
+	return reportedStatus(r.Color)
 }
 
 // CheckTacResult represents the result of TAC-based check
 type CheckTacResult struct {
-	Status  string   // "ok" or "error"
-	IMEI    string   // The checked IMEI
-	Color   string   // "black", "grey", "white", "unknown"
-	TacInfo *TacInfo // TAC information if found
+	Status  string                 // "ok" or "error"
+	IMEI    string                 // The checked IMEI
+	Color   models.EquipmentStatus // Status of the enclosing TAC range, or "unknown"
+	TacInfo *TacInfo               // TAC information if found
+}
+
+// EquipmentStatus is the status the check reports: the color of the
+// enclosing range, or WHITELISTED when no range matched
+func (r *CheckTacResult) EquipmentStatus() models.EquipmentStatus {
+	return reportedStatus(r.Color)
+}
+
+// reportedStatus lets equipment no list covers through, as the N5g-eir and
+// S13 interfaces expect
+func reportedStatus(color models.EquipmentStatus) models.EquipmentStatus {
+	if models.ValidateStatus(color) != nil {
+		return models.EquipmentStatusWhitelisted
+	}
+	return color
 }
 
 // InsertImeiResult represents the result of IMEI insertion
 type InsertImeiResult struct {
 	Status string  // "ok" or "error"
 	IMEI   string  // The inserted IMEI
-	Error  *string // Error code: "overload", "invalid_parameter", "invalid_value", "invalid_length", "invalid_color", "color_conflict", "imei_exist"
+	Error  *string // Error code: "overload", "invalid_parameter", "invalid_value", "invalid_length", "invalid_color", "color_conflict", "imei_exist", "version_conflict"
 }
 
 // InsertTacResult represents the result of TAC insertion
 type InsertTacResult struct {
 	Status  string   // "ok" or "error"
-	Error   *string  // Error code: "invalid_length", "invalid_color", "invalid_value", "range_exist"
+	Error   *string  // Error code: "invalid_length", "invalid_color", "invalid_value", "range_exist", "version_conflict"
 	TacInfo *TacInfo // The TAC info that was processed
 }
 
 // TacInfo represents TAC range information
 type TacInfo struct {
-	KeyTac        string  // Computed key for storage
-	StartRangeTac string  // Start of TAC range
-	EndRangeTac   string  // End of TAC range
-	Color         string  // "black", "grey", "white"
-	PrevLink      *string // Link to previous range (for optimization)
+	KeyTac        string                 // Computed key for storage
+	StartRangeTac string                 // Start of TAC range
+	EndRangeTac   string                 // End of TAC range
+	Color         models.EquipmentStatus // Also accepts the legacy "black", "grey" and "white"
+	PrevLink      *string                // Link to previous range (for optimization)
+	Version       int64                  // See VersionConflictError
 }
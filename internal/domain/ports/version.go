@@ -0,0 +1,43 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict matches every VersionConflictError with errors.Is
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError is returned by a conditional write to an equipment
+// record, IMEI_INFO entry or TAC range whose stored version is no longer the
+// one the caller read, because someone else changed or removed it since.
+//
+// Writes are conditional when the record carries a non-zero Version: the
+// write only succeeds if the stored version equals it, and then advances the
+// record's Version. A zero Version writes unconditionally.
+type VersionConflictError struct {
+	Entity   string // "equipment", "imei_info" or "tac_info"
+	Key      string // IMEI, start IMEI or TAC key
+	Expected int64
+	Actual   int64 // 0 when the record does not exist
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.Actual == 0 {
+		return fmt.Sprintf("%s %s: expected version %d, but it no longer exists", e.Entity, e.Key, e.Expected)
+	}
+	return fmt.Sprintf("%s %s: expected version %d, found %d", e.Entity, e.Key, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// NextVersion checks a conditional write of a record whose stored version is
+// actual (0 when it does not exist) and returns the version to store
+func NextVersion(entity, key string, expected, actual int64) (int64, error) {
+	if expected != 0 && expected != actual {
+		return 0, &VersionConflictError{Entity: entity, Key: key, Expected: expected, Actual: actual}
+	}
+	return actual + 1, nil
+}
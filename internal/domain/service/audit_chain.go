@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// AuditChainKeys holds the key checkpoints are signed with and the public
+// keys they are verified with
+type AuditChainKeys struct {
+	signingID string
+	signing   ed25519.PrivateKey // nil when this process only verifies
+	public    map[string]ed25519.PublicKey
+}
+
+// NewAuditChainKeys loads the checkpoint keys of cfg. The signing key's
+// public half is always among the verification keys.
+func NewAuditChainKeys(cfg config.AuditChainConfig) (*AuditChainKeys, error) {
+	keys := &AuditChainKeys{public: make(map[string]ed25519.PublicKey, len(cfg.PublicKeys)+1)}
+	for id, encoded := range cfg.PublicKeys {
+		public, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(public) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("public key %q must be %d bytes encoded as base64", id, ed25519.PublicKeySize)
+		}
+		keys.public[id] = public
+	}
+
+	if cfg.SigningKey != "" {
+		seed, err := base64.StdEncoding.DecodeString(cfg.SigningKey)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key must be %d bytes encoded as base64", ed25519.SeedSize)
+		}
+		keys.signingID = cfg.SigningKeyID
+		keys.signing = ed25519.NewKeyFromSeed(seed)
+		keys.public[cfg.SigningKeyID] = keys.signing.Public().(ed25519.PublicKey)
+	}
+	return keys, nil
+}
+
+// sign sets the key ID and signature of checkpoint
+func (k *AuditChainKeys) sign(checkpoint *models.AuditCheckpoint) error {
+	if k.signing == nil {
+		return errors.New("no checkpoint signing key is configured")
+	}
+	checkpoint.KeyID = k.signingID
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.signing, checkpoint.SignedContent()))
+	return nil
+}
+
+// verify checks the signature of checkpoint, returning why it is not valid
+func (k *AuditChainKeys) verify(checkpoint *models.AuditCheckpoint) string {
+	public, ok := k.public[checkpoint.KeyID]
+	if !ok {
+		return fmt.Sprintf("checkpoint signed with unknown key %q", checkpoint.KeyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil || !ed25519.Verify(public, checkpoint.SignedContent(), signature) {
+		return fmt.Sprintf("checkpoint signature by key %q is invalid", checkpoint.KeyID)
+	}
+	return ""
+}
+
+// AuditCheckpointer periodically signs the head of every audit chain
+// partition that advanced since its last checkpoint
+type AuditCheckpointer struct {
+	store    ports.AuditChainStore
+	keys     *AuditChainKeys
+	interval time.Duration
+
+	mu     sync.Mutex
+	signed map[string]int64 // Partition to the seq of its last checkpoint
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewAuditCheckpointer creates a checkpointer signing the heads of store every interval
+func NewAuditCheckpointer(store ports.AuditChainStore, keys *AuditChainKeys, interval time.Duration) (*AuditCheckpointer, error) {
+	if keys.signing == nil {
+		return nil, errors.New("no checkpoint signing key is configured")
+	}
+	if interval <= 0 {
+		return nil, errors.New("checkpoint interval must be positive")
+	}
+	return &AuditCheckpointer{
+		store:    store,
+		keys:     keys,
+		interval: interval,
+		signed:   make(map[string]int64),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Start checkpoints in the background until Stop is called
+func (c *AuditCheckpointer) Start() {
+	c.wg.Add(1)
+	go c.loop()
+}
+
+// Stop ends the checkpoints and waits for a run in progress to finish
+func (c *AuditCheckpointer) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.wg.Wait()
+}
+
+// RunOnce signs the heads that advanced since their last checkpoint and
+// returns the checkpoints it saved
+func (c *AuditCheckpointer) RunOnce(ctx context.Context) ([]*models.AuditCheckpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	partitions, err := c.store.GetAuditChainPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []*models.AuditCheckpoint
+	for _, partition := range partitions {
+		last, known := c.signed[partition]
+		if !known {
+			if last, err = c.lastCheckpoint(ctx, partition); err != nil {
+				return saved, err
+			}
+		}
+
+		head, err := c.store.GetAuditChainHead(ctx, partition)
+		if err != nil {
+			return saved, err
+		}
+		if head == nil || head.Seq <= last {
+			c.signed[partition] = last
+			continue
+		}
+
+		checkpoint := &models.AuditCheckpoint{
+			Partition: partition,
+			Seq:       head.Seq,
+			Hash:      head.Hash,
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		}
+		if err := c.keys.sign(checkpoint); err != nil {
+			return saved, err
+		}
+		if err := c.store.SaveAuditCheckpoint(ctx, checkpoint); err != nil {
+			return saved, err
+		}
+		c.signed[partition] = head.Seq
+		saved = append(saved, checkpoint)
+	}
+	return saved, nil
+}
+
+// lastCheckpoint returns the seq of the latest stored checkpoint of partition, 0 when there is none
+func (c *AuditCheckpointer) lastCheckpoint(ctx context.Context, partition string) (int64, error) {
+	checkpoints, err := c.store.GetAuditCheckpoints(ctx, partition)
+	if err != nil {
+		return 0, err
+	}
+	if len(checkpoints) == 0 {
+		return 0, nil
+	}
+	return checkpoints[len(checkpoints)-1].Seq, nil
+}
+
+func (c *AuditCheckpointer) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		saved, err := c.RunOnce(context.Background())
+		if err != nil {
+			logger.Log.Errorw("Audit checkpoint run failed", "error", err)
+			continue
+		}
+		for _, checkpoint := range saved {
+			logger.Log.Infow("Audit chain checkpoint signed", "partition", checkpoint.Partition, "seq", checkpoint.Seq, "key", checkpoint.KeyID)
+		}
+	}
+}
+
+// errChainBroken stops a chain walk at its first broken link
+var errChainBroken = errors.New("audit chain broken")
+
+// VerifyAuditChain walks the chains of partitions, or of every partition when
+// none are given, and reports the first broken link. A partition may start
+// after seq 1 once retention purged its oldest records; from its first
+// surviving record on, every link, record hash, signed checkpoint and the
+// stored head must agree. The error is only set when the store fails.
+func VerifyAuditChain(ctx context.Context, store ports.AuditChainStore, keys *AuditChainKeys, partitions []string) (*models.AuditChainReport, error) {
+	if len(partitions) == 0 {
+		var err error
+		if partitions, err = store.GetAuditChainPartitions(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.AuditChainReport{}
+	for _, partition := range partitions {
+		report.Partitions++
+		broken, err := verifyPartition(ctx, store, keys, partition, report)
+		if err != nil {
+			return nil, err
+		}
+		if broken != nil {
+			report.Break = broken
+			return report, nil
+		}
+	}
+	return report, nil
+}
+
+// verifyPartition verifies one partition, returning its first broken link
+func verifyPartition(ctx context.Context, store ports.AuditChainStore, keys *AuditChainKeys, partition string, report *models.AuditChainReport) (*models.AuditChainBreak, error) {
+	checkpoints, err := store.GetAuditCheckpoints(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	report.Checkpoints += len(checkpoints)
+	bySeq := make(map[int64][]*models.AuditCheckpoint, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		if reason := keys.verify(checkpoint); reason != "" {
+			return &models.AuditChainBreak{Partition: partition, Seq: checkpoint.Seq, Reason: reason}, nil
+		}
+		bySeq[checkpoint.Seq] = append(bySeq[checkpoint.Seq], checkpoint)
+	}
+
+	var prev *models.AuditLogExtended
+	var broken *models.AuditChainBreak
+	fail := func(audit *models.AuditLogExtended, seq int64, reason string, args ...interface{}) error {
+		broken = &models.AuditChainBreak{Partition: partition, Seq: seq, Reason: fmt.Sprintf(reason, args...)}
+		if audit != nil {
+			broken.AuditID = audit.ID
+		}
+		return errChainBroken
+	}
+
+	err = store.WalkAuditChain(ctx, partition, func(audit *models.AuditLogExtended) error {
+		report.Records++
+		switch {
+		case prev == nil && audit.ChainSeq == 1 && audit.PrevHash != "":
+			return fail(audit, audit.ChainSeq, "first record links to a previous hash")
+		case prev != nil && audit.ChainSeq <= prev.ChainSeq:
+			return fail(audit, audit.ChainSeq, "record duplicates chain position %d", audit.ChainSeq)
+		case prev != nil && audit.ChainSeq != prev.ChainSeq+1:
+			return fail(nil, prev.ChainSeq+1, "record missing after seq %d", prev.ChainSeq)
+		case prev != nil && audit.PrevHash != prev.Hash:
+			return fail(audit, audit.ChainSeq, "previous hash does not match record %d", prev.ChainSeq)
+		}
+		if audit.ChainHash() != audit.Hash {
+			return fail(audit, audit.ChainSeq, "record content does not match its hash")
+		}
+		for _, checkpoint := range bySeq[audit.ChainSeq] {
+			if checkpoint.Hash != audit.Hash {
+				return fail(audit, audit.ChainSeq, "record does not match the checkpoint signed at %s", checkpoint.CreatedAt.Format(time.RFC3339))
+			}
+		}
+		prev = audit
+		return nil
+	})
+	if errors.Is(err, errChainBroken) {
+		return broken, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil {
+		// Every record was purged; there is nothing left to verify
+		return nil, nil
+	}
+
+	// Records removed from the end of the chain leave the head and the
+	// checkpoints pointing past the last record
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Seq > prev.ChainSeq {
+			return &models.AuditChainBreak{Partition: partition, Seq: prev.ChainSeq + 1,
+				Reason: fmt.Sprintf("records after seq %d are missing; a checkpoint covers seq %d", prev.ChainSeq, checkpoint.Seq)}, nil
+		}
+	}
+	head, err := store.GetAuditChainHead(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil && (head.Seq != prev.ChainSeq || head.Hash != prev.Hash) {
+		if head.Seq > prev.ChainSeq {
+			return &models.AuditChainBreak{Partition: partition, Seq: prev.ChainSeq + 1,
+				Reason: fmt.Sprintf("records after seq %d are missing; the chain head is at seq %d", prev.ChainSeq, head.Seq)}, nil
+		}
+		return &models.AuditChainBreak{Partition: partition, Seq: prev.ChainSeq, AuditID: prev.ID,
+			Reason: fmt.Sprintf("last record does not match the chain head at seq %d", head.Seq)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/hsdfat8/eir/pkg/logic"
+)
+
+const (
+	changeFeedMinRetry = time.Second
+	changeFeedMaxRetry = 30 * time.Second
+)
+
+// ChangeApplier applies changes committed by any replica to the state this
+// replica keeps locally: the equipment cache, the decision cache and any
+// registered lookup indexes
+type ChangeApplier struct {
+	cache     ports.CacheRepository // Optional
+	decisions ports.DecisionCache   // Optional
+
+	mu      sync.RWMutex
+	indexes []ports.LookupIndex
+}
+
+// NewChangeApplier creates a change applier; either cache may be nil
+func NewChangeApplier(cache ports.CacheRepository, decisions ports.DecisionCache) *ChangeApplier {
+	return &ChangeApplier{
+		cache:     cache,
+		decisions: decisions,
+	}
+}
+
+// RegisterIndex adds a lookup index to reload when its table changes
+func (a *ChangeApplier) RegisterIndex(index ports.LookupIndex) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.indexes = append(a.indexes, index)
+}
+
+// Run listens on feed until ctx is cancelled, restarting it with a bounded
+// backoff. Every restart is followed by a resync because events may have been missed.
+func (a *ChangeApplier) Run(ctx context.Context, feed ports.ChangeFeed) {
+	retry := changeFeedMinRetry
+	for {
+		started := time.Now()
+		err := feed.Listen(ctx, func(event ports.ChangeEvent) {
+			a.Apply(ctx, event)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > changeFeedMaxRetry {
+			retry = changeFeedMinRetry
+		}
+		logger.Log.Warnw("Change feed stopped, restarting", "retry_in", retry, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		for _, table := range ports.ChangeTables {
+			a.Apply(ctx, ports.ChangeEvent{Table: table, Op: ports.ChangeOpResync})
+		}
+		retry *= 2
+		if retry > changeFeedMaxRetry {
+			retry = changeFeedMaxRetry
+		}
+	}
+}
+
+// Apply invalidates everything derived from the rows named in event
+func (a *ChangeApplier) Apply(ctx context.Context, event ports.ChangeEvent) {
+	logger.Log.Debugw("Applying change", "table", event.Table, "op", event.Op, "keys", len(event.Keys))
+
+	switch event.Table {
+	case ports.ChangeTableEquipment:
+		a.applyEquipment(ctx, event.Keys)
+	case ports.ChangeTableImeiInfo:
+		a.applyImeiInfo(event.Keys)
+	case ports.ChangeTableTacInfo:
+		a.applyTacInfo(event.Keys)
+	default:
+		logger.Log.Warnw("Ignoring change to unknown table", "table", event.Table)
+		return
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, index := range a.indexes {
+		if index.Table() != event.Table {
+			continue
+		}
+		if err := index.Reload(ctx, event.Keys); err != nil {
+			logger.Log.Errorw("Failed to reload lookup index", "table", event.Table, "error", err)
+		}
+	}
+}
+
+func (a *ChangeApplier) applyEquipment(ctx context.Context, keys []string) {
+	// The equipment cache is shared between replicas, so only keyed changes
+	// are worth a round trip; whole-table changes expire with its TTL
+	if a.cache != nil {
+		for _, imei := range keys {
+			_ = a.cache.Delete(ctx, imei)
+		}
+	}
+	if a.decisions == nil {
+		return
+	}
+	if keys == nil {
+		a.decisions.Purge(ports.CheckKindIMEI)
+		a.decisions.Purge(ports.CheckKindTAC)
+		return
+	}
+	for _, imei := range keys {
+		a.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(imei))
+		a.decisions.Invalidate(ports.CheckKindTAC, imei)
+	}
+}
+
+func (a *ChangeApplier) applyImeiInfo(keys []string) {
+	if a.decisions == nil {
+		return
+	}
+	if keys == nil {
+		a.decisions.Purge(ports.CheckKindIMEI)
+		return
+	}
+	for _, start := range keys {
+		a.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(start))
+	}
+}
+
+func (a *ChangeApplier) applyTacInfo(keys []string) {
+	if a.decisions == nil {
+		return
+	}
+	if keys == nil {
+		a.decisions.Purge(ports.CheckKindTAC)
+		return
+	}
+	for _, key := range keys {
+		// KeyTac is "<start padded with spaces>-<end padded with ÿ>"
+		start, end, ok := strings.Cut(key, "-")
+		if !ok {
+			a.decisions.Purge(ports.CheckKindTAC)
+			return
+		}
+		a.decisions.InvalidateMatching(ports.CheckKindTAC, logic.TacRangeMatcher(start, end))
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// CheckCounter counts equipment checks in memory and periodically flushes
+// them to the equipment records in batches, so the check path never waits
+// on a database write
+type CheckCounter struct {
+	repo       ports.IMEIRepository
+	interval   time.Duration
+	batchSize  int
+	maxPending int
+
+	mu      sync.Mutex
+	pending map[string]*ports.CheckCountDelta
+
+	flushMu sync.Mutex // Serialises flushes so a failed batch is requeued before the next one
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewCheckCounter creates a counter flushing to repo as cfg describes
+func NewCheckCounter(repo ports.IMEIRepository, cfg config.CheckCountersConfig) (*CheckCounter, error) {
+	if cfg.FlushInterval <= 0 || cfg.BatchSize <= 0 || cfg.MaxPending <= 0 {
+		return nil, errors.New("check counter flush interval, batch size and max pending must be positive")
+	}
+	return &CheckCounter{
+		repo:       repo,
+		interval:   cfg.FlushInterval,
+		batchSize:  cfg.BatchSize,
+		maxPending: cfg.MaxPending,
+		pending:    make(map[string]*ports.CheckCountDelta),
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+// RecordCheck counts a check of imei made at the given time
+func (c *CheckCounter) RecordCheck(imei string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.add(imei, 1, at) {
+		logger.CheckCountsDroppedTotal.Inc()
+	}
+}
+
+// add merges count checks into the pending deltas; c.mu must be held. It
+// reports false when the IMEI is new and the pending deltas are full.
+func (c *CheckCounter) add(imei string, count int64, last time.Time) bool {
+	delta, ok := c.pending[imei]
+	if !ok {
+		if len(c.pending) >= c.maxPending {
+			return false
+		}
+		c.pending[imei] = &ports.CheckCountDelta{IMEI: imei, Count: count, LastCheckTime: last}
+		return true
+	}
+	delta.Count += count
+	if last.After(delta.LastCheckTime) {
+		delta.LastCheckTime = last
+	}
+	return true
+}
+
+// Pending returns the number of IMEIs with checks not flushed yet
+func (c *CheckCounter) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Flush writes the pending checks and returns how many it wrote. Batches
+// that fail are kept and retried by the next flush.
+func (c *CheckCounter) Flush(ctx context.Context) (int64, error) {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	deltas := make([]ports.CheckCountDelta, 0, len(c.pending))
+	for _, delta := range c.pending {
+		deltas = append(deltas, *delta)
+	}
+	c.pending = make(map[string]*ports.CheckCountDelta, len(deltas))
+	c.mu.Unlock()
+
+	// A fixed order keeps replicas flushing the same IMEIs from deadlocking
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].IMEI < deltas[j].IMEI })
+
+	var flushed int64
+	for start := 0; start < len(deltas); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(deltas) {
+			end = len(deltas)
+		}
+		batch := deltas[start:end]
+		if err := c.repo.ApplyCheckCounts(ctx, batch); err != nil {
+			failed := c.requeue(deltas[start:])
+			logger.CheckCountsFlushedTotal.WithLabelValues("error").Add(float64(failed))
+			return flushed, err
+		}
+		for _, delta := range batch {
+			flushed += delta.Count
+		}
+	}
+
+	logger.CheckCountsFlushedTotal.WithLabelValues("ok").Add(float64(flushed))
+	return flushed, nil
+}
+
+// requeue returns unwritten deltas to the pending ones and the number of checks they hold
+func (c *CheckCounter) requeue(deltas []ports.CheckCountDelta) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var checks int64
+	for _, delta := range deltas {
+		checks += delta.Count
+		if !c.add(delta.IMEI, delta.Count, delta.LastCheckTime) {
+			logger.CheckCountsDroppedTotal.Add(float64(delta.Count))
+		}
+	}
+	return checks
+}
+
+// Start flushes in the background until Stop is called
+func (c *CheckCounter) Start() {
+	c.wg.Add(1)
+	go c.loop()
+}
+
+// Stop ends the background flushes and writes the checks still pending
+func (c *CheckCounter) Stop(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.wg.Wait()
+	_, err := c.Flush(ctx)
+	return err
+}
+
+func (c *CheckCounter) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		if _, err := c.Flush(context.Background()); err != nil {
+			logger.Log.Errorw("Check counter flush failed, retrying on the next flush", "pending", c.Pending(), "error", err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hsdfat8/eir/internal/config"
 	"github.com/hsdfat8/eir/internal/domain/models"
@@ -11,11 +12,18 @@ import (
 	"github.com/hsdfat8/eir/internal/logger"
 	legacyModels "github.com/hsdfat8/eir/models"
 	"github.com/hsdfat8/eir/pkg/logic"
+	"github.com/hsdfat8/eir/utils"
 )
 
 var (
-	ErrEquipmentNotFound = errors.New("equipment not found")
-	ErrInvalidRequest    = errors.New("invalid request")
+	ErrEquipmentNotFound    = errors.New("equipment not found")
+	ErrInvalidRequest       = errors.New("invalid request")
+	ErrHistoryUnavailable   = errors.New("equipment history is not configured")
+	ErrSnapshotsUnavailable = errors.New("equipment snapshots are not configured")
+	ErrSnapshotNotFound     = errors.New("snapshot not found")
+	ErrEquipmentNotDeleted  = errors.New("equipment is not deleted")
+	ErrEquipmentExists      = errors.New("equipment already exists")
+	ErrAuditsUnavailable    = errors.New("audit logs are not configured")
 )
 
 // eirService implements the EIRService interface
@@ -23,8 +31,13 @@ type eirService struct {
 	cfg       *config.Config
 	imeiRepo  ports.IMEIRepository
 	auditRepo ports.AuditRepository
-	cache     ports.CacheRepository // Optional
-	logger    logger.Logger         // Optional custom logger
+	cache     ports.CacheRepository     // Optional
+	decisions ports.DecisionCache       // Optional check decision cache
+	checks    ports.CheckRecorder       // Optional check counters
+	txs       ports.TransactionProvider // Optional, makes provisioning atomic
+	history   ports.HistoryRepository   // Optional change history
+	snapshots ports.SnapshotRepository  // Optional equipment snapshots
+	logger    logger.Logger             // Optional custom logger
 }
 
 // NewEIRService creates a new EIR service instance
@@ -48,6 +61,39 @@ func (s *eirService) SetLogger(l logger.Logger) {
 	s.logger = l
 }
 
+// SetDecisionCache enables caching of check decisions
+func (s *eirService) SetDecisionCache(c ports.DecisionCache) {
+	s.decisions = c
+}
+
+// SetCheckRecorder counts every check in r
+func (s *eirService) SetCheckRecorder(r ports.CheckRecorder) {
+	s.checks = r
+}
+
+// recordCheck counts a check of imei when check counters are enabled
+func (s *eirService) recordCheck(imei string) {
+	if s.checks != nil {
+		s.checks.RecordCheck(imei, time.Now())
+	}
+}
+
+// SetTransactionProvider runs provisioning inside transactions begun from p
+func (s *eirService) SetTransactionProvider(p ports.TransactionProvider) {
+	s.txs = p
+}
+
+// SetHistoryRepository records every provisioning change in h
+func (s *eirService) SetHistoryRepository(h ports.HistoryRepository) {
+	s.history = h
+}
+
+// SetSnapshotRepository enables equipment snapshots, including the PRE_UPDATE
+// snapshot taken before every status change
+func (s *eirService) SetSnapshotRepository(r ports.SnapshotRepository) {
+	s.snapshots = r
+}
+
 // getLogger returns the custom logger if set, otherwise returns the global logger
 func (s *eirService) getLogger() logger.Logger {
 	if s.logger != nil {
@@ -56,6 +102,15 @@ func (s *eirService) getLogger() logger.Logger {
 	return logger.Log
 }
 
+// checkRepo returns the repository checks read from: its replica-backed
+// view when it has one
+func (s *eirService) checkRepo() ports.IMEIRepository {
+	if reader, ok := s.imeiRepo.(ports.CheckReader); ok {
+		return reader.ForChecks()
+	}
+	return s.imeiRepo
+}
+
 // GetConfig returns the service configuration
 func (s *eirService) GetConfig() *config.Config {
 	return s.cfg
@@ -64,6 +119,7 @@ func (s *eirService) GetConfig() *config.Config {
 // CheckImei performs IMEI check using pkg/logic
 func (s *eirService) CheckImei(ctx context.Context, imei string, status models.SystemStatus) (*ports.CheckImeiResult, error) {
 	s.getLogger().Infow("CheckImei started", "imei", imei, "overload_level", status.OverloadLevel, "tps_overload", status.TPSOverload)
+	s.recordCheck(imei)
 
 	// Convert domain model to legacy model
 	legacyStatus := legacyModels.SystemStatus{
@@ -71,11 +127,30 @@ func (s *eirService) CheckImei(ctx context.Context, imei string, status models.S
 		TPSOverload:   status.TPSOverload,
 	}
 
-	// Use pkg/logic for IMEI checking
-	result := logic.CheckImei(imei, legacyStatus)
+	// Overload rejections are transient and must not be served from cache
+	useCache := s.decisions != nil && !utils.IsOverLoad(legacyStatus)
+	var generation uint64
+	if useCache {
+		if d, ok := s.decisions.Get(ports.CheckKindIMEI, imei); ok {
+			s.getLogger().Debugw("CheckImei decision cache hit", "imei", imei, "color", d.Color)
+			return &ports.CheckImeiResult{Status: d.Status, IMEI: d.IMEI, Color: d.Color}, nil
+		}
+		generation = s.decisions.Generation()
+	}
+
+	// Use pkg/logic for IMEI checking against the stored IMEI lists
+	result := logic.CheckImeiInRepo(s.checkRepo(), imei, legacyStatus)
 
 	s.getLogger().Infow("CheckImei completed", "imei", imei, "status", result.Status, "color", result.Color)
 
+	if useCache && result.Color != models.CheckOutcomeOverload {
+		s.decisions.Set(ports.CheckKindIMEI, imei, generation, &ports.CheckDecision{
+			Status: result.Status,
+			IMEI:   result.IMEI,
+			Color:  result.Color,
+		})
+	}
+
 	return &ports.CheckImeiResult{
 		Status: result.Status,
 		IMEI:   result.IMEI,
@@ -93,18 +168,22 @@ func (s *eirService) CheckTac(ctx context.Context, imei string, status models.Sy
 		return &ports.CheckTacResult{
 			Status: "error",
 			IMEI:   imei,
-			Color:  "unknown",
+			Color:  models.CheckOutcomeUnknown,
 		}, fmt.Errorf("IMEI validation failed: %w", err)
 	}
+	s.recordCheck(imei)
 
-	// Convert domain model to legacy model
-	legacyStatus := legacyModels.SystemStatus{
-		OverloadLevel: status.OverloadLevel,
-		TPSOverload:   status.TPSOverload,
+	var generation uint64
+	if s.decisions != nil {
+		if d, ok := s.decisions.Get(ports.CheckKindTAC, imei); ok {
+			s.getLogger().Debugw("CheckTac decision cache hit", "imei", imei, "color", d.Color)
+			return &ports.CheckTacResult{Status: d.Status, IMEI: d.IMEI, Color: d.Color, TacInfo: d.TacInfo}, nil
+		}
+		generation = s.decisions.Generation()
 	}
 
-	// Use pkg/logic for TAC checking
-	result, tacInfo := logic.CheckTac(imei, legacyStatus)
+	// Resolve the IMEI against the provisioned TAC ranges
+	result, tacInfo := logic.CheckTacInRepo(s.checkRepo(), imei)
 
 	var tacInfoPtr *ports.TacInfo
 	if result.Status == "ok" {
@@ -120,6 +199,15 @@ func (s *eirService) CheckTac(ctx context.Context, imei string, status models.Sy
 		s.getLogger().Warnw("CheckTac completed with error", "imei", imei, "status", result.Status, "color", result.Color)
 	}
 
+	if s.decisions != nil {
+		s.decisions.Set(ports.CheckKindTAC, imei, generation, &ports.CheckDecision{
+			Status:  result.Status,
+			IMEI:    result.IMEI,
+			Color:   result.Color,
+			TacInfo: tacInfoPtr,
+		})
+	}
+
 	return &ports.CheckTacResult{
 		Status:  result.Status,
 		IMEI:    result.IMEI,
@@ -129,7 +217,7 @@ func (s *eirService) CheckTac(ctx context.Context, imei string, status models.Sy
 }
 
 // InsertImei provisions equipment using pkg/logic
-func (s *eirService) InsertImei(ctx context.Context, imei string, color string, status models.SystemStatus) (*ports.InsertImeiResult, error) {
+func (s *eirService) InsertImei(ctx context.Context, imei string, color models.EquipmentStatus, status models.SystemStatus) (*ports.InsertImeiResult, error) {
 	s.getLogger().Infow("InsertImei started", "imei", imei, "color", color, "overload_level", status.OverloadLevel, "tps_overload", status.TPSOverload)
 
 	// Convert domain model to legacy model
@@ -138,8 +226,23 @@ func (s *eirService) InsertImei(ctx context.Context, imei string, color string,
 		TPSOverload:   status.TPSOverload,
 	}
 
-	// Use pkg/logic for IMEI insertion with the imeiRepo
-	result := logic.InsertImei(s.imeiRepo, imei, color, legacyStatus)
+	// Use pkg/logic for IMEI insertion, recording its history and the
+	// equipment record of a full IMEI atomically when transactions are available
+	var result legacyModels.InsertImeiResult
+	err := s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		if history != nil {
+			result = logic.InsertImeiWithHistory(ctx, repo, history, imei, color, legacyStatus)
+		} else {
+			result = logic.InsertImei(repo, imei, color, legacyStatus)
+		}
+		if result.Status != "ok" {
+			return errImeiNotInserted
+		}
+		return s.deriveEquipment(ctx, repo, imei, color)
+	})
+	if err != nil && result.Status == "ok" {
+		result = legacyModels.InsertImeiResult{Status: "error", IMEI: imei, Error: err.Error()}
+	}
 
 	errorPtr := (*string)(nil)
 	if result.Error != "" {
@@ -147,6 +250,10 @@ func (s *eirService) InsertImei(ctx context.Context, imei string, color string,
 		s.getLogger().Errorw("InsertImei failed", "imei", imei, "color", color, "status", result.Status, "error", result.Error)
 	} else {
 		s.getLogger().Infow("InsertImei completed successfully", "imei", imei, "color", color, "status", result.Status)
+		if s.decisions != nil {
+			n := s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(imei))
+			s.getLogger().Debugw("InsertImei invalidated cached decisions", "imei", imei, "count", n)
+		}
 	}
 
 	return &ports.InsertImeiResult{
@@ -156,6 +263,37 @@ func (s *eirService) InsertImei(ctx context.Context, imei string, color string,
 	}, nil
 }
 
+// errImeiNotInserted rolls back an InsertImei whose result carries the error
+var errImeiNotInserted = errors.New("imei not inserted")
+
+// deriveEquipment gives a full IMEI just inserted into the IMEI lists the
+// equipment record it stands for, so the management API agrees with the
+// checks. Prefixes have none. The insert is the history entry of the change.
+func (s *eirService) deriveEquipment(ctx context.Context, repo ports.IMEIRepository, imei string, status models.EquipmentStatus) error {
+	if models.ValidateIMEI(imei) != nil {
+		return nil
+	}
+
+	current, err := repo.GetByIMEI(ctx, imei)
+	if err != nil {
+		return s.createEquipment(ctx, repo, nil, &models.Equipment{
+			IMEI:        imei,
+			Status:      status,
+			LastUpdated: time.Now(),
+			AddedBy:     ports.ActorFromContext(ctx),
+		}, nil)
+	}
+	if !current.IsDeleted() && current.Status == status {
+		return nil
+	}
+
+	updated := *current
+	updated.Status = status
+	updated.DeletedAt, updated.DeletedBy = nil, nil
+	updated.LastUpdated = time.Now()
+	return s.updateEquipment(ctx, repo, nil, current, &updated, nil)
+}
+
 // InsertTac provisions equipment using pkg/logic
 func (s *eirService) InsertTac(ctx context.Context, tacInfo *ports.TacInfo) (*ports.InsertTacResult, error) {
 	if tacInfo == nil {
@@ -177,8 +315,16 @@ func (s *eirService) InsertTac(ctx context.Context, tacInfo *ports.TacInfo) (*po
 		PrevLink:      tacInfo.PrevLink,
 	}
 
-	// Use pkg/logic for TAC insertion with the imeiRepo
-	result := logic.InsertTac(s.imeiRepo, legacyTacInfo)
+	// Use pkg/logic for TAC insertion, atomically when transactions are available
+	var result legacyModels.InsertTacResult
+	switch {
+	case s.txs != nil:
+		result = logic.InsertTacTx(ctx, s.txs, legacyTacInfo)
+	case s.history != nil:
+		result = logic.InsertTacWithHistory(ctx, s.imeiRepo, s.unreportedHistory(), legacyTacInfo)
+	default:
+		result = logic.InsertTac(s.imeiRepo, legacyTacInfo)
+	}
 
 	var resultTacInfo *ports.TacInfo
 	if result.TacInfo.KeyTac != "" {
@@ -197,6 +343,10 @@ func (s *eirService) InsertTac(ctx context.Context, tacInfo *ports.TacInfo) (*po
 		s.getLogger().Errorw("InsertTac failed", "start_range", tacInfo.StartRangeTac, "end_range", tacInfo.EndRangeTac, "status", result.Status, "error", result.Error)
 	} else {
 		s.getLogger().Infow("InsertTac completed successfully", "start_range", tacInfo.StartRangeTac, "end_range", tacInfo.EndRangeTac, "status", result.Status, "key_tac", result.TacInfo.KeyTac)
+		if s.decisions != nil {
+			n := s.decisions.InvalidateMatching(ports.CheckKindTAC, logic.TacRangeMatcher(tacInfo.StartRangeTac, tacInfo.EndRangeTac))
+			s.getLogger().Debugw("InsertTac invalidated cached decisions", "start_range", tacInfo.StartRangeTac, "end_range", tacInfo.EndRangeTac, "count", n)
+		}
 	}
 
 	return &ports.InsertTacResult{
@@ -208,10 +358,16 @@ func (s *eirService) InsertTac(ctx context.Context, tacInfo *ports.TacInfo) (*po
 
 func (s *eirService) ClearTacInfo(ctx context.Context) {
 	logic.ClearTacInfo(s.imeiRepo)
+	if s.decisions != nil {
+		s.decisions.Purge(ports.CheckKindTAC)
+	}
 }
 
 func (s *eirService) ClearImeiInfo(ctx context.Context) {
 	logic.ClearImeiInfo(s.imeiRepo)
+	if s.decisions != nil {
+		s.decisions.Purge(ports.CheckKindIMEI)
+	}
 }
 
 // Helper function
@@ -219,8 +375,60 @@ func strPtr(s string) *string {
 	return &s
 }
 
-// GetEquipment retrieves equipment information
-func (s *eirService) GetEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+// ProvisionEquipment adds an equipment record and lists it in the IMEI lists
+// with the color of its status, so checks see it at once. A deleted record of
+// the same IMEI is replaced; a live one is left alone.
+func (s *eirService) ProvisionEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	s.getLogger().Infow("ProvisionEquipment started", "imei", equipment.IMEI, "status", equipment.Status)
+
+	if err := models.ValidateIMEI(equipment.IMEI); err != nil {
+		s.getLogger().Errorw("ProvisionEquipment IMEI validation failed", "imei", equipment.IMEI, "error", err)
+		return nil, fmt.Errorf("%w: invalid IMEI: %w", ErrInvalidRequest, err)
+	}
+	if err := models.ValidateStatus(equipment.Status); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	provisioned := *equipment
+	provisioned.LastUpdated = time.Now()
+	provisioned.AddedBy = ports.ActorFromContext(ctx)
+	provisioned.DeletedAt, provisioned.DeletedBy = nil, nil
+
+	details := models.ChangeDetails{"list": "equipment"}
+	err := s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		current, err := repo.GetByIMEI(ctx, provisioned.IMEI)
+		if err != nil {
+			provisioned.Version = 0
+			return s.createEquipment(ctx, repo, history, &provisioned, details)
+		}
+		if !current.IsDeleted() {
+			return ErrEquipmentExists
+		}
+
+		provisioned.ID = current.ID
+		provisioned.CheckCount, provisioned.LastCheckTime = current.CheckCount, current.LastCheckTime
+		provisioned.Version = current.Version
+		return s.updateEquipment(ctx, repo, history, current, &provisioned, details)
+	})
+	if err != nil {
+		s.getLogger().Errorw("ProvisionEquipment failed", "imei", equipment.IMEI, "error", err)
+		return nil, err
+	}
+
+	if s.cache != nil {
+		_ = s.cache.Delete(ctx, provisioned.IMEI)
+	}
+	if s.decisions != nil {
+		s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(provisioned.IMEI))
+	}
+
+	s.getLogger().Infow("ProvisionEquipment completed successfully", "imei", provisioned.IMEI, "status", provisioned.Status, "version", provisioned.Version)
+	return &provisioned, nil
+}
+
+// GetEquipment retrieves equipment information. A deleted record is only
+// returned with includeDeleted.
+func (s *eirService) GetEquipment(ctx context.Context, imei string, includeDeleted bool) (*models.Equipment, error) {
 	s.getLogger().Infow("GetEquipment started", "imei", imei)
 
 	if err := models.ValidateIMEI(imei); err != nil {
@@ -244,24 +452,39 @@ func (s *eirService) GetEquipment(ctx context.Context, imei string) (*models.Equ
 		s.getLogger().Errorw("GetEquipment not found in database", "imei", imei, "error", err)
 		return nil, ErrEquipmentNotFound
 	}
+	if equipment.IsDeleted() {
+		if !includeDeleted {
+			s.getLogger().Infow("GetEquipment found a deleted record", "imei", imei)
+			return nil, ErrEquipmentNotFound
+		}
+		// Tombstones are never cached, so cache hits are always live records
+		return equipment, nil
+	}
 
 	s.getLogger().Infow("GetEquipment completed successfully", "imei", imei, "status", equipment.Status)
 
-	// Update cache asynchronously
+	// Update cache asynchronously; detach from the request so the write
+	// is not cancelled once the response has been sent
 	if s.cache != nil {
+		cacheCtx := context.WithoutCancel(ctx)
 		go func() {
-			_ = s.cache.Set(ctx, imei, equipment, 300) // 5 minutes TTL
+			_ = s.cache.Set(cacheCtx, imei, equipment, 0) // cache default TTL
 		}()
 	}
 
 	return equipment, nil
 }
 
-// ListEquipment retrieves paginated equipment list
-func (s *eirService) ListEquipment(ctx context.Context, offset, limit int) ([]*models.Equipment, error) {
-	s.getLogger().Infow("ListEquipment started", "offset", offset, "limit", limit)
+// ListEquipment retrieves paginated equipment list, with deleted records
+// only when includeDeleted
+func (s *eirService) ListEquipment(ctx context.Context, offset, limit int, includeDeleted bool) ([]*models.Equipment, error) {
+	s.getLogger().Infow("ListEquipment started", "offset", offset, "limit", limit, "include_deleted", includeDeleted)
 
-	equipments, err := s.imeiRepo.List(ctx, offset, limit)
+	list := s.imeiRepo.List
+	if includeDeleted {
+		list = s.imeiRepo.ListWithDeleted
+	}
+	equipments, err := list(ctx, offset, limit)
 	if err != nil {
 		s.getLogger().Errorw("ListEquipment failed", "offset", offset, "limit", limit, "error", err)
 		return nil, err
@@ -271,7 +494,58 @@ func (s *eirService) ListEquipment(ctx context.Context, offset, limit int) ([]*m
 	return equipments, nil
 }
 
-// RemoveEquipment removes equipment from the system
+// UpdateEquipment changes the status, reason and descriptive fields of an
+// existing equipment record. A non-zero equipment.Version makes the update
+// conditional on it, failing with a ports.VersionConflictError when the record
+// has changed since that version was read.
+func (s *eirService) UpdateEquipment(ctx context.Context, equipment *models.Equipment) (*models.Equipment, error) {
+	s.getLogger().Infow("UpdateEquipment started", "imei", equipment.IMEI, "status", equipment.Status, "expected_version", equipment.Version)
+
+	if err := models.ValidateIMEI(equipment.IMEI); err != nil {
+		s.getLogger().Errorw("UpdateEquipment IMEI validation failed", "imei", equipment.IMEI, "error", err)
+		return nil, fmt.Errorf("invalid IMEI: %w", err)
+	}
+	if err := models.ValidateStatus(equipment.Status); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	var updated *models.Equipment
+	err := s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		current, err := repo.GetByIMEI(ctx, equipment.IMEI)
+		if err != nil || current.IsDeleted() {
+			return ErrEquipmentNotFound
+		}
+
+		changed := *current
+		changed.IMEISV = equipment.IMEISV
+		changed.Status = equipment.Status
+		changed.Reason = equipment.Reason
+		changed.Metadata = equipment.Metadata
+		changed.ManufacturerTAC = equipment.ManufacturerTAC
+		changed.ManufacturerName = equipment.ManufacturerName
+		changed.LastUpdated = time.Now()
+		changed.Version = equipment.Version
+		updated = &changed
+		return s.updateEquipment(ctx, repo, history, current, updated, models.ChangeDetails{"list": "equipment"})
+	})
+	if err != nil {
+		s.getLogger().Errorw("UpdateEquipment failed", "imei", equipment.IMEI, "error", err)
+		return nil, err
+	}
+
+	if s.cache != nil {
+		_ = s.cache.Delete(ctx, equipment.IMEI)
+	}
+	if s.decisions != nil {
+		s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(equipment.IMEI))
+	}
+
+	s.getLogger().Infow("UpdateEquipment completed successfully", "imei", equipment.IMEI, "status", updated.Status, "version", updated.Version)
+	return updated, nil
+}
+
+// RemoveEquipment deletes equipment from the system, leaving a tombstone
+// that UndeleteEquipment can bring back until it is purged
 func (s *eirService) RemoveEquipment(ctx context.Context, imei string) error {
 	s.getLogger().Infow("RemoveEquipment started", "imei", imei)
 
@@ -280,16 +554,218 @@ func (s *eirService) RemoveEquipment(ctx context.Context, imei string) error {
 		return fmt.Errorf("invalid IMEI: %w", err)
 	}
 
-	if err := s.imeiRepo.Delete(ctx, imei); err != nil {
+	err := s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		return removeEquipment(ctx, repo, history, imei)
+	})
+	if err != nil {
 		s.getLogger().Errorw("RemoveEquipment failed to delete from database", "imei", imei, "error", err)
-		return fmt.Errorf("failed to delete equipment: %w", err)
+		return err
 	}
 
 	// Invalidate cache
 	if s.cache != nil {
 		_ = s.cache.Delete(ctx, imei)
 	}
+	if s.decisions != nil {
+		s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(imei))
+		s.decisions.Invalidate(ports.CheckKindTAC, imei)
+	}
 
 	s.getLogger().Infow("RemoveEquipment completed successfully", "imei", imei)
 	return nil
 }
+
+// removeEquipment turns the equipment record of imei into a tombstone,
+// recording its last state in history when one is given
+func removeEquipment(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, imei string) error {
+	current, err := repo.GetByIMEI(ctx, imei)
+	if err != nil || current.IsDeleted() {
+		return ErrEquipmentNotFound
+	}
+
+	now := time.Now()
+	actor := ports.ActorFromContext(ctx)
+	tombstone := *current
+	tombstone.DeletedAt = &now
+	tombstone.DeletedBy = &actor
+	tombstone.LastUpdated = now
+	if err := repo.Update(ctx, &tombstone); err != nil {
+		return fmt.Errorf("failed to delete equipment: %w", err)
+	}
+	if err := syncImeiInfo(ctx, repo, history, &tombstone); err != nil {
+		return err
+	}
+
+	if history == nil {
+		return nil
+	}
+	return history.RecordChange(ctx, &models.EquipmentHistory{
+		IMEI:           imei,
+		ChangeType:     models.ChangeTypeDelete,
+		ChangedAt:      now,
+		ChangedBy:      actor,
+		PreviousStatus: &current.Status,
+		NewStatus:      current.Status,
+		PreviousReason: current.Reason,
+		ChangeDetails:  models.ChangeDetails{"list": "equipment"},
+	})
+}
+
+// UndeleteEquipment brings back a deleted equipment record as it was when
+// it was deleted
+func (s *eirService) UndeleteEquipment(ctx context.Context, imei string) (*models.Equipment, error) {
+	s.getLogger().Infow("UndeleteEquipment started", "imei", imei)
+
+	if err := models.ValidateIMEI(imei); err != nil {
+		s.getLogger().Errorw("UndeleteEquipment IMEI validation failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("invalid IMEI: %w", err)
+	}
+
+	var restored *models.Equipment
+	err := s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		current, err := repo.GetByIMEI(ctx, imei)
+		if err != nil {
+			return ErrEquipmentNotFound
+		}
+		if !current.IsDeleted() {
+			return ErrEquipmentNotDeleted
+		}
+
+		undeleted := *current
+		undeleted.DeletedAt, undeleted.DeletedBy = nil, nil
+		undeleted.LastUpdated = time.Now()
+		if err := repo.Update(ctx, &undeleted); err != nil {
+			return fmt.Errorf("failed to undelete equipment: %w", err)
+		}
+		if err := syncImeiInfo(ctx, repo, history, &undeleted); err != nil {
+			return err
+		}
+		restored = &undeleted
+
+		if history == nil {
+			return nil
+		}
+		return history.RecordChange(ctx, &models.EquipmentHistory{
+			IMEI:          imei,
+			ChangeType:    models.ChangeTypeCreate,
+			ChangedAt:     undeleted.LastUpdated,
+			ChangedBy:     ports.ActorFromContext(ctx),
+			NewStatus:     undeleted.Status,
+			NewReason:     undeleted.Reason,
+			ChangeDetails: models.ChangeDetails{"list": "equipment", "undeleted": true},
+		})
+	})
+	if err != nil {
+		s.getLogger().Errorw("UndeleteEquipment failed", "imei", imei, "error", err)
+		return nil, err
+	}
+
+	if s.decisions != nil {
+		s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(imei))
+		s.decisions.Invalidate(ports.CheckKindTAC, imei)
+	}
+
+	s.getLogger().Infow("UndeleteEquipment completed successfully", "imei", imei, "status", restored.Status)
+	return restored, nil
+}
+
+// GetEquipmentHistory retrieves the recorded changes to an IMEI, newest first
+func (s *eirService) GetEquipmentHistory(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentHistory, error) {
+	s.getLogger().Infow("GetEquipmentHistory started", "imei", imei, "offset", offset, "limit", limit)
+
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	entries, err := s.history.GetHistoryByIMEI(ctx, imei, offset, limit)
+	if err != nil {
+		s.getLogger().Errorw("GetEquipmentHistory failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("failed to get equipment history: %w", err)
+	}
+
+	s.getLogger().Infow("GetEquipmentHistory completed successfully", "imei", imei, "count", len(entries))
+	return entries, nil
+}
+
+// GetAuditsByIMEI retrieves the audit logs of the checks of an IMEI
+func (s *eirService) GetAuditsByIMEI(ctx context.Context, imei string, offset, limit int) ([]*models.AuditLog, error) {
+	s.getLogger().Infow("GetAuditsByIMEI started", "imei", imei, "offset", offset, "limit", limit)
+
+	if s.auditRepo == nil {
+		return nil, ErrAuditsUnavailable
+	}
+
+	audits, err := s.auditRepo.GetAuditsByIMEI(ctx, imei, offset, limit)
+	if err != nil {
+		s.getLogger().Errorw("GetAuditsByIMEI failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("failed to get audits: %w", err)
+	}
+
+	s.getLogger().Infow("GetAuditsByIMEI completed successfully", "imei", imei, "count", len(audits))
+	return audits, nil
+}
+
+// GetAuditsBySUPI retrieves the audit logs of the checks made for a
+// subscriber, newest first. The SUPI itself is never logged.
+func (s *eirService) GetAuditsBySUPI(ctx context.Context, supi string, offset, limit int) ([]*models.AuditLog, error) {
+	s.getLogger().Infow("GetAuditsBySUPI started", "offset", offset, "limit", limit)
+
+	if s.auditRepo == nil {
+		return nil, ErrAuditsUnavailable
+	}
+
+	audits, err := s.auditRepo.GetAuditsBySUPI(ctx, []string{supi}, offset, limit)
+	if err != nil {
+		s.getLogger().Errorw("GetAuditsBySUPI failed", "error", err)
+		return nil, fmt.Errorf("failed to get audits: %w", err)
+	}
+
+	s.getLogger().Infow("GetAuditsBySUPI completed successfully", "count", len(audits))
+	return audits, nil
+}
+
+// inTransaction runs fn against the repositories of a new transaction when a
+// provider is set, committing only if fn succeeds. Without one, fn runs
+// directly against the service repositories.
+func (s *eirService) inTransaction(ctx context.Context, fn func(repo ports.IMEIRepository, history ports.HistoryRepository) error) error {
+	if s.txs == nil {
+		var history ports.HistoryRepository
+		if s.history != nil {
+			history = s.unreportedHistory()
+		}
+		return fn(s.imeiRepo, history)
+	}
+
+	tx, err := s.txs.BeginTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx.GetIMEIRepository(), tx.GetHistoryRepository()); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			s.getLogger().Errorw("Transaction rollback failed", "error", rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// unreportedHistory records history outside a transaction. The change it
+// describes is already stored by then, so failures are logged, not returned.
+func (s *eirService) unreportedHistory() ports.HistoryRepository {
+	return loggedHistory{HistoryRepository: s.history, log: s.getLogger()}
+}
+
+type loggedHistory struct {
+	ports.HistoryRepository
+	log logger.Logger
+}
+
+func (h loggedHistory) RecordChange(ctx context.Context, entry *models.EquipmentHistory) error {
+	if err := h.HistoryRepository.RecordChange(ctx, entry); err != nil {
+		h.log.Warnw("Failed to record equipment history", "imei", entry.IMEI, "change_type", entry.ChangeType, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/robfig/cron/v3"
+)
+
+// ErrMaintenanceRunning is returned when a maintenance run is requested while one is in progress
+var ErrMaintenanceRunning = errors.New("a maintenance run is already in progress")
+
+// purgeFunc deletes at most limit records older than before
+type purgeFunc func(ctx context.Context, before time.Time, limit int) (int64, error)
+
+// maintenanceDataset is one kind of record the scheduler applies a retention to
+type maintenanceDataset struct {
+	name      string
+	retention time.Duration
+	purge     purgeFunc
+}
+
+// MaintenanceScheduler purges audit logs, change history, snapshots and
+// equipment tombstones past their retention and optionally optimizes the database afterwards. Purges
+// run in batches with a pause between them, and scheduled runs only delete
+// inside the configured window, resuming at the next run when it closes.
+type MaintenanceScheduler struct {
+	database ports.DatabaseAdapter
+	cfg      config.MaintenanceConfig
+	schedule cron.Schedule // nil when runs are only started manually
+
+	window                 bool
+	windowStart, windowEnd int // minutes past midnight
+
+	mu      sync.Mutex
+	running bool
+	lastRun *models.MaintenanceRun
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMaintenanceScheduler creates a scheduler applying cfg to database. The
+// schedule is only parsed when cfg.Enabled; manual runs are always possible.
+func NewMaintenanceScheduler(database ports.DatabaseAdapter, cfg config.MaintenanceConfig) (*MaintenanceScheduler, error) {
+	if cfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	m := &MaintenanceScheduler{database: database, cfg: cfg}
+	if cfg.Enabled {
+		schedule, err := cron.ParseStandard(cfg.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse maintenance schedule: %w", err)
+		}
+		m.schedule = schedule
+	}
+	if cfg.WindowStart != "" || cfg.WindowEnd != "" {
+		start, err := minutesOfDay(cfg.WindowStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse window start: %w", err)
+		}
+		end, err := minutesOfDay(cfg.WindowEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse window end: %w", err)
+		}
+		m.window, m.windowStart, m.windowEnd = true, start, end
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	return m, nil
+}
+
+// Start runs the schedule in the background until Stop is called. It does
+// nothing when the scheduler was created disabled.
+func (m *MaintenanceScheduler) Start() {
+	if m.schedule == nil {
+		return
+	}
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop ends the schedule, interrupting a run in progress between batches
+func (m *MaintenanceScheduler) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// RunNow starts a manual run in the background. Manual runs ignore the window.
+func (m *MaintenanceScheduler) RunNow() error {
+	if !m.begin() {
+		return ErrMaintenanceRunning
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run(m.ctx, models.MaintenanceTriggerManual)
+	}()
+	return nil
+}
+
+// Run performs a run synchronously and returns its report
+func (m *MaintenanceScheduler) Run(ctx context.Context, trigger models.MaintenanceTrigger) (*models.MaintenanceRun, error) {
+	if !m.begin() {
+		return nil, ErrMaintenanceRunning
+	}
+	return m.run(ctx, trigger), nil
+}
+
+// Status reports whether a run is in progress, when the next one is due and
+// how the last one went
+func (m *MaintenanceScheduler) Status() models.MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := models.MaintenanceStatus{Running: m.running}
+	if m.schedule != nil {
+		next := m.schedule.Next(time.Now())
+		status.NextRun = &next
+	}
+	if m.lastRun != nil {
+		last := *m.lastRun
+		last.Datasets = append([]models.DatasetPurge(nil), m.lastRun.Datasets...)
+		status.LastRun = &last
+	}
+	return status
+}
+
+func (m *MaintenanceScheduler) loop() {
+	defer m.wg.Done()
+
+	for {
+		timer := time.NewTimer(time.Until(m.schedule.Next(time.Now())))
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := m.Run(m.ctx, models.MaintenanceTriggerSchedule); err != nil {
+			logger.Log.Warnw("Scheduled maintenance skipped", "error", err)
+		}
+	}
+}
+
+// begin marks a run as in progress, reporting false if one already is
+func (m *MaintenanceScheduler) begin() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return false
+	}
+	m.running = true
+	return true
+}
+
+func (m *MaintenanceScheduler) run(ctx context.Context, trigger models.MaintenanceTrigger) *models.MaintenanceRun {
+	started := time.Now()
+	run := &models.MaintenanceRun{
+		Trigger:   trigger,
+		StartedAt: started,
+		Datasets:  []models.DatasetPurge{},
+	}
+	logger.Log.Infow("Maintenance run started", "trigger", trigger)
+
+	enforceWindow := trigger == models.MaintenanceTriggerSchedule
+	complete, failed := true, false
+	for _, dataset := range m.datasets() {
+		purge := m.purge(ctx, dataset, started.Add(-dataset.retention), enforceWindow)
+		run.Datasets = append(run.Datasets, purge)
+		complete = complete && purge.Complete
+		failed = failed || purge.Error != ""
+	}
+
+	if complete && m.cfg.Optimize && (!enforceWindow || m.inWindow(time.Now())) {
+		if err := m.database.OptimizeDatabase(ctx); err != nil {
+			run.Error = fmt.Sprintf("failed to optimize database: %v", err)
+			failed = true
+		} else {
+			run.Optimized = true
+		}
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+
+	result := "complete"
+	switch {
+	case failed:
+		result = "error"
+	case !complete:
+		result = "incomplete"
+	}
+	logger.MaintenanceRunsTotal.WithLabelValues(string(trigger), result).Inc()
+	logger.MaintenanceRunDuration.Observe(finished.Sub(started).Seconds())
+	logger.MaintenanceLastRun.Set(float64(finished.Unix()))
+
+	m.mu.Lock()
+	m.running = false
+	m.lastRun = run
+	m.mu.Unlock()
+
+	logger.Log.Infow("Maintenance run finished", "trigger", trigger, "result", result, "duration", finished.Sub(started).String())
+	return run
+}
+
+// purge deletes the records of dataset older than before, batch by batch
+func (m *MaintenanceScheduler) purge(ctx context.Context, dataset maintenanceDataset, before time.Time, enforceWindow bool) models.DatasetPurge {
+	result := models.DatasetPurge{Dataset: dataset.name, Before: before}
+	for {
+		if err := ctx.Err(); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if enforceWindow && !m.inWindow(time.Now()) {
+			logger.Log.Infow("Maintenance window closed, resuming at the next run", "dataset", dataset.name, "purged", result.Purged)
+			return result
+		}
+
+		purged, err := dataset.purge(ctx, before, m.cfg.BatchSize)
+		result.Batches++
+		result.Purged += purged
+		logger.MaintenancePurgedTotal.WithLabelValues(dataset.name).Add(float64(purged))
+		if err != nil {
+			logger.Log.Errorw("Maintenance purge failed", "dataset", dataset.name, "error", err)
+			result.Error = err.Error()
+			return result
+		}
+		if purged < int64(m.cfg.BatchSize) {
+			result.Complete = true
+			return result
+		}
+
+		if m.cfg.BatchPause > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(m.cfg.BatchPause):
+			}
+		}
+	}
+}
+
+// datasets returns the datasets with a retention, purged in batches when the
+// adapter supports it and in a single statement otherwise
+func (m *MaintenanceScheduler) datasets() []maintenanceDataset {
+	var audits, history, snapshots purgeFunc
+	if batcher, ok := m.database.(ports.BatchPurger); ok {
+		audits, history, snapshots = batcher.PurgeAuditsBatch, batcher.PurgeHistoryBatch, batcher.PurgeSnapshotsBatch
+	} else {
+		audits = func(ctx context.Context, before time.Time, _ int) (int64, error) {
+			return m.database.PurgeOldAudits(ctx, before.UTC().Format("2006-01-02 15:04:05"))
+		}
+		history = func(ctx context.Context, before time.Time, _ int) (int64, error) {
+			return m.database.PurgeOldHistory(ctx, before.UTC().Format("2006-01-02 15:04:05"))
+		}
+		snapshots = func(ctx context.Context, before time.Time, _ int) (int64, error) {
+			return m.database.GetSnapshotRepository().DeleteOldSnapshots(ctx, before)
+		}
+	}
+
+	var datasets []maintenanceDataset
+	for _, d := range []maintenanceDataset{
+		{name: "audits", retention: m.cfg.AuditRetention, purge: audits},
+		{name: "history", retention: m.cfg.HistoryRetention, purge: history},
+		{name: "snapshots", retention: m.cfg.SnapshotRetention, purge: snapshots},
+		{name: "tombstones", retention: m.cfg.TombstoneRetention, purge: func(ctx context.Context, before time.Time, limit int) (int64, error) {
+			return m.database.GetIMEIRepository().PurgeDeleted(ctx, before, limit)
+		}},
+	} {
+		if d.retention > 0 {
+			datasets = append(datasets, d)
+		}
+	}
+	return datasets
+}
+
+// inWindow reports whether deletes may run at t
+func (m *MaintenanceScheduler) inWindow(t time.Time) bool {
+	if !m.window {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if m.windowStart <= m.windowEnd {
+		return minute >= m.windowStart && minute < m.windowEnd
+	}
+	// The window spans midnight
+	return minute >= m.windowStart || minute < m.windowEnd
+}
+
+// minutesOfDay parses an "HH:MM" time of day
+func minutesOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/pkg/logic"
+)
+
+// scanPageSize is the page size used when reading a whole repository
+const scanPageSize = 1000
+
+// GetStatusAt reconstructs the status of imei at the moment at by replaying
+// the change history up to it. The equipment record starts from the latest
+// snapshot taken at or before at, when a snapshot repository is set.
+//
+// Per-IMEI provisioning is more specific than a TAC range, so an IMEI list
+// entry decides the status first, then the equipment record, then the
+// innermost enclosing TAC range. Changes whose history was purged are not
+// reflected.
+func (s *eirService) GetStatusAt(ctx context.Context, imei string, at time.Time) (*models.PointInTimeStatus, error) {
+	s.getLogger().Infow("GetStatusAt started", "imei", imei, "at", at)
+
+	if err := models.ValidateIMEI(imei); err != nil {
+		s.getLogger().Warnw("GetStatusAt IMEI validation failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	entries, err := s.historyAt(ctx, imei, at)
+	if err != nil {
+		s.getLogger().Errorw("GetStatusAt failed to read history", "imei", imei, "error", err)
+		return nil, err
+	}
+
+	var snapshot *models.EquipmentSnapshot
+	if s.snapshots != nil {
+		if snapshot, err = s.snapshots.GetLatestSnapshot(ctx, imei, at); err != nil {
+			s.getLogger().Errorw("GetStatusAt failed to read snapshots", "imei", imei, "error", err)
+			return nil, fmt.Errorf("failed to get equipment snapshot: %w", err)
+		}
+	}
+
+	result := reconstructStatus(imei, at, entries, snapshot)
+	s.getLogger().Infow("GetStatusAt completed successfully", "imei", imei, "at", at, "source", result.Source, "contributions", len(result.Contributions))
+	return result, nil
+}
+
+// historyAt returns, oldest first, the history entries recorded at or before
+// at that can decide the status of imei: the changes to its equipment record,
+// to the IMEI_INFO entry a check of it resolves to and to the TAC ranges
+// enclosing it
+func (s *eirService) historyAt(ctx context.Context, imei string, at time.Time) ([]*models.EquipmentHistory, error) {
+	entries, err := s.history.GetListHistory(ctx, logic.ImeiInfoStart(imei), logic.TacValue(imei), at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list history: %w", err)
+	}
+
+	var own []*models.EquipmentHistory
+	for offset := 0; ; offset += scanPageSize {
+		page, err := s.history.GetHistoryByIMEI(ctx, imei, offset, scanPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get equipment history: %w", err)
+		}
+		for _, entry := range page {
+			// List entries keyed by imei came with the list history
+			if list := detail(entry, "list"); (list == "equipment" || list == "") && !entry.ChangedAt.After(at) {
+				own = append(own, entry)
+			}
+		}
+		if len(page) < scanPageSize {
+			break
+		}
+	}
+	for i := len(own) - 1; i >= 0; i-- {
+		entries = append(entries, own[i])
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ChangedAt.Before(entries[j].ChangedAt)
+	})
+	return entries, nil
+}
+
+// reconstructStatus replays entries, oldest first, on top of snapshot
+func reconstructStatus(imei string, at time.Time, entries []*models.EquipmentHistory, snapshot *models.EquipmentSnapshot) *models.PointInTimeStatus {
+	matchesImei := logic.ImeiMatcher(imei)
+
+	var imeiEntry *models.StatusContribution
+	var equipment *models.StatusContribution
+	if snapshot != nil {
+		equipment = &models.StatusContribution{
+			Source:   models.StatusSourceEquipment,
+			Status:   snapshot.Status,
+			History:  []*models.EquipmentHistory{},
+			Snapshot: snapshot,
+		}
+	}
+	var tac *models.EquipmentHistory
+	var tacLo, tacHi string
+
+	for _, entry := range entries {
+		if entry.ChangeType == models.ChangeTypeCheck {
+			continue
+		}
+
+		switch detail(entry, "list") {
+		case "imei":
+			if !matchesImei(detail(entry, "start_imei")) {
+				continue
+			}
+			if entry.ChangeType == models.ChangeTypeDelete {
+				imeiEntry = nil
+				continue
+			}
+			if imeiEntry == nil {
+				imeiEntry = &models.StatusContribution{Source: models.StatusSourceIMEI}
+			}
+			imeiEntry.Status = entry.NewStatus
+			imeiEntry.History = append(imeiEntry.History, entry)
+
+		case "tac":
+			end := detail(entry, "end_tac")
+			if !logic.TacRangeMatcher(entry.IMEI, end)(imei) {
+				continue
+			}
+			lo, hi := logic.TacRangeBounds(entry.IMEI, end)
+			if tac == nil || lo > tacLo || (lo == tacLo && hi <= tacHi) {
+				tac, tacLo, tacHi = entry, lo, hi
+			}
+
+		case "equipment", "":
+			// Entries without a list were written by the equipment trigger
+			if entry.IMEI != imei || (snapshot != nil && !entry.ChangedAt.After(snapshot.SnapshotTime)) {
+				continue
+			}
+			if entry.ChangeType == models.ChangeTypeDelete {
+				equipment = nil
+				continue
+			}
+			if equipment == nil {
+				equipment = &models.StatusContribution{Source: models.StatusSourceEquipment}
+			}
+			equipment.Status = entry.NewStatus
+			equipment.History = append(equipment.History, entry)
+		}
+	}
+
+	result := &models.PointInTimeStatus{
+		IMEI:          imei,
+		At:            at,
+		Contributions: []models.StatusContribution{},
+	}
+	if imeiEntry != nil {
+		result.Contributions = append(result.Contributions, *imeiEntry)
+	}
+	if equipment != nil {
+		result.Contributions = append(result.Contributions, *equipment)
+	}
+	if tac != nil {
+		result.Contributions = append(result.Contributions, models.StatusContribution{
+			Source:  models.StatusSourceTAC,
+			Status:  tac.NewStatus,
+			History: []*models.EquipmentHistory{tac},
+		})
+	}
+
+	if len(result.Contributions) > 0 {
+		effective := &result.Contributions[0]
+		effective.Effective = true
+		status := effective.Status
+		result.Status = &status
+		result.Source = effective.Source
+	}
+	return result
+}
+
+// detail returns the string stored under key in the details of entry
+func detail(entry *models.EquipmentHistory, key string) string {
+	value, _ := entry.ChangeDetails[key].(string)
+	return value
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/robfig/cron/v3"
+)
+
+// SnapshotScheduler takes SCHEDULED snapshots of every equipment record on a
+// cron schedule and then deletes the snapshots older than the retention
+type SnapshotScheduler struct {
+	svc       ports.EIRService
+	schedule  cron.Schedule
+	retention time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewSnapshotScheduler creates a scheduler running cfg.Schedule against svc
+func NewSnapshotScheduler(svc ports.EIRService, cfg config.SnapshotConfig) (*SnapshotScheduler, error) {
+	schedule, err := cron.ParseStandard(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot schedule: %w", err)
+	}
+	return &SnapshotScheduler{
+		svc:       svc,
+		schedule:  schedule,
+		retention: cfg.Retention,
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// Start runs the schedule in the background until Stop is called
+func (s *SnapshotScheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop ends the schedule and waits for a run in progress to finish
+func (s *SnapshotScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
+
+// RunOnce snapshots every equipment record, then applies the retention
+func (s *SnapshotScheduler) RunOnce(ctx context.Context) error {
+	ctx = ports.WithActor(ctx, ports.SystemActor)
+	count, err := s.svc.SnapshotAllEquipment(ctx)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	if s.retention > 0 {
+		if deleted, err = s.svc.PurgeSnapshots(ctx, time.Now().Add(-s.retention)); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Infow("Scheduled snapshot run completed", "snapshots", count, "purged", deleted)
+	return nil
+}
+
+func (s *SnapshotScheduler) loop() {
+	defer s.wg.Done()
+
+	for {
+		timer := time.NewTimer(time.Until(s.schedule.Next(time.Now())))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.RunOnce(context.Background()); err != nil {
+			logger.Log.Errorw("Scheduled snapshot run failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/pkg/logic"
+)
+
+// SnapshotEquipment takes a MANUAL snapshot of the equipment record of imei
+func (s *eirService) SnapshotEquipment(ctx context.Context, imei string) (*models.EquipmentSnapshot, error) {
+	s.getLogger().Infow("SnapshotEquipment started", "imei", imei)
+
+	if s.snapshots == nil {
+		return nil, ErrSnapshotsUnavailable
+	}
+
+	equipment, err := s.imeiRepo.GetByIMEI(ctx, imei)
+	if err != nil || equipment.IsDeleted() {
+		s.getLogger().Warnw("SnapshotEquipment equipment not found", "imei", imei, "error", err)
+		return nil, ErrEquipmentNotFound
+	}
+
+	snapshot := snapshotOf(ctx, equipment, models.SnapshotTypeManual)
+	if err := s.snapshots.CreateSnapshot(ctx, snapshot); err != nil {
+		s.getLogger().Errorw("SnapshotEquipment failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	s.getLogger().Infow("SnapshotEquipment completed successfully", "imei", imei, "snapshot_id", snapshot.ID)
+	return snapshot, nil
+}
+
+// SnapshotAllEquipment takes a SCHEDULED snapshot of every equipment record
+// and returns how many were taken
+func (s *eirService) SnapshotAllEquipment(ctx context.Context) (int, error) {
+	s.getLogger().Infow("SnapshotAllEquipment started")
+
+	if s.snapshots == nil {
+		return 0, ErrSnapshotsUnavailable
+	}
+
+	// Read every page before writing, so the snapshots reflect one pass
+	var equipments []*models.Equipment
+	for offset := 0; ; offset += scanPageSize {
+		page, err := s.imeiRepo.List(ctx, offset, scanPageSize)
+		if err != nil {
+			s.getLogger().Errorw("SnapshotAllEquipment failed to list equipment", "offset", offset, "error", err)
+			return 0, fmt.Errorf("failed to list equipment: %w", err)
+		}
+		equipments = append(equipments, page...)
+		if len(page) < scanPageSize {
+			break
+		}
+	}
+
+	for i, equipment := range equipments {
+		if err := s.snapshots.CreateSnapshot(ctx, snapshotOf(ctx, equipment, models.SnapshotTypeScheduled)); err != nil {
+			s.getLogger().Errorw("SnapshotAllEquipment failed", "imei", equipment.IMEI, "taken", i, "error", err)
+			return i, fmt.Errorf("failed to create snapshot: %w", err)
+		}
+	}
+
+	s.getLogger().Infow("SnapshotAllEquipment completed successfully", "count", len(equipments))
+	return len(equipments), nil
+}
+
+// ListSnapshots retrieves the snapshots of imei, newest first
+func (s *eirService) ListSnapshots(ctx context.Context, imei string, offset, limit int) ([]*models.EquipmentSnapshot, error) {
+	if s.snapshots == nil {
+		return nil, ErrSnapshotsUnavailable
+	}
+
+	snapshots, err := s.snapshots.GetSnapshotsByIMEI(ctx, imei, offset, limit)
+	if err != nil {
+		s.getLogger().Errorw("ListSnapshots failed", "imei", imei, "error", err)
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// RestoreSnapshot rolls the equipment record of imei back to the state saved
+// in a snapshot, recreating or undeleting the record if it was removed since. A non-zero
+// expectedVersion makes the restore conditional on the record's version.
+func (s *eirService) RestoreSnapshot(ctx context.Context, imei string, snapshotID int64, expectedVersion int64) (*models.Equipment, error) {
+	s.getLogger().Infow("RestoreSnapshot started", "imei", imei, "snapshot_id", snapshotID, "expected_version", expectedVersion)
+
+	if s.snapshots == nil {
+		return nil, ErrSnapshotsUnavailable
+	}
+
+	snapshot, err := s.snapshots.GetSnapshotByID(ctx, snapshotID)
+	if err != nil || snapshot.IMEI != imei {
+		s.getLogger().Warnw("RestoreSnapshot snapshot not found", "imei", imei, "snapshot_id", snapshotID, "error", err)
+		return nil, ErrSnapshotNotFound
+	}
+
+	details := models.ChangeDetails{"list": "equipment", "restored_snapshot": snapshot.ID}
+	var restored *models.Equipment
+	err = s.inTransaction(ctx, func(repo ports.IMEIRepository, history ports.HistoryRepository) error {
+		current, err := repo.GetByIMEI(ctx, imei)
+		if err != nil {
+			if expectedVersion != 0 {
+				return &ports.VersionConflictError{Entity: "equipment", Key: imei, Expected: expectedVersion}
+			}
+			restored = &models.Equipment{
+				IMEI:        imei,
+				Status:      snapshot.Status,
+				Reason:      snapshot.Reason,
+				Metadata:    snapshot.Metadata,
+				CheckCount:  snapshot.CheckCount,
+				LastUpdated: time.Now(),
+				AddedBy:     ports.ActorFromContext(ctx),
+			}
+			return s.createEquipment(ctx, repo, history, restored, details)
+		}
+
+		updated := *current
+		updated.Status = snapshot.Status
+		updated.Reason = snapshot.Reason
+		updated.Metadata = snapshot.Metadata
+		updated.LastUpdated = time.Now()
+		updated.Version = expectedVersion
+		updated.DeletedAt, updated.DeletedBy = nil, nil
+		restored = &updated
+		return s.updateEquipment(ctx, repo, history, current, restored, details)
+	})
+	if err != nil {
+		s.getLogger().Errorw("RestoreSnapshot failed", "imei", imei, "snapshot_id", snapshotID, "error", err)
+		return nil, err
+	}
+
+	if s.cache != nil {
+		_ = s.cache.Delete(ctx, imei)
+	}
+	if s.decisions != nil {
+		s.decisions.InvalidateMatching(ports.CheckKindIMEI, logic.ImeiMatcher(imei))
+	}
+
+	s.getLogger().Infow("RestoreSnapshot completed successfully", "imei", imei, "snapshot_id", snapshotID, "status", restored.Status)
+	return restored, nil
+}
+
+// PurgeSnapshots deletes the snapshots taken before the given time
+func (s *eirService) PurgeSnapshots(ctx context.Context, before time.Time) (int64, error) {
+	if s.snapshots == nil {
+		return 0, ErrSnapshotsUnavailable
+	}
+
+	deleted, err := s.snapshots.DeleteOldSnapshots(ctx, before)
+	if err != nil {
+		s.getLogger().Errorw("PurgeSnapshots failed", "before", before, "error", err)
+		return 0, fmt.Errorf("failed to delete old snapshots: %w", err)
+	}
+
+	s.getLogger().Infow("PurgeSnapshots completed successfully", "before", before, "deleted", deleted)
+	return deleted, nil
+}
+
+// createEquipment adds equipment to repo and records its creation in history
+func (s *eirService) createEquipment(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, equipment *models.Equipment, details models.ChangeDetails) error {
+	if err := repo.Create(ctx, equipment); err != nil {
+		return fmt.Errorf("failed to create equipment: %w", err)
+	}
+	if err := syncImeiInfo(ctx, repo, history, equipment); err != nil {
+		return err
+	}
+	if history == nil {
+		return nil
+	}
+	return history.RecordChange(ctx, &models.EquipmentHistory{
+		IMEI:          equipment.IMEI,
+		ChangeType:    models.ChangeTypeCreate,
+		ChangedAt:     time.Now(),
+		ChangedBy:     ports.ActorFromContext(ctx),
+		NewStatus:     equipment.Status,
+		NewReason:     equipment.Reason,
+		ChangeDetails: details,
+	})
+}
+
+// updateEquipment replaces current with updated in repo and records the change
+// in history. A status change is preceded by a PRE_UPDATE snapshot of current;
+// the snapshot is taken outside any transaction, which is harmless if the
+// update then fails as it still records a real past state. An update whose
+// non-zero Version is already stale fails before the snapshot is taken.
+func (s *eirService) updateEquipment(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, current, updated *models.Equipment, details models.ChangeDetails) error {
+	if _, err := ports.NextVersion("equipment", updated.IMEI, updated.Version, current.Version); err != nil {
+		return err
+	}
+	if s.snapshots != nil && current.Status != updated.Status {
+		if err := s.snapshots.CreateSnapshot(ctx, snapshotOf(ctx, current, models.SnapshotTypePreUpdate)); err != nil {
+			return fmt.Errorf("failed to create snapshot: %w", err)
+		}
+	}
+
+	if err := repo.Update(ctx, updated); err != nil {
+		return fmt.Errorf("failed to update equipment: %w", err)
+	}
+	if err := syncImeiInfo(ctx, repo, history, updated); err != nil {
+		return err
+	}
+	if history == nil {
+		return nil
+	}
+	return history.RecordChange(ctx, &models.EquipmentHistory{
+		IMEI:           updated.IMEI,
+		ChangeType:     models.ChangeTypeUpdate,
+		ChangedAt:      time.Now(),
+		ChangedBy:      ports.ActorFromContext(ctx),
+		PreviousStatus: &current.Status,
+		NewStatus:      updated.Status,
+		PreviousReason: current.Reason,
+		NewReason:      updated.Reason,
+		ChangeDetails:  details,
+	})
+}
+
+// syncImeiInfo keeps the IMEI lists that checks read in step with an
+// equipment record: a live record is listed with the color of its status and
+// a deleted one is taken out, with the list change recorded in history
+func syncImeiInfo(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, equipment *models.Equipment) error {
+	var err error
+	if equipment.IsDeleted() {
+		err = logic.RemoveImei(ctx, repo, history, equipment.IMEI)
+	} else {
+		err = logic.PutImei(ctx, repo, history, equipment.IMEI, equipment.Status)
+	}
+	if errors.Is(err, logic.ErrImeiColorConflict) {
+		return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update IMEI lists: %w", err)
+	}
+	return nil
+}
+
+// snapshotOf captures the current state of equipment
+func snapshotOf(ctx context.Context, equipment *models.Equipment, snapshotType string) *models.EquipmentSnapshot {
+	return &models.EquipmentSnapshot{
+		EquipmentID:  equipment.ID,
+		IMEI:         equipment.IMEI,
+		SnapshotTime: time.Now(),
+		Status:       equipment.Status,
+		Reason:       equipment.Reason,
+		CheckCount:   equipment.CheckCount,
+		Metadata:     equipment.Metadata,
+		CreatedBy:    ports.ActorFromContext(ctx),
+		SnapshotType: snapshotType,
+	}
+}
@@ -43,7 +43,7 @@ var (
 			Name: "eir_cache_hit_total",
 			Help: "Total number of cache hits and misses",
 		},
-		[]string{"result"}, // "hit" or "miss"
+		[]string{"cache", "result"}, // cache: "redis" or "decision"; result: "hit" or "miss"
 	)
 
 	// ActiveConnections tracks active Diameter connections
@@ -62,6 +62,86 @@ var (
 		},
 		[]string{"status"},
 	)
+
+	// MaintenanceRunsTotal counts maintenance runs by trigger and result
+	MaintenanceRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eir_maintenance_runs_total",
+			Help: "Total number of database maintenance runs",
+		},
+		[]string{"trigger", "result"}, // result: "complete", "incomplete" or "error"
+	)
+
+	// MaintenancePurgedTotal counts records purged by maintenance per dataset
+	MaintenancePurgedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eir_maintenance_purged_total",
+			Help: "Total number of records purged by database maintenance",
+		},
+		[]string{"dataset"},
+	)
+
+	// MaintenanceRunDuration measures how long maintenance runs take
+	MaintenanceRunDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "eir_maintenance_run_duration_seconds",
+			Help:    "Database maintenance run duration in seconds",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		},
+	)
+
+	// MaintenanceLastRun records when the last maintenance run finished
+	MaintenanceLastRun = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eir_maintenance_last_run_timestamp_seconds",
+			Help: "Unix time the last database maintenance run finished",
+		},
+	)
+
+	// DualWriteErrorsTotal counts writes that reached the primary database but
+	// failed on the secondary during a migration's dual-write phase
+	DualWriteErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eir_dual_write_errors_total",
+			Help: "Total number of writes that failed on the dual-write secondary database",
+		},
+		[]string{"repository"},
+	)
+
+	// CheckCountsFlushedTotal counts equipment checks written by counter flushes
+	CheckCountsFlushedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eir_check_counts_flushed_total",
+			Help: "Total number of equipment checks flushed to the equipment records",
+		},
+		[]string{"result"}, // "ok" or "error"; failed checks are retried on the next flush
+	)
+
+	// CheckCountsDroppedTotal counts checks not counted because too many IMEIs were pending
+	CheckCountsDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "eir_check_counts_dropped_total",
+			Help: "Total number of equipment checks dropped while the check counters were full",
+		},
+	)
+
+	// DatabaseReplicaHealthy reports whether each read replica is serving reads
+	DatabaseReplicaHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eir_database_replica_healthy",
+			Help: "Whether a database read replica is serving reads (1) or excluded (0)",
+		},
+		[]string{"replica"},
+	)
+
+	// DatabaseReplicaLag reports the replication lag last measured on each read replica
+	DatabaseReplicaLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eir_database_replica_lag_seconds",
+			Help: "Replication lag of a database read replica in seconds",
+		},
+		[]string{"replica"},
+	)
 )
 
 // InitMetrics registers Prometheus metrics
@@ -72,6 +152,15 @@ func InitMetrics() {
 	prometheus.MustRegister(CacheHitTotal)
 	prometheus.MustRegister(ActiveConnections)
 	prometheus.MustRegister(EquipmentByStatus)
+	prometheus.MustRegister(MaintenanceRunsTotal)
+	prometheus.MustRegister(MaintenancePurgedTotal)
+	prometheus.MustRegister(MaintenanceRunDuration)
+	prometheus.MustRegister(MaintenanceLastRun)
+	prometheus.MustRegister(DualWriteErrorsTotal)
+	prometheus.MustRegister(CheckCountsFlushedTotal)
+	prometheus.MustRegister(CheckCountsDroppedTotal)
+	prometheus.MustRegister(DatabaseReplicaHealthy)
+	prometheus.MustRegister(DatabaseReplicaLag)
 }
 
 // MetricsHandler returns HTTP handler for Prometheus metrics
@@ -1,6 +1,10 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
+)
 
 type SystemStatus struct {
 	OverloadLevel int
@@ -10,7 +14,7 @@ type SystemStatus struct {
 type CheckResult struct {
 	Status string
 	IMEI   string
-	Color  string
+	Color  domainModels.EquipmentStatus
 }
 
 type InsertImeiResult struct {
@@ -28,14 +32,14 @@ type InsertTacResult struct {
 type ImeiInfo struct {
 	StartIMEI string
 	EndIMEI   []string
-	Color     string
+	Color     domainModels.EquipmentStatus
 }
 
 type TacInfo struct {
 	KeyTac        string
 	StartRangeTac string
 	EndRangeTac   string
-	Color         string
+	Color         domainModels.EquipmentStatus
 	PrevLink      *string
 }
 
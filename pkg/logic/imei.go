@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hsdfat8/eir/config"
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/hsdfat8/eir/internal/logger"
 	"github.com/hsdfat8/eir/models"
@@ -27,10 +29,10 @@ func normalizeImei(imei string) string {
 	}
 }
 
-func lookupImeiInfo(imei string) (string, error) {
+func lookupImeiInfo(imei string) (domainModels.EquipmentStatus, error) {
 	logger.Log.Debugw("lookupImeiInfo started", "imei", imei)
 	bestLen := -1
-	bestColor := ""
+	var bestColor domainModels.EquipmentStatus
 
 	for _, info := range utils.ImeiSampleData {
 		if strings.HasPrefix(info.StartIMEI, imei) {
@@ -51,13 +53,14 @@ func lookupImeiInfo(imei string) (string, error) {
 func CheckImei(imei string, status models.SystemStatus) models.CheckResult {
 	logger.Log.Debugw("CheckImei logic started", "imei", imei, "overload_level", status.OverloadLevel)
 
+	imeiCheckLength = utils.GetImeiCheckLength()
 	imei = normalizeImei(imei)
 	if utils.IsOverLoad(status) {
 		logger.Log.Warnw("CheckImei system overloaded", "imei", imei, "overload_level", status.OverloadLevel)
 		return models.CheckResult{
 			Status: "error",
 			IMEI:   imei,
-			Color:  "overload",
+			Color:  domainModels.CheckOutcomeOverload,
 		}
 	}
 	color, err := lookupImeiInfo(imei)
@@ -66,7 +69,7 @@ func CheckImei(imei string, status models.SystemStatus) models.CheckResult {
 		return models.CheckResult{
 			Status: "error",
 			IMEI:   imei,
-			Color:  "unkown",
+			Color:  domainModels.CheckOutcomeUnknown,
 		}
 	}
 
@@ -78,7 +81,41 @@ func CheckImei(imei string, status models.SystemStatus) models.CheckResult {
 	}
 }
 
-func validateAddImei(imei string, color string) error {
+// CheckImeiInRepo resolves the color of imei against the IMEI_INFO entries
+// stored in repo, which provisioning keeps in step with the equipment records
+func CheckImeiInRepo(repo ports.IMEIRepository, imei string, status models.SystemStatus) models.CheckResult {
+	logger.Log.Debugw("CheckImeiInRepo logic started", "imei", imei, "overload_level", status.OverloadLevel)
+
+	imeiCheckLength = utils.GetImeiCheckLength()
+	imei = normalizeImei(imei)
+	if utils.IsOverLoad(status) {
+		logger.Log.Warnw("CheckImeiInRepo system overloaded", "imei", imei, "overload_level", status.OverloadLevel)
+		return models.CheckResult{
+			Status: "error",
+			IMEI:   imei,
+			Color:  domainModels.CheckOutcomeOverload,
+		}
+	}
+
+	info, ok := repo.LookupImeiInfo(context.Background(), imei)
+	if !ok {
+		logger.Log.Debugw("CheckImeiInRepo IMEI not found", "imei", imei)
+		return models.CheckResult{
+			Status: "error",
+			IMEI:   imei,
+			Color:  domainModels.CheckOutcomeUnknown,
+		}
+	}
+
+	logger.Log.Debugw("CheckImeiInRepo logic completed", "imei", imei, "color", info.Color)
+	return models.CheckResult{
+		Status: "ok",
+		IMEI:   imei,
+		Color:  info.Color,
+	}
+}
+
+func validateAddImei(imei string, color domainModels.EquipmentStatus) error {
 	logger.Log.Debugw("validateAddImei started", "imei", imei, "color", color)
 
 	if imei == "" {
@@ -96,9 +133,7 @@ func validateAddImei(imei string, color string) error {
 		logger.Log.Warnw("validateAddImei invalid length", "imei", imei, "length", len(imei), "max_length", imeiMaxLength)
 		return errors.New("invalid_length")
 	}
-	switch color {
-	case "b", "g", "w":
-	default:
+	if domainModels.ValidateStatus(color) != nil {
 		logger.Log.Warnw("validateAddImei invalid color", "imei", imei, "color", color)
 		return errors.New("invalid_color")
 	}
@@ -121,7 +156,46 @@ func normalizeImeiForInsert(imei string) (start string, end string) {
 	return
 }
 
-func InsertImei(repo ports.IMEIRepository, imei string, color string, status models.SystemStatus) models.InsertImeiResult {
+func InsertImei(repo ports.IMEIRepository, imei string, color domainModels.EquipmentStatus, status models.SystemStatus) models.InsertImeiResult {
+	return insertImei(context.Background(), repo, imei, color, status, nil)
+}
+
+// InsertImeiTx runs InsertImei inside a transaction begun from txs, so the
+// IMEI_INFO entry and its history record commit or roll back together
+func InsertImeiTx(ctx context.Context, txs ports.TransactionProvider, imei string, color domainModels.EquipmentStatus, status models.SystemStatus) models.InsertImeiResult {
+	tx, err := txs.BeginTransaction(ctx)
+	if err != nil {
+		logger.Log.Errorw("InsertImei failed to begin transaction", "imei", imei, "error", err)
+		return models.InsertImeiResult{Status: "error", IMEI: imei, Error: err.Error()}
+	}
+
+	result := InsertImeiWithHistory(ctx, tx.GetIMEIRepository(), tx.GetHistoryRepository(), imei, color, status)
+	if result.Status != "ok" {
+		if err := tx.Rollback(ctx); err != nil {
+			logger.Log.Errorw("InsertImei rollback failed", "imei", imei, "error", err)
+		}
+		return result
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger.Log.Errorw("InsertImei commit failed", "imei", imei, "error", err)
+		return models.InsertImeiResult{Status: "error", IMEI: imei, Error: err.Error()}
+	}
+	return result
+}
+
+// InsertImeiWithHistory runs InsertImei against repo and records the new
+// entry in history, attributed to the actor carried by ctx
+func InsertImeiWithHistory(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, imei string, color domainModels.EquipmentStatus, status models.SystemStatus) models.InsertImeiResult {
+	return insertImei(ctx, repo, imei, color, status, func(saved *ports.ImeiInfo, end string, extended bool) error {
+		return history.RecordChange(ctx, imeiHistory(ctx, imei, saved, end, extended))
+	})
+}
+
+// imeiSaved is called once an IMEI_INFO entry is written; extended reports
+// whether end was appended to an existing start IMEI
+type imeiSaved func(saved *ports.ImeiInfo, end string, extended bool) error
+
+func insertImei(ctx context.Context, repo ports.IMEIRepository, imei string, color domainModels.EquipmentStatus, status models.SystemStatus, onSaved imeiSaved) models.InsertImeiResult {
 	logger.Log.Infow("InsertImei logic started", "imei", imei, "color", color)
 
 	config.LoadEnv()
@@ -148,7 +222,6 @@ func InsertImei(repo ports.IMEIRepository, imei string, color string, status mod
 
 	start, end := normalizeImeiForInsert(imei)
 	logger.Log.Debugw("InsertImei normalized", "imei", imei, "start", start, "end", end)
-	ctx := context.Background()
 	if info, ok := repo.LookupImeiInfo(ctx, start); ok {
 		logger.Log.Debugw("InsertImei found existing start IMEI", "imei", imei, "start", start, "existing_color", info.Color)
 
@@ -180,11 +253,15 @@ func InsertImei(repo ports.IMEIRepository, imei string, color string, status mod
 
 		logger.Log.Debugw("InsertImei updating existing entry", "imei", imei, "start", start, "end", end)
 		err := repo.SaveImeiInfo(ctx, info)
+		if err == nil && onSaved != nil {
+			err = onSaved(info, end, true)
+		}
 		if err != nil {
 			logger.Log.Infow("InsertImei logic completed failed: ", "imei", imei, "start", start, "error", err.Error())
 			return models.InsertImeiResult{
 				Status: "error",
 				IMEI:   imei,
+				Error:  saveErrorCode(err),
 			}
 		}
 		logger.Log.Infow("InsertImei logic completed successfully (updated)", "imei", imei, "start", start)
@@ -195,16 +272,21 @@ func InsertImei(repo ports.IMEIRepository, imei string, color string, status mod
 	}
 
 	logger.Log.Debugw("InsertImei creating new entry", "imei", imei, "start", start, "end", end, "color", color)
-	err := repo.SaveImeiInfo(ctx, &ports.ImeiInfo{
+	info := &ports.ImeiInfo{
 		StartIMEI: start,
 		EndIMEI:   []string{end},
 		Color:     color,
-	})
+	}
+	err := repo.SaveImeiInfo(ctx, info)
+	if err == nil && onSaved != nil {
+		err = onSaved(info, end, false)
+	}
 	if err != nil {
 		logger.Log.Infow("InsertImei logic completed failed: ", "imei", imei, "start", start, "error", err.Error())
 		return models.InsertImeiResult{
 			Status: "error",
 			IMEI:   imei,
+			Error:  saveErrorCode(err),
 		}
 	}
 	logger.Log.Infow("InsertImei logic completed successfully (new)", "imei", imei, "start", start)
@@ -214,6 +296,167 @@ func InsertImei(repo ports.IMEIRepository, imei string, color string, status mod
 	}
 }
 
+// ErrImeiColorConflict is returned by PutImei when imei shares its IMEI_INFO
+// entry with IMEIs of another color
+var ErrImeiColorConflict = errors.New("IMEI shares its IMEI_INFO entry with IMEIs of another color")
+
+// PutImei lists a single IMEI in IMEI_INFO with color, as InsertImei would,
+// or recolors the entry it is already listed in when no other IMEI shares it.
+// It is how equipment records are mirrored into the IMEI lists. The change is
+// recorded in history when one is given.
+func PutImei(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, imei string, color domainModels.EquipmentStatus) error {
+	imeiCheckLength = utils.GetImeiCheckLength()
+	start, end := normalizeImeiForInsert(imei)
+
+	info, ok := repo.LookupImeiInfo(ctx, start)
+	if !ok {
+		created := &ports.ImeiInfo{StartIMEI: start, EndIMEI: []string{end}, Color: color}
+		if err := repo.SaveImeiInfo(ctx, created); err != nil {
+			return err
+		}
+		return recordImeiChange(ctx, history, imeiHistory(ctx, imei, created, end, false))
+	}
+
+	listed, placeholder := false, len(info.EndIMEI) == 1 && info.EndIMEI[0] == " "
+	for _, e := range info.EndIMEI {
+		listed = listed || e == end
+	}
+
+	updated := *info
+	var entry *domainModels.EquipmentHistory
+	switch {
+	case info.Color == color && listed:
+		return nil
+	case info.Color == color:
+		if placeholder {
+			updated.EndIMEI = []string{end}
+		} else {
+			updated.EndIMEI = append(append([]string(nil), info.EndIMEI...), end)
+		}
+		entry = imeiHistory(ctx, imei, &updated, end, true)
+	case listed && len(info.EndIMEI) == 1:
+		updated.EndIMEI = []string{end}
+		updated.Color = color
+		entry = imeiChange(ctx, imei, domainModels.ChangeTypeUpdate, info, &updated, end)
+	default:
+		return ErrImeiColorConflict
+	}
+	if err := repo.SaveImeiInfo(ctx, &updated); err != nil {
+		return err
+	}
+	return recordImeiChange(ctx, history, entry)
+}
+
+// RemoveImei takes a single IMEI out of its IMEI_INFO entry, deleting the
+// entry along with its last IMEI. Unlisted IMEIs are left alone. The change
+// is recorded in history when one is given.
+func RemoveImei(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, imei string) error {
+	imeiCheckLength = utils.GetImeiCheckLength()
+	start, end := normalizeImeiForInsert(imei)
+
+	info, ok := repo.LookupImeiInfo(ctx, start)
+	if !ok {
+		return nil
+	}
+
+	remaining := make([]string, 0, len(info.EndIMEI))
+	for _, e := range info.EndIMEI {
+		if e != end {
+			remaining = append(remaining, e)
+		}
+	}
+	switch len(remaining) {
+	case len(info.EndIMEI):
+		return nil
+	case 0:
+		if err := repo.DeleteImeiInfo(ctx, info); err != nil {
+			return err
+		}
+		return recordImeiChange(ctx, history, imeiChange(ctx, imei, domainModels.ChangeTypeDelete, info, info, end))
+	}
+
+	updated := *info
+	updated.EndIMEI = remaining
+	if err := repo.SaveImeiInfo(ctx, &updated); err != nil {
+		return err
+	}
+	return recordImeiChange(ctx, history, imeiChange(ctx, imei, domainModels.ChangeTypeUpdate, info, &updated, end))
+}
+
+// saveErrorCode is the result error code of a failed IMEI_INFO write:
+// "version_conflict" when the entry changed since it was looked up, so the
+// caller may retry, and none otherwise
+func saveErrorCode(err error) string {
+	if errors.Is(err, ports.ErrVersionConflict) {
+		return "version_conflict"
+	}
+	return ""
+}
+
+// imeiHistory describes a new IMEI_INFO entry for the history table, keyed by
+// the IMEI as it was provisioned
+func imeiHistory(ctx context.Context, imei string, saved *ports.ImeiInfo, end string, extended bool) *domainModels.EquipmentHistory {
+	return &domainModels.EquipmentHistory{
+		IMEI:       imei,
+		ChangeType: domainModels.ChangeTypeCreate,
+		ChangedAt:  time.Now(),
+		ChangedBy:  ports.ActorFromContext(ctx),
+		NewStatus:  saved.Color,
+		ChangeDetails: domainModels.ChangeDetails{
+			"list":       "imei",
+			"start_imei": saved.StartIMEI,
+			"end_imei":   end,
+			"color":      saved.Color,
+			"extended":   extended,
+		},
+	}
+}
+
+// imeiChange describes a change of end to the IMEI_INFO entry previous,
+// which leaves it as current, for the history table
+func imeiChange(ctx context.Context, imei string, changeType domainModels.ChangeType, previous, current *ports.ImeiInfo, end string) *domainModels.EquipmentHistory {
+	previousColor := previous.Color
+	return &domainModels.EquipmentHistory{
+		IMEI:           imei,
+		ChangeType:     changeType,
+		ChangedAt:      time.Now(),
+		ChangedBy:      ports.ActorFromContext(ctx),
+		PreviousStatus: &previousColor,
+		NewStatus:      current.Color,
+		ChangeDetails: domainModels.ChangeDetails{
+			"list":       "imei",
+			"start_imei": current.StartIMEI,
+			"end_imei":   end,
+			"color":      current.Color,
+		},
+	}
+}
+
+// recordImeiChange records entry in history, if there is one
+func recordImeiChange(ctx context.Context, history ports.HistoryRepository, entry *domainModels.EquipmentHistory) error {
+	if history == nil {
+		return nil
+	}
+	return history.RecordChange(ctx, entry)
+}
+
+// ImeiMatcher reports whether a check of another IMEI resolves to the same
+// IMEI_INFO entry as imei, i.e. whether inserting imei can change its result
+func ImeiMatcher(imei string) func(other string) bool {
+	imeiCheckLength = utils.GetImeiCheckLength()
+	target := normalizeImei(imei)
+	return func(other string) bool {
+		return normalizeImei(other) == target
+	}
+}
+
+// ImeiInfoStart returns the start IMEI of the IMEI_INFO entry a check of imei
+// resolves to
+func ImeiInfoStart(imei string) string {
+	imeiCheckLength = utils.GetImeiCheckLength()
+	return normalizeImei(imei)
+}
+
 func ClearImeiInfo(repo ports.IMEIRepository) {
 	ctx := context.Background()
 	repo.ClearImeiInfo(ctx)
@@ -3,11 +3,14 @@ package logic
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/hsdfat8/eir/config"
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/hsdfat8/eir/internal/logger"
 	"github.com/hsdfat8/eir/models"
@@ -111,7 +114,7 @@ func CheckTac(imei string, status models.SystemStatus) (models.CheckResult, mode
 		return models.CheckResult{
 			Status: "error",
 			IMEI:   imei,
-			Color:  "unknown",
+			Color:  domainModels.CheckOutcomeUnknown,
 		}, models.TacInfo{}
 	}
 
@@ -132,7 +135,7 @@ func CheckTac(imei string, status models.SystemStatus) (models.CheckResult, mode
 			return models.CheckResult{
 				Status: "error",
 				IMEI:   imei,
-				Color:  "unknown",
+				Color:  domainModels.CheckOutcomeUnknown,
 			}, models.TacInfo{}
 		}
 		if bytes.Compare([]byte(tacInfo.EndRangeTac), imeiConvert) >= 0 {
@@ -148,18 +151,10 @@ func CheckTac(imei string, status models.SystemStatus) (models.CheckResult, mode
 	return models.CheckResult{
 		Status: "error",
 		IMEI:   imei,
-		Color:  "unknown",
+		Color:  domainModels.CheckOutcomeUnknown,
 	}, models.TacInfo{}
 }
 
-func isValidColor(c string) bool {
-	switch c {
-	case "black", "grey", "white":
-		return true
-	}
-	return false
-}
-
 func fillRight(s string, pad rune) string {
 	cur := utf8.RuneCountInString(s)
 	if cur >= tacMaxLength {
@@ -169,11 +164,51 @@ func fillRight(s string, pad rune) string {
 }
 
 func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertTacResult {
+	return insertTac(context.Background(), repo, tacInfo, nil)
+}
+
+// InsertTacTx runs InsertTac inside a transaction begun from txs. The new
+// range, the children re-linked under it and its history record commit
+// together; any failure rolls all of them back.
+func InsertTacTx(ctx context.Context, txs ports.TransactionProvider, tacInfo models.TacInfo) models.InsertTacResult {
+	tx, err := txs.BeginTransaction(ctx)
+	if err != nil {
+		logger.Log.Errorw("InsertTac failed to begin transaction", "start_range", tacInfo.StartRangeTac, "error", err)
+		return models.InsertTacResult{Status: "error", Error: err.Error(), TacInfo: tacInfo}
+	}
+
+	result := InsertTacWithHistory(ctx, tx.GetIMEIRepository(), tx.GetHistoryRepository(), tacInfo)
+	if result.Status != "ok" {
+		if err := tx.Rollback(ctx); err != nil {
+			logger.Log.Errorw("InsertTac rollback failed", "start_range", tacInfo.StartRangeTac, "error", err)
+		}
+		return result
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger.Log.Errorw("InsertTac commit failed", "start_range", tacInfo.StartRangeTac, "error", err)
+		return models.InsertTacResult{Status: "error", Error: err.Error(), TacInfo: tacInfo}
+	}
+	return result
+}
+
+// InsertTacWithHistory runs InsertTac against repo and records the new range
+// in history, attributed to the actor carried by ctx
+func InsertTacWithHistory(ctx context.Context, repo ports.IMEIRepository, history ports.HistoryRepository, tacInfo models.TacInfo) models.InsertTacResult {
+	return insertTac(ctx, repo, tacInfo, func(saved *ports.TacInfo, children []*ports.TacInfo) error {
+		return history.RecordChange(ctx, tacHistory(ctx, tacInfo, saved, children))
+	})
+}
+
+// tacSaved is called once a range and its re-linked children are written,
+// so transactional callers can add to the same transaction
+type tacSaved func(saved *ports.TacInfo, children []*ports.TacInfo) error
+
+func insertTac(ctx context.Context, repo ports.IMEIRepository, tacInfo models.TacInfo, onSaved tacSaved) models.InsertTacResult {
 	logger.Log.Infow("InsertTac logic started", "start_range", tacInfo.StartRangeTac, "end_range", tacInfo.EndRangeTac, "color", tacInfo.Color)
 
 	// config.LoadEnv()
 	tacMaxLength = utils.GetTacMaxLength()
-	logger.Log.Debugw("tacMaxLength: ", tacMaxLength)
+	logger.Log.Debugw("InsertTac max TAC length", "tac_max_length", tacMaxLength)
 	if len(tacInfo.StartRangeTac) == 0 || len(tacInfo.StartRangeTac) > tacMaxLength {
 		logger.Log.Warnw("InsertTac invalid start range length", "start_range", tacInfo.StartRangeTac, "length", len(tacInfo.StartRangeTac), "max_length", tacMaxLength)
 		return models.InsertTacResult{
@@ -217,7 +252,7 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 	} else {
 		newEnd = fillRight(tacInfo.EndRangeTac, maxByteCharacter)
 	}
-	if !isValidColor(tacInfo.Color) {
+	if domainModels.ValidateStatus(tacInfo.Color) != nil {
 		logger.Log.Warnw("InsertTac invalid color", "color", tacInfo.Color)
 		return models.InsertTacResult{
 			Status:  "error",
@@ -235,7 +270,36 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 	}
 	startRangeSearch := newStart + "-" + newEnd
 	logger.Log.Debugw("InsertTac normalized ranges", "new_start", newStart, "new_end", newEnd, "key", startRangeSearch)
-	ctx := context.Background()
+
+	var bestParent *ports.TacInfo
+	var listUpdate []*ports.TacInfo
+
+	if ranges, ok := repo.(ports.TacRangeRepository); ok {
+		// Single round trip: every existing range touching the new one
+		overlaps, err := ranges.OverlappingTacInfo(ctx, newStart, newEnd)
+		if err != nil {
+			logger.Log.Errorw("InsertTac overlap lookup failed", "key", startRangeSearch, "error", err)
+			return models.InsertTacResult{Status: "error", Error: err.Error(), TacInfo: tacInfo}
+		}
+		for _, o := range overlaps {
+			switch {
+			case o.KeyTac == startRangeSearch:
+				logger.Log.Warnw("InsertTac range already exists", "key", startRangeSearch)
+				return models.InsertTacResult{Status: "error", Error: "range_exist", TacInfo: tacInfo}
+			case o.StartRangeTac <= newStart && o.EndRangeTac >= newEnd:
+				if bestParent == nil || (o.StartRangeTac >= bestParent.StartRangeTac && o.EndRangeTac <= bestParent.EndRangeTac) {
+					bestParent = o
+				}
+			case o.StartRangeTac >= newStart && o.EndRangeTac <= newEnd:
+				listUpdate = append(listUpdate, o)
+			default:
+				return models.InsertTacResult{Status: "error", Error: "range_exist", TacInfo: tacInfo}
+			}
+		}
+		listUpdate = directChildren(listUpdate, bestParent, startRangeSearch)
+		return saveTacRange(ctx, repo, tacInfo, startRangeSearch, newStart, newEnd, bestParent, listUpdate, onSaved)
+	}
+
 	if lookup, ok := repo.LookupTacInfo(ctx, startRangeSearch); ok {
 		logger.Log.Warnw("InsertTac range already exists", "key", startRangeSearch, "lookup", lookup)
 		return models.InsertTacResult{
@@ -245,9 +309,6 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 		}
 	}
 
-	var bestParent *ports.TacInfo
-	var listUpdate []*ports.TacInfo
-
 	p, ok := repo.PrevTacInfo(ctx, startRangeSearch)
 	for ok {
 		isParent := p.StartRangeTac <= newStart && p.EndRangeTac >= newEnd
@@ -258,22 +319,29 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 				bestParent = p
 			}
 		} else if isChild {
-			u := *p
-			u.PrevLink = &startRangeSearch
-			listUpdate = append(listUpdate, &u)
+			listUpdate = append(listUpdate, p)
 		} else if p.EndRangeTac >= newStart {
 			return models.InsertTacResult{Status: "error", Error: "range_exist", TacInfo: tacInfo}
 		}
 
 		if p.EndRangeTac < newStart {
-			if p.PrevLink == nil || *p.PrevLink == "" {
-				break
-			}
-			parent, found := repo.LookupTacInfo(ctx, *p.PrevLink)
-			if found && parent.StartRangeTac <= newStart && parent.EndRangeTac >= newEnd {
-				if bestParent == nil || (parent.StartRangeTac >= bestParent.StartRangeTac && parent.EndRangeTac <= bestParent.EndRangeTac) {
-					bestParent = parent
+			// Ancestors of p start before the new range: the first one reaching
+			// into it must enclose it entirely, otherwise the ranges overlap
+			for p.PrevLink != nil && *p.PrevLink != "" {
+				parent, found := repo.LookupTacInfo(ctx, *p.PrevLink)
+				if !found {
+					break
 				}
+				if parent.EndRangeTac >= newEnd {
+					if bestParent == nil || (parent.StartRangeTac >= bestParent.StartRangeTac && parent.EndRangeTac <= bestParent.EndRangeTac) {
+						bestParent = parent
+					}
+					break
+				}
+				if parent.EndRangeTac >= newStart {
+					return models.InsertTacResult{Status: "error", Error: "range_exist", TacInfo: tacInfo}
+				}
+				p = parent
 			}
 			break
 		}
@@ -290,9 +358,7 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 				bestParent = n
 			}
 		} else if isChild {
-			u := *n
-			u.PrevLink = &startRangeSearch
-			listUpdate = append(listUpdate, &u)
+			listUpdate = append(listUpdate, n)
 		} else if n.StartRangeTac <= newEnd {
 			return models.InsertTacResult{Status: "error", Error: "range_exist", TacInfo: tacInfo}
 		}
@@ -303,6 +369,36 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 		n, ok = repo.NextTacInfo(ctx, n.KeyTac)
 	}
 
+	listUpdate = directChildren(listUpdate, bestParent, startRangeSearch)
+	return saveTacRange(ctx, repo, tacInfo, startRangeSearch, newStart, newEnd, bestParent, listUpdate, onSaved)
+}
+
+// directChildren keeps the contained ranges whose current parent is the new
+// range's parent, and re-parents them under key. Ranges nested deeper keep
+// their own parent.
+func directChildren(contained []*ports.TacInfo, parent *ports.TacInfo, key string) []*ports.TacInfo {
+	var parentKey string
+	if parent != nil {
+		parentKey = parent.KeyTac
+	}
+
+	var children []*ports.TacInfo
+	for _, c := range contained {
+		var current string
+		if c.PrevLink != nil {
+			current = *c.PrevLink
+		}
+		if current != parentKey {
+			continue
+		}
+		u := *c
+		u.PrevLink = &key
+		children = append(children, &u)
+	}
+	return children
+}
+
+func saveTacRange(ctx context.Context, repo ports.IMEIRepository, tacInfo models.TacInfo, key, newStart, newEnd string, bestParent *ports.TacInfo, listUpdate []*ports.TacInfo, onSaved tacSaved) models.InsertTacResult {
 	var finalPrevLink *string
 	if bestParent != nil {
 		k := bestParent.KeyTac
@@ -310,21 +406,150 @@ func InsertTac(repo ports.IMEIRepository, tacInfo models.TacInfo) models.InsertT
 	}
 
 	tacInsert := &ports.TacInfo{
-		KeyTac: startRangeSearch, StartRangeTac: newStart, EndRangeTac: newEnd,
+		KeyTac: key, StartRangeTac: newStart, EndRangeTac: newEnd,
 		Color: tacInfo.Color, PrevLink: finalPrevLink,
 	}
 
 	if err := repo.SaveTacInfo(ctx, tacInsert); err != nil {
-		return models.InsertTacResult{Status: "error", Error: err.Error(), TacInfo: tacInfo}
+		return models.InsertTacResult{Status: "error", Error: tacErrorCode(err), TacInfo: tacInfo}
 	}
 
+	// Children carry the version they were read at, so a range changed by
+	// someone else in the meantime is not silently re-linked
 	for _, child := range listUpdate {
-		_ = repo.SaveTacInfo(ctx, child)
+		if err := repo.SaveTacInfo(ctx, child); err != nil {
+			logger.Log.Errorw("InsertTac failed to re-link child range", "key", key, "child", child.KeyTac, "error", err)
+			return models.InsertTacResult{Status: "error", Error: tacErrorCode(err), TacInfo: tacInfo}
+		}
+	}
+
+	if onSaved != nil {
+		if err := onSaved(tacInsert, listUpdate); err != nil {
+			return models.InsertTacResult{Status: "error", Error: err.Error(), TacInfo: tacInfo}
+		}
 	}
 
 	return models.InsertTacResult{Status: "ok", TacInfo: tacInfo}
 }
 
+// tacErrorCode is the result error of a failed TAC_INFO write:
+// "version_conflict" when a range changed since it was looked up, so the
+// caller may retry, and the error text otherwise
+func tacErrorCode(err error) string {
+	if errors.Is(err, ports.ErrVersionConflict) {
+		return "version_conflict"
+	}
+	return err.Error()
+}
+
+// tacHistory describes a new TAC range for the history table, which keys
+// list entries by their start range
+func tacHistory(ctx context.Context, tacInfo models.TacInfo, saved *ports.TacInfo, children []*ports.TacInfo) *domainModels.EquipmentHistory {
+	relinked := make([]string, 0, len(children))
+	for _, c := range children {
+		relinked = append(relinked, c.KeyTac)
+	}
+	details := domainModels.ChangeDetails{
+		"list":     "tac",
+		"key_tac":  saved.KeyTac,
+		"end_tac":  tacInfo.EndRangeTac,
+		"color":    saved.Color,
+		"relinked": relinked,
+	}
+	if saved.PrevLink != nil {
+		details["parent"] = *saved.PrevLink
+	}
+
+	return &domainModels.EquipmentHistory{
+		IMEI:          tacInfo.StartRangeTac,
+		ChangeType:    domainModels.ChangeTypeCreate,
+		ChangedAt:     time.Now(),
+		ChangedBy:     ports.ActorFromContext(ctx),
+		NewStatus:     saved.Color,
+		ChangeDetails: details,
+	}
+}
+
+// CheckTacInRepo resolves the color of imei against the TAC ranges stored in
+// repo. Repositories implementing ports.TacRangeRepository answer in a single
+// query; others are walked with PrevTacInfo and the PrevLink chain.
+func CheckTacInRepo(repo ports.IMEIRepository, imei string) (models.CheckResult, models.TacInfo) {
+	logger.Log.Debugw("CheckTacInRepo logic started", "imei", imei)
+
+	tacMaxLength = utils.GetTacMaxLength()
+	value := string(normalizeTac(imei))
+	ctx := context.Background()
+
+	var (
+		info *ports.TacInfo
+		ok   bool
+	)
+	if ranges, isRange := repo.(ports.TacRangeRepository); isRange {
+		info, ok = ranges.EnclosingTacInfo(ctx, value)
+	} else {
+		// Sorts after every key whose start range is <= value
+		search := value + "-" + strings.Repeat(maxByteString, tacMaxLength+1)
+		info, ok = repo.PrevTacInfo(ctx, search)
+		for ok && info.EndRangeTac < value {
+			if info.PrevLink == nil || *info.PrevLink == "" {
+				ok = false
+				break
+			}
+			info, ok = repo.LookupTacInfo(ctx, *info.PrevLink)
+		}
+	}
+
+	if !ok {
+		logger.Log.Warnw("CheckTacInRepo logic completed - no match found", "imei", imei)
+		return models.CheckResult{
+			Status: "error",
+			IMEI:   imei,
+			Color:  domainModels.CheckOutcomeUnknown,
+		}, models.TacInfo{}
+	}
+
+	logger.Log.Debugw("CheckTacInRepo logic completed - match found", "imei", imei, "color", info.Color, "key_tac", info.KeyTac)
+	return models.CheckResult{
+		Status: "ok",
+		IMEI:   imei,
+		Color:  info.Color,
+	}, models.TacInfo{
+		KeyTac:        info.KeyTac,
+		StartRangeTac: info.StartRangeTac,
+		EndRangeTac:   info.EndRangeTac,
+		Color:         info.Color,
+		PrevLink:      info.PrevLink,
+	}
+}
+
+// TacRangeMatcher reports whether an IMEI falls inside the TAC range
+// start-end, padded the same way as InsertTac pads a new range
+func TacRangeMatcher(start, end string) func(imei string) bool {
+	lo, hi := TacRangeBounds(start, end)
+	return func(imei string) bool {
+		value := string(normalizeTac(imei))
+		return value >= lo && value <= hi
+	}
+}
+
+// TacRangeBounds returns the padded bounds of the TAC range start-end. Of two
+// nested ranges, the inner one has the greater lo or, on a tie, the lesser hi.
+func TacRangeBounds(start, end string) (lo, hi string) {
+	tacMaxLength = utils.GetTacMaxLength()
+	lo = fillRight(start, ' ')
+	hi = lo
+	if end != "" {
+		hi = fillRight(end, maxByteCharacter)
+	}
+	return lo, hi
+}
+
+// TacValue returns imei padded the way it is compared with TAC range bounds
+func TacValue(imei string) string {
+	tacMaxLength = utils.GetTacMaxLength()
+	return string(normalizeTac(imei))
+}
+
 func ClearTacInfo(repo ports.IMEIRepository) {
 	ctx := context.Background()
 	repo.ClearTacInfo(ctx)
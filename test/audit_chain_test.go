@@ -0,0 +1,235 @@
+package test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+)
+
+// chainedAuditRepository is an audit repository that exposes its hash chain
+type chainedAuditRepository interface {
+	ports.AuditRepository
+	ports.AuditChainStore
+}
+
+func newChainedAuditRepository() chainedAuditRepository {
+	return memory.NewInMemoryAuditRepository().(chainedAuditRepository)
+}
+
+func newChainSigningConfig(t *testing.T, id string) config.AuditChainConfig {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		t.Fatalf("rand.Read failed: %v", err)
+	}
+	return config.AuditChainConfig{
+		CheckpointInterval: time.Hour,
+		SigningKeyID:       id,
+		SigningKey:         base64.StdEncoding.EncodeToString(seed),
+	}
+}
+
+// logChainedAudits logs n audits on the same day and returns them as stored
+func logChainedAudits(t *testing.T, repo chainedAuditRepository, day time.Time, n int) []*models.AuditLog {
+	audits := make([]*models.AuditLog, n)
+	for i := range audits {
+		audits[i] = &models.AuditLog{
+			IMEI:          "490154203237518",
+			Status:        models.EquipmentStatusWhitelisted,
+			CheckTime:     day.Add(time.Duration(i) * time.Minute),
+			RequestSource: "HTTP_5G",
+		}
+		if err := repo.LogCheck(context.Background(), audits[i]); err != nil {
+			t.Fatalf("LogCheck failed: %v", err)
+		}
+	}
+	return audits
+}
+
+func TestAuditChainVerify(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	repo := newChainedAuditRepository()
+	audits := logChainedAudits(t, repo, day, 5)
+	logChainedAudits(t, repo, day.AddDate(0, 0, 1), 2)
+
+	for i, audit := range audits {
+		if audit.ChainPartition != "2025-03-01" || audit.ChainSeq != int64(i+1) {
+			t.Fatalf("audit %d placed at %s/%d", i, audit.ChainPartition, audit.ChainSeq)
+		}
+		if i > 0 && audit.PrevHash != audits[i-1].Hash {
+			t.Fatalf("audit %d does not link to the audit before it", i)
+		}
+	}
+
+	keys, err := service.NewAuditChainKeys(config.AuditChainConfig{})
+	if err != nil {
+		t.Fatalf("NewAuditChainKeys failed: %v", err)
+	}
+	report, err := service.VerifyAuditChain(ctx, repo, keys, nil)
+	if err != nil {
+		t.Fatalf("VerifyAuditChain failed: %v", err)
+	}
+	if report.Break != nil || report.Partitions != 2 || report.Records != 7 {
+		t.Fatalf("unexpected report for an intact chain: %+v", report)
+	}
+
+	// Altering a record's content breaks its own hash
+	audits[2].Status = models.EquipmentStatusBlacklisted
+	report, err = service.VerifyAuditChain(ctx, repo, keys, []string{"2025-03-01"})
+	if err != nil {
+		t.Fatalf("VerifyAuditChain failed: %v", err)
+	}
+	if report.Break == nil || report.Break.Seq != 3 || report.Break.AuditID != audits[2].ID {
+		t.Fatalf("expected a break at seq 3, got %+v", report.Break)
+	}
+
+	// Recomputing the altered hash moves the break to the next link
+	audits[2].Hash = audits[2].ChainHash()
+	report, _ = service.VerifyAuditChain(ctx, repo, keys, []string{"2025-03-01"})
+	if report.Break == nil || report.Break.Seq != 4 || !strings.Contains(report.Break.Reason, "previous hash") {
+		t.Fatalf("expected a broken link at seq 4, got %+v", report.Break)
+	}
+}
+
+func TestAuditChainVerify_RemovedRecords(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	keys, _ := service.NewAuditChainKeys(config.AuditChainConfig{})
+
+	repo := newChainedAuditRepository()
+	audits := logChainedAudits(t, repo, day, 4)
+	audits[1].ChainPartition = "removed"
+	report, _ := service.VerifyAuditChain(ctx, repo, keys, []string{"2025-03-01"})
+	if report.Break == nil || report.Break.Seq != 2 || !strings.Contains(report.Break.Reason, "missing") {
+		t.Fatalf("expected seq 2 to be reported missing, got %+v", report.Break)
+	}
+
+	// The last record is only covered by the stored chain head
+	repo = newChainedAuditRepository()
+	audits = logChainedAudits(t, repo, day, 4)
+	audits[3].ChainPartition = "removed"
+	report, _ = service.VerifyAuditChain(ctx, repo, keys, []string{"2025-03-01"})
+	if report.Break == nil || report.Break.Seq != 4 {
+		t.Fatalf("expected seq 4 to be reported missing, got %+v", report.Break)
+	}
+
+	// Retention purges the oldest records; the surviving chain still verifies
+	repo = newChainedAuditRepository()
+	audits = logChainedAudits(t, repo, day, 4)
+	audits[0].ChainPartition = "purged"
+	audits[1].ChainPartition = "purged"
+	report, _ = service.VerifyAuditChain(ctx, repo, keys, []string{"2025-03-01"})
+	if report.Break != nil || report.Records != 2 {
+		t.Fatalf("expected a purged start to verify, got %+v", report)
+	}
+}
+
+func TestAuditCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	cfg := newChainSigningConfig(t, "k1")
+	keys, err := service.NewAuditChainKeys(cfg)
+	if err != nil {
+		t.Fatalf("NewAuditChainKeys failed: %v", err)
+	}
+
+	repo := newChainedAuditRepository()
+	audits := logChainedAudits(t, repo, day, 3)
+
+	checkpointer, err := service.NewAuditCheckpointer(repo, keys, cfg.CheckpointInterval)
+	if err != nil {
+		t.Fatalf("NewAuditCheckpointer failed: %v", err)
+	}
+	saved, err := checkpointer.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if len(saved) != 1 || saved[0].Seq != 3 || saved[0].Hash != audits[2].Hash || saved[0].KeyID != "k1" {
+		t.Fatalf("unexpected checkpoints: %+v", saved)
+	}
+
+	// Nothing advanced, so nothing is signed again
+	if saved, _ = checkpointer.RunOnce(ctx); len(saved) != 0 {
+		t.Fatalf("expected no new checkpoints, got %d", len(saved))
+	}
+
+	more := logChainedAudits(t, repo, day.Add(time.Hour), 2)
+	if saved, _ = checkpointer.RunOnce(ctx); len(saved) != 1 || saved[0].Seq != 5 {
+		t.Fatalf("expected a checkpoint at seq 5, got %+v", saved)
+	}
+
+	report, err := service.VerifyAuditChain(ctx, repo, keys, nil)
+	if err != nil || report.Break != nil || report.Checkpoints != 2 {
+		t.Fatalf("expected the checkpointed chain to verify, got %+v (%v)", report, err)
+	}
+
+	// Rewriting the chain from a signed record on cannot forge the checkpoint
+	audits[2].Status = models.EquipmentStatusBlacklisted
+	audits[2].Hash = audits[2].ChainHash()
+	for prev, audit := range more {
+		if prev == 0 {
+			audit.PrevHash = audits[2].Hash
+		} else {
+			audit.PrevHash = more[prev-1].Hash
+		}
+		audit.Hash = audit.ChainHash()
+	}
+	report, _ = service.VerifyAuditChain(ctx, repo, keys, nil)
+	if report.Break == nil || report.Break.Seq != 3 || !strings.Contains(report.Break.Reason, "checkpoint") {
+		t.Fatalf("expected the checkpoint at seq 3 to catch the rewrite, got %+v", report.Break)
+	}
+}
+
+func TestAuditCheckpointer_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	repo := newChainedAuditRepository()
+
+	oldCfg := newChainSigningConfig(t, "k1")
+	oldKeys, _ := service.NewAuditChainKeys(oldCfg)
+	logChainedAudits(t, repo, day, 2)
+	checkpointer, _ := service.NewAuditCheckpointer(repo, oldKeys, time.Hour)
+	if _, err := checkpointer.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	// A verifier that does not know the retired key reports its checkpoint
+	newCfg := newChainSigningConfig(t, "k2")
+	newKeys, _ := service.NewAuditChainKeys(newCfg)
+	report, _ := service.VerifyAuditChain(ctx, repo, newKeys, nil)
+	if report.Break == nil || !strings.Contains(report.Break.Reason, "unknown key") {
+		t.Fatalf("expected an unknown key, got %+v", report.Break)
+	}
+
+	seed, _ := base64.StdEncoding.DecodeString(oldCfg.SigningKey)
+	newCfg.PublicKeys = map[string]string{
+		"k1": base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)),
+	}
+	newKeys, err := service.NewAuditChainKeys(newCfg)
+	if err != nil {
+		t.Fatalf("NewAuditChainKeys failed: %v", err)
+	}
+	logChainedAudits(t, repo, day.Add(time.Hour), 1)
+	checkpointer, _ = service.NewAuditCheckpointer(repo, newKeys, time.Hour)
+	saved, err := checkpointer.RunOnce(ctx)
+	if err != nil || len(saved) != 1 || saved[0].Seq != 3 || saved[0].KeyID != "k2" {
+		t.Fatalf("expected one checkpoint at seq 3 by k2, got %+v (%v)", saved, err)
+	}
+
+	report, _ = service.VerifyAuditChain(ctx, repo, newKeys, nil)
+	if report.Break != nil || report.Checkpoints != 2 {
+		t.Fatalf("expected checkpoints by both keys to verify, got %+v", report)
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// fakeFeed replays events, then blocks until the listener is cancelled
+type fakeFeed struct {
+	events []ports.ChangeEvent
+}
+
+func (f *fakeFeed) Listen(ctx context.Context, handler func(ports.ChangeEvent)) error {
+	for _, e := range f.events {
+		handler(e)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+type fakeIndex struct {
+	table   ports.ChangeTable
+	reloads [][]string
+}
+
+func (i *fakeIndex) Table() ports.ChangeTable { return i.table }
+
+func (i *fakeIndex) Reload(ctx context.Context, keys []string) error {
+	i.reloads = append(i.reloads, keys)
+	return nil
+}
+
+func newTestDecisions() *memory.DecisionCache {
+	return memory.NewDecisionCache(memory.DecisionCacheConfig{
+		Shards: 4, Capacity: 100, PositiveTTL: time.Minute, NegativeTTL: time.Minute,
+	})
+}
+
+func TestChangeApplierTacRange(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	decisions := newTestDecisions()
+	d := &ports.CheckDecision{Status: "error", Color: "unknown"}
+	decisions.Set(ports.CheckKindTAC, "353123000000000", decisions.Generation(), d)
+	decisions.Set(ports.CheckKindTAC, "490154203237518", decisions.Generation(), d)
+
+	index := &fakeIndex{table: ports.ChangeTableTacInfo}
+	applier := service.NewChangeApplier(nil, decisions)
+	applier.RegisterIndex(index)
+
+	applier.Apply(context.Background(), ports.ChangeEvent{
+		Table: ports.ChangeTableTacInfo,
+		Op:    ports.ChangeOpInsert,
+		Keys:  []string{"353             -353ÿÿÿÿÿÿÿÿÿÿÿÿÿ"},
+	})
+
+	if _, ok := decisions.Get(ports.CheckKindTAC, "353123000000000"); ok {
+		t.Error("decision inside the changed range should be invalidated")
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, "490154203237518"); !ok {
+		t.Error("decision outside the changed range should be kept")
+	}
+	if len(index.reloads) != 1 || len(index.reloads[0]) != 1 {
+		t.Errorf("index should be reloaded with the changed key, got %v", index.reloads)
+	}
+}
+
+func TestChangeApplierRunResync(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	decisions := newTestDecisions()
+	d := &ports.CheckDecision{Status: "ok", Color: models.EquipmentStatusWhitelisted}
+	decisions.Set(ports.CheckKindIMEI, "490154203237518", decisions.Generation(), d)
+	decisions.Set(ports.CheckKindTAC, "490154203237518", decisions.Generation(), d)
+
+	feed := &fakeFeed{events: []ports.ChangeEvent{
+		{Table: ports.ChangeTableImeiInfo, Op: ports.ChangeOpResync},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.NewChangeApplier(nil, decisions).Run(ctx, feed)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for decisions.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if _, ok := decisions.Get(ports.CheckKindIMEI, "490154203237518"); ok {
+		t.Error("a resync of imei_info should drop every IMEI decision")
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, "490154203237518"); !ok {
+		t.Error("a resync of imei_info should not touch TAC decisions")
+	}
+}
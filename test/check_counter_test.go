@@ -0,0 +1,230 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func newCheckCounterConfig(batchSize, maxPending int) config.CheckCountersConfig {
+	return config.CheckCountersConfig{
+		Enabled:       true,
+		FlushInterval: time.Hour,
+		BatchSize:     batchSize,
+		MaxPending:    maxPending,
+	}
+}
+
+// flushSpyRepository passes check count flushes to applyFunc
+type flushSpyRepository struct {
+	ports.IMEIRepository
+	applyFunc func(ctx context.Context, deltas []ports.CheckCountDelta) error
+}
+
+func (r *flushSpyRepository) ApplyCheckCounts(ctx context.Context, deltas []ports.CheckCountDelta) error {
+	return r.applyFunc(ctx, deltas)
+}
+
+func insertEquipment(t *testing.T, repo ports.IMEIRepository, imei string) {
+	t.Helper()
+	if err := repo.Create(context.Background(), &models.Equipment{
+		IMEI:        imei,
+		Status:      models.EquipmentStatusWhitelisted,
+		AddedBy:     "test",
+		LastUpdated: time.Now(),
+	}); err != nil {
+		t.Fatalf("Create(%s) failed: %v", imei, err)
+	}
+}
+
+func TestCheckCounterFlush(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+	repo := memory.NewInMemoryIMEIRepository()
+	imeis := []string{"490154203237518", "356938035643809", "353456789012347"}
+	for _, imei := range imeis {
+		insertEquipment(t, repo, imei)
+	}
+	before, _ := repo.GetByIMEI(ctx, imeis[0])
+
+	var batches [][]ports.CheckCountDelta
+	spy := &flushSpyRepository{IMEIRepository: repo, applyFunc: func(ctx context.Context, deltas []ports.CheckCountDelta) error {
+		batches = append(batches, append([]ports.CheckCountDelta(nil), deltas...))
+		return repo.ApplyCheckCounts(ctx, deltas)
+	}}
+
+	counter, err := service.NewCheckCounter(spy, newCheckCounterConfig(2, 100))
+	if err != nil {
+		t.Fatalf("NewCheckCounter failed: %v", err)
+	}
+
+	base := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	counter.RecordCheck(imeis[0], base.Add(2*time.Minute))
+	counter.RecordCheck(imeis[0], base) // Out of order: last seen stays at the later check
+	counter.RecordCheck(imeis[0], base.Add(time.Minute))
+	counter.RecordCheck(imeis[1], base)
+	counter.RecordCheck(imeis[2], base)
+	counter.RecordCheck("999999999999994", base) // Unknown IMEIs are skipped by the repository
+
+	if got := counter.Pending(); got != 4 {
+		t.Fatalf("Pending() = %d, want 4", got)
+	}
+
+	flushed, err := counter.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if flushed != 6 {
+		t.Errorf("Flush() = %d checks, want 6", flushed)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Errorf("4 IMEIs with batch size 2 should be written in 2 full batches, got %v", batches)
+	}
+	if counter.Pending() != 0 {
+		t.Errorf("Pending() = %d after flush, want 0", counter.Pending())
+	}
+
+	equipment, err := repo.GetByIMEI(ctx, imeis[0])
+	if err != nil {
+		t.Fatalf("GetByIMEI failed: %v", err)
+	}
+	if equipment.CheckCount != 3 {
+		t.Errorf("CheckCount = %d, want 3", equipment.CheckCount)
+	}
+	if equipment.LastCheckTime == nil || !equipment.LastCheckTime.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("LastCheckTime = %v, want %v", equipment.LastCheckTime, base.Add(2*time.Minute))
+	}
+	if equipment.Version != before.Version {
+		t.Errorf("flushing check counts must not advance the version: %d -> %d", before.Version, equipment.Version)
+	}
+
+	// A second flush adds to the stored counts
+	counter.RecordCheck(imeis[0], base.Add(time.Second))
+	if _, err := counter.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	equipment, _ = repo.GetByIMEI(ctx, imeis[0])
+	if equipment.CheckCount != 4 {
+		t.Errorf("CheckCount = %d after second flush, want 4", equipment.CheckCount)
+	}
+	if !equipment.LastCheckTime.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("an older check must not move LastCheckTime back, got %v", equipment.LastCheckTime)
+	}
+}
+
+func TestCheckCounterRequeuesFailedBatches(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+	var calls int
+	fail := true
+	repo := &flushSpyRepository{IMEIRepository: memory.NewInMemoryIMEIRepository(), applyFunc: func(ctx context.Context, deltas []ports.CheckCountDelta) error {
+		calls++
+		if fail && calls == 2 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}}
+
+	counter, err := service.NewCheckCounter(repo, newCheckCounterConfig(1, 100))
+	if err != nil {
+		t.Fatalf("NewCheckCounter failed: %v", err)
+	}
+	now := time.Now()
+	counter.RecordCheck("490154203237518", now)
+	counter.RecordCheck("356938035643809", now)
+	counter.RecordCheck("356938035643809", now)
+
+	flushed, err := counter.Flush(ctx)
+	if err == nil {
+		t.Fatal("Flush should return the repository error")
+	}
+	if flushed != 2 {
+		t.Errorf("Flush() = %d checks before the failure, want 2", flushed)
+	}
+	if got := counter.Pending(); got != 1 {
+		t.Fatalf("failed batch should stay pending, Pending() = %d", got)
+	}
+
+	// Checks made meanwhile merge with the requeued ones
+	counter.RecordCheck("490154203237518", now)
+	fail = false
+	flushed, err = counter.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if flushed != 2 {
+		t.Errorf("retried Flush() = %d checks, want 2", flushed)
+	}
+	if counter.Pending() != 0 {
+		t.Errorf("Pending() = %d after retry, want 0", counter.Pending())
+	}
+}
+
+func TestCheckCounterMaxPending(t *testing.T) {
+	_ = logger.New("test", "info")
+	counter, err := service.NewCheckCounter(memory.NewInMemoryIMEIRepository(), newCheckCounterConfig(10, 2))
+	if err != nil {
+		t.Fatalf("NewCheckCounter failed: %v", err)
+	}
+	now := time.Now()
+	counter.RecordCheck("490154203237518", now)
+	counter.RecordCheck("356938035643809", now)
+	counter.RecordCheck("353456789012347", now) // Dropped: the pending deltas are full
+	counter.RecordCheck("490154203237518", now) // Still counted: the IMEI is already pending
+
+	if got := counter.Pending(); got != 2 {
+		t.Errorf("Pending() = %d, want 2", got)
+	}
+	flushed, err := counter.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if flushed != 3 {
+		t.Errorf("Flush() = %d checks, want 3", flushed)
+	}
+}
+
+func TestCheckCounterStopFlushes(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+	repo := memory.NewInMemoryIMEIRepository()
+	imei := "490154203237518"
+	insertEquipment(t, repo, imei)
+
+	counter, err := service.NewCheckCounter(repo, newCheckCounterConfig(10, 100))
+	if err != nil {
+		t.Fatalf("NewCheckCounter failed: %v", err)
+	}
+	counter.Start()
+
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	eirService.SetCheckRecorder(counter)
+	if _, err := eirService.CheckImei(ctx, imei, models.SystemStatus{}); err != nil {
+		t.Fatalf("CheckImei failed: %v", err)
+	}
+	if _, err := eirService.CheckTac(ctx, imei, models.SystemStatus{}); err != nil {
+		t.Fatalf("CheckTac failed: %v", err)
+	}
+
+	if err := counter.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	equipment, err := repo.GetByIMEI(ctx, imei)
+	if err != nil {
+		t.Fatalf("GetByIMEI failed: %v", err)
+	}
+	if equipment.CheckCount != 2 {
+		t.Errorf("CheckCount = %d after Stop, want 2", equipment.CheckCount)
+	}
+	if equipment.LastCheckTime == nil {
+		t.Error("LastCheckTime should be set after Stop")
+	}
+}
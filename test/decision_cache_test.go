@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestDecisionCacheInvalidation(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	decisions := memory.NewDecisionCache(memory.DecisionCacheConfig{
+		Shards:      4,
+		Capacity:    1000,
+		PositiveTTL: time.Minute,
+		NegativeTTL: time.Minute,
+	})
+	eirService := service.NewEIRService(nil, memory.NewInMemoryIMEIRepository(), nil, nil)
+	eirService.SetDecisionCache(decisions)
+
+	ctx := context.Background()
+	imei := "490154203237518"
+	other := "356938035643809"
+
+	for _, i := range []string{imei, other} {
+		if _, err := eirService.CheckTac(ctx, i, models.SystemStatus{}); err != nil {
+			t.Fatalf("CheckTac(%s): %v", i, err)
+		}
+		if _, err := eirService.CheckImei(ctx, i, models.SystemStatus{}); err != nil {
+			t.Fatalf("CheckImei(%s): %v", i, err)
+		}
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, imei); !ok {
+		t.Fatal("CheckTac decision (including unknown results) should be cached")
+	}
+
+	// A range elsewhere leaves the cached decision in place
+	if res, _ := eirService.InsertTac(ctx, &ports.TacInfo{StartRangeTac: "1", EndRangeTac: "2", Color: models.EquipmentStatusBlacklisted}); res.Status != "ok" {
+		t.Fatalf("InsertTac failed: %v", *res.Error)
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, imei); !ok {
+		t.Error("unrelated TAC range must not invalidate the decision")
+	}
+
+	// A range covering the IMEI drops exactly that decision
+	if res, _ := eirService.InsertTac(ctx, &ports.TacInfo{StartRangeTac: "4901", EndRangeTac: "4902", Color: models.EquipmentStatusGreylisted}); res.Status != "ok" {
+		t.Fatalf("InsertTac failed: %v", *res.Error)
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, imei); ok {
+		t.Error("TAC range covering the IMEI must invalidate its decision")
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, other); !ok {
+		t.Error("IMEI outside the inserted range must stay cached")
+	}
+
+	// InsertImei only touches IMEI check decisions of the same entry
+	if res, _ := eirService.InsertImei(ctx, imei, models.EquipmentStatusBlacklisted, models.SystemStatus{}); res.Status != "ok" {
+		t.Fatalf("InsertImei failed: %v", *res.Error)
+	}
+	if _, ok := decisions.Get(ports.CheckKindIMEI, imei); ok {
+		t.Error("InsertImei must invalidate the IMEI check decision")
+	}
+	if _, ok := decisions.Get(ports.CheckKindIMEI, other); !ok {
+		t.Error("InsertImei must not invalidate other IMEIs")
+	}
+	if _, ok := decisions.Get(ports.CheckKindTAC, other); !ok {
+		t.Error("InsertImei must not invalidate TAC decisions")
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestEquipmentHistory(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	for _, transactional := range []bool{true, false} {
+		repo := memory.NewInMemoryIMEIRepository()
+		history := memory.NewInMemoryHistoryRepository()
+		eirService := service.NewEIRService(nil, repo, nil, nil)
+		eirService.SetHistoryRepository(history)
+		if transactional {
+			txs, err := memory.NewTransactionManager(repo, memory.NewInMemoryAuditRepository(), history)
+			if err != nil {
+				t.Fatalf("NewTransactionManager failed: %v", err)
+			}
+			eirService.SetTransactionProvider(txs)
+		}
+
+		ctx := ports.WithActor(context.Background(), "alice")
+		imei := "490154203237518"
+
+		if res, _ := eirService.InsertImei(ctx, imei, models.EquipmentStatusBlacklisted, models.SystemStatus{}); res.Status != "ok" {
+			t.Fatalf("InsertImei failed: %v", *res.Error)
+		}
+		if res, _ := eirService.InsertTac(context.Background(), &ports.TacInfo{StartRangeTac: "4901", EndRangeTac: "4902", Color: models.EquipmentStatusGreylisted}); res.Status != "ok" {
+			t.Fatalf("InsertTac failed: %v", *res.Error)
+		}
+		// InsertImei gave the IMEI its equipment record, so it can be removed
+		if err := eirService.RemoveEquipment(ctx, imei); err != nil {
+			t.Fatalf("RemoveEquipment failed: %v", err)
+		}
+
+		entries, err := eirService.GetEquipmentHistory(ctx, imei, 0, 10)
+		if err != nil {
+			t.Fatalf("GetEquipmentHistory failed: %v", err)
+		}
+		// Each equipment change comes with the IMEI list change it made
+		if len(entries) != 3 {
+			t.Fatalf("transactional=%v: expected 3 entries, got %d", transactional, len(entries))
+		}
+		changes := map[string]*models.EquipmentHistory{}
+		for _, e := range entries {
+			changes[e.ChangeDetails["list"].(string)+" "+string(e.ChangeType)] = e
+		}
+		if removed := changes["equipment DELETE"]; removed == nil || removed.PreviousStatus == nil || *removed.PreviousStatus != models.EquipmentStatusBlacklisted {
+			t.Errorf("expected a DELETE of a BLACKLISTED equipment, got %+v", removed)
+		}
+		if inserted := changes["imei CREATE"]; inserted == nil || inserted.NewStatus != models.EquipmentStatusBlacklisted {
+			t.Errorf("expected a CREATE as BLACKLISTED, got %+v", inserted)
+		}
+		if unlisted := changes["imei DELETE"]; unlisted == nil || unlisted.ChangeDetails["start_imei"] != "49015420323751" {
+			t.Errorf("expected the IMEI_INFO entry to be deleted, got %+v", unlisted)
+		}
+		for _, e := range entries {
+			if e.ChangedBy != "alice" {
+				t.Errorf("expected changes by alice, got %q", e.ChangedBy)
+			}
+		}
+
+		tacEntries, _ := eirService.GetEquipmentHistory(ctx, "4901", 0, 10)
+		if len(tacEntries) != 1 || tacEntries[0].ChangedBy != ports.SystemActor {
+			t.Errorf("expected one TAC entry by %s, got %+v", ports.SystemActor, tacEntries)
+		}
+	}
+}
+
+func TestEquipmentHistoryEndpoint(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	eirService := service.NewEIRService(nil, memory.NewInMemoryIMEIRepository(), nil, nil)
+	router := httpAdapter.SetupRouter(eirService)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/490154203237518/history", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a history repository, got %d", rec.Code)
+	}
+
+	// X-Actor is only believed from a trusted proxy
+	const anonymous = "anonymous@192.0.2.1"
+	eirService.SetHistoryRepository(memory.NewInMemoryHistoryRepository())
+	for _, imei := range []string{"490154203237518", "490154203237526", "490154203237534"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/equipment", strings.NewReader(`{"imei":"`+imei+`","status":"BLACKLISTED"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "ops")
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("provisioning %s: expected 201, got %d: %s", imei, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/490154203237526/history?offset=0&limit=5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page httpAdapter.HistoryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	// The equipment record and its IMEI_INFO entry
+	if len(page.Entries) != 2 || page.Entries[0].ChangedBy != anonymous || page.Entries[1].ChangedBy != anonymous {
+		t.Fatalf("expected two entries by %s, got %+v", anonymous, page.Entries)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/490154203237526/history?offset=2", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Entries) != 0 {
+		t.Errorf("expected an empty second page, got %+v (%v)", page.Entries, err)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/490154203237526/history?limit=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid limit, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestEquipmentAndImeiListsAgree(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	for _, transactional := range []bool{true, false} {
+		repo := memory.NewInMemoryIMEIRepository()
+		eirService := service.NewEIRService(nil, repo, nil, nil)
+		if transactional {
+			txs, err := memory.NewTransactionManager(repo, memory.NewInMemoryAuditRepository(), memory.NewInMemoryHistoryRepository())
+			if err != nil {
+				t.Fatalf("NewTransactionManager failed: %v", err)
+			}
+			eirService.SetTransactionProvider(txs)
+		}
+		router := httpAdapter.SetupRouter(eirService)
+		ctx := context.Background()
+		imei := "490154203237518"
+
+		serve := func(method, path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+		color := func() models.EquipmentStatus {
+			res, err := eirService.CheckImei(ctx, imei, models.SystemStatus{})
+			if err != nil {
+				t.Fatalf("CheckImei failed: %v", err)
+			}
+			return res.Color
+		}
+
+		// A provisioned device can be read back and is checked with its status
+		if rec := serve(http.MethodPost, "/api/v1/equipment", `{"imei":"`+imei+`","status":"BLACKLISTED"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("transactional=%v: expected 201, got %d: %s", transactional, rec.Code, rec.Body.String())
+		}
+		if rec := serve(http.MethodGet, "/api/v1/equipment/"+imei, ""); rec.Code != http.StatusOK {
+			t.Fatalf("transactional=%v: GET after POST: expected 200, got %d", transactional, rec.Code)
+		}
+		rec := serve(http.MethodGet, "/api/v1/equipment", "")
+		var listed []httpAdapter.EquipmentResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0].IMEI != imei {
+			t.Errorf("expected the provisioned device to be listed, got %s (%v)", rec.Body.String(), err)
+		}
+		if got := color(); got != models.EquipmentStatusBlacklisted {
+			t.Errorf("transactional=%v: expected a blacklisted check, got %q", transactional, got)
+		}
+		if rec := serve(http.MethodPost, "/api/v1/equipment", `{"imei":"`+imei+`","status":"WHITELISTED"}`); rec.Code != http.StatusConflict {
+			t.Errorf("provisioning twice: expected 409, got %d", rec.Code)
+		}
+
+		// A status change recolors the IMEI list entry
+		if rec := serve(http.MethodPut, "/api/v1/equipment/"+imei, `{"status":"GREYLISTED"}`); rec.Code != http.StatusOK {
+			t.Fatalf("PUT: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := color(); got != models.EquipmentStatusGreylisted {
+			t.Errorf("expected a greylisted check after the update, got %q", got)
+		}
+
+		// Deleting takes the IMEI out of the lists, undeleting puts it back
+		if rec := serve(http.MethodDelete, "/api/v1/equipment/"+imei, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("DELETE: expected 204, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := color(); got != models.CheckOutcomeUnknown {
+			t.Errorf("expected a deleted device to be unknown, got %q", got)
+		}
+		if _, ok := repo.LookupImeiInfo(ctx, imei); ok {
+			t.Error("a deleted device should have no IMEI list entry")
+		}
+		if rec := serve(http.MethodPost, "/api/v1/equipment/"+imei+"/undelete", ""); rec.Code != http.StatusOK {
+			t.Fatalf("undelete: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := color(); got != models.EquipmentStatusGreylisted {
+			t.Errorf("expected an undeleted device to be greylisted again, got %q", got)
+		}
+
+		// Inserting a full IMEI into the lists gives it an equipment record
+		inserted := "356938035643809"
+		if res, _ := eirService.InsertImei(ports.WithActor(ctx, "ops"), inserted, models.EquipmentStatusWhitelisted, models.SystemStatus{}); res.Status != "ok" {
+			t.Fatalf("InsertImei failed: %v", *res.Error)
+		}
+		if rec := serve(http.MethodGet, "/api/v1/equipment/"+inserted, ""); rec.Code != http.StatusOK {
+			t.Errorf("GET after InsertImei: expected 200, got %d", rec.Code)
+		}
+		equipment, err := eirService.GetEquipment(ctx, inserted, false)
+		if err != nil || equipment.Status != models.EquipmentStatusWhitelisted || equipment.AddedBy != "ops" {
+			t.Errorf("expected a WHITELISTED record added by ops, got %+v (%v)", equipment, err)
+		}
+	}
+}
+
+func TestInsertImeiPrefixHasNoEquipment(t *testing.T) {
+	_ = logger.New("test", "info")
+	repo := memory.NewInMemoryIMEIRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	ctx := context.Background()
+
+	// A prefix lists a whole family of devices, none of which is equipment
+	if res, _ := eirService.InsertImei(ctx, "4901542032", models.EquipmentStatusBlacklisted, models.SystemStatus{}); res.Status != "ok" {
+		t.Fatalf("InsertImei failed: %v", *res.Error)
+	}
+	if all, _ := repo.List(ctx, 0, 10); len(all) != 0 {
+		t.Errorf("a prefix should not create equipment, got %+v", all)
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestExportEmbedded(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	ctx := context.Background()
+	adapter := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	if err := adapter.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer adapter.Disconnect(ctx)
+
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		at := base.Add(time.Duration(i) * 24 * time.Hour)
+		if err := adapter.GetAuditRepository().LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", CheckTime: at}); err != nil {
+			t.Fatalf("LogCheck failed: %v", err)
+		}
+		if err := adapter.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{IMEI: "490154203237518", ChangeType: models.ChangeTypeUpdate, ChangedAt: at}); err != nil {
+			t.Fatalf("RecordChange failed: %v", err)
+		}
+	}
+
+	// The embedded adapter has no native exporter and pages through its repositories
+	if _, ok := ports.DatabaseAdapter(adapter).(ports.ExportableAdapter); ok {
+		t.Fatalf("expected the embedded adapter to rely on the repository exporter")
+	}
+	exporter := memory.NewDataExporter(adapter.GetIMEIRepository(), adapter.GetAuditRepository(), adapter.GetHistoryRepository())
+	defer exporter.Close()
+
+	var out bytes.Buffer
+	if err := exporter.ExportAudits(ctx, &out, "ndjson", "", ""); err != nil {
+		t.Fatalf("ExportAudits failed: %v", err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 3 {
+		t.Errorf("expected every audit with an open range, got %d lines", lines)
+	}
+
+	out.Reset()
+	if err := exporter.ExportHistory(ctx, &out, "csv", "2025-03-02", ""); err != nil {
+		t.Fatalf("ExportHistory failed: %v", err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 3 {
+		t.Errorf("expected a header and the two later changes, got %d lines: %s", lines, out.String())
+	}
+
+	if err := exporter.ExportEquipment(ctx, &out, "xml"); err == nil {
+		t.Errorf("expected an unsupported format to fail")
+	}
+}
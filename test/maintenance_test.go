@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestMaintenanceScheduler(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	ctx := context.Background()
+	adapter := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	if err := adapter.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer adapter.Disconnect(ctx)
+
+	old := time.Now().Add(-72 * time.Hour)
+	for i := 0; i < 5; i++ {
+		at := old.Add(time.Duration(i) * time.Minute)
+		if err := adapter.GetAuditRepository().LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", CheckTime: at}); err != nil {
+			t.Fatalf("LogCheck failed: %v", err)
+		}
+		if err := adapter.GetHistoryRepository().RecordChange(ctx, &models.EquipmentHistory{IMEI: "490154203237518", ChangeType: models.ChangeTypeUpdate, ChangedAt: at}); err != nil {
+			t.Fatalf("RecordChange failed: %v", err)
+		}
+	}
+	if err := adapter.GetAuditRepository().LogCheck(ctx, &models.AuditLog{IMEI: "490154203237518", CheckTime: time.Now()}); err != nil {
+		t.Fatalf("LogCheck failed: %v", err)
+	}
+
+	// A window that is closed right now holds back scheduled runs only
+	now := time.Now()
+	cfg := config.MaintenanceConfig{
+		WindowStart:    now.Add(2 * time.Hour).Format("15:04"),
+		WindowEnd:      now.Add(3 * time.Hour).Format("15:04"),
+		BatchSize:      2,
+		AuditRetention: 24 * time.Hour,
+	}
+	scheduler, err := service.NewMaintenanceScheduler(adapter, cfg)
+	if err != nil {
+		t.Fatalf("NewMaintenanceScheduler failed: %v", err)
+	}
+
+	run, err := scheduler.Run(ctx, models.MaintenanceTriggerSchedule)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(run.Datasets) != 1 || run.Datasets[0].Purged != 0 || run.Datasets[0].Complete {
+		t.Fatalf("expected an incomplete audits purge outside the window, got %+v", run.Datasets)
+	}
+
+	run, err = scheduler.Run(ctx, models.MaintenanceTriggerManual)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	audits := run.Datasets[0]
+	if audits.Dataset != "audits" || audits.Purged != 5 || audits.Batches != 3 || !audits.Complete {
+		t.Fatalf("expected 5 audits purged in 3 batches, got %+v", audits)
+	}
+	remaining, _ := adapter.GetAuditRepository().GetAuditsByIMEI(ctx, "490154203237518", 0, 10)
+	if len(remaining) != 1 {
+		t.Errorf("expected the recent audit to be kept, got %d audits", len(remaining))
+	}
+	history, _ := adapter.GetHistoryRepository().GetHistoryByIMEI(ctx, "490154203237518", 0, 10)
+	if len(history) != 5 {
+		t.Errorf("expected history without a retention to be kept, got %d entries", len(history))
+	}
+
+	status := scheduler.Status()
+	if status.Running || status.NextRun != nil || status.LastRun == nil || status.LastRun.Trigger != models.MaintenanceTriggerManual {
+		t.Errorf("expected an idle manual-only scheduler reporting the manual run, got %+v", status)
+	}
+
+	if err := scheduler.RunNow(); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	scheduler.Stop()
+	if last := scheduler.Status().LastRun; last == nil || last.FinishedAt == nil {
+		t.Errorf("expected Stop to wait for the background run, got %+v", last)
+	}
+}
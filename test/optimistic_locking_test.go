@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestUpdateEquipmentVersionConflict(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	if err := repo.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	updated, err := eirService.UpdateEquipment(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusGreylisted, Version: 1})
+	if err != nil {
+		t.Fatalf("UpdateEquipment failed: %v", err)
+	}
+	if updated.Version != 2 || updated.Status != models.EquipmentStatusGreylisted {
+		t.Fatalf("expected GREYLISTED at version 2, got %+v", updated)
+	}
+
+	// A second writer still holding version 1 must not overwrite the change
+	_, err = eirService.UpdateEquipment(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusBlacklisted, Version: 1})
+	var conflict *ports.VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Actual != 2 {
+		t.Fatalf("expected a conflict between versions 1 and 2, got %v", err)
+	}
+	if stored, _ := repo.GetByIMEI(ctx, imei); stored.Status != models.EquipmentStatusGreylisted {
+		t.Errorf("expected the conflicting update to be rejected, got %s", stored.Status)
+	}
+
+	// Version 0 writes unconditionally
+	if updated, err = eirService.UpdateEquipment(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusBlacklisted}); err != nil || updated.Version != 3 {
+		t.Fatalf("expected an unconditional update to version 3, got %+v (%v)", updated, err)
+	}
+
+	if _, err := eirService.UpdateEquipment(ctx, &models.Equipment{IMEI: "356938035643809", Status: models.EquipmentStatusBlacklisted}); err != service.ErrEquipmentNotFound {
+		t.Errorf("expected ErrEquipmentNotFound, got %v", err)
+	}
+}
+
+func TestListEntryVersionConflict(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	ctx := context.Background()
+
+	if err := repo.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "49015420", EndIMEI: []string{"49015429"}, Color: models.EquipmentStatusBlacklisted}); err != nil {
+		t.Fatalf("SaveImeiInfo failed: %v", err)
+	}
+	stored, ok := repo.LookupImeiInfo(ctx, "49015420")
+	if !ok || stored.Version != 1 {
+		t.Fatalf("expected version 1, got %+v", stored)
+	}
+	if err := repo.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "49015420", EndIMEI: []string{"49015428"}, Color: models.EquipmentStatusBlacklisted, Version: 1}); err != nil {
+		t.Fatalf("conditional SaveImeiInfo failed: %v", err)
+	}
+	err := repo.SaveImeiInfo(ctx, &ports.ImeiInfo{StartIMEI: "49015420", EndIMEI: []string{"49015427"}, Color: models.EquipmentStatusBlacklisted, Version: 1})
+	if !errors.Is(err, ports.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict for a stale IMEI_INFO entry, got %v", err)
+	}
+
+	tac := &ports.TacInfo{KeyTac: "35693803-35693809", StartRangeTac: "35693803", EndRangeTac: "35693809", Color: models.EquipmentStatusWhitelisted, Version: 4}
+	if err := repo.SaveTacInfo(ctx, tac); !errors.Is(err, ports.ErrVersionConflict) {
+		t.Fatalf("expected a conditional save of a missing TAC range to conflict, got %v", err)
+	}
+}
+
+func TestEquipmentETagEndpoints(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	router := httpAdapter.SetupRouter(eirService)
+	imei := "490154203237518"
+
+	if err := repo.Create(context.Background(), &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/"+imei, nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag != `"1"` {
+		t.Fatalf("expected 200 with ETag \"1\", got %d %q", rec.Code, etag)
+	}
+
+	put := func(ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/equipment/"+imei, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = put(etag, `{"status":"BLACKLISTED","reason":"stolen"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected 200 with ETag \"2\", got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+	var response httpAdapter.EquipmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Version != 2 || response.Status != models.EquipmentStatusBlacklisted {
+		t.Fatalf("expected BLACKLISTED at version 2, got %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = put(etag, `{"status":"GREYLISTED"}`)
+	if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected 412 with the current ETag, got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	if rec = put("abc", `{"status":"GREYLISTED"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed If-Match, got %d", rec.Code)
+	}
+	if rec = put("*", `{"status":"GREYLISTED"}`); rec.Code != http.StatusOK {
+		t.Errorf("expected If-Match * to update unconditionally, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Restoring a snapshot honours If-Match as well
+	snapshots := memory.NewInMemorySnapshotRepository()
+	eirService.SetSnapshotRepository(snapshots)
+	snapshot, err := eirService.SnapshotEquipment(context.Background(), imei)
+	if err != nil {
+		t.Fatalf("SnapshotEquipment failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/equipment/%s/snapshots/%d/restore", imei, snapshot.ID), nil)
+	req.Header.Set("If-Match", `"1"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale restore, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
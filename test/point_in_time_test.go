@@ -0,0 +1,170 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+// fixedSnapshots serves a single snapshot
+type fixedSnapshots struct {
+	ports.SnapshotRepository
+	snapshot *models.EquipmentSnapshot
+}
+
+func (f fixedSnapshots) GetLatestSnapshot(ctx context.Context, imei string, at time.Time) (*models.EquipmentSnapshot, error) {
+	if f.snapshot.IMEI != imei || f.snapshot.SnapshotTime.After(at) {
+		return nil, nil
+	}
+	return f.snapshot, nil
+}
+
+func TestGetStatusAt(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	repo := memory.NewInMemoryIMEIRepository()
+	history := memory.NewInMemoryHistoryRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	eirService.SetHistoryRepository(history)
+
+	before := time.Now()
+	if res, _ := eirService.InsertTac(ctx, &ports.TacInfo{StartRangeTac: "4901", EndRangeTac: "4902", Color: models.EquipmentStatusGreylisted}); res.Status != "ok" {
+		t.Fatalf("InsertTac failed: %v", *res.Error)
+	}
+	afterOuter := time.Now()
+	if res, _ := eirService.InsertTac(ctx, &ports.TacInfo{StartRangeTac: "490154", EndRangeTac: "490154", Color: models.EquipmentStatusBlacklisted}); res.Status != "ok" {
+		t.Fatalf("InsertTac failed: %v", *res.Error)
+	}
+	afterInner := time.Now()
+	if res, _ := eirService.InsertImei(ctx, imei, models.EquipmentStatusWhitelisted, models.SystemStatus{}); res.Status != "ok" {
+		t.Fatalf("InsertImei failed: %v", *res.Error)
+	}
+	afterImei := time.Now()
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		want   models.EquipmentStatus
+		source models.StatusSource
+		count  int
+	}{
+		{name: "before any provisioning", at: before},
+		{name: "outer TAC range", at: afterOuter, want: models.EquipmentStatusGreylisted, source: models.StatusSourceTAC, count: 1},
+		{name: "inner TAC range", at: afterInner, want: models.EquipmentStatusBlacklisted, source: models.StatusSourceTAC, count: 1},
+		{name: "IMEI entry over TAC range", at: afterImei, want: models.EquipmentStatusWhitelisted, source: models.StatusSourceIMEI, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := eirService.GetStatusAt(ctx, imei, tt.at)
+			if err != nil {
+				t.Fatalf("GetStatusAt failed: %v", err)
+			}
+			if len(status.Contributions) != tt.count {
+				t.Fatalf("expected %d contributions, got %+v", tt.count, status.Contributions)
+			}
+			if tt.count == 0 {
+				if status.Status != nil {
+					t.Errorf("expected no status, got %s", *status.Status)
+				}
+				return
+			}
+			if status.Status == nil || *status.Status != tt.want || status.Source != tt.source {
+				t.Errorf("expected %s from %s, got %+v", tt.want, tt.source, status)
+			}
+			for i, c := range status.Contributions {
+				if len(c.History) == 0 {
+					t.Errorf("contribution %s cites no history", c.Source)
+				}
+				if c.Effective != (i == 0) {
+					t.Errorf("contribution %s effective=%v", c.Source, c.Effective)
+				}
+			}
+		})
+	}
+
+	// The equipment record starts from its snapshot and ends with its removal
+	snapshotTime := time.Now()
+	eirService.SetSnapshotRepository(fixedSnapshots{snapshot: &models.EquipmentSnapshot{
+		ID: 7, IMEI: imei, SnapshotTime: snapshotTime, Status: models.EquipmentStatusGreylisted,
+	}})
+	equipment, err := repo.GetByIMEI(ctx, imei)
+	if err != nil {
+		t.Fatalf("InsertImei should have created the equipment record: %v", err)
+	}
+	equipment.Status = models.EquipmentStatusGreylisted
+	if err := repo.Update(ctx, equipment); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	afterSnapshot := time.Now()
+	if err := eirService.RemoveEquipment(ctx, imei); err != nil {
+		t.Fatalf("RemoveEquipment failed: %v", err)
+	}
+
+	status, err := eirService.GetStatusAt(ctx, imei, afterSnapshot)
+	if err != nil {
+		t.Fatalf("GetStatusAt failed: %v", err)
+	}
+	if len(status.Contributions) != 3 || status.Contributions[1].Snapshot == nil || status.Contributions[1].Snapshot.ID != 7 {
+		t.Fatalf("expected the equipment contribution to cite snapshot 7, got %+v", status.Contributions)
+	}
+
+	// Removing the equipment also took it out of the IMEI list
+	status, _ = eirService.GetStatusAt(ctx, imei, time.Now())
+	for _, c := range status.Contributions {
+		if c.Source == models.StatusSourceEquipment || c.Source == models.StatusSourceIMEI {
+			t.Errorf("removed equipment still contributes: %+v", c)
+		}
+	}
+
+	if _, err := eirService.GetStatusAt(ctx, "12345", time.Now()); err == nil {
+		t.Error("expected an invalid IMEI to be rejected")
+	}
+}
+
+func TestGetStatusAt_ImeiListRemoval(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	history := memory.NewInMemoryHistoryRepository()
+	eirService := service.NewEIRService(nil, memory.NewInMemoryIMEIRepository(), nil, nil)
+	eirService.SetHistoryRepository(history)
+
+	listed := time.Now().Add(-2 * time.Hour)
+	removed := time.Now().Add(-time.Hour)
+	previous := models.EquipmentStatusBlacklisted
+	for _, entry := range []*models.EquipmentHistory{
+		{IMEI: imei, ChangeType: models.ChangeTypeCreate, NewStatus: models.EquipmentStatusBlacklisted, ChangedAt: listed},
+		{IMEI: imei, ChangeType: models.ChangeTypeDelete, PreviousStatus: &previous, ChangedAt: removed},
+	} {
+		entry.ChangedBy = "tester"
+		entry.ChangeDetails = models.ChangeDetails{"list": "imei", "start_imei": "49015420323751"}
+		if err := history.RecordChange(ctx, entry); err != nil {
+			t.Fatalf("RecordChange failed: %v", err)
+		}
+	}
+
+	status, err := eirService.GetStatusAt(ctx, imei, removed.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetStatusAt failed: %v", err)
+	}
+	if status.Status == nil || *status.Status != models.EquipmentStatusBlacklisted || status.Source != models.StatusSourceIMEI {
+		t.Fatalf("expected the listed IMEI to be blacklisted, got %+v", status)
+	}
+
+	status, err = eirService.GetStatusAt(ctx, imei, time.Now())
+	if err != nil {
+		t.Fatalf("GetStatusAt failed: %v", err)
+	}
+	if status.Status != nil || len(status.Contributions) != 0 {
+		t.Errorf("removed IMEI entry still contributes: %+v", status.Contributions)
+	}
+}
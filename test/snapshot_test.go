@@ -0,0 +1,212 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	history := memory.NewInMemoryHistoryRepository()
+	snapshots := memory.NewInMemorySnapshotRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	eirService.SetHistoryRepository(history)
+	eirService.SetSnapshotRepository(snapshots)
+	txs, err := memory.NewTransactionManager(repo, memory.NewInMemoryAuditRepository(), history)
+	if err != nil {
+		t.Fatalf("NewTransactionManager failed: %v", err)
+	}
+	eirService.SetTransactionProvider(txs)
+
+	ctx := ports.WithActor(context.Background(), "alice")
+	imei := "490154203237518"
+
+	if _, err := eirService.SnapshotEquipment(ctx, imei); err != service.ErrEquipmentNotFound {
+		t.Fatalf("expected ErrEquipmentNotFound for a missing IMEI, got %v", err)
+	}
+
+	if err := repo.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	whitelisted, err := eirService.SnapshotEquipment(ctx, imei)
+	if err != nil {
+		t.Fatalf("SnapshotEquipment failed: %v", err)
+	}
+	if whitelisted.SnapshotType != models.SnapshotTypeManual || whitelisted.CreatedBy != "alice" || whitelisted.Status != models.EquipmentStatusWhitelisted {
+		t.Fatalf("expected a MANUAL WHITELISTED snapshot by alice, got %+v", whitelisted)
+	}
+
+	equipment, _ := repo.GetByIMEI(ctx, imei)
+	equipment.Status = models.EquipmentStatusBlacklisted
+	if err := repo.Update(ctx, equipment); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	restored, err := eirService.RestoreSnapshot(ctx, imei, whitelisted.ID, 0)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if restored.Status != models.EquipmentStatusWhitelisted {
+		t.Errorf("expected WHITELISTED after restore, got %s", restored.Status)
+	}
+	if stored, _ := repo.GetByIMEI(ctx, imei); stored.Status != models.EquipmentStatusWhitelisted {
+		t.Errorf("expected the repository to hold WHITELISTED, got %s", stored.Status)
+	}
+
+	list, err := eirService.ListSnapshots(ctx, imei, 0, 10)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(list) != 2 || list[0].SnapshotType != models.SnapshotTypePreUpdate || list[0].Status != models.EquipmentStatusBlacklisted {
+		t.Fatalf("expected a PRE_UPDATE BLACKLISTED snapshot first, got %+v", list)
+	}
+
+	var entries []*models.EquipmentHistory
+	all, _ := eirService.GetEquipmentHistory(ctx, imei, 0, 10)
+	for _, e := range all {
+		if e.ChangeDetails["list"] == "equipment" {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) != 1 || entries[0].ChangeType != models.ChangeTypeUpdate || entries[0].ChangeDetails["restored_snapshot"] != whitelisted.ID {
+		t.Fatalf("expected one UPDATE entry citing the snapshot, got %+v", entries)
+	}
+
+	// Restoring a removed IMEI recreates its equipment record
+	if err := eirService.RemoveEquipment(ctx, imei); err != nil {
+		t.Fatalf("RemoveEquipment failed: %v", err)
+	}
+	if _, err := eirService.RestoreSnapshot(ctx, imei, whitelisted.ID, 0); err != nil {
+		t.Fatalf("RestoreSnapshot of a removed IMEI failed: %v", err)
+	}
+	if stored, err := repo.GetByIMEI(ctx, imei); err != nil || stored.Status != models.EquipmentStatusWhitelisted {
+		t.Errorf("expected a recreated WHITELISTED record, got %+v (%v)", stored, err)
+	}
+
+	if _, err := eirService.RestoreSnapshot(ctx, "356938035643809", whitelisted.ID, 0); err != service.ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound for a snapshot of another IMEI, got %v", err)
+	}
+}
+
+func TestSnapshotScheduler(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	snapshots := memory.NewInMemorySnapshotRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	eirService.SetSnapshotRepository(snapshots)
+
+	ctx := context.Background()
+	imeis := []string{"490154203237518", "490154203237526", "490154203237534"}
+	for _, imei := range imeis {
+		if err := repo.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusGreylisted}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	expired := &models.EquipmentSnapshot{IMEI: imeis[0], SnapshotTime: time.Now().Add(-48 * time.Hour), SnapshotType: models.SnapshotTypeScheduled}
+	if err := snapshots.CreateSnapshot(ctx, expired); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+
+	if _, err := service.NewSnapshotScheduler(eirService, config.SnapshotConfig{Schedule: "every day"}); err == nil {
+		t.Fatal("expected an invalid schedule to be rejected")
+	}
+	scheduler, err := service.NewSnapshotScheduler(eirService, config.SnapshotConfig{Schedule: "0 3 * * *", Retention: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewSnapshotScheduler failed: %v", err)
+	}
+	if err := scheduler.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	for _, imei := range imeis {
+		list, _ := snapshots.GetSnapshotsByIMEI(ctx, imei, 0, 10)
+		if len(list) != 1 || list[0].SnapshotType != models.SnapshotTypeScheduled || list[0].CreatedBy != ports.SystemActor {
+			t.Errorf("%s: expected one SCHEDULED snapshot by %s, got %+v", imei, ports.SystemActor, list)
+		}
+	}
+	if _, err := snapshots.GetSnapshotByID(ctx, expired.ID); err == nil {
+		t.Error("expected the snapshot older than the retention to be purged")
+	}
+
+	scheduler.Start()
+	scheduler.Stop()
+}
+
+func TestSnapshotEndpoints(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	router := httpAdapter.SetupRouter(eirService)
+	imei := "490154203237518"
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/equipment/"+imei+"/snapshots", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a snapshot repository, got %d", rec.Code)
+	}
+
+	eirService.SetSnapshotRepository(memory.NewInMemorySnapshotRepository())
+	if err := repo.Create(context.Background(), &models.Equipment{IMEI: imei, Status: models.EquipmentStatusGreylisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/equipment/"+imei+"/snapshots", nil)
+	req.Header.Set("X-Actor", "ops")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var snapshot httpAdapter.SnapshotResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	// X-Actor is only believed from a trusted proxy
+	if snapshot.CreatedBy != "anonymous@192.0.2.1" || snapshot.SnapshotType != models.SnapshotTypeManual {
+		t.Fatalf("expected a MANUAL snapshot by the anonymous client, got %+v", snapshot)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/equipment/"+imei+"/snapshots", nil))
+	var page httpAdapter.SnapshotsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Snapshots) != 1 {
+		t.Fatalf("expected one snapshot, got %s (%v)", rec.Body.String(), err)
+	}
+
+	equipment, _ := repo.GetByIMEI(context.Background(), imei)
+	equipment.Status = models.EquipmentStatusBlacklisted
+	_ = repo.Update(context.Background(), equipment)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/equipment/%s/snapshots/%d/restore", imei, snapshot.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var restored httpAdapter.EquipmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &restored); err != nil || restored.Status != models.EquipmentStatusGreylisted {
+		t.Errorf("expected GREYLISTED after restore, got %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/equipment/"+imei+"/snapshots/999/restore", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown snapshot, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hsdfat8/eir/internal/adapters/embedded"
+	httpAdapter "github.com/hsdfat8/eir/internal/adapters/http"
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/config"
+	"github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+)
+
+func TestRemoveEquipmentLeavesTombstone(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	history := memory.NewInMemoryHistoryRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	eirService.SetHistoryRepository(history)
+	ctx := ports.WithActor(context.Background(), "operator")
+	imei := "490154203237518"
+
+	if err := repo.Create(ctx, &models.Equipment{IMEI: imei, Status: models.EquipmentStatusBlacklisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := eirService.RemoveEquipment(ctx, imei); err != nil {
+		t.Fatalf("RemoveEquipment failed: %v", err)
+	}
+
+	if _, err := eirService.GetEquipment(ctx, imei, false); err != service.ErrEquipmentNotFound {
+		t.Fatalf("expected a deleted record to be hidden, got %v", err)
+	}
+	if list, _ := eirService.ListEquipment(ctx, 0, 10, false); len(list) != 0 {
+		t.Errorf("expected a deleted record not to be listed, got %d records", len(list))
+	}
+	tombstone, err := eirService.GetEquipment(ctx, imei, true)
+	if err != nil || !tombstone.IsDeleted() || tombstone.DeletedBy == nil || *tombstone.DeletedBy != "operator" {
+		t.Fatalf("expected a tombstone deleted by operator, got %+v (%v)", tombstone, err)
+	}
+	if list, _ := eirService.ListEquipment(ctx, 0, 10, true); len(list) != 1 {
+		t.Errorf("expected include_deleted to list the tombstone, got %d records", len(list))
+	}
+	if err := eirService.RemoveEquipment(ctx, imei); err != service.ErrEquipmentNotFound {
+		t.Errorf("expected deleting a tombstone to fail with ErrEquipmentNotFound, got %v", err)
+	}
+
+	restored, err := eirService.UndeleteEquipment(ctx, imei)
+	if err != nil || restored.IsDeleted() || restored.Status != models.EquipmentStatusBlacklisted {
+		t.Fatalf("expected the BLACKLISTED record back, got %+v (%v)", restored, err)
+	}
+	if _, err := eirService.UndeleteEquipment(ctx, imei); err != service.ErrEquipmentNotDeleted {
+		t.Errorf("expected ErrEquipmentNotDeleted, got %v", err)
+	}
+
+	entries, _ := history.GetHistoryByIMEI(ctx, imei, 0, 10)
+	// The record was never listed, so only the undelete lists it
+	if len(entries) != 3 {
+		t.Fatalf("expected a delete, an undelete and its listing in history, got %d entries", len(entries))
+	}
+}
+
+func TestTombstonePurge(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	ctx := context.Background()
+	adapter := embedded.NewEmbeddedAdapter(&ports.EmbeddedConfig{Path: filepath.Join(t.TempDir(), "eir.db"), LockTimeout: 1})
+	if err := adapter.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer adapter.Disconnect(ctx)
+
+	repo := adapter.GetIMEIRepository()
+	old := time.Now().Add(-72 * time.Hour)
+	recent := time.Now()
+	for i, imei := range []string{"490154203237518", "356938035643809", "352099001761481"} {
+		equipment := &models.Equipment{IMEI: imei, Status: models.EquipmentStatusWhitelisted}
+		if err := repo.Create(ctx, equipment); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		switch i {
+		case 0:
+			equipment.DeletedAt = &old
+		case 1:
+			equipment.DeletedAt = &recent
+		default:
+			continue
+		}
+		if err := repo.Update(ctx, equipment); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	scheduler, err := service.NewMaintenanceScheduler(adapter, config.MaintenanceConfig{BatchSize: 10, TombstoneRetention: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewMaintenanceScheduler failed: %v", err)
+	}
+	run, err := scheduler.Run(ctx, models.MaintenanceTriggerManual)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(run.Datasets) != 1 || run.Datasets[0].Dataset != "tombstones" || run.Datasets[0].Purged != 1 {
+		t.Fatalf("expected one tombstone purged, got %+v", run.Datasets)
+	}
+
+	if _, err := repo.GetByIMEI(ctx, "490154203237518"); err == nil {
+		t.Errorf("expected the expired tombstone to be purged")
+	}
+	all, _ := repo.ListWithDeleted(ctx, 0, 10)
+	if len(all) != 2 {
+		t.Errorf("expected the recent tombstone and the live record to be kept, got %d records", len(all))
+	}
+	if live, _ := repo.List(ctx, 0, 10); len(live) != 1 || live[0].IMEI != "352099001761481" {
+		t.Errorf("expected List to skip tombstones, got %+v", live)
+	}
+}
+
+func TestUndeleteEquipmentEndpoint(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repo := memory.NewInMemoryIMEIRepository()
+	eirService := service.NewEIRService(nil, repo, nil, nil)
+	router := httpAdapter.SetupRouter(eirService)
+	imei := "490154203237518"
+
+	if err := repo.Create(context.Background(), &models.Equipment{IMEI: imei, Status: models.EquipmentStatusGreylisted}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/api/v1/equipment/"+imei+"/undelete"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a live record, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/api/v1/equipment/"+imei); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodDelete, "/api/v1/equipment/"+imei); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/v1/equipment/"+imei); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted record, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/api/v1/equipment/"+imei+"?include_deleted=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed include_deleted, got %d", rec.Code)
+	}
+
+	rec := serve(http.MethodGet, "/api/v1/equipment/"+imei+"?include_deleted=true")
+	var response httpAdapter.EquipmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK || response.DeletedAt == nil {
+		t.Fatalf("expected the tombstone with deleted_at, got %d %s (%v)", rec.Code, rec.Body.String(), err)
+	}
+
+	rec = serve(http.MethodGet, "/api/v1/equipment?include_deleted=true")
+	var listed []httpAdapter.EquipmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed) != 1 {
+		t.Fatalf("expected the tombstone to be listed, got %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = serve(http.MethodPost, "/api/v1/equipment/"+imei+"/undelete")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("expected 200 with ETag \"3\", got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/api/v1/equipment/"+imei); rec.Code != http.StatusOK {
+		t.Errorf("expected the undeleted record to be found, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/api/v1/equipment/356938035643809/undelete"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown equipment, got %d", rec.Code)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/hsdfat8/eir/internal/adapters/memory"
+	"github.com/hsdfat8/eir/internal/domain/models"
 	"github.com/hsdfat8/eir/internal/domain/ports"
 	"github.com/hsdfat8/eir/internal/domain/service"
 	"github.com/hsdfat8/eir/internal/logger"
@@ -30,7 +31,7 @@ func TestTacParentChildLinking(t *testing.T) {
 	childTacInfo := &ports.TacInfo{
 		StartRangeTac: "133",
 		EndRangeTac:   "135",
-		Color:         "black",
+		Color:         models.EquipmentStatusBlacklisted,
 	}
 
 	result1, err := eirService.InsertTac(ctx, childTacInfo)
@@ -58,7 +59,7 @@ func TestTacParentChildLinking(t *testing.T) {
 	parentTacInfo := &ports.TacInfo{
 		StartRangeTac: "133",
 		EndRangeTac:   "139",
-		Color:         "grey",
+		Color:         models.EquipmentStatusGreylisted,
 	}
 
 	result2, err := eirService.InsertTac(ctx, parentTacInfo)
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/domain/service"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/hsdfat8/eir/models"
+	"github.com/hsdfat8/eir/pkg/logic"
+)
+
+// rangeRepo adds ports.TacRangeRepository to the in-memory repository so the
+// single-query path of pkg/logic can be exercised without PostgreSQL
+type rangeRepo struct {
+	ports.IMEIRepository
+}
+
+func (r rangeRepo) EnclosingTacInfo(ctx context.Context, value string) (*ports.TacInfo, bool) {
+	var best *ports.TacInfo
+	for _, t := range r.ListAllTacInfo(ctx) {
+		if t.StartRangeTac <= value && t.EndRangeTac >= value {
+			if best == nil || t.StartRangeTac > best.StartRangeTac ||
+				(t.StartRangeTac == best.StartRangeTac && t.EndRangeTac < best.EndRangeTac) {
+				best = t
+			}
+		}
+	}
+	return best, best != nil
+}
+
+func (r rangeRepo) OverlappingTacInfo(ctx context.Context, start, end string) ([]*ports.TacInfo, error) {
+	var result []*ports.TacInfo
+	for _, t := range r.ListAllTacInfo(ctx) {
+		if t.StartRangeTac <= end && t.EndRangeTac >= start {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func TestTacRangeResolution(t *testing.T) {
+	_ = logger.New("test", "info")
+
+	repos := map[string]func() ports.IMEIRepository{
+		"walk":  func() ports.IMEIRepository { return memory.NewInMemoryIMEIRepository() },
+		"range": func() ports.IMEIRepository { return rangeRepo{memory.NewInMemoryIMEIRepository()} },
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			ctx := context.Background()
+
+			inserts := []models.TacInfo{
+				{StartRangeTac: "1331", EndRangeTac: "1332", Color: domainModels.EquipmentStatusBlacklisted},
+				{StartRangeTac: "133", EndRangeTac: "135", Color: domainModels.EquipmentStatusGreylisted},
+				{StartRangeTac: "13", EndRangeTac: "19", Color: domainModels.EquipmentStatusWhitelisted},
+			}
+			for _, in := range inserts {
+				if res := logic.InsertTac(repo, in); res.Status != "ok" {
+					t.Fatalf("InsertTac(%s-%s) = %s/%s", in.StartRangeTac, in.EndRangeTac, res.Status, res.Error)
+				}
+			}
+
+			if res := logic.InsertTac(repo, models.TacInfo{StartRangeTac: "134", EndRangeTac: "136", Color: domainModels.EquipmentStatusBlacklisted}); res.Error != "range_exist" {
+				t.Errorf("partial overlap: expected range_exist, got %s/%s", res.Status, res.Error)
+			}
+			if res := logic.InsertTac(repo, models.TacInfo{StartRangeTac: "133", EndRangeTac: "135", Color: domainModels.EquipmentStatusBlacklisted}); res.Error != "range_exist" {
+				t.Errorf("duplicate range: expected range_exist, got %s/%s", res.Status, res.Error)
+			}
+
+			links := map[string]string{
+				"1331            -1332ÿÿÿÿÿÿÿÿÿÿÿÿ": "133             -135ÿÿÿÿÿÿÿÿÿÿÿÿÿ",
+				"133             -135ÿÿÿÿÿÿÿÿÿÿÿÿÿ": "13              -19ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ",
+				"13              -19ÿÿÿÿÿÿÿÿÿÿÿÿÿÿ": "",
+			}
+			for key, want := range links {
+				info, ok := repo.LookupTacInfo(ctx, key)
+				if !ok {
+					t.Fatalf("range %q not stored", key)
+				}
+				got := ""
+				if info.PrevLink != nil {
+					got = *info.PrevLink
+				}
+				if got != want {
+					t.Errorf("PrevLink of %q = %q, want %q", key, got, want)
+				}
+			}
+
+			checks := map[string]domainModels.EquipmentStatus{
+				"133150000000000": domainModels.EquipmentStatusBlacklisted,
+				"134000000000000": domainModels.EquipmentStatusGreylisted,
+				"139000000000000": domainModels.EquipmentStatusWhitelisted,
+				"200000000000000": domainModels.CheckOutcomeUnknown,
+			}
+			for imei, want := range checks {
+				result, _ := logic.CheckTacInRepo(repo, imei)
+				if result.Color != want {
+					t.Errorf("CheckTacInRepo(%s) color = %s, want %s", imei, result.Color, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckTacUsesProvisionedRanges(t *testing.T) {
+	_ = logger.New("test", "info")
+	eirService := service.NewEIRService(nil, rangeRepo{memory.NewInMemoryIMEIRepository()}, nil, nil)
+	ctx := context.Background()
+	imei := "490154203237518"
+
+	if res, _ := eirService.CheckTac(ctx, imei, domainModels.SystemStatus{}); res.Status != "error" {
+		t.Fatalf("expected no range to match before provisioning, got %+v", res)
+	}
+
+	if res, _ := eirService.InsertTac(ctx, &ports.TacInfo{StartRangeTac: "4901", EndRangeTac: "4902", Color: domainModels.EquipmentStatusBlacklisted}); res.Status != "ok" {
+		t.Fatalf("InsertTac failed: %v", *res.Error)
+	}
+	res, err := eirService.CheckTac(ctx, imei, domainModels.SystemStatus{})
+	if err != nil {
+		t.Fatalf("CheckTac failed: %v", err)
+	}
+	if res.Status != "ok" || res.Color != domainModels.EquipmentStatusBlacklisted {
+		t.Errorf("expected the provisioned range to blacklist the IMEI, got %+v", res)
+	}
+	if res.TacInfo == nil || strings.TrimSpace(res.TacInfo.StartRangeTac) != "4901" {
+		t.Errorf("expected the matched range in the result, got %+v", res.TacInfo)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hsdfat8/eir/internal/adapters/memory"
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/internal/domain/ports"
+	"github.com/hsdfat8/eir/internal/logger"
+	"github.com/hsdfat8/eir/models"
+	"github.com/hsdfat8/eir/pkg/logic"
+)
+
+// failingHistory begins transactions whose history repository rejects every
+// record, failing InsertTacTx after the range and its children are written
+type failingHistory struct {
+	ports.TransactionProvider
+}
+
+func (f failingHistory) BeginTransaction(ctx context.Context) (ports.Transaction, error) {
+	tx, err := f.TransactionProvider.BeginTransaction(ctx)
+	return failingHistoryTx{tx}, err
+}
+
+type failingHistoryTx struct {
+	ports.Transaction
+}
+
+func (failingHistoryTx) GetHistoryRepository() ports.HistoryRepository {
+	return failingHistoryRepo{}
+}
+
+type failingHistoryRepo struct {
+	ports.HistoryRepository
+}
+
+func (failingHistoryRepo) RecordChange(ctx context.Context, history *domainModels.EquipmentHistory) error {
+	return errors.New("history unavailable")
+}
+
+func TestInsertTacTx(t *testing.T) {
+	_ = logger.New("test", "info")
+	ctx := context.Background()
+
+	repo := memory.NewInMemoryIMEIRepository()
+	history := memory.NewInMemoryHistoryRepository()
+	txs, err := memory.NewTransactionManager(repo, memory.NewInMemoryAuditRepository(), history)
+	if err != nil {
+		t.Fatalf("NewTransactionManager failed: %v", err)
+	}
+
+	child := models.TacInfo{StartRangeTac: "133", EndRangeTac: "135", Color: domainModels.EquipmentStatusBlacklisted}
+	if r := logic.InsertTacTx(ctx, txs, child); r.Status != "ok" {
+		t.Fatalf("insert child failed: %s", r.Error)
+	}
+	if r := logic.InsertTacTx(ctx, txs, models.TacInfo{StartRangeTac: "13", EndRangeTac: "19", Color: domainModels.EquipmentStatusGreylisted}); r.Status != "ok" {
+		t.Fatalf("insert parent failed: %s", r.Error)
+	}
+
+	entries, _ := history.GetHistoryByIMEI(ctx, "13", 0, 10)
+	if len(entries) != 1 {
+		t.Fatalf("expected one history entry for the parent, got %d", len(entries))
+	}
+	if entries[0].NewStatus != domainModels.EquipmentStatusGreylisted {
+		t.Errorf("expected GREYLISTED, got %s", entries[0].NewStatus)
+	}
+	if relinked, _ := entries[0].ChangeDetails["relinked"].([]string); len(relinked) != 1 {
+		t.Errorf("expected the child to be recorded as re-linked, got %v", entries[0].ChangeDetails["relinked"])
+	}
+
+	// A failure after the writes rolls back the range and the re-linking
+	before := repo.ListAllTacInfo(ctx)
+	r := logic.InsertTacTx(ctx, failingHistory{txs}, models.TacInfo{StartRangeTac: "130", EndRangeTac: "139", Color: domainModels.EquipmentStatusWhitelisted})
+	if r.Status != "error" {
+		t.Fatalf("expected the insert to fail, got %s", r.Status)
+	}
+
+	after := repo.ListAllTacInfo(ctx)
+	if len(after) != len(before) {
+		t.Fatalf("expected %d ranges after rollback, got %d", len(before), len(after))
+	}
+	for i := range before {
+		if after[i].KeyTac != before[i].KeyTac || prevLink(after[i]) != prevLink(before[i]) {
+			t.Errorf("range %s changed by a rolled back insert", before[i].KeyTac)
+		}
+	}
+
+	// The child still resolves through its original parent
+	result, _ := logic.CheckTacInRepo(repo, "1340000000000000")
+	if result.Color != domainModels.EquipmentStatusBlacklisted {
+		t.Errorf("expected black for the untouched child, got %s", result.Color)
+	}
+}
+
+func prevLink(t *ports.TacInfo) string {
+	if t.PrevLink == nil {
+		return ""
+	}
+	return *t.PrevLink
+}
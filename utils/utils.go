@@ -1,6 +1,9 @@
 package utils
 
-import "github.com/hsdfat8/eir/models"
+import (
+	domainModels "github.com/hsdfat8/eir/internal/domain/models"
+	"github.com/hsdfat8/eir/models"
+)
 
 func IsOverLoad(status models.SystemStatus) bool {
 	return false
@@ -10,35 +13,35 @@ var ImeiSampleData = map[string]*models.ImeiInfo{
 	"9": {
 		StartIMEI: "9              ",
 		EndIMEI:   []string{""},
-		Color:     "b",
+		Color:     domainModels.EquipmentStatusBlacklisted,
 	},
 	"91": {
 		StartIMEI: "91             ",
 		EndIMEI:   []string{""},
-		Color:     "w",
+		Color:     domainModels.EquipmentStatusWhitelisted,
 	},
 	"912": {
 		StartIMEI: "912            ",
 		EndIMEI:   []string{""},
-		Color:     "g",
+		Color:     domainModels.EquipmentStatusGreylisted,
 	},
 	"9123": {
 		StartIMEI: "9123           ",
 		EndIMEI:   []string{""},
-		Color:     "w",
+		Color:     domainModels.EquipmentStatusWhitelisted,
 	},
 	"9123456789012": {
 		StartIMEI: "9123456789012 ",
 		EndIMEI:   []string{""},
-		Color:     "b",
+		Color:     domainModels.EquipmentStatusBlacklisted,
 	},
 	"91234567895264": {
 		StartIMEI: "91234567895264",
 		EndIMEI:   []string{""},
-		Color:     "w",
+		Color:     domainModels.EquipmentStatusWhitelisted,
 	},
 }
 
 var TacSampleData = []models.TacInfo{
-	{StartRangeTac: "9100000000000000", EndRangeTac: "9899999999999999", Color: "grey", PrevLink: nil},
+	{StartRangeTac: "9100000000000000", EndRangeTac: "9899999999999999", Color: domainModels.EquipmentStatusGreylisted, PrevLink: nil},
 }